@@ -22,6 +22,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/store"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
@@ -388,6 +389,12 @@ func main() {
 		}
 	}
 	usage.SetStatisticsEnabled(cfg.UsageStatisticsEnabled)
+	metrics.SetEnabled(cfg.MetricsEnabled)
+	util.SetDefaultThinkingBudgets(cfg.DefaultThinkingBudgets)
+	util.SetModelParamDefaults(cfg.ModelParamDefaults)
+	util.SetSamplingProfiles(cfg.SamplingProfiles)
+	util.SetPromptInjections(cfg.PromptInjections)
+	util.SetSystemPromptPolicies(cfg.SystemPromptPolicies)
 	coreauth.SetQuotaCooldownDisabled(cfg.DisableCooling)
 
 	if err = logging.ConfigureLogOutput(cfg.LoggingToFile); err != nil {
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// auditState tracks the audit log configuration so reloads only reopen the file on change.
+type auditState struct {
+	mu      sync.Mutex
+	auditor atomic.Pointer[audit.Auditor]
+	// sink and redact are supplied by the host through WithAuditSink.
+	sink   audit.AuditSink
+	redact audit.Redactor
+	// file is the sink opened from configuration, closed when replaced.
+	file    *audit.FileSink
+	current *config.AuditLogConfig
+}
+
+// configureAudit applies the audit-log settings. A custom sink from WithAuditSink replaces
+// the file sink; an invalid setting disables audit logging with an error in the log so a
+// misconfiguration is never silently treated as compliant.
+func (s *Server) configureAudit(cfg config.AuditLogConfig) {
+	s.audit.mu.Lock()
+	defer s.audit.mu.Unlock()
+	if s.audit.current != nil && reflect.DeepEqual(*s.audit.current, cfg) {
+		return
+	}
+	s.audit.current = &cfg
+	s.audit.auditor.Store(nil)
+	s.closeAuditFileLocked()
+	if !cfg.Enabled {
+		return
+	}
+
+	redact, err := audit.PatternRedactor(cfg.Redact)
+	if err != nil {
+		log.Errorf("audit log disabled: %v", err)
+		return
+	}
+	if custom := s.audit.redact; custom != nil {
+		if redact == nil {
+			redact = custom
+		} else {
+			patterns := redact
+			redact = func(record *audit.AuditRecord) {
+				patterns(record)
+				custom(record)
+			}
+		}
+	}
+
+	sink := s.audit.sink
+	if sink == nil {
+		path := cfg.File
+		if path == "" {
+			path = filepath.Join("logs", "audit.jsonl")
+			if base := util.WritablePath(); base != "" {
+				path = filepath.Join(base, "logs", "audit.jsonl")
+			}
+		}
+		file, errOpen := audit.NewFileSink(path)
+		if errOpen != nil {
+			log.Errorf("audit log disabled: %v", errOpen)
+			return
+		}
+		s.audit.file = file
+		sink = file
+		log.Infof("audit log writing to %s", path)
+	}
+	s.audit.auditor.Store(&audit.Auditor{Sink: sink, Redact: redact})
+}
+
+// closeAudit stops audit logging and closes the file sink. Sinks supplied by the host are
+// left for the host to close.
+func (s *Server) closeAudit() {
+	s.audit.mu.Lock()
+	defer s.audit.mu.Unlock()
+	s.audit.auditor.Store(nil)
+	s.closeAuditFileLocked()
+}
+
+func (s *Server) closeAuditFileLocked() {
+	if s.audit.file == nil {
+		return
+	}
+	if err := s.audit.file.Close(); err != nil {
+		log.Warnf("audit: failed to close log file: %v", err)
+	}
+	s.audit.file = nil
+}
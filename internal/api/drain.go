@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// defaultShutdownDrainTimeout bounds the wait for in-flight requests when none is configured.
+const defaultShutdownDrainTimeout = 30 * time.Second
+
+// ShutdownDrainTimeout returns how long shutdown waits for in-flight requests to complete.
+func ShutdownDrainTimeout(cfg *config.Config) time.Duration {
+	if cfg != nil && cfg.ShutdownDrainTimeoutSeconds > 0 {
+		return time.Duration(cfg.ShutdownDrainTimeoutSeconds) * time.Second
+	}
+	return defaultShutdownDrainTimeout
+}
+
+// inFlightRequests tracks the requests being served so shutdown can wait for them and cancel
+// the ones that outlive the drain timeout. Unlike http.Server.Shutdown it also covers hijacked
+// connections such as websockets.
+type inFlightRequests struct {
+	mu      sync.Mutex
+	next    uint64
+	cancels map[uint64]context.CancelFunc
+	// empty is closed once the last tracked request finishes while someone waits.
+	empty chan struct{}
+}
+
+func newInFlightRequests() *inFlightRequests {
+	return &inFlightRequests{cancels: make(map[uint64]context.CancelFunc)}
+}
+
+// middleware registers each request for the duration of its handlers. Cancelling the request
+// context on forced shutdown propagates to the upstream call like a client disconnect.
+func (r *inFlightRequests) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		id := r.begin(cancel)
+		defer r.end(id)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func (r *inFlightRequests) begin(cancel context.CancelFunc) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	r.cancels[r.next] = cancel
+	return r.next
+}
+
+func (r *inFlightRequests) end(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cancels, id)
+	if len(r.cancels) == 0 && r.empty != nil {
+		close(r.empty)
+		r.empty = nil
+	}
+}
+
+func (r *inFlightRequests) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.cancels)
+}
+
+// wait blocks until no request is in flight, reporting false when ctx ends first.
+func (r *inFlightRequests) wait(ctx context.Context) bool {
+	r.mu.Lock()
+	if len(r.cancels) == 0 {
+		r.mu.Unlock()
+		return true
+	}
+	if r.empty == nil {
+		r.empty = make(chan struct{})
+	}
+	empty := r.empty
+	r.mu.Unlock()
+	select {
+	case <-empty:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// cancelAll cancels every request still in flight and returns how many there were.
+func (r *inFlightRequests) cancelAll() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+	return len(r.cancels)
+}
@@ -0,0 +1,18 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// GetAccounts reports, per credential, its provider, status, cooldowns, circuit breaker state
+// and remaining quota for GET /v0/admin/accounts. It reads local state only and redacts secrets.
+func (h *Handler) GetAccounts(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusOK, gin.H{"accounts": []coreauth.AccountStatus{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": h.authManager.Accounts()})
+}
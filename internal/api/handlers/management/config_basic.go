@@ -141,6 +141,14 @@ func (h *Handler) PutUsageStatisticsEnabled(c *gin.Context) {
 	h.updateBoolField(c, func(v bool) { h.cfg.UsageStatisticsEnabled = v })
 }
 
+// MetricsEnabled
+func (h *Handler) GetMetricsEnabled(c *gin.Context) {
+	c.JSON(200, gin.H{"metrics-enabled": h.cfg.MetricsEnabled})
+}
+func (h *Handler) PutMetricsEnabled(c *gin.Context) {
+	h.updateBoolField(c, func(v bool) { h.cfg.MetricsEnabled = v })
+}
+
 // UsageStatisticsEnabled
 func (h *Handler) GetLoggingToFile(c *gin.Context) {
 	c.JSON(200, gin.H{"logging-to-file": h.cfg.LoggingToFile})
@@ -155,6 +163,28 @@ func (h *Handler) PutRequestLog(c *gin.Context) {
 	h.updateBoolField(c, func(v bool) { h.cfg.RequestLog = v })
 }
 
+// Routing override headers
+func (h *Handler) GetAllowRoutingOverride(c *gin.Context) {
+	c.JSON(200, gin.H{"allow-routing-override": h.cfg.AllowRoutingOverride})
+}
+func (h *Handler) PutAllowRoutingOverride(c *gin.Context) {
+	h.updateBoolField(c, func(v bool) { h.cfg.AllowRoutingOverride = v })
+}
+
+// DryRun
+func (h *Handler) GetDryRun(c *gin.Context) { c.JSON(200, gin.H{"dry-run": h.cfg.DryRun}) }
+func (h *Handler) PutDryRun(c *gin.Context) {
+	h.updateBoolField(c, func(v bool) { h.cfg.DryRun = v })
+}
+
+// CoalesceRequests
+func (h *Handler) GetCoalesceRequests(c *gin.Context) {
+	c.JSON(200, gin.H{"coalesce-requests": h.cfg.CoalesceRequests})
+}
+func (h *Handler) PutCoalesceRequests(c *gin.Context) {
+	h.updateBoolField(c, func(v bool) { h.cfg.CoalesceRequests = v })
+}
+
 // Websocket auth
 func (h *Handler) GetWebsocketAuth(c *gin.Context) {
 	c.JSON(200, gin.H{"ws-auth": h.cfg.WebsocketAuth})
@@ -168,7 +198,7 @@ func (h *Handler) GetRequestRetry(c *gin.Context) {
 	c.JSON(200, gin.H{"request-retry": h.cfg.RequestRetry})
 }
 func (h *Handler) PutRequestRetry(c *gin.Context) {
-	h.updateIntField(c, func(v int) { h.cfg.RequestRetry = v })
+	h.updateNonNegativeIntField(c, func(v int) { h.cfg.RequestRetry = v })
 }
 
 // Max retry interval
@@ -176,7 +206,29 @@ func (h *Handler) GetMaxRetryInterval(c *gin.Context) {
 	c.JSON(200, gin.H{"max-retry-interval": h.cfg.MaxRetryInterval})
 }
 func (h *Handler) PutMaxRetryInterval(c *gin.Context) {
-	h.updateIntField(c, func(v int) { h.cfg.MaxRetryInterval = v })
+	h.updateNonNegativeIntField(c, func(v int) { h.cfg.MaxRetryInterval = v })
+}
+
+// Max credential attempts
+func (h *Handler) GetMaxCredentialAttempts(c *gin.Context) {
+	c.JSON(200, gin.H{"max-credential-attempts": h.cfg.MaxCredentialAttempts})
+}
+func (h *Handler) PutMaxCredentialAttempts(c *gin.Context) {
+	h.updateIntField(c, func(v int) { h.cfg.MaxCredentialAttempts = v })
+}
+
+// Retry backoff
+func (h *Handler) GetRetryBackoffBaseMs(c *gin.Context) {
+	c.JSON(200, gin.H{"retry-backoff-base-ms": h.cfg.RetryBackoffBaseMs})
+}
+func (h *Handler) PutRetryBackoffBaseMs(c *gin.Context) {
+	h.updateIntField(c, func(v int) { h.cfg.RetryBackoffBaseMs = v })
+}
+func (h *Handler) GetRetryBackoffMaxMs(c *gin.Context) {
+	c.JSON(200, gin.H{"retry-backoff-max-ms": h.cfg.RetryBackoffMaxMs})
+}
+func (h *Handler) PutRetryBackoffMaxMs(c *gin.Context) {
+	h.updateIntField(c, func(v int) { h.cfg.RetryBackoffMaxMs = v })
 }
 
 // Proxy URL
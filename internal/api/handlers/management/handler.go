@@ -15,7 +15,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	"golang.org/x/crypto/bcrypt"
@@ -40,6 +42,8 @@ type Handler struct {
 	allowRemoteOverride bool
 	envSecret           string
 	logDir              string
+	credentialReloader  func() (watcher.ReloadSummary, error)
+	modelReloader       func() (registry.ReloadSummary, error)
 }
 
 // NewHandler creates a new management handler instance.
@@ -269,6 +273,23 @@ func (h *Handler) updateIntField(c *gin.Context, set func(int)) {
 	h.persist(c)
 }
 
+// updateNonNegativeIntField is updateIntField for settings where a negative value is invalid.
+func (h *Handler) updateNonNegativeIntField(c *gin.Context, set func(int)) {
+	var body struct {
+		Value *int `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Value == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if *body.Value < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "value must not be negative"})
+		return
+	}
+	set(*body.Value)
+	h.persist(c)
+}
+
 func (h *Handler) updateStringField(c *gin.Context, set func(string)) {
 	var body struct {
 		Value *string `json:"value"`
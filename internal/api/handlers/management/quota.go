@@ -1,6 +1,9 @@
 package management
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
 
 // Quota exceeded toggles
 func (h *Handler) GetSwitchProject(c *gin.Context) {
@@ -16,3 +19,12 @@ func (h *Handler) GetSwitchPreviewModel(c *gin.Context) {
 func (h *Handler) PutSwitchPreviewModel(c *gin.Context) {
 	h.updateBoolField(c, func(v bool) { h.cfg.QuotaExceeded.SwitchPreviewModel = v })
 }
+
+// GetAccountQuotas reports each credential's usage and remaining quota in the current window.
+func (h *Handler) GetAccountQuotas(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(200, gin.H{"account-quotas": []coreauth.QuotaStatus{}})
+		return
+	}
+	c.JSON(200, gin.H{"account-quotas": h.authManager.Quotas()})
+}
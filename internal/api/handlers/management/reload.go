@@ -0,0 +1,103 @@
+package management
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SetCredentialReloader registers the function that re-reads the config and credential
+// store for POST /v0/admin/reload.
+func (h *Handler) SetCredentialReloader(reload func() (watcher.ReloadSummary, error)) {
+	h.mu.Lock()
+	h.credentialReloader = reload
+	h.mu.Unlock()
+}
+
+// SetModelReloader registers the function that rebuilds the model registry for
+// POST /v0/admin/reload-models.
+func (h *Handler) SetModelReloader(reload func() (registry.ReloadSummary, error)) {
+	h.mu.Lock()
+	h.modelReloader = reload
+	h.mu.Unlock()
+}
+
+// AdminMiddleware guards the admin endpoints with remote-management.admin-token, which is
+// independent of the management key. The endpoints are hidden (404) while no token is set.
+func (h *Handler) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var token string
+		if h.cfg != nil {
+			token = strings.TrimSpace(h.cfg.RemoteManagement.AdminToken)
+		}
+		if token == "" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		provided := strings.TrimSpace(c.GetHeader("X-Admin-Token"))
+		if ah := c.GetHeader("Authorization"); provided == "" && ah != "" {
+			parts := strings.SplitN(ah, " ", 2)
+			if len(parts) == 2 && strings.EqualFold(parts[0], "bearer") {
+				provided = strings.TrimSpace(parts[1])
+			}
+		}
+		if provided == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing admin token"})
+			return
+		}
+		if !adminTokenMatches(token, provided) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func adminTokenMatches(token, provided string) bool {
+	if strings.HasPrefix(token, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(token), []byte(provided)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(provided)) == 1
+}
+
+// PostReload re-reads the config and credential store and atomically swaps the active
+// credential set, reporting how many credentials were added, removed, modified and
+// unchanged. Requests in flight on old credentials complete normally.
+func (h *Handler) PostReload(c *gin.Context) {
+	h.mu.Lock()
+	reload := h.credentialReloader
+	h.mu.Unlock()
+	if reload == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "credential reload not available"})
+		return
+	}
+	summary, err := reload()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reload failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// PostReloadModels rebuilds the model registry from its sources and swaps it in atomically,
+// reporting the models added and removed and the new registry version.
+func (h *Handler) PostReloadModels(c *gin.Context) {
+	h.mu.Lock()
+	reload := h.modelReloader
+	h.mu.Unlock()
+	if reload == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "model reload not available"})
+		return
+	}
+	summary, err := reload()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "model reload failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultMaintenanceRetryAfter is the Retry-After sent during maintenance when none is given.
+const defaultMaintenanceRetryAfter = 30 * time.Second
+
+// maintenancePrefixes are the API paths rejected during maintenance. Probes, metrics, the
+// management and admin endpoints and OAuth callbacks keep working.
+var maintenancePrefixes = []string{"/v1/", "/v1beta/", "/v1internal", "/api/"}
+
+// maintenanceMode rejects new API requests with 503 while enabled. Requests, including
+// streams, that started before it was enabled are left to finish.
+type maintenanceMode struct {
+	mu         sync.RWMutex
+	enabled    bool
+	retryAfter time.Duration
+	since      time.Time
+}
+
+// maintenanceStatus is the body of the maintenance admin endpoints.
+type maintenanceStatus struct {
+	Enabled           bool       `json:"enabled"`
+	RetryAfterSeconds int        `json:"retry_after_seconds,omitempty"`
+	Since             *time.Time `json:"since,omitempty"`
+	InFlight          int        `json:"in_flight"`
+}
+
+func (m *maintenanceMode) set(enabled bool, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = defaultMaintenanceRetryAfter
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if enabled && !m.enabled {
+		m.since = time.Now()
+	}
+	m.enabled = enabled
+	m.retryAfter = retryAfter
+}
+
+// active reports whether maintenance is on and the Retry-After to send.
+func (m *maintenanceMode) active() (bool, time.Duration) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled, m.retryAfter
+}
+
+func (m *maintenanceMode) status(inFlight int) maintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status := maintenanceStatus{Enabled: m.enabled, InFlight: inFlight}
+	if m.enabled {
+		since := m.since
+		status.Since = &since
+		status.RetryAfterSeconds = int(m.retryAfter / time.Second)
+	}
+	return status
+}
+
+// middleware answers new API requests with 503 and a Retry-After while maintenance is on.
+func (m *maintenanceMode) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		enabled, retryAfter := m.active()
+		if !enabled || !isMaintenancePath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": gin.H{
+				"message": "The server is in maintenance mode; retry later",
+				"type":    "server_error",
+				"code":    "maintenance",
+			},
+		})
+	}
+}
+
+func isMaintenancePath(path string) bool {
+	for _, prefix := range maintenancePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGetMaintenance reports whether maintenance mode is on and how many requests are
+// still in flight.
+func (s *Server) handleGetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, s.maintenance.status(s.inFlight.count()))
+}
+
+// handlePutMaintenance turns maintenance mode on or off. While on, new API requests get 503
+// with Retry-After (retry_after_seconds, default 30) and /readyz reports not ready.
+func (s *Server) handlePutMaintenance(c *gin.Context) {
+	var body struct {
+		Enabled           *bool `json:"enabled"`
+		RetryAfterSeconds int   `json:"retry_after_seconds"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Enabled == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body: expected {\"enabled\": true|false}"})
+		return
+	}
+	if body.RetryAfterSeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retry_after_seconds must not be negative"})
+		return
+	}
+	s.maintenance.set(*body.Enabled, time.Duration(body.RetryAfterSeconds)*time.Second)
+	status := s.maintenance.status(s.inFlight.count())
+	if status.Enabled {
+		log.Infof("maintenance mode enabled: rejecting new requests, %d in flight", status.InFlight)
+	} else {
+		log.Info("maintenance mode disabled")
+	}
+	c.JSON(http.StatusOK, status)
+}
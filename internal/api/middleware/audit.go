@@ -0,0 +1,89 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the audit middleware that records full prompts and responses to the
+// configured audit sink.
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// auditResponseWriter copies everything written to the client.
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// AuditMiddleware creates a Gin middleware that records each API request with its body, the
+// model and credential that served it, and the response returned to the client. The
+// auditor callback is consulted on every request so a nil result (audit logging disabled)
+// follows configuration reloads. It must run after authentication to attribute requests to
+// clients. GET requests, such as model listings and websocket upgrades, are not audited.
+func AuditMiddleware(auditor func() *audit.Auditor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var current *audit.Auditor
+		if auditor != nil {
+			current = auditor()
+		}
+		if current == nil || c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+
+		started := time.Now()
+		var requestBody []byte
+		if c.Request.Body != nil {
+			requestBody, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(requestBody))
+		}
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		// Exposed to handlers that record additional requests, such as model mirrors.
+		c.Set("AUDITOR", current)
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		record := audit.AuditRecord{
+			Time:       started.UTC(),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Status:     writer.Status(),
+			DurationMs: time.Since(started).Milliseconds(),
+			Request:    string(requestBody),
+			Response:   writer.body.String(),
+		}
+		if apiKey, exists := c.Get("apiKey"); exists {
+			record.Client = util.HideAPIKey(fmt.Sprint(apiKey))
+		}
+		if value, exists := c.Get("REQUEST_RECORD"); exists {
+			if requestRecord, ok := value.(*logging.RequestRecord); ok {
+				snapshot := requestRecord.Snapshot()
+				record.RequestID = snapshot.RequestID
+				record.Model = snapshot.Model
+				record.Stream = snapshot.Stream
+				record.Provider = snapshot.Provider
+				record.AuthID = snapshot.AuthID
+			}
+		}
+		current.Record(record)
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+)
+
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []audit.AuditRecord
+	err     error
+}
+
+func (s *memoryAuditSink) Write(record audit.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return s.err
+}
+
+func newAuditTestEngine(auditor *audit.Auditor) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("apiKey", "sk-team-a-0123456789")
+		c.Next()
+	})
+	engine.Use(AuditMiddleware(func() *audit.Auditor { return auditor }))
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		record := logging.NewRequestRecord(c.Request.Method, c.Request.URL.Path, "openai")
+		record.SetModel("gpt-test", false)
+		record.ObserveAttempt("codex", "auth-1", time.Millisecond)
+		c.Set("REQUEST_RECORD", record)
+		c.String(http.StatusOK, "echo:"+string(body))
+	})
+	engine.GET("/v1/models", func(c *gin.Context) { c.String(http.StatusOK, "models") })
+	return engine
+}
+
+func TestAuditMiddleware_RecordsRequestAndResponse(t *testing.T) {
+	sink := &memoryAuditSink{}
+	redact, err := audit.PatternRedactor([]string{`secret-\w+`})
+	if err != nil {
+		t.Fatalf("PatternRedactor: %v", err)
+	}
+	engine := newAuditTestEngine(&audit.Auditor{Sink: sink, Redact: redact})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"prompt":"hi secret-token"}`))
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != `echo:{"prompt":"hi secret-token"}` {
+		t.Fatalf("Expected the handler to see the unmodified body, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+
+	if len(sink.records) != 1 {
+		t.Fatalf("Expected one audit record, got %d", len(sink.records))
+	}
+	got := sink.records[0]
+	if got.Request != `{"prompt":"hi [REDACTED]"}` || got.Response != `echo:{"prompt":"hi [REDACTED]"}` {
+		t.Fatalf("Expected redacted bodies, got request %q response %q", got.Request, got.Response)
+	}
+	if got.Model != "gpt-test" || got.Provider != "codex" || got.AuthID != "auth-1" || got.Status != http.StatusOK {
+		t.Fatalf("Expected model, provider, credential and status in the record, got %+v", got)
+	}
+	if got.Client == "" || strings.Contains(got.Client, "0123456789") {
+		t.Fatalf("Expected a masked client key, got %q", got.Client)
+	}
+}
+
+func TestAuditMiddleware_FailsOpen(t *testing.T) {
+	sink := &memoryAuditSink{err: errors.New("disk full")}
+	engine := newAuditTestEngine(&audit.Auditor{Sink: sink})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "echo:{}" {
+		t.Fatalf("Expected the request to succeed despite the sink error, got %d %q", rec.Code, rec.Body.String())
+	}
+	if len(sink.records) != 1 {
+		t.Fatalf("Expected the sink to be called once, got %d", len(sink.records))
+	}
+}
+
+func TestAuditMiddleware_DisabledPassesThrough(t *testing.T) {
+	engine := newAuditTestEngine(nil)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with audit logging disabled, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,124 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the inbound request body size limit.
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// BodyLimits bounds inbound request bodies. Text applies to every body; Multimodal, when
+// larger, applies instead to bodies carrying inline images, audio or files, whose base64
+// payloads are legitimately much bigger. A Text of zero disables the limit.
+type BodyLimits struct {
+	Text       int64
+	Multimodal int64
+}
+
+// mediaPartTypes are the content part types of media in the OpenAI Chat Completions,
+// OpenAI Responses and Claude request formats.
+var mediaPartTypes = map[string]struct{}{
+	"image_url":   {},
+	"input_audio": {},
+	"file":        {},
+	"input_image": {},
+	"input_file":  {},
+	"image":       {},
+	"document":    {},
+}
+
+// mediaPartKeys are the object keys of inline media parts in the Gemini request format.
+var mediaPartKeys = map[string]struct{}{
+	"inlineData":  {},
+	"inline_data": {},
+}
+
+// BodyLimitMiddleware creates a Gin middleware that rejects request bodies over the limits
+// with 413 before any handler parses or translates them. The body is read through
+// http.MaxBytesReader, so chunked uploads without a Content-Length are bounded as well; bodies
+// within the limits are buffered and handed on unchanged. The limits callback is consulted on
+// every request so it follows configuration reloads.
+func BodyLimitMiddleware(limits func() BodyLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var current BodyLimits
+		if limits != nil {
+			current = limits()
+		}
+		if current.Text <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		ceiling := max(current.Text, current.Multimodal)
+		if c.Request.ContentLength > ceiling {
+			rejectBody(c, ceiling)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, ceiling))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				rejectBody(c, ceiling)
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"message": fmt.Sprintf("failed to read request body: %v", err),
+					"type":    "invalid_request_error",
+				},
+			})
+			return
+		}
+		if int64(len(body)) > current.Text && !isMultimodalBody(body) {
+			rejectBody(c, current.Text)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Next()
+	}
+}
+
+// isMultimodalBody reports whether body carries media parts. The JSON structure is inspected
+// rather than the raw bytes, so text that merely mentions a media type does not count.
+func isMultimodalBody(body []byte) bool {
+	return hasMediaPart(gjson.ParseBytes(body))
+}
+
+func hasMediaPart(value gjson.Result) bool {
+	if !value.IsObject() && !value.IsArray() {
+		return false
+	}
+	if value.IsObject() {
+		if _, ok := mediaPartTypes[value.Get("type").String()]; ok {
+			return true
+		}
+	}
+	found := false
+	value.ForEach(func(key, child gjson.Result) bool {
+		if _, ok := mediaPartKeys[key.String()]; ok && child.IsObject() {
+			found = true
+		} else {
+			found = hasMediaPart(child)
+		}
+		return !found
+	})
+	return found
+}
+
+func rejectBody(c *gin.Context, limit int64) {
+	c.Header("Connection", "close")
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": gin.H{
+			"message": fmt.Sprintf("request body exceeds the limit of %d bytes", limit),
+			"type":    "invalid_request_error",
+			"code":    "request_too_large",
+		},
+	})
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	testTextLimit       = 256
+	testMultimodalLimit = 1024
+)
+
+func newBodyLimitTestEngine(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(BodyLimitMiddleware(func() BodyLimits {
+		return BodyLimits{Text: testTextLimit, Multimodal: testMultimodalLimit}
+	}))
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, strconv.Itoa(len(body)))
+	})
+	return engine
+}
+
+// textBody returns a text chat request of exactly size bytes.
+func textBody(size int) string {
+	prefix, suffix := `{"messages":[{"role":"user","content":"`, `"}]}`
+	return prefix + strings.Repeat("a", size-len(prefix)-len(suffix)) + suffix
+}
+
+func TestBodyLimitMiddleware_OverLimitRejected(t *testing.T) {
+	engine := newBodyLimitTestEngine(t)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(textBody(testTextLimit+1))))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected 413 for an over-limit body, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := gjson.Get(w.Body.String(), "error.code").String(); code != "request_too_large" {
+		t.Fatalf("Expected the request_too_large error code, got %s", w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), strconv.Itoa(testTextLimit)) {
+		t.Fatalf("Expected the error to name the limit, got %s", w.Body.String())
+	}
+}
+
+func TestBodyLimitMiddleware_AtLimitAccepted(t *testing.T) {
+	engine := newBodyLimitTestEngine(t)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(textBody(testTextLimit))))
+
+	if w.Code != http.StatusOK || w.Body.String() != strconv.Itoa(testTextLimit) {
+		t.Fatalf("Expected the handler to read the full at-limit body, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBodyLimitMiddleware_MultimodalLimit(t *testing.T) {
+	engine := newBodyLimitTestEngine(t)
+	image := `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + strings.Repeat("A", 600) + `"}}]}]}`
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(image)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected a multimodal body within its limit to pass, got %d: %s", w.Code, w.Body.String())
+	}
+
+	image = strings.Replace(image, "AAAA", strings.Repeat("A", testMultimodalLimit), 1)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(image)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected 413 for a multimodal body over its limit, got %d", w.Code)
+	}
+}
+
+func TestBodyLimitMiddleware_TextMentioningMediaHeldToTextLimit(t *testing.T) {
+	engine := newBodyLimitTestEngine(t)
+	body := `{"messages":[{"role":"user","content":"Explain the \"image_url\" and \"inlineData\" fields: ` + strings.Repeat("a", testTextLimit) + `"}]}`
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected a text body mentioning media to get the text limit, got %d", w.Code)
+	}
+}
+
+func TestIsMultimodalBody(t *testing.T) {
+	cases := map[string]bool{
+		`{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:"}}]}]}`:                       true,
+		`{"input":[{"role":"user","content":[{"type":"input_image","image_url":"data:"}]}]}`:                                true,
+		`{"messages":[{"role":"user","content":[{"type":"image","source":{"type":"base64","media_type":"image/png"}}]}]}`:   true,
+		`{"contents":[{"role":"user","parts":[{"inlineData":{"mimeType":"image/png","data":"AA=="}}]}]}`:                    true,
+		`{"messages":[{"role":"user","content":[{"type":"text","text":"{\"type\":\"image_url\",\"media_type\":\"x\"}"}]}]}`: false,
+		`{"contents":[{"role":"user","parts":[{"text":"inline_data"}]}]}`:                                                   false,
+	}
+	for body, want := range cases {
+		if got := isMultimodalBody([]byte(body)); got != want {
+			t.Errorf("isMultimodalBody(%s) = %v, want %v", body, got, want)
+		}
+	}
+}
+
+func TestBodyLimitMiddleware_ChunkedUploadBounded(t *testing.T) {
+	engine := newBodyLimitTestEngine(t)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	// An io.Reader without a known length is sent with chunked transfer encoding.
+	body := io.MultiReader(strings.NewReader(textBody(testMultimodalLimit)), strings.NewReader(strings.Repeat(" ", 64)))
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions", body)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if req.ContentLength != 0 || resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected 413 for a chunked upload over the limit, got %d", resp.StatusCode)
+	}
+}
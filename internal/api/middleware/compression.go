@@ -0,0 +1,222 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the response compression middleware negotiated through Accept-Encoding.
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type compressionState int
+
+const (
+	// compressionBuffering holds the body until it reaches the threshold or the request ends.
+	compressionBuffering compressionState = iota
+	// compressionActive writes the body through the encoder.
+	compressionActive
+	// compressionBypassed writes the body unchanged.
+	compressionBypassed
+)
+
+// flushWriteCloser is implemented by the gzip and zlib writers.
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressResponseWriter buffers the start of a response to decide whether it is worth
+// compressing. Bodies reaching minBytes are encoded; smaller bodies, SSE streams, bodies the
+// handler flushes early and bodies that already carry a Content-Encoding are sent unchanged.
+type compressResponseWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minBytes int
+	status   int
+	state    compressionState
+	buf      bytes.Buffer
+	encoder  flushWriteCloser
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.state == compressionBuffering {
+		w.status = code
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressResponseWriter) WriteHeaderNow() {
+	if w.state == compressionBuffering {
+		w.bypass()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressResponseWriter) Status() int {
+	if w.state == compressionBuffering && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressResponseWriter) Written() bool {
+	if w.state == compressionBuffering {
+		return w.status != 0 || w.buf.Len() > 0
+	}
+	return w.ResponseWriter.Written()
+}
+
+func (w *compressResponseWriter) Write(data []byte) (int, error) {
+	switch w.state {
+	case compressionActive:
+		return w.encoder.Write(data)
+	case compressionBypassed:
+		return w.ResponseWriter.Write(data)
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") {
+		if err := w.bypass(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	w.buf.Write(data)
+	if w.buf.Len() >= w.minBytes {
+		if err := w.compress(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressResponseWriter) WriteString(data string) (int, error) {
+	return w.Write([]byte(data))
+}
+
+// Flush sends what has been written so far. A flush while buffering marks the response as a
+// stream, which is then sent uncompressed.
+func (w *compressResponseWriter) Flush() {
+	switch w.state {
+	case compressionBuffering:
+		_ = w.bypass()
+	case compressionActive:
+		_ = w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// commitStatus forwards the status recorded while buffering.
+func (w *compressResponseWriter) commitStatus() {
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// bypass sends the buffered body unchanged and passes later writes through.
+func (w *compressResponseWriter) bypass() error {
+	w.state = compressionBypassed
+	w.commitStatus()
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// compress switches to the negotiated encoding and writes the buffered body through it. The
+// encoded length is unknown up front, so Content-Length is dropped and the body is chunked.
+func (w *compressResponseWriter) compress() error {
+	w.state = compressionActive
+	header := w.Header()
+	header.Set("Content-Encoding", w.encoding)
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+	w.commitStatus()
+	if w.encoding == "gzip" {
+		w.encoder = gzip.NewWriter(w.ResponseWriter)
+	} else {
+		w.encoder = zlib.NewWriter(w.ResponseWriter)
+	}
+	_, err := w.encoder.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// finish completes the response once the handler chain has returned.
+func (w *compressResponseWriter) finish() {
+	switch w.state {
+	case compressionBuffering:
+		_ = w.bypass()
+	case compressionActive:
+		_ = w.encoder.Close()
+	}
+}
+
+// CompressionMiddleware creates a Gin middleware that gzip- or deflate-encodes response bodies
+// of at least minBytes for clients whose Accept-Encoding allows it. The minBytes callback is
+// consulted on every request so the threshold follows configuration reloads; zero disables
+// compression. Server-sent event streams and responses the handler flushes before reaching
+// the threshold are never compressed, so streaming latency is unaffected.
+func CompressionMiddleware(minBytes func() int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		threshold := 0
+		if minBytes != nil {
+			threshold = minBytes()
+		}
+		if threshold <= 0 || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressResponseWriter{ResponseWriter: c.Writer, encoding: encoding, minBytes: threshold}
+		c.Writer = writer
+		c.Next()
+		writer.finish()
+		c.Writer = writer.ResponseWriter
+	}
+}
+
+// negotiateEncoding picks gzip, then deflate, from an Accept-Encoding header, honouring q=0
+// exclusions. It returns "" when neither is acceptable.
+func negotiateEncoding(accept string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[name] = q > 0
+	}
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if ok, listed := accepted[encoding]; listed {
+			if ok {
+				return encoding
+			}
+			continue
+		}
+		if accepted["*"] {
+			return encoding
+		}
+	}
+	return ""
+}
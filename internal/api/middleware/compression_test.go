@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCompressionTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(CompressionMiddleware(func() int { return 1024 }))
+	engine.GET("/large", func(c *gin.Context) {
+		body := `{"content":"` + strings.Repeat("compressible ", 400) + `"}`
+		c.Header("Content-Length", strconv.Itoa(len(body)))
+		c.Data(http.StatusOK, "application/json", []byte(body))
+	})
+	engine.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"content": "short"})
+	})
+	engine.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			_, _ = c.Writer.WriteString("data: " + strings.Repeat("x", 600) + "\n\n")
+			c.Writer.Flush()
+		}
+	})
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// fetchRaw requests path without the transport's transparent gzip handling.
+func fetchRaw(t *testing.T, url, acceptEncoding string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp, body
+}
+
+func TestCompressionMiddleware_CompressesLargeResponse(t *testing.T) {
+	server := newCompressionTestServer(t)
+	plainResp, plain := fetchRaw(t, server.URL+"/large", "")
+	if plainResp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("Expected no encoding without Accept-Encoding, got %q", plainResp.Header.Get("Content-Encoding"))
+	}
+
+	cases := []struct {
+		accept string
+		want   string
+		decode func(io.Reader) (io.Reader, error)
+	}{
+		{accept: "gzip, deflate, br", want: "gzip", decode: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{accept: "gzip;q=0, deflate", want: "deflate", decode: func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.want, func(t *testing.T) {
+			resp, body := fetchRaw(t, server.URL+"/large", tc.accept)
+			if got := resp.Header.Get("Content-Encoding"); got != tc.want {
+				t.Fatalf("Expected Content-Encoding %q, got %q", tc.want, got)
+			}
+			if resp.Header.Get("Vary") != "Accept-Encoding" {
+				t.Fatalf("Expected Vary: Accept-Encoding, got %q", resp.Header.Get("Vary"))
+			}
+			// The handler's Content-Length describes the plain body, so the response must be
+			// chunked or carry the length net/http computed for the encoded body.
+			if resp.ContentLength != -1 && resp.ContentLength != int64(len(body)) {
+				t.Fatalf("Expected Content-Length to match the encoded %d bytes, got %d", len(body), resp.ContentLength)
+			}
+			if len(body) >= len(plain) {
+				t.Fatalf("Expected the body to shrink from %d bytes, got %d", len(plain), len(body))
+			}
+			reader, err := tc.decode(strings.NewReader(string(body)))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			decoded, err := io.ReadAll(reader)
+			if err != nil || string(decoded) != string(plain) {
+				t.Fatalf("Expected the decoded body to match the plain one (%v)", err)
+			}
+		})
+	}
+}
+
+func TestCompressionMiddleware_LeavesSmallResponseUncompressed(t *testing.T) {
+	server := newCompressionTestServer(t)
+	resp, body := fetchRaw(t, server.URL+"/small", "gzip")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201 to be preserved, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("Expected no encoding below the threshold, got %q", resp.Header.Get("Content-Encoding"))
+	}
+	if resp.ContentLength != int64(len(body)) || string(body) != `{"content":"short"}` {
+		t.Fatalf("Expected Content-Length %d for %q, got %d", len(body), body, resp.ContentLength)
+	}
+}
+
+func TestCompressionMiddleware_LeavesStreamsUncompressed(t *testing.T) {
+	server := newCompressionTestServer(t)
+	resp, body := fetchRaw(t, server.URL+"/stream", "gzip")
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("Expected SSE streams to stay uncompressed, got %q", resp.Header.Get("Content-Encoding"))
+	}
+	if strings.Count(string(body), "data: ") != 3 {
+		t.Fatalf("Expected three events, got %q", body)
+	}
+}
+
+func TestCompressionMiddleware_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(CompressionMiddleware(func() int { return 0 }))
+	engine.GET("/large", func(c *gin.Context) {
+		c.String(http.StatusOK, strings.Repeat("a", 4096))
+	})
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	engine.ServeHTTP(rr, req)
+	if rr.Header().Get("Content-Encoding") != "" || rr.Body.Len() != 4096 {
+		t.Fatalf("Expected compression to be off, got %q with %d bytes", rr.Header().Get("Content-Encoding"), rr.Body.Len())
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                  "",
+		"br":                "",
+		"gzip":              "gzip",
+		"deflate":           "deflate",
+		"deflate, gzip":     "gzip",
+		"GZIP;q=0.5":        "gzip",
+		"gzip;q=0":          "",
+		"*":                 "gzip",
+		"*, gzip;q=0":       "deflate",
+		"identity, deflate": "deflate",
+	}
+	for accept, want := range cases {
+		if got := negotiateEncoding(accept); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
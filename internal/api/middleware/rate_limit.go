@@ -0,0 +1,125 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the per-client rate limiting middleware backed by a pluggable
+// token-bucket limiter.
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/ratelimit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// RateLimitMiddleware creates a Gin middleware that enforces per-client token-bucket limits.
+// The policy callback is consulted on every request so limits follow configuration reloads.
+// It must run after authentication because clients are keyed on the "apiKey" context value
+// unless the policy names a header. Clients with a per-user limit are additionally limited per
+// end user, identified by the OpenAI "user" field of the request body. Clients without a limit
+// bypass the limiter, and limiter backend errors fail open so an unavailable store never
+// blocks traffic. For limited clients the "rateLimitAllow" context value lets handlers that
+// fan one request out into several, such as the batch endpoint, take further tokens from the
+// same bucket.
+func RateLimitMiddleware(limiter ratelimit.Limiter, policy func() *ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil || policy == nil {
+			c.Next()
+			return
+		}
+		p := policy()
+		if !p.Enabled() {
+			c.Next()
+			return
+		}
+
+		key := rateLimitKey(c, p.Header)
+		if userLimit, ok := p.LookupUser(key); ok {
+			if user := requestEndUser(c); user != "" {
+				if !allowRequest(c, limiter, ratelimit.UserKey(key, user), userLimit, "Rate limit exceeded for this user, retry later") {
+					return
+				}
+			}
+		}
+		limit, ok := p.Lookup(key)
+		if !ok || limit.Unlimited() {
+			c.Next()
+			return
+		}
+		if !allowRequest(c, limiter, key, limit, "Rate limit exceeded, retry later") {
+			return
+		}
+		c.Set("rateLimitAllow", func(ctx context.Context) (bool, time.Duration) {
+			allowed, retryAfter, err := limiter.Allow(ctx, key, limit)
+			if err != nil {
+				log.Warnf("rate limiter error, allowing request: %v", err)
+				return true, 0
+			}
+			return allowed, retryAfter
+		})
+		c.Next()
+	}
+}
+
+// allowRequest takes a token for key and aborts the request with 429 when none is left. It
+// reports whether the request may proceed.
+func allowRequest(c *gin.Context, limiter ratelimit.Limiter, key string, limit ratelimit.Limit, message string) bool {
+	allowed, retryAfter, err := limiter.Allow(c.Request.Context(), key, limit)
+	if err != nil {
+		log.Warnf("rate limiter error, allowing request: %v", err)
+		return true
+	}
+	if allowed {
+		return true
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    "rate_limit_error",
+		},
+	})
+	return false
+}
+
+// requestEndUser returns the OpenAI "user" field of the request body, leaving the body intact
+// for the handler. Invalid values yield "" and are rejected by the handler.
+func requestEndUser(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	_ = c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	user, _ := util.NormalizeEndUser(gjson.GetBytes(body, "user"))
+	return user
+}
+
+// rateLimitKey identifies the client by the configured header, falling back to the
+// authenticated API key.
+func rateLimitKey(c *gin.Context, header string) string {
+	if header != "" {
+		return strings.TrimSpace(c.GetHeader(header))
+	}
+	if value, exists := c.Get("apiKey"); exists {
+		if key, ok := value.(string); ok {
+			return key
+		}
+	}
+	return ""
+}
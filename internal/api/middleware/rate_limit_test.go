@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/ratelimit"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := ratelimit.NewPolicy(config.RateLimitConfig{Keys: []config.RateLimitKey{
+		{Key: "team-a", RequestsPerMinute: 6, Burst: 1},
+	}})
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("apiKey", c.GetHeader("Authorization"))
+		c.Next()
+	})
+	engine.Use(RateLimitMiddleware(ratelimit.NewMemoryLimiter(), func() *ratelimit.Policy { return policy }))
+	engine.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", key)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("team-a"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected first request to pass, got %d", rec.Code)
+	}
+	rec := do("team-a")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the bucket is empty, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "10" {
+		t.Fatalf("Expected Retry-After of 10s at 6 rpm, got %q", got)
+	}
+	for i := 0; i < 3; i++ {
+		if rec = do("team-b"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected unlisted key to bypass the limiter, got %d", rec.Code)
+		}
+	}
+}
+
+func TestRateLimitMiddleware_ExposesAllowForFurtherTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := ratelimit.NewPolicy(config.RateLimitConfig{Keys: []config.RateLimitKey{
+		{Key: "team-a", RequestsPerMinute: 6, Burst: 2},
+	}})
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("apiKey", "team-a")
+		c.Next()
+	})
+	engine.Use(RateLimitMiddleware(ratelimit.NewMemoryLimiter(), func() *ratelimit.Policy { return policy }))
+	var results []bool
+	engine.GET("/", func(c *gin.Context) {
+		value, exists := c.Get("rateLimitAllow")
+		allow, ok := value.(func(context.Context) (bool, time.Duration))
+		if !exists || !ok {
+			t.Fatalf("Expected rateLimitAllow to be set for a limited key")
+		}
+		for i := 0; i < 2; i++ {
+			allowed, _ := allow(c.Request.Context())
+			results = append(results, allowed)
+		}
+		c.Status(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected request to pass, got %d", rec.Code)
+	}
+	if len(results) != 2 || !results[0] || results[1] {
+		t.Fatalf("Expected one further token out of a burst of 2, got %v", results)
+	}
+}
+
+func TestRateLimitMiddleware_LimitsEndUsersWithinKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := ratelimit.NewPolicy(config.RateLimitConfig{Keys: []config.RateLimitKey{
+		{Key: "team-a", PerUserRequestsPerMinute: 6, PerUserBurst: 1},
+	}})
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("apiKey", "team-a")
+		c.Next()
+	})
+	engine.Use(RateLimitMiddleware(ratelimit.NewMemoryLimiter(), func() *ratelimit.Policy { return policy }))
+	engine.POST("/", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+
+	do := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rec
+	}
+
+	alice := `{"user":"alice","messages":[]}`
+	if rec := do(alice); rec.Code != http.StatusOK || rec.Body.String() != alice {
+		t.Fatalf("Expected first request of alice to pass with its body intact, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := do(alice); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected alice to be limited, got %d", rec.Code)
+	}
+	if rec := do(`{"user":"bob"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected another user of the key to pass, got %d", rec.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if rec := do(`{"messages":[]}`); rec.Code != http.StatusOK {
+			t.Fatalf("Expected requests without a user to bypass the per-user limit, got %d", rec.Code)
+		}
+	}
+}
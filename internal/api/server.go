@@ -9,9 +9,11 @@ import (
 	"crypto/subtle"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -23,11 +25,16 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/middleware"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules"
 	ampmodule "github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules/amp"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/ratelimit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers/claude"
@@ -49,6 +56,9 @@ type serverOptionConfig struct {
 	keepAliveEnabled     bool
 	keepAliveTimeout     time.Duration
 	keepAliveOnTimeout   func()
+	rateLimiter          ratelimit.Limiter
+	auditSink            audit.AuditSink
+	auditRedact          audit.Redactor
 }
 
 // ServerOption customises HTTP server construction.
@@ -102,6 +112,22 @@ func WithKeepAliveEndpoint(timeout time.Duration, onTimeout func()) ServerOption
 	}
 }
 
+// WithRateLimiter replaces the in-memory token-bucket store used for per-client rate limits.
+func WithRateLimiter(limiter ratelimit.Limiter) ServerOption {
+	return func(cfg *serverOptionConfig) {
+		cfg.rateLimiter = limiter
+	}
+}
+
+// WithAuditSink replaces the audit log file with sink, and adds redact (optional) to the
+// configured redaction patterns. Records are only written while audit-log.enabled is set.
+func WithAuditSink(sink audit.AuditSink, redact audit.Redactor) ServerOption {
+	return func(cfg *serverOptionConfig) {
+		cfg.auditSink = sink
+		cfg.auditRedact = redact
+	}
+}
+
 // WithRequestLoggerFactory customises request logger creation.
 func WithRequestLoggerFactory(factory func(*config.Config, string) logging.RequestLogger) ServerOption {
 	return func(cfg *serverOptionConfig) {
@@ -135,6 +161,13 @@ type Server struct {
 	requestLogger logging.RequestLogger
 	loggerToggle  func(bool)
 
+	// rateLimiter stores per-client token buckets; rateLimitPolicy holds the current limits.
+	rateLimiter     ratelimit.Limiter
+	rateLimitPolicy atomic.Pointer[ratelimit.Policy]
+
+	// audit holds the audit log state; audit.auditor is nil while audit logging is disabled.
+	audit auditState
+
 	// configFilePath is the absolute path to the YAML config file for persistence.
 	configFilePath string
 
@@ -163,6 +196,19 @@ type Server struct {
 
 	localPassword string
 
+	// inFlight tracks requests being served so shutdown can drain them.
+	inFlight *inFlightRequests
+
+	// maintenance rejects new API requests while deploys drain the server.
+	maintenance *maintenanceMode
+
+	// compressionMinBytes is the response size from which bodies are compressed; zero while
+	// compression is disabled.
+	compressionMinBytes *atomic.Int64
+
+	// bodyLimits holds the inbound request body limits; zero while the limit is disabled.
+	bodyLimits *atomic.Pointer[middleware.BodyLimits]
+
 	keepAliveEnabled   bool
 	keepAliveTimeout   time.Duration
 	keepAliveOnTimeout func()
@@ -199,12 +245,27 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	}
 
 	// Add middleware
+	engine.Use(logging.RequestIDMiddleware())
 	engine.Use(logging.GinLogrusLogger())
 	engine.Use(logging.GinLogrusRecovery())
+	maintenance := &maintenanceMode{}
+	engine.Use(maintenance.middleware())
+	inFlight := newInFlightRequests()
+	engine.Use(inFlight.middleware())
 	for _, mw := range optionState.extraMiddleware {
 		engine.Use(mw)
 	}
 
+	// Bound request bodies before anything reads them.
+	bodyLimits := &atomic.Pointer[middleware.BodyLimits]{}
+	bodyLimits.Store(requestBodyLimits(cfg.RequestBodyLimit))
+	engine.Use(middleware.BodyLimitMiddleware(func() middleware.BodyLimits { return *bodyLimits.Load() }))
+
+	// Compress before request logging so logged bodies stay readable.
+	compressionMinBytes := &atomic.Int64{}
+	compressionMinBytes.Store(int64(compressionThreshold(cfg.Compression)))
+	engine.Use(middleware.CompressionMiddleware(func() int { return int(compressionMinBytes.Load()) }))
+
 	// Add request logging middleware (positioned after recovery, before auth)
 	// Resolve logs directory relative to the configuration file directory.
 	var requestLogger logging.RequestLogger
@@ -245,13 +306,34 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 		currentPath:         wd,
 		envManagementSecret: envManagementSecret,
 		wsRoutes:            make(map[string]struct{}),
+		inFlight:            inFlight,
+		maintenance:         maintenance,
+		compressionMinBytes: compressionMinBytes,
+		bodyLimits:          bodyLimits,
 	}
 	s.wsAuthEnabled.Store(cfg.WebsocketAuth)
+	s.rateLimiter = optionState.rateLimiter
+	if s.rateLimiter == nil {
+		s.rateLimiter = ratelimit.NewMemoryLimiter()
+	}
+	s.rateLimitPolicy.Store(ratelimit.NewPolicy(cfg.RateLimit))
+	s.audit.sink = optionState.auditSink
+	s.audit.redact = optionState.auditRedact
+	s.configureAudit(cfg.AuditLog)
 	// Save initial YAML snapshot
 	s.oldConfigYaml, _ = yaml.Marshal(cfg)
 	s.applyAccessConfig(nil, cfg)
 	if authManager != nil {
 		authManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
+		authManager.SetCredentialAttempts(cfg.MaxCredentialAttempts)
+		authManager.SetRetryBackoff(time.Duration(cfg.RetryBackoffBaseMs)*time.Millisecond, time.Duration(cfg.RetryBackoffMaxMs)*time.Millisecond)
+		authManager.SetTimeouts(time.Duration(cfg.AttemptTimeoutSeconds)*time.Second, time.Duration(cfg.StreamAttemptTimeoutSeconds)*time.Second, time.Duration(cfg.RequestDeadlineSeconds)*time.Second)
+		authManager.SetSessionAffinity(time.Duration(cfg.StickySessionTTLSeconds) * time.Second)
+		authManager.SetSelectionMode(cfg.AccountSelection)
+		authManager.SetRetryClassification(auth.RetryClassification{StatusCodes: cfg.RetryOn.StatusCodes, BodySubstrings: cfg.RetryOn.BodyContains})
+		authManager.SetCircuitBreaker(circuitBreakerConfig(cfg.CircuitBreaker))
+		authManager.SetConcurrencyLimits(concurrencyConfig(cfg.ModelConcurrency))
+		authManager.SetQuotas(accountQuotas(cfg.AccountQuotas))
 	}
 	managementasset.SetCurrentConfig(cfg)
 	auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
@@ -319,11 +401,15 @@ func (s *Server) setupRoutes() {
 
 	// OpenAI compatible API routes
 	v1 := s.engine.Group("/v1")
-	v1.Use(AuthMiddleware(s.accessManager))
+	v1.Use(AuthMiddleware(s.accessManager), middleware.RateLimitMiddleware(s.rateLimiter, s.rateLimitPolicy.Load), middleware.AuditMiddleware(s.audit.auditor.Load))
 	{
 		v1.GET("/models", s.unifiedModelsHandler(openaiHandlers, claudeCodeHandlers))
+		v1.GET("/models/:id/capabilities", openaiHandlers.ModelCapabilities)
 		v1.POST("/chat/completions", openaiHandlers.ChatCompletions)
+		v1.GET("/chat/completions/ws", openaiHandlers.ChatCompletionsWebSocket)
+		v1.POST("/chat/completions/batch", openaiHandlers.ChatCompletionsBatch)
 		v1.POST("/completions", openaiHandlers.Completions)
+		v1.POST("/embeddings", openaiHandlers.Embeddings)
 		v1.POST("/messages", claudeCodeHandlers.ClaudeMessages)
 		v1.POST("/messages/count_tokens", claudeCodeHandlers.ClaudeCountTokens)
 		v1.POST("/responses", openaiResponsesHandlers.Responses)
@@ -331,13 +417,23 @@ func (s *Server) setupRoutes() {
 
 	// Gemini compatible API routes
 	v1beta := s.engine.Group("/v1beta")
-	v1beta.Use(AuthMiddleware(s.accessManager))
+	v1beta.Use(AuthMiddleware(s.accessManager), middleware.RateLimitMiddleware(s.rateLimiter, s.rateLimitPolicy.Load), middleware.AuditMiddleware(s.audit.auditor.Load))
 	{
 		v1beta.GET("/models", geminiHandlers.GeminiModels)
 		v1beta.POST("/models/:action", geminiHandlers.GeminiHandler)
 		v1beta.GET("/models/:action", geminiHandlers.GeminiGetHandler)
 	}
 
+	s.engine.POST("/v0/admin/reload", s.mgmt.AdminMiddleware(), s.mgmt.PostReload)
+	s.engine.POST("/v0/admin/reload-models", s.mgmt.AdminMiddleware(), s.mgmt.PostReloadModels)
+	s.engine.GET("/v0/admin/maintenance", s.mgmt.AdminMiddleware(), s.handleGetMaintenance)
+	s.engine.GET("/v0/admin/accounts", s.mgmt.AdminMiddleware(), s.mgmt.GetAccounts)
+	s.engine.PUT("/v0/admin/maintenance", s.mgmt.AdminMiddleware(), s.handlePutMaintenance)
+
+	s.engine.GET("/metrics", s.handleMetrics)
+	s.engine.GET("/healthz", s.handleHealthz)
+	s.engine.GET("/readyz", s.handleReadyz)
+
 	// Root endpoint
 	s.engine.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -346,6 +442,7 @@ func (s *Server) setupRoutes() {
 				"POST /v1/chat/completions",
 				"POST /v1/completions",
 				"GET /v1/models",
+				"GET /v1/models/{id}/capabilities",
 			},
 		})
 	})
@@ -485,11 +582,17 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PUT("/usage-statistics-enabled", s.mgmt.PutUsageStatisticsEnabled)
 		mgmt.PATCH("/usage-statistics-enabled", s.mgmt.PutUsageStatisticsEnabled)
 
+		mgmt.GET("/metrics-enabled", s.mgmt.GetMetricsEnabled)
+		mgmt.PUT("/metrics-enabled", s.mgmt.PutMetricsEnabled)
+		mgmt.PATCH("/metrics-enabled", s.mgmt.PutMetricsEnabled)
+
 		mgmt.GET("/proxy-url", s.mgmt.GetProxyURL)
 		mgmt.PUT("/proxy-url", s.mgmt.PutProxyURL)
 		mgmt.PATCH("/proxy-url", s.mgmt.PutProxyURL)
 		mgmt.DELETE("/proxy-url", s.mgmt.DeleteProxyURL)
 
+		mgmt.GET("/account-quotas", s.mgmt.GetAccountQuotas)
+
 		mgmt.GET("/quota-exceeded/switch-project", s.mgmt.GetSwitchProject)
 		mgmt.PUT("/quota-exceeded/switch-project", s.mgmt.PutSwitchProject)
 		mgmt.PATCH("/quota-exceeded/switch-project", s.mgmt.PutSwitchProject)
@@ -520,6 +623,18 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/request-log", s.mgmt.GetRequestLog)
 		mgmt.PUT("/request-log", s.mgmt.PutRequestLog)
 		mgmt.PATCH("/request-log", s.mgmt.PutRequestLog)
+
+		mgmt.GET("/allow-routing-override", s.mgmt.GetAllowRoutingOverride)
+		mgmt.PUT("/allow-routing-override", s.mgmt.PutAllowRoutingOverride)
+		mgmt.PATCH("/allow-routing-override", s.mgmt.PutAllowRoutingOverride)
+
+		mgmt.GET("/dry-run", s.mgmt.GetDryRun)
+		mgmt.PUT("/dry-run", s.mgmt.PutDryRun)
+		mgmt.PATCH("/dry-run", s.mgmt.PutDryRun)
+
+		mgmt.GET("/coalesce-requests", s.mgmt.GetCoalesceRequests)
+		mgmt.PUT("/coalesce-requests", s.mgmt.PutCoalesceRequests)
+		mgmt.PATCH("/coalesce-requests", s.mgmt.PutCoalesceRequests)
 		mgmt.GET("/ws-auth", s.mgmt.GetWebsocketAuth)
 		mgmt.PUT("/ws-auth", s.mgmt.PutWebsocketAuth)
 		mgmt.PATCH("/ws-auth", s.mgmt.PutWebsocketAuth)
@@ -530,6 +645,15 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/max-retry-interval", s.mgmt.GetMaxRetryInterval)
 		mgmt.PUT("/max-retry-interval", s.mgmt.PutMaxRetryInterval)
 		mgmt.PATCH("/max-retry-interval", s.mgmt.PutMaxRetryInterval)
+		mgmt.GET("/max-credential-attempts", s.mgmt.GetMaxCredentialAttempts)
+		mgmt.PUT("/max-credential-attempts", s.mgmt.PutMaxCredentialAttempts)
+		mgmt.PATCH("/max-credential-attempts", s.mgmt.PutMaxCredentialAttempts)
+		mgmt.GET("/retry-backoff-base-ms", s.mgmt.GetRetryBackoffBaseMs)
+		mgmt.PUT("/retry-backoff-base-ms", s.mgmt.PutRetryBackoffBaseMs)
+		mgmt.PATCH("/retry-backoff-base-ms", s.mgmt.PutRetryBackoffBaseMs)
+		mgmt.GET("/retry-backoff-max-ms", s.mgmt.GetRetryBackoffMaxMs)
+		mgmt.PUT("/retry-backoff-max-ms", s.mgmt.PutRetryBackoffMaxMs)
+		mgmt.PATCH("/retry-backoff-max-ms", s.mgmt.PutRetryBackoffMaxMs)
 
 		mgmt.GET("/claude-api-key", s.mgmt.GetClaudeKeys)
 		mgmt.PUT("/claude-api-key", s.mgmt.PutClaudeKeys)
@@ -605,6 +729,180 @@ func (s *Server) serveManagementControlPanel(c *gin.Context) {
 	c.File(filePath)
 }
 
+// handleMetrics serves collected metrics in the Prometheus text format, or in the OpenMetrics
+// format, which carries request ID exemplars, when the scraper asks for it.
+// The endpoint reports 404 while metrics are disabled. Because the series carry credential
+// IDs, scrapers must present metrics-token as a bearer token; without a configured token
+// only loopback clients are served.
+func (s *Server) handleMetrics(c *gin.Context) {
+	if !metrics.Enabled() {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var token string
+	if s.cfg != nil {
+		token = strings.TrimSpace(s.cfg.MetricsToken)
+	}
+	if token == "" {
+		if !isLoopbackPeer(c.Request.RemoteAddr) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "remote metrics scraping requires metrics-token"})
+			return
+		}
+	} else {
+		provided := ""
+		if parts := strings.SplitN(strings.TrimSpace(c.GetHeader("Authorization")), " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], "bearer") {
+			provided = strings.TrimSpace(parts[1])
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid metrics token"})
+			return
+		}
+	}
+	openMetrics := metrics.AcceptsOpenMetrics(c.GetHeader("Accept"))
+	write := metrics.WriteText
+	c.Header("Content-Type", metrics.ContentType)
+	if openMetrics {
+		write = metrics.WriteOpenMetrics
+		c.Header("Content-Type", metrics.OpenMetricsContentType)
+	}
+	c.Status(http.StatusOK)
+	if err := write(c.Writer); err != nil {
+		log.Errorf("failed to write metrics: %v", err)
+		return
+	}
+	s.writeStateGauges(c)
+	if openMetrics {
+		if err := metrics.WriteEOF(c.Writer); err != nil {
+			log.Errorf("failed to write metrics: %v", err)
+		}
+	}
+}
+
+// isLoopbackPeer reports whether the TCP peer of a request is a loopback address. It
+// reads RemoteAddr rather than ClientIP, which honours client-controlled forwarding headers.
+func isLoopbackPeer(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// writeStateGauges writes the gauges computed from auth manager state at scrape time.
+func (s *Server) writeStateGauges(c *gin.Context) {
+	if s.handlers == nil || s.handlers.AuthManager == nil {
+		return
+	}
+	breakers := s.handlers.AuthManager.CircuitBreakers()
+	samples := make([]metrics.GaugeSample, 0, len(breakers))
+	for _, breaker := range breakers {
+		samples = append(samples, metrics.GaugeSample{Values: []string{breaker.Provider, breaker.AuthID, string(breaker.State)}, Value: 1})
+	}
+	if err := metrics.WriteGauge(c.Writer, "cliproxy_circuit_breaker_state", "Circuit breaker state of credentials that recently failed upstream.", []string{"provider", "auth_id", "state"}, samples); err != nil {
+		log.Errorf("failed to write metrics: %v", err)
+	}
+
+	quotas := s.handlers.AuthManager.Quotas()
+	samples = make([]metrics.GaugeSample, 0, 2*len(quotas))
+	for _, quota := range quotas {
+		if quota.RemainingTokens != nil {
+			samples = append(samples, metrics.GaugeSample{Values: []string{quota.Provider, quota.AuthID, string(quota.Window), "tokens"}, Value: float64(*quota.RemainingTokens)})
+		}
+		if quota.RemainingRequests != nil {
+			samples = append(samples, metrics.GaugeSample{Values: []string{quota.Provider, quota.AuthID, string(quota.Window), "requests"}, Value: float64(*quota.RemainingRequests)})
+		}
+	}
+	if err := metrics.WriteGauge(c.Writer, "cliproxy_account_quota_remaining", "Remaining quota of credentials in the current window.", []string{"provider", "auth_id", "window", "kind"}, samples); err != nil {
+		log.Errorf("failed to write metrics: %v", err)
+	}
+
+	loads := s.handlers.AuthManager.ModelConcurrency()
+	samples = make([]metrics.GaugeSample, 0, len(loads))
+	queued := make([]metrics.GaugeSample, 0, len(loads))
+	for _, load := range loads {
+		samples = append(samples, metrics.GaugeSample{Values: []string{load.Model}, Value: float64(load.InFlight)})
+		queued = append(queued, metrics.GaugeSample{Values: []string{load.Model}, Value: float64(load.Queued)})
+	}
+	if err := metrics.WriteGauge(c.Writer, "cliproxy_model_in_flight_requests", "Requests in flight for models with a concurrency limit.", []string{"model"}, samples); err != nil {
+		log.Errorf("failed to write metrics: %v", err)
+	}
+	if err := metrics.WriteGauge(c.Writer, "cliproxy_model_queued_requests", "Requests waiting for a concurrency slot, by model.", []string{"model"}, queued); err != nil {
+		log.Errorf("failed to write metrics: %v", err)
+	}
+}
+
+// concurrencyConfig converts the configured per-model concurrency limits for the auth manager.
+func concurrencyConfig(cfg config.ModelConcurrencyConfig) auth.ConcurrencyConfig {
+	return auth.ConcurrencyConfig{
+		Limits:           cfg.Limits,
+		QueueTimeout:     time.Duration(cfg.QueueTimeoutSeconds) * time.Second,
+		FlexQueueTimeout: time.Duration(cfg.FlexQueueTimeoutSeconds) * time.Second,
+	}
+}
+
+// circuitBreakerConfig converts the configured circuit breaker settings for the auth manager.
+func circuitBreakerConfig(cfg config.CircuitBreakerConfig) auth.CircuitBreakerConfig {
+	return auth.CircuitBreakerConfig{
+		FailureThreshold: cfg.FailureThreshold,
+		Window:           time.Duration(cfg.WindowSeconds) * time.Second,
+		Cooldown:         time.Duration(cfg.CooldownSeconds) * time.Second,
+	}
+}
+
+// accountQuotas converts the configured account quotas for the auth manager.
+func accountQuotas(quotas []config.AccountQuota) []auth.QuotaLimit {
+	limits := make([]auth.QuotaLimit, 0, len(quotas))
+	for _, quota := range quotas {
+		limits = append(limits, auth.QuotaLimit{
+			AuthID:      quota.AuthID,
+			Provider:    quota.Provider,
+			Window:      auth.QuotaWindow(quota.Window),
+			MaxTokens:   quota.MaxTokens,
+			MaxRequests: quota.MaxRequests,
+		})
+	}
+	return limits
+}
+
+// handleHealthz is the liveness probe; it succeeds whenever the process is serving.
+func (s *Server) handleHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// handleReadyz is the readiness probe. It returns 503 listing the unhealthy providers
+// unless every enabled provider has at least one usable credential, and 503 while the
+// server is in maintenance mode.
+func (s *Server) handleReadyz(c *gin.Context) {
+	if enabled, _ := s.maintenance.active(); enabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "reason": "maintenance"})
+		return
+	}
+	var providers []auth.ProviderHealth
+	if s.handlers != nil && s.handlers.AuthManager != nil {
+		readiness := s.cfg.Readiness
+		providers = s.handlers.AuthManager.Readiness(c.Request.Context(), auth.ReadinessOptions{
+			Ping:    readiness.UpstreamPing,
+			PingTTL: time.Duration(readiness.PingCacheSeconds) * time.Second,
+		})
+	}
+	if len(providers) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "reason": "no credentials configured", "providers": []auth.ProviderHealth{}})
+		return
+	}
+	unhealthy := make([]auth.ProviderHealth, 0)
+	for _, provider := range providers {
+		if !provider.Ready {
+			unhealthy = append(unhealthy, provider)
+		}
+	}
+	if len(unhealthy) > 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "unhealthy": unhealthy, "providers": providers})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "providers": providers})
+}
+
 func (s *Server) enableKeepAlive(timeout time.Duration, onTimeout func()) {
 	if timeout <= 0 || onTimeout == nil {
 		return
@@ -683,16 +981,46 @@ func (s *Server) watchKeepAlive() {
 	}
 }
 
+// compressionThreshold returns the response size from which compression applies, or zero
+// when compression is disabled.
+func compressionThreshold(cfg config.CompressionConfig) int {
+	if !cfg.Enabled {
+		return 0
+	}
+	if cfg.MinBytes <= 0 {
+		return 1024
+	}
+	return cfg.MinBytes
+}
+
+// requestBodyLimits returns the inbound body limits for cfg, or zero limits when the limit
+// is disabled.
+func requestBodyLimits(cfg config.RequestBodyLimitConfig) *middleware.BodyLimits {
+	if !cfg.Enabled {
+		return &middleware.BodyLimits{}
+	}
+	limits := &middleware.BodyLimits{Text: cfg.MaxBytes, Multimodal: cfg.MultimodalMaxBytes}
+	if limits.Text <= 0 {
+		limits.Text = 10 << 20
+	}
+	if limits.Multimodal <= 0 {
+		limits.Multimodal = 64 << 20
+	}
+	limits.Multimodal = max(limits.Multimodal, limits.Text)
+	return limits
+}
+
 // unifiedModelsHandler creates a unified handler for the /v1/models endpoint
-// that routes to different handlers based on the User-Agent header.
-// If User-Agent starts with "claude-cli", it routes to Claude handler,
-// otherwise it routes to OpenAI handler.
+// that routes to different handlers based on the inbound client format.
+// If User-Agent starts with "claude-cli" or the request carries the
+// anthropic-version header sent by every Anthropic SDK, it routes to Claude
+// handler, otherwise it routes to OpenAI handler.
 func (s *Server) unifiedModelsHandler(openaiHandler *openai.OpenAIAPIHandler, claudeHandler *claude.ClaudeCodeAPIHandler) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userAgent := c.GetHeader("User-Agent")
 
-		// Route to Claude handler if User-Agent starts with "claude-cli"
-		if strings.HasPrefix(userAgent, "claude-cli") {
+		// Route to Claude handler for Claude Code and Anthropic SDK clients
+		if strings.HasPrefix(userAgent, "claude-cli") || c.GetHeader("Anthropic-Version") != "" {
 			// log.Debugf("Routing /v1/models to Claude handler for User-Agent: %s", userAgent)
 			claudeHandler.ClaudeModels(c)
 		} else {
@@ -734,8 +1062,10 @@ func (s *Server) Start() error {
 	return nil
 }
 
-// Stop gracefully shuts down the API server without interrupting any
-// active connections.
+// Stop gracefully shuts down the API server. It stops accepting new connections and waits
+// up to the configured drain timeout (or until ctx ends) for in-flight requests, streams
+// included, to complete; requests still running afterwards are cancelled, which also cancels
+// their upstream calls, and their connections are closed.
 //
 // Parameters:
 //   - ctx: The context for graceful shutdown
@@ -752,11 +1082,25 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
-	// Shutdown the HTTP server.
-	if err := s.server.Shutdown(ctx); err != nil {
-		return fmt.Errorf("failed to shutdown HTTP server: %v", err)
+	drainCtx, cancel := context.WithTimeout(ctx, ShutdownDrainTimeout(s.cfg))
+	defer cancel()
+	pending := s.inFlight.count()
+
+	// Shutdown the HTTP server; hijacked websocket connections are only tracked by inFlight.
+	errShutdown := s.server.Shutdown(drainCtx)
+	drained := s.inFlight.wait(drainCtx)
+	forced := 0
+	if errShutdown != nil || !drained {
+		forced = s.inFlight.cancelAll()
+		if errClose := s.server.Close(); errClose != nil {
+			return fmt.Errorf("failed to close HTTP server: %v", errClose)
+		}
+	}
+	if pending > 0 || forced > 0 {
+		log.Infof("API server shutdown: %d in-flight request(s) drained, %d forcibly terminated", max(pending-forced, 0), forced)
 	}
 
+	s.closeAudit()
 	log.Debug("API server stopped")
 	return nil
 }
@@ -838,6 +1182,39 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		}
 	}
 
+	if oldCfg == nil || oldCfg.MetricsEnabled != cfg.MetricsEnabled {
+		metrics.SetEnabled(cfg.MetricsEnabled)
+		if oldCfg != nil {
+			log.Debugf("metrics_enabled updated from %t to %t", oldCfg.MetricsEnabled, cfg.MetricsEnabled)
+		} else {
+			log.Debugf("metrics_enabled toggled to %t", cfg.MetricsEnabled)
+		}
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.DefaultThinkingBudgets, cfg.DefaultThinkingBudgets) {
+		util.SetDefaultThinkingBudgets(cfg.DefaultThinkingBudgets)
+		log.Debugf("default_thinking_budgets updated (%d entries)", len(cfg.DefaultThinkingBudgets))
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.ModelParamDefaults, cfg.ModelParamDefaults) {
+		util.SetModelParamDefaults(cfg.ModelParamDefaults)
+		log.Debugf("model_param_defaults updated (%d entries)", len(cfg.ModelParamDefaults))
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.SamplingProfiles, cfg.SamplingProfiles) {
+		util.SetSamplingProfiles(cfg.SamplingProfiles)
+		log.Debugf("sampling_profiles updated (%d entries)", len(cfg.SamplingProfiles))
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.PromptInjections, cfg.PromptInjections) {
+		util.SetPromptInjections(cfg.PromptInjections)
+		log.Debugf("prompt_injections updated (%d entries)", len(cfg.PromptInjections))
+	}
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.SystemPromptPolicies, cfg.SystemPromptPolicies) {
+		util.SetSystemPromptPolicies(cfg.SystemPromptPolicies)
+		log.Debugf("system_prompt_policies updated (%d entries)", len(cfg.SystemPromptPolicies))
+	}
+
 	if oldCfg == nil || oldCfg.DisableCooling != cfg.DisableCooling {
 		auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
 		if oldCfg != nil {
@@ -848,6 +1225,15 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 	}
 	if s.handlers != nil && s.handlers.AuthManager != nil {
 		s.handlers.AuthManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
+		s.handlers.AuthManager.SetCredentialAttempts(cfg.MaxCredentialAttempts)
+		s.handlers.AuthManager.SetRetryBackoff(time.Duration(cfg.RetryBackoffBaseMs)*time.Millisecond, time.Duration(cfg.RetryBackoffMaxMs)*time.Millisecond)
+		s.handlers.AuthManager.SetTimeouts(time.Duration(cfg.AttemptTimeoutSeconds)*time.Second, time.Duration(cfg.StreamAttemptTimeoutSeconds)*time.Second, time.Duration(cfg.RequestDeadlineSeconds)*time.Second)
+		s.handlers.AuthManager.SetSessionAffinity(time.Duration(cfg.StickySessionTTLSeconds) * time.Second)
+		s.handlers.AuthManager.SetSelectionMode(cfg.AccountSelection)
+		s.handlers.AuthManager.SetRetryClassification(auth.RetryClassification{StatusCodes: cfg.RetryOn.StatusCodes, BodySubstrings: cfg.RetryOn.BodyContains})
+		s.handlers.AuthManager.SetCircuitBreaker(circuitBreakerConfig(cfg.CircuitBreaker))
+		s.handlers.AuthManager.SetConcurrencyLimits(concurrencyConfig(cfg.ModelConcurrency))
+		s.handlers.AuthManager.SetQuotas(accountQuotas(cfg.AccountQuotas))
 	}
 
 	// Update log level dynamically when debug flag changes
@@ -893,6 +1279,10 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 	}
 
 	s.applyAccessConfig(oldCfg, cfg)
+	s.rateLimitPolicy.Store(ratelimit.NewPolicy(cfg.RateLimit))
+	s.configureAudit(cfg.AuditLog)
+	s.compressionMinBytes.Store(int64(compressionThreshold(cfg.Compression)))
+	s.bodyLimits.Store(requestBodyLimits(cfg.RequestBodyLimit))
 	s.cfg = cfg
 	s.wsAuthEnabled.Store(cfg.WebsocketAuth)
 	if oldCfg != nil && s.wsAuthChanged != nil && oldCfg.WebsocketAuth != cfg.WebsocketAuth {
@@ -953,6 +1343,22 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 	)
 }
 
+// SetCredentialReloader wires the function behind POST /v0/admin/reload.
+func (s *Server) SetCredentialReloader(reload func() (watcher.ReloadSummary, error)) {
+	if s == nil || s.mgmt == nil {
+		return
+	}
+	s.mgmt.SetCredentialReloader(reload)
+}
+
+// SetModelReloader wires the function behind POST /v0/admin/reload-models.
+func (s *Server) SetModelReloader(reload func() (registry.ReloadSummary, error)) {
+	if s == nil || s.mgmt == nil {
+		return
+	}
+	s.mgmt.SetModelReloader(reload)
+}
+
 func (s *Server) SetWebsocketAuthChangeHandler(fn func(bool, bool)) {
 	if s == nil {
 		return
@@ -1,18 +1,31 @@
 package api
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	gin "github.com/gin-gonic/gin"
 	proxyconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
 )
 
 func newTestServer(t *testing.T) *Server {
@@ -109,3 +122,563 @@ func TestAmpProviderModelRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsEndpoint(t *testing.T) {
+	server := newTestServer(t)
+
+	scrape := func() (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = "127.0.0.1:9090"
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		return rr.Code, rr.Body.String()
+	}
+
+	if code, _ := scrape(); code != http.StatusNotFound {
+		t.Fatalf("Expected /metrics to be disabled by default, got status %d", code)
+	}
+
+	metrics.SetEnabled(true)
+	t.Cleanup(func() { metrics.SetEnabled(false) })
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"metrics-test-model","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Authorization", "Bearer test-key")
+	rr := httptest.NewRecorder()
+	server.engine.ServeHTTP(rr, req)
+
+	code, body := scrape()
+	if code != http.StatusOK {
+		t.Fatalf("Expected metrics scrape to succeed, got status %d", code)
+	}
+	series := fmt.Sprintf(`cliproxy_requests_total{model="metrics-test-model",provider="unknown",status="%d"} 1`, rr.Code)
+	if !strings.Contains(body, series) {
+		t.Fatalf("Expected %s in scrape output:\n%s", series, body)
+	}
+	if strings.Contains(body, "test-key") {
+		t.Fatalf("Expected API keys to be absent from metrics:\n%s", body)
+	}
+}
+
+func TestMetricsEndpoint_RequiresTokenForRemoteScrapes(t *testing.T) {
+	server := newTestServer(t)
+	metrics.SetEnabled(true)
+	t.Cleanup(func() { metrics.SetEnabled(false) })
+
+	scrape := func(authorization string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = "203.0.113.7:40000"
+		req.Header.Set("X-Forwarded-For", "127.0.0.1")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := scrape(""); code != http.StatusForbidden {
+		t.Fatalf("Expected a spoofed loopback X-Forwarded-For not to exempt a remote scrape, got %d", code)
+	}
+	server.cfg.MetricsToken = "scrape-secret"
+	if code := scrape(""); code != http.StatusUnauthorized {
+		t.Fatalf("Expected a spoofed loopback scrape without the token to be rejected, got %d", code)
+	}
+	if code := scrape("Bearer wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected a wrong token to be rejected, got %d", code)
+	}
+	if code := scrape("Bearer scrape-secret"); code != http.StatusOK {
+		t.Fatalf("Expected the metrics token to be accepted, got %d", code)
+	}
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	server := newTestServer(t)
+
+	probe := func(path string) (int, string) {
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr.Code, rr.Body.String()
+	}
+
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Fatalf("Expected /healthz to return 200, got %d", code)
+	}
+	if code, body := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected /readyz to return 503 without credentials, got %d: %s", code, body)
+	}
+
+	manager := server.handlers.AuthManager
+	if _, err := manager.Register(context.Background(), &auth.Auth{ID: "ready-a", Provider: "gemini"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if code, body := probe("/readyz"); code != http.StatusOK || !strings.Contains(body, `"provider":"gemini"`) {
+		t.Fatalf("Expected /readyz to return 200 with a usable credential, got %d: %s", code, body)
+	}
+
+	if _, err := manager.Register(context.Background(), &auth.Auth{ID: "ready-b", Provider: "claude", Unavailable: true, NextRetryAfter: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	code, body := probe("/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected /readyz to return 503 with a cooling provider, got %d: %s", code, body)
+	}
+	var payload struct {
+		Unhealthy []auth.ProviderHealth `json:"unhealthy"`
+	}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("decode readiness body: %v", err)
+	}
+	if len(payload.Unhealthy) != 1 || payload.Unhealthy[0].Provider != "claude" {
+		t.Fatalf("Expected only claude to be unhealthy, got %+v", payload.Unhealthy)
+	}
+}
+
+func TestAdminReloadEndpoint(t *testing.T) {
+	server := newTestServer(t)
+	calls := 0
+	server.SetCredentialReloader(func() (watcher.ReloadSummary, error) {
+		calls++
+		return watcher.ReloadSummary{Added: 1, Removed: 2, Unchanged: 3}, nil
+	})
+
+	reload := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v0/admin/reload", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := reload("anything"); rr.Code != http.StatusNotFound {
+		t.Fatalf("Expected admin endpoint to be hidden without an admin token, got %d", rr.Code)
+	}
+
+	server.cfg.RemoteManagement.AdminToken = "admin-secret"
+	server.cfg.RemoteManagement.SecretKey = "management-secret"
+	if rr := reload(""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without a token, got %d", rr.Code)
+	}
+	if rr := reload("management-secret"); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the management key to be rejected, got %d", rr.Code)
+	}
+	if calls != 0 {
+		t.Fatalf("Expected no reload for unauthorized requests, got %d", calls)
+	}
+
+	rr := reload("admin-secret")
+	if rr.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("Expected reload to run, got %d after %d calls", rr.Code, calls)
+	}
+	var summary watcher.ReloadSummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &summary); err != nil || summary != (watcher.ReloadSummary{Added: 1, Removed: 2, Unchanged: 3}) {
+		t.Fatalf("Unexpected reload response %s", rr.Body.String())
+	}
+}
+
+// serveSlowStream registers a stream at path writing chunks chunks interval apart, starts s
+// on a loopback listener and returns the stream URL and a channel reporting whether the
+// handler saw its request cancelled.
+func serveSlowStream(t *testing.T, s *Server, path string, chunks int, interval time.Duration) (string, <-chan bool) {
+	t.Helper()
+	cancelled := make(chan bool, 1)
+	s.engine.GET(path, func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < chunks; i++ {
+			_, _ = fmt.Fprintf(c.Writer, "data: %d\n\n", i)
+			c.Writer.Flush()
+			select {
+			case <-time.After(interval):
+			case <-c.Request.Context().Done():
+				cancelled <- true
+				return
+			}
+		}
+		cancelled <- false
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = s.server.Serve(ln) }()
+	return "http://" + ln.Addr().String() + path, cancelled
+}
+
+func TestServerStop_DrainsInFlightStream(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.ShutdownDrainTimeoutSeconds = 5
+	url, cancelled := serveSlowStream(t, s, "/test/slow-stream", 5, 100*time.Millisecond)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	if _, err = reader.ReadString('\n'); err != nil {
+		t.Fatalf("read first chunk: %v", err)
+	}
+
+	start := time.Now()
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(context.Background()) }()
+
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Expected the stream to complete during shutdown, got %v", err)
+	}
+	if !strings.Contains(string(rest), "data: 4") {
+		t.Fatalf("Expected every chunk to be delivered, got %q", rest)
+	}
+	if err = <-stopped; err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 5*time.Second {
+		t.Fatalf("Expected shutdown to finish within the drain window, took %v", elapsed)
+	}
+	if <-cancelled {
+		t.Fatal("Expected the drained stream not to be cancelled")
+	}
+	if _, err = http.Get(url); err == nil {
+		t.Fatal("Expected new connections to be refused after shutdown")
+	}
+}
+
+func TestServerStop_ForcesStreamsPastDrainTimeout(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.ShutdownDrainTimeoutSeconds = 1
+	url, cancelled := serveSlowStream(t, s, "/test/slow-stream", 100, 100*time.Millisecond)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if _, err = bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Fatalf("read first chunk: %v", err)
+	}
+
+	start := time.Now()
+	if err = s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 3*time.Second {
+		t.Fatalf("Expected shutdown to force-close after the 1s drain timeout, took %v", elapsed)
+	}
+	select {
+	case wasCancelled := <-cancelled:
+		if !wasCancelled {
+			t.Fatal("Expected the stream to be cancelled")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the forced shutdown to cancel the stream's request context")
+	}
+}
+
+func TestAdminReloadModelsEndpoint(t *testing.T) {
+	server := newTestServer(t)
+	server.cfg.RemoteManagement.AdminToken = "admin-secret"
+	server.SetModelReloader(func() (registry.ReloadSummary, error) {
+		return registry.ReloadSummary{Clients: 2, Models: 5, Added: []string{"new-model"}, Version: 7}, nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v0/admin/reload-models", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rr := httptest.NewRecorder()
+	server.engine.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected model reload to run, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var summary registry.ReloadSummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &summary); err != nil || summary.Version != 7 || len(summary.Added) != 1 {
+		t.Fatalf("Unexpected model reload response %s", rr.Body.String())
+	}
+}
+
+func TestAdminMaintenanceMode(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.RemoteManagement.AdminToken = "admin-secret"
+	if _, err := s.handlers.AuthManager.Register(context.Background(), &auth.Auth{ID: "maintenance-a", Provider: "gemini"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	url, cancelled := serveSlowStream(t, s, "/v1/test/slow-stream", 5, 100*time.Millisecond)
+	base := strings.TrimSuffix(url, "/v1/test/slow-stream")
+
+	setMaintenance := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/v0/admin/maintenance", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rr := httptest.NewRecorder()
+		s.engine.ServeHTTP(rr, req)
+		return rr
+	}
+	probe := func(path string) int {
+		rr := httptest.NewRecorder()
+		s.engine.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr.Code
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	if _, err = reader.ReadString('\n'); err != nil {
+		t.Fatalf("read first chunk: %v", err)
+	}
+
+	rr := setMaintenance(`{"enabled":true,"retry_after_seconds":120}`)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"enabled":true`) {
+		t.Fatalf("Expected maintenance to be enabled, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rejected, err := http.Get(base + "/v1/models")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	_ = rejected.Body.Close()
+	if rejected.StatusCode != http.StatusServiceUnavailable || rejected.Header.Get("Retry-After") != "120" {
+		t.Fatalf("Expected 503 with Retry-After 120 for a new request, got %d (%q)", rejected.StatusCode, rejected.Header.Get("Retry-After"))
+	}
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected /readyz to report not ready during maintenance, got %d", code)
+	}
+	if code := probe("/healthz"); code != http.StatusOK {
+		t.Fatalf("Expected /healthz to keep working during maintenance, got %d", code)
+	}
+
+	rest, err := io.ReadAll(reader)
+	if err != nil || !strings.Contains(string(rest), "data: 4") {
+		t.Fatalf("Expected the in-flight stream to complete, got %q (%v)", rest, err)
+	}
+	if <-cancelled {
+		t.Fatal("Expected the in-flight stream not to be cancelled")
+	}
+
+	if rr = setMaintenance(`{"enabled":false}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected maintenance to be disabled, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if code := probe("/readyz"); code != http.StatusOK {
+		t.Fatalf("Expected /readyz to recover after maintenance, got %d", code)
+	}
+	if rr = setMaintenance(`{}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without enabled, got %d", rr.Code)
+	}
+}
+
+func TestAdminAccountsEndpoint(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.RemoteManagement.AdminToken = "admin-secret"
+	manager := s.handlers.AuthManager
+	ctx := context.Background()
+	cooldown := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if _, err := manager.Register(ctx, &auth.Auth{ID: "claude-key", Provider: "claude", Status: auth.StatusActive, Attributes: map[string]string{"api_key": "sk-ant-secret-value-123456"}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if _, err := manager.Register(ctx, &auth.Auth{
+		ID:             "gemini-oauth",
+		Provider:       "gemini",
+		Status:         auth.StatusError,
+		Unavailable:    true,
+		NextRetryAfter: cooldown,
+		Metadata:       map[string]any{"email": "ops@example.com", "access_token": "ya29.token-value"},
+		ModelStates:    map[string]*auth.ModelState{"gemini-2.5-pro": {Unavailable: true, NextRetryAfter: cooldown}},
+	}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	manager.SetCircuitBreaker(auth.CircuitBreakerConfig{FailureThreshold: 5})
+	manager.MarkResult(ctx, auth.Result{AuthID: "gemini-oauth", Provider: "gemini", Error: &auth.Error{HTTPStatus: http.StatusBadGateway, Message: "bad gateway"}})
+	manager.SetQuotas([]auth.QuotaLimit{{Provider: "claude", Window: auth.QuotaDaily, MaxRequests: 100}})
+
+	req := httptest.NewRequest(http.MethodGet, "/v0/admin/accounts", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rr := httptest.NewRecorder()
+	s.engine.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	body := rr.Body.String()
+	if strings.Contains(body, "sk-ant-secret-value-123456") || strings.Contains(body, "ya29.token-value") {
+		t.Fatalf("Expected secrets to be redacted, got %s", body)
+	}
+
+	accounts := gjson.Get(body, "accounts").Array()
+	if len(accounts) != 2 {
+		t.Fatalf("Expected two accounts, got %s", body)
+	}
+	healthy, cooling := accounts[0], accounts[1]
+	if healthy.Get("id").String() != "claude-key" || healthy.Get("provider").String() != "claude" || healthy.Get("disabled").Bool() {
+		t.Fatalf("Unexpected healthy account %s", healthy.Raw)
+	}
+	if healthy.Get("cooldown_until").Exists() || healthy.Get("circuit_state").String() != "closed" || healthy.Get("consecutive_failures").Int() != 0 {
+		t.Fatalf("Expected the healthy account to be closed without cooldown, got %s", healthy.Raw)
+	}
+	if quota := healthy.Get("quotas.0"); quota.Get("window").String() != "daily" || quota.Get("remaining_requests").Int() != 100 {
+		t.Fatalf("Expected the remaining quota of the healthy account, got %s", healthy.Raw)
+	}
+	if account := healthy.Get("account").String(); account == "" || strings.Contains(account, "secret-value") {
+		t.Fatalf("Expected a masked API key, got %q", account)
+	}
+
+	if cooling.Get("id").String() != "gemini-oauth" || cooling.Get("account").String() != "ops@example.com" || cooling.Get("status").String() != "error" {
+		t.Fatalf("Unexpected cooling account %s", cooling.Raw)
+	}
+	if got := cooling.Get("cooldown_until").Time(); !got.After(time.Now()) {
+		t.Fatalf("Expected cooldown_until in the future, got %s", cooling.Raw)
+	}
+	if got := cooling.Get(`model_cooldowns.gemini-2\.5-pro`).Time(); !got.Equal(cooldown) {
+		t.Fatalf("Expected the model cooldown to be listed, got %s", cooling.Raw)
+	}
+	if cooling.Get("consecutive_failures").Int() != 1 || cooling.Get("circuit_state").String() != "closed" || len(cooling.Get("quotas").Array()) != 0 {
+		t.Fatalf("Expected one recorded failure and no quotas, got %s", cooling.Raw)
+	}
+}
+
+func TestInboundFormatsShareGeminiBackend(t *testing.T) {
+	var upstreamPaths []string
+	var upstreamBodies []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		upstreamPaths = append(upstreamPaths, r.URL.Path)
+		upstreamBodies = append(upstreamBodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hello from gemini"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":4,"totalTokenCount":7},"modelVersion":"gemini-2.5-flash"}`))
+	}))
+	defer upstream.Close()
+
+	s := newTestServer(t)
+	s.handlers.AuthManager.RegisterExecutor(executor.NewGeminiExecutor(s.cfg))
+	if _, err := s.handlers.AuthManager.Register(context.Background(), &auth.Auth{
+		ID:         "inbound-formats",
+		Provider:   "gemini",
+		Attributes: map[string]string{"api_key": "upstream-key", "base_url": upstream.URL},
+	}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient("inbound-formats", "gemini", []*registry.ModelInfo{{
+		ID:      "gemini-2.5-flash",
+		Object:  "model",
+		OwnedBy: "google",
+		Type:    "gemini",
+		Name:    "models/gemini-2.5-flash",
+	}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient("inbound-formats") })
+
+	testCases := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		body    string
+		check   func(t *testing.T, body string)
+	}{
+		{
+			name:    "openai chat completions",
+			method:  http.MethodPost,
+			path:    "/v1/chat/completions",
+			headers: map[string]string{"Authorization": "Bearer test-key"},
+			body:    `{"model":"gemini-2.5-flash","messages":[{"role":"user","content":"hi"}]}`,
+			check: func(t *testing.T, body string) {
+				if got := gjson.Get(body, "choices.0.message.content").String(); got != "hello from gemini" {
+					t.Fatalf("Expected an OpenAI chat completion, got %s", body)
+				}
+			},
+		},
+		{
+			name:    "anthropic messages",
+			method:  http.MethodPost,
+			path:    "/v1/messages",
+			headers: map[string]string{"X-Api-Key": "test-key", "Anthropic-Version": "2023-06-01"},
+			body:    `{"model":"gemini-2.5-flash","max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`,
+			check: func(t *testing.T, body string) {
+				if gjson.Get(body, "type").String() != "message" || gjson.Get(body, "content.0.text").String() != "hello from gemini" {
+					t.Fatalf("Expected an Anthropic message, got %s", body)
+				}
+			},
+		},
+		{
+			name:    "gemini generate content",
+			method:  http.MethodPost,
+			path:    "/v1beta/models/gemini-2.5-flash:generateContent",
+			headers: map[string]string{"X-Goog-Api-Key": "test-key"},
+			body:    `{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`,
+			check: func(t *testing.T, body string) {
+				if got := gjson.Get(body, "candidates.0.content.parts.0.text").String(); got != "hello from gemini" {
+					t.Fatalf("Expected a Gemini response, got %s", body)
+				}
+			},
+		},
+		{
+			name:    "openai models",
+			method:  http.MethodGet,
+			path:    "/v1/models",
+			headers: map[string]string{"Authorization": "Bearer test-key"},
+			check: func(t *testing.T, body string) {
+				if gjson.Get(body, "object").String() != "list" || !strings.Contains(body, `"gemini-2.5-flash"`) {
+					t.Fatalf("Expected an OpenAI model list, got %s", body)
+				}
+			},
+		},
+		{
+			name:    "anthropic models",
+			method:  http.MethodGet,
+			path:    "/v1/models",
+			headers: map[string]string{"X-Api-Key": "test-key", "Anthropic-Version": "2023-06-01"},
+			check: func(t *testing.T, body string) {
+				if gjson.Get(body, "object").Exists() || !strings.Contains(body, `"gemini-2.5-flash"`) {
+					t.Fatalf("Expected an Anthropic model list, got %s", body)
+				}
+			},
+		},
+		{
+			name:    "gemini models",
+			method:  http.MethodGet,
+			path:    "/v1beta/models",
+			headers: map[string]string{"X-Goog-Api-Key": "test-key"},
+			check: func(t *testing.T, body string) {
+				if !strings.Contains(body, `"models/gemini-2.5-flash"`) {
+					t.Fatalf("Expected a Gemini model list, got %s", body)
+				}
+			},
+		},
+		{
+			name:    "capabilities with gemini credentials",
+			method:  http.MethodGet,
+			path:    "/v1/models/gemini-2.5-flash/capabilities",
+			headers: map[string]string{"X-Goog-Api-Key": "test-key"},
+			check: func(t *testing.T, body string) {
+				if !gjson.Get(body, "logit_bias").Exists() {
+					t.Fatalf("Expected model capabilities, got %s", body)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			upstreamPaths, upstreamBodies = nil, nil
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			s.engine.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("unexpected status code for %s: got %d; body=%s", tc.path, rr.Code, rr.Body.String())
+			}
+			tc.check(t, rr.Body.String())
+			if tc.method != http.MethodPost {
+				return
+			}
+			if len(upstreamPaths) != 1 || upstreamPaths[0] != "/v1beta/models/gemini-2.5-flash:generateContent" {
+				t.Fatalf("Expected one Gemini generateContent call, got %v", upstreamPaths)
+			}
+			if got := gjson.Get(upstreamBodies[0], "contents.0.parts.0.text").String(); got != "hi" {
+				t.Fatalf("Expected the prompt translated into Gemini contents, got %s", upstreamBodies[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package audit records full prompts and responses to an append-only audit trail, kept
+// separate from the operational logs. Records are written to a pluggable AuditSink; FileSink
+// appends JSON lines to a local file, and other backends (object storage, message queues)
+// only need to implement Write.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AuditRecord describes one audited request.
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Client     string    `json:"client,omitempty"`
+	Model      string    `json:"model,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	AuthID     string    `json:"auth_id,omitempty"`
+	Stream     bool      `json:"stream,omitempty"`
+	Status     int       `json:"status"`
+	DurationMs int64     `json:"duration_ms"`
+	// Request is the inbound request body as sent by the client.
+	Request string `json:"request"`
+	// Response is the body returned to the client; for streams, every chunk concatenated.
+	Response string `json:"response"`
+	// RequestID links the records of a request and its mirror.
+	RequestID string `json:"request_id,omitempty"`
+	// MirrorOf is set on the record of a mirrored copy to the model the client requested.
+	MirrorOf string `json:"mirror_of,omitempty"`
+}
+
+// AuditSink persists audit records. Write may be called concurrently.
+type AuditSink interface {
+	Write(record AuditRecord) error
+}
+
+// Redactor scrubs sensitive data, such as PII, from a record before it is written.
+type Redactor func(record *AuditRecord)
+
+// Auditor writes records to a sink after applying an optional redactor.
+type Auditor struct {
+	Sink   AuditSink
+	Redact Redactor
+}
+
+// Record redacts and writes record. Audit logging fails open: a sink error is logged and
+// never surfaces to the client request.
+func (a *Auditor) Record(record AuditRecord) {
+	if a == nil || a.Sink == nil {
+		return
+	}
+	if a.Redact != nil {
+		a.Redact(&record)
+	}
+	if err := a.Sink.Write(record); err != nil {
+		log.Warnf("audit: failed to write record for %s %s: %v", record.Method, record.Path, err)
+	}
+}
+
+// PatternRedactor returns a Redactor replacing every match of the given regular expressions
+// in the request and response bodies with "[REDACTED]".
+func PatternRedactor(patterns []string) (Redactor, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("audit: invalid redact pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	if len(compiled) == 0 {
+		return nil, nil
+	}
+	return func(record *AuditRecord) {
+		for _, re := range compiled {
+			record.Request = re.ReplaceAllString(record.Request, "[REDACTED]")
+			record.Response = re.ReplaceAllString(record.Response, "[REDACTED]")
+		}
+	}, nil
+}
+
+// FileSink appends records as JSON lines to a file opened in append-only mode.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it and its directory when missing.
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("audit: create directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open %s: %w", path, err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Write implements AuditSink.
+func (s *FileSink) Write(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	_, err = s.file.Write(line)
+	return err
+}
+
+// Close closes the underlying file; later writes fail with os.ErrClosed.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSink_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	for _, model := range []string{"m1", "m2"} {
+		if err := sink.Write(AuditRecord{Method: "POST", Path: "/v1/messages", Model: model}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := sink.Write(AuditRecord{}); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Expected os.ErrClosed after Close, got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %q", len(lines), data)
+	}
+	var record AuditRecord
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil || record.Model != "m2" {
+		t.Fatalf("Expected the second record for m2, got %+v (%v)", record, err)
+	}
+}
+
+func TestPatternRedactor(t *testing.T) {
+	if redact, err := PatternRedactor(nil); err != nil || redact != nil {
+		t.Fatalf("Expected no redactor without patterns, got %v", err)
+	}
+	if _, err := PatternRedactor([]string{"("}); err == nil {
+		t.Fatal("Expected an error for an invalid pattern")
+	}
+	redact, err := PatternRedactor([]string{`\d{3}-\d{4}`})
+	if err != nil {
+		t.Fatalf("PatternRedactor: %v", err)
+	}
+	record := AuditRecord{Request: "call 555-1234", Response: "ok 555-9876"}
+	redact(&record)
+	if record.Request != "call [REDACTED]" || record.Response != "ok [REDACTED]" {
+		t.Fatalf("Unexpected redaction result: %+v", record)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 	"syscall"
@@ -26,6 +27,10 @@ type Config struct {
 	// TLS config controls HTTPS server settings.
 	TLS TLSConfig `yaml:"tls" json:"tls"`
 
+	// ShutdownDrainTimeoutSeconds is how long shutdown waits for in-flight requests, including
+	// streams, before terminating them (0 = default of 30).
+	ShutdownDrainTimeoutSeconds int `yaml:"shutdown-drain-timeout-seconds" json:"shutdown-drain-timeout-seconds"`
+
 	// RemoteManagement nests management-related options under 'remote-management'.
 	RemoteManagement RemoteManagement `yaml:"remote-management" json:"-"`
 
@@ -41,17 +46,104 @@ type Config struct {
 	// UsageStatisticsEnabled toggles in-memory usage aggregation; when false, usage data is discarded.
 	UsageStatisticsEnabled bool `yaml:"usage-statistics-enabled" json:"usage-statistics-enabled"`
 
+	// MetricsEnabled exposes Prometheus metrics on /metrics; when false, no metrics are collected.
+	MetricsEnabled bool `yaml:"metrics-enabled" json:"metrics-enabled"`
+
+	// MetricsToken is the bearer token required to scrape /metrics. When empty, only
+	// loopback clients may scrape, since the metrics name every credential.
+	MetricsToken string `yaml:"metrics-token" json:"-"`
+
 	// DisableCooling disables quota cooldown scheduling when true.
 	DisableCooling bool `yaml:"disable-cooling" json:"disable-cooling"`
 
-	// RequestRetry defines the retry times when the request failed.
+	// RequestRetry defines the retry times when the request failed. Zero makes exactly one
+	// attempt; credential failover within that attempt is governed by MaxCredentialAttempts.
 	RequestRetry int `yaml:"request-retry" json:"request-retry"`
 	// MaxRetryInterval defines the maximum wait time in seconds before retrying a cooled-down credential.
 	MaxRetryInterval int `yaml:"max-retry-interval" json:"max-retry-interval"`
+	// MaxCredentialAttempts caps how many credentials of one provider are tried per request (0 = all).
+	MaxCredentialAttempts int `yaml:"max-credential-attempts" json:"max-credential-attempts"`
+	// RetryBackoffBaseMs is the base delay in milliseconds for exponential retry backoff (0 = default).
+	RetryBackoffBaseMs int `yaml:"retry-backoff-base-ms" json:"retry-backoff-base-ms"`
+	// RetryBackoffMaxMs caps the exponential retry backoff delay in milliseconds (0 = default).
+	RetryBackoffMaxMs int `yaml:"retry-backoff-max-ms" json:"retry-backoff-max-ms"`
+	// RetryOn selects which upstream failures are retried.
+	RetryOn RetryOnConfig `yaml:"retry-on,omitempty" json:"retry-on,omitempty"`
+	// AttemptTimeoutSeconds bounds each non-streaming upstream call in seconds (0 = no timeout).
+	AttemptTimeoutSeconds int `yaml:"attempt-timeout-seconds" json:"attempt-timeout-seconds"`
+	// StreamAttemptTimeoutSeconds bounds each streaming upstream call, including the whole
+	// stream, in seconds (0 = no timeout).
+	StreamAttemptTimeoutSeconds int `yaml:"stream-attempt-timeout-seconds" json:"stream-attempt-timeout-seconds"`
+	// RequestDeadlineSeconds bounds a request including every retry and backoff wait, in seconds (0 = no deadline).
+	RequestDeadlineSeconds int `yaml:"request-deadline-seconds" json:"request-deadline-seconds"`
+	// StickySessionTTLSeconds keeps requests carrying the same session ID on one credential
+	// until the session is idle for this many seconds (0 = disabled).
+	StickySessionTTLSeconds int `yaml:"sticky-session-ttl-seconds" json:"sticky-session-ttl-seconds"`
+	// AccountSelection chooses how a credential is picked among the available ones:
+	// "round-robin" (default) or "deterministic", which hashes the request so tests can
+	// predict the credential. Deterministic selection does not balance load.
+	AccountSelection string `yaml:"account-selection,omitempty" json:"account-selection,omitempty"`
 
 	// QuotaExceeded defines the behavior when a quota is exceeded.
 	QuotaExceeded QuotaExceeded `yaml:"quota-exceeded" json:"quota-exceeded"`
 
+	// RateLimit configures per-client token-bucket limits for inbound API requests.
+	RateLimit RateLimitConfig `yaml:"rate-limit" json:"rate-limit"`
+
+	// AuditLog records full prompts and responses to an append-only audit trail.
+	AuditLog AuditLogConfig `yaml:"audit-log" json:"audit-log"`
+
+	// Readiness configures the /readyz probe.
+	Readiness ReadinessConfig `yaml:"readiness" json:"readiness"`
+
+	// Compression gzip- or deflate-encodes large non-streaming responses for clients that accept it.
+	Compression CompressionConfig `yaml:"compression" json:"compression"`
+
+	// RequestBodyLimit rejects oversized request bodies with 413 before they are parsed.
+	RequestBodyLimit RequestBodyLimitConfig `yaml:"request-body-limit" json:"request-body-limit"`
+
+	// CircuitBreaker fails fast on credentials whose upstream keeps failing.
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit-breaker" json:"circuit-breaker"`
+
+	// ModelConcurrency caps concurrent upstream requests per model.
+	ModelConcurrency ModelConcurrencyConfig `yaml:"model-concurrency" json:"model-concurrency"`
+
+	// UpstreamEndpoints overrides the official upstream base URL per provider, for example to
+	// route through a gateway or a regional endpoint. Account-level base URLs take precedence.
+	UpstreamEndpoints map[string]UpstreamEndpoint `yaml:"upstream-endpoints,omitempty" json:"upstream-endpoints,omitempty"`
+
+	// ProviderProxies maps provider identifiers (e.g. "claude") to the proxy URL used for their
+	// upstream calls instead of proxy-url. Per-key proxy URLs take precedence.
+	ProviderProxies map[string]string `yaml:"provider-proxies,omitempty" json:"provider-proxies,omitempty"`
+
+	// UpstreamHTTP tunes the connection pool shared by the HTTP clients of upstream calls.
+	UpstreamHTTP UpstreamHTTPConfig `yaml:"upstream-http" json:"upstream-http"`
+
+	// AccountQuotas caps per-credential usage within daily or monthly windows.
+	AccountQuotas []AccountQuota `yaml:"account-quotas,omitempty" json:"account-quotas,omitempty"`
+
+	// ModelRewrites rewrites the model name sent to individual providers.
+	ModelRewrites []ModelRewriteRule `yaml:"model-rewrites,omitempty" json:"model-rewrites,omitempty"`
+
+	// DefaultThinkingBudgets maps model names, or family prefixes ending in "*", to the thinking
+	// budget used when a client enables thinking without specifying one.
+	DefaultThinkingBudgets map[string]int `yaml:"default-thinking-budgets,omitempty" json:"default-thinking-budgets,omitempty"`
+
+	// ModelParamDefaults maps model names, or family prefixes ending in "*", to the sampling
+	// parameters applied when a client omits them.
+	ModelParamDefaults map[string]ModelParamDefaults `yaml:"model-param-defaults,omitempty" json:"model-param-defaults,omitempty"`
+
+	// SamplingProfiles maps profile names to the sampling parameters a request expands when it
+	// names the profile in the X-Sampling-Profile header or the sampling_profile field.
+	SamplingProfiles map[string]ModelParamDefaults `yaml:"sampling-profiles,omitempty" json:"sampling-profiles,omitempty"`
+
+	// PromptInjections maps model names, or family prefixes ending in "*", to text added to the
+	// system prompt and the final user message of every request for them.
+	PromptInjections map[string]PromptInjection `yaml:"prompt-injections,omitempty" json:"prompt-injections,omitempty"`
+
+	// SystemPromptPolicies enforces a system prompt for individual client API keys.
+	SystemPromptPolicies []SystemPromptPolicy `yaml:"system-prompt-policies,omitempty" json:"system-prompt-policies,omitempty"`
+
 	// WebsocketAuth enables or disables authentication for the WebSocket API.
 	WebsocketAuth bool `yaml:"ws-auth" json:"ws-auth"`
 
@@ -101,6 +193,9 @@ type RemoteManagement struct {
 	SecretKey string `yaml:"secret-key"`
 	// DisableControlPanel skips serving and syncing the bundled management UI when true.
 	DisableControlPanel bool `yaml:"disable-control-panel"`
+	// AdminToken guards the admin endpoints (POST /v0/admin/reload), separately from the
+	// management key. Plaintext or bcrypt hashed; empty disables the admin endpoints.
+	AdminToken string `yaml:"admin-token"`
 }
 
 // QuotaExceeded defines the behavior when API quota limits are exceeded.
@@ -113,6 +208,187 @@ type QuotaExceeded struct {
 	SwitchPreviewModel bool `yaml:"switch-preview-model" json:"switch-preview-model"`
 }
 
+// RateLimitConfig defines per-client token-bucket limits applied to inbound API requests.
+// Clients are identified by their authenticated API key unless Header names a request
+// header to key on instead. Clients without a matching entry are not limited.
+type RateLimitConfig struct {
+	// Header optionally names the request header whose value identifies the client.
+	Header string `yaml:"header,omitempty" json:"header,omitempty"`
+
+	// Keys lists the limited clients and their budgets.
+	Keys []RateLimitKey `yaml:"keys,omitempty" json:"keys,omitempty"`
+}
+
+// RateLimitKey sets the request budget for a single client key.
+type RateLimitKey struct {
+	// Key is the API key (or header value) the limit applies to.
+	Key string `yaml:"key" json:"key"`
+
+	// RequestsPerMinute is the sustained refill rate; zero or negative means unlimited.
+	RequestsPerMinute int `yaml:"requests-per-minute" json:"requests-per-minute"`
+
+	// Burst is the bucket capacity; zero defaults to RequestsPerMinute.
+	Burst int `yaml:"burst,omitempty" json:"burst,omitempty"`
+
+	// PerUserRequestsPerMinute limits each end user of the key, identified by the OpenAI "user"
+	// field of the request body, on top of the key's own limit; zero disables it. Requests
+	// without the field are only subject to the key limit.
+	PerUserRequestsPerMinute int `yaml:"per-user-requests-per-minute,omitempty" json:"per-user-requests-per-minute,omitempty"`
+
+	// PerUserBurst is the per-user bucket capacity; zero defaults to PerUserRequestsPerMinute.
+	PerUserBurst int `yaml:"per-user-burst,omitempty" json:"per-user-burst,omitempty"`
+}
+
+// AuditLogConfig controls the prompt and response audit log.
+type AuditLogConfig struct {
+	// Enabled turns audit logging on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// File is the JSON lines file records are appended to; empty defaults to audit.jsonl in
+	// the logs directory. Ignored when the host application supplies its own sink.
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+
+	// Redact lists regular expressions whose matches in request and response bodies are
+	// replaced with "[REDACTED]" before records are written.
+	Redact []string `yaml:"redact,omitempty" json:"redact,omitempty"`
+}
+
+// ReadinessConfig controls the checks performed by the /readyz endpoint.
+type ReadinessConfig struct {
+	// UpstreamPing additionally verifies one usable credential per provider upstream,
+	// for providers that support a cheap ping.
+	UpstreamPing bool `yaml:"upstream-ping" json:"upstream-ping"`
+
+	// PingCacheSeconds caches ping results per provider; zero defaults to 60 seconds.
+	PingCacheSeconds int `yaml:"ping-cache-seconds,omitempty" json:"ping-cache-seconds,omitempty"`
+}
+
+// CompressionConfig controls response compression negotiated through Accept-Encoding.
+type CompressionConfig struct {
+	// Enabled turns compression on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MinBytes is the smallest response body that is compressed; zero defaults to 1024.
+	MinBytes int `yaml:"min-bytes,omitempty" json:"min-bytes,omitempty"`
+}
+
+// RequestBodyLimitConfig bounds the size of inbound request bodies.
+type RequestBodyLimitConfig struct {
+	// Enabled turns the limit on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MaxBytes is the limit for text requests; zero defaults to 10 MiB.
+	MaxBytes int64 `yaml:"max-bytes,omitempty" json:"max-bytes,omitempty"`
+
+	// MultimodalMaxBytes is the limit for requests carrying inline images, audio or files;
+	// zero defaults to 64 MiB. It never falls below MaxBytes.
+	MultimodalMaxBytes int64 `yaml:"multimodal-max-bytes,omitempty" json:"multimodal-max-bytes,omitempty"`
+}
+
+// AccountQuota limits the tokens and requests a credential may use per window. Once a limit
+// is reached the credential receives no new traffic until the window resets.
+type AccountQuota struct {
+	// AuthID selects a single credential.
+	AuthID string `yaml:"auth-id,omitempty" json:"auth-id,omitempty"`
+
+	// Provider applies the limit to each credential of the provider when AuthID is empty.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+
+	// Window is "daily" or "monthly"; windows are calendar-aligned in UTC.
+	Window string `yaml:"window" json:"window"`
+
+	// MaxTokens caps prompt plus completion tokens per window; zero means unlimited.
+	MaxTokens int64 `yaml:"max-tokens,omitempty" json:"max-tokens,omitempty"`
+
+	// MaxRequests caps upstream requests per window; zero means unlimited.
+	MaxRequests int64 `yaml:"max-requests,omitempty" json:"max-requests,omitempty"`
+}
+
+// ModelRewriteRule rewrites the model name of requests dispatched to one provider, so the
+// canonical name used by clients and the registry can differ from the provider's own.
+type ModelRewriteRule struct {
+	// Provider is the provider key the rule applies to, e.g. "claude" or an openai-compatibility name.
+	Provider string `yaml:"provider" json:"provider"`
+
+	// Match is the substring to replace, or a regular expression when Regex is set.
+	Match string `yaml:"match" json:"match"`
+
+	// Replace is the replacement; regular expression rules may reference groups as $1.
+	Replace string `yaml:"replace" json:"replace"`
+
+	// Regex treats Match as a regular expression.
+	Regex bool `yaml:"regex,omitempty" json:"regex,omitempty"`
+}
+
+// RetryOnConfig classifies upstream failures as retryable.
+type RetryOnConfig struct {
+	// StatusCodes replaces the default retryable HTTP statuses (403, 408, 429, 500, 502, 503
+	// and 504) when set. Transport failures without a response are always retried.
+	StatusCodes []int `yaml:"status-codes,omitempty" json:"status-codes,omitempty"`
+
+	// BodyContains marks an upstream error as retryable when its body contains any of these
+	// substrings, whatever its status. A non-streaming 200 response whose error, error.message
+	// or error.status field matches is treated as a retryable upstream error, for providers
+	// that embed errors in successes.
+	BodyContains []string `yaml:"body-contains,omitempty" json:"body-contains,omitempty"`
+}
+
+// CircuitBreakerConfig controls the per-credential circuit breaker.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive upstream failures (transport errors,
+	// timeouts, 5xx) that opens a credential's circuit; zero disables circuit breaking.
+	FailureThreshold int `yaml:"failure-threshold" json:"failure-threshold"`
+
+	// WindowSeconds bounds how far apart consecutive failures may be; zero defaults to 60.
+	WindowSeconds int `yaml:"window-seconds,omitempty" json:"window-seconds,omitempty"`
+
+	// CooldownSeconds is how long an open circuit fails fast before a probe request is let
+	// through; zero defaults to 30.
+	CooldownSeconds int `yaml:"cooldown-seconds,omitempty" json:"cooldown-seconds,omitempty"`
+}
+
+// ModelConcurrencyConfig caps the number of requests in flight per model across all accounts.
+type ModelConcurrencyConfig struct {
+	// Limits maps model names, or family prefixes ending in "*", to the maximum number of
+	// concurrent requests for each matching model; unlisted models are unlimited.
+	Limits map[string]int `yaml:"limits,omitempty" json:"limits,omitempty"`
+
+	// QueueTimeoutSeconds is how long a request beyond the limit waits for a free slot; zero
+	// rejects it immediately with 429.
+	QueueTimeoutSeconds int `yaml:"queue-timeout-seconds,omitempty" json:"queue-timeout-seconds,omitempty"`
+
+	// FlexQueueTimeoutSeconds is how long a request with the OpenAI "flex" service_tier waits
+	// instead; zero uses QueueTimeoutSeconds.
+	FlexQueueTimeoutSeconds int `yaml:"flex-queue-timeout-seconds,omitempty" json:"flex-queue-timeout-seconds,omitempty"`
+}
+
+// UpstreamEndpoint overrides where requests of one provider are sent.
+type UpstreamEndpoint struct {
+	// BaseURL replaces the official base URL of the provider, including any path it carries
+	// (e.g. https://chatgpt.com/backend-api/codex). Empty keeps the official base URL.
+	BaseURL string `yaml:"base-url,omitempty" json:"base-url,omitempty"`
+
+	// PathPrefix is inserted between the base URL and the request path, e.g. "/anthropic" for
+	// a gateway that routes by path.
+	PathPrefix string `yaml:"path-prefix,omitempty" json:"path-prefix,omitempty"`
+}
+
+// UpstreamHTTPConfig tunes the pooled transports used for upstream requests.
+type UpstreamHTTPConfig struct {
+	// MaxIdleConns caps idle connections across all hosts; zero defaults to 256.
+	MaxIdleConns int `yaml:"max-idle-conns,omitempty" json:"max-idle-conns,omitempty"`
+
+	// MaxIdleConnsPerHost caps idle connections kept per upstream host; zero defaults to 64.
+	MaxIdleConnsPerHost int `yaml:"max-idle-conns-per-host,omitempty" json:"max-idle-conns-per-host,omitempty"`
+
+	// IdleConnTimeoutSeconds is how long an idle connection is kept; zero defaults to 90.
+	IdleConnTimeoutSeconds int `yaml:"idle-conn-timeout-seconds,omitempty" json:"idle-conn-timeout-seconds,omitempty"`
+
+	// ForceAttemptHTTP2 negotiates HTTP/2 even through custom dialers such as SOCKS5 proxies.
+	// Nil defaults to true.
+	ForceAttemptHTTP2 *bool `yaml:"force-attempt-http2,omitempty" json:"force-attempt-http2,omitempty"`
+}
+
 // AmpModelMapping defines a model name mapping for Amp CLI requests.
 // When Amp requests a model that isn't available locally, this mapping
 // allows routing to an alternative model that IS available.
@@ -145,6 +421,40 @@ type AmpCode struct {
 	ModelMappings []AmpModelMapping `yaml:"model-mappings" json:"model-mappings"`
 }
 
+// ModelParamDefaults holds default sampling parameters for a model or a sampling profile.
+// Unset fields leave the parameter to the client and the upstream.
+type ModelParamDefaults struct {
+	Temperature *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	TopP        *float64 `yaml:"top-p,omitempty" json:"top-p,omitempty"`
+	TopK        *int64   `yaml:"top-k,omitempty" json:"top-k,omitempty"`
+}
+
+// PromptInjection holds the text injected into the prompts sent for a model.
+type PromptInjection struct {
+	// Prefix is prepended to the system prompt, which is created when the request has none.
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	// Suffix is appended to the final user message.
+	Suffix string `yaml:"suffix,omitempty" json:"suffix,omitempty"`
+}
+
+// System prompt policy modes.
+const (
+	// SystemPromptModeOverride replaces any client system prompt with the configured one.
+	SystemPromptModeOverride = "override"
+	// SystemPromptModeMerge prepends the configured prompt to the client system prompt.
+	SystemPromptModeMerge = "merge"
+)
+
+// SystemPromptPolicy holds the system prompt enforced for one client API key.
+type SystemPromptPolicy struct {
+	// Key is the client API key the policy applies to.
+	Key string `yaml:"key" json:"key"`
+	// Mode is SystemPromptModeOverride or SystemPromptModeMerge; empty means merge.
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	// Prompt is the enforced system prompt.
+	Prompt string `yaml:"prompt" json:"prompt"`
+}
+
 // PayloadConfig defines default and override parameter rules applied to provider payloads.
 type PayloadConfig struct {
 	// Default defines rules that only set parameters when they are missing in the payload.
@@ -190,6 +500,12 @@ type ClaudeKey struct {
 
 	// ExcludedModels lists model IDs that should be excluded for this provider.
 	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
+	// Weight controls the share of traffic routed to this key under weighted round-robin.
+	// Defaults to 1 when omitted; 0 keeps the key configured but excludes it from new requests.
+	Weight *int `yaml:"weight,omitempty" json:"weight,omitempty"`
+	// Priority sets the selection tier of this key; higher tiers are used first and lower
+	// ones only while every key above them is unavailable. Defaults to 0.
+	Priority *int `yaml:"priority,omitempty" json:"priority,omitempty"`
 }
 
 // ClaudeModel describes a mapping between an alias and the actual upstream model name.
@@ -219,6 +535,12 @@ type CodexKey struct {
 
 	// ExcludedModels lists model IDs that should be excluded for this provider.
 	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
+	// Weight controls the share of traffic routed to this key under weighted round-robin.
+	// Defaults to 1 when omitted; 0 keeps the key configured but excludes it from new requests.
+	Weight *int `yaml:"weight,omitempty" json:"weight,omitempty"`
+	// Priority sets the selection tier of this key; higher tiers are used first and lower
+	// ones only while every key above them is unavailable. Defaults to 0.
+	Priority *int `yaml:"priority,omitempty" json:"priority,omitempty"`
 }
 
 // GeminiKey represents the configuration for a Gemini API key,
@@ -238,6 +560,12 @@ type GeminiKey struct {
 
 	// ExcludedModels lists model IDs that should be excluded for this provider.
 	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
+	// Weight controls the share of traffic routed to this key under weighted round-robin.
+	// Defaults to 1 when omitted; 0 keeps the key configured but excludes it from new requests.
+	Weight *int `yaml:"weight,omitempty" json:"weight,omitempty"`
+	// Priority sets the selection tier of this key; higher tiers are used first and lower
+	// ones only while every key above them is unavailable. Defaults to 0.
+	Priority *int `yaml:"priority,omitempty" json:"priority,omitempty"`
 }
 
 // OpenAICompatibility represents the configuration for OpenAI API compatibility
@@ -266,6 +594,13 @@ type OpenAICompatibilityAPIKey struct {
 
 	// ProxyURL overrides the global proxy setting for this API key if provided.
 	ProxyURL string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
+
+	// Weight controls the share of traffic routed to this key under weighted round-robin.
+	// Defaults to 1 when omitted; 0 keeps the key configured but excludes it from new requests.
+	Weight *int `yaml:"weight,omitempty" json:"weight,omitempty"`
+	// Priority sets the selection tier of this key; higher tiers are used first and lower
+	// ones only while every key above them is unavailable. Defaults to 0.
+	Priority *int `yaml:"priority,omitempty" json:"priority,omitempty"`
 }
 
 // OpenAICompatibilityModel represents a model configuration for OpenAI compatibility,
@@ -376,6 +711,21 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	// Normalize OAuth provider model exclusion map.
 	cfg.OAuthExcludedModels = NormalizeOAuthExcludedModels(cfg.OAuthExcludedModels)
 
+	// Reject malformed upstream endpoint overrides instead of failing on every request.
+	if err = cfg.ValidateUpstreamEndpoints(); err != nil {
+		return nil, err
+	}
+
+	// Reject malformed proxy URLs instead of silently connecting direct.
+	if err = cfg.ValidateProxyURLs(); err != nil {
+		return nil, err
+	}
+
+	// Reject negative retry settings instead of silently clamping them.
+	if err = cfg.ValidateRetrySettings(); err != nil {
+		return nil, err
+	}
+
 	if cfg.legacyMigrationPending {
 		fmt.Println("Detected legacy configuration keys, attempting to persist the normalized config...")
 		if !optional && configFile != "" {
@@ -392,6 +742,56 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	return &cfg, nil
 }
 
+// ValidateRetrySettings returns an error for a negative request-retry or max-retry-interval.
+func (cfg *Config) ValidateRetrySettings() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.RequestRetry < 0 {
+		return fmt.Errorf("invalid request-retry %d: must be zero or greater", cfg.RequestRetry)
+	}
+	if cfg.MaxRetryInterval < 0 {
+		return fmt.Errorf("invalid max-retry-interval %d: must be zero or greater", cfg.MaxRetryInterval)
+	}
+	return nil
+}
+
+// ValidateUpstreamEndpoints normalizes the upstream endpoint overrides: provider keys are
+// lowercased, trailing slashes are trimmed and path prefixes get a leading slash. It returns an
+// error for a base URL that is not an absolute http or https URL or carries a query or fragment.
+func (cfg *Config) ValidateUpstreamEndpoints() error {
+	if cfg == nil || len(cfg.UpstreamEndpoints) == 0 {
+		return nil
+	}
+	normalized := make(map[string]UpstreamEndpoint, len(cfg.UpstreamEndpoints))
+	for provider, endpoint := range cfg.UpstreamEndpoints {
+		key := strings.ToLower(strings.TrimSpace(provider))
+		if key == "" {
+			continue
+		}
+		endpoint.BaseURL = strings.TrimRight(strings.TrimSpace(endpoint.BaseURL), "/")
+		if endpoint.BaseURL != "" {
+			parsed, errParse := url.Parse(endpoint.BaseURL)
+			if errParse != nil {
+				return fmt.Errorf("invalid upstream-endpoints.%s.base-url %q: %w", key, endpoint.BaseURL, errParse)
+			}
+			if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("invalid upstream-endpoints.%s.base-url %q: must be an absolute http or https URL", key, endpoint.BaseURL)
+			}
+			if parsed.RawQuery != "" || parsed.Fragment != "" {
+				return fmt.Errorf("invalid upstream-endpoints.%s.base-url %q: must not contain a query or fragment", key, endpoint.BaseURL)
+			}
+		}
+		endpoint.PathPrefix = strings.Trim(strings.TrimSpace(endpoint.PathPrefix), "/")
+		if endpoint.PathPrefix != "" {
+			endpoint.PathPrefix = "/" + endpoint.PathPrefix
+		}
+		normalized[key] = endpoint
+	}
+	cfg.UpstreamEndpoints = normalized
+	return nil
+}
+
 // SanitizeOpenAICompatibility removes OpenAI-compatibility provider entries that are
 // not actionable, specifically those missing a BaseURL. It trims whitespace before
 // evaluation and preserves the relative order of remaining entries.
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ValidateProxyURL reports whether raw is a usable upstream proxy URL: an http, https or
+// socks5 URL with a host. Credentials may be given as user info. An empty URL is valid.
+func ValidateProxyURL(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	parsed, errParse := url.Parse(raw)
+	if errParse != nil {
+		return fmt.Errorf("proxy URL is malformed")
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q: must be http, https or socks5", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("proxy URL has no host")
+	}
+	return nil
+}
+
+// RedactProxyURL returns raw with the password of its credentials masked, for logging.
+// A URL that does not parse is replaced entirely since it may still carry credentials.
+func RedactProxyURL(raw string) string {
+	parsed, errParse := url.Parse(strings.TrimSpace(raw))
+	if errParse != nil {
+		return "<invalid proxy URL>"
+	}
+	return parsed.Redacted()
+}
+
+// ValidateProxyURLs normalizes the provider keys of provider-proxies and rejects malformed
+// proxy URLs in the global, per-provider and per-key settings, so a typo fails at startup
+// instead of silently sending traffic direct. Errors name the setting and never include
+// the proxy credentials.
+func (cfg *Config) ValidateProxyURLs() error {
+	if cfg == nil {
+		return nil
+	}
+	check := func(setting, raw string) error {
+		if err := ValidateProxyURL(raw); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", setting, RedactProxyURL(raw), err)
+		}
+		return nil
+	}
+	if err := check("proxy-url", cfg.ProxyURL); err != nil {
+		return err
+	}
+	if len(cfg.ProviderProxies) > 0 {
+		normalized := make(map[string]string, len(cfg.ProviderProxies))
+		for provider, raw := range cfg.ProviderProxies {
+			key := strings.ToLower(strings.TrimSpace(provider))
+			raw = strings.TrimSpace(raw)
+			if key == "" || raw == "" {
+				continue
+			}
+			if err := check("provider-proxies."+key, raw); err != nil {
+				return err
+			}
+			normalized[key] = raw
+		}
+		cfg.ProviderProxies = normalized
+	}
+	for i, entry := range cfg.GeminiKey {
+		if err := check(fmt.Sprintf("gemini-api-key[%d].proxy-url", i), entry.ProxyURL); err != nil {
+			return err
+		}
+	}
+	for i, entry := range cfg.ClaudeKey {
+		if err := check(fmt.Sprintf("claude-api-key[%d].proxy-url", i), entry.ProxyURL); err != nil {
+			return err
+		}
+	}
+	for i, entry := range cfg.CodexKey {
+		if err := check(fmt.Sprintf("codex-api-key[%d].proxy-url", i), entry.ProxyURL); err != nil {
+			return err
+		}
+	}
+	for i, entry := range cfg.VertexCompatAPIKey {
+		if err := check(fmt.Sprintf("vertex-api-key[%d].proxy-url", i), entry.ProxyURL); err != nil {
+			return err
+		}
+	}
+	for i, compat := range cfg.OpenAICompatibility {
+		for j, entry := range compat.APIKeyEntries {
+			if err := check(fmt.Sprintf("openai-compatibility[%d].api-key-entries[%d].proxy-url", i, j), entry.ProxyURL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
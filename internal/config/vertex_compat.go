@@ -27,6 +27,13 @@ type VertexCompatKey struct {
 
 	// Models defines the model configurations including aliases for routing.
 	Models []VertexCompatModel `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// Weight controls the share of traffic routed to this key under weighted round-robin.
+	// Defaults to 1 when omitted; 0 keeps the key configured but excludes it from new requests.
+	Weight *int `yaml:"weight,omitempty" json:"weight,omitempty"`
+	// Priority sets the selection tier of this key; higher tiers are used first and lower
+	// ones only while every key above them is unavailable. Defaults to 0.
+	Priority *int `yaml:"priority,omitempty" json:"priority,omitempty"`
 }
 
 // VertexCompatModel represents a model configuration for Vertex compatibility,
@@ -22,6 +22,9 @@ const (
 	// OpenaiResponse represents the OpenAI response format identifier.
 	OpenaiResponse = "openai-response"
 
+	// OpenAIEmbeddings represents the OpenAI embeddings request format identifier.
+	OpenAIEmbeddings = "openai-embeddings"
+
 	// Antigravity represents the Antigravity response format identifier.
 	Antigravity = "antigravity"
 )
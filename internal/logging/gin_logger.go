@@ -49,13 +49,14 @@ func GinLogrusLogger() gin.HandlerFunc {
 			logLine = logLine + " | " + errorMessage
 		}
 
+		entry := log.WithContext(c.Request.Context())
 		switch {
 		case statusCode >= http.StatusInternalServerError:
-			log.Error(logLine)
+			entry.Error(logLine)
 		case statusCode >= http.StatusBadRequest:
-			log.Warn(logLine)
+			entry.Warn(logLine)
 		default:
-			log.Info(logLine)
+			entry.Info(logLine)
 		}
 	}
 }
@@ -68,7 +69,7 @@ func GinLogrusLogger() gin.HandlerFunc {
 //   - gin.HandlerFunc: A middleware handler for panic recovery
 func GinLogrusRecovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		log.WithFields(log.Fields{
+		log.WithContext(c.Request.Context()).WithFields(log.Fields{
 			"panic": recovered,
 			"stack": string(debug.Stack()),
 			"path":  c.Request.URL.Path,
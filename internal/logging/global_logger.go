@@ -24,7 +24,8 @@ var (
 )
 
 // LogFormatter defines a custom log format for logrus.
-// This formatter adds timestamp, level, and source location to each log entry.
+// This formatter adds timestamp, level, source location and, for request-scoped entries, the
+// request ID to each log entry.
 type LogFormatter struct{}
 
 // Format renders a single log entry with custom formatting.
@@ -38,7 +39,12 @@ func (m *LogFormatter) Format(entry *log.Entry) ([]byte, error) {
 
 	timestamp := entry.Time.Format("2006-01-02 15:04:05")
 	message := strings.TrimRight(entry.Message, "\r\n")
-	formatted := fmt.Sprintf("[%s] [%s] [%s:%d] %s\n", timestamp, entry.Level, filepath.Base(entry.Caller.File), entry.Caller.Line, message)
+	var formatted string
+	if requestID := requestIDOf(entry); requestID != "" {
+		formatted = fmt.Sprintf("[%s] [%s] [%s:%d] [%s] %s\n", timestamp, entry.Level, filepath.Base(entry.Caller.File), entry.Caller.Line, requestID, message)
+	} else {
+		formatted = fmt.Sprintf("[%s] [%s] [%s:%d] %s\n", timestamp, entry.Level, filepath.Base(entry.Caller.File), entry.Caller.Line, message)
+	}
 	buffer.WriteString(formatted)
 
 	return buffer.Bytes(), nil
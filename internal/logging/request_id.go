@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-Id"
+
+// requestIDField is the log field and gin key holding the request ID.
+const requestIDField = "request_id"
+
+type requestIDContextKey struct{}
+
+// RequestIDMiddleware gives every request an ID: the client's X-Request-Id when it is a valid
+// UUID or ULID, otherwise a new random UUID. The ID is echoed in the response headers, stored
+// in the request context for handlers and executors, and attached to log records created with
+// log.WithContext and to the access log.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := strings.TrimSpace(c.GetHeader(RequestIDHeader))
+		if id != "" && !ValidRequestID(id) {
+			log.Debugf("replacing invalid %s %q", RequestIDHeader, truncateRequestID(id))
+			id = ""
+		}
+		if id == "" {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDField, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// ValidRequestID reports whether id is a UUID or a ULID.
+func ValidRequestID(id string) bool {
+	if len(id) == 36 {
+		_, err := uuid.Parse(id)
+		return err == nil
+	}
+	return isULID(id)
+}
+
+// isULID reports whether id is a 26 character Crockford base32 ULID. The first character
+// encodes the top bits of the 48-bit timestamp, so it cannot exceed 7.
+func isULID(id string) bool {
+	if len(id) != 26 || id[0] > '7' {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if !strings.ContainsRune("0123456789ABCDEFGHJKMNPQRSTVWXYZabcdefghjkmnpqrstvwxyz", rune(id[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func truncateRequestID(id string) string {
+	if len(id) > 64 {
+		return id[:64] + "..."
+	}
+	return id
+}
+
+// WithRequestID attaches id to ctx.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID attached to ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// requestIDOf returns the request ID of a log entry, from its fields or its context.
+func requestIDOf(entry *log.Entry) string {
+	if id, ok := entry.Data[requestIDField].(string); ok {
+		return id
+	}
+	return RequestIDFromContext(entry.Context)
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func serveWithRequestID(t *testing.T, requestID string) (*httptest.ResponseRecorder, []*log.Entry) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), GinLogrusLogger())
+	router.GET("/v1/models", func(c *gin.Context) {
+		log.WithContext(c.Request.Context()).Info("handling request")
+		c.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	if requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec, hook.AllEntries()
+}
+
+func assertLoggedWithRequestID(t *testing.T, entries []*log.Entry, id string) {
+	t.Helper()
+	var messages []string
+	for _, entry := range entries {
+		messages = append(messages, entry.Message)
+		if got := requestIDOf(entry); got != id {
+			t.Fatalf("log entry %q has request ID %q, want %q", entry.Message, got, id)
+		}
+	}
+	if len(entries) != 2 || !strings.Contains(messages[1], "/v1/models") {
+		t.Fatalf("expected the handler log and the access log, got %v", messages)
+	}
+}
+
+func TestRequestIDMiddleware_EchoesSuppliedID(t *testing.T) {
+	for _, id := range []string{"0f8fad5b-d9cb-469f-a165-70867728950e", "01ARZ3NDEKTSV4RRFFQ69G5FAV"} {
+		rec, entries := serveWithRequestID(t, id)
+		if got := rec.Header().Get(RequestIDHeader); got != id {
+			t.Fatalf("response %s = %q, want %q", RequestIDHeader, got, id)
+		}
+		assertLoggedWithRequestID(t, entries, id)
+	}
+}
+
+func TestRequestIDMiddleware_GeneratesID(t *testing.T) {
+	for _, supplied := range []string{"", "not a request id", "81ARZ3NDEKTSV4RRFFQ69G5FAV"} {
+		rec, entries := serveWithRequestID(t, supplied)
+		id := rec.Header().Get(RequestIDHeader)
+		if id == supplied || !ValidRequestID(id) || len(id) != 36 {
+			t.Fatalf("supplied %q: expected a generated UUID, got %q", supplied, id)
+		}
+		assertLoggedWithRequestID(t, entries, id)
+	}
+}
+
+func TestLogFormatter_IncludesRequestID(t *testing.T) {
+	entry := &log.Entry{
+		Logger:  log.StandardLogger(),
+		Time:    time.Now(),
+		Level:   log.InfoLevel,
+		Message: "hello",
+		Caller:  &runtime.Frame{File: "/src/handler.go", Line: 42},
+		Context: WithRequestID(context.Background(), "01ARZ3NDEKTSV4RRFFQ69G5FAV"),
+	}
+	out, err := (&LogFormatter{}).Format(entry)
+	if err != nil {
+		t.Fatalf("format: %v", err)
+	}
+	if !strings.HasSuffix(string(out), "[handler.go:42] [01ARZ3NDEKTSV4RRFFQ69G5FAV] hello\n") {
+		t.Fatalf("unexpected log line %q", out)
+	}
+}
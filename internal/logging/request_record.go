@@ -0,0 +1,269 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxRecordErrorLength bounds the error text attached to a request record so upstream
+// error bodies cannot flood the log.
+const maxRecordErrorLength = 512
+
+type requestRecordContextKey struct{}
+
+var requestRecordLogger atomic.Pointer[slog.Logger]
+
+func init() {
+	requestRecordLogger.Store(slog.New(slog.NewJSONHandler(logOutputWriter{}, nil)))
+}
+
+// logOutputWriter forwards writes to the current logrus output so request records follow
+// the logging-to-file setting.
+type logOutputWriter struct{}
+
+func (logOutputWriter) Write(p []byte) (int, error) {
+	out := log.StandardLogger().Out
+	if out == nil {
+		return io.Discard.Write(p)
+	}
+	return out.Write(p)
+}
+
+// SetRequestRecordLogger replaces the slog logger used to emit request records.
+// Passing nil restores the default JSON logger.
+func SetRequestRecordLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.New(slog.NewJSONHandler(logOutputWriter{}, nil))
+	}
+	requestRecordLogger.Store(logger)
+}
+
+// RequestRecord accumulates the facts about a single inbound request as it moves through
+// handlers, the auth manager and executors, and is emitted once as a structured log line.
+// It deliberately carries no API keys or prompt content.
+type RequestRecord struct {
+	mu sync.Mutex
+
+	startedAt       time.Time
+	requestID       string
+	method          string
+	path            string
+	handler         string
+	model           string
+	stream          bool
+	provider        string
+	authID          string
+	attempts        int
+	upstreamLatency time.Duration
+	thinkingBudget  *int64
+	reasoningEffort string
+	cacheHit        bool
+
+	promptTokens     int64
+	completionTokens int64
+	reasoningTokens  int64
+	cachedTokens     int64
+	totalTokens      int64
+
+	emitOnce sync.Once
+}
+
+// NewRequestRecord starts a record for an inbound request.
+func NewRequestRecord(method, path, handler string) *RequestRecord {
+	return &RequestRecord{startedAt: time.Now(), method: method, path: path, handler: handler}
+}
+
+// SetRequestID records the ID of the request, as assigned by RequestIDMiddleware.
+func (r *RequestRecord) SetRequestID(id string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requestID = id
+}
+
+// WithRequestRecord attaches record to ctx.
+func WithRequestRecord(ctx context.Context, record *RequestRecord) context.Context {
+	return context.WithValue(ctx, requestRecordContextKey{}, record)
+}
+
+// RequestRecordFromContext returns the record attached to ctx, or nil. All RequestRecord
+// methods are safe to call on a nil receiver.
+func RequestRecordFromContext(ctx context.Context) *RequestRecord {
+	if ctx == nil {
+		return nil
+	}
+	record, _ := ctx.Value(requestRecordContextKey{}).(*RequestRecord)
+	return record
+}
+
+// SetModel records the model requested by the client and whether the response streams.
+func (r *RequestRecord) SetModel(model string, stream bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.model = model
+	r.stream = stream
+}
+
+// ObserveAttempt records one upstream attempt against provider with the given credential.
+// The latest attempt determines the reported provider, credential and upstream latency.
+func (r *RequestRecord) ObserveAttempt(provider, authID string, latency time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	r.provider = provider
+	r.authID = authID
+	r.upstreamLatency = latency
+}
+
+// ObserveUsage records the token usage reported by the upstream.
+func (r *RequestRecord) ObserveUsage(prompt, completion, reasoning, cached, total int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.promptTokens = prompt
+	r.completionTokens = completion
+	r.reasoningTokens = reasoning
+	r.cachedTokens = cached
+	r.totalTokens = total
+}
+
+// ObserveThinking records the thinking budget or reasoning effort sent upstream.
+func (r *RequestRecord) ObserveThinking(budget *int64, effort string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.thinkingBudget = budget
+	r.reasoningEffort = effort
+}
+
+// ObserveCacheHit records that the response was served from the response cache.
+func (r *RequestRecord) ObserveCacheHit() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cacheHit = true
+}
+
+// RequestSnapshot is a point-in-time copy of the values a RequestRecord has accumulated.
+type RequestSnapshot struct {
+	RequestID        string
+	Model            string
+	Stream           bool
+	Provider         string
+	AuthID           string
+	Attempts         int
+	UpstreamLatency  time.Duration
+	PromptTokens     int64
+	CompletionTokens int64
+	ThinkingBudget   *int64
+	CacheHit         bool
+}
+
+// Snapshot returns a copy of the accumulated values.
+func (r *RequestRecord) Snapshot() RequestSnapshot {
+	if r == nil {
+		return RequestSnapshot{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return RequestSnapshot{
+		RequestID:        r.requestID,
+		Model:            r.model,
+		Stream:           r.stream,
+		Provider:         r.provider,
+		AuthID:           r.authID,
+		Attempts:         r.attempts,
+		UpstreamLatency:  r.upstreamLatency,
+		PromptTokens:     r.promptTokens,
+		CompletionTokens: r.completionTokens,
+		ThinkingBudget:   r.thinkingBudget,
+		CacheHit:         r.cacheHit,
+	}
+}
+
+// Emit writes the record once with the final HTTP status and error, if any.
+// It reports whether this call emitted the record.
+func (r *RequestRecord) Emit(status int, err error) bool {
+	if r == nil {
+		return false
+	}
+	emitted := false
+	r.emitOnce.Do(func() {
+		emitted = true
+		attrs := r.attrs(status, err)
+		level := slog.LevelInfo
+		if err != nil || status >= 400 {
+			level = slog.LevelWarn
+		}
+		requestRecordLogger.Load().LogAttrs(context.Background(), level, "request completed", attrs...)
+	})
+	return emitted
+}
+
+func (r *RequestRecord) attrs(status int, err error) []slog.Attr {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	retries := r.attempts - 1
+	if retries < 0 {
+		retries = 0
+	}
+	attrs := []slog.Attr{
+		slog.String("method", r.method),
+		slog.String("path", r.path),
+		slog.String("handler", r.handler),
+		slog.String("model", r.model),
+		slog.Bool("stream", r.stream),
+		slog.String("provider", r.provider),
+		slog.String("auth_id", r.authID),
+		slog.Int("attempts", r.attempts),
+		slog.Int("retries", retries),
+		slog.Int64("upstream_latency_ms", r.upstreamLatency.Milliseconds()),
+		slog.Int64("duration_ms", time.Since(r.startedAt).Milliseconds()),
+		slog.Int64("prompt_tokens", r.promptTokens),
+		slog.Int64("completion_tokens", r.completionTokens),
+		slog.Int64("reasoning_tokens", r.reasoningTokens),
+		slog.Int64("cached_tokens", r.cachedTokens),
+		slog.Int64("total_tokens", r.totalTokens),
+		slog.Int("status", status),
+	}
+	if r.requestID != "" {
+		attrs = append(attrs, slog.String(requestIDField, r.requestID))
+	}
+	if r.thinkingBudget != nil {
+		attrs = append(attrs, slog.Int64("thinking_budget", *r.thinkingBudget))
+	}
+	if r.reasoningEffort != "" {
+		attrs = append(attrs, slog.String("reasoning_effort", r.reasoningEffort))
+	}
+	if r.cacheHit {
+		attrs = append(attrs, slog.Bool("cache_hit", true))
+	}
+	if err != nil {
+		msg := err.Error()
+		if len(msg) > maxRecordErrorLength {
+			msg = msg[:maxRecordErrorLength] + "...(truncated)"
+		}
+		attrs = append(attrs, slog.String("error", msg))
+	}
+	return attrs
+}
@@ -0,0 +1,337 @@
+// Package metrics exposes request, latency and token metrics in the Prometheus text
+// exposition format. Collection is disabled by default so deployments that do not
+// scrape metrics pay only an atomic load per request.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+)
+
+// maxModelLabels bounds the number of distinct model label values. Models are supplied by
+// clients, so once the limit is reached unseen models are reported as "other".
+const maxModelLabels = 200
+
+const otherLabel = "other"
+
+var (
+	enabled atomic.Bool
+
+	defaultRegistry = newRegistry()
+)
+
+// SetEnabled toggles metric collection.
+func SetEnabled(value bool) { enabled.Store(value) }
+
+// Enabled reports whether metrics are being collected.
+func Enabled() bool { return enabled.Load() }
+
+// ObserveRequest records a completed request described by snapshot with the final HTTP status.
+func ObserveRequest(snapshot logging.RequestSnapshot, status int) {
+	if !enabled.Load() {
+		return
+	}
+	defaultRegistry.observe(snapshot, status)
+}
+
+// WriteText writes all collected metrics to w in the Prometheus text format.
+func WriteText(w io.Writer) error {
+	return defaultRegistry.write(w, false)
+}
+
+// WriteOpenMetrics writes all collected metrics to w in the OpenMetrics text format, which
+// adds the request ID of a recent request to each upstream latency bucket as an exemplar.
+// The caller appends further families, such as gauges, and ends the exposition with WriteEOF.
+func WriteOpenMetrics(w io.Writer) error {
+	return defaultRegistry.write(w, true)
+}
+
+// WriteEOF terminates an OpenMetrics exposition.
+func WriteEOF(w io.Writer) error {
+	_, err := io.WriteString(w, "# EOF\n")
+	return err
+}
+
+// AcceptsOpenMetrics reports whether an Accept header asks for the OpenMetrics format.
+func AcceptsOpenMetrics(accept string) bool {
+	return strings.Contains(accept, "application/openmetrics-text")
+}
+
+// GaugeSample is one labelled value of a gauge computed at scrape time.
+type GaugeSample struct {
+	Values []string
+	Value  float64
+}
+
+// WriteGauge writes a gauge whose samples the caller computes at scrape time, for state
+// owned outside this package. Samples are written in the order given.
+func WriteGauge(w io.Writer, name, help string, labels []string, samples []GaugeSample) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	for _, s := range samples {
+		fmt.Fprintf(&b, "%s%s %s\n", name, formatLabels(labels, s.Values), formatFloat(s.Value))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ContentType is the media type of the output produced by WriteText.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// OpenMetricsContentType is the media type of the output produced by WriteOpenMetrics.
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+var (
+	latencyBuckets  = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	thinkingBuckets = []float64{0, 1024, 2048, 4096, 8192, 16384, 32768}
+)
+
+type registry struct {
+	mu     sync.Mutex
+	models map[string]struct{}
+
+	requests        *counterVec
+	retries         *counterVec
+	tokens          *counterVec
+	cacheHits       *counterVec
+	upstreamLatency *histogramVec
+	thinkingBudget  *histogramVec
+}
+
+func newRegistry() *registry {
+	return &registry{
+		models:          make(map[string]struct{}),
+		requests:        newCounterVec("cliproxy_requests_total", "Completed API requests by model, provider and HTTP status.", "model", "provider", "status"),
+		retries:         newCounterVec("cliproxy_upstream_retries_total", "Upstream attempts beyond the first, by provider.", "provider"),
+		tokens:          newCounterVec("cliproxy_tokens_total", "Tokens reported by upstream providers, by model and token type.", "model", "type"),
+		cacheHits:       newCounterVec("cliproxy_response_cache_hits_total", "Requests served from the response cache without an upstream call, by model.", "model"),
+		upstreamLatency: newHistogramVec("cliproxy_upstream_latency_seconds", "Latency of the final upstream attempt, by provider.", latencyBuckets, "provider"),
+		thinkingBudget:  newHistogramVec("cliproxy_thinking_budget_tokens", "Thinking budget applied to upstream requests, by model.", thinkingBuckets, "model"),
+	}
+}
+
+func (r *registry) observe(s logging.RequestSnapshot, status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	model := r.modelLabel(s.Model)
+	provider := labelOrUnknown(s.Provider)
+	r.requests.add(1, model, provider, strconv.Itoa(status))
+	if s.CacheHit {
+		r.cacheHits.add(1, model)
+	}
+	if s.Attempts == 0 {
+		return
+	}
+	if s.Attempts > 1 {
+		r.retries.add(float64(s.Attempts-1), provider)
+	}
+	r.upstreamLatency.observeExemplar(s.UpstreamLatency.Seconds(), s.RequestID, provider)
+	if s.PromptTokens > 0 {
+		r.tokens.add(float64(s.PromptTokens), model, "prompt")
+	}
+	if s.CompletionTokens > 0 {
+		r.tokens.add(float64(s.CompletionTokens), model, "completion")
+	}
+	if s.ThinkingBudget != nil {
+		r.thinkingBudget.observe(float64(*s.ThinkingBudget), model)
+	}
+}
+
+func (r *registry) modelLabel(model string) string {
+	model = labelOrUnknown(model)
+	if _, ok := r.models[model]; ok {
+		return model
+	}
+	if len(r.models) >= maxModelLabels {
+		return otherLabel
+	}
+	r.models[model] = struct{}{}
+	return model
+}
+
+func (r *registry) write(w io.Writer, openMetrics bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	r.requests.write(&b, openMetrics)
+	r.retries.write(&b, openMetrics)
+	r.tokens.write(&b, openMetrics)
+	r.cacheHits.write(&b, openMetrics)
+	r.upstreamLatency.write(&b, openMetrics)
+	r.thinkingBudget.write(&b, openMetrics)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func labelOrUnknown(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+type counterVec struct {
+	name, help string
+	labels     []string
+	series     map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	value  float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, series: make(map[string]*counterSeries)}
+}
+
+func (c *counterVec) add(delta float64, values ...string) {
+	key := strings.Join(values, "\xff")
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{values: values}
+		c.series[key] = s
+	}
+	s.value += delta
+}
+
+func (c *counterVec) write(b *strings.Builder, openMetrics bool) {
+	family := c.name
+	if openMetrics {
+		// OpenMetrics names the counter family without the _total suffix of its samples.
+		family = strings.TrimSuffix(family, "_total")
+	}
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", family, c.help, family)
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		fmt.Fprintf(b, "%s%s %s\n", c.name, formatLabels(c.labels, s.values), formatFloat(s.value))
+	}
+}
+
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64
+	sum    float64
+	count  uint64
+	// exemplars holds the latest exemplar of each bucket, with +Inf last.
+	exemplars []exemplar
+}
+
+// exemplar links a bucket to the request ID of an observation that fell into it.
+type exemplar struct {
+	requestID string
+	value     float64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)}
+}
+
+func (h *histogramVec) observe(value float64, values ...string) {
+	key := strings.Join(values, "\xff")
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{values: values, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// observeExemplar observes value and keeps requestID as the exemplar of the smallest bucket
+// containing it.
+func (h *histogramVec) observeExemplar(value float64, requestID string, values ...string) {
+	h.observe(value, values...)
+	if requestID == "" {
+		return
+	}
+	s := h.series[strings.Join(values, "\xff")]
+	if s.exemplars == nil {
+		s.exemplars = make([]exemplar, len(h.buckets)+1)
+	}
+	bucket := len(h.buckets)
+	for i, upper := range h.buckets {
+		if value <= upper {
+			bucket = i
+			break
+		}
+	}
+	s.exemplars[bucket] = exemplar{requestID: requestID, value: value}
+}
+
+// exemplarSuffix renders the exemplar of bucket i in the OpenMetrics syntax.
+func (s *histogramSeries) exemplarSuffix(i int, openMetrics bool) string {
+	if !openMetrics || s.exemplars == nil || s.exemplars[i].requestID == "" {
+		return ""
+	}
+	e := s.exemplars[i]
+	return fmt.Sprintf(" # {request_id=\"%s\"} %s", labelEscaper.Replace(e.requestID), formatFloat(e.value))
+}
+
+func (h *histogramVec) write(b *strings.Builder, openMetrics bool) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	bucketLabels := append(append([]string(nil), h.labels...), "le")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		for i, upper := range h.buckets {
+			values := append(append([]string(nil), s.values...), formatFloat(upper))
+			fmt.Fprintf(b, "%s_bucket%s %d%s\n", h.name, formatLabels(bucketLabels, values), s.counts[i], s.exemplarSuffix(i, openMetrics))
+		}
+		values := append(append([]string(nil), s.values...), "+Inf")
+		fmt.Fprintf(b, "%s_bucket%s %d%s\n", h.name, formatLabels(bucketLabels, values), s.count, s.exemplarSuffix(len(h.buckets), openMetrics))
+		fmt.Fprintf(b, "%s_sum%s %s\n", h.name, formatLabels(h.labels, s.values), formatFloat(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", h.name, formatLabels(h.labels, s.values), s.count)
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
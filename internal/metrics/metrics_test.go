@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := newRegistry()
+	budget := int64(4096)
+	r.observe(logging.RequestSnapshot{
+		Model:            "gemini-2.5-pro",
+		Provider:         "gemini",
+		Attempts:         3,
+		UpstreamLatency:  300 * time.Millisecond,
+		PromptTokens:     10,
+		CompletionTokens: 4,
+		ThinkingBudget:   &budget,
+	}, 200)
+
+	var b strings.Builder
+	if err := r.write(&b, false); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE cliproxy_requests_total counter",
+		`cliproxy_requests_total{model="gemini-2.5-pro",provider="gemini",status="200"} 1`,
+		`cliproxy_upstream_retries_total{provider="gemini"} 2`,
+		`cliproxy_tokens_total{model="gemini-2.5-pro",type="prompt"} 10`,
+		`cliproxy_tokens_total{model="gemini-2.5-pro",type="completion"} 4`,
+		`cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="0.25"} 0`,
+		`cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="0.5"} 1`,
+		`cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="+Inf"} 1`,
+		`cliproxy_upstream_latency_seconds_count{provider="gemini"} 1`,
+		`cliproxy_thinking_budget_tokens_bucket{model="gemini-2.5-pro",le="4096"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestRegistryBoundsModelLabels(t *testing.T) {
+	r := newRegistry()
+	for i := 0; i < maxModelLabels+5; i++ {
+		r.observe(logging.RequestSnapshot{Model: fmt.Sprintf("model-%d", i)}, 400)
+	}
+	if len(r.models) != maxModelLabels {
+		t.Fatalf("Expected %d model labels, got %d", maxModelLabels, len(r.models))
+	}
+	var b strings.Builder
+	_ = r.write(&b, false)
+	if !strings.Contains(b.String(), `cliproxy_requests_total{model="other",provider="unknown",status="400"} 5`) {
+		t.Fatalf("Expected overflow models to collapse into \"other\":\n%s", b.String())
+	}
+}
+
+func TestRegistryWriteOpenMetricsExemplars(t *testing.T) {
+	r := newRegistry()
+	r.observe(logging.RequestSnapshot{
+		RequestID:       "0f8fad5b-d9cb-469f-a165-70867728950e",
+		Model:           "gemini-2.5-pro",
+		Provider:        "gemini",
+		Attempts:        1,
+		UpstreamLatency: 300 * time.Millisecond,
+	}, 200)
+
+	var b strings.Builder
+	_ = r.write(&b, true)
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE cliproxy_requests counter",
+		`cliproxy_requests_total{model="gemini-2.5-pro",provider="gemini",status="200"} 1`,
+		`cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="0.5"} 1 # {request_id="0f8fad5b-d9cb-469f-a165-70867728950e"} 0.3`,
+		`cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="1"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+
+	b.Reset()
+	_ = r.write(&b, false)
+	if strings.Contains(b.String(), "request_id") {
+		t.Errorf("Expected no exemplars in the Prometheus text format:\n%s", b.String())
+	}
+}
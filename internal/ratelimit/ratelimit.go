@@ -0,0 +1,153 @@
+// Package ratelimit provides per-client token-bucket rate limiting for inbound API requests.
+// The Limiter interface decouples bucket storage from the HTTP middleware so the in-memory
+// implementation can be swapped for a shared backend such as Redis.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// Limit describes a token-bucket budget for a single client.
+type Limit struct {
+	// RequestsPerMinute is the sustained refill rate of the bucket.
+	RequestsPerMinute int
+	// Burst is the bucket capacity.
+	Burst int
+}
+
+// Unlimited reports whether the limit imposes no restriction.
+func (l Limit) Unlimited() bool {
+	return l.RequestsPerMinute <= 0
+}
+
+// Limiter decides whether a request identified by key may proceed under limit.
+// When the request is rejected, retryAfter reports how long until a token is available.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// Policy maps client keys to their configured limits.
+type Policy struct {
+	// Header names the request header used to identify clients; empty means the API key.
+	Header     string
+	limits     map[string]Limit
+	userLimits map[string]Limit
+}
+
+// NewPolicy builds a lookup policy from configuration. Entries with an empty key or a
+// non-positive rate are dropped, so those clients bypass the limiter.
+func NewPolicy(cfg config.RateLimitConfig) *Policy {
+	p := &Policy{
+		Header:     strings.TrimSpace(cfg.Header),
+		limits:     make(map[string]Limit, len(cfg.Keys)),
+		userLimits: make(map[string]Limit),
+	}
+	for _, entry := range cfg.Keys {
+		key := strings.TrimSpace(entry.Key)
+		if key == "" {
+			continue
+		}
+		if limit, ok := newLimit(entry.RequestsPerMinute, entry.Burst); ok {
+			p.limits[key] = limit
+		}
+		if limit, ok := newLimit(entry.PerUserRequestsPerMinute, entry.PerUserBurst); ok {
+			p.userLimits[key] = limit
+		}
+	}
+	return p
+}
+
+func newLimit(requestsPerMinute, burst int) (Limit, bool) {
+	if requestsPerMinute <= 0 {
+		return Limit{}, false
+	}
+	if burst <= 0 {
+		burst = requestsPerMinute
+	}
+	return Limit{RequestsPerMinute: requestsPerMinute, Burst: burst}, true
+}
+
+// Enabled reports whether any client is limited.
+func (p *Policy) Enabled() bool {
+	return p != nil && (len(p.limits) > 0 || len(p.userLimits) > 0)
+}
+
+// LookupUser returns the limit applied to each end user of key.
+func (p *Policy) LookupUser(key string) (Limit, bool) {
+	if p == nil || key == "" {
+		return Limit{}, false
+	}
+	limit, ok := p.userLimits[key]
+	return limit, ok
+}
+
+// UserKey returns the limiter key of end user under client key.
+func UserKey(key, user string) string {
+	return key + "\x00user:" + user
+}
+
+// Lookup returns the limit configured for key.
+func (p *Policy) Lookup(key string) (Limit, bool) {
+	if p == nil || key == "" {
+		return Limit{}, false
+	}
+	limit, ok := p.limits[key]
+	return limit, ok
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// MemoryLimiter keeps token buckets in process memory.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewMemoryLimiter constructs an in-memory token-bucket limiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Allow consumes a token from the bucket for key, refilling it for the time elapsed
+// since the previous call. Buckets are recreated when their limit changes.
+func (m *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	if limit.Unlimited() {
+		return true, 0, nil
+	}
+	capacity := float64(limit.Burst)
+	if capacity < 1 {
+		capacity = 1
+	}
+	rate := float64(limit.RequestsPerMinute) / float64(time.Minute)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	b, ok := m.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: capacity, last: now, limit: limit}
+		m.buckets[key] = b
+	} else if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)*rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration(math.Ceil((1 - b.tokens) / rate))
+	return false, wait, nil
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func newTestLimiter(start time.Time) (*MemoryLimiter, *time.Time) {
+	now := start
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestMemoryLimiter_Burst(t *testing.T) {
+	limiter, _ := newTestLimiter(time.Unix(0, 0))
+	limit := Limit{RequestsPerMinute: 60, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := limiter.Allow(context.Background(), "team-a", limit); !allowed {
+			t.Fatalf("Expected request %d within burst to be allowed", i+1)
+		}
+	}
+	allowed, retryAfter, _ := limiter.Allow(context.Background(), "team-a", limit)
+	if allowed {
+		t.Fatal("Expected request beyond burst to be rejected")
+	}
+	if retryAfter != time.Second {
+		t.Fatalf("Expected retry after 1s at 60 rpm, got %v", retryAfter)
+	}
+	if allowed, _, _ := limiter.Allow(context.Background(), "team-b", limit); !allowed {
+		t.Fatal("Expected buckets to be tracked per key")
+	}
+}
+
+func TestMemoryLimiter_Refill(t *testing.T) {
+	limiter, now := newTestLimiter(time.Unix(0, 0))
+	limit := Limit{RequestsPerMinute: 30, Burst: 2}
+
+	limiter.Allow(context.Background(), "k", limit)
+	limiter.Allow(context.Background(), "k", limit)
+	if allowed, _, _ := limiter.Allow(context.Background(), "k", limit); allowed {
+		t.Fatal("Expected empty bucket to reject")
+	}
+
+	*now = now.Add(time.Second)
+	if allowed, retryAfter, _ := limiter.Allow(context.Background(), "k", limit); allowed || retryAfter != time.Second {
+		t.Fatalf("Expected half a token after 1s at 30 rpm, got allowed=%v retryAfter=%v", allowed, retryAfter)
+	}
+
+	*now = now.Add(time.Second)
+	if allowed, _, _ := limiter.Allow(context.Background(), "k", limit); !allowed {
+		t.Fatal("Expected one token to refill after 2s at 30 rpm")
+	}
+
+	// A long idle period refills up to the burst capacity only.
+	*now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if allowed, _, _ := limiter.Allow(context.Background(), "k", limit); !allowed {
+			t.Fatalf("Expected request %d after idle refill to be allowed", i+1)
+		}
+	}
+	if allowed, _, _ := limiter.Allow(context.Background(), "k", limit); allowed {
+		t.Fatal("Expected refill to be capped at burst")
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	policy := NewPolicy(config.RateLimitConfig{Keys: []config.RateLimitKey{
+		{Key: "limited", RequestsPerMinute: 120},
+		{Key: "unlimited", RequestsPerMinute: 0, Burst: 5},
+		{Key: "", RequestsPerMinute: 10},
+	}})
+
+	limit, ok := policy.Lookup("limited")
+	if !ok || limit.RequestsPerMinute != 120 || limit.Burst != 120 {
+		t.Fatalf("Expected burst to default to rpm, got %+v (ok=%v)", limit, ok)
+	}
+	if _, ok = policy.Lookup("unlimited"); ok {
+		t.Fatal("Expected non-positive rate to be treated as unlimited")
+	}
+	if _, ok = policy.Lookup("unknown"); ok {
+		t.Fatal("Expected unknown keys to bypass the limiter")
+	}
+}
+
+func TestNewPolicy_PerUserLimits(t *testing.T) {
+	policy := NewPolicy(config.RateLimitConfig{Keys: []config.RateLimitKey{
+		{Key: "tenant", PerUserRequestsPerMinute: 30},
+	}})
+
+	if !policy.Enabled() {
+		t.Fatal("Expected a per-user limit alone to enable the policy")
+	}
+	if _, ok := policy.Lookup("tenant"); ok {
+		t.Fatal("Expected no key limit without requests-per-minute")
+	}
+	limit, ok := policy.LookupUser("tenant")
+	if !ok || limit.RequestsPerMinute != 30 || limit.Burst != 30 {
+		t.Fatalf("Expected per-user burst to default to its rpm, got %+v (ok=%v)", limit, ok)
+	}
+	if UserKey("tenant", "a") == UserKey("tenant", "b") || UserKey("tenant", "a") == "tenant" {
+		t.Fatal("Expected distinct limiter keys per user")
+	}
+}
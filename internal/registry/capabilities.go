@@ -0,0 +1,92 @@
+package registry
+
+import "strings"
+
+// ModelCapabilities summarises what a model accepts and produces, derived from its metadata.
+// LogitBias depends on the providers serving the model and is filled in by the caller.
+type ModelCapabilities struct {
+	ID               string           `json:"id"`
+	Object           string           `json:"object"`
+	OwnedBy          string           `json:"owned_by,omitempty"`
+	Type             string           `json:"type,omitempty"`
+	ContextLength    int              `json:"context_length,omitempty"`
+	MaxOutputTokens  int              `json:"max_output_tokens,omitempty"`
+	Thinking         *ThinkingSupport `json:"thinking"`
+	Tools            bool             `json:"tools"`
+	Vision           bool             `json:"vision"`
+	LogitBias        bool             `json:"logit_bias"`
+	InputModalities  []string         `json:"input_modalities"`
+	OutputModalities []string         `json:"output_modalities"`
+}
+
+// visionTypes lists the model types whose chat models accept image input.
+var visionTypes = map[string]struct{}{
+	"claude": {},
+	"gemini": {},
+	"openai": {},
+}
+
+// CapabilitiesOf derives the capabilities of a model. Limits come from whichever of the
+// OpenAI-style and Gemini-style fields the model sets. Tool support follows the supported
+// parameters when the model lists them; image input is assumed for Claude, Gemini and OpenAI
+// chat models and for models named as vision models, image output for image models and
+// audio output for text-to-speech models.
+func CapabilitiesOf(info *ModelInfo) ModelCapabilities {
+	caps := ModelCapabilities{
+		ID:               info.ID,
+		Object:           "model.capabilities",
+		OwnedBy:          info.OwnedBy,
+		Type:             info.Type,
+		ContextLength:    info.ContextLength,
+		MaxOutputTokens:  info.MaxCompletionTokens,
+		InputModalities:  []string{"text"},
+		OutputModalities: []string{"text"},
+	}
+	if caps.ContextLength == 0 {
+		caps.ContextLength = info.InputTokenLimit
+	}
+	if caps.MaxOutputTokens == 0 {
+		caps.MaxOutputTokens = info.OutputTokenLimit
+	}
+	if info.Thinking != nil {
+		thinking := *info.Thinking
+		caps.Thinking = &thinking
+	}
+
+	id := strings.ToLower(info.ID)
+	if isEmbeddingModel(info) {
+		caps.OutputModalities = []string{"embedding"}
+		return caps
+	}
+	caps.Tools = len(info.SupportedParameters) == 0 || containsString(info.SupportedParameters, "tools")
+	_, visionType := visionTypes[info.Type]
+	caps.Vision = visionType || strings.Contains(id, "vision") || strings.Contains(id, "-vl")
+	if caps.Vision {
+		caps.InputModalities = append(caps.InputModalities, "image")
+	}
+	if strings.Contains(id, "-image") {
+		caps.OutputModalities = append(caps.OutputModalities, "image")
+	}
+	if strings.Contains(id, "-tts") {
+		caps.OutputModalities = append(caps.OutputModalities, "audio")
+	}
+	return caps
+}
+
+// isEmbeddingModel reports whether a model only produces embeddings.
+func isEmbeddingModel(info *ModelInfo) bool {
+	if strings.Contains(strings.ToLower(info.ID), "embedding") {
+		return true
+	}
+	methods := info.SupportedGenerationMethods
+	return containsString(methods, "embedContent") && !containsString(methods, "generateContent")
+}
+
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
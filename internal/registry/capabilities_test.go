@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapabilitiesOf(t *testing.T) {
+	gemini := CapabilitiesOf(&ModelInfo{
+		ID:               "gemini-2.5-flash",
+		Type:             "gemini",
+		InputTokenLimit:  1048576,
+		OutputTokenLimit: 65536,
+		Thinking:         &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+	})
+	if gemini.ContextLength != 1048576 || gemini.MaxOutputTokens != 65536 {
+		t.Fatalf("Expected the Gemini token limits to be used, got %+v", gemini)
+	}
+	if gemini.Thinking == nil || gemini.Thinking.Max != 24576 || !gemini.Tools || !gemini.Vision {
+		t.Fatalf("Expected thinking, tools and vision for a Gemini chat model, got %+v", gemini)
+	}
+	if !reflect.DeepEqual(gemini.InputModalities, []string{"text", "image"}) {
+		t.Fatalf("Expected text and image input, got %v", gemini.InputModalities)
+	}
+
+	embedding := CapabilitiesOf(&ModelInfo{ID: "gemini-embedding-001", Type: "gemini"})
+	if embedding.Tools || embedding.Thinking != nil || !reflect.DeepEqual(embedding.OutputModalities, []string{"embedding"}) {
+		t.Fatalf("Expected an embedding model without tools or thinking, got %+v", embedding)
+	}
+
+	restricted := CapabilitiesOf(&ModelInfo{ID: "qwen3-coder", Type: "qwen", SupportedParameters: []string{"temperature"}})
+	if restricted.Tools || restricted.Vision {
+		t.Fatalf("Expected no tools or vision for a model that does not list them, got %+v", restricted)
+	}
+}
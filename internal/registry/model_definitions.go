@@ -16,6 +16,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4.5 Haiku",
 			ContextLength:       200000,
 			MaxCompletionTokens: 64000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 		},
 		{
 			ID:                  "claude-sonnet-4-5-20250929",
@@ -26,6 +27,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4.5 Sonnet",
 			ContextLength:       200000,
 			MaxCompletionTokens: 64000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 		},
 		{
 			ID:                  "claude-sonnet-4-5-thinking",
@@ -36,6 +38,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4.5 Sonnet Thinking",
 			ContextLength:       200000,
 			MaxCompletionTokens: 64000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 			Thinking:            &ThinkingSupport{Min: 1024, Max: 100000, ZeroAllowed: false, DynamicAllowed: true},
 		},
 		{
@@ -47,6 +50,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4.5 Opus Thinking",
 			ContextLength:       200000,
 			MaxCompletionTokens: 64000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 			Thinking:            &ThinkingSupport{Min: 1024, Max: 100000, ZeroAllowed: false, DynamicAllowed: true},
 		},
 		{
@@ -58,6 +62,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4.5 Opus Thinking Low",
 			ContextLength:       200000,
 			MaxCompletionTokens: 64000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 			Thinking:            &ThinkingSupport{Min: 1024, Max: 100000, ZeroAllowed: false, DynamicAllowed: true},
 		},
 		{
@@ -69,6 +74,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4.5 Opus Thinking Medium",
 			ContextLength:       200000,
 			MaxCompletionTokens: 64000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 			Thinking:            &ThinkingSupport{Min: 1024, Max: 100000, ZeroAllowed: false, DynamicAllowed: true},
 		},
 		{
@@ -80,6 +86,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4.5 Opus Thinking High",
 			ContextLength:       200000,
 			MaxCompletionTokens: 64000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 			Thinking:            &ThinkingSupport{Min: 1024, Max: 100000, ZeroAllowed: false, DynamicAllowed: true},
 		},
 		{
@@ -92,6 +99,7 @@ func GetClaudeModels() []*ModelInfo {
 			Description:         "Premium model combining maximum intelligence with practical performance",
 			ContextLength:       200000,
 			MaxCompletionTokens: 64000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 		},
 		{
 			ID:                  "claude-opus-4-1-20250805",
@@ -102,6 +110,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4.1 Opus",
 			ContextLength:       200000,
 			MaxCompletionTokens: 32000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 		},
 		{
 			ID:                  "claude-opus-4-20250514",
@@ -112,6 +121,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4 Opus",
 			ContextLength:       200000,
 			MaxCompletionTokens: 32000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 		},
 		{
 			ID:                  "claude-sonnet-4-20250514",
@@ -122,6 +132,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 4 Sonnet",
 			ContextLength:       200000,
 			MaxCompletionTokens: 64000,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 		},
 		{
 			ID:                  "claude-3-7-sonnet-20250219",
@@ -132,6 +143,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 3.7 Sonnet",
 			ContextLength:       128000,
 			MaxCompletionTokens: 8192,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 		},
 		{
 			ID:                  "claude-3-5-haiku-20241022",
@@ -142,6 +154,7 @@ func GetClaudeModels() []*ModelInfo {
 			DisplayName:         "Claude 3.5 Haiku",
 			ContextLength:       128000,
 			MaxCompletionTokens: 8192,
+			Sampling:            &SamplingRange{MaxTemperature: 1, MaxTopP: 1},
 		},
 	}
 }
@@ -163,6 +176,8 @@ func GetGeminiModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-2.5-flash",
@@ -178,6 +193,8 @@ func GetGeminiModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-2.5-flash-lite",
@@ -193,6 +210,8 @@ func GetGeminiModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-3-pro-preview",
@@ -208,6 +227,8 @@ func GetGeminiModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-3-pro-image-preview",
@@ -223,6 +244,22 @@ func GetGeminiModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
+		},
+		{
+			ID:                         "gemini-embedding-001",
+			Object:                     "model",
+			Created:                    1752537600,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-embedding-001",
+			Version:                    "001",
+			DisplayName:                "Gemini Embedding 001",
+			Description:                "Obtain a distributed representation of a text.",
+			InputTokenLimit:            2048,
+			OutputTokenLimit:           1,
+			SupportedGenerationMethods: []string{"embedContent", "batchEmbedContents", "countTokens"},
 		},
 	}
 }
@@ -243,6 +280,8 @@ func GetGeminiVertexModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-2.5-flash",
@@ -258,6 +297,8 @@ func GetGeminiVertexModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-2.5-flash-lite",
@@ -273,6 +314,8 @@ func GetGeminiVertexModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-3-pro-preview",
@@ -288,6 +331,8 @@ func GetGeminiVertexModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-3-pro-image-preview",
@@ -303,6 +348,8 @@ func GetGeminiVertexModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 	}
 }
@@ -324,6 +371,8 @@ func GetGeminiCLIModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-2.5-flash",
@@ -339,6 +388,8 @@ func GetGeminiCLIModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-2.5-flash-lite",
@@ -354,6 +405,8 @@ func GetGeminiCLIModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-3-pro-preview",
@@ -369,6 +422,8 @@ func GetGeminiCLIModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 	}
 }
@@ -390,6 +445,8 @@ func GetAIStudioModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-2.5-flash",
@@ -405,6 +462,8 @@ func GetAIStudioModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-2.5-flash-lite",
@@ -420,6 +479,8 @@ func GetAIStudioModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-3-pro-preview",
@@ -435,6 +496,8 @@ func GetAIStudioModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-pro-latest",
@@ -450,6 +513,8 @@ func GetAIStudioModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-flash-latest",
@@ -465,6 +530,8 @@ func GetAIStudioModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-flash-lite-latest",
@@ -480,6 +547,8 @@ func GetAIStudioModels() []*ModelInfo {
 			OutputTokenLimit:           65536,
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 512, Max: 24576, ZeroAllowed: true, DynamicAllowed: true},
+			Penalty:                    &PenaltyRange{Min: -2, Max: 2},
+			Sampling:                   &SamplingRange{MaxTemperature: 2, MaxTopP: 1},
 		},
 		{
 			ID:                         "gemini-2.5-flash-image-preview",
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	misc "github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
@@ -50,6 +51,27 @@ type ModelInfo struct {
 	// Thinking holds provider-specific reasoning/thinking budget capabilities.
 	// This is optional and currently used for Gemini thinking budget normalization.
 	Thinking *ThinkingSupport `json:"thinking,omitempty"`
+
+	// Penalty holds the accepted range of frequency and presence penalties. Nil means the
+	// range is unknown and values are forwarded unchanged.
+	Penalty *PenaltyRange `json:"penalty,omitempty"`
+
+	// Sampling holds the accepted range of temperature and top_p. Nil means the range is
+	// unknown and configured defaults are applied unclamped.
+	Sampling *SamplingRange `json:"sampling,omitempty"`
+}
+
+// PenaltyRange describes the inclusive range of frequency and presence penalties a model accepts.
+type PenaltyRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// SamplingRange describes the inclusive upper bounds of temperature and top_p for a model.
+// Both parameters start at zero.
+type SamplingRange struct {
+	MaxTemperature float64 `json:"max_temperature"`
+	MaxTopP        float64 `json:"max_top_p"`
 }
 
 // ThinkingSupport describes a model family's supported internal reasoning budget range.
@@ -63,6 +85,9 @@ type ThinkingSupport struct {
 	ZeroAllowed bool `json:"zero_allowed,omitempty"`
 	// DynamicAllowed indicates whether -1 is a valid value (dynamic thinking budget).
 	DynamicAllowed bool `json:"dynamic_allowed,omitempty"`
+	// Step is the required granularity of the budget; approximated or clamped
+	// values are rounded down to a multiple of Step. Zero means no granularity.
+	Step int `json:"step,omitempty"`
 }
 
 // ModelRegistration tracks a model's availability
@@ -91,6 +116,8 @@ type ModelRegistry struct {
 	clientProviders map[string]string
 	// mutex ensures thread-safe access to the registry
 	mutex *sync.RWMutex
+	// version increments whenever registered model metadata may have changed
+	version atomic.Uint64
 }
 
 // Global model registry instance
@@ -118,6 +145,7 @@ func GetGlobalRegistry() *ModelRegistry {
 func (r *ModelRegistry) RegisterClient(clientID, clientProvider string, models []*ModelInfo) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
+	defer r.version.Add(1)
 
 	provider := strings.ToLower(clientProvider)
 	uniqueModelIDs := make([]string, 0, len(models))
@@ -387,6 +415,13 @@ func (r *ModelRegistry) UnregisterClient(clientID string) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	r.unregisterClientInternal(clientID)
+	r.version.Add(1)
+}
+
+// Version returns a counter that changes whenever registered model metadata may have
+// changed. Callers can use it to invalidate caches derived from GetModelInfo.
+func (r *ModelRegistry) Version() uint64 {
+	return r.version.Load()
 }
 
 // unregisterClientInternal performs the actual client unregistration (internal, no locking)
@@ -563,45 +598,109 @@ func (r *ModelRegistry) GetAvailableModels(handlerType string) []map[string]any
 	quotaExpiredDuration := 5 * time.Minute
 
 	for _, registration := range r.models {
-		// Check if model has any non-quota-exceeded clients
-		availableClients := registration.Count
-		now := time.Now()
-
-		// Count clients that have exceeded quota but haven't recovered yet
-		expiredClients := 0
-		for _, quotaTime := range registration.QuotaExceededClients {
-			if quotaTime != nil && now.Sub(*quotaTime) < quotaExpiredDuration {
-				expiredClients++
+		// Include models that have available clients, or those solely cooling down.
+		if listed, _ := registrationAvailability(registration, time.Now(), quotaExpiredDuration); listed {
+			model := r.convertModelToMap(registration.Info, handlerType)
+			if model != nil {
+				models = append(models, model)
 			}
 		}
+	}
 
-		cooldownSuspended := 0
-		otherSuspended := 0
-		if registration.SuspendedClients != nil {
-			for _, reason := range registration.SuspendedClients {
-				if strings.EqualFold(reason, "quota") {
-					cooldownSuspended++
-					continue
-				}
-				otherSuspended++
+	return models
+}
+
+// GetAllModels returns every registered model for the handler type, including models
+// whose clients are currently exhausted or suspended, sorted by model ID.
+// Each entry carries an "available" flag; when the model metadata lacks an owner the
+// "owned_by" field falls back to the provider supplying the most clients.
+// Parameters:
+//   - handlerType: The handler type to filter models for (e.g., "openai", "claude", "gemini")
+//
+// Returns:
+//   - []map[string]any: List of model maps in the handler's format
+func (r *ModelRegistry) GetAllModels(handlerType string) []map[string]any {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ids := make([]string, 0, len(r.models))
+	for id, registration := range r.models {
+		if registration != nil && registration.Info != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	now := time.Now()
+	models := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		registration := r.models[id]
+		model := r.convertModelToMap(registration.Info, handlerType)
+		if model == nil {
+			continue
+		}
+		if owner, ok := model["owned_by"].(string); ok && owner == "" {
+			if provider := primaryProvider(registration.Providers); provider != "" {
+				model["owned_by"] = provider
 			}
 		}
+		_, available := registrationAvailability(registration, now, 5*time.Minute)
+		model["available"] = available
+		models = append(models, model)
+	}
+	return models
+}
+
+// registrationAvailability reports whether the model should be listed as available
+// (it has usable clients or is solely cooling down) and whether at least one client
+// can serve it right now.
+func registrationAvailability(registration *ModelRegistration, now time.Time, quotaExpiredDuration time.Duration) (listed bool, available bool) {
+	availableClients := registration.Count
 
-		effectiveClients := availableClients - expiredClients - otherSuspended
-		if effectiveClients < 0 {
-			effectiveClients = 0
+	// Count clients that have exceeded quota but haven't recovered yet
+	expiredClients := 0
+	for _, quotaTime := range registration.QuotaExceededClients {
+		if quotaTime != nil && now.Sub(*quotaTime) < quotaExpiredDuration {
+			expiredClients++
 		}
+	}
 
-		// Include models that have available clients, or those solely cooling down.
-		if effectiveClients > 0 || (availableClients > 0 && (expiredClients > 0 || cooldownSuspended > 0) && otherSuspended == 0) {
-			model := r.convertModelToMap(registration.Info, handlerType)
-			if model != nil {
-				models = append(models, model)
+	cooldownSuspended := 0
+	otherSuspended := 0
+	if registration.SuspendedClients != nil {
+		for _, reason := range registration.SuspendedClients {
+			if strings.EqualFold(reason, "quota") {
+				cooldownSuspended++
+				continue
 			}
+			otherSuspended++
 		}
 	}
 
-	return models
+	effectiveClients := availableClients - expiredClients - otherSuspended
+	if effectiveClients < 0 {
+		effectiveClients = 0
+	}
+	coolingDown := availableClients > 0 && (expiredClients > 0 || cooldownSuspended > 0) && otherSuspended == 0
+	listed = effectiveClients > 0 || coolingDown
+	available = effectiveClients-cooldownSuspended > 0
+	return listed, available
+}
+
+// primaryProvider returns the provider with the most clients, breaking ties by name.
+func primaryProvider(providers map[string]int) string {
+	best := ""
+	bestCount := 0
+	for name, count := range providers {
+		if count <= 0 {
+			continue
+		}
+		if count > bestCount || (count == bestCount && name < best) {
+			best = name
+			bestCount = count
+		}
+	}
+	return best
 }
 
 // GetModelCount returns the number of available clients for a specific model
@@ -695,6 +794,25 @@ func (r *ModelRegistry) GetModelProviders(modelID string) []string {
 	return result
 }
 
+// GetModelsForClient returns copies of the model metadata currently registered for the client.
+// Returns nil if the client is unknown to the registry.
+func (r *ModelRegistry) GetModelsForClient(clientID string) []*ModelInfo {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	modelIDs := r.clientModels[clientID]
+	if len(modelIDs) == 0 {
+		return nil
+	}
+	models := make([]*ModelInfo, 0, len(modelIDs))
+	for _, id := range modelIDs {
+		if reg, ok := r.models[id]; ok && reg != nil && reg.Info != nil {
+			models = append(models, cloneModelInfo(reg.Info))
+		}
+	}
+	return models
+}
+
 // GetModelInfo returns the registered ModelInfo for the given model ID, if present.
 // Returns nil if the model is unknown to the registry.
 func (r *ModelRegistry) GetModelInfo(modelID string) *ModelInfo {
@@ -706,6 +824,22 @@ func (r *ModelRegistry) GetModelInfo(modelID string) *ModelInfo {
 	return nil
 }
 
+// GetThinkingModelIDs returns the sorted IDs of all registered models whose metadata
+// declares Thinking support, regardless of current client availability.
+func (r *ModelRegistry) GetThinkingModelIDs() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ids := make([]string, 0)
+	for id, registration := range r.models {
+		if registration != nil && registration.Info != nil && registration.Info.Thinking != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // convertModelToMap converts ModelInfo to the appropriate format for different handler types
 func (r *ModelRegistry) convertModelToMap(model *ModelInfo, handlerType string) map[string]any {
 	if model == nil {
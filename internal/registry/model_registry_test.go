@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"sync"
+	"testing"
+)
+
+func newTestRegistry() *ModelRegistry {
+	return &ModelRegistry{
+		models:          make(map[string]*ModelRegistration),
+		clientModels:    make(map[string][]string),
+		clientProviders: make(map[string]string),
+		mutex:           &sync.RWMutex{},
+	}
+}
+
+func TestGetAllModels_MergesAndFlagsUnavailable(t *testing.T) {
+	r := newTestRegistry()
+	r.RegisterClient("gemini-a", "gemini", []*ModelInfo{
+		{ID: "gemini-2.5-pro", Object: "model", OwnedBy: "google"},
+		{ID: "shared-model", Object: "model", OwnedBy: "google"},
+	})
+	r.RegisterClient("compat-a", "openrouter", []*ModelInfo{
+		{ID: "shared-model", Object: "model", OwnedBy: "google"},
+		{ID: "custom-model", Object: "model"},
+	})
+	r.SuspendClientModel("gemini-a", "gemini-2.5-pro", "quota")
+
+	models := r.GetAllModels("openai")
+	if len(models) != 3 {
+		t.Fatalf("Expected 3 de-duplicated models, got %d: %v", len(models), models)
+	}
+	byID := make(map[string]map[string]any, len(models))
+	for _, m := range models {
+		byID[m["id"].(string)] = m
+	}
+
+	if got := byID["gemini-2.5-pro"]["available"]; got != false {
+		t.Errorf("Expected exhausted model to be flagged unavailable, got %v", got)
+	}
+	if got := byID["shared-model"]["available"]; got != true {
+		t.Errorf("Expected shared model to be available, got %v", got)
+	}
+	if got := byID["custom-model"]["owned_by"]; got != "openrouter" {
+		t.Errorf("Expected owned_by to fall back to provider, got %v", got)
+	}
+	if models[0]["id"] != "custom-model" || models[2]["id"] != "shared-model" {
+		t.Errorf("Expected models sorted by id, got %v", models)
+	}
+}
+
+func TestGetModelsForClient(t *testing.T) {
+	r := newTestRegistry()
+	r.RegisterClient("client", "antigravity", []*ModelInfo{{ID: "m1"}, {ID: "m2"}})
+
+	models := r.GetModelsForClient("client")
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 models for client, got %d", len(models))
+	}
+	if r.GetModelsForClient("missing") != nil {
+		t.Fatal("Expected nil for unknown client")
+	}
+}
+
+func TestReload_ReplacesClientsAndKeepsSuspensions(t *testing.T) {
+	r := newTestRegistry()
+	r.RegisterClient("a", "gemini", []*ModelInfo{{ID: "m1"}, {ID: "m2"}})
+	r.RegisterClient("b", "claude", []*ModelInfo{{ID: "m3"}})
+	r.SuspendClientModel("a", "m1", "quota")
+	before := r.Version()
+
+	summary := r.Reload([]ClientModels{{ClientID: "a", Provider: "gemini", Models: []*ModelInfo{{ID: "m1"}, {ID: "m4"}}}})
+	if summary.Clients != 1 || summary.Models != 2 || summary.Version <= before {
+		t.Fatalf("Unexpected summary %+v", summary)
+	}
+	if len(summary.Added) != 1 || summary.Added[0] != "m4" {
+		t.Errorf("Expected m4 to be added, got %v", summary.Added)
+	}
+	if len(summary.Removed) != 2 || summary.Removed[0] != "m2" || summary.Removed[1] != "m3" {
+		t.Errorf("Expected m2 and m3 to be removed, got %v", summary.Removed)
+	}
+	if r.GetModelInfo("m3") != nil || r.ClientSupportsModel("b", "m3") {
+		t.Error("Expected client b to be gone after reload")
+	}
+	if reason := r.models["m1"].SuspendedClients["a"]; reason != "quota" {
+		t.Errorf("Expected suspension of m1 to survive reload, got %q", reason)
+	}
+}
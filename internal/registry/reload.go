@@ -0,0 +1,105 @@
+package registry
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ClientModels is the model list of one client in a registry reload.
+type ClientModels struct {
+	ClientID string
+	Provider string
+	Models   []*ModelInfo
+}
+
+// ReloadSummary describes the outcome of a registry reload.
+type ReloadSummary struct {
+	Clients int      `json:"clients"`
+	Models  int      `json:"models"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Version uint64   `json:"version"`
+}
+
+// Reload replaces every client registration with clients in a single step. The new state is
+// built aside and swapped in under the write lock, so concurrent lookups see either the old or
+// the new registry, never a mix, and the version is bumped once so caches derived from
+// GetModelInfo are invalidated. Quota and suspension marks are kept for clients that still
+// provide the model.
+func (r *ModelRegistry) Reload(clients []ClientModels) ReloadSummary {
+	staged := &ModelRegistry{
+		models:          make(map[string]*ModelRegistration),
+		clientModels:    make(map[string][]string),
+		clientProviders: make(map[string]string),
+	}
+	now := time.Now()
+	for _, client := range clients {
+		if client.ClientID == "" {
+			continue
+		}
+		provider := strings.ToLower(client.Provider)
+		modelIDs := make([]string, 0, len(client.Models))
+		for _, model := range client.Models {
+			if model == nil || model.ID == "" {
+				continue
+			}
+			staged.addModelRegistration(model.ID, provider, model, now)
+			modelIDs = append(modelIDs, model.ID)
+		}
+		if len(modelIDs) == 0 {
+			continue
+		}
+		staged.clientModels[client.ClientID] = modelIDs
+		if provider != "" {
+			staged.clientProviders[client.ClientID] = provider
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	summary := ReloadSummary{Clients: len(staged.clientModels), Models: len(staged.models)}
+	for modelID, registration := range staged.models {
+		previous, existed := r.models[modelID]
+		if !existed {
+			summary.Added = append(summary.Added, modelID)
+			continue
+		}
+		for clientID, until := range previous.QuotaExceededClients {
+			if staged.clientProvides(clientID, modelID) {
+				registration.QuotaExceededClients[clientID] = until
+			}
+		}
+		for clientID, reason := range previous.SuspendedClients {
+			if staged.clientProvides(clientID, modelID) {
+				registration.SuspendedClients[clientID] = reason
+			}
+		}
+	}
+	for modelID := range r.models {
+		if _, kept := staged.models[modelID]; !kept {
+			summary.Removed = append(summary.Removed, modelID)
+		}
+	}
+	sort.Strings(summary.Added)
+	sort.Strings(summary.Removed)
+
+	r.models = staged.models
+	r.clientModels = staged.clientModels
+	r.clientProviders = staged.clientProviders
+	summary.Version = r.version.Add(1)
+	log.Debugf("Reloaded model registry: %d clients, %d models", summary.Clients, summary.Models)
+	return summary
+}
+
+// clientProvides reports whether clientID lists modelID. It does not lock.
+func (r *ModelRegistry) clientProvides(clientID, modelID string) bool {
+	for _, id := range r.clientModels[clientID] {
+		if id == modelID {
+			return true
+		}
+	}
+	return false
+}
@@ -77,8 +77,13 @@ func (e *AntigravityExecutor) Execute(ctx context.Context, auth *cliproxyauth.Au
 	translated := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), false)
 
 	translated = applyThinkingMetadataCLI(translated, req.Metadata, req.Model)
+	translated = applySystemPromptPolicy(ctx, to, translated)
+	translated = applyPromptInjection(to, req.Model, translated)
+	if translated, err = applyRequestTransformers(ctx, req, to, translated); err != nil {
+		return resp, err
+	}
 
-	baseURLs := antigravityBaseURLFallbackOrder(auth)
+	baseURLs := antigravityBaseURLFallbackOrder(e.cfg, auth)
 	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
 
 	var lastStatus int
@@ -170,8 +175,13 @@ func (e *AntigravityExecutor) ExecuteStream(ctx context.Context, auth *cliproxya
 	translated := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), true)
 
 	translated = applyThinkingMetadataCLI(translated, req.Metadata, req.Model)
+	translated = applySystemPromptPolicy(ctx, to, translated)
+	translated = applyPromptInjection(to, req.Model, translated)
+	if translated, err = applyRequestTransformers(ctx, req, to, translated); err != nil {
+		return nil, err
+	}
 
-	baseURLs := antigravityBaseURLFallbackOrder(auth)
+	baseURLs := antigravityBaseURLFallbackOrder(e.cfg, auth)
 	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
 
 	var lastStatus int
@@ -316,7 +326,7 @@ func FetchAntigravityModels(ctx context.Context, auth *cliproxyauth.Auth, cfg *c
 		auth = updatedAuth
 	}
 
-	baseURLs := antigravityBaseURLFallbackOrder(auth)
+	baseURLs := antigravityBaseURLFallbackOrder(cfg, auth)
 	httpClient := newProxyAwareHTTPClient(ctx, cfg, auth, 0)
 
 	for idx, baseURL := range baseURLs {
@@ -488,7 +498,7 @@ func (e *AntigravityExecutor) buildRequest(ctx context.Context, auth *cliproxyau
 
 	base := strings.TrimSuffix(baseURL, "/")
 	if base == "" {
-		base = buildBaseURL(auth)
+		base = buildBaseURL(e.cfg, auth)
 	}
 	path := antigravityGeneratePath
 	if stream {
@@ -523,6 +533,9 @@ func (e *AntigravityExecutor) buildRequest(ctx context.Context, auth *cliproxyau
 	} else {
 		httpReq.Header.Set("Accept", "application/json")
 	}
+	if auth != nil {
+		util.ApplyCustomHeadersFromAttrs(httpReq, auth.Attributes)
+	}
 	if host := resolveHost(base); host != "" {
 		httpReq.Host = host
 	}
@@ -606,8 +619,8 @@ func int64Value(value any) (int64, bool) {
 	return 0, false
 }
 
-func buildBaseURL(auth *cliproxyauth.Auth) string {
-	if baseURLs := antigravityBaseURLFallbackOrder(auth); len(baseURLs) > 0 {
+func buildBaseURL(cfg *config.Config, auth *cliproxyauth.Auth) string {
+	if baseURLs := antigravityBaseURLFallbackOrder(cfg, auth); len(baseURLs) > 0 {
 		return baseURLs[0]
 	}
 	return antigravityBaseURLAutopush
@@ -640,10 +653,15 @@ func resolveUserAgent(auth *cliproxyauth.Auth) string {
 	return defaultAntigravityAgent
 }
 
-func antigravityBaseURLFallbackOrder(auth *cliproxyauth.Auth) []string {
+func antigravityBaseURLFallbackOrder(cfg *config.Config, auth *cliproxyauth.Auth) []string {
 	if base := resolveCustomAntigravityBaseURL(auth); base != "" {
 		return []string{base}
 	}
+	// A configured endpoint replaces the sandbox fallbacks; without a base URL of its own the
+	// path prefix applies to the production endpoint.
+	if hasUpstreamEndpoint(cfg, "antigravity") {
+		return []string{upstreamBaseURL(cfg, "antigravity", "", antigravityBaseURLProd)}
+	}
 	return []string{
 		antigravityBaseURLDaily,
 		antigravityBaseURLAutopush,
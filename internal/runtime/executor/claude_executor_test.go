@@ -0,0 +1,262 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+const claudeTestResponse = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5-20250929","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":1,"cache_read_input_tokens":8}}`
+
+func executeClaudeCapture(t *testing.T, from string, payload string) []byte {
+	t.Helper()
+	var upstreamBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(claudeTestResponse))
+	}))
+	defer server.Close()
+
+	exec := NewClaudeExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{
+		ID:         "claude-test",
+		Provider:   "claude",
+		Attributes: map[string]string{"api_key": "sk-test", "base_url": server.URL},
+	}
+	req := cliproxyexecutor.Request{Model: "claude-sonnet-4-5-20250929", Payload: []byte(payload)}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString(from), OriginalRequest: []byte(payload)}
+	if _, err := exec.Execute(context.Background(), auth, req, opts); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	return upstreamBody
+}
+
+func TestClaudeExecutor_PreservesCachedSystemPrompt(t *testing.T) {
+	payload := `{"model":"claude-sonnet-4-5-20250929","max_tokens":64,
+		"system":[{"type":"text","text":"Long reusable instructions","cache_control":{"type":"ephemeral"}}],
+		"messages":[{"role":"user","content":[{"type":"text","text":"hi","cache_control":{"type":"ephemeral"}}]}]}`
+
+	body := executeClaudeCapture(t, "claude", payload)
+
+	cached := false
+	gjson.GetBytes(body, "system").ForEach(func(_, part gjson.Result) bool {
+		if part.Get("text").String() == "Long reusable instructions" {
+			cached = part.Get("cache_control.type").String() == "ephemeral"
+		}
+		return true
+	})
+	if !cached {
+		t.Fatalf("Expected cached system block to reach the upstream, got %s", gjson.GetBytes(body, "system").Raw)
+	}
+	if got := gjson.GetBytes(body, "messages.0.content.0.cache_control.type").String(); got != "ephemeral" {
+		t.Fatalf("Expected message cache_control to reach the upstream, got %s", body)
+	}
+}
+
+func TestClaudeExecutor_OpenAICacheControlExtension(t *testing.T) {
+	payload := `{"model":"claude-sonnet-4-5-20250929","messages":[
+		{"role":"system","content":"Long reusable instructions","cache_control":{"type":"ephemeral"}},
+		{"role":"user","content":"hi"}]}`
+
+	body := executeClaudeCapture(t, "openai", payload)
+
+	cached := false
+	gjson.GetBytes(body, "system").ForEach(func(_, part gjson.Result) bool {
+		if part.Get("text").String() == "Long reusable instructions" {
+			cached = part.Get("cache_control.type").String() == "ephemeral"
+		}
+		return true
+	})
+	if !cached {
+		t.Fatalf("Expected system segment to be marked cacheable, got %s", body)
+	}
+	if gjson.GetBytes(body, "messages.0.content.0.cache_control").Exists() {
+		t.Fatalf("Expected unmarked message to stay uncached, got %s", body)
+	}
+}
+
+func TestClaudeExecutor_StreamCancelPropagatesUpstream(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude-sonnet-4-5-20250929\"}}\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			close(upstreamDone)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	exec := NewClaudeExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{
+		ID:         "claude-test",
+		Provider:   "claude",
+		Attributes: map[string]string{"api_key": "sk-test", "base_url": server.URL},
+	}
+	payload := `{"model":"claude-sonnet-4-5-20250929","max_tokens":64,"stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	req := cliproxyexecutor.Request{Model: "claude-sonnet-4-5-20250929", Payload: []byte(payload)}
+	opts := cliproxyexecutor.Options{Stream: true, SourceFormat: sdktranslator.FromString("claude"), OriginalRequest: []byte(payload)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := exec.ExecuteStream(ctx, auth, req, opts)
+	if err != nil {
+		t.Fatalf("ExecuteStream returned error: %v", err)
+	}
+	if _, ok := <-stream; !ok {
+		t.Fatal("Expected a first chunk before cancelling")
+	}
+	cancel()
+
+	select {
+	case <-upstreamDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the upstream request context to be cancelled after the client went away")
+	}
+	// Drain the remaining chunks; the stream must close once the body is released.
+	drained := make(chan struct{})
+	go func() {
+		for range stream {
+		}
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the stream to close after cancellation")
+	}
+}
+
+func TestClaudeExecutor_SendsAccountHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(claudeTestResponse))
+	}))
+	defer server.Close()
+
+	exec := NewClaudeExecutor(&config.Config{})
+	withHeaders := &cliproxyauth.Auth{ID: "claude-org", Provider: "claude", Attributes: map[string]string{
+		"api_key":               "sk-org",
+		"base_url":              server.URL,
+		"header:X-Org-Id":       "org-123",
+		"header:anthropic-beta": "extra-feature-2025-01-01",
+		"header:Authorization":  "Bearer hijacked",
+	}}
+	plain := &cliproxyauth.Auth{ID: "claude-plain", Provider: "claude", Attributes: map[string]string{"api_key": "sk-plain", "base_url": server.URL}}
+	payload := `{"model":"claude-sonnet-4-5-20250929","max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`
+	req := cliproxyexecutor.Request{Model: "claude-sonnet-4-5-20250929", Payload: []byte(payload)}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), OriginalRequest: []byte(payload)}
+
+	if _, err := exec.Execute(context.Background(), withHeaders, req, opts); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if got.Get("X-Org-Id") != "org-123" {
+		t.Fatalf("Expected the account header to be sent, got %v", got)
+	}
+	if got.Get("Authorization") != "Bearer sk-org" {
+		t.Fatalf("Expected the proxy-managed Authorization header to win, got %q", got.Get("Authorization"))
+	}
+	betas := got.Get("Anthropic-Beta")
+	if !strings.Contains(betas, "extra-feature-2025-01-01") || !strings.Contains(betas, "oauth-2025-04-20") {
+		t.Fatalf("Expected the account beta flag to be merged with the defaults, got %q", betas)
+	}
+
+	if _, err := exec.Execute(context.Background(), plain, req, opts); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if got.Get("X-Org-Id") != "" || strings.Contains(got.Get("Anthropic-Beta"), "extra-feature-2025-01-01") {
+		t.Fatalf("Expected no account headers for another credential, got %v", got)
+	}
+}
+
+func TestClaudeExecutor_UsesUpstreamEndpointOverride(t *testing.T) {
+	var gatewayPath, accountPath string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gatewayPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(claudeTestResponse))
+	}))
+	defer gateway.Close()
+	account := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accountPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(claudeTestResponse))
+	}))
+	defer account.Close()
+
+	cfg := &config.Config{UpstreamEndpoints: map[string]config.UpstreamEndpoint{
+		"Claude": {BaseURL: gateway.URL + "/", PathPrefix: "anthropic/"},
+	}}
+	if err := cfg.ValidateUpstreamEndpoints(); err != nil {
+		t.Fatalf("ValidateUpstreamEndpoints: %v", err)
+	}
+	exec := NewClaudeExecutor(cfg)
+	payload := `{"model":"claude-sonnet-4-5-20250929","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`
+	req := cliproxyexecutor.Request{Model: "claude-sonnet-4-5-20250929", Payload: []byte(payload)}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), OriginalRequest: []byte(payload)}
+
+	oauth := &cliproxyauth.Auth{ID: "claude-oauth", Provider: "claude", Metadata: map[string]any{"access_token": "token"}}
+	if _, err := exec.Execute(context.Background(), oauth, req, opts); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if gatewayPath != "/anthropic/v1/messages" {
+		t.Fatalf("Expected the request to go through the configured endpoint, got path %q", gatewayPath)
+	}
+
+	withBaseURL := &cliproxyauth.Auth{ID: "claude-key", Provider: "claude", Attributes: map[string]string{"api_key": "sk-test", "base_url": account.URL}}
+	if _, err := exec.Execute(context.Background(), withBaseURL, req, opts); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if accountPath != "/v1/messages" {
+		t.Fatalf("Expected the account base URL to take precedence, got path %q", accountPath)
+	}
+}
+
+func TestClaudeExecutor_RecordsUpstreamRateLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", "49")
+		w.Header().Set("anthropic-ratelimit-tokens-remaining", "79500")
+		w.Header().Set("anthropic-ratelimit-tokens-reset", time.Now().Add(30*time.Second).UTC().Format(time.RFC3339))
+		_, _ = w.Write([]byte(claudeTestResponse))
+	}))
+	defer server.Close()
+
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	exec := NewClaudeExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{ID: "claude-test", Provider: "claude", Attributes: map[string]string{"api_key": "sk-test", "base_url": server.URL}}
+	payload := []byte(`{"model":"claude-sonnet-4-5-20250929","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`)
+	req := cliproxyexecutor.Request{Model: "claude-sonnet-4-5-20250929", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), OriginalRequest: payload}
+	if _, err := exec.Execute(context.WithValue(context.Background(), "gin", ginCtx), auth, req, opts); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+
+	value, _ := ginCtx.Get(util.UpstreamRateLimitsKey)
+	headers, _ := value.(http.Header)
+	if headers.Get(util.RateLimitRemainingRequestsHeader) != "49" || headers.Get(util.RateLimitRemainingTokensHeader) != "79500" {
+		t.Fatalf("Expected the Claude rate limits to be normalized, got %v", headers)
+	}
+	if reset := headers.Get(util.RateLimitResetHeader); reset != "29" && reset != "30" {
+		t.Fatalf("Expected the reset in seconds, got %q", reset)
+	}
+}
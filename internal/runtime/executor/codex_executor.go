@@ -42,20 +42,26 @@ func (e *CodexExecutor) PrepareRequest(_ *http.Request, _ *cliproxyauth.Auth) er
 
 func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
 	apiKey, baseURL := codexCreds(auth)
-
-	if baseURL == "" {
-		baseURL = "https://chatgpt.com/backend-api/codex"
-	}
+	baseURL = upstreamBaseURL(e.cfg, e.Identifier(), baseURL, "https://chatgpt.com/backend-api/codex")
 	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
 	defer reporter.trackFailure(ctx, &err)
 
 	from := opts.SourceFormat
 	to := sdktranslator.FromString("codex")
-	body := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), false)
+	// The Codex translation folds client system prompts into the official instructions and
+	// user messages, so the system prompt policy and prompt injection are applied to the
+	// source payload instead.
+	payload := applySystemPromptPolicy(ctx, from, bytes.Clone(req.Payload))
+	payload = applyPromptInjection(from, req.Model, payload)
+	body := sdktranslator.TranslateRequest(from, to, req.Model, payload, false)
 
 	body = e.setReasoningEffortByAlias(req.Model, body)
 
 	body = applyPayloadConfig(e.cfg, req.Model, body)
+	if body, err = applyRequestTransformers(ctx, req, to, body); err != nil {
+		return resp, err
+	}
+	body = applyMaxOutputTokensClamp(req.Model, body, "max_output_tokens")
 
 	body, _ = sjson.SetBytes(body, "stream", true)
 	body, _ = sjson.DeleteBytes(body, "previous_response_id")
@@ -98,7 +104,7 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
 		b, _ := io.ReadAll(httpResp.Body)
 		appendAPIResponseChunk(ctx, e.cfg, b)
-		log.Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
+		log.WithContext(ctx).Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
 		err = statusErr{code: httpResp.StatusCode, msg: string(b)}
 		return resp, err
 	}
@@ -123,6 +129,7 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 		if detail, ok := parseCodexUsage(line); ok {
 			reporter.publish(ctx, detail)
 		}
+		reporter.ensurePublished(ctx)
 
 		var param any
 		out := sdktranslator.TranslateNonStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), body, line, &param)
@@ -135,19 +142,25 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 
 func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (stream <-chan cliproxyexecutor.StreamChunk, err error) {
 	apiKey, baseURL := codexCreds(auth)
-
-	if baseURL == "" {
-		baseURL = "https://chatgpt.com/backend-api/codex"
-	}
+	baseURL = upstreamBaseURL(e.cfg, e.Identifier(), baseURL, "https://chatgpt.com/backend-api/codex")
 	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
 	defer reporter.trackFailure(ctx, &err)
 
 	from := opts.SourceFormat
 	to := sdktranslator.FromString("codex")
-	body := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), true)
+	// The Codex translation folds client system prompts into the official instructions and
+	// user messages, so the system prompt policy and prompt injection are applied to the
+	// source payload instead.
+	payload := applySystemPromptPolicy(ctx, from, bytes.Clone(req.Payload))
+	payload = applyPromptInjection(from, req.Model, payload)
+	body := sdktranslator.TranslateRequest(from, to, req.Model, payload, true)
 
 	body = e.setReasoningEffortByAlias(req.Model, body)
 	body = applyPayloadConfig(e.cfg, req.Model, body)
+	if body, err = applyRequestTransformers(ctx, req, to, body); err != nil {
+		return nil, err
+	}
+	body = applyMaxOutputTokensClamp(req.Model, body, "max_output_tokens")
 	body, _ = sjson.DeleteBytes(body, "previous_response_id")
 
 	url := strings.TrimSuffix(baseURL, "/") + "/responses"
@@ -191,7 +204,7 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 			return nil, readErr
 		}
 		appendAPIResponseChunk(ctx, e.cfg, data)
-		log.Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
+		log.WithContext(ctx).Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
 		err = statusErr{code: httpResp.StatusCode, msg: string(data)}
 		return nil, err
 	}
@@ -199,6 +212,7 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 	stream = out
 	go func() {
 		defer close(out)
+		defer reporter.ensurePublished(ctx)
 		defer func() {
 			if errClose := httpResp.Body.Close(); errClose != nil {
 				log.Errorf("codex executor: close response body error: %v", errClose)
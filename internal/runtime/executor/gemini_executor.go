@@ -10,7 +10,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
@@ -83,6 +82,12 @@ func (e *GeminiExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 	body = util.StripThinkingConfigIfUnsupported(req.Model, body)
 	body = fixGeminiImageAspectRatio(req.Model, body)
 	body = applyPayloadConfig(e.cfg, req.Model, body)
+	body = applySystemPromptPolicy(ctx, to, body)
+	body = applyPromptInjection(to, req.Model, body)
+	if body, err = applyRequestTransformers(ctx, req, to, body); err != nil {
+		return resp, err
+	}
+	body = applyMaxOutputTokensClamp(req.Model, body, "generationConfig.maxOutputTokens")
 
 	action := "generateContent"
 	if req.Metadata != nil {
@@ -90,9 +95,12 @@ func (e *GeminiExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 			action = "countTokens"
 		}
 	}
-	baseURL := resolveGeminiBaseURL(auth)
+	if from == sdktranslator.FormatOpenAIEmbeddings {
+		action = "batchEmbedContents"
+	}
+	baseURL := resolveGeminiBaseURL(e.cfg, e.Identifier(), auth)
 	url := fmt.Sprintf("%s/%s/models/%s:%s", baseURL, glAPIVersion, req.Model, action)
-	if opts.Alt != "" && action != "countTokens" {
+	if opts.Alt != "" && action == "generateContent" {
 		url = url + fmt.Sprintf("?$alt=%s", opts.Alt)
 	}
 
@@ -142,8 +150,8 @@ func (e *GeminiExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
 		b, _ := io.ReadAll(httpResp.Body)
 		appendAPIResponseChunk(ctx, e.cfg, b)
-		log.Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
-		err = statusErr{code: httpResp.StatusCode, msg: string(b)}
+		log.WithContext(ctx).Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
+		err = newGeminiStatusErr(httpResp.StatusCode, httpResp.Header, b)
 		return resp, err
 	}
 	data, err := io.ReadAll(httpResp.Body)
@@ -153,6 +161,7 @@ func (e *GeminiExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 	}
 	appendAPIResponseChunk(ctx, e.cfg, data)
 	reporter.publish(ctx, parseGeminiUsage(data))
+	reporter.ensurePublished(ctx)
 	var param any
 	out := sdktranslator.TranslateNonStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), body, data, &param)
 	resp = cliproxyexecutor.Response{Payload: []byte(out)}
@@ -172,8 +181,14 @@ func (e *GeminiExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 	body = util.StripThinkingConfigIfUnsupported(req.Model, body)
 	body = fixGeminiImageAspectRatio(req.Model, body)
 	body = applyPayloadConfig(e.cfg, req.Model, body)
+	body = applySystemPromptPolicy(ctx, to, body)
+	body = applyPromptInjection(to, req.Model, body)
+	if body, err = applyRequestTransformers(ctx, req, to, body); err != nil {
+		return nil, err
+	}
+	body = applyMaxOutputTokensClamp(req.Model, body, "generationConfig.maxOutputTokens")
 
-	baseURL := resolveGeminiBaseURL(auth)
+	baseURL := resolveGeminiBaseURL(e.cfg, e.Identifier(), auth)
 	url := fmt.Sprintf("%s/%s/models/%s:%s", baseURL, glAPIVersion, req.Model, "streamGenerateContent")
 	if opts.Alt == "" {
 		url = url + "?alt=sse"
@@ -222,17 +237,18 @@ func (e *GeminiExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
 		b, _ := io.ReadAll(httpResp.Body)
 		appendAPIResponseChunk(ctx, e.cfg, b)
-		log.Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
+		log.WithContext(ctx).Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
 		if errClose := httpResp.Body.Close(); errClose != nil {
 			log.Errorf("gemini executor: close response body error: %v", errClose)
 		}
-		err = statusErr{code: httpResp.StatusCode, msg: string(b)}
+		err = newGeminiStatusErr(httpResp.StatusCode, httpResp.Header, b)
 		return nil, err
 	}
 	out := make(chan cliproxyexecutor.StreamChunk)
 	stream = out
 	go func() {
 		defer close(out)
+		defer reporter.ensurePublished(ctx)
 		defer func() {
 			if errClose := httpResp.Body.Close(); errClose != nil {
 				log.Errorf("gemini executor: close response body error: %v", errClose)
@@ -284,7 +300,7 @@ func (e *GeminiExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Aut
 	translatedReq, _ = sjson.DeleteBytes(translatedReq, "generationConfig")
 	translatedReq, _ = sjson.DeleteBytes(translatedReq, "safetySettings")
 
-	baseURL := resolveGeminiBaseURL(auth)
+	baseURL := resolveGeminiBaseURL(e.cfg, e.Identifier(), auth)
 	url := fmt.Sprintf("%s/%s/models/%s:%s", baseURL, glAPIVersion, req.Model, "countTokens")
 
 	requestBody := bytes.NewReader(translatedReq)
@@ -334,8 +350,8 @@ func (e *GeminiExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Aut
 	}
 	appendAPIResponseChunk(ctx, e.cfg, data)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		log.Debugf("request error, error status: %d, error body: %s", resp.StatusCode, summarizeErrorBody(resp.Header.Get("Content-Type"), data))
-		return cliproxyexecutor.Response{}, statusErr{code: resp.StatusCode, msg: string(data)}
+		log.WithContext(ctx).Debugf("request error, error status: %d, error body: %s", resp.StatusCode, summarizeErrorBody(resp.Header.Get("Content-Type"), data))
+		return cliproxyexecutor.Response{}, newGeminiStatusErr(resp.StatusCode, resp.Header, data)
 	}
 
 	count := gjson.GetBytes(data, "totalTokens").Int()
@@ -343,6 +359,35 @@ func (e *GeminiExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Aut
 	return cliproxyexecutor.Response{Payload: []byte(translated)}, nil
 }
 
+// Ping lists a single model to verify that the credential is accepted upstream.
+// It implements cliproxyauth.Pinger for readiness probes.
+func (e *GeminiExecutor) Ping(ctx context.Context, auth *cliproxyauth.Auth) error {
+	apiKey, bearer := geminiCreds(auth)
+	url := fmt.Sprintf("%s/%s/models?pageSize=1", resolveGeminiBaseURL(e.cfg, e.Identifier(), auth), glAPIVersion)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("x-goog-api-key", apiKey)
+	} else {
+		httpReq.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	applyGeminiHeaders(httpReq, auth)
+
+	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 10*time.Second)
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusErr{code: resp.StatusCode}
+	}
+	return nil
+}
+
 func (e *GeminiExecutor) Refresh(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
 	log.Debugf("gemini executor: refresh called")
 	// OAuth bearer token refresh for official Gemini API.
@@ -469,17 +514,12 @@ func geminiCreds(a *cliproxyauth.Auth) (apiKey, bearer string) {
 	return
 }
 
-func resolveGeminiBaseURL(auth *cliproxyauth.Auth) string {
-	base := glEndpoint
+func resolveGeminiBaseURL(cfg *config.Config, provider string, auth *cliproxyauth.Auth) string {
+	var custom string
 	if auth != nil && auth.Attributes != nil {
-		if custom := strings.TrimSpace(auth.Attributes["base_url"]); custom != "" {
-			base = strings.TrimRight(custom, "/")
-		}
-	}
-	if base == "" {
-		return glEndpoint
+		custom = auth.Attributes["base_url"]
 	}
-	return base
+	return upstreamBaseURL(cfg, provider, custom, glEndpoint)
 }
 
 func applyGeminiHeaders(req *http.Request, auth *cliproxyauth.Auth) {
@@ -0,0 +1,109 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestGeminiExecutor_EmbeddingsUseBatchEmbedContents(t *testing.T) {
+	var upstreamPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"embeddings":[{"values":[0.1,0.2]},{"values":[0.3,0.4]}]}`))
+	}))
+	defer server.Close()
+
+	exec := NewGeminiExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{
+		ID:         "gemini-test",
+		Provider:   "gemini",
+		Attributes: map[string]string{"api_key": "key", "base_url": server.URL},
+	}
+	payload := []byte(`{"model":"gemini-embedding-001","input":["a","b"]}`)
+	req := cliproxyexecutor.Request{Model: "gemini-embedding-001", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAIEmbeddings, OriginalRequest: payload}
+
+	resp, err := exec.Execute(context.Background(), auth, req, opts)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if upstreamPath != "/v1beta/models/gemini-embedding-001:batchEmbedContents" {
+		t.Fatalf("Expected batchEmbedContents call, got %s", upstreamPath)
+	}
+	if got := len(gjson.GetBytes(resp.Payload, "data").Array()); got != 2 {
+		t.Fatalf("Expected two embeddings in the response, got %s", resp.Payload)
+	}
+}
+
+func TestGeminiExecutor_DryRunCapturesTranslatedRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected dry run not to reach the upstream, got %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	manager := cliproxyauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(NewGeminiExecutor(&config.Config{}))
+	auth := &cliproxyauth.Auth{
+		ID:         "gemini-dry-run",
+		Provider:   "gemini",
+		Label:      "primary",
+		Attributes: map[string]string{"api_key": "secret-key", "base_url": server.URL},
+	}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, "gemini", []*registry.ModelInfo{{ID: "gemini-2.5-pro"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	payload := []byte(`{"model":"gemini-2.5-pro","temperature":0.2,"top_p":0.9,"max_tokens":64,"messages":[{"role":"system","content":"Be terse."},{"role":"user","content":"hi"}]}`)
+	req := cliproxyexecutor.Request{Model: "gemini-2.5-pro", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai"), OriginalRequest: payload}
+
+	_, err := manager.Execute(cliproxyexecutor.WithDryRun(context.Background()), []string{"gemini"}, req, opts)
+	var dry *cliproxyexecutor.DryRunError
+	if !errors.As(err, &dry) {
+		t.Fatalf("Expected a dry-run result, got %v", err)
+	}
+	out := []byte(dry.Error())
+
+	if got := gjson.GetBytes(out, "provider").String(); got != "gemini" {
+		t.Errorf("Expected provider gemini, got %q", got)
+	}
+	if got := gjson.GetBytes(out, "auth_id").String(); got != "gemini-dry-run" {
+		t.Errorf("Expected auth_id gemini-dry-run, got %q", got)
+	}
+	if got := gjson.GetBytes(out, "url").String(); got != server.URL+"/v1beta/models/gemini-2.5-pro:generateContent" {
+		t.Errorf("Expected generateContent URL, got %q", got)
+	}
+	if got := gjson.GetBytes(out, "headers.X-Goog-Api-Key.0").String(); got != "[redacted]" {
+		t.Errorf("Expected API key header to be redacted, got %q", got)
+	}
+	if strings.Contains(string(out), "secret-key") {
+		t.Errorf("Expected credentials to be absent from dry-run output")
+	}
+	if got := gjson.GetBytes(out, "body.system_instruction.parts.0.text").String(); got != "Be terse." {
+		t.Errorf("Expected translated system instruction in body, got %s", gjson.GetBytes(out, "body").Raw)
+	}
+	if got := gjson.GetBytes(out, "body.generationConfig.topP").Float(); got != 0.9 {
+		t.Errorf("Expected topP to be translated, got %v", got)
+	}
+	if strings.Contains(gjson.GetBytes(out, "mutated_params").Raw, "top_p") {
+		t.Errorf("Expected translated top_p not to be reported as mutated, got %s", gjson.GetBytes(out, "mutated_params").Raw)
+	}
+	if got := gjson.GetBytes(out, `mutated_params.#(param=="max_tokens").action`).String(); got != "stripped" {
+		t.Errorf("Expected max_tokens to be reported as stripped, got %s", gjson.GetBytes(out, "mutated_params").Raw)
+	}
+}
@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+const (
+	defaultUpstreamMaxIdleConns        = 256
+	defaultUpstreamMaxIdleConnsPerHost = 64
+	defaultUpstreamIdleConnTimeout     = 90 * time.Second
+)
+
+// upstreamTransports holds the transports shared by all upstream clients. http.Client values
+// are cheap and carry the per-call timeout, so each call builds its own; the connections live
+// in the transport, which pools them per upstream host. Sharing one transport per proxy lets
+// every account and request to a host reuse them. Per-account headers and base URLs are
+// set on each request and are unaffected.
+var upstreamTransports = &transportPool{transports: make(map[string]*http.Transport)}
+
+// poolSettings is the resolved form of config.UpstreamHTTPConfig.
+type poolSettings struct {
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	forceAttemptHTTP2   bool
+}
+
+func resolvePoolSettings(cfg *config.Config) poolSettings {
+	settings := poolSettings{
+		maxIdleConns:        defaultUpstreamMaxIdleConns,
+		maxIdleConnsPerHost: defaultUpstreamMaxIdleConnsPerHost,
+		idleConnTimeout:     defaultUpstreamIdleConnTimeout,
+		forceAttemptHTTP2:   true,
+	}
+	if cfg == nil {
+		return settings
+	}
+	pool := cfg.UpstreamHTTP
+	if pool.MaxIdleConns > 0 {
+		settings.maxIdleConns = pool.MaxIdleConns
+	}
+	if pool.MaxIdleConnsPerHost > 0 {
+		settings.maxIdleConnsPerHost = pool.MaxIdleConnsPerHost
+	}
+	if pool.IdleConnTimeoutSeconds > 0 {
+		settings.idleConnTimeout = time.Duration(pool.IdleConnTimeoutSeconds) * time.Second
+	}
+	if pool.ForceAttemptHTTP2 != nil {
+		settings.forceAttemptHTTP2 = *pool.ForceAttemptHTTP2
+	}
+	return settings
+}
+
+// transportPool caches one transport per proxy URL, with "" for direct connections. A change
+// of the pool settings drops the cached transports and closes their idle connections.
+type transportPool struct {
+	mu         sync.Mutex
+	settings   poolSettings
+	transports map[string]*http.Transport
+}
+
+// get returns the shared transport for proxyURL, or nil when the proxy URL is unusable.
+func (p *transportPool) get(cfg *config.Config, proxyURL string) *http.Transport {
+	settings := resolvePoolSettings(cfg)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if settings != p.settings {
+		for _, transport := range p.transports {
+			transport.CloseIdleConnections()
+		}
+		p.transports = make(map[string]*http.Transport)
+		p.settings = settings
+	}
+	if transport, ok := p.transports[proxyURL]; ok {
+		return transport
+	}
+
+	var transport *http.Transport
+	if proxyURL == "" {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	} else if transport = buildProxyTransport(proxyURL); transport == nil {
+		return nil
+	}
+	transport.MaxIdleConns = settings.maxIdleConns
+	transport.MaxIdleConnsPerHost = settings.maxIdleConnsPerHost
+	transport.IdleConnTimeout = settings.idleConnTimeout
+	transport.ForceAttemptHTTP2 = settings.forceAttemptHTTP2
+	p.transports[proxyURL] = transport
+	return transport
+}
@@ -0,0 +1,89 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// newCountingServer returns a server that echoes the X-Account header and counts the
+// connections opened to it.
+func newCountingServer(t testing.TB) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Account")))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &conns
+}
+
+func doPooledRequest(t testing.TB, cfg *config.Config, auth *cliproxyauth.Auth, url string) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("X-Account", auth.ID)
+	resp, err := newProxyAwareHTTPClient(context.Background(), cfg, auth, 0).Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestNewProxyAwareHTTPClient_ReusesConnections(t *testing.T) {
+	server, conns := newCountingServer(t)
+	cfg := &config.Config{}
+	for i, id := range []string{"account-a", "account-b", "account-a", "account-c"} {
+		auth := &cliproxyauth.Auth{ID: id}
+		if got := doPooledRequest(t, cfg, auth, server.URL+"/v1/"+id); got != id {
+			t.Fatalf("request %d echoed %q, want the per-account header %q", i, got, id)
+		}
+	}
+	if got := conns.Load(); got != 1 {
+		t.Fatalf("opened %d connections for 4 sequential requests, want 1", got)
+	}
+}
+
+func TestTransportPool_RebuildsOnSettingsChange(t *testing.T) {
+	pool := &transportPool{transports: make(map[string]*http.Transport)}
+	first := pool.get(&config.Config{}, "")
+	if first.MaxIdleConnsPerHost != defaultUpstreamMaxIdleConnsPerHost || !first.ForceAttemptHTTP2 {
+		t.Fatalf("unexpected default transport: per-host %d, http2 %t", first.MaxIdleConnsPerHost, first.ForceAttemptHTTP2)
+	}
+	if pool.get(&config.Config{}, "") != first {
+		t.Fatal("expected the transport to be shared")
+	}
+	disabled := false
+	tuned := pool.get(&config.Config{UpstreamHTTP: config.UpstreamHTTPConfig{MaxIdleConnsPerHost: 8, ForceAttemptHTTP2: &disabled}}, "")
+	if tuned == first || tuned.MaxIdleConnsPerHost != 8 || tuned.ForceAttemptHTTP2 {
+		t.Fatalf("expected a rebuilt transport with the new settings, got per-host %d, http2 %t", tuned.MaxIdleConnsPerHost, tuned.ForceAttemptHTTP2)
+	}
+}
+
+func BenchmarkNewProxyAwareHTTPClient_SequentialRequests(b *testing.B) {
+	server, conns := newCountingServer(b)
+	cfg := &config.Config{}
+	auth := &cliproxyauth.Auth{ID: "bench"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doPooledRequest(b, cfg, auth, server.URL)
+	}
+	b.ReportMetric(float64(conns.Load()), "conns")
+}
@@ -47,9 +47,7 @@ func (e *IFlowExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 		err = fmt.Errorf("iflow executor: missing api key")
 		return resp, err
 	}
-	if baseURL == "" {
-		baseURL = iflowauth.DefaultAPIBaseURL
-	}
+	baseURL = upstreamBaseURL(e.cfg, e.Identifier(), baseURL, iflowauth.DefaultAPIBaseURL)
 
 	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
 	defer reporter.trackFailure(ctx, &err)
@@ -58,6 +56,12 @@ func (e *IFlowExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 	to := sdktranslator.FromString("openai")
 	body := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), false)
 	body = applyPayloadConfig(e.cfg, req.Model, body)
+	body = applySystemPromptPolicy(ctx, to, body)
+	body = applyPromptInjection(to, req.Model, body)
+	if body, err = applyRequestTransformers(ctx, req, to, body); err != nil {
+		return resp, err
+	}
+	body = applyMaxOutputTokensClamp(req.Model, body, "max_tokens")
 
 	endpoint := strings.TrimSuffix(baseURL, "/") + iflowDefaultEndpoint
 
@@ -65,7 +69,7 @@ func (e *IFlowExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 	if err != nil {
 		return resp, err
 	}
-	applyIFlowHeaders(httpReq, apiKey, false)
+	applyIFlowHeaders(httpReq, auth, apiKey, false)
 	var authID, authLabel, authType, authValue string
 	if auth != nil {
 		authID = auth.ID
@@ -128,9 +132,7 @@ func (e *IFlowExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 		err = fmt.Errorf("iflow executor: missing api key")
 		return nil, err
 	}
-	if baseURL == "" {
-		baseURL = iflowauth.DefaultAPIBaseURL
-	}
+	baseURL = upstreamBaseURL(e.cfg, e.Identifier(), baseURL, iflowauth.DefaultAPIBaseURL)
 
 	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
 	defer reporter.trackFailure(ctx, &err)
@@ -145,6 +147,12 @@ func (e *IFlowExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 		body = ensureToolsArray(body)
 	}
 	body = applyPayloadConfig(e.cfg, req.Model, body)
+	body = applySystemPromptPolicy(ctx, to, body)
+	body = applyPromptInjection(to, req.Model, body)
+	if body, err = applyRequestTransformers(ctx, req, to, body); err != nil {
+		return nil, err
+	}
+	body = applyMaxOutputTokensClamp(req.Model, body, "max_tokens")
 
 	endpoint := strings.TrimSuffix(baseURL, "/") + iflowDefaultEndpoint
 
@@ -152,7 +160,7 @@ func (e *IFlowExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 	if err != nil {
 		return nil, err
 	}
-	applyIFlowHeaders(httpReq, apiKey, true)
+	applyIFlowHeaders(httpReq, auth, apiKey, true)
 	var authID, authLabel, authType, authValue string
 	if auth != nil {
 		authID = auth.ID
@@ -380,7 +388,7 @@ func (e *IFlowExecutor) refreshOAuthBased(ctx context.Context, auth *cliproxyaut
 	return auth, nil
 }
 
-func applyIFlowHeaders(r *http.Request, apiKey string, stream bool) {
+func applyIFlowHeaders(r *http.Request, auth *cliproxyauth.Auth, apiKey string, stream bool) {
 	r.Header.Set("Content-Type", "application/json")
 	r.Header.Set("Authorization", "Bearer "+apiKey)
 	r.Header.Set("User-Agent", iflowUserAgent)
@@ -389,6 +397,11 @@ func applyIFlowHeaders(r *http.Request, apiKey string, stream bool) {
 	} else {
 		r.Header.Set("Accept", "application/json")
 	}
+	var attrs map[string]string
+	if auth != nil {
+		attrs = auth.Attributes
+	}
+	util.ApplyCustomHeadersFromAttrs(r, attrs)
 }
 
 func iflowCreds(a *cliproxyauth.Auth) (apiKey, baseURL string) {
@@ -12,7 +12,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
 )
 
 const (
@@ -48,6 +50,7 @@ type upstreamAttempt struct {
 
 // recordAPIRequest stores the upstream request metadata in Gin context for request logging.
 func recordAPIRequest(ctx context.Context, cfg *config.Config, info upstreamRequestLog) {
+	logging.RequestRecordFromContext(ctx).ObserveThinking(upstreamThinking(info.Body))
 	if cfg == nil || !cfg.RequestLog {
 		return
 	}
@@ -94,7 +97,10 @@ func recordAPIRequest(ctx context.Context, cfg *config.Config, info upstreamRequ
 }
 
 // recordAPIResponseMetadata captures upstream response status/header information for the latest attempt.
+// The upstream rate-limit headers are remembered for the client response even when request
+// logging is disabled.
 func recordAPIResponseMetadata(ctx context.Context, cfg *config.Config, status int, headers http.Header) {
+	recordUpstreamRateLimits(ctx, headers)
 	if cfg == nil || !cfg.RequestLog {
 		return
 	}
@@ -119,6 +125,14 @@ func recordAPIResponseMetadata(ctx context.Context, cfg *config.Config, status i
 	updateAggregatedResponse(ginCtx, attempts)
 }
 
+// recordUpstreamRateLimits stores the normalized rate-limit headers of the latest upstream
+// response in the gin context, replacing those of an earlier attempt on another account.
+func recordUpstreamRateLimits(ctx context.Context, headers http.Header) {
+	if ginCtx := ginContextFrom(ctx); ginCtx != nil {
+		ginCtx.Set(util.UpstreamRateLimitsKey, util.RateLimitHeaders(headers, time.Now()))
+	}
+}
+
 // recordAPIResponseError adds an error entry for the latest attempt when no HTTP response is available.
 func recordAPIResponseError(ctx context.Context, cfg *config.Config, err error) {
 	if cfg == nil || !cfg.RequestLog || err == nil {
@@ -362,3 +376,27 @@ func extractHTMLTitle(body []byte) string {
 	}
 	return strings.Join(strings.Fields(title), " ")
 }
+
+// upstreamThinking extracts the thinking budget or reasoning effort carried by an upstream
+// payload in any of the provider formats, for inclusion in the request record.
+func upstreamThinking(body []byte) (*int64, string) {
+	if len(body) == 0 {
+		return nil, ""
+	}
+	for _, path := range []string{
+		"generationConfig.thinkingConfig.thinkingBudget",
+		"request.generationConfig.thinkingConfig.thinkingBudget",
+		"thinking.budget_tokens",
+	} {
+		if value := gjson.GetBytes(body, path); value.Type == gjson.Number {
+			budget := value.Int()
+			return &budget, ""
+		}
+	}
+	for _, path := range []string{"reasoning_effort", "reasoning.effort"} {
+		if value := gjson.GetBytes(body, path); value.Type == gjson.String && value.Str != "" {
+			return nil, value.Str
+		}
+	}
+	return nil, ""
+}
@@ -1,10 +1,13 @@
 package executor
 
 import (
+	"context"
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -40,6 +43,36 @@ func applyThinkingMetadataCLI(payload []byte, metadata map[string]any, model str
 	return util.ApplyGeminiCLIThinkingConfig(payload, budgetOverride, includeOverride)
 }
 
+// applyMaxOutputTokensClamp clamps the output token limit found at each of paths to the
+// model's registry-reported maximum so oversized client values don't trigger upstream 400s.
+func applyMaxOutputTokensClamp(model string, payload []byte, paths ...string) []byte {
+	for _, path := range paths {
+		value := gjson.GetBytes(payload, path)
+		if value.Type != gjson.Number {
+			continue
+		}
+		requested := int(value.Int())
+		if clamped := util.NormalizeMaxOutputTokens(model, requested); clamped != requested {
+			if updated, err := sjson.SetBytes(payload, path, clamped); err == nil {
+				payload = updated
+			}
+		}
+	}
+	return payload
+}
+
+// applyRequestTransformers runs the registered request transformers over the translated
+// payload, which is in the provider format to.
+func applyRequestTransformers(ctx context.Context, req cliproxyexecutor.Request, to sdktranslator.Format, payload []byte) ([]byte, error) {
+	transformed := req
+	transformed.Payload = payload
+	transformed.Format = to
+	if err := cliproxyexecutor.TransformRequest(ctx, &transformed); err != nil {
+		return nil, err
+	}
+	return transformed.Payload, nil
+}
+
 // applyPayloadConfig applies payload default and override rules from configuration
 // to the given JSON payload for the specified model.
 // Defaults only fill missing fields, while overrides always overwrite existing values.
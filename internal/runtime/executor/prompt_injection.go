@@ -0,0 +1,255 @@
+package executor
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// promptSeparator separates injected text from the prompt it is added to.
+const promptSeparator = "\n\n"
+
+// applyPromptInjection adds the prompt prefix and suffix configured for model to a payload in
+// format to, usually the translated provider payload; Codex applies it to the source payload.
+// Text already at the start of the system prompt or the
+// end of the final user message is not added again, so clients replaying a conversation do
+// not accumulate copies. Formats without a known prompt layout are returned unchanged.
+func applyPromptInjection(to sdktranslator.Format, model string, payload []byte) []byte {
+	injection, ok := util.PromptInjectionFor(model)
+	if !ok || len(payload) == 0 {
+		return payload
+	}
+	switch to.String() {
+	case "gemini":
+		return injectGeminiPrompt(payload, "", injection)
+	case "gemini-cli", "antigravity":
+		return injectGeminiPrompt(payload, "request", injection)
+	case "claude":
+		return injectClaudePrompt(payload, injection)
+	case "openai":
+		return injectOpenAIPrompt(payload, injection)
+	case "openai-response", "codex":
+		return injectResponsesPrompt(payload, injection)
+	default:
+		return payload
+	}
+}
+
+func injectGeminiPrompt(payload []byte, root string, injection config.PromptInjection) []byte {
+	if injection.Prefix != "" {
+		systemPath := ""
+		for _, key := range []string{"systemInstruction", "system_instruction"} {
+			if path := buildPayloadPath(root, key); gjson.GetBytes(payload, path).Exists() {
+				systemPath = path
+				break
+			}
+		}
+		part := geminiTextPart(injection.Prefix)
+		switch {
+		case systemPath == "":
+			key := "systemInstruction"
+			if root == "" {
+				key = "system_instruction"
+			}
+			payload, _ = sjson.SetRawBytes(payload, buildPayloadPath(root, key), []byte(`{"parts":[`+part+`]}`))
+		case !gjson.GetBytes(payload, systemPath+".parts").IsArray():
+			payload, _ = sjson.SetRawBytes(payload, systemPath+".parts", []byte(`[`+part+`]`))
+		default:
+			payload = prependPromptText(payload, systemPath+".parts", injection.Prefix, part)
+		}
+	}
+	if injection.Suffix != "" {
+		contentsPath := buildPayloadPath(root, "contents")
+		last := -1
+		for i, content := range gjson.GetBytes(payload, contentsPath).Array() {
+			if role := content.Get("role").String(); role == "" || role == "user" {
+				last = i
+			}
+		}
+		if last >= 0 {
+			payload = appendPromptText(payload, contentsPath+"."+strconv.Itoa(last)+".parts", injection.Suffix, geminiTextPart(injection.Suffix))
+		}
+	}
+	return payload
+}
+
+func injectClaudePrompt(payload []byte, injection config.PromptInjection) []byte {
+	if injection.Prefix != "" {
+		// The prefix is kept as a block of its own, after the Claude Code identity block when
+		// present, since checkSystemInstructions drops a plain string system prompt.
+		system := gjson.GetBytes(payload, "system")
+		blocks := gjson.Parse("[]")
+		if system.IsArray() {
+			blocks = system
+		} else if system.Type == gjson.String && system.String() != "" {
+			blocks = gjson.Parse("[" + typedTextPart(system.String()) + "]")
+		}
+		index := 0
+		if blocks.Get("0.text").String() == claudeCodeIdentity {
+			index = 1
+		}
+		if !strings.HasPrefix(blocks.Get(strconv.Itoa(index)+".text").String(), injection.Prefix) {
+			payload, _ = sjson.SetRawBytes(payload, "system", insertRaw(blocks, index, typedTextPart(injection.Prefix)))
+		}
+	}
+	if injection.Suffix != "" {
+		if last := lastMessageWithRole(payload, "messages", "user"); last >= 0 {
+			payload = appendPromptText(payload, "messages."+strconv.Itoa(last)+".content", injection.Suffix, typedTextPart(injection.Suffix))
+		}
+	}
+	return payload
+}
+
+func injectOpenAIPrompt(payload []byte, injection config.PromptInjection) []byte {
+	if injection.Prefix != "" {
+		system := -1
+		for i, message := range gjson.GetBytes(payload, "messages").Array() {
+			if role := message.Get("role").String(); role == "system" || role == "developer" {
+				system = i
+				break
+			}
+		}
+		if system >= 0 {
+			payload = prependPromptText(payload, "messages."+strconv.Itoa(system)+".content", injection.Prefix, typedTextPart(injection.Prefix))
+		} else if messages := gjson.GetBytes(payload, "messages"); messages.IsArray() {
+			message, _ := sjson.Set(`{"role":"system"}`, "content", injection.Prefix)
+			payload, _ = sjson.SetRawBytes(payload, "messages", insertRaw(messages, 0, message))
+		}
+	}
+	if injection.Suffix != "" {
+		if last := lastMessageWithRole(payload, "messages", "user"); last >= 0 {
+			payload = appendPromptText(payload, "messages."+strconv.Itoa(last)+".content", injection.Suffix, typedTextPart(injection.Suffix))
+		}
+	}
+	return payload
+}
+
+func injectResponsesPrompt(payload []byte, injection config.PromptInjection) []byte {
+	if injection.Prefix != "" {
+		// The system prompt is the instructions field or, without one, a system input item.
+		path := "instructions"
+		if instructions := gjson.GetBytes(payload, path); instructions.Type != gjson.String || instructions.String() == "" {
+			for i, item := range gjson.GetBytes(payload, "input").Array() {
+				if role := item.Get("role").String(); role == "system" || role == "developer" {
+					path = "input." + strconv.Itoa(i) + ".content"
+					break
+				}
+			}
+		}
+		payload = prependPromptText(payload, path, injection.Prefix, inputTextPart(injection.Prefix))
+	}
+	if injection.Suffix != "" {
+		if gjson.GetBytes(payload, "input").Type == gjson.String {
+			payload = appendPromptText(payload, "input", injection.Suffix, "")
+		} else if last := lastMessageWithRole(payload, "input", "user"); last >= 0 {
+			payload = appendPromptText(payload, "input."+strconv.Itoa(last)+".content", injection.Suffix, inputTextPart(injection.Suffix))
+		}
+	}
+	return payload
+}
+
+// prependPromptText puts text at the start of the prompt at path, which is either a string or
+// an array of parts with the text under "text". A missing prompt is created as a string and
+// part is put first when the array has no text part.
+func prependPromptText(payload []byte, path, text, part string) []byte {
+	node := gjson.GetBytes(payload, path)
+	switch {
+	case !node.Exists() || node.Type == gjson.Null:
+		payload, _ = sjson.SetBytes(payload, path, text)
+	case node.Type == gjson.String:
+		if !strings.HasPrefix(node.String(), text) {
+			payload, _ = sjson.SetBytes(payload, path, joinPrompt(text, node.String()))
+		}
+	case node.IsArray():
+		for i, item := range node.Array() {
+			if existing := item.Get("text"); existing.Type == gjson.String {
+				if !strings.HasPrefix(existing.String(), text) {
+					payload, _ = sjson.SetBytes(payload, path+"."+strconv.Itoa(i)+".text", joinPrompt(text, existing.String()))
+				}
+				return payload
+			}
+		}
+		payload, _ = sjson.SetRawBytes(payload, path, insertRaw(node, 0, part))
+	}
+	return payload
+}
+
+// appendPromptText puts text at the end of the message content at path, a string or an array
+// of parts, adding part when the content has no text part.
+func appendPromptText(payload []byte, path, text, part string) []byte {
+	node := gjson.GetBytes(payload, path)
+	switch {
+	case node.Type == gjson.String:
+		if !strings.HasSuffix(node.String(), text) {
+			payload, _ = sjson.SetBytes(payload, path, joinPrompt(node.String(), text))
+		}
+	case node.IsArray():
+		items := node.Array()
+		for i := len(items) - 1; i >= 0; i-- {
+			if existing := items[i].Get("text"); existing.Type == gjson.String {
+				if !strings.HasSuffix(existing.String(), text) {
+					payload, _ = sjson.SetBytes(payload, path+"."+strconv.Itoa(i)+".text", joinPrompt(existing.String(), text))
+				}
+				return payload
+			}
+		}
+		payload, _ = sjson.SetRawBytes(payload, path+".-1", []byte(part))
+	}
+	return payload
+}
+
+func lastMessageWithRole(payload []byte, path, role string) int {
+	last := -1
+	for i, message := range gjson.GetBytes(payload, path).Array() {
+		if message.Get("role").String() == role {
+			last = i
+		}
+	}
+	return last
+}
+
+func joinPrompt(first, second string) string {
+	if first == "" {
+		return second
+	}
+	if second == "" {
+		return first
+	}
+	return first + promptSeparator + second
+}
+
+// insertRaw returns the JSON array list with item inserted at index.
+func insertRaw(list gjson.Result, index int, item string) []byte {
+	items := list.Array()
+	raw := make([]string, 0, len(items)+1)
+	for i, existing := range items {
+		if i == index {
+			raw = append(raw, item)
+		}
+		raw = append(raw, existing.Raw)
+	}
+	if index >= len(items) {
+		raw = append(raw, item)
+	}
+	return []byte("[" + strings.Join(raw, ",") + "]")
+}
+
+func geminiTextPart(text string) string {
+	part, _ := sjson.Set(`{}`, "text", text)
+	return part
+}
+
+func typedTextPart(text string) string {
+	part, _ := sjson.Set(`{"type":"text"}`, "text", text)
+	return part
+}
+
+func inputTextPart(text string) string {
+	part, _ := sjson.Set(`{"type":"input_text"}`, "text", text)
+	return part
+}
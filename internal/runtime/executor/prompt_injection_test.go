@@ -0,0 +1,145 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func setPromptInjections(t *testing.T, injections map[string]config.PromptInjection) {
+	t.Helper()
+	util.SetPromptInjections(injections)
+	t.Cleanup(func() { util.SetPromptInjections(nil) })
+}
+
+func TestGeminiExecutor_InjectsPromptPrefixAndSuffix(t *testing.T) {
+	setPromptInjections(t, map[string]config.PromptInjection{"gemini-2.5-*": {Prefix: "Follow the guidelines.", Suffix: "Answer briefly."}})
+	var upstream []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	exec := NewGeminiExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{ID: "gemini-test", Provider: "gemini", Attributes: map[string]string{"api_key": "key", "base_url": server.URL}}
+	payload := []byte(`{"model":"gemini-2.5-flash","messages":[{"role":"system","content":"Be kind."},{"role":"user","content":"Hi"},{"role":"assistant","content":"Hello"},{"role":"user","content":"Tell me a joke"}]}`)
+	req := cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, OriginalRequest: payload}
+	if _, err := exec.Execute(context.Background(), auth, req, opts); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	system := gjson.GetBytes(upstream, "system_instruction.parts.0.text").String()
+	if system != "Follow the guidelines.\n\nBe kind." {
+		t.Fatalf("Expected the prefix to start the system instruction, got %q in %s", system, upstream)
+	}
+	if first := gjson.GetBytes(upstream, "contents.0.parts.0.text").String(); first != "Hi" {
+		t.Fatalf("Expected earlier user turns to be left alone, got %q", first)
+	}
+	if last := gjson.GetBytes(upstream, "contents.2.parts.0.text").String(); last != "Tell me a joke\n\nAnswer briefly." {
+		t.Fatalf("Expected the suffix to end the final user message, got %q", last)
+	}
+	if strings.Count(string(upstream), "Follow the guidelines.") != 1 || strings.Count(string(upstream), "Answer briefly.") != 1 {
+		t.Fatalf("Expected a single copy of the injected text, got %s", upstream)
+	}
+}
+
+func TestApplyPromptInjection_GeminiCreatesSystemInstruction(t *testing.T) {
+	setPromptInjections(t, map[string]config.PromptInjection{"gemini-2.5-pro": {Prefix: "Follow the guidelines."}})
+	payload := []byte(`{"request":{"contents":[{"role":"user","parts":[{"text":"Hi"}]}]}}`)
+
+	out := applyPromptInjection(sdktranslator.FromString("gemini-cli"), "gemini-2.5-pro", payload)
+	if got := gjson.GetBytes(out, "request.systemInstruction.parts.0.text").String(); got != "Follow the guidelines." {
+		t.Fatalf("Expected a system instruction holding the prefix, got %s", out)
+	}
+	if again := applyPromptInjection(sdktranslator.FromString("gemini-cli"), "gemini-2.5-pro", out); string(again) != string(out) {
+		t.Fatalf("Expected reapplying the injection to change nothing, got %s", again)
+	}
+}
+
+func TestApplyPromptInjection_ClaudeIsIdempotent(t *testing.T) {
+	setPromptInjections(t, map[string]config.PromptInjection{"claude-*": {Prefix: "Follow the guidelines.", Suffix: "Answer briefly."}})
+	payload := []byte(`{"system":"Be kind.","messages":[{"role":"user","content":"Hi"},{"role":"assistant","content":"Hello"},{"role":"user","content":[{"type":"text","text":"Tell me a joke"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AA=="}}]}]}`)
+
+	out := applyPromptInjection(sdktranslator.FromString("claude"), "claude-sonnet-4-5", payload)
+	out = applyPromptInjection(sdktranslator.FromString("claude"), "claude-sonnet-4-5", out)
+	out = checkSystemInstructions(out)
+
+	system := gjson.GetBytes(out, "system").Array()
+	if len(system) != 3 || system[0].Get("text").String() != claudeCodeIdentity || system[1].Get("text").String() != "Follow the guidelines." || system[2].Get("text").String() != "Be kind." {
+		t.Fatalf("Expected the prefix block between the identity and the client system prompt, got %s", gjson.GetBytes(out, "system").Raw)
+	}
+	if got := gjson.GetBytes(out, "messages.2.content.0.text").String(); got != "Tell me a joke\n\nAnswer briefly." {
+		t.Fatalf("Expected the suffix once at the end of the final user text, got %q", got)
+	}
+	if got := gjson.GetBytes(out, "messages.0.content").String(); got != "Hi" {
+		t.Fatalf("Expected earlier user turns to be left alone, got %q", got)
+	}
+}
+
+func TestCodexExecutor_InjectsPromptPrefixAndSuffix(t *testing.T) {
+	setPromptInjections(t, map[string]config.PromptInjection{"gpt-5-codex": {Prefix: "Follow the guidelines.", Suffix: "Answer briefly."}})
+	var upstream []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\",\"output\":[]}}\n\n"))
+	}))
+	defer server.Close()
+
+	exec := NewCodexExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{ID: "codex-test", Provider: "codex", Attributes: map[string]string{"api_key": "key", "base_url": server.URL}}
+	payload := []byte(`{"model":"gpt-5-codex","messages":[{"role":"system","content":"Be kind."},{"role":"user","content":"Tell me a joke"}]}`)
+	req := cliproxyexecutor.Request{Model: "gpt-5-codex", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, OriginalRequest: payload}
+	if _, err := exec.Execute(context.Background(), auth, req, opts); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if got := gjson.GetBytes(upstream, "input.0.content.0.text").String(); got != "Follow the guidelines.\n\nBe kind." {
+		t.Fatalf("Expected the prefix to start the client system prompt, got %q", got)
+	}
+	if got := gjson.GetBytes(upstream, "input.1.content.0.text").String(); got != "Tell me a joke\n\nAnswer briefly." {
+		t.Fatalf("Expected the suffix to end the final user message, got %q", got)
+	}
+	if strings.Count(string(upstream), "Follow the guidelines.") != 1 || strings.Count(string(upstream), "Answer briefly.") != 1 {
+		t.Fatalf("Expected a single copy of the injected text, got %s", upstream)
+	}
+}
+
+func TestApplyPromptInjection_Responses(t *testing.T) {
+	setPromptInjections(t, map[string]config.PromptInjection{"gpt-5": {Prefix: "Follow the guidelines.", Suffix: "Answer briefly."}})
+	format := sdktranslator.FromString("openai-response")
+
+	out := applyPromptInjection(format, "gpt-5", []byte(`{"instructions":"Be kind.","input":"Tell me a joke"}`))
+	out = applyPromptInjection(format, "gpt-5", out)
+	if got := gjson.GetBytes(out, "instructions").String(); got != "Follow the guidelines.\n\nBe kind." {
+		t.Fatalf("Expected the prefix once at the start of the instructions, got %q", got)
+	}
+	if got := gjson.GetBytes(out, "input").String(); got != "Tell me a joke\n\nAnswer briefly." {
+		t.Fatalf("Expected the suffix once at the end of the input, got %q", got)
+	}
+
+	out = applyPromptInjection(format, "gpt-5", []byte(`{"input":[{"type":"message","role":"developer","content":[{"type":"input_text","text":"Be kind."}]},{"type":"message","role":"user","content":[{"type":"input_text","text":"Tell me a joke"}]}]}`))
+	if got := gjson.GetBytes(out, "input.0.content.0.text").String(); got != "Follow the guidelines.\n\nBe kind." {
+		t.Fatalf("Expected the prefix in the developer message, got %s", out)
+	}
+	if got := gjson.GetBytes(out, "input.1.content.0.text").String(); got != "Tell me a joke\n\nAnswer briefly." {
+		t.Fatalf("Expected the suffix in the final user message, got %s", out)
+	}
+	if gjson.GetBytes(out, "instructions").Exists() {
+		t.Fatalf("Expected no instructions to be created beside the developer message, got %s", out)
+	}
+}
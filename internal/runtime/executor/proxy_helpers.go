@@ -10,14 +10,20 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/proxy"
 )
 
 // newProxyAwareHTTPClient creates an HTTP client with proper proxy configuration priority:
+// 0. Capture the request without sending it when the context requests a dry run
 // 1. Use auth.ProxyURL if configured (highest priority)
-// 2. Use cfg.ProxyURL if auth proxy is not configured
-// 3. Use RoundTripper from context if neither are configured
+// 2. Use cfg.ProviderProxies for the auth's provider if auth proxy is not configured
+// 3. Use cfg.ProxyURL if neither are configured
+// 4. Use RoundTripper from context if no proxy is configured
+// 5. Use the shared direct transport otherwise, which honors the HTTP(S)_PROXY environment
+//
+// Proxied and direct connections come from the pooled transports in upstreamTransports.
 //
 // Parameters:
 //   - ctx: The context containing optional RoundTripper
@@ -32,6 +38,10 @@ func newProxyAwareHTTPClient(ctx context.Context, cfg *config.Config, auth *clip
 	if timeout > 0 {
 		httpClient.Timeout = timeout
 	}
+	if cliproxyexecutor.IsDryRun(ctx) {
+		httpClient.Transport = cliproxyexecutor.DryRunTransport()
+		return httpClient
+	}
 
 	// Priority 1: Use auth.ProxyURL if configured
 	var proxyURL string
@@ -39,27 +49,35 @@ func newProxyAwareHTTPClient(ctx context.Context, cfg *config.Config, auth *clip
 		proxyURL = strings.TrimSpace(auth.ProxyURL)
 	}
 
-	// Priority 2: Use cfg.ProxyURL if auth proxy is not configured
+	// Priority 2: Use the provider proxy if auth proxy is not configured
+	if proxyURL == "" && cfg != nil && auth != nil {
+		proxyURL = strings.TrimSpace(cfg.ProviderProxies[strings.ToLower(auth.Provider)])
+	}
+
+	// Priority 3: Use cfg.ProxyURL if neither are configured
 	if proxyURL == "" && cfg != nil {
 		proxyURL = strings.TrimSpace(cfg.ProxyURL)
 	}
 
 	// If we have a proxy URL configured, set up the transport
 	if proxyURL != "" {
-		transport := buildProxyTransport(proxyURL)
+		transport := upstreamTransports.get(cfg, proxyURL)
 		if transport != nil {
 			httpClient.Transport = transport
 			return httpClient
 		}
 		// If proxy setup failed, log and fall through to context RoundTripper
-		log.Debugf("failed to setup proxy from URL: %s, falling back to context transport", proxyURL)
+		log.Debugf("failed to setup proxy from URL: %s, falling back to context transport", config.RedactProxyURL(proxyURL))
 	}
 
-	// Priority 3: Use RoundTripper from context (typically from RoundTripperFor)
+	// Priority 4: Use RoundTripper from context (typically from RoundTripperFor)
 	if rt, ok := ctx.Value("cliproxy.roundtripper").(http.RoundTripper); ok && rt != nil {
 		httpClient.Transport = rt
+		return httpClient
 	}
 
+	// Priority 5: Use the shared direct transport
+	httpClient.Transport = upstreamTransports.get(cfg, "")
 	return httpClient
 }
 
@@ -78,7 +96,8 @@ func buildProxyTransport(proxyURL string) *http.Transport {
 
 	parsedURL, errParse := url.Parse(proxyURL)
 	if errParse != nil {
-		log.Errorf("parse proxy URL failed: %v", errParse)
+		// The parse error quotes the URL, credentials included.
+		log.Errorf("parse proxy URL failed: %s", config.RedactProxyURL(proxyURL))
 		return nil
 	}
 
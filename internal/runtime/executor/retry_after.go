@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// newStatusErrFromResponse builds a status error for a failed upstream response and
+// attaches the Retry-After hint of 429 responses so the auth manager can cool the
+// credential down and schedule the next retry accordingly.
+func newStatusErrFromResponse(statusCode int, header http.Header, body []byte) statusErr {
+	err := statusErr{code: statusCode, msg: string(body)}
+	if statusCode == http.StatusTooManyRequests && header != nil {
+		err.retryAfter = parseRetryAfter(header.Get("Retry-After"), time.Now())
+	}
+	return err
+}
+
+// parseRetryAfter parses a Retry-After header value in either delta-seconds
+// ("120") or HTTP-date ("Wed, 21 Oct 2015 07:28:00 GMT") form relative to now.
+// Dates in the past yield a zero delay. Empty or malformed values return nil.
+func parseRetryAfter(value string, now time.Time) *time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return nil
+		}
+		delay := time.Duration(seconds) * time.Second
+		return &delay
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return nil
+	}
+	delay := at.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	return &delay
+}
@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name  string
+		value string
+		want  *time.Duration
+	}{
+		{"delta seconds", "120", durationPtr(120 * time.Second)},
+		{"delta seconds with spaces", " 5 ", durationPtr(5 * time.Second)},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), durationPtr(90 * time.Second)},
+		{"http date in the past", now.Add(-time.Minute).Format(http.TimeFormat), durationPtr(0)},
+		{"malformed", "soon", nil},
+		{"negative", "-3", nil},
+		{"empty", "", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseRetryAfter(tc.value, now)
+			switch {
+			case tc.want == nil && got != nil:
+				t.Fatalf("Expected nil for %q, got %v", tc.value, *got)
+			case tc.want != nil && got == nil:
+				t.Fatalf("Expected %v for %q, got nil", *tc.want, tc.value)
+			case tc.want != nil && *got != *tc.want:
+				t.Fatalf("Expected %v for %q, got %v", *tc.want, tc.value, *got)
+			}
+		})
+	}
+}
+
+func TestNewGeminiStatusErr_PrefersHeader(t *testing.T) {
+	body := []byte(`{"error":{"code":429,"details":[{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"30s"}]}}`)
+
+	header := http.Header{}
+	header.Set("Retry-After", "7")
+	err := newGeminiStatusErr(http.StatusTooManyRequests, header, body)
+	if err.retryAfter == nil || *err.retryAfter != 7*time.Second {
+		t.Fatalf("Expected header delay of 7s to win, got %v", err.retryAfter)
+	}
+
+	header.Set("Retry-After", "not-a-delay")
+	err = newGeminiStatusErr(http.StatusTooManyRequests, header, body)
+	if err.retryAfter == nil || *err.retryAfter != 30*time.Second {
+		t.Fatalf("Expected malformed header to fall back to body retryDelay, got %v", err.retryAfter)
+	}
+
+	err = newStatusErrFromResponse(http.StatusServiceUnavailable, http.Header{"Retry-After": {"7"}}, nil)
+	if err.retryAfter != nil {
+		t.Fatalf("Expected Retry-After to be applied to 429 responses only, got %v", *err.retryAfter)
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration { return &d }
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// applySystemPromptPolicy enforces the system prompt policy of the client API key on a payload
+// in format. Override drops every client system prompt and sends only the configured one;
+// merge puts the configured prompt first, like a prompt injection prefix, so a replayed
+// conversation keeps a single copy. Formats without a known prompt layout are returned
+// unchanged.
+func applySystemPromptPolicy(ctx context.Context, format sdktranslator.Format, payload []byte) []byte {
+	policy, ok := util.SystemPromptPolicyFor(apiKeyFromContext(ctx))
+	if !ok || len(payload) == 0 {
+		return payload
+	}
+	if policy.Mode == config.SystemPromptModeMerge {
+		injection := config.PromptInjection{Prefix: policy.Prompt}
+		switch format.String() {
+		case "gemini":
+			return injectGeminiPrompt(payload, "", injection)
+		case "gemini-cli", "antigravity":
+			return injectGeminiPrompt(payload, "request", injection)
+		case "claude":
+			return injectClaudePrompt(payload, injection)
+		case "openai":
+			return injectOpenAIPrompt(payload, injection)
+		case "openai-response":
+			if instructions := gjson.GetBytes(payload, "instructions").String(); !strings.HasPrefix(instructions, policy.Prompt) {
+				payload, _ = sjson.SetBytes(payload, "instructions", joinPrompt(policy.Prompt, instructions))
+			}
+			return payload
+		default:
+			return payload
+		}
+	}
+	switch format.String() {
+	case "gemini":
+		return overrideGeminiSystemPrompt(payload, "", policy.Prompt)
+	case "gemini-cli", "antigravity":
+		return overrideGeminiSystemPrompt(payload, "request", policy.Prompt)
+	case "claude":
+		system := "[" + typedTextPart(policy.Prompt) + "]"
+		if gjson.GetBytes(payload, "system.0.text").String() == claudeCodeIdentity {
+			system = "[" + typedTextPart(claudeCodeIdentity) + "," + typedTextPart(policy.Prompt) + "]"
+		}
+		payload, _ = sjson.SetRawBytes(payload, "system", []byte(system))
+		return payload
+	case "openai":
+		messages := gjson.GetBytes(payload, "messages")
+		if !messages.IsArray() {
+			return payload
+		}
+		message, _ := sjson.Set(`{"role":"system"}`, "content", policy.Prompt)
+		kept := gjson.ParseBytes(withoutSystemMessages(messages))
+		payload, _ = sjson.SetRawBytes(payload, "messages", insertRaw(kept, 0, message))
+		return payload
+	case "openai-response":
+		if input := gjson.GetBytes(payload, "input"); input.IsArray() {
+			payload, _ = sjson.SetRawBytes(payload, "input", withoutSystemMessages(input))
+		}
+		payload, _ = sjson.SetBytes(payload, "instructions", policy.Prompt)
+		return payload
+	default:
+		return payload
+	}
+}
+
+func overrideGeminiSystemPrompt(payload []byte, root, prompt string) []byte {
+	payload, _ = sjson.DeleteBytes(payload, buildPayloadPath(root, "systemInstruction"))
+	payload, _ = sjson.DeleteBytes(payload, buildPayloadPath(root, "system_instruction"))
+	key := "systemInstruction"
+	if root == "" {
+		key = "system_instruction"
+	}
+	payload, _ = sjson.SetRawBytes(payload, buildPayloadPath(root, key), []byte(`{"parts":[`+geminiTextPart(prompt)+`]}`))
+	return payload
+}
+
+// withoutSystemMessages returns the JSON array messages without its system and developer
+// messages.
+func withoutSystemMessages(messages gjson.Result) []byte {
+	raw := make([]string, 0, len(messages.Array()))
+	for _, message := range messages.Array() {
+		if role := message.Get("role").String(); role == "system" || role == "developer" {
+			continue
+		}
+		raw = append(raw, message.Raw)
+	}
+	return []byte("[" + strings.Join(raw, ",") + "]")
+}
@@ -0,0 +1,35 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// upstreamBaseURL returns the base URL for requests of provider. An account-level base URL
+// wins; otherwise the upstream-endpoints override of the provider applies on top of the
+// official base URL.
+func upstreamBaseURL(cfg *config.Config, provider, account, official string) string {
+	if account = strings.TrimSpace(account); account != "" {
+		return strings.TrimRight(account, "/")
+	}
+	if cfg != nil {
+		if endpoint, ok := cfg.UpstreamEndpoints[provider]; ok {
+			base := endpoint.BaseURL
+			if base == "" {
+				base = official
+			}
+			return strings.TrimRight(base, "/") + endpoint.PathPrefix
+		}
+	}
+	return official
+}
+
+// hasUpstreamEndpoint reports whether upstream-endpoints overrides provider.
+func hasUpstreamEndpoint(cfg *config.Config, provider string) bool {
+	if cfg == nil {
+		return false
+	}
+	_, ok := cfg.UpstreamEndpoints[provider]
+	return ok
+}
@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -54,7 +56,7 @@ func (r *usageReporter) trackFailure(ctx context.Context, errPtr *error) {
 	if r == nil || errPtr == nil {
 		return
 	}
-	if *errPtr != nil {
+	if *errPtr != nil && !cliproxyexecutor.IsDryRun(ctx) {
 		r.publishFailure(ctx)
 	}
 }
@@ -72,6 +74,9 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 	if detail.InputTokens == 0 && detail.OutputTokens == 0 && detail.ReasoningTokens == 0 && detail.CachedTokens == 0 && detail.TotalTokens == 0 && !failed {
 		return
 	}
+	if !failed {
+		logging.RequestRecordFromContext(ctx).ObserveUsage(detail.InputTokens, detail.OutputTokens, detail.ReasoningTokens, detail.CachedTokens, detail.TotalTokens)
+	}
 	r.once.Do(func() {
 		usage.PublishRecord(ctx, usage.Record{
 			Provider:    r.provider,
@@ -486,7 +491,8 @@ func FilterSSEUsageMetadata(payload []byte) []byte {
 	return bytes.Join(lines, []byte("\n"))
 }
 
-// StripUsageMetadataFromJSON drops usageMetadata unless finishReason is present (terminal).
+// StripUsageMetadataFromJSON drops usageMetadata unless finishReason or a prompt blockReason is
+// present (terminal).
 // It handles both formats:
 // - Aistudio: candidates.0.finishReason
 // - Antigravity: response.candidates.0.finishReason
@@ -502,6 +508,10 @@ func StripUsageMetadataFromJSON(rawJSON []byte) ([]byte, bool) {
 		finishReason = gjson.GetBytes(jsonBytes, "response.candidates.0.finishReason")
 	}
 	terminalReason := finishReason.Exists() && strings.TrimSpace(finishReason.String()) != ""
+	// A prompt blocked by a content filter ends the stream without any finishReason.
+	if !terminalReason {
+		terminalReason = hasPromptBlockReason(jsonBytes)
+	}
 
 	usageMetadata := gjson.GetBytes(jsonBytes, "usageMetadata")
 	if !usageMetadata.Exists() {
@@ -548,6 +558,14 @@ func hasUsageMetadata(jsonBytes []byte) bool {
 	return false
 }
 
+func hasPromptBlockReason(jsonBytes []byte) bool {
+	blockReason := gjson.GetBytes(jsonBytes, "promptFeedback.blockReason")
+	if !blockReason.Exists() {
+		blockReason = gjson.GetBytes(jsonBytes, "response.promptFeedback.blockReason")
+	}
+	return strings.TrimSpace(blockReason.String()) != ""
+}
+
 func isStopChunkWithoutUsage(jsonBytes []byte) bool {
 	if len(jsonBytes) == 0 || !gjson.ValidBytes(jsonBytes) {
 		return false
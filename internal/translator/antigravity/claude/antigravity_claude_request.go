@@ -163,12 +163,13 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 	// Map Anthropic thinking -> Gemini thinkingBudget/include_thoughts when type==enabled
 	if t := gjson.GetBytes(rawJSON, "thinking"); t.Exists() && t.IsObject() && util.ModelSupportsThinking(modelName) {
 		if t.Get("type").String() == "enabled" {
+			// Without an explicit budget, fall back to the configured per-model default.
+			budget := util.DefaultThinkingBudgetFor(modelName)
 			if b := t.Get("budget_tokens"); b.Exists() && b.Type == gjson.Number {
-				budget := int(b.Int())
-				budget = util.NormalizeThinkingBudget(modelName, budget)
-				out, _ = sjson.Set(out, "request.generationConfig.thinkingConfig.thinkingBudget", budget)
-				out, _ = sjson.Set(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
+				budget = util.NormalizeThinkingBudget(modelName, int(b.Int()))
 			}
+			out, _ = sjson.Set(out, "request.generationConfig.thinkingConfig.thinkingBudget", budget)
+			out, _ = sjson.Set(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
 		}
 	}
 	if v := gjson.GetBytes(rawJSON, "temperature"); v.Exists() && v.Type == gjson.Number {
@@ -14,6 +14,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/finishreason"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -34,6 +37,7 @@ type Params struct {
 	TotalTokenCount      int64  // Cached total token count from usage metadata
 	HasSentFinalEvents   bool   // Indicates if final content/message events have been sent
 	HasToolUse           bool   // Indicates if tool use was observed in the stream
+	SafetyDetails        string // Content filter details when the response was blocked
 }
 
 // ConvertAntigravityResponseToClaude performs sophisticated streaming response format conversion.
@@ -91,6 +95,8 @@ func ConvertAntigravityResponseToClaude(_ context.Context, _ string, originalReq
 			messageStartTemplate, _ = sjson.Set(messageStartTemplate, "message.id", responseIDResult.String())
 		}
 		output = output + fmt.Sprintf("data: %s\n\n\n", messageStartTemplate)
+		// Anthropic sends a ping right after message_start; clients may rely on it as a keepalive.
+		output = output + "event: ping\ndata: {\"type\":\"ping\"}\n\n\n"
 
 		params.HasFirstResponse = true
 	}
@@ -223,10 +229,21 @@ func ConvertAntigravityResponseToClaude(_ context.Context, _ string, originalReq
 		}
 	}
 
+	// Grounding citations belong to the text block still open at this point.
+	if params.ResponseType == 1 {
+		output = output + common.ClaudeCitationEvents(params.ResponseIndex, common.GroundingCitations(gjson.GetBytes(rawJSON, "response.candidates.0")))
+	}
+
 	if finishReasonResult := gjson.GetBytes(rawJSON, "response.candidates.0.finishReason"); finishReasonResult.Exists() {
 		params.HasFinishReason = true
 		params.FinishReason = finishReasonResult.String()
 	}
+	// Blocked prompts end the stream through promptFeedback without any finishReason.
+	if blockReason, safetyDetails, blocked := common.SafetyBlock(gjson.GetBytes(rawJSON, "response")); blocked {
+		params.HasFinishReason = true
+		params.FinishReason = blockReason
+		params.SafetyDetails = safetyDetails
+	}
 
 	if usageResult := gjson.GetBytes(rawJSON, "response.usageMetadata"); usageResult.Exists() {
 		params.HasUsageMetadata = true
@@ -277,24 +294,19 @@ func appendFinalEvents(params *Params, output *string, force bool) {
 	*output = *output + "event: message_delta\n"
 	*output = *output + "data: "
 	delta := fmt.Sprintf(`{"type":"message_delta","delta":{"stop_reason":"%s","stop_sequence":null},"usage":{"input_tokens":%d,"output_tokens":%d}}`, stopReason, params.PromptTokenCount, usageOutputTokens)
+	if params.SafetyDetails != "" {
+		delta, _ = sjson.SetRaw(delta, "content_filter", params.SafetyDetails)
+	}
 	*output = *output + delta + "\n\n\n"
 
 	params.HasSentFinalEvents = true
 }
 
 func resolveStopReason(params *Params) string {
-	if params.HasToolUse {
-		return "tool_use"
+	if params.SafetyDetails != "" {
+		return "refusal"
 	}
-
-	switch params.FinishReason {
-	case "MAX_TOKENS":
-		return "max_tokens"
-	case "STOP", "FINISH_REASON_UNSPECIFIED", "UNKNOWN":
-		return "end_turn"
-	}
-
-	return "end_turn"
+	return finishreason.Normalize(constant.Gemini, constant.Claude, params.FinishReason, params.HasToolUse)
 }
 
 // ConvertAntigravityResponseToClaudeNonStream converts a non-streaming Gemini CLI response to a non-streaming Claude response.
@@ -410,22 +422,17 @@ func ConvertAntigravityResponseToClaudeNonStream(_ context.Context, _ string, or
 	flushThinking()
 	flushText()
 
+	if citations := common.GroundingCitations(root.Get("response.candidates.0")); len(citations) > 0 {
+		attachCitations(contentBlocks, citations)
+	}
+
 	response["content"] = contentBlocks
 
-	stopReason := "end_turn"
-	if hasToolCall {
-		stopReason = "tool_use"
-	} else {
-		if finish := root.Get("response.candidates.0.finishReason"); finish.Exists() {
-			switch finish.String() {
-			case "MAX_TOKENS":
-				stopReason = "max_tokens"
-			case "STOP", "FINISH_REASON_UNSPECIFIED", "UNKNOWN":
-				stopReason = "end_turn"
-			default:
-				stopReason = "end_turn"
-			}
-		}
+	stopReason := finishreason.Normalize(constant.Gemini, constant.Claude, root.Get("response.candidates.0.finishReason").String(), hasToolCall)
+	if _, safetyDetails, blocked := common.SafetyBlock(root.Get("response")); blocked {
+		// Claude reports withheld output as a refusal; the Gemini details go in an extension field.
+		stopReason = "refusal"
+		response["content_filter"] = json.RawMessage(safetyDetails)
 	}
 	response["stop_reason"] = stopReason
 
@@ -442,6 +449,17 @@ func ConvertAntigravityResponseToClaudeNonStream(_ context.Context, _ string, or
 	return string(encoded)
 }
 
+// attachCitations adds grounding citations to the last text block, which closes the grounded
+// answer.
+func attachCitations(contentBlocks []interface{}, citations []common.Citation) {
+	for i := len(contentBlocks) - 1; i >= 0; i-- {
+		if block, ok := contentBlocks[i].(map[string]interface{}); ok && block["type"] == "text" {
+			block["citations"] = json.RawMessage(common.ClaudeCitations(citations))
+			return
+		}
+	}
+}
+
 func ClaudeTokenCount(ctx context.Context, count int64) string {
 	return fmt.Sprintf(`{"input_tokens":%d}`, count)
 }
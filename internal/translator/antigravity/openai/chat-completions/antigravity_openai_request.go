@@ -36,35 +36,24 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 	out, _ = sjson.SetBytes(out, "model", modelName)
 
 	// Reasoning effort -> thinkingBudget/include_thoughts
-	// Note: OpenAI official fields take precedence over extra_body.google.thinking_config
+	// Note: an explicit budget in extra_body.google.thinking_config takes precedence over reasoning_effort
 	re := gjson.GetBytes(rawJSON, "reasoning_effort")
-	hasOfficialThinking := re.Exists()
-	if hasOfficialThinking && util.ModelSupportsThinking(modelName) {
-		switch re.String() {
-		case "none":
+	tc := gjson.GetBytes(rawJSON, "extra_body.google.thinking_config")
+	hasExplicitBudget := tc.IsObject() && (tc.Get("thinkingBudget").Exists() || tc.Get("thinking_budget").Exists())
+	useEffort := re.Exists() && !hasExplicitBudget
+	if useEffort && util.ModelSupportsThinking(modelName) {
+		if re.String() == "none" {
 			out, _ = sjson.DeleteBytes(out, "request.generationConfig.thinkingConfig.include_thoughts")
 			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", 0)
-		case "auto":
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", -1)
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
-		case "low":
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.NormalizeThinkingBudget(modelName, 1024))
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
-		case "medium":
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.NormalizeThinkingBudget(modelName, 8192))
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
-		case "high":
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.NormalizeThinkingBudget(modelName, 32768))
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
-		default:
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", -1)
+		} else {
+			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.ReasoningEffortToBudget(modelName, re.String()))
 			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
 		}
 	}
 
-	// Cherry Studio extension extra_body.google.thinking_config (effective only when official fields are absent)
-	if !hasOfficialThinking && util.ModelSupportsThinking(modelName) {
-		if tc := gjson.GetBytes(rawJSON, "extra_body.google.thinking_config"); tc.Exists() && tc.IsObject() {
+	// Cherry Studio extension extra_body.google.thinking_config (effective unless reasoning_effort is used)
+	if !useEffort && util.ModelSupportsThinking(modelName) {
+		if tc.IsObject() {
 			var setBudget bool
 			var normalized int
 
@@ -95,7 +84,7 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 	// For models that should enable thinking, set default thinkingConfig when none specified.
 	// This matches the Antigravity2api behavior which always sends thinkingConfig for thinking models.
 	if !gjson.GetBytes(out, "request.generationConfig.thinkingConfig").Exists() && enableThinking {
-		out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.DefaultThinkingBudget)
+		out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.DefaultThinkingBudgetFor(modelName))
 		out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
 	}
 
@@ -109,6 +98,14 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 	if tkr := gjson.GetBytes(rawJSON, "top_k"); tkr.Exists() && tkr.Type == gjson.Number {
 		out, _ = sjson.SetBytes(out, "request.generationConfig.topK", tkr.Num)
 	}
+	// Seed for reproducible sampling
+	if sr := gjson.GetBytes(rawJSON, "seed"); sr.Exists() && sr.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "request.generationConfig.seed", sr.Int())
+	}
+	// Stop sequences: a string or an array, capped at Gemini's limit
+	if stop := util.NormalizeStopSequences(gjson.GetBytes(rawJSON, "stop"), util.GeminiMaxStopSequences, "Gemini"); len(stop) > 0 {
+		out, _ = sjson.SetBytes(out, "request.generationConfig.stopSequences", stop)
+	}
 
 	// Per Antigravity2api reference: when thinking is enabled for Claude models, remove topP.
 	// This is required for proper thinking chain operation with Claude models via Antigravity.
@@ -116,6 +113,11 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 		out, _ = sjson.DeleteBytes(out, "request.generationConfig.topP")
 	}
 
+	// Structured output: response_format -> request.generationConfig.responseMimeType/responseSchema
+	if rf := gjson.GetBytes(rawJSON, "response_format"); rf.IsObject() && !util.IsAntigravityClaudeModel(modelName) {
+		out = common.ApplyOpenAIResponseFormat(out, rf, "request.generationConfig")
+	}
+
 	// Map OpenAI modalities -> Gemini CLI request.generationConfig.responseModalities
 	// e.g. "modalities": ["image", "text"] -> ["IMAGE", "TEXT"]
 	if mods := gjson.GetBytes(rawJSON, "modalities"); mods.Exists() && mods.IsArray() {
@@ -182,21 +184,16 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 			}
 		}
 
+		hasNonSystem := common.HasNonSystemMessage(arr)
 		for i := 0; i < len(arr); i++ {
 			m := arr[i]
 			role := m.Get("role").String()
 			content := m.Get("content")
 
-			if role == "system" && len(arr) > 1 {
-				// system -> request.systemInstruction as a user message style
-				if content.Type == gjson.String {
-					out, _ = sjson.SetBytes(out, "request.systemInstruction.role", "user")
-					out, _ = sjson.SetBytes(out, "request.systemInstruction.parts.0.text", content.String())
-				} else if content.IsObject() && content.Get("type").String() == "text" {
-					out, _ = sjson.SetBytes(out, "request.systemInstruction.role", "user")
-					out, _ = sjson.SetBytes(out, "request.systemInstruction.parts.0.text", content.Get("text").String())
-				}
-			} else if role == "user" || (role == "system" && len(arr) == 1) {
+			if role == "system" && hasNonSystem {
+				// system -> request.systemInstruction, concatenated in message order
+				out = common.AppendSystemInstruction(out, "request.systemInstruction", content)
+			} else if role == "user" || role == "system" {
 				// Build single user content node to avoid splitting into multiple contents
 				node := []byte(`{"role":"user","parts":[]}`)
 				if content.Type == gjson.String {
@@ -211,7 +208,10 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 							p++
 						case "image_url":
 							imageURL := item.Get("image_url.url").String()
-							if len(imageURL) > 5 {
+							if updated, ok := common.SetRemoteImageFileData(node, "parts."+itoa(p), imageURL); ok {
+								node = updated
+								p++
+							} else if len(imageURL) > 5 {
 								pieces := strings.SplitN(imageURL[5:], ";", 2)
 								if len(pieces) == 2 && len(pieces[1]) > 7 {
 									mime := pieces[0]
@@ -343,6 +343,7 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 						}
 					}
 					fnRaw, _ = sjson.Delete(fnRaw, "strict")
+					fnRaw = common.SanitizeFunctionDeclaration(fnRaw)
 					if !hasFunction {
 						toolNode, _ = sjson.SetRawBytes(toolNode, "functionDeclarations", []byte("[]"))
 					}
@@ -13,6 +13,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/finishreason"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -195,18 +197,7 @@ func ConvertClaudeResponseToGemini(_ context.Context, modelName string, original
 		// Handle message-level changes (like stop reason and usage information)
 		if delta := root.Get("delta"); delta.Exists() {
 			if stopReason := delta.Get("stop_reason"); stopReason.Exists() {
-				switch stopReason.String() {
-				case "end_turn":
-					template, _ = sjson.Set(template, "candidates.0.finishReason", "STOP")
-				case "tool_use":
-					template, _ = sjson.Set(template, "candidates.0.finishReason", "STOP")
-				case "max_tokens":
-					template, _ = sjson.Set(template, "candidates.0.finishReason", "MAX_TOKENS")
-				case "stop_sequence":
-					template, _ = sjson.Set(template, "candidates.0.finishReason", "STOP")
-				default:
-					template, _ = sjson.Set(template, "candidates.0.finishReason", "STOP")
-				}
+				template, _ = sjson.Set(template, "candidates.0.finishReason", finishreason.Normalize(constant.Claude, constant.Gemini, stopReason.String(), false))
 			}
 		}
 
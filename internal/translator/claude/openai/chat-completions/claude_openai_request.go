@@ -16,6 +16,8 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -35,6 +37,8 @@ var (
 // 3. Tool call and tool result handling with proper ID mapping
 // 4. Image data conversion from OpenAI data URLs to Claude Code base64 format
 // 5. Stop sequence and streaming configuration handling
+// 6. Prompt caching markers via the "cache_control" extension field on messages, content parts and tools
+// 7. JSON response_format emulation through a system instruction
 //
 // Parameters:
 //   - modelName: The name of the model to use for the request
@@ -65,18 +69,17 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 
 	root := gjson.ParseBytes(rawJSON)
 
-	if v := root.Get("reasoning_effort"); v.Exists() {
-		out, _ = sjson.Set(out, "thinking.type", "enabled")
-
-		switch v.String() {
+	// Reasoning effort -> thinking. Claude has no dynamic budget, so "auto" and unknown
+	// efforts are dropped rather than sent as enabled thinking without a budget.
+	if v := root.Get("reasoning_effort"); v.Exists() && util.ModelSupportsThinking(modelName) {
+		switch effort := strings.ToLower(strings.TrimSpace(v.String())); effort {
 		case "none":
 			out, _ = sjson.Set(out, "thinking.type", "disabled")
-		case "low":
-			out, _ = sjson.Set(out, "thinking.budget_tokens", 1024)
-		case "medium":
-			out, _ = sjson.Set(out, "thinking.budget_tokens", 8192)
-		case "high":
-			out, _ = sjson.Set(out, "thinking.budget_tokens", 24576)
+		case "minimal", "low", "medium", "high", "xhigh":
+			if budget := util.ReasoningEffortToBudget(modelName, effort); budget > 0 {
+				out, _ = sjson.Set(out, "thinking.type", "enabled")
+				out, _ = sjson.Set(out, "thinking.budget_tokens", budget)
+			}
 		}
 	}
 
@@ -112,27 +115,43 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 	}
 
 	// Stop sequences configuration for custom termination conditions
-	if stop := root.Get("stop"); stop.Exists() {
-		if stop.IsArray() {
-			var stopSequences []string
-			stop.ForEach(func(_, value gjson.Result) bool {
-				stopSequences = append(stopSequences, value.String())
-				return true
-			})
-			if len(stopSequences) > 0 {
-				out, _ = sjson.Set(out, "stop_sequences", stopSequences)
-			}
-		} else {
-			out, _ = sjson.Set(out, "stop_sequences", []string{stop.String()})
+	if stop := util.DropWhitespaceStopSequences(util.NormalizeStopSequences(root.Get("stop"), 0, "Claude")); len(stop) > 0 {
+		out, _ = sjson.Set(out, "stop_sequences", stop)
+	}
+
+	// Claude has no seed parameter; the response translator flags the result as nondeterministic
+	if root.Get("seed").Exists() {
+		log.Warnf("seed is not supported by Claude model %s and was dropped", modelName)
+	}
+
+	// Claude has no frequency or presence penalty; 0 is the OpenAI default and dropped silently
+	for _, penalty := range []string{"frequency_penalty", "presence_penalty"} {
+		if value := root.Get(penalty); value.Type == gjson.Number && value.Num != 0 {
+			log.Warnf("%s is not supported by Claude model %s and was dropped", penalty, modelName)
 		}
 	}
 
+	// An explicit OpenAI end-user identifier replaces the generated metadata.user_id
+	if user, err := util.NormalizeEndUser(root.Get("user")); err == nil && user != "" {
+		out, _ = sjson.Set(out, "metadata.user_id", user)
+	}
+
+	// OpenAI service tiers map onto Claude's: "auto" and "priority" may use Priority Tier
+	// capacity, "default" and "flex" are served from standard capacity only
+	switch root.Get("service_tier").String() {
+	case "auto", "priority":
+		out, _ = sjson.Set(out, "service_tier", "auto")
+	case "default", "flex":
+		out, _ = sjson.Set(out, "service_tier", "standard_only")
+	}
+
 	// Stream configuration to enable or disable streaming responses
 	out, _ = sjson.Set(out, "stream", stream)
 
 	// Process messages and transform them to Claude Code format
 	var anthropicMessages []interface{}
-	var toolCallIDs []string // Track tool call IDs for matching with tool results
+	var systemBlocks []interface{} // System messages, moved to the top-level system field
+	var toolCallIDs []string       // Track tool call IDs for matching with tool results
 
 	if messages := root.Get("messages"); messages.Exists() && messages.IsArray() {
 		messages.ForEach(func(_, message gjson.Result) bool {
@@ -140,12 +159,16 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 			contentResult := message.Get("content")
 
 			switch role {
-			case "system", "user", "assistant":
-				// Create Claude Code message with appropriate role mapping
-				if role == "system" {
-					role = "user"
+			case "system":
+				// The Messages API rejects system-role messages; their text goes to the top-level
+				// system field, in order, as separate blocks.
+				blocks := systemTextBlocks(contentResult)
+				if len(blocks) > 0 {
+					// A message-level cache_control marks the end of the message as a cache breakpoint.
+					applyCacheControl(blocks[len(blocks)-1].(map[string]interface{}), message)
 				}
-
+				systemBlocks = append(systemBlocks, blocks...)
+			case "user", "assistant":
 				msg := map[string]interface{}{
 					"role":    role,
 					"content": []interface{}{},
@@ -154,12 +177,11 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 				// Handle content based on its type (string or array)
 				if contentResult.Exists() && contentResult.Type == gjson.String && contentResult.String() != "" {
 					// Simple text content conversion
-					msg["content"] = []interface{}{
-						map[string]interface{}{
-							"type": "text",
-							"text": contentResult.String(),
-						},
+					textPart := map[string]interface{}{
+						"type": "text",
+						"text": contentResult.String(),
 					}
+					msg["content"] = []interface{}{textPart}
 				} else if contentResult.Exists() && contentResult.IsArray() {
 					// Array of content parts processing
 					var contentParts []interface{}
@@ -169,10 +191,12 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 						switch partType {
 						case "text":
 							// Text part conversion
-							contentParts = append(contentParts, map[string]interface{}{
+							textPart := map[string]interface{}{
 								"type": "text",
 								"text": part.Get("text").String(),
-							})
+							}
+							applyCacheControl(textPart, part)
+							contentParts = append(contentParts, textPart)
 
 						case "image_url":
 							// Convert OpenAI image format to Claude Code format
@@ -185,14 +209,16 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 									mediaType := strings.TrimPrefix(mediaTypePart, "data:")
 									data := parts[1]
 
-									contentParts = append(contentParts, map[string]interface{}{
+									imagePart := map[string]interface{}{
 										"type": "image",
 										"source": map[string]interface{}{
 											"type":       "base64",
 											"media_type": mediaType,
 											"data":       data,
 										},
-									})
+									}
+									applyCacheControl(imagePart, part)
+									contentParts = append(contentParts, imagePart)
 								}
 							}
 						}
@@ -230,22 +256,9 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 								"name": function.Get("name").String(),
 							}
 
-							// Parse arguments for the tool call
-							if args := function.Get("arguments"); args.Exists() {
-								argsStr := args.String()
-								if argsStr != "" {
-									var argsMap map[string]interface{}
-									if err := json.Unmarshal([]byte(argsStr), &argsMap); err == nil {
-										toolUse["input"] = argsMap
-									} else {
-										toolUse["input"] = map[string]interface{}{}
-									}
-								} else {
-									toolUse["input"] = map[string]interface{}{}
-								}
-							} else {
-								toolUse["input"] = map[string]interface{}{}
-							}
+							// Forward the arguments object verbatim so nested values and large
+							// numbers survive the round trip unchanged
+							toolUse["input"] = toolUseInput(function.Get("arguments"))
 
 							contentParts = append(contentParts, toolUse)
 						}
@@ -254,23 +267,46 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 					msg["content"] = contentParts
 				}
 
+				// Replay signed thinking from a previous assistant turn; Claude requires it to lead the
+				// content when extended thinking is combined with tool use.
+				if role == "assistant" {
+					if thinkingBlock := thinkingBlockFromMessage(message); thinkingBlock != nil {
+						existing, _ := msg["content"].([]interface{})
+						msg["content"] = append([]interface{}{thinkingBlock}, existing...)
+					}
+				}
+
+				// A message-level cache_control marks the end of the message as a cache breakpoint.
+				if contentParts, ok := msg["content"].([]interface{}); ok && len(contentParts) > 0 {
+					if lastPart, okPart := contentParts[len(contentParts)-1].(map[string]interface{}); okPart {
+						applyCacheControl(lastPart, message)
+					}
+				}
+
 				anthropicMessages = append(anthropicMessages, msg)
 
 			case "tool":
 				// Handle tool result messages conversion
 				toolCallID := message.Get("tool_call_id").String()
-				content := message.Get("content").String()
 
 				// Create tool result message in Claude Code format
+				toolResult := map[string]interface{}{
+					"type":        "tool_result",
+					"tool_use_id": toolCallID,
+					"content":     toolResultContent(contentResult),
+				}
+				applyCacheControl(toolResult, message)
+
+				// Claude expects the results of parallel tool calls together in the user turn
+				// that follows the assistant's tool_use blocks.
+				if last := len(anthropicMessages) - 1; last >= 0 && isToolResultMessage(anthropicMessages[last]) {
+					previous := anthropicMessages[last].(map[string]interface{})
+					previous["content"] = append(previous["content"].([]interface{}), toolResult)
+					return true
+				}
 				msg := map[string]interface{}{
-					"role": "user",
-					"content": []interface{}{
-						map[string]interface{}{
-							"type":        "tool_result",
-							"tool_use_id": toolCallID,
-							"content":     content,
-						},
-					},
+					"role":    "user",
+					"content": []interface{}{toolResult},
 				}
 
 				anthropicMessages = append(anthropicMessages, msg)
@@ -302,6 +338,7 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 				} else if parameters = function.Get("parametersJsonSchema"); parameters.Exists() {
 					anthropicTool["input_schema"] = parameters.Value()
 				}
+				applyCacheControl(anthropicTool, tool)
 
 				anthropicTools = append(anthropicTools, anthropicTool)
 			}
@@ -340,5 +377,133 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 		}
 	}
 
+	// Claude has no native structured output mode, so emulate response_format with a system instruction.
+	if instruction := responseFormatInstruction(root.Get("response_format")); instruction != "" {
+		systemBlocks = append(systemBlocks, map[string]interface{}{"type": "text", "text": instruction})
+	}
+	if len(systemBlocks) > 0 {
+		out, _ = sjson.Set(out, "system", systemBlocks)
+	}
+
 	return []byte(out)
 }
+
+// systemTextBlocks converts the content of an OpenAI system message into Claude system text
+// blocks. Array content keeps one block per text part, with its cache_control; Claude system
+// prompts only accept text, so other parts are dropped.
+func systemTextBlocks(content gjson.Result) []interface{} {
+	var blocks []interface{}
+	if content.Type == gjson.String {
+		if text := content.String(); text != "" {
+			blocks = append(blocks, map[string]interface{}{"type": "text", "text": text})
+		}
+		return blocks
+	}
+	content.ForEach(func(_, part gjson.Result) bool {
+		if part.Get("type").String() != "text" || part.Get("text").String() == "" {
+			return true
+		}
+		block := map[string]interface{}{"type": "text", "text": part.Get("text").String()}
+		applyCacheControl(block, part)
+		blocks = append(blocks, block)
+		return true
+	})
+	return blocks
+}
+
+// toolUseInput converts OpenAI function call arguments, a JSON-encoded string, into the
+// Claude tool_use input object. The arguments are kept as raw JSON rather than decoded so
+// key order and numeric precision are preserved; anything that is not a JSON object
+// becomes an empty input.
+func toolUseInput(arguments gjson.Result) interface{} {
+	argsStr := strings.TrimSpace(arguments.String())
+	if arguments.IsObject() {
+		argsStr = arguments.Raw
+	}
+	if argsStr == "" || !gjson.Valid(argsStr) || !gjson.Parse(argsStr).IsObject() {
+		return map[string]interface{}{}
+	}
+	return json.RawMessage(argsStr)
+}
+
+// toolResultContent converts the content of an OpenAI tool message into tool_result content:
+// plain strings pass through and arrays of text parts become Claude text blocks.
+func toolResultContent(content gjson.Result) interface{} {
+	if !content.IsArray() {
+		return content.String()
+	}
+	var blocks []interface{}
+	content.ForEach(func(_, part gjson.Result) bool {
+		if part.Get("type").String() == "text" {
+			blocks = append(blocks, map[string]interface{}{"type": "text", "text": part.Get("text").String()})
+		}
+		return true
+	})
+	if len(blocks) == 0 {
+		return ""
+	}
+	return blocks
+}
+
+// isToolResultMessage reports whether a translated message is a user turn holding only
+// tool_result blocks.
+func isToolResultMessage(message interface{}) bool {
+	msg, ok := message.(map[string]interface{})
+	if !ok || msg["role"] != "user" {
+		return false
+	}
+	parts, ok := msg["content"].([]interface{})
+	if !ok || len(parts) == 0 {
+		return false
+	}
+	for _, part := range parts {
+		block, okBlock := part.(map[string]interface{})
+		if !okBlock || block["type"] != "tool_result" {
+			return false
+		}
+	}
+	return true
+}
+
+// responseFormatInstruction renders an OpenAI response_format as a prompt instruction asking
+// the model to answer with bare JSON, embedding the schema for json_schema requests.
+func responseFormatInstruction(responseFormat gjson.Result) string {
+	switch responseFormat.Get("type").String() {
+	case "json_object":
+		return "Respond only with a single valid JSON object. Do not wrap it in code fences or add any other text."
+	case "json_schema":
+		schema := responseFormat.Get("json_schema.schema")
+		if !schema.IsObject() {
+			return "Respond only with a single valid JSON object. Do not wrap it in code fences or add any other text."
+		}
+		return "Respond only with a single valid JSON value that conforms to the following JSON Schema. " +
+			"Do not wrap it in code fences or add any other text.\n\nJSON Schema:\n" + schema.Raw
+	}
+	return ""
+}
+
+// thinkingBlockFromMessage rebuilds a Claude thinking block from the reasoning_content and
+// reasoning_signature fields that the response translator attaches to assistant messages.
+// Unsigned reasoning is dropped because Claude rejects thinking blocks without a signature.
+func thinkingBlockFromMessage(message gjson.Result) map[string]interface{} {
+	signature := message.Get("reasoning_signature").String()
+	if signature == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"type":      "thinking",
+		"thinking":  message.Get("reasoning_content").String(),
+		"signature": signature,
+	}
+}
+
+// applyCacheControl copies an Anthropic prompt caching marker from the OpenAI-side
+// extension field "cache_control" onto the translated Claude block. Only object
+// values carrying a type (e.g. {"type":"ephemeral"}) are forwarded.
+func applyCacheControl(block map[string]interface{}, source gjson.Result) {
+	cacheControl := source.Get("cache_control")
+	if !cacheControl.IsObject() || cacheControl.Get("type").String() == "" {
+		return
+	}
+	block["cache_control"] = cacheControl.Value()
+}
@@ -0,0 +1,234 @@
+package chat_completions
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIRequestToClaude_CacheControl(t *testing.T) {
+	raw := `{"model":"claude-sonnet-4-5-20250929","messages":[
+		{"role":"user","content":[
+			{"type":"text","text":"reference document","cache_control":{"type":"ephemeral"}},
+			{"type":"text","text":"question"}
+		]},
+		{"role":"assistant","content":"answer","cache_control":{"type":"ephemeral","ttl":"1h"}},
+		{"role":"tool","tool_call_id":"call_1","content":"result","cache_control":"bogus"}
+	],"tools":[{"type":"function","function":{"name":"lookup","parameters":{"type":"object"}},"cache_control":{"type":"ephemeral"}}]}`
+
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5-20250929", []byte(raw), false)
+
+	if got := gjson.GetBytes(out, "messages.0.content.0.cache_control.type").String(); got != "ephemeral" {
+		t.Errorf("Expected part-level cache_control to be preserved, got %s", out)
+	}
+	if gjson.GetBytes(out, "messages.0.content.1.cache_control").Exists() {
+		t.Errorf("Expected unmarked part to stay uncached, got %s", out)
+	}
+	if got := gjson.GetBytes(out, "messages.1.content.0.cache_control.ttl").String(); got != "1h" {
+		t.Errorf("Expected message-level cache_control on the last block, got %s", out)
+	}
+	if gjson.GetBytes(out, "messages.2.content.0.cache_control").Exists() {
+		t.Errorf("Expected malformed cache_control to be ignored, got %s", out)
+	}
+	if got := gjson.GetBytes(out, "tools.0.cache_control.type").String(); got != "ephemeral" {
+		t.Errorf("Expected tool cache_control to be preserved, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_ResponseFormatEmulation(t *testing.T) {
+	raw := `{"model":"claude-sonnet-4-5-20250929","messages":[{"role":"user","content":"Describe a user"}],
+		"response_format":{"type":"json_schema","json_schema":{"name":"user","schema":{"type":"object",
+			"properties":{"name":{"type":"string"},"address":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}},
+			"required":["name","address"]}}}}`
+
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5-20250929", []byte(raw), false)
+
+	instruction := gjson.GetBytes(out, "system.0.text").String()
+	if !strings.Contains(instruction, "JSON Schema") || !strings.Contains(instruction, `"city":{"type":"string"}`) {
+		t.Fatalf("Expected schema instruction in system prompt, got %s", out)
+	}
+	if gjson.GetBytes(out, "response_format").Exists() {
+		t.Fatalf("Expected response_format not to be forwarded to Claude, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_SeedDropped(t *testing.T) {
+	original := `{"model":"claude-sonnet-4-5","seed":12345,"messages":[{"role":"user","content":"hi"}]}`
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(original), false)
+	if gjson.GetBytes(out, "seed").Exists() {
+		t.Fatalf("Expected seed to be dropped, got %s", out)
+	}
+
+	upstream := `data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":5}}}` + "\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}` + "\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`
+	resp := ConvertClaudeResponseToOpenAINonStream(context.Background(), "claude-sonnet-4-5", []byte(original), out, []byte(upstream), nil)
+	if !gjson.Get(resp, "nondeterministic").Bool() {
+		t.Fatalf("Expected the response to be flagged nondeterministic, got %s", resp)
+	}
+	resp = ConvertClaudeResponseToOpenAINonStream(context.Background(), "claude-sonnet-4-5", []byte(`{"messages":[]}`), out, []byte(upstream), nil)
+	if gjson.Get(resp, "nondeterministic").Exists() {
+		t.Fatalf("Expected no flag without a seed, got %s", resp)
+	}
+
+	var param any
+	chunks := ConvertClaudeResponseToOpenAI(context.Background(), "claude-sonnet-4-5", []byte(original), out, []byte(strings.Split(upstream, "\n")[0]), &param)
+	if len(chunks) != 1 || !gjson.Get(chunks[0], "nondeterministic").Bool() {
+		t.Fatalf("Expected streamed chunks to be flagged nondeterministic, got %v", chunks)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_StopSequences(t *testing.T) {
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(`{"model":"claude-sonnet-4-5","stop":"END","messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "stop_sequences").Raw; got != `["END"]` {
+		t.Fatalf("Expected a string stop to become a single stop sequence, got %s", out)
+	}
+	out = ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(`{"model":"claude-sonnet-4-5","stop":["a"," ","a","b"],"messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "stop_sequences").Raw; got != `["a","b"]` {
+		t.Fatalf("Expected deduplicated stop sequences without whitespace, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_PenaltiesDropped(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(`{"frequency_penalty":0.5,"presence_penalty":0,"messages":[{"role":"user","content":"hi"}]}`), false)
+	for _, field := range []string{"frequency_penalty", "presence_penalty", "frequencyPenalty", "presencePenalty"} {
+		if gjson.GetBytes(out, field).Exists() {
+			t.Fatalf("Expected %s to be dropped, got %s", field, out)
+		}
+	}
+
+	var warnings []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == log.WarnLevel {
+			warnings = append(warnings, entry.Message)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "frequency_penalty") {
+		t.Fatalf("Expected a single warning for the non-zero frequency_penalty, got %v", warnings)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_ForwardsEndUser(t *testing.T) {
+	raw := `{"model":"claude-sonnet-4-5","user":" end-user-7\n","messages":[{"role":"user","content":"hi"}]}`
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(raw), false)
+	if got := gjson.GetBytes(out, "metadata.user_id").String(); got != "end-user-7" {
+		t.Fatalf("Expected sanitized user in metadata.user_id, got %q in %s", got, out)
+	}
+	if gjson.GetBytes(out, "user").Exists() {
+		t.Fatalf("Expected OpenAI user field to be dropped, got %s", out)
+	}
+
+	out = ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "metadata.user_id").String(); !strings.HasPrefix(got, "user_") {
+		t.Fatalf("Expected the generated user_id without a user, got %q", got)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_ServiceTier(t *testing.T) {
+	cases := map[string]string{
+		`"auto"`:     "auto",
+		`"priority"`: "auto",
+		`"default"`:  "standard_only",
+		`"flex"`:     "standard_only",
+	}
+	for tier, want := range cases {
+		raw := `{"model":"claude-sonnet-4-5","service_tier":` + tier + `,"messages":[{"role":"user","content":"hi"}]}`
+		out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(raw), false)
+		if got := gjson.GetBytes(out, "service_tier").String(); got != want {
+			t.Errorf("Expected service_tier %s to map to %q, got %q", tier, want, got)
+		}
+	}
+
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}]}`), false)
+	if gjson.GetBytes(out, "service_tier").Exists() {
+		t.Fatalf("Expected no service_tier without one in the request, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_SystemMessage(t *testing.T) {
+	raw := `{"model":"claude-sonnet-4-5","messages":[{"role":"system","content":"You are terse."},{"role":"user","content":"hi"}]}`
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(raw), false)
+
+	if system := gjson.GetBytes(out, "system").Array(); len(system) != 1 || system[0].Get("type").String() != "text" || system[0].Get("text").String() != "You are terse." {
+		t.Fatalf("Expected the system message as the top-level system field, got %s", out)
+	}
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 1 || messages[0].Get("role").String() != "user" || messages[0].Get("content.0.text").String() != "hi" {
+		t.Fatalf("Expected only the user message in messages, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_MultipleSystemMessages(t *testing.T) {
+	raw := `{"model":"claude-sonnet-4-5","response_format":{"type":"json_object"},"messages":[
+		{"role":"system","content":"You are terse."},
+		{"role":"user","content":"hi"},
+		{"role":"system","content":[{"type":"text","text":"Policy A"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}},{"type":"text","text":"Policy B","cache_control":{"type":"ephemeral"}}]},
+		{"role":"assistant","content":"hello"}]}`
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(raw), false)
+
+	system := gjson.GetBytes(out, "system").Array()
+	if len(system) != 4 {
+		t.Fatalf("Expected four system blocks, got %s", gjson.GetBytes(out, "system").Raw)
+	}
+	for i, want := range []string{"You are terse.", "Policy A", "Policy B"} {
+		if system[i].Get("type").String() != "text" || system[i].Get("text").String() != want {
+			t.Fatalf("system block %d = %s, want text %q", i, system[i].Raw, want)
+		}
+	}
+	if system[2].Get("cache_control.type").String() != "ephemeral" || system[1].Get("cache_control").Exists() {
+		t.Fatalf("Expected cache_control only on the marked block, got %s", gjson.GetBytes(out, "system").Raw)
+	}
+	if !strings.Contains(system[3].Get("text").String(), "JSON") {
+		t.Fatalf("Expected the response_format instruction after the system messages, got %s", system[3].Raw)
+	}
+	for _, message := range gjson.GetBytes(out, "messages").Array() {
+		if message.Get("role").String() == "system" {
+			t.Fatalf("Expected no system-role message, got %s", out)
+		}
+	}
+	if roles := gjson.GetBytes(out, "messages.#.role").Raw; roles != `["user","assistant"]` {
+		t.Fatalf("Expected user and assistant messages only, got %s", roles)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_ReasoningEffort(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("claude-effort-test", "claude", []*registry.ModelInfo{
+		{ID: "claude-effort-thinking", Thinking: &registry.ThinkingSupport{Min: 1024, Max: 32000}},
+		{ID: "claude-effort-plain"},
+	})
+	t.Cleanup(func() { reg.UnregisterClient("claude-effort-test") })
+
+	cases := []struct {
+		name, model, effort string
+		wantType            string
+		wantBudget          int64
+	}{
+		{"minimal", "claude-effort-thinking", "minimal", "enabled", 1024},
+		{"high", "claude-effort-thinking", "high", "enabled", 32000},
+		{"none", "claude-effort-thinking", "none", "disabled", 0},
+		{"auto has no fixed budget", "claude-effort-thinking", "auto", "", 0},
+		{"unknown effort", "claude-effort-thinking", "extreme", "", 0},
+		{"non-thinking model", "claude-effort-plain", "high", "", 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := `{"model":"` + tc.model + `","reasoning_effort":"` + tc.effort + `","messages":[{"role":"user","content":"hi"}]}`
+			out := ConvertOpenAIRequestToClaude(tc.model, []byte(raw), false)
+			if got := gjson.GetBytes(out, "thinking.type").String(); got != tc.wantType {
+				t.Fatalf("Expected thinking.type %q, got %s", tc.wantType, out)
+			}
+			if got := gjson.GetBytes(out, "thinking.budget_tokens").Int(); got != tc.wantBudget {
+				t.Fatalf("Expected budget_tokens %d, got %s", tc.wantBudget, out)
+			}
+		})
+	}
+}
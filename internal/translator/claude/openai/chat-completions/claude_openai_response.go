@@ -12,6 +12,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/finishreason"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -25,15 +28,28 @@ type ConvertAnthropicResponseToOpenAIParams struct {
 	CreatedAt    int64
 	ResponseID   string
 	FinishReason string
-	// Tool calls accumulator for streaming
+	// Tool calls accumulator for streaming, keyed by Claude content block index
 	ToolCallsAccumulator map[int]*ToolCallAccumulator
+	// ToolCallCount numbers tool calls in the order OpenAI clients expect, independent of
+	// text and thinking blocks interleaved by Claude
+	ToolCallCount int
+	// InputTokens and OutputTokens accumulate usage across message_start and message_delta
+	InputTokens  int64
+	OutputTokens int64
+	HasUsage     bool
+	// ServiceTier is the OpenAI service_tier matching the tier Claude served the request at,
+	// reported when the client asked for one
+	ServiceTier string
 }
 
 // ToolCallAccumulator holds the state for accumulating tool call data
 type ToolCallAccumulator struct {
+	Index     int
 	ID        string
 	Name      string
 	Arguments strings.Builder
+	// Input is the input object sent with content_block_start, used when no deltas follow
+	Input string
 }
 
 // ConvertClaudeResponseToOpenAI converts Claude Code streaming response format to OpenAI Chat Completions format.
@@ -74,6 +90,11 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 		template, _ = sjson.Set(template, "model", modelName)
 	}
 
+	// Claude cannot honor a requested seed, so tell the client the sampling was not reproducible
+	if seedIgnored(originalRequestRawJSON) {
+		template, _ = sjson.Set(template, "nondeterministic", true)
+	}
+
 	// Set response ID and creation time
 	if (*param).(*ConvertAnthropicResponseToOpenAIParams).ResponseID != "" {
 		template, _ = sjson.Set(template, "id", (*param).(*ConvertAnthropicResponseToOpenAIParams).ResponseID)
@@ -81,6 +102,9 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 	if (*param).(*ConvertAnthropicResponseToOpenAIParams).CreatedAt > 0 {
 		template, _ = sjson.Set(template, "created", (*param).(*ConvertAnthropicResponseToOpenAIParams).CreatedAt)
 	}
+	if tier := (*param).(*ConvertAnthropicResponseToOpenAIParams).ServiceTier; tier != "" {
+		template, _ = sjson.Set(template, "service_tier", tier)
+	}
 
 	switch eventType {
 	case "message_start":
@@ -96,6 +120,17 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 			// Set initial role to assistant for the response
 			template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
 
+			// Claude reports prompt tokens up front; output tokens arrive in message_delta
+			if usage := message.Get("usage"); usage.Exists() {
+				(*param).(*ConvertAnthropicResponseToOpenAIParams).InputTokens = usage.Get("input_tokens").Int()
+				(*param).(*ConvertAnthropicResponseToOpenAIParams).OutputTokens = usage.Get("output_tokens").Int()
+				(*param).(*ConvertAnthropicResponseToOpenAIParams).HasUsage = true
+				(*param).(*ConvertAnthropicResponseToOpenAIParams).ServiceTier = openAIServiceTier(originalRequestRawJSON, usage)
+				if tier := (*param).(*ConvertAnthropicResponseToOpenAIParams).ServiceTier; tier != "" {
+					template, _ = sjson.Set(template, "service_tier", tier)
+				}
+			}
+
 			// Initialize tool calls accumulator for tracking tool call progress
 			if (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator == nil {
 				(*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator = make(map[int]*ToolCallAccumulator)
@@ -119,9 +154,12 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 				}
 
 				(*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator[index] = &ToolCallAccumulator{
-					ID:   toolCallID,
-					Name: toolName,
+					Index: (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallCount,
+					ID:    toolCallIDOrSynthesized(toolCallID),
+					Name:  toolName,
+					Input: contentBlock.Get("input").Raw,
 				}
+				(*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallCount++
 
 				// Don't output anything yet - wait for complete tool call
 				return []string{}
@@ -143,11 +181,17 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 					hasContent = true
 				}
 			case "thinking_delta":
-				// Accumulate reasoning/thinking content
-				if thinking := delta.Get("thinking"); thinking.Exists() {
+				// Surface reasoning/thinking content unless the client opted out
+				if thinking := delta.Get("thinking"); thinking.Exists() && includeReasoning(originalRequestRawJSON) {
 					template, _ = sjson.Set(template, "choices.0.delta.reasoning_content", thinking.String())
 					hasContent = true
 				}
+			case "signature_delta":
+				// Forward the thinking signature so clients can replay the block on the next turn
+				if signature := delta.Get("signature"); signature.Exists() && includeReasoning(originalRequestRawJSON) {
+					template, _ = sjson.Set(template, "choices.0.delta.reasoning_signature", signature.String())
+					hasContent = true
+				}
 			case "input_json_delta":
 				// Tool use input delta - accumulate arguments for tool calls
 				if partialJSON := delta.Get("partial_json"); partialJSON.Exists() {
@@ -174,13 +218,10 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 		if (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator != nil {
 			if accumulator, exists := (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator[index]; exists {
 				// Build complete tool call with accumulated arguments
-				arguments := accumulator.Arguments.String()
-				if arguments == "" {
-					arguments = "{}"
-				}
+				arguments := toolCallArguments(accumulator.Arguments.String(), accumulator.Input)
 
 				toolCall := map[string]interface{}{
-					"index": index,
+					"index": accumulator.Index,
 					"id":    accumulator.ID,
 					"type":  "function",
 					"function": map[string]interface{}{
@@ -210,6 +251,14 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 
 		// Handle usage information for token counts
 		if usage := root.Get("usage"); usage.Exists() {
+			state := (*param).(*ConvertAnthropicResponseToOpenAIParams)
+			if inputTokens := usage.Get("input_tokens"); inputTokens.Exists() && inputTokens.Int() > 0 {
+				state.InputTokens = inputTokens.Int()
+			}
+			if outputTokens := usage.Get("output_tokens"); outputTokens.Exists() {
+				state.OutputTokens = outputTokens.Int()
+			}
+			state.HasUsage = true
 			usageObj := map[string]interface{}{
 				"prompt_tokens":     usage.Get("input_tokens").Int(),
 				"completion_tokens": usage.Get("output_tokens").Int(),
@@ -220,7 +269,17 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 		return []string{template}
 
 	case "message_stop":
-		// Final message event - no additional output needed
+		// Final message event - emit the accumulated usage when the client requested it
+		state := (*param).(*ConvertAnthropicResponseToOpenAIParams)
+		if state.HasUsage && gjson.GetBytes(originalRequestRawJSON, "stream_options.include_usage").Bool() {
+			template, _ = sjson.SetRaw(template, "choices", `[]`)
+			template, _ = sjson.Set(template, "usage", map[string]interface{}{
+				"prompt_tokens":     state.InputTokens,
+				"completion_tokens": state.OutputTokens,
+				"total_tokens":      state.InputTokens + state.OutputTokens,
+			})
+			return []string{template}
+		}
 		return []string{}
 
 	case "ping":
@@ -249,18 +308,56 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 
 // mapAnthropicStopReasonToOpenAI maps Anthropic stop reasons to OpenAI stop reasons
 func mapAnthropicStopReasonToOpenAI(anthropicReason string) string {
-	switch anthropicReason {
-	case "end_turn":
-		return "stop"
-	case "tool_use":
-		return "tool_calls"
-	case "max_tokens":
-		return "length"
-	case "stop_sequence":
-		return "stop"
-	default:
-		return "stop"
+	return finishreason.Normalize(constant.Claude, constant.OpenAI, anthropicReason, false)
+}
+
+// toolCallArguments returns the OpenAI arguments string for a Claude tool_use block: the
+// streamed input JSON verbatim, falling back to the input sent with content_block_start.
+func toolCallArguments(streamed, initial string) string {
+	if streamed != "" {
+		return streamed
+	}
+	if initial = strings.TrimSpace(initial); initial != "" && initial != "null" {
+		return initial
 	}
+	return "{}"
+}
+
+// toolCallIDOrSynthesized keeps the Claude tool_use id so tool results map back to it on the
+// next turn, synthesizing an OpenAI-style id when the upstream omitted one.
+func toolCallIDOrSynthesized(id string) string {
+	if id != "" {
+		return id
+	}
+	return "call_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// openAIServiceTier returns the OpenAI service_tier for the tier Claude reports in usage, or ""
+// when the client did not ask for a service tier.
+func openAIServiceTier(originalRequestRawJSON []byte, usage gjson.Result) string {
+	if !gjson.GetBytes(originalRequestRawJSON, "service_tier").Exists() {
+		return ""
+	}
+	switch usage.Get("service_tier").String() {
+	case "priority":
+		return "priority"
+	case "standard", "batch":
+		return "default"
+	}
+	return ""
+}
+
+// seedIgnored reports whether the client asked for a seed, which Claude does not support.
+func seedIgnored(originalRequestRawJSON []byte) bool {
+	return gjson.GetBytes(originalRequestRawJSON, "seed").Exists()
+}
+
+// includeReasoning reports whether thinking content should be surfaced to the client.
+// Reasoning is included by default; clients send "include_reasoning": false to receive
+// only the final answer.
+func includeReasoning(originalRequestRawJSON []byte) bool {
+	v := gjson.GetBytes(originalRequestRawJSON, "include_reasoning")
+	return !v.Exists() || v.Bool()
 }
 
 // ConvertClaudeResponseToOpenAINonStream converts a non-streaming Claude Code response to a non-streaming OpenAI response.
@@ -295,13 +392,16 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 	var createdAt int64
 	var inputTokens, outputTokens int64
 	var reasoningTokens int64
+	var serviceTier string
 	var stopReason string
 	var contentParts []string
 	var reasoningParts []string
+	var reasoningSignature string
 	// Use map to track tool calls by index for proper merging
 	toolCallsMap := make(map[int]map[string]interface{})
-	// Track tool call arguments accumulation
-	toolCallArgsMap := make(map[int]strings.Builder)
+	// Track tool call arguments accumulation; builders must not be copied once written
+	toolCallArgsMap := make(map[int]*strings.Builder)
+	toolCallInputMap := make(map[int]string)
 
 	for _, chunk := range chunks {
 		root := gjson.ParseBytes(chunk)
@@ -316,6 +416,7 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 				createdAt = time.Now().Unix()
 				if usage := message.Get("usage"); usage.Exists() {
 					inputTokens = usage.Get("input_tokens").Int()
+					serviceTier = openAIServiceTier(originalRequestRawJSON, usage)
 				}
 			}
 
@@ -330,7 +431,7 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 					// Initialize tool call tracking for this index
 					index := int(root.Get("index").Int())
 					toolCallsMap[index] = map[string]interface{}{
-						"id":   contentBlock.Get("id").String(),
+						"id":   toolCallIDOrSynthesized(contentBlock.Get("id").String()),
 						"type": "function",
 						"function": map[string]interface{}{
 							"name":      contentBlock.Get("name").String(),
@@ -338,7 +439,8 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 						},
 					}
 					// Initialize arguments builder for this tool call
-					toolCallArgsMap[index] = strings.Builder{}
+					toolCallArgsMap[index] = &strings.Builder{}
+					toolCallInputMap[index] = contentBlock.Get("input").Raw
 				}
 			}
 
@@ -357,13 +459,17 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 					if thinking := delta.Get("thinking"); thinking.Exists() {
 						reasoningParts = append(reasoningParts, thinking.String())
 					}
+				case "signature_delta":
+					// Keep the signature of the thinking block for multi-turn replay
+					if signature := delta.Get("signature"); signature.Exists() {
+						reasoningSignature = signature.String()
+					}
 				case "input_json_delta":
 					// Accumulate tool call arguments
 					if partialJSON := delta.Get("partial_json"); partialJSON.Exists() {
 						index := int(root.Get("index").Int())
 						if builder, exists := toolCallArgsMap[index]; exists {
 							builder.WriteString(partialJSON.String())
-							toolCallArgsMap[index] = builder
 						}
 					}
 				}
@@ -375,11 +481,7 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 			if toolCall, exists := toolCallsMap[index]; exists {
 				if builder, argsExists := toolCallArgsMap[index]; argsExists {
 					// Set the accumulated arguments for the tool call
-					arguments := builder.String()
-					if arguments == "" {
-						arguments = "{}"
-					}
-					toolCall["function"].(map[string]interface{})["arguments"] = arguments
+					toolCall["function"].(map[string]interface{})["arguments"] = toolCallArguments(builder.String(), toolCallInputMap[index])
 				}
 			}
 
@@ -402,18 +504,27 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 
 	// Set basic response fields including message ID, creation time, and model
 	out, _ = sjson.Set(out, "id", messageID)
+	if seedIgnored(originalRequestRawJSON) {
+		out, _ = sjson.Set(out, "nondeterministic", true)
+	}
 	out, _ = sjson.Set(out, "created", createdAt)
 	out, _ = sjson.Set(out, "model", model)
+	if serviceTier != "" {
+		out, _ = sjson.Set(out, "service_tier", serviceTier)
+	}
 
 	// Set message content by combining all text parts
 	messageContent := strings.Join(contentParts, "")
 	out, _ = sjson.Set(out, "choices.0.message.content", messageContent)
 
-	// Add reasoning content if available (following OpenAI reasoning format)
-	if len(reasoningParts) > 0 {
+	// Add reasoning content if available, unless the client opted out of seeing it
+	if len(reasoningParts) > 0 && includeReasoning(originalRequestRawJSON) {
 		reasoningContent := strings.Join(reasoningParts, "")
 		// Add reasoning as a separate field in the message
-		out, _ = sjson.Set(out, "choices.0.message.reasoning", reasoningContent)
+		out, _ = sjson.Set(out, "choices.0.message.reasoning_content", reasoningContent)
+		if reasoningSignature != "" {
+			out, _ = sjson.Set(out, "choices.0.message.reasoning_signature", reasoningSignature)
+		}
 	}
 
 	// Set tool calls if any were accumulated during processing
@@ -435,10 +546,8 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 		}
 		if len(toolCallsArray) > 0 {
 			out, _ = sjson.Set(out, "choices.0.message.tool_calls", toolCallsArray)
-			out, _ = sjson.Set(out, "choices.0.finish_reason", "tool_calls")
-		} else {
-			out, _ = sjson.Set(out, "choices.0.finish_reason", mapAnthropicStopReasonToOpenAI(stopReason))
 		}
+		out, _ = sjson.Set(out, "choices.0.finish_reason", finishreason.Normalize(constant.Claude, constant.OpenAI, stopReason, len(toolCallsArray) > 0))
 	} else {
 		out, _ = sjson.Set(out, "choices.0.finish_reason", mapAnthropicStopReasonToOpenAI(stopReason))
 	}
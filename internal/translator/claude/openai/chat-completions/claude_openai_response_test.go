@@ -0,0 +1,257 @@
+package chat_completions
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertClaudeResponseToOpenAI_IncludeUsage(t *testing.T) {
+	events := []string{
+		`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":12}}`,
+	}
+
+	run := func(original string) []string {
+		var param any
+		for _, event := range events {
+			ConvertClaudeResponseToOpenAI(context.Background(), "claude-sonnet-4-5", []byte(original), nil, []byte(event), &param)
+		}
+		return ConvertClaudeResponseToOpenAI(context.Background(), "claude-sonnet-4-5", []byte(original), nil, []byte(`data: {"type":"message_stop"}`), &param)
+	}
+
+	final := run(`{"stream":true,"stream_options":{"include_usage":true}}`)
+	if len(final) != 1 {
+		t.Fatalf("Expected a trailing usage chunk, got %v", final)
+	}
+	if got := gjson.Get(final[0], "usage.prompt_tokens").Int(); got != 25 {
+		t.Errorf("Expected prompt_tokens from message_start (25), got %d", got)
+	}
+	if got := gjson.Get(final[0], "usage.completion_tokens").Int(); got != 12 {
+		t.Errorf("Expected completion_tokens from message_delta (12), got %d", got)
+	}
+	if got := gjson.Get(final[0], "usage.total_tokens").Int(); got != 37 {
+		t.Errorf("Expected total_tokens 37, got %d", got)
+	}
+	if got := gjson.Get(final[0], "id").String(); got != "msg_1" {
+		t.Errorf("Expected id msg_1, got %s", got)
+	}
+
+	if final = run(`{"stream":true}`); len(final) != 0 {
+		t.Errorf("Expected no usage chunk without include_usage, got %v", final)
+	}
+}
+
+func TestConvertClaudeResponseToOpenAI_Thinking(t *testing.T) {
+	upstream := strings.Join([]string{
+		`data: {"type":"message_start","message":{"id":"msg_2","usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me think."}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-abc"}}`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"42"}}`,
+		`data: {"type":"content_block_stop","index":1}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":20}}`,
+		`data: {"type":"message_stop"}`,
+	}, "\n")
+
+	t.Run("non-stream round trip", func(t *testing.T) {
+		original := `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"What is the answer?"}]}`
+		out := ConvertClaudeResponseToOpenAINonStream(context.Background(), "claude-sonnet-4-5", []byte(original), nil, []byte(upstream), nil)
+		message := gjson.Get(out, "choices.0.message")
+		if got := message.Get("reasoning_content").String(); got != "Let me think." {
+			t.Fatalf("Expected reasoning_content, got %q in %s", got, out)
+		}
+		if got := message.Get("reasoning_signature").String(); got != "sig-abc" {
+			t.Fatalf("Expected reasoning_signature sig-abc, got %q", got)
+		}
+		if got := message.Get("content").String(); got != "42" {
+			t.Fatalf("Expected content 42, got %q", got)
+		}
+
+		// Replay the assistant message on the next turn; the signed thinking block must lead.
+		next := `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"What is the answer?"},` +
+			message.Raw + `,{"role":"user","content":"Why?"}]}`
+		req := gjson.ParseBytes(ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(next), false))
+		assistant := req.Get("messages.1")
+		if assistant.Get("role").String() != "assistant" {
+			t.Fatalf("Expected assistant message at index 1, got %s", req.Get("messages").Raw)
+		}
+		block := assistant.Get("content.0")
+		if block.Get("type").String() != "thinking" || block.Get("thinking").String() != "Let me think." || block.Get("signature").String() != "sig-abc" {
+			t.Fatalf("Expected signed thinking block first, got %s", assistant.Get("content").Raw)
+		}
+		if got := assistant.Get("content.1.text").String(); got != "42" {
+			t.Fatalf("Expected text after thinking block, got %s", assistant.Get("content").Raw)
+		}
+	})
+
+	t.Run("non-stream opt out", func(t *testing.T) {
+		original := `{"model":"claude-sonnet-4-5","include_reasoning":false,"messages":[{"role":"user","content":"What is the answer?"}]}`
+		out := ConvertClaudeResponseToOpenAINonStream(context.Background(), "claude-sonnet-4-5", []byte(original), nil, []byte(upstream), nil)
+		message := gjson.Get(out, "choices.0.message")
+		if message.Get("reasoning_content").Exists() || message.Get("reasoning_signature").Exists() {
+			t.Fatalf("Expected no reasoning fields, got %s", message.Raw)
+		}
+		if got := message.Get("content").String(); got != "42" {
+			t.Fatalf("Expected content 42, got %q", got)
+		}
+	})
+
+	stream := func(original string) (reasoning, signature, content string) {
+		var param any
+		for _, event := range strings.Split(upstream, "\n") {
+			for _, chunk := range ConvertClaudeResponseToOpenAI(context.Background(), "claude-sonnet-4-5", []byte(original), nil, []byte(event), &param) {
+				delta := gjson.Get(chunk, "choices.0.delta")
+				reasoning += delta.Get("reasoning_content").String()
+				signature += delta.Get("reasoning_signature").String()
+				content += delta.Get("content").String()
+			}
+		}
+		return reasoning, signature, content
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		reasoning, signature, content := stream(`{"stream":true}`)
+		if reasoning != "Let me think." || signature != "sig-abc" || content != "42" {
+			t.Fatalf("Unexpected stream output: reasoning=%q signature=%q content=%q", reasoning, signature, content)
+		}
+	})
+
+	t.Run("stream opt out", func(t *testing.T) {
+		reasoning, signature, content := stream(`{"stream":true,"include_reasoning":false}`)
+		if reasoning != "" || signature != "" || content != "42" {
+			t.Fatalf("Unexpected stream output: reasoning=%q signature=%q content=%q", reasoning, signature, content)
+		}
+	})
+}
+
+func TestConvertOpenAIRequestToClaude_UnsignedReasoningDropped(t *testing.T) {
+	input := `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"Hi"},{"role":"assistant","content":"Hello","reasoning_content":"unsigned"},{"role":"user","content":"Again"}]}`
+	req := gjson.ParseBytes(ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(input), false))
+	if got := req.Get("messages.1.content.0.type").String(); got != "text" {
+		t.Fatalf("Expected unsigned reasoning to be dropped, got %s", req.Get("messages.1.content").Raw)
+	}
+}
+
+func TestConvertClaudeResponseToOpenAI_ToolCallRoundTrip(t *testing.T) {
+	// Two arguments, one of them nested, streamed in fragments that split keys and values.
+	arguments := `{"location":{"city":"Paris","coords":[48.8566,2.3522]},"unit":"celsius"}`
+	upstream := strings.Join([]string{
+		`data: {"type":"message_start","message":{"id":"msg_3","usage":{"input_tokens":30,"output_tokens":1}}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking."}}`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_weather","name":"get_weather","input":{}}}`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"location\":{\"ci"}}`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"ty\":\"Paris\",\"coords\":[48.8566,"}}`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"2.3522]},\"unit\":\"celsius\"}"}}`,
+		`data: {"type":"content_block_stop","index":1}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":40}}`,
+		`data: {"type":"message_stop"}`,
+	}, "\n")
+
+	t.Run("stream", func(t *testing.T) {
+		var param any
+		var toolCalls []gjson.Result
+		finish := ""
+		for _, event := range strings.Split(upstream, "\n") {
+			for _, chunk := range ConvertClaudeResponseToOpenAI(context.Background(), "claude-sonnet-4-5", []byte(`{"stream":true}`), nil, []byte(event), &param) {
+				toolCalls = append(toolCalls, gjson.Get(chunk, "choices.0.delta.tool_calls").Array()...)
+				if reason := gjson.Get(chunk, "choices.0.finish_reason").String(); reason != "" {
+					finish = reason
+				}
+			}
+		}
+		if len(toolCalls) != 1 {
+			t.Fatalf("Expected one tool call, got %v", toolCalls)
+		}
+		call := toolCalls[0]
+		if call.Get("index").Int() != 0 || call.Get("id").String() != "toolu_weather" || call.Get("function.name").String() != "get_weather" {
+			t.Fatalf("Unexpected tool call %s", call.Raw)
+		}
+		if got := call.Get("function.arguments").String(); got != arguments {
+			t.Fatalf("Expected arguments %s, got %s", arguments, got)
+		}
+		if finish != "tool_calls" {
+			t.Fatalf("Expected finish_reason tool_calls, got %q", finish)
+		}
+	})
+
+	out := ConvertClaudeResponseToOpenAINonStream(context.Background(), "claude-sonnet-4-5", nil, nil, []byte(upstream), nil)
+	message := gjson.Get(out, "choices.0.message")
+	call := message.Get("tool_calls.0")
+	if call.Get("id").String() != "toolu_weather" || call.Get("type").String() != "function" || call.Get("function.arguments").String() != arguments {
+		t.Fatalf("Unexpected non-stream tool call in %s", out)
+	}
+
+	// Send the assistant turn back with the tool's result.
+	next := `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"Weather in Paris?"},` + message.Raw +
+		`,{"role":"tool","tool_call_id":"toolu_weather","content":"18C and sunny"}]}`
+	req := gjson.ParseBytes(ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(next), false))
+	toolUse := req.Get("messages.1.content.1")
+	if toolUse.Get("type").String() != "tool_use" || toolUse.Get("id").String() != "toolu_weather" || toolUse.Get("name").String() != "get_weather" {
+		t.Fatalf("Expected tool_use block after text, got %s", req.Get("messages.1.content").Raw)
+	}
+	if toolUse.Get("input").Raw != arguments {
+		t.Fatalf("Expected input %s, got %s", arguments, toolUse.Get("input").Raw)
+	}
+	toolResult := req.Get("messages.2.content.0")
+	if req.Get("messages.2.role").String() != "user" || toolResult.Get("type").String() != "tool_result" ||
+		toolResult.Get("tool_use_id").String() != "toolu_weather" || toolResult.Get("content").String() != "18C and sunny" {
+		t.Fatalf("Expected tool_result for toolu_weather, got %s", req.Get("messages.2").Raw)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_ParallelToolResults(t *testing.T) {
+	input := `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"Compare"},
+		{"role":"assistant","content":null,"tool_calls":[
+			{"id":"call_a","type":"function","function":{"name":"lookup","arguments":"{\"id\":12345678901234567890}"}},
+			{"id":"call_b","type":"function","function":{"name":"lookup","arguments":"not json"}}]},
+		{"role":"tool","tool_call_id":"call_a","content":[{"type":"text","text":"first"}]},
+		{"role":"tool","tool_call_id":"call_b","content":"second"}]}`
+	req := gjson.ParseBytes(ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(input), false))
+	if got := req.Get("messages.1.content.0.input").Raw; got != `{"id":12345678901234567890}` {
+		t.Fatalf("Expected large integer to survive, got %s", got)
+	}
+	if got := req.Get("messages.1.content.1.input").Raw; got != `{}` {
+		t.Fatalf("Expected invalid arguments to become an empty input, got %s", got)
+	}
+	if n := len(req.Get("messages").Array()); n != 3 {
+		t.Fatalf("Expected tool results merged into one user turn, got %s", req.Get("messages").Raw)
+	}
+	results := req.Get("messages.2.content")
+	if results.Get("0.tool_use_id").String() != "call_a" || results.Get("0.content.0.text").String() != "first" ||
+		results.Get("1.tool_use_id").String() != "call_b" || results.Get("1.content").String() != "second" {
+		t.Fatalf("Unexpected tool results %s", results.Raw)
+	}
+}
+
+func TestConvertClaudeResponseToOpenAI_ServiceTier(t *testing.T) {
+	start := `data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":5,"output_tokens":1,"service_tier":"priority"}}}`
+	delta := `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`
+
+	var param any
+	original := []byte(`{"stream":true,"service_tier":"auto"}`)
+	for _, event := range []string{start, delta} {
+		for _, chunk := range ConvertClaudeResponseToOpenAI(context.Background(), "claude-sonnet-4-5", original, nil, []byte(event), &param) {
+			if got := gjson.Get(chunk, "service_tier").String(); got != "priority" {
+				t.Fatalf("Expected every chunk to report the priority tier, got %s", chunk)
+			}
+		}
+	}
+
+	body := []byte(strings.Join([]string{start, delta}, "\n"))
+	out := ConvertClaudeResponseToOpenAINonStream(context.Background(), "", []byte(`{"service_tier":"default"}`), nil, body, nil)
+	if got := gjson.Get(out, "service_tier").String(); got != "priority" {
+		t.Fatalf("Expected the non-streaming response to report the priority tier, got %s", out)
+	}
+	out = ConvertClaudeResponseToOpenAINonStream(context.Background(), "", []byte(`{}`), nil, body, nil)
+	if gjson.Get(out, "service_tier").Exists() {
+		t.Fatalf("Expected no service_tier when the client did not ask for one, got %s", out)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -52,6 +53,11 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 
 	root := gjson.ParseBytes(rawJSON)
 
+	// An explicit end-user identifier replaces the generated one
+	if endUser, err := util.NormalizeEndUser(root.Get("user")); err == nil && endUser != "" {
+		out, _ = sjson.Set(out, "metadata.user_id", endUser)
+	}
+
 	if v := root.Get("reasoning.effort"); v.Exists() {
 		out, _ = sjson.Set(out, "thinking.type", "enabled")
 
@@ -61,6 +61,9 @@ func ConvertCodexResponseToClaude(_ context.Context, _ string, originalRequestRa
 
 		output = "event: message_start\n"
 		output += fmt.Sprintf("data: %s\n\n", template)
+		output += "event: ping\n"
+		output += `data: {"type":"ping"}`
+		output += "\n\n"
 	} else if typeStr == "response.reasoning_summary_part.added" {
 		template = `{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`
 		template, _ = sjson.Set(template, "index", rootResult.Get("output_index").Int())
@@ -57,6 +57,10 @@ func ConvertCodexResponseToOpenAI(_ context.Context, modelName string, originalR
 
 	// Initialize the OpenAI SSE template.
 	template := `{"id":"","object":"chat.completion.chunk","created":12345,"model":"model","choices":[{"index":0,"delta":{"role":null,"content":null,"reasoning_content":null,"tool_calls":null},"finish_reason":null,"native_finish_reason":null}]}`
+	// Codex has no seed parameter, so a requested seed cannot make the output reproducible.
+	if gjson.GetBytes(originalRequestRawJSON, "seed").Exists() {
+		template, _ = sjson.Set(template, "nondeterministic", true)
+	}
 
 	rootResult := gjson.ParseBytes(rawJSON)
 
@@ -176,6 +180,9 @@ func ConvertCodexResponseToOpenAINonStream(_ context.Context, _ string, original
 	responseResult := rootResult.Get("response")
 
 	template := `{"id":"","object":"chat.completion","created":123456,"model":"model","choices":[{"index":0,"message":{"role":"assistant","content":null,"reasoning_content":null,"tool_calls":null},"finish_reason":null,"native_finish_reason":null}]}`
+	if gjson.GetBytes(originalRequestRawJSON, "seed").Exists() {
+		template, _ = sjson.Set(template, "nondeterministic", true)
+	}
 
 	// Extract and set the model version.
 	if modelResult := responseResult.Get("model"); modelResult.Exists() {
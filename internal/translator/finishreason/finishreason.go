@@ -0,0 +1,82 @@
+// Package finishreason normalizes the terminal reasons providers report for a response into
+// the vocabulary of the client's API format, so an OpenAI client always sees stop, length,
+// tool_calls or content_filter whichever upstream served the request.
+package finishreason
+
+import "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+
+// reason is the provider-neutral meaning of a terminal reason.
+type reason int
+
+const (
+	stop reason = iota
+	length
+	toolCalls
+	contentFilter
+)
+
+// fromNative maps each format's terminal reasons to their meaning. Reasons missing from a
+// table, such as Gemini's FINISH_REASON_UNSPECIFIED, are treated as a natural stop.
+var fromNative = map[string]map[string]reason{
+	constant.OpenAI: {
+		"stop":           stop,
+		"length":         length,
+		"tool_calls":     toolCalls,
+		"function_call":  toolCalls,
+		"content_filter": contentFilter,
+	},
+	constant.Claude: {
+		"end_turn":                      stop,
+		"stop_sequence":                 stop,
+		"pause_turn":                    stop,
+		"max_tokens":                    length,
+		"model_context_window_exceeded": length,
+		"tool_use":                      toolCalls,
+		"refusal":                       contentFilter,
+	},
+	constant.Gemini: {
+		"STOP":               stop,
+		"MAX_TOKENS":         length,
+		"SAFETY":             contentFilter,
+		"RECITATION":         contentFilter,
+		"BLOCKLIST":          contentFilter,
+		"PROHIBITED_CONTENT": contentFilter,
+		"SPII":               contentFilter,
+		"IMAGE_SAFETY":       contentFilter,
+	},
+}
+
+// toNative maps each meaning to the terminal reason of a format. Gemini has no tool call
+// reason and reports STOP after a function call.
+var toNative = map[string]map[reason]string{
+	constant.OpenAI: {stop: "stop", length: "length", toolCalls: "tool_calls", contentFilter: "content_filter"},
+	constant.Claude: {stop: "end_turn", length: "max_tokens", toolCalls: "tool_use", contentFilter: "refusal"},
+	constant.Gemini: {stop: "STOP", length: "MAX_TOKENS", toolCalls: "STOP", contentFilter: "SAFETY"},
+}
+
+// vocabulary returns the format whose finish reasons format uses: Gemini CLI and Antigravity
+// wrap Gemini responses, and the Responses API shares the chat vocabulary.
+func vocabulary(format string) string {
+	switch format {
+	case constant.GeminiCLI, constant.Antigravity:
+		return constant.Gemini
+	case constant.OpenaiResponse, constant.Codex:
+		return constant.OpenAI
+	}
+	return format
+}
+
+// Normalize maps the terminal reason native, reported in format from, to the vocabulary of
+// format to. A response that ended on a tool invocation reports the tool call reason unless
+// it was cut short or filtered. Unknown target formats get native unchanged.
+func Normalize(from, to, native string, endedOnToolCall bool) string {
+	target, ok := toNative[vocabulary(to)]
+	if !ok {
+		return native
+	}
+	meaning := fromNative[vocabulary(from)][native]
+	if endedOnToolCall && meaning == stop {
+		meaning = toolCalls
+	}
+	return target[meaning]
+}
@@ -0,0 +1,49 @@
+package finishreason
+
+import "testing"
+
+func TestNormalize_ToOpenAI(t *testing.T) {
+	for _, tc := range []struct {
+		from, native    string
+		endedOnToolCall bool
+		want            string
+	}{
+		{"gemini", "STOP", false, "stop"},
+		{"gemini", "MAX_TOKENS", false, "length"},
+		{"gemini", "SAFETY", false, "content_filter"},
+		{"gemini", "RECITATION", false, "content_filter"},
+		{"gemini", "FINISH_REASON_UNSPECIFIED", false, "stop"},
+		{"gemini", "STOP", true, "tool_calls"},
+		{"gemini", "MAX_TOKENS", true, "length"},
+		{"gemini-cli", "STOP", true, "tool_calls"},
+		{"antigravity", "SAFETY", false, "content_filter"},
+		{"claude", "end_turn", false, "stop"},
+		{"claude", "stop_sequence", false, "stop"},
+		{"claude", "max_tokens", false, "length"},
+		{"claude", "tool_use", false, "tool_calls"},
+		{"claude", "refusal", false, "content_filter"},
+		{"claude", "end_turn", true, "tool_calls"},
+	} {
+		if got := Normalize(tc.from, "openai", tc.native, tc.endedOnToolCall); got != tc.want {
+			t.Errorf("%s %s (tool call %t): got %q, want %q", tc.from, tc.native, tc.endedOnToolCall, got, tc.want)
+		}
+	}
+}
+
+func TestNormalize_BetweenProviderVocabularies(t *testing.T) {
+	if got := Normalize("gemini", "claude", "MAX_TOKENS", false); got != "max_tokens" {
+		t.Errorf("Expected max_tokens, got %q", got)
+	}
+	if got := Normalize("gemini", "claude", "STOP", true); got != "tool_use" {
+		t.Errorf("Expected tool_use, got %q", got)
+	}
+	if got := Normalize("claude", "gemini", "tool_use", false); got != "STOP" {
+		t.Errorf("Expected STOP, got %q", got)
+	}
+	if got := Normalize("openai", "claude", "content_filter", false); got != "refusal" {
+		t.Errorf("Expected refusal, got %q", got)
+	}
+	if got := Normalize("claude", "unknown-format", "end_turn", false); got != "end_turn" {
+		t.Errorf("Expected an unknown target format to pass the reason through, got %q", got)
+	}
+}
@@ -36,35 +36,24 @@ func ConvertOpenAIRequestToGeminiCLI(modelName string, inputRawJSON []byte, _ bo
 	out, _ = sjson.SetBytes(out, "model", modelName)
 
 	// Reasoning effort -> thinkingBudget/include_thoughts
-	// Note: OpenAI official fields take precedence over extra_body.google.thinking_config
+	// Note: an explicit budget in extra_body.google.thinking_config takes precedence over reasoning_effort
 	re := gjson.GetBytes(rawJSON, "reasoning_effort")
-	hasOfficialThinking := re.Exists()
-	if hasOfficialThinking && util.ModelSupportsThinking(modelName) {
-		switch re.String() {
-		case "none":
+	tc := gjson.GetBytes(rawJSON, "extra_body.google.thinking_config")
+	hasExplicitBudget := tc.IsObject() && (tc.Get("thinkingBudget").Exists() || tc.Get("thinking_budget").Exists())
+	useEffort := re.Exists() && !hasExplicitBudget
+	if useEffort && util.ModelSupportsThinking(modelName) {
+		if re.String() == "none" {
 			out, _ = sjson.DeleteBytes(out, "request.generationConfig.thinkingConfig.include_thoughts")
 			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", 0)
-		case "auto":
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", -1)
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
-		case "low":
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.NormalizeThinkingBudget(modelName, 1024))
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
-		case "medium":
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.NormalizeThinkingBudget(modelName, 8192))
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
-		case "high":
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.NormalizeThinkingBudget(modelName, 32768))
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
-		default:
-			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", -1)
+		} else {
+			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget", util.ReasoningEffortToBudget(modelName, re.String()))
 			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.include_thoughts", true)
 		}
 	}
 
-	// Cherry Studio extension extra_body.google.thinking_config (effective only when official fields are absent)
-	if !hasOfficialThinking && util.ModelSupportsThinking(modelName) {
-		if tc := gjson.GetBytes(rawJSON, "extra_body.google.thinking_config"); tc.Exists() && tc.IsObject() {
+	// Cherry Studio extension extra_body.google.thinking_config (effective unless reasoning_effort is used)
+	if !useEffort && util.ModelSupportsThinking(modelName) {
+		if tc.IsObject() {
 			var setBudget bool
 			var normalized int
 
@@ -107,6 +96,27 @@ func ConvertOpenAIRequestToGeminiCLI(modelName string, inputRawJSON []byte, _ bo
 	if tkr := gjson.GetBytes(rawJSON, "top_k"); tkr.Exists() && tkr.Type == gjson.Number {
 		out, _ = sjson.SetBytes(out, "request.generationConfig.topK", tkr.Num)
 	}
+	// Seed for reproducible sampling
+	if sr := gjson.GetBytes(rawJSON, "seed"); sr.Exists() && sr.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "request.generationConfig.seed", sr.Int())
+	}
+	// Frequency/presence penalties, clamped to the model's range. An explicit 0 is forwarded;
+	// absent or null values leave the Gemini default in place.
+	if fp := gjson.GetBytes(rawJSON, "frequency_penalty"); fp.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "request.generationConfig.frequencyPenalty", util.NormalizePenalty(modelName, fp.Num))
+	}
+	if pp := gjson.GetBytes(rawJSON, "presence_penalty"); pp.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "request.generationConfig.presencePenalty", util.NormalizePenalty(modelName, pp.Num))
+	}
+	// Stop sequences: a string or an array, capped at Gemini's limit
+	if stop := util.NormalizeStopSequences(gjson.GetBytes(rawJSON, "stop"), util.GeminiMaxStopSequences, "Gemini"); len(stop) > 0 {
+		out, _ = sjson.SetBytes(out, "request.generationConfig.stopSequences", stop)
+	}
+
+	// Structured output: response_format -> request.generationConfig.responseMimeType/responseSchema
+	if rf := gjson.GetBytes(rawJSON, "response_format"); rf.IsObject() {
+		out = common.ApplyOpenAIResponseFormat(out, rf, "request.generationConfig")
+	}
 
 	// Map OpenAI modalities -> Gemini CLI request.generationConfig.responseModalities
 	// e.g. "modalities": ["image", "text"] -> ["IMAGE", "TEXT"]
@@ -174,21 +184,16 @@ func ConvertOpenAIRequestToGeminiCLI(modelName string, inputRawJSON []byte, _ bo
 			}
 		}
 
+		hasNonSystem := common.HasNonSystemMessage(arr)
 		for i := 0; i < len(arr); i++ {
 			m := arr[i]
 			role := m.Get("role").String()
 			content := m.Get("content")
 
-			if role == "system" && len(arr) > 1 {
-				// system -> request.systemInstruction as a user message style
-				if content.Type == gjson.String {
-					out, _ = sjson.SetBytes(out, "request.systemInstruction.role", "user")
-					out, _ = sjson.SetBytes(out, "request.systemInstruction.parts.0.text", content.String())
-				} else if content.IsObject() && content.Get("type").String() == "text" {
-					out, _ = sjson.SetBytes(out, "request.systemInstruction.role", "user")
-					out, _ = sjson.SetBytes(out, "request.systemInstruction.parts.0.text", content.Get("text").String())
-				}
-			} else if role == "user" || (role == "system" && len(arr) == 1) {
+			if role == "system" && hasNonSystem {
+				// system -> request.systemInstruction, concatenated in message order
+				out = common.AppendSystemInstruction(out, "request.systemInstruction", content)
+			} else if role == "user" || role == "system" {
 				// Build single user content node to avoid splitting into multiple contents
 				node := []byte(`{"role":"user","parts":[]}`)
 				if content.Type == gjson.String {
@@ -203,7 +208,10 @@ func ConvertOpenAIRequestToGeminiCLI(modelName string, inputRawJSON []byte, _ bo
 							p++
 						case "image_url":
 							imageURL := item.Get("image_url.url").String()
-							if len(imageURL) > 5 {
+							if updated, ok := common.SetRemoteImageFileData(node, "parts."+itoa(p), imageURL); ok {
+								node = updated
+								p++
+							} else if len(imageURL) > 5 {
 								pieces := strings.SplitN(imageURL[5:], ";", 2)
 								if len(pieces) == 2 && len(pieces[1]) > 7 {
 									mime := pieces[0]
@@ -327,6 +335,7 @@ func ConvertOpenAIRequestToGeminiCLI(modelName string, inputRawJSON []byte, _ bo
 						}
 					}
 					fnRaw, _ = sjson.Delete(fnRaw, "strict")
+					fnRaw = common.SanitizeFunctionDeclaration(fnRaw)
 					if !hasFunction {
 						toolNode, _ = sjson.SetRawBytes(toolNode, "functionDeclarations", []byte("[]"))
 					}
@@ -12,6 +12,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/finishreason"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
 	. "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/openai/chat-completions"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -75,10 +78,21 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 
 	// Extract and set the finish reason.
 	if finishReasonResult := gjson.GetBytes(rawJSON, "response.candidates.0.finishReason"); finishReasonResult.Exists() {
-		template, _ = sjson.Set(template, "choices.0.finish_reason", finishReasonResult.String())
+		template, _ = sjson.Set(template, "choices.0.finish_reason", common.OpenAIFinishReason(finishReasonResult.String()))
 		template, _ = sjson.Set(template, "choices.0.native_finish_reason", finishReasonResult.String())
 	}
 
+	// Prompts blocked before any candidate was generated carry no finishReason at all.
+	if blockReason, safetyDetails, blocked := common.SafetyBlock(gjson.GetBytes(rawJSON, "response")); blocked {
+		template, _ = sjson.Set(template, "choices.0.finish_reason", "content_filter")
+		template, _ = sjson.Set(template, "choices.0.native_finish_reason", blockReason)
+		template, _ = sjson.SetRaw(template, "choices.0.content_filter", safetyDetails)
+	}
+
+	if citations := common.GroundingCitations(gjson.GetBytes(rawJSON, "response.candidates.0")); len(citations) > 0 {
+		template, _ = sjson.SetRaw(template, "choices.0.delta.annotations", common.OpenAIAnnotations(citations))
+	}
+
 	// Extract and set usage metadata (token counts).
 	if usageResult := gjson.GetBytes(rawJSON, "response.usageMetadata"); usageResult.Exists() {
 		if candidatesTokenCountResult := usageResult.Get("candidatesTokenCount"); candidatesTokenCountResult.Exists() {
@@ -97,7 +111,6 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 
 	// Process the main content part of the response.
 	partsResult := gjson.GetBytes(rawJSON, "response.candidates.0.content.parts")
-	hasFunctionCall := false
 	if partsResult.IsArray() {
 		partResults := partsResult.Array()
 		for i := 0; i < len(partResults); i++ {
@@ -133,7 +146,6 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 				template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
 			} else if functionCallResult.Exists() {
 				// Handle function call content.
-				hasFunctionCall = true
 				toolCallsResult := gjson.Get(template, "choices.0.delta.tool_calls")
 				functionCallIndex := (*param).(*convertCliResponseToOpenAIChatParams).FunctionIndex
 				(*param).(*convertCliResponseToOpenAIChatParams).FunctionIndex++
@@ -185,9 +197,10 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 		}
 	}
 
-	if hasFunctionCall {
-		template, _ = sjson.Set(template, "choices.0.finish_reason", "tool_calls")
-		template, _ = sjson.Set(template, "choices.0.native_finish_reason", "tool_calls")
+	// The final chunk reports tool_calls when any chunk of the stream carried a function call.
+	if finishReasonResult := gjson.GetBytes(rawJSON, "response.candidates.0.finishReason"); finishReasonResult.Exists() {
+		endedOnToolCall := (*param).(*convertCliResponseToOpenAIChatParams).FunctionIndex > 0
+		template, _ = sjson.Set(template, "choices.0.finish_reason", finishreason.Normalize(constant.GeminiCLI, constant.OpenAI, finishReasonResult.String(), endedOnToolCall))
 	}
 
 	return []string{template}
@@ -156,12 +156,13 @@ func ConvertClaudeRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 	// Map Anthropic thinking -> Gemini thinkingBudget/include_thoughts when enabled
 	if t := gjson.GetBytes(rawJSON, "thinking"); t.Exists() && t.IsObject() && util.ModelSupportsThinking(modelName) {
 		if t.Get("type").String() == "enabled" {
+			// Without an explicit budget, fall back to the configured per-model default.
+			budget := util.DefaultThinkingBudgetFor(modelName)
 			if b := t.Get("budget_tokens"); b.Exists() && b.Type == gjson.Number {
-				budget := int(b.Int())
-				budget = util.NormalizeThinkingBudget(modelName, budget)
-				out, _ = sjson.Set(out, "generationConfig.thinkingConfig.thinkingBudget", budget)
-				out, _ = sjson.Set(out, "generationConfig.thinkingConfig.include_thoughts", true)
+				budget = util.NormalizeThinkingBudget(modelName, int(b.Int()))
 			}
+			out, _ = sjson.Set(out, "generationConfig.thinkingConfig.thinkingBudget", budget)
+			out, _ = sjson.Set(out, "generationConfig.thinkingConfig.include_thoughts", true)
 		}
 	}
 	if v := gjson.GetBytes(rawJSON, "temperature"); v.Exists() && v.Type == gjson.Number {
@@ -14,6 +14,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/finishreason"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -79,6 +82,8 @@ func ConvertGeminiResponseToClaude(_ context.Context, _ string, originalRequestR
 			messageStartTemplate, _ = sjson.Set(messageStartTemplate, "message.id", responseIDResult.String())
 		}
 		output = output + fmt.Sprintf("data: %s\n\n\n", messageStartTemplate)
+		// Anthropic sends a ping right after message_start; clients may rely on it as a keepalive.
+		output = output + "event: ping\ndata: {\"type\":\"ping\"}\n\n\n"
 
 		(*param).(*Params).HasFirstResponse = true
 	}
@@ -211,27 +216,43 @@ func ConvertGeminiResponseToClaude(_ context.Context, _ string, originalRequestR
 		}
 	}
 
+	// Grounding citations belong to the text block still open at this point.
+	if (*param).(*Params).ResponseType == 1 {
+		output = output + common.ClaudeCitationEvents((*param).(*Params).ResponseIndex, common.GroundingCitations(gjson.GetBytes(rawJSON, "candidates.0")))
+	}
+
 	usageResult := gjson.GetBytes(rawJSON, "usageMetadata")
-	if usageResult.Exists() && bytes.Contains(rawJSON, []byte(`"finishReason"`)) {
-		if candidatesTokenCountResult := usageResult.Get("candidatesTokenCount"); candidatesTokenCountResult.Exists() {
+	candidatesTokenCountResult := usageResult.Get("candidatesTokenCount")
+	_, safetyDetails, blocked := common.SafetyBlock(gjson.ParseBytes(rawJSON))
+	// Process usage metadata and finish reason when present in the response. Blocked prompts
+	// end the message without a finishReason, so a content filter block always terminates it.
+	if blocked || (usageResult.Exists() && candidatesTokenCountResult.Exists() && bytes.Contains(rawJSON, []byte(`"finishReason"`))) {
+		// Close the final content block
+		if (*param).(*Params).ResponseType != 0 {
 			output = output + "event: content_block_stop\n"
 			output = output + fmt.Sprintf(`data: {"type":"content_block_stop","index":%d}`, (*param).(*Params).ResponseIndex)
 			output = output + "\n\n\n"
+		}
 
-			output = output + "event: message_delta\n"
-			output = output + `data: `
-
-			template := `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"input_tokens":0,"output_tokens":0}}`
-			if usedTool {
-				template = `{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"input_tokens":0,"output_tokens":0}}`
-			}
+		// Send the final message delta with usage information and stop reason
+		output = output + "event: message_delta\n"
+		output = output + `data: `
+
+		// Create the message delta template with the normalized stop reason, tool_use when tools
+		// were used in this response
+		template := `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"input_tokens":0,"output_tokens":0}}`
+		template, _ = sjson.Set(template, "delta.stop_reason", finishreason.Normalize(constant.Gemini, constant.Claude, gjson.GetBytes(rawJSON, "candidates.0.finishReason").String(), usedTool))
+		if blocked {
+			template, _ = sjson.Set(template, "delta.stop_reason", "refusal")
+			template, _ = sjson.SetRaw(template, "content_filter", safetyDetails)
+		}
 
-			thoughtsTokenCount := usageResult.Get("thoughtsTokenCount").Int()
-			template, _ = sjson.Set(template, "usage.output_tokens", candidatesTokenCountResult.Int()+thoughtsTokenCount)
-			template, _ = sjson.Set(template, "usage.input_tokens", usageResult.Get("promptTokenCount").Int())
+		// Include thinking tokens in output token count if present
+		thoughtsTokenCount := usageResult.Get("thoughtsTokenCount").Int()
+		template, _ = sjson.Set(template, "usage.output_tokens", candidatesTokenCountResult.Int()+thoughtsTokenCount)
+		template, _ = sjson.Set(template, "usage.input_tokens", usageResult.Get("promptTokenCount").Int())
 
-			output = output + template + "\n\n\n"
-		}
+		output = output + template + "\n\n\n"
 	}
 
 	return []string{output}
@@ -339,22 +360,17 @@ func ConvertGeminiResponseToClaudeNonStream(_ context.Context, _ string, origina
 	flushThinking()
 	flushText()
 
+	if citations := common.GroundingCitations(root.Get("candidates.0")); len(citations) > 0 {
+		attachCitations(contentBlocks, citations)
+	}
+
 	response["content"] = contentBlocks
 
-	stopReason := "end_turn"
-	if hasToolCall {
-		stopReason = "tool_use"
-	} else {
-		if finish := root.Get("candidates.0.finishReason"); finish.Exists() {
-			switch finish.String() {
-			case "MAX_TOKENS":
-				stopReason = "max_tokens"
-			case "STOP", "FINISH_REASON_UNSPECIFIED", "UNKNOWN":
-				stopReason = "end_turn"
-			default:
-				stopReason = "end_turn"
-			}
-		}
+	stopReason := finishreason.Normalize(constant.Gemini, constant.Claude, root.Get("candidates.0.finishReason").String(), hasToolCall)
+	if _, safetyDetails, blocked := common.SafetyBlock(root); blocked {
+		// Claude reports withheld output as a refusal; the Gemini details go in an extension field.
+		stopReason = "refusal"
+		response["content_filter"] = json.RawMessage(safetyDetails)
 	}
 	response["stop_reason"] = stopReason
 
@@ -371,6 +387,17 @@ func ConvertGeminiResponseToClaudeNonStream(_ context.Context, _ string, origina
 	return string(encoded)
 }
 
+// attachCitations adds grounding citations to the last text block, which closes the grounded
+// answer.
+func attachCitations(contentBlocks []interface{}, citations []common.Citation) {
+	for i := len(contentBlocks) - 1; i >= 0; i-- {
+		if block, ok := contentBlocks[i].(map[string]interface{}); ok && block["type"] == "text" {
+			block["citations"] = json.RawMessage(common.ClaudeCitations(citations))
+			return
+		}
+	}
+}
+
 func ClaudeTokenCount(ctx context.Context, count int64) string {
 	return fmt.Sprintf(`{"input_tokens":%d}`, count)
 }
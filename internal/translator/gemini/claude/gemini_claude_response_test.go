@@ -0,0 +1,121 @@
+package claude
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertGeminiResponseToClaude_EventOrder(t *testing.T) {
+	chunks := []string{
+		`{"candidates":[{"content":{"parts":[{"text":"Hi"}],"role":"model"}}],"modelVersion":"gemini-2.5-pro","responseId":"r1"}`,
+		`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":3}}`,
+		`[DONE]`,
+	}
+	var param any
+	var events []string
+	var toolInput string
+	for _, chunk := range chunks {
+		for _, out := range ConvertGeminiResponseToClaude(context.Background(), "gemini-2.5-pro", []byte(`{"stream":true}`), nil, []byte(chunk), &param) {
+			for _, line := range strings.Split(out, "\n") {
+				if name, ok := strings.CutPrefix(line, "event: "); ok {
+					events = append(events, name)
+				}
+				if data, ok := strings.CutPrefix(line, "data: "); ok && gjson.Get(data, "delta.type").String() == "input_json_delta" {
+					toolInput += gjson.Get(data, "delta.partial_json").String()
+				}
+			}
+		}
+	}
+
+	want := []string{
+		"message_start",
+		"ping",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta",
+		"message_stop",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("Unexpected event order:\n got %v\nwant %v", events, want)
+	}
+	if toolInput != `{"city":"Paris"}` {
+		t.Fatalf("Expected tool input streamed as input_json_delta, got %q", toolInput)
+	}
+}
+
+func TestConvertGeminiResponseToClaude_BlockedPrompt(t *testing.T) {
+	chunk := `{"promptFeedback":{"blockReason":"PROHIBITED_CONTENT"},"usageMetadata":{"promptTokenCount":6,"totalTokenCount":6}}`
+	var param any
+	var delta string
+	var events []string
+	for _, out := range ConvertGeminiResponseToClaude(context.Background(), "gemini-2.5-pro", []byte(`{"stream":true}`), nil, []byte(chunk), &param) {
+		for _, line := range strings.Split(out, "\n") {
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				events = append(events, name)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok && gjson.Get(data, "type").String() == "message_delta" {
+				delta = data
+			}
+		}
+	}
+	if want := []string{"message_start", "ping", "message_delta"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("Expected events %v, got %v", want, events)
+	}
+	if gjson.Get(delta, "delta.stop_reason").String() != "refusal" || gjson.Get(delta, "content_filter.reason").String() != "PROHIBITED_CONTENT" {
+		t.Fatalf("Expected a refusal with content_filter details, got %s", delta)
+	}
+
+	nonStream := ConvertGeminiResponseToClaudeNonStream(context.Background(), "", nil, nil, []byte(chunk), nil)
+	if gjson.Get(nonStream, "stop_reason").String() != "refusal" || gjson.Get(nonStream, "content_filter.source").String() != "prompt" {
+		t.Fatalf("Expected a refusal with content_filter details, got %s", nonStream)
+	}
+}
+
+func TestConvertGeminiResponseToClaude_GroundingCitations(t *testing.T) {
+	chunks := []string{
+		`{"candidates":[{"content":{"parts":[{"text":"Paris is the capital"}],"role":"model"}}],"responseId":"r1"}`,
+		`{"candidates":[{"content":{"parts":[{"text":" of France."}]},"finishReason":"STOP","groundingMetadata":{"groundingChunks":[{"web":{"uri":"https://example.com/paris","title":"example.com"}}],"groundingSupports":[{"segment":{"startIndex":0,"endIndex":30,"text":"Paris is the capital of France"},"groundingChunkIndices":[0]}]}}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":7}}`,
+	}
+	var param any
+	var events, deltaTypes []string
+	var citation string
+	for _, chunk := range chunks {
+		for _, out := range ConvertGeminiResponseToClaude(context.Background(), "gemini-2.5-pro", []byte(`{"stream":true}`), nil, []byte(chunk), &param) {
+			for _, line := range strings.Split(out, "\n") {
+				if name, ok := strings.CutPrefix(line, "event: "); ok {
+					events = append(events, name)
+				}
+				if data, ok := strings.CutPrefix(line, "data: "); ok && gjson.Get(data, "type").String() == "content_block_delta" {
+					deltaTypes = append(deltaTypes, gjson.Get(data, "delta.type").String())
+					if gjson.Get(data, "delta.type").String() == "citations_delta" {
+						citation = gjson.Get(data, "delta.citation").Raw
+					}
+				}
+			}
+		}
+	}
+	if want := []string{"text_delta", "text_delta", "citations_delta"}; !reflect.DeepEqual(deltaTypes, want) {
+		t.Fatalf("Expected deltas %v, got %v (events %v)", want, deltaTypes, events)
+	}
+	if events[len(events)-2] != "content_block_stop" {
+		t.Fatalf("Expected the citation before the block stop, got %v", events)
+	}
+	if gjson.Get(citation, "type").String() != "web_search_result_location" || gjson.Get(citation, "url").String() != "https://example.com/paris" || gjson.Get(citation, "cited_text").String() != "Paris is the capital of France" {
+		t.Fatalf("Unexpected citation: %s", citation)
+	}
+
+	nonStream := ConvertGeminiResponseToClaudeNonStream(context.Background(), "", nil, nil, []byte(chunks[1]), nil)
+	citations := gjson.Get(nonStream, "content.0.citations").Array()
+	if len(citations) != 1 || citations[0].Get("url").String() != "https://example.com/paris" || citations[0].Get("title").String() != "example.com" {
+		t.Fatalf("Expected the citation on the text block, got %s", nonStream)
+	}
+
+	plain := ConvertGeminiResponseToClaudeNonStream(context.Background(), "", nil, nil, []byte(`{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`), nil)
+	if gjson.Get(plain, "content.0.citations").Exists() {
+		t.Errorf("Expected no citations without grounding, got %s", plain)
+	}
+}
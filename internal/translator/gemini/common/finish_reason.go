@@ -0,0 +1,13 @@
+package common
+
+import (
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/finishreason"
+)
+
+// OpenAIFinishReason maps a Gemini candidate finishReason to the OpenAI Chat Completions
+// finish_reason. Stops on a stop sequence are reported by Gemini as STOP like natural ends,
+// so both become "stop"; the raw value remains available as native_finish_reason.
+func OpenAIFinishReason(finishReason string) string {
+	return finishreason.Normalize(constant.Gemini, constant.OpenAI, finishReason, false)
+}
@@ -0,0 +1,124 @@
+package common
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// functionSchemaKeywords lists the JSON Schema keywords supported by Gemini's
+// parametersJsonSchema for function declarations.
+var functionSchemaKeywords = map[string]struct{}{
+	"$id":                  {},
+	"$defs":                {},
+	"$ref":                 {},
+	"$anchor":              {},
+	"type":                 {},
+	"format":               {},
+	"title":                {},
+	"description":          {},
+	"enum":                 {},
+	"items":                {},
+	"prefixItems":          {},
+	"minItems":             {},
+	"maxItems":             {},
+	"minimum":              {},
+	"maximum":              {},
+	"anyOf":                {},
+	"oneOf":                {},
+	"properties":           {},
+	"additionalProperties": {},
+	"required":             {},
+	"propertyOrdering":     {},
+}
+
+// SanitizeFunctionDeclaration prepares the parametersJsonSchema of a Gemini function declaration.
+// Constraints Gemini understands (required, enum, nested properties, array items, ...) are kept
+// as sent; const becomes a single-value enum, and other unsupported keywords are removed with a
+// warning. The $schema marker is dropped silently.
+func SanitizeFunctionDeclaration(fnRaw string) string {
+	params := gjson.Get(fnRaw, "parametersJsonSchema")
+	if !params.IsObject() {
+		return fnRaw
+	}
+	sanitized, dropped := SanitizeFunctionSchema(params.Raw)
+	if sanitized == "" {
+		return fnRaw
+	}
+	if len(dropped) > 0 {
+		log.Warnf("tool %q: dropped schema keywords unsupported by Gemini: %s", gjson.Get(fnRaw, "name").String(), strings.Join(dropped, ", "))
+	}
+	out, err := sjson.SetRaw(fnRaw, "parametersJsonSchema", sanitized)
+	if err != nil {
+		return fnRaw
+	}
+	return out
+}
+
+// SanitizeFunctionSchema reduces a JSON Schema document to the keywords Gemini accepts for
+// function parameters. It returns the sanitized schema and the sorted, de-duplicated list of
+// dropped keywords.
+func SanitizeFunctionSchema(raw string) (string, []string) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+		return "", nil
+	}
+	delete(schema, "$schema")
+	dropped := make(map[string]struct{})
+	sanitizeFunctionSchemaNode(schema, dropped)
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return "", nil
+	}
+	keywords := make([]string, 0, len(dropped))
+	for keyword := range dropped {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	return string(data), keywords
+}
+
+func sanitizeFunctionSchemaNode(node map[string]interface{}, dropped map[string]struct{}) {
+	if value, ok := node["const"]; ok {
+		if _, hasEnum := node["enum"]; !hasEnum {
+			node["enum"] = []interface{}{value}
+		}
+		delete(node, "const")
+	}
+	for key, value := range node {
+		if _, ok := functionSchemaKeywords[key]; !ok {
+			delete(node, key)
+			dropped[key] = struct{}{}
+			continue
+		}
+		switch key {
+		case "properties", "$defs":
+			children, ok := value.(map[string]interface{})
+			if !ok {
+				delete(node, key)
+				continue
+			}
+			for _, child := range children {
+				if childNode, okChild := child.(map[string]interface{}); okChild {
+					sanitizeFunctionSchemaNode(childNode, dropped)
+				}
+			}
+		case "items", "additionalProperties":
+			if child, ok := value.(map[string]interface{}); ok {
+				sanitizeFunctionSchemaNode(child, dropped)
+			}
+		case "prefixItems", "anyOf", "oneOf":
+			if variants, ok := value.([]interface{}); ok {
+				for _, variant := range variants {
+					if child, okChild := variant.(map[string]interface{}); okChild {
+						sanitizeFunctionSchemaNode(child, dropped)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Citation is a web source that grounds part of a Gemini response. When HasSpan is set,
+// StartIndex and EndIndex are the byte offsets of the supported text in the response, as
+// reported by Gemini, and Text is that text.
+type Citation struct {
+	URL        string
+	Title      string
+	Text       string
+	StartIndex int64
+	EndIndex   int64
+	HasSpan    bool
+}
+
+// GroundingCitations returns the citations in the groundingMetadata of a Gemini candidate:
+// one per source of each grounding support, followed by the sources no support refers to.
+// It returns nil when the candidate is not grounded.
+func GroundingCitations(candidate gjson.Result) []Citation {
+	metadata := candidate.Get("groundingMetadata")
+	if !metadata.Exists() {
+		return nil
+	}
+	var sources []Citation
+	for _, chunk := range metadata.Get("groundingChunks").Array() {
+		source := chunk.Get("web")
+		if !source.Exists() {
+			source = chunk.Get("retrievedContext")
+		}
+		sources = append(sources, Citation{URL: source.Get("uri").String(), Title: source.Get("title").String()})
+	}
+
+	var citations []Citation
+	cited := make([]bool, len(sources))
+	for _, support := range metadata.Get("groundingSupports").Array() {
+		segment := support.Get("segment")
+		for _, index := range support.Get("groundingChunkIndices").Array() {
+			i := int(index.Int())
+			if i < 0 || i >= len(sources) || sources[i].URL == "" {
+				continue
+			}
+			citation := sources[i]
+			citation.Text = segment.Get("text").String()
+			citation.StartIndex = segment.Get("startIndex").Int()
+			citation.EndIndex = segment.Get("endIndex").Int()
+			citation.HasSpan = true
+			citations = append(citations, citation)
+			cited[i] = true
+		}
+	}
+	for i, source := range sources {
+		if !cited[i] && source.URL != "" {
+			citations = append(citations, source)
+		}
+	}
+	return citations
+}
+
+// OpenAIAnnotations renders citations as the annotations array of an OpenAI chat message,
+// using url_citation entries. The cited text is kept in the text extension field.
+func OpenAIAnnotations(citations []Citation) string {
+	out := "[]"
+	for _, citation := range citations {
+		entry := `{"type":"url_citation","url_citation":{"url":""}}`
+		entry, _ = sjson.Set(entry, "url_citation.url", citation.URL)
+		if citation.Title != "" {
+			entry, _ = sjson.Set(entry, "url_citation.title", citation.Title)
+		}
+		if citation.HasSpan {
+			entry, _ = sjson.Set(entry, "url_citation.start_index", citation.StartIndex)
+			entry, _ = sjson.Set(entry, "url_citation.end_index", citation.EndIndex)
+			entry, _ = sjson.Set(entry, "url_citation.text", citation.Text)
+		}
+		out, _ = sjson.SetRaw(out, "-1", entry)
+	}
+	return out
+}
+
+// ClaudeCitations renders citations as the citations array of a Claude text block.
+func ClaudeCitations(citations []Citation) string {
+	out := "[]"
+	for _, citation := range citations {
+		out, _ = sjson.SetRaw(out, "-1", claudeCitation(citation))
+	}
+	return out
+}
+
+// ClaudeCitationEvents renders citations as the citations_delta events of the Claude text
+// block at index. They must be sent before the block is stopped.
+func ClaudeCitationEvents(index int, citations []Citation) string {
+	var b strings.Builder
+	for _, citation := range citations {
+		data := fmt.Sprintf(`{"type":"content_block_delta","index":%d,"delta":{"type":"citations_delta","citation":{}}}`, index)
+		data, _ = sjson.SetRaw(data, "delta.citation", claudeCitation(citation))
+		b.WriteString("event: content_block_delta\n")
+		b.WriteString(fmt.Sprintf("data: %s\n\n\n", data))
+	}
+	return b.String()
+}
+
+// claudeCitation renders a citation as a Claude web_search_result_location, with the supported
+// text as cited_text.
+func claudeCitation(citation Citation) string {
+	out := `{"type":"web_search_result_location","url":"","title":"","cited_text":""}`
+	out, _ = sjson.Set(out, "url", citation.URL)
+	out, _ = sjson.Set(out, "title", citation.Title)
+	out, _ = sjson.Set(out, "cited_text", citation.Text)
+	return out
+}
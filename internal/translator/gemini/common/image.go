@@ -0,0 +1,27 @@
+package common
+
+import (
+	"path"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/tidwall/sjson"
+)
+
+// SetRemoteImageFileData writes an http(s) image URL as a Gemini fileData part at partPath.
+// It reports false, leaving node unchanged, when imageURL is not a remote URL. The MIME type
+// is inferred from the URL's file extension when possible.
+func SetRemoteImageFileData(node []byte, partPath, imageURL string) ([]byte, bool) {
+	lower := strings.ToLower(imageURL)
+	if !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "http://") {
+		return node, false
+	}
+	node, _ = sjson.SetBytes(node, partPath+".fileData.fileUri", imageURL)
+	clean, _, _ := strings.Cut(lower, "?")
+	if ext := strings.TrimPrefix(path.Ext(clean), "."); ext != "" {
+		if mimeType, ok := misc.MimeTypes[ext]; ok {
+			node, _ = sjson.SetBytes(node, partPath+".fileData.mimeType", mimeType)
+		}
+	}
+	return node, true
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// GeminiMaxTopLogprobs is the largest number of alternatives Gemini returns per token.
+const GeminiMaxTopLogprobs = 20
+
+// ApplyOpenAILogprobs maps the OpenAI logprobs and top_logprobs request flags onto the Gemini
+// generation config at path (e.g. "generationConfig"): responseLogprobs enables the chosen
+// token log probabilities and logprobs sets how many alternatives accompany each token.
+// top_logprobs is only honored together with logprobs, as in the OpenAI API.
+func ApplyOpenAILogprobs(out, rawJSON []byte, path string) []byte {
+	if !gjson.GetBytes(rawJSON, "logprobs").Bool() {
+		return out
+	}
+	out, _ = sjson.SetBytes(out, path+".responseLogprobs", true)
+	if top := gjson.GetBytes(rawJSON, "top_logprobs"); top.Type == gjson.Number && top.Int() > 0 {
+		count := top.Int()
+		if count > GeminiMaxTopLogprobs {
+			count = GeminiMaxTopLogprobs
+		}
+		out, _ = sjson.SetBytes(out, path+".logprobs", count)
+	}
+	return out
+}
+
+// OpenAILogprobs converts the logprobsResult of a Gemini candidate into the OpenAI Chat
+// Completions choice logprobs object. The i-th entry of topCandidates holds the alternatives
+// for the i-th chosen token. It returns false when the candidate carries no log probabilities.
+func OpenAILogprobs(candidate gjson.Result) (string, bool) {
+	chosen := candidate.Get("logprobsResult.chosenCandidates")
+	if !chosen.IsArray() {
+		return "", false
+	}
+	top := candidate.Get("logprobsResult.topCandidates").Array()
+	out := `{"content":[],"refusal":null}`
+	for i, token := range chosen.Array() {
+		entry := openAITokenLogprob(token)
+		entry, _ = sjson.SetRaw(entry, "top_logprobs", "[]")
+		if i < len(top) {
+			for _, alternative := range top[i].Get("candidates").Array() {
+				entry, _ = sjson.SetRaw(entry, "top_logprobs.-1", openAITokenLogprob(alternative))
+			}
+		}
+		out, _ = sjson.SetRaw(out, "content.-1", entry)
+	}
+	return out, true
+}
+
+func openAITokenLogprob(token gjson.Result) string {
+	text := token.Get("token").String()
+	entry := `{"token":"","logprob":0,"bytes":[]}`
+	entry, _ = sjson.Set(entry, "token", text)
+	entry, _ = sjson.Set(entry, "logprob", token.Get("logProbability").Float())
+	byteValues := make([]int, len(text))
+	for i := 0; i < len(text); i++ {
+		byteValues[i] = int(text[i])
+	}
+	entry, _ = sjson.Set(entry, "bytes", byteValues)
+	return entry
+}
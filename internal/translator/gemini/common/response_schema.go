@@ -0,0 +1,135 @@
+package common
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// responseSchemaKeywords lists the JSON Schema keywords supported by Gemini's responseSchema.
+var responseSchemaKeywords = map[string]struct{}{
+	"type":             {},
+	"format":           {},
+	"title":            {},
+	"description":      {},
+	"nullable":         {},
+	"enum":             {},
+	"properties":       {},
+	"required":         {},
+	"items":            {},
+	"minItems":         {},
+	"maxItems":         {},
+	"minProperties":    {},
+	"maxProperties":    {},
+	"minLength":        {},
+	"maxLength":        {},
+	"pattern":          {},
+	"minimum":          {},
+	"maximum":          {},
+	"anyOf":            {},
+	"propertyOrdering": {},
+	"default":          {},
+	"example":          {},
+}
+
+// ApplyOpenAIResponseFormat maps an OpenAI response_format onto Gemini generation config.
+// json_object requests JSON output, json_schema additionally sets a responseSchema reduced to
+// the keywords Gemini accepts. The caller provides the generationConfig path
+// (e.g. "generationConfig" or "request.generationConfig").
+func ApplyOpenAIResponseFormat(out []byte, responseFormat gjson.Result, path string) []byte {
+	switch responseFormat.Get("type").String() {
+	case "json_object":
+		out, _ = sjson.SetBytes(out, path+".responseMimeType", "application/json")
+	case "json_schema":
+		out, _ = sjson.SetBytes(out, path+".responseMimeType", "application/json")
+		schema := responseFormat.Get("json_schema.schema")
+		if !schema.IsObject() {
+			return out
+		}
+		sanitized, dropped := SanitizeResponseSchema(schema.Raw)
+		if len(dropped) > 0 {
+			log.Warnf("response_format schema %q: dropped keywords unsupported by Gemini: %s", responseFormat.Get("json_schema.name").String(), strings.Join(dropped, ", "))
+		}
+		if sanitized != "" {
+			out, _ = sjson.SetRawBytes(out, path+".responseSchema", []byte(sanitized))
+		}
+	}
+	return out
+}
+
+// SanitizeResponseSchema reduces a JSON Schema document to the subset Gemini's responseSchema
+// understands. Type unions with "null" become nullable, and every other unsupported keyword is
+// removed. It returns the sanitized schema and the sorted, de-duplicated list of dropped keywords.
+func SanitizeResponseSchema(raw string) (string, []string) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+		return "", nil
+	}
+	dropped := make(map[string]struct{})
+	sanitizeSchemaNode(schema, dropped)
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return "", nil
+	}
+	keywords := make([]string, 0, len(dropped))
+	for keyword := range dropped {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	return string(data), keywords
+}
+
+func sanitizeSchemaNode(node map[string]interface{}, dropped map[string]struct{}) {
+	if types, ok := node["type"].([]interface{}); ok {
+		var kept []interface{}
+		for _, t := range types {
+			if t == "null" {
+				node["nullable"] = true
+				continue
+			}
+			kept = append(kept, t)
+		}
+		if len(kept) == 1 {
+			node["type"] = kept[0]
+		} else {
+			delete(node, "type")
+			dropped["type"] = struct{}{}
+		}
+	}
+	for key, value := range node {
+		if _, ok := responseSchemaKeywords[key]; !ok {
+			delete(node, key)
+			dropped[key] = struct{}{}
+			continue
+		}
+		switch key {
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				delete(node, key)
+				continue
+			}
+			for _, prop := range props {
+				if child, okChild := prop.(map[string]interface{}); okChild {
+					sanitizeSchemaNode(child, dropped)
+				}
+			}
+		case "items":
+			if child, ok := value.(map[string]interface{}); ok {
+				sanitizeSchemaNode(child, dropped)
+			}
+		case "anyOf":
+			if variants, ok := value.([]interface{}); ok {
+				for _, variant := range variants {
+					if child, okChild := variant.(map[string]interface{}); okChild {
+						sanitizeSchemaNode(child, dropped)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package common
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// IsSafetyFinishReason reports whether a Gemini candidate finishReason means the response was
+// withheld by a content filter.
+func IsSafetyFinishReason(finishReason string) bool {
+	return OpenAIFinishReason(finishReason) == "content_filter"
+}
+
+// SafetyBlock reports whether a Gemini response (the object holding candidates and
+// promptFeedback) was blocked by a content filter, either on the prompt
+// (promptFeedback.blockReason) or while generating (a safety finishReason).
+//
+// It returns the raw Gemini reason and a JSON object describing the block, for translators
+// to expose as an extension field:
+//
+//	{"source":"prompt","reason":"SAFETY","categories":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}
+//
+// Only the ratings that triggered the block are listed: those marked blocked, or rated
+// MEDIUM or HIGH when none are marked. BLOCKLIST and PROHIBITED_CONTENT blocks carry no
+// ratings, so the list may be empty.
+func SafetyBlock(response gjson.Result) (string, string, bool) {
+	source := "prompt"
+	reason := response.Get("promptFeedback.blockReason").String()
+	ratings := response.Get("promptFeedback.safetyRatings")
+	if reason == "" || reason == "BLOCK_REASON_UNSPECIFIED" {
+		reason = response.Get("candidates.0.finishReason").String()
+		if !IsSafetyFinishReason(reason) {
+			return "", "", false
+		}
+		source = "response"
+		ratings = response.Get("candidates.0.safetyRatings")
+	}
+
+	details := `{"source":"","reason":"","categories":[]}`
+	details, _ = sjson.Set(details, "source", source)
+	details, _ = sjson.Set(details, "reason", reason)
+	var triggered, elevated []gjson.Result
+	ratings.ForEach(func(_, rating gjson.Result) bool {
+		if rating.Get("blocked").Bool() {
+			triggered = append(triggered, rating)
+		}
+		if probability := rating.Get("probability").String(); probability == "MEDIUM" || probability == "HIGH" {
+			elevated = append(elevated, rating)
+		}
+		return true
+	})
+	if len(triggered) == 0 {
+		triggered = elevated
+	}
+	for _, rating := range triggered {
+		category := `{"category":"","probability":"","blocked":false}`
+		category, _ = sjson.Set(category, "category", rating.Get("category").String())
+		category, _ = sjson.Set(category, "probability", rating.Get("probability").String())
+		category, _ = sjson.Set(category, "blocked", rating.Get("blocked").Bool())
+		details, _ = sjson.SetRaw(details, "categories.-1", category)
+	}
+	return reason, details, true
+}
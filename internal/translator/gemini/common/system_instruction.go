@@ -0,0 +1,49 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// HasNonSystemMessage reports whether an OpenAI messages array contains any message
+// that is not a system message. Gemini rejects requests whose contents are empty, so a
+// conversation made only of system messages must keep them as user turns instead.
+func HasNonSystemMessage(messages []gjson.Result) bool {
+	for _, m := range messages {
+		if m.Get("role").String() != "system" {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendSystemInstruction appends the text of an OpenAI system message to the Gemini
+// systemInstruction at path (e.g. "system_instruction" or "request.systemInstruction").
+// Each text segment becomes its own part so multiple system messages keep their order.
+func AppendSystemInstruction(out []byte, path string, content gjson.Result) []byte {
+	var texts []string
+	switch {
+	case content.Type == gjson.String:
+		texts = append(texts, content.String())
+	case content.IsObject():
+		if content.Get("type").String() == "text" {
+			texts = append(texts, content.Get("text").String())
+		}
+	case content.IsArray():
+		for _, item := range content.Array() {
+			if item.Get("type").String() == "text" {
+				texts = append(texts, item.Get("text").String())
+			}
+		}
+	}
+	for _, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		out, _ = sjson.SetBytes(out, path+".role", "user")
+		out, _ = sjson.SetBytes(out, path+".parts.-1.text", text)
+	}
+	return out
+}
@@ -0,0 +1,266 @@
+package common
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ToolCallDelta is one OpenAI tool_calls delta entry. Name is only set on the first delta of
+// a call (First), and the Arguments of every delta of a call concatenate to its JSON arguments.
+type ToolCallDelta struct {
+	Index     int
+	First     bool
+	Name      string
+	Arguments string
+}
+
+// ToolCallStream converts the functionCall parts of a streamed Gemini response into OpenAI
+// tool_calls deltas. Each call keeps one index for all of its deltas. Calls streamed with
+// willContinue and partialArgs (streamFunctionCallArguments) are emitted as incremental
+// argument fragments; complete calls are emitted in a single delta.
+type ToolCallStream struct {
+	next int
+	open *argumentsWriter
+	seen bool
+}
+
+// SawToolCall reports whether any function call has been streamed so far.
+func (s *ToolCallStream) SawToolCall() bool { return s.seen }
+
+// Add returns the deltas for a functionCall part.
+func (s *ToolCallStream) Add(functionCall gjson.Result) []ToolCallDelta {
+	s.seen = true
+	name := functionCall.Get("name")
+	var deltas []ToolCallDelta
+	if s.open != nil && !name.Exists() {
+		delta := ToolCallDelta{Index: s.open.index}
+		delta.Arguments = s.open.write(functionCall.Get("partialArgs"))
+		if !functionCall.Get("willContinue").Bool() {
+			delta.Arguments += s.open.finish()
+			s.open = nil
+		}
+		return append(deltas, delta)
+	}
+	// A named part starts a new call; close a previous one that never signalled its end.
+	deltas = append(deltas, s.Close()...)
+
+	delta := ToolCallDelta{Index: s.next, First: true, Name: name.String()}
+	s.next++
+	partialArgs := functionCall.Get("partialArgs")
+	if functionCall.Get("willContinue").Bool() || partialArgs.Exists() {
+		writer := &argumentsWriter{index: delta.Index}
+		delta.Arguments = "{" + writer.write(partialArgs)
+		if functionCall.Get("willContinue").Bool() {
+			s.open = writer
+		} else {
+			delta.Arguments += writer.finish()
+		}
+		return append(deltas, delta)
+	}
+	delta.Arguments = "{}"
+	if args := functionCall.Get("args"); args.Exists() && args.Raw != "" {
+		delta.Arguments = args.Raw
+	}
+	return append(deltas, delta)
+}
+
+// Close returns the delta that terminates the arguments of a call still streaming, if any.
+// Call it when the candidate finishes.
+func (s *ToolCallStream) Close() []ToolCallDelta {
+	if s.open == nil {
+		return nil
+	}
+	delta := ToolCallDelta{Index: s.open.index, Arguments: s.open.finish()}
+	s.open = nil
+	return []ToolCallDelta{delta}
+}
+
+// argumentsWriter serialises partialArgs, which address values by JSON path in document
+// order, into a JSON object one fragment at a time.
+type argumentsWriter struct {
+	index int
+	// stack holds the path of the containers open below the root object; isArray and
+	// hasItems describe the root followed by each of them.
+	stack    []pathSegment
+	isArray  []bool
+	hasItems []bool
+	// stringPath is the path of a string value whose fragments are still arriving.
+	stringPath string
+}
+
+type pathSegment struct {
+	key   string
+	index int
+	isKey bool
+}
+
+func (w *argumentsWriter) write(partialArgs gjson.Result) string {
+	if len(w.hasItems) == 0 {
+		w.isArray, w.hasItems = []bool{false}, []bool{false}
+	}
+	var b strings.Builder
+	for _, arg := range partialArgs.Array() {
+		path := arg.Get("jsonPath").String()
+		segments := parseJSONPath(path)
+		if len(segments) == 0 {
+			continue
+		}
+		value, isString := partialArgValue(arg)
+		if w.stringPath != "" {
+			if w.stringPath == path && isString {
+				b.WriteString(value)
+				if !arg.Get("willContinue").Bool() {
+					b.WriteByte('"')
+					w.stringPath = ""
+				}
+				continue
+			}
+			b.WriteByte('"')
+			w.stringPath = ""
+		}
+		b.WriteString(w.moveTo(segments))
+		if isString {
+			b.WriteByte('"')
+			b.WriteString(value)
+			if arg.Get("willContinue").Bool() {
+				w.stringPath = path
+			} else {
+				b.WriteByte('"')
+			}
+		} else {
+			b.WriteString(value)
+		}
+	}
+	return b.String()
+}
+
+// moveTo closes the containers that are not ancestors of segments, opens the missing ones
+// and writes the separator and key of the final segment.
+func (w *argumentsWriter) moveTo(segments []pathSegment) string {
+	var b strings.Builder
+	parents := segments[:len(segments)-1]
+	common := 0
+	for common < len(w.stack) && common < len(parents) && w.stack[common] == parents[common] {
+		common++
+	}
+	for len(w.stack) > common {
+		b.WriteString(w.closeContainer())
+	}
+	for _, segment := range segments[len(w.stack):] {
+		b.WriteString(w.openMember(segment))
+		if len(w.stack) == len(parents) {
+			break
+		}
+		next := segments[len(w.stack)+1]
+		if next.isKey {
+			b.WriteByte('{')
+		} else {
+			b.WriteByte('[')
+		}
+		w.stack = append(w.stack, segment)
+		w.isArray = append(w.isArray, !next.isKey)
+		w.hasItems = append(w.hasItems, false)
+	}
+	return b.String()
+}
+
+// openMember writes the separator and, inside objects, the key for a new member.
+func (w *argumentsWriter) openMember(segment pathSegment) string {
+	var b strings.Builder
+	top := len(w.hasItems) - 1
+	if w.hasItems[top] {
+		b.WriteByte(',')
+	}
+	w.hasItems[top] = true
+	if !w.isArray[top] {
+		key, _ := json.Marshal(segment.key)
+		b.Write(key)
+		b.WriteByte(':')
+	}
+	return b.String()
+}
+
+func (w *argumentsWriter) closeContainer() string {
+	top := len(w.isArray) - 1
+	closing := "}"
+	if w.isArray[top] {
+		closing = "]"
+	}
+	w.isArray, w.hasItems = w.isArray[:top], w.hasItems[:top]
+	if len(w.stack) > 0 {
+		w.stack = w.stack[:len(w.stack)-1]
+	}
+	return closing
+}
+
+// finish closes an open string and every open container, including the root object.
+func (w *argumentsWriter) finish() string {
+	if len(w.hasItems) == 0 {
+		return "}"
+	}
+	var b strings.Builder
+	if w.stringPath != "" {
+		b.WriteByte('"')
+		w.stringPath = ""
+	}
+	for len(w.isArray) > 0 {
+		b.WriteString(w.closeContainer())
+	}
+	return b.String()
+}
+
+// partialArgValue returns the JSON encoding of a partial argument value. String values are
+// returned escaped but without quotes so that fragments can be concatenated.
+func partialArgValue(arg gjson.Result) (string, bool) {
+	switch {
+	case arg.Get("stringValue").Exists():
+		encoded, _ := json.Marshal(arg.Get("stringValue").String())
+		return string(encoded[1 : len(encoded)-1]), true
+	case arg.Get("numberValue").Exists():
+		return arg.Get("numberValue").Raw, false
+	case arg.Get("boolValue").Exists():
+		return strconv.FormatBool(arg.Get("boolValue").Bool()), false
+	default:
+		return "null", false
+	}
+}
+
+// parseJSONPath splits a path such as $.items[0].name or $['a key'] into segments.
+func parseJSONPath(path string) []pathSegment {
+	path = strings.TrimPrefix(path, "$")
+	var segments []pathSegment
+	for path != "" {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			segments = append(segments, pathSegment{key: path[:end], isKey: true})
+			path = path[end:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil
+			}
+			inner := path[1:end]
+			path = path[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') {
+				segments = append(segments, pathSegment{key: inner[1 : len(inner)-1], isKey: true})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil
+			}
+			segments = append(segments, pathSegment{index: index})
+		default:
+			return nil
+		}
+	}
+	return segments
+}
@@ -36,35 +36,24 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 	out, _ = sjson.SetBytes(out, "model", modelName)
 
 	// Reasoning effort -> thinkingBudget/include_thoughts
-	// Note: OpenAI official fields take precedence over extra_body.google.thinking_config
+	// Note: an explicit budget in extra_body.google.thinking_config takes precedence over reasoning_effort
 	re := gjson.GetBytes(rawJSON, "reasoning_effort")
-	hasOfficialThinking := re.Exists()
-	if hasOfficialThinking && util.ModelSupportsThinking(modelName) {
-		switch re.String() {
-		case "none":
+	tc := gjson.GetBytes(rawJSON, "extra_body.google.thinking_config")
+	hasExplicitBudget := tc.IsObject() && (tc.Get("thinkingBudget").Exists() || tc.Get("thinking_budget").Exists())
+	useEffort := re.Exists() && !hasExplicitBudget
+	if useEffort && util.ModelSupportsThinking(modelName) {
+		if re.String() == "none" {
 			out, _ = sjson.DeleteBytes(out, "generationConfig.thinkingConfig.include_thoughts")
 			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.thinkingBudget", 0)
-		case "auto":
-			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.thinkingBudget", -1)
-			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.include_thoughts", true)
-		case "low":
-			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.thinkingBudget", util.NormalizeThinkingBudget(modelName, 1024))
-			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.include_thoughts", true)
-		case "medium":
-			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.thinkingBudget", util.NormalizeThinkingBudget(modelName, 8192))
-			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.include_thoughts", true)
-		case "high":
-			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.thinkingBudget", util.NormalizeThinkingBudget(modelName, 32768))
-			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.include_thoughts", true)
-		default:
-			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.thinkingBudget", -1)
+		} else {
+			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.thinkingBudget", util.ReasoningEffortToBudget(modelName, re.String()))
 			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.include_thoughts", true)
 		}
 	}
 
-	// Cherry Studio extension extra_body.google.thinking_config (effective only when official fields are absent)
-	if !hasOfficialThinking && util.ModelSupportsThinking(modelName) {
-		if tc := gjson.GetBytes(rawJSON, "extra_body.google.thinking_config"); tc.Exists() && tc.IsObject() {
+	// Cherry Studio extension extra_body.google.thinking_config (effective unless reasoning_effort is used)
+	if !useEffort && util.ModelSupportsThinking(modelName) {
+		if tc.IsObject() {
 			var setBudget bool
 			var normalized int
 
@@ -98,6 +87,30 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 	if tkr := gjson.GetBytes(rawJSON, "top_k"); tkr.Exists() && tkr.Type == gjson.Number {
 		out, _ = sjson.SetBytes(out, "generationConfig.topK", tkr.Num)
 	}
+	// Seed for reproducible sampling
+	if sr := gjson.GetBytes(rawJSON, "seed"); sr.Exists() && sr.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "generationConfig.seed", sr.Int())
+	}
+	// Frequency/presence penalties, clamped to the model's range. An explicit 0 is forwarded;
+	// absent or null values leave the Gemini default in place.
+	if fp := gjson.GetBytes(rawJSON, "frequency_penalty"); fp.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "generationConfig.frequencyPenalty", util.NormalizePenalty(modelName, fp.Num))
+	}
+	if pp := gjson.GetBytes(rawJSON, "presence_penalty"); pp.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "generationConfig.presencePenalty", util.NormalizePenalty(modelName, pp.Num))
+	}
+	// Stop sequences: a string or an array, capped at Gemini's limit
+	if stop := util.NormalizeStopSequences(gjson.GetBytes(rawJSON, "stop"), util.GeminiMaxStopSequences, "Gemini"); len(stop) > 0 {
+		out, _ = sjson.SetBytes(out, "generationConfig.stopSequences", stop)
+	}
+
+	// Structured output: response_format -> generationConfig.responseMimeType/responseSchema
+	if rf := gjson.GetBytes(rawJSON, "response_format"); rf.IsObject() {
+		out = common.ApplyOpenAIResponseFormat(out, rf, "generationConfig")
+	}
+
+	// Log probabilities: logprobs/top_logprobs -> generationConfig.responseLogprobs/logprobs
+	out = common.ApplyOpenAILogprobs(out, rawJSON, "generationConfig")
 
 	// Map OpenAI modalities -> Gemini generationConfig.responseModalities
 	// e.g. "modalities": ["image", "text"] -> ["IMAGE", "TEXT"]
@@ -109,6 +122,8 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 				responseMods = append(responseMods, "TEXT")
 			case "image":
 				responseMods = append(responseMods, "IMAGE")
+			case "audio":
+				responseMods = append(responseMods, "AUDIO")
 			}
 		}
 		if len(responseMods) > 0 {
@@ -116,6 +131,11 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 		}
 	}
 
+	// Audio output voice: audio.voice -> generationConfig.speechConfig prebuilt voice
+	if voice := gjson.GetBytes(rawJSON, "audio.voice"); voice.Type == gjson.String && voice.String() != "" {
+		out, _ = sjson.SetBytes(out, "generationConfig.speechConfig.voiceConfig.prebuiltVoiceConfig.voiceName", voice.String())
+	}
+
 	// OpenRouter-style image_config support
 	// If the input uses top-level image_config.aspect_ratio, map it into generationConfig.imageConfig.aspectRatio.
 	if imgCfg := gjson.GetBytes(rawJSON, "image_config"); imgCfg.Exists() && imgCfg.IsObject() {
@@ -165,21 +185,16 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 			}
 		}
 
+		hasNonSystem := common.HasNonSystemMessage(arr)
 		for i := 0; i < len(arr); i++ {
 			m := arr[i]
 			role := m.Get("role").String()
 			content := m.Get("content")
 
-			if role == "system" && len(arr) > 1 {
-				// system -> system_instruction as a user message style
-				if content.Type == gjson.String {
-					out, _ = sjson.SetBytes(out, "system_instruction.role", "user")
-					out, _ = sjson.SetBytes(out, "system_instruction.parts.0.text", content.String())
-				} else if content.IsObject() && content.Get("type").String() == "text" {
-					out, _ = sjson.SetBytes(out, "system_instruction.role", "user")
-					out, _ = sjson.SetBytes(out, "system_instruction.parts.0.text", content.Get("text").String())
-				}
-			} else if role == "user" || (role == "system" && len(arr) == 1) {
+			if role == "system" && hasNonSystem {
+				// system -> system_instruction, concatenated in message order
+				out = common.AppendSystemInstruction(out, "system_instruction", content)
+			} else if role == "user" || role == "system" {
 				// Build single user content node to avoid splitting into multiple contents
 				node := []byte(`{"role":"user","parts":[]}`)
 				if content.Type == gjson.String {
@@ -194,7 +209,10 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 							p++
 						case "image_url":
 							imageURL := item.Get("image_url.url").String()
-							if len(imageURL) > 5 {
+							if updated, ok := common.SetRemoteImageFileData(node, "parts."+itoa(p), imageURL); ok {
+								node = updated
+								p++
+							} else if len(imageURL) > 5 {
 								pieces := strings.SplitN(imageURL[5:], ";", 2)
 								if len(pieces) == 2 && len(pieces[1]) > 7 {
 									mime := pieces[0]
@@ -223,15 +241,18 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 				}
 				out, _ = sjson.SetRawBytes(out, "contents.-1", node)
 			} else if role == "assistant" {
+				tcs := m.Get("tool_calls")
+				hasToolCalls := tcs.IsArray() && len(tcs.Array()) > 0
+				node := []byte(`{"role":"model","parts":[]}`)
+				p := 0
 				if content.Type == gjson.String {
-					// Assistant text -> single model content
-					node := []byte(`{"role":"model","parts":[{"text":""}]}`)
-					node, _ = sjson.SetBytes(node, "parts.0.text", content.String())
-					out, _ = sjson.SetRawBytes(out, "contents.-1", node)
+					// Assistant text -> model text part (skipped when empty and tool calls follow)
+					if text := content.String(); text != "" || !hasToolCalls {
+						node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".text", text)
+						p++
+					}
 				} else if content.IsArray() {
 					// Assistant multimodal content (e.g. text + image) -> single model content with parts
-					node := []byte(`{"role":"model","parts":[]}`)
-					p := 0
 					for _, item := range content.Array() {
 						switch item.Get("type").String() {
 						case "text":
@@ -252,49 +273,54 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 							}
 						}
 					}
-					out, _ = sjson.SetRawBytes(out, "contents.-1", node)
-				} else if !content.Exists() || content.Type == gjson.Null {
-					// Tool calls -> single model content with functionCall parts
-					tcs := m.Get("tool_calls")
-					if tcs.IsArray() {
-						node := []byte(`{"role":"model","parts":[]}`)
-						p := 0
-						fIDs := make([]string, 0)
-						for _, tc := range tcs.Array() {
-							if tc.Get("type").String() != "function" {
-								continue
-							}
-							fid := tc.Get("id").String()
-							fname := tc.Get("function.name").String()
-							fargs := tc.Get("function.arguments").String()
-							node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".functionCall.name", fname)
-							node, _ = sjson.SetRawBytes(node, "parts."+itoa(p)+".functionCall.args", []byte(fargs))
-							node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".thoughtSignature", geminiFunctionThoughtSignature)
-							p++
-							if fid != "" {
-								fIDs = append(fIDs, fid)
-							}
-						}
+				}
+
+				if !hasToolCalls {
+					if content.Type == gjson.String || content.IsArray() {
 						out, _ = sjson.SetRawBytes(out, "contents.-1", node)
+					}
+					continue
+				}
 
-						// Append a single tool content combining name + response per function
-						toolNode := []byte(`{"role":"tool","parts":[]}`)
-						pp := 0
-						for _, fid := range fIDs {
-							if name, ok := tcID2Name[fid]; ok {
-								toolNode, _ = sjson.SetBytes(toolNode, "parts."+itoa(pp)+".functionResponse.name", name)
-								resp := toolResponses[fid]
-								if resp == "" {
-									resp = "{}"
-								}
-								toolNode, _ = sjson.SetBytes(toolNode, "parts."+itoa(pp)+".functionResponse.response.result", []byte(resp))
-								pp++
-							}
-						}
-						if pp > 0 {
-							out, _ = sjson.SetRawBytes(out, "contents.-1", toolNode)
-						}
+				// Tool calls -> one functionCall part per call in the same model turn
+				followingTools := followingToolMessages(arr, i)
+				calls := 0
+				toolNode := []byte(`{"role":"tool","parts":[]}`)
+				pp := 0
+				for _, tc := range tcs.Array() {
+					if tc.Get("type").String() != "function" {
+						continue
+					}
+					fid := tc.Get("id").String()
+					fname := tc.Get("function.name").String()
+					fargs := tc.Get("function.arguments").String()
+					node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".functionCall.name", fname)
+					node, _ = sjson.SetRawBytes(node, "parts."+itoa(p)+".functionCall.args", []byte(fargs))
+					node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".thoughtSignature", geminiFunctionThoughtSignature)
+					p++
+
+					// Gemini has no call IDs, so responses are matched to calls by tool_call_id
+					// and fall back to the position of the tool message after this turn.
+					resp, ok := matchToolResponse(followingTools, calls, fid, toolResponses)
+					calls++
+					if !ok && fid == "" {
+						continue
 					}
+					if name, exists := tcID2Name[fid]; exists {
+						fname = name
+					}
+					toolNode, _ = sjson.SetBytes(toolNode, "parts."+itoa(pp)+".functionResponse.name", fname)
+					if resp == "" {
+						resp = "{}"
+					}
+					toolNode, _ = sjson.SetBytes(toolNode, "parts."+itoa(pp)+".functionResponse.response.result", []byte(resp))
+					pp++
+				}
+				out, _ = sjson.SetRawBytes(out, "contents.-1", node)
+
+				// Append a single tool content combining name + response per function
+				if pp > 0 {
+					out, _ = sjson.SetRawBytes(out, "contents.-1", toolNode)
 				}
 			}
 		}
@@ -343,6 +369,7 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 						}
 					}
 					fnRaw, _ = sjson.Delete(fnRaw, "strict")
+					fnRaw = common.SanitizeFunctionDeclaration(fnRaw)
 					if !hasFunction {
 						toolNode, _ = sjson.SetRawBytes(toolNode, "functionDeclarations", []byte("[]"))
 					}
@@ -377,6 +404,39 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 	return out
 }
 
+// followingToolMessages returns the contiguous tool messages that directly follow the
+// assistant message at index i.
+func followingToolMessages(arr []gjson.Result, i int) []gjson.Result {
+	var tools []gjson.Result
+	for j := i + 1; j < len(arr) && arr[j].Get("role").String() == "tool"; j++ {
+		tools = append(tools, arr[j])
+	}
+	return tools
+}
+
+// matchToolResponse finds the raw response content for the call at position callIndex.
+// Responses directly following the call turn are preferred by tool_call_id, then by
+// position; the global id lookup is used for histories where tool messages are not adjacent.
+func matchToolResponse(followingTools []gjson.Result, callIndex int, callID string, toolResponses map[string]string) (string, bool) {
+	if callID != "" {
+		for _, t := range followingTools {
+			if t.Get("tool_call_id").String() == callID {
+				return t.Get("content").Raw, true
+			}
+		}
+		if resp, ok := toolResponses[callID]; ok {
+			return resp, true
+		}
+	}
+	if callIndex < len(followingTools) {
+		t := followingTools[callIndex]
+		if tid := t.Get("tool_call_id").String(); tid == "" || tid == callID {
+			return t.Get("content").Raw, true
+		}
+	}
+	return "", false
+}
+
 // itoa converts int to string without strconv import for few usages.
 func itoa(i int) string { return fmt.Sprintf("%d", i) }
 
@@ -0,0 +1,429 @@
+package chat_completions
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	openaigemini "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/openai/gemini"
+	"github.com/tidwall/gjson"
+)
+
+const parallelToolCallsRequest = `{
+	"model": "gemini-2.5-pro",
+	"messages": [
+		{"role": "user", "content": "Weather and time in Paris?"},
+		{"role": "assistant", "content": "", "tool_calls": [
+			{"id": "call_weather", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}},
+			{"id": "call_time", "type": "function", "function": {"name": "get_time", "arguments": "{\"tz\":\"Europe/Paris\"}"}}
+		]},
+		{"role": "tool", "tool_call_id": "call_time", "content": "12:00"},
+		{"role": "tool", "tool_call_id": "call_weather", "content": "sunny"}
+	]
+}`
+
+func TestConvertOpenAIRequestToGemini_ParallelToolCalls(t *testing.T) {
+	out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(parallelToolCallsRequest), false)
+
+	contents := gjson.GetBytes(out, "contents").Array()
+	if len(contents) != 3 {
+		t.Fatalf("Expected 3 contents (user, model, tool), got %d: %s", len(contents), out)
+	}
+
+	modelParts := contents[1].Get("parts").Array()
+	if contents[1].Get("role").String() != "model" || len(modelParts) != 2 {
+		t.Fatalf("Expected model turn with 2 functionCall parts, got %s", contents[1].Raw)
+	}
+	if got := modelParts[0].Get("functionCall.name").String(); got != "get_weather" {
+		t.Errorf("Expected first call get_weather, got %s", got)
+	}
+	if got := modelParts[1].Get("functionCall.name").String(); got != "get_time" {
+		t.Errorf("Expected second call get_time, got %s", got)
+	}
+	if got := modelParts[1].Get("functionCall.args.tz").String(); got != "Europe/Paris" {
+		t.Errorf("Expected second call args to be preserved, got %s", got)
+	}
+
+	toolParts := contents[2].Get("parts").Array()
+	if contents[2].Get("role").String() != "tool" || len(toolParts) != 2 {
+		t.Fatalf("Expected tool turn with 2 functionResponse parts, got %s", contents[2].Raw)
+	}
+	if got := toolParts[0].Get("functionResponse.name").String(); got != "get_weather" {
+		t.Errorf("Expected first response get_weather, got %s", got)
+	}
+	if got := toolParts[0].Get("functionResponse.response.result").String(); got != `"sunny"` {
+		t.Errorf("Expected first response matched by tool_call_id, got %s", got)
+	}
+	if got := toolParts[1].Get("functionResponse.name").String(); got != "get_time" {
+		t.Errorf("Expected second response get_time, got %s", got)
+	}
+	if got := toolParts[1].Get("functionResponse.response.result").String(); got != `"12:00"` {
+		t.Errorf("Expected second response matched by tool_call_id, got %s", got)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_ToolCallsWithoutIDs(t *testing.T) {
+	raw := `{"messages":[
+		{"role":"user","content":"hi"},
+		{"role":"assistant","tool_calls":[
+			{"type":"function","function":{"name":"a","arguments":"{}"}},
+			{"type":"function","function":{"name":"b","arguments":"{}"}}
+		]},
+		{"role":"tool","content":"ra"},
+		{"role":"tool","content":"rb"}
+	]}`
+	out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(raw), false)
+
+	toolParts := gjson.GetBytes(out, "contents.2.parts").Array()
+	if len(toolParts) != 2 {
+		t.Fatalf("Expected 2 positional functionResponse parts, got %s", out)
+	}
+	if toolParts[0].Get("functionResponse.name").String() != "a" || toolParts[0].Get("functionResponse.response.result").String() != `"ra"` {
+		t.Errorf("Unexpected first response: %s", toolParts[0].Raw)
+	}
+	if toolParts[1].Get("functionResponse.name").String() != "b" || toolParts[1].Get("functionResponse.response.result").String() != `"rb"` {
+		t.Errorf("Unexpected second response: %s", toolParts[1].Raw)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAI_ParallelFunctionCalls(t *testing.T) {
+	chunk := `{"candidates":[{"content":{"role":"model","parts":[
+		{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}},
+		{"functionCall":{"name":"get_weather","args":{"city":"Rome"}}}
+	]}}]}`
+	var param any
+	results := ConvertGeminiResponseToOpenAI(context.Background(), "", nil, nil, []byte(chunk), &param)
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(results))
+	}
+
+	calls := gjson.Get(results[0], "choices.0.delta.tool_calls").Array()
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 tool calls, got %s", results[0])
+	}
+	if calls[0].Get("index").Int() != 0 || calls[1].Get("index").Int() != 1 {
+		t.Errorf("Expected sequential indices, got %d and %d", calls[0].Get("index").Int(), calls[1].Get("index").Int())
+	}
+	if calls[0].Get("id").String() == calls[1].Get("id").String() {
+		t.Errorf("Expected distinct call IDs, got %s twice", calls[0].Get("id").String())
+	}
+
+	next := `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"get_time","args":{}}}]}}]}`
+	results = ConvertGeminiResponseToOpenAI(context.Background(), "", nil, nil, []byte(next), &param)
+	if got := gjson.Get(results[0], "choices.0.delta.tool_calls.0.index").Int(); got != 2 {
+		t.Errorf("Expected index to continue across chunks (2), got %d", got)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_ResponseFormatJSONSchema(t *testing.T) {
+	raw := `{"model":"gemini-2.5-pro","messages":[{"role":"user","content":"Describe a user"}],
+		"response_format":{"type":"json_schema","json_schema":{"name":"user","strict":true,"schema":{
+			"$schema":"http://json-schema.org/draft-07/schema#",
+			"type":"object",
+			"additionalProperties":false,
+			"properties":{
+				"name":{"type":"string","description":"Full name"},
+				"address":{"type":"object","additionalProperties":false,"properties":{
+					"city":{"type":"string"},
+					"zip":{"type":["string","null"]}
+				},"required":["city"]},
+				"tags":{"type":"array","items":{"type":"string","const":"x"}}
+			},
+			"required":["name","address"]
+		}}}}`
+
+	out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(raw), false)
+
+	if got := gjson.GetBytes(out, "generationConfig.responseMimeType").String(); got != "application/json" {
+		t.Fatalf("Expected JSON mime type, got %s", out)
+	}
+	schema := gjson.GetBytes(out, "generationConfig.responseSchema")
+	if schema.Get("properties.address.properties.city.type").String() != "string" {
+		t.Fatalf("Expected nested object properties to survive, got %s", schema.Raw)
+	}
+	if got := schema.Get("properties.address.required.0").String(); got != "city" {
+		t.Fatalf("Expected nested required list to survive, got %s", schema.Raw)
+	}
+	zip := schema.Get("properties.address.properties.zip")
+	if zip.Get("type").String() != "string" || !zip.Get("nullable").Bool() {
+		t.Fatalf("Expected nullable type union to map to nullable, got %s", zip.Raw)
+	}
+	for _, keyword := range []string{`"$schema"`, `"additionalProperties"`, `"const"`} {
+		if strings.Contains(schema.Raw, keyword) {
+			t.Fatalf("Expected unsupported keyword %s to be stripped, got %s", keyword, schema.Raw)
+		}
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_SystemInstruction(t *testing.T) {
+	cases := []struct {
+		name      string
+		messages  string
+		wantParts []string
+	}{
+		{
+			name:     "no system message",
+			messages: `[{"role":"user","content":"hi"}]`,
+		},
+		{
+			name:      "one system message",
+			messages:  `[{"role":"system","content":"Be terse."},{"role":"user","content":"hi"}]`,
+			wantParts: []string{"Be terse."},
+		},
+		{
+			name:      "multiple system messages",
+			messages:  `[{"role":"system","content":"Be terse."},{"role":"user","content":"hi"},{"role":"system","content":[{"type":"text","text":"Answer in French."}]}]`,
+			wantParts: []string{"Be terse.", "Answer in French."},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(`{"model":"gemini-2.5-pro","messages":`+tc.messages+`}`), false)
+
+			instruction := gjson.GetBytes(out, "system_instruction")
+			if len(tc.wantParts) == 0 {
+				if instruction.Exists() {
+					t.Fatalf("Expected no system_instruction, got %s", instruction.Raw)
+				}
+			} else {
+				parts := instruction.Get("parts").Array()
+				if len(parts) != len(tc.wantParts) {
+					t.Fatalf("Expected %d system parts, got %s", len(tc.wantParts), instruction.Raw)
+				}
+				for i, want := range tc.wantParts {
+					if got := parts[i].Get("text").String(); got != want {
+						t.Errorf("Expected system part %d to be %q, got %q", i, want, got)
+					}
+				}
+			}
+
+			contents := gjson.GetBytes(out, "contents").Array()
+			if len(contents) != 1 || contents[0].Get("parts.0.text").String() != "hi" {
+				t.Fatalf("Expected only the user turn in contents, got %s", gjson.GetBytes(out, "contents").Raw)
+			}
+
+			reverse := openaigemini.ConvertGeminiRequestToOpenAI("gemini-2.5-pro", out, false)
+			systemCount := 0
+			for _, msg := range gjson.GetBytes(reverse, "messages").Array() {
+				if msg.Get("role").String() == "system" {
+					systemCount++
+				}
+				for _, want := range tc.wantParts {
+					if msg.Get("role").String() != "system" && strings.Contains(msg.Get("content").Raw, want) {
+						t.Errorf("Expected system content %q only in the system message, got %s", want, msg.Raw)
+					}
+				}
+			}
+			if wantCount := min(len(tc.wantParts), 1); systemCount != wantCount {
+				t.Fatalf("Expected %d system message after reverse translation, got %s", wantCount, reverse)
+			}
+		})
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_ImageParts(t *testing.T) {
+	request := `{"model":"gemini-2.5-pro","messages":[{"role":"user","content":[
+		{"type":"text","text":"compare"},
+		{"type":"image_url","image_url":{"url":"data:image/png;base64,aGVsbG8=","detail":"low"}},
+		{"type":"image_url","image_url":{"url":"https://example.com/images/cat.JPG?size=large"}}
+	]}]}`
+	out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(request), false)
+
+	parts := gjson.GetBytes(out, "contents.0.parts").Array()
+	if len(parts) != 3 {
+		t.Fatalf("Expected text and two image parts, got %s", gjson.GetBytes(out, "contents.0.parts").Raw)
+	}
+	if parts[1].Get("inlineData.mime_type").String() != "image/png" || parts[1].Get("inlineData.data").String() != "aGVsbG8=" {
+		t.Errorf("Expected data URI as inlineData, got %s", parts[1].Raw)
+	}
+	if parts[2].Get("fileData.fileUri").String() != "https://example.com/images/cat.JPG?size=large" || parts[2].Get("fileData.mimeType").String() != "image/jpeg" {
+		t.Errorf("Expected remote URL as fileData, got %s", parts[2].Raw)
+	}
+	if strings.Contains(string(out), "detail") {
+		t.Errorf("Expected detail to be dropped, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_Seed(t *testing.T) {
+	out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(`{"model":"gemini-2.5-pro","seed":12345,"messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.seed"); got.Type != gjson.Number || got.Int() != 12345 {
+		t.Fatalf("Expected generationConfig.seed 12345, got %s", out)
+	}
+	if gjson.GetBytes(out, "seed").Exists() {
+		t.Fatalf("Expected seed not to be forwarded at the top level, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_StopSequences(t *testing.T) {
+	cases := []struct {
+		stop string
+		want string
+	}{
+		{`"END"`, `["END"]`},
+		{`["a","b","a"]`, `["a","b"]`},
+		{`["1","2","3","4","5","6","7"]`, `["1","2","3","4","5"]`},
+	}
+	for _, tc := range cases {
+		out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(`{"model":"gemini-2.5-pro","stop":`+tc.stop+`,"messages":[{"role":"user","content":"hi"}]}`), false)
+		if got := gjson.GetBytes(out, "generationConfig.stopSequences").Raw; got != tc.want {
+			t.Errorf("stop %s: expected stopSequences %s, got %s", tc.stop, tc.want, got)
+		}
+	}
+
+	chunk := `{"candidates":[{"content":{"parts":[{"text":"done"}]},"finishReason":"STOP"}]}`
+	resp := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(chunk), nil)
+	if got := gjson.Get(resp, "choices.0.finish_reason").String(); got != "stop" {
+		t.Fatalf("Expected finish_reason stop, got %s", resp)
+	}
+	if got := gjson.Get(resp, "choices.0.native_finish_reason").String(); got != "STOP" {
+		t.Fatalf("Expected native_finish_reason STOP, got %s", resp)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_Logprobs(t *testing.T) {
+	out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(`{"model":"gemini-2.5-pro","logprobs":true,"top_logprobs":3,"messages":[{"role":"user","content":"hi"}]}`), false)
+	if !gjson.GetBytes(out, "generationConfig.responseLogprobs").Bool() || gjson.GetBytes(out, "generationConfig.logprobs").Int() != 3 {
+		t.Fatalf("Expected responseLogprobs and logprobs 3, got %s", out)
+	}
+	if gjson.GetBytes(out, "logprobs").Exists() || gjson.GetBytes(out, "top_logprobs").Exists() {
+		t.Fatalf("Expected OpenAI flags not to be forwarded at the top level, got %s", out)
+	}
+
+	out = ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(`{"model":"gemini-2.5-pro","top_logprobs":3,"messages":[{"role":"user","content":"hi"}]}`), false)
+	if gjson.GetBytes(out, "generationConfig.responseLogprobs").Exists() || gjson.GetBytes(out, "generationConfig.logprobs").Exists() {
+		t.Fatalf("Expected top_logprobs without logprobs to be ignored, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_Penalties(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("penalty-test", "gemini", []*registry.ModelInfo{
+		{ID: "penalty-test-model", Penalty: &registry.PenaltyRange{Min: -2, Max: 2}},
+	})
+	t.Cleanup(func() { reg.UnregisterClient("penalty-test") })
+
+	out := ConvertOpenAIRequestToGemini("penalty-test-model", []byte(`{"frequency_penalty":0.5,"presence_penalty":-1.25,"messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.frequencyPenalty").Float(); got != 0.5 {
+		t.Errorf("Expected frequencyPenalty 0.5, got %s", out)
+	}
+	if got := gjson.GetBytes(out, "generationConfig.presencePenalty").Float(); got != -1.25 {
+		t.Errorf("Expected presencePenalty -1.25, got %s", out)
+	}
+	if gjson.GetBytes(out, "frequency_penalty").Exists() || gjson.GetBytes(out, "presence_penalty").Exists() {
+		t.Errorf("Expected the OpenAI fields not to be forwarded, got %s", out)
+	}
+
+	out = ConvertOpenAIRequestToGemini("penalty-test-model", []byte(`{"frequency_penalty":3.5,"presence_penalty":-9,"messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.frequencyPenalty").Float(); got != 2 {
+		t.Errorf("Expected frequencyPenalty clamped to 2, got %s", out)
+	}
+	if got := gjson.GetBytes(out, "generationConfig.presencePenalty").Float(); got != -2 {
+		t.Errorf("Expected presencePenalty clamped to -2, got %s", out)
+	}
+
+	out = ConvertOpenAIRequestToGemini("penalty-test-model", []byte(`{"frequency_penalty":0,"presence_penalty":null,"messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.frequencyPenalty"); got.Type != gjson.Number || got.Float() != 0 {
+		t.Errorf("Expected an explicit frequencyPenalty of 0 to be forwarded, got %s", out)
+	}
+	if gjson.GetBytes(out, "generationConfig.presencePenalty").Exists() {
+		t.Errorf("Expected a null presence_penalty to be left unset, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_StrictToolSchema(t *testing.T) {
+	raw := `{"model":"gemini-2.5-pro","messages":[{"role":"user","content":"Book a flight"}],
+		"tools":[{"type":"function","function":{"name":"book_flight","description":"Book a flight","strict":true,
+			"parameters":{
+				"$schema":"http://json-schema.org/draft-07/schema#",
+				"type":"object",
+				"additionalProperties":false,
+				"properties":{
+					"cabin":{"type":"string","enum":["economy","business","first"]},
+					"passenger":{"type":"object","additionalProperties":false,"properties":{
+						"name":{"type":"string","pattern":"^[A-Z]"},
+						"age":{"type":"integer","minimum":0}
+					},"required":["name","age"]},
+					"legs":{"type":"array","items":{"type":"object","properties":{
+						"from":{"type":"string"},
+						"to":{"type":"string"},
+						"kind":{"const":"oneway"}
+					},"required":["from","to"]}}
+				},
+				"required":["cabin","passenger","legs"]
+			}}}]}`
+
+	out := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(raw), false)
+
+	want := `{"name":"book_flight","description":"Book a flight","parametersJsonSchema":{
+		"type":"object",
+		"additionalProperties":false,
+		"properties":{
+			"cabin":{"type":"string","enum":["economy","business","first"]},
+			"passenger":{"type":"object","additionalProperties":false,"properties":{
+				"name":{"type":"string"},
+				"age":{"type":"integer","minimum":0}
+			},"required":["name","age"]},
+			"legs":{"type":"array","items":{"type":"object","properties":{
+				"from":{"type":"string"},
+				"to":{"type":"string"},
+				"kind":{"enum":["oneway"]}
+			},"required":["from","to"]}}
+		},
+		"required":["cabin","passenger","legs"]
+	}}`
+	var got, expected interface{}
+	if err := json.Unmarshal([]byte(gjson.GetBytes(out, "tools.0.functionDeclarations.0").Raw), &got); err != nil {
+		t.Fatalf("Expected a function declaration, got %s", out)
+	}
+	if err := json.Unmarshal([]byte(want), &expected); err != nil {
+		t.Fatalf("invalid expectation: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Unexpected function declaration:\n got %s\nwant %s", gjson.GetBytes(out, "tools.0.functionDeclarations.0").Raw, want)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_ReasoningEffort(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("effort-test", "gemini", []*registry.ModelInfo{
+		{ID: "effort-test-model", Thinking: &registry.ThinkingSupport{Min: 128, Max: 32768, DynamicAllowed: true}},
+		{ID: "effort-test-plain"},
+	})
+	t.Cleanup(func() { reg.UnregisterClient("effort-test") })
+
+	out := ConvertOpenAIRequestToGemini("effort-test-model", []byte(`{"reasoning_effort":"high","messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.thinkingConfig.thinkingBudget").Int(); got != 32768 {
+		t.Errorf("Expected high effort to use the registry maximum, got %s", out)
+	}
+	if !gjson.GetBytes(out, "generationConfig.thinkingConfig.include_thoughts").Bool() {
+		t.Errorf("Expected thoughts to be included, got %s", out)
+	}
+
+	out = ConvertOpenAIRequestToGemini("effort-test-model", []byte(`{"reasoning_effort":"high","extra_body":{"google":{"thinking_config":{"thinking_budget":2048}}},"messages":[{"role":"user","content":"hi"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.thinkingConfig.thinkingBudget").Int(); got != 2048 {
+		t.Errorf("Expected the explicit budget to win over reasoning_effort, got %s", out)
+	}
+
+	out = ConvertOpenAIRequestToGemini("effort-test-plain", []byte(`{"reasoning_effort":"high","messages":[{"role":"user","content":"hi"}]}`), false)
+	if gjson.GetBytes(out, "generationConfig.thinkingConfig").Exists() {
+		t.Errorf("Expected reasoning_effort to be ignored without thinking support, got %s", out)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_OutputModalities(t *testing.T) {
+	out := ConvertOpenAIRequestToGemini("gemini-2.5-flash-image", []byte(`{"model":"gemini-2.5-flash-image","modalities":["text","image"],"messages":[{"role":"user","content":"Draw a cat"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.responseModalities").Raw; got != `["TEXT","IMAGE"]` {
+		t.Fatalf("Expected TEXT and IMAGE response modalities, got %s", got)
+	}
+
+	out = ConvertOpenAIRequestToGemini("gemini-2.5-flash-preview-tts", []byte(`{"model":"gemini-2.5-flash-preview-tts","modalities":["audio"],"audio":{"voice":"Kore","format":"pcm16"},"messages":[{"role":"user","content":"Say hi"}]}`), false)
+	if got := gjson.GetBytes(out, "generationConfig.responseModalities").Raw; got != `["AUDIO"]` {
+		t.Fatalf("Expected the AUDIO response modality, got %s", got)
+	}
+	if got := gjson.GetBytes(out, "generationConfig.speechConfig.voiceConfig.prebuiltVoiceConfig.voiceName").String(); got != "Kore" {
+		t.Fatalf("Expected the requested voice, got %q", got)
+	}
+}
@@ -8,10 +8,16 @@ package chat_completions
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -19,7 +25,15 @@ import (
 // convertGeminiResponseToOpenAIChatParams holds parameters for response conversion.
 type convertGeminiResponseToOpenAIChatParams struct {
 	UnixTimestamp int64
-	FunctionIndex int
+	// ToolCalls assigns stable indices to tool calls and streams their arguments.
+	ToolCalls common.ToolCallStream
+	// ResponseID and Model remember the last seen identifiers for the final usage chunk.
+	ResponseID string
+	Model      string
+	// ToolCallSeed derives tool call IDs when the upstream sends no response ID.
+	ToolCallSeed string
+	// Usage holds the latest OpenAI-format usage object; Gemini reports running totals.
+	Usage string
 }
 
 // ConvertGeminiResponseToOpenAI translates a single chunk of a streaming response from the
@@ -40,7 +54,6 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 	if *param == nil {
 		*param = &convertGeminiResponseToOpenAIChatParams{
 			UnixTimestamp: 0,
-			FunctionIndex: 0,
 		}
 	}
 
@@ -48,7 +61,19 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 		rawJSON = bytes.TrimSpace(rawJSON[5:])
 	}
 
+	state := (*param).(*convertGeminiResponseToOpenAIChatParams)
 	if bytes.Equal(rawJSON, []byte("[DONE]")) {
+		// Emit the trailing usage chunk when the client opted in via stream_options.include_usage.
+		if state.Usage != "" && gjson.GetBytes(originalRequestRawJSON, "stream_options.include_usage").Bool() {
+			usageChunk := `{"id":"","object":"chat.completion.chunk","created":0,"model":"model","choices":[]}`
+			usageChunk, _ = sjson.Set(usageChunk, "id", state.ResponseID)
+			usageChunk, _ = sjson.Set(usageChunk, "created", state.UnixTimestamp)
+			if state.Model != "" {
+				usageChunk, _ = sjson.Set(usageChunk, "model", state.Model)
+			}
+			usageChunk, _ = sjson.SetRaw(usageChunk, "usage", state.Usage)
+			return []string{usageChunk}
+		}
 		return []string{}
 	}
 
@@ -58,6 +83,7 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 	// Extract and set the model version.
 	if modelVersionResult := gjson.GetBytes(rawJSON, "modelVersion"); modelVersionResult.Exists() {
 		template, _ = sjson.Set(template, "model", modelVersionResult.String())
+		state.Model = modelVersionResult.String()
 	}
 
 	// Extract and set the creation timestamp.
@@ -74,14 +100,31 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 	// Extract and set the response ID.
 	if responseIDResult := gjson.GetBytes(rawJSON, "responseId"); responseIDResult.Exists() {
 		template, _ = sjson.Set(template, "id", responseIDResult.String())
+		state.ResponseID = responseIDResult.String()
 	}
 
 	// Extract and set the finish reason.
-	if finishReasonResult := gjson.GetBytes(rawJSON, "candidates.0.finishReason"); finishReasonResult.Exists() {
-		template, _ = sjson.Set(template, "choices.0.finish_reason", finishReasonResult.String())
+	finishReasonResult := gjson.GetBytes(rawJSON, "candidates.0.finishReason")
+	if finishReasonResult.Exists() {
+		template, _ = sjson.Set(template, "choices.0.finish_reason", common.OpenAIFinishReason(finishReasonResult.String()))
 		template, _ = sjson.Set(template, "choices.0.native_finish_reason", finishReasonResult.String())
 	}
 
+	// Prompts blocked before any candidate was generated carry no finishReason at all.
+	if blockReason, safetyDetails, blocked := common.SafetyBlock(gjson.ParseBytes(rawJSON)); blocked {
+		template, _ = sjson.Set(template, "choices.0.finish_reason", "content_filter")
+		template, _ = sjson.Set(template, "choices.0.native_finish_reason", blockReason)
+		template, _ = sjson.SetRaw(template, "choices.0.content_filter", safetyDetails)
+	}
+
+	if logprobs, ok := common.OpenAILogprobs(gjson.GetBytes(rawJSON, "candidates.0")); ok {
+		template, _ = sjson.SetRaw(template, "choices.0.logprobs", logprobs)
+	}
+
+	if citations := common.GroundingCitations(gjson.GetBytes(rawJSON, "candidates.0")); len(citations) > 0 {
+		template, _ = sjson.SetRaw(template, "choices.0.delta.annotations", common.OpenAIAnnotations(citations))
+	}
+
 	// Extract and set usage metadata (token counts).
 	if usageResult := gjson.GetBytes(rawJSON, "usageMetadata"); usageResult.Exists() {
 		if candidatesTokenCountResult := usageResult.Get("candidatesTokenCount"); candidatesTokenCountResult.Exists() {
@@ -96,11 +139,11 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 		if thoughtsTokenCount > 0 {
 			template, _ = sjson.Set(template, "usage.completion_tokens_details.reasoning_tokens", thoughtsTokenCount)
 		}
+		state.Usage = gjson.Get(template, "usage").Raw
 	}
 
 	// Process the main content part of the response.
 	partsResult := gjson.GetBytes(rawJSON, "candidates.0.content.parts")
-	hasFunctionCall := false
 	if partsResult.IsArray() {
 		partResults := partsResult.Array()
 		for i := 0; i < len(partResults); i++ {
@@ -134,27 +177,10 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 				}
 				template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
 			} else if functionCallResult.Exists() {
-				// Handle function call content.
-				hasFunctionCall = true
-				toolCallsResult := gjson.Get(template, "choices.0.delta.tool_calls")
-				functionCallIndex := (*param).(*convertGeminiResponseToOpenAIChatParams).FunctionIndex
-				(*param).(*convertGeminiResponseToOpenAIChatParams).FunctionIndex++
-				if toolCallsResult.Exists() && toolCallsResult.IsArray() {
-					functionCallIndex = len(toolCallsResult.Array())
-				} else {
-					template, _ = sjson.SetRaw(template, "choices.0.delta.tool_calls", `[]`)
-				}
-
-				functionCallTemplate := `{"id": "","index": 0,"type": "function","function": {"name": "","arguments": ""}}`
-				fcName := functionCallResult.Get("name").String()
-				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "id", fmt.Sprintf("%s-%d", fcName, time.Now().UnixNano()))
-				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "index", functionCallIndex)
-				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "function.name", fcName)
-				if fcArgsResult := functionCallResult.Get("args"); fcArgsResult.Exists() {
-					functionCallTemplate, _ = sjson.Set(functionCallTemplate, "function.arguments", fcArgsResult.Raw)
-				}
+				// Handle function call content. The stream keeps one index per call, across
+				// chunks, so parallel and incrementally streamed calls never share an index.
+				template = appendToolCallDeltas(template, state.toolCallSeed(), state.ToolCalls.Add(functionCallResult))
 				template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
-				template, _ = sjson.SetRaw(template, "choices.0.delta.tool_calls.-1", functionCallTemplate)
 			} else if inlineDataResult.Exists() {
 				data := inlineDataResult.Get("data").String()
 				if data == "" {
@@ -167,6 +193,12 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 				if mimeType == "" {
 					mimeType = "image/png"
 				}
+				if strings.HasPrefix(mimeType, "audio/") {
+					// Speech output maps to the OpenAI audio object; Gemini returns raw PCM.
+					template = appendAudioData(template, "choices.0.delta.audio", data)
+					template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
+					continue
+				}
 				imageURL := fmt.Sprintf("data:%s;base64,%s", mimeType, data)
 				imagePayload, err := json.Marshal(map[string]any{
 					"type": "image_url",
@@ -187,14 +219,54 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 		}
 	}
 
-	if hasFunctionCall {
-		template, _ = sjson.Set(template, "choices.0.finish_reason", "tool_calls")
-		template, _ = sjson.Set(template, "choices.0.native_finish_reason", "tool_calls")
+	// Arguments still streaming when the candidate finishes are closed in the final chunk, which
+	// is the only one that carries finish_reason tool_calls.
+	if finishReasonResult.Exists() {
+		template = appendToolCallDeltas(template, state.toolCallSeed(), state.ToolCalls.Close())
+		if state.ToolCalls.SawToolCall() && gjson.Get(template, "choices.0.finish_reason").String() == "stop" {
+			template, _ = sjson.Set(template, "choices.0.finish_reason", "tool_calls")
+			template, _ = sjson.Set(template, "choices.0.native_finish_reason", "tool_calls")
+		}
 	}
 
 	return []string{template}
 }
 
+// toolCallSeed returns the seed of the tool call IDs of the streamed response: its response
+// ID, or a random seed kept for the whole stream when the upstream sent none.
+func (p *convertGeminiResponseToOpenAIChatParams) toolCallSeed() string {
+	if p.ResponseID != "" {
+		return p.ResponseID
+	}
+	if p.ToolCallSeed == "" {
+		p.ToolCallSeed = uuid.NewString()
+	}
+	return p.ToolCallSeed
+}
+
+// appendToolCallDeltas adds OpenAI tool_calls delta entries to a chunk. The ID, type and
+// function name are only sent in the first delta of each call.
+func appendToolCallDeltas(template, seed string, deltas []common.ToolCallDelta) string {
+	if len(deltas) == 0 {
+		return template
+	}
+	if toolCallsResult := gjson.Get(template, "choices.0.delta.tool_calls"); !toolCallsResult.IsArray() {
+		template, _ = sjson.SetRaw(template, "choices.0.delta.tool_calls", `[]`)
+	}
+	for _, delta := range deltas {
+		functionCallTemplate := `{"index":0,"function":{"arguments":""}}`
+		functionCallTemplate, _ = sjson.Set(functionCallTemplate, "index", delta.Index)
+		if delta.First {
+			functionCallTemplate, _ = sjson.Set(functionCallTemplate, "id", toolCallID(seed, delta.Name, delta.Index))
+			functionCallTemplate, _ = sjson.Set(functionCallTemplate, "type", "function")
+			functionCallTemplate, _ = sjson.Set(functionCallTemplate, "function.name", delta.Name)
+		}
+		functionCallTemplate, _ = sjson.Set(functionCallTemplate, "function.arguments", delta.Arguments)
+		template, _ = sjson.SetRaw(template, "choices.0.delta.tool_calls.-1", functionCallTemplate)
+	}
+	return template
+}
+
 // ConvertGeminiResponseToOpenAINonStream converts a non-streaming Gemini response to a non-streaming OpenAI response.
 // This function processes the complete Gemini response and transforms it into a single OpenAI-compatible
 // JSON response. It handles message content, tool calls, reasoning content, and usage metadata, combining all
@@ -225,15 +297,34 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 		template, _ = sjson.Set(template, "created", unixTimestamp)
 	}
 
+	toolCallSeed := gjson.GetBytes(rawJSON, "responseId").String()
 	if responseIDResult := gjson.GetBytes(rawJSON, "responseId"); responseIDResult.Exists() {
 		template, _ = sjson.Set(template, "id", responseIDResult.String())
 	}
+	if toolCallSeed == "" {
+		toolCallSeed = uuid.NewString()
+	}
 
 	if finishReasonResult := gjson.GetBytes(rawJSON, "candidates.0.finishReason"); finishReasonResult.Exists() {
-		template, _ = sjson.Set(template, "choices.0.finish_reason", finishReasonResult.String())
+		template, _ = sjson.Set(template, "choices.0.finish_reason", common.OpenAIFinishReason(finishReasonResult.String()))
 		template, _ = sjson.Set(template, "choices.0.native_finish_reason", finishReasonResult.String())
 	}
 
+	// Prompts blocked before any candidate was generated carry no finishReason at all.
+	if blockReason, safetyDetails, blocked := common.SafetyBlock(gjson.ParseBytes(rawJSON)); blocked {
+		template, _ = sjson.Set(template, "choices.0.finish_reason", "content_filter")
+		template, _ = sjson.Set(template, "choices.0.native_finish_reason", blockReason)
+		template, _ = sjson.SetRaw(template, "choices.0.content_filter", safetyDetails)
+	}
+
+	if logprobs, ok := common.OpenAILogprobs(gjson.GetBytes(rawJSON, "candidates.0")); ok {
+		template, _ = sjson.SetRaw(template, "choices.0.logprobs", logprobs)
+	}
+
+	if citations := common.GroundingCitations(gjson.GetBytes(rawJSON, "candidates.0")); len(citations) > 0 {
+		template, _ = sjson.SetRaw(template, "choices.0.message.annotations", common.OpenAIAnnotations(citations))
+	}
+
 	if usageResult := gjson.GetBytes(rawJSON, "usageMetadata"); usageResult.Exists() {
 		if candidatesTokenCountResult := usageResult.Get("candidatesTokenCount"); candidatesTokenCountResult.Exists() {
 			template, _ = sjson.Set(template, "usage.completion_tokens", candidatesTokenCountResult.Int())
@@ -252,6 +343,7 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 	// Process the main content part of the response.
 	partsResult := gjson.GetBytes(rawJSON, "candidates.0.content.parts")
 	hasFunctionCall := false
+	functionCallIndex := 0
 	if partsResult.IsArray() {
 		partsResults := partsResult.Array()
 		for i := 0; i < len(partsResults); i++ {
@@ -280,7 +372,8 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 				}
 				functionCallItemTemplate := `{"id": "","type": "function","function": {"name": "","arguments": ""}}`
 				fcName := functionCallResult.Get("name").String()
-				functionCallItemTemplate, _ = sjson.Set(functionCallItemTemplate, "id", fmt.Sprintf("%s-%d", fcName, time.Now().UnixNano()))
+				functionCallItemTemplate, _ = sjson.Set(functionCallItemTemplate, "id", toolCallID(toolCallSeed, fcName, functionCallIndex))
+				functionCallIndex++
 				functionCallItemTemplate, _ = sjson.Set(functionCallItemTemplate, "function.name", fcName)
 				if fcArgsResult := functionCallResult.Get("args"); fcArgsResult.Exists() {
 					functionCallItemTemplate, _ = sjson.Set(functionCallItemTemplate, "function.arguments", fcArgsResult.Raw)
@@ -299,6 +392,12 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 				if mimeType == "" {
 					mimeType = "image/png"
 				}
+				if strings.HasPrefix(mimeType, "audio/") {
+					// Speech output maps to the OpenAI audio object; Gemini returns raw PCM.
+					template = appendAudioData(template, "choices.0.message.audio", data)
+					template, _ = sjson.Set(template, "choices.0.message.role", "assistant")
+					continue
+				}
 				imageURL := fmt.Sprintf("data:%s;base64,%s", mimeType, data)
 				imagePayload, err := json.Marshal(map[string]any{
 					"type": "image_url",
@@ -326,3 +425,30 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 
 	return template
 }
+
+// toolCallID builds an OpenAI tool call ID for a Gemini function call. Gemini does not
+// assign call IDs, so the ID is derived from a per-response seed, normally the response ID,
+// and the call position: the same upstream response always yields the same IDs, streamed or
+// not, and parallel calls with the same function name stay distinct.
+func toolCallID(seed, name string, index int) string {
+	sum := sha256.Sum256([]byte(seed))
+	return fmt.Sprintf("%s-%s-%d", name, hex.EncodeToString(sum[:8]), index)
+}
+
+// appendAudioData appends base64 audio data to the OpenAI audio object at path. Gemini may
+// split speech over several parts; the base64 chunks are decoded and re-encoded together so
+// the client receives one contiguous clip.
+func appendAudioData(template, path, data string) string {
+	if existing := gjson.Get(template, path+".data").String(); existing != "" {
+		prev, errPrev := base64.StdEncoding.DecodeString(existing)
+		next, errNext := base64.StdEncoding.DecodeString(data)
+		if errPrev == nil && errNext == nil {
+			data = base64.StdEncoding.EncodeToString(append(prev, next...))
+		}
+	} else {
+		template, _ = sjson.Set(template, path+".id", fmt.Sprintf("audio_%d", time.Now().UnixNano()))
+		template, _ = sjson.Set(template, path+".transcript", "")
+	}
+	template, _ = sjson.Set(template, path+".data", data)
+	return template
+}
@@ -0,0 +1,317 @@
+package chat_completions
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertGeminiResponseToOpenAI_IncludeUsage(t *testing.T) {
+	chunks := []string{
+		`{"responseId":"resp-1","modelVersion":"gemini-2.5-pro","candidates":[{"content":{"parts":[{"text":"Hel"}]}}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":1,"totalTokenCount":11}}`,
+		`{"responseId":"resp-1","modelVersion":"gemini-2.5-pro","candidates":[{"content":{"parts":[{"text":"lo"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}}`,
+	}
+
+	run := func(original string) []string {
+		var param any
+		for _, chunk := range chunks {
+			ConvertGeminiResponseToOpenAI(context.Background(), "", []byte(original), nil, []byte(chunk), &param)
+		}
+		return ConvertGeminiResponseToOpenAI(context.Background(), "", []byte(original), nil, []byte("[DONE]"), &param)
+	}
+
+	final := run(`{"stream":true,"stream_options":{"include_usage":true}}`)
+	if len(final) != 1 {
+		t.Fatalf("Expected a trailing usage chunk, got %v", final)
+	}
+	if n := len(gjson.Get(final[0], "choices").Array()); n != 0 {
+		t.Errorf("Expected empty choices in usage chunk, got %d", n)
+	}
+	if got := gjson.Get(final[0], "usage.prompt_tokens").Int(); got != 10 {
+		t.Errorf("Expected prompt_tokens 10, got %d", got)
+	}
+	if got := gjson.Get(final[0], "usage.completion_tokens").Int(); got != 5 {
+		t.Errorf("Expected completion_tokens from the latest chunk (5), got %d", got)
+	}
+	if got := gjson.Get(final[0], "id").String(); got != "resp-1" {
+		t.Errorf("Expected response id resp-1, got %s", got)
+	}
+
+	if final = run(`{"stream":true}`); len(final) != 0 {
+		t.Errorf("Expected no usage chunk without include_usage, got %v", final)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAI_BlockedPrompt(t *testing.T) {
+	chunk := `{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true},{"category":"HARM_CATEGORY_HATE_SPEECH","probability":"NEGLIGIBLE"}]},"usageMetadata":{"promptTokenCount":8,"totalTokenCount":8},"modelVersion":"gemini-2.5-pro"}`
+	var param any
+	out := ConvertGeminiResponseToOpenAI(context.Background(), "", []byte(`{"stream":true}`), nil, []byte(chunk), &param)
+	if len(out) != 1 {
+		t.Fatalf("Expected a terminal chunk for the blocked prompt, got %v", out)
+	}
+	choice := gjson.Get(out[0], "choices.0")
+	if choice.Get("finish_reason").String() != "content_filter" || choice.Get("native_finish_reason").String() != "SAFETY" {
+		t.Fatalf("Expected content_filter finish reason, got %s", out[0])
+	}
+	filter := choice.Get("content_filter")
+	if filter.Get("source").String() != "prompt" || filter.Get("reason").String() != "SAFETY" {
+		t.Fatalf("Unexpected content_filter details %s", filter.Raw)
+	}
+	if categories := filter.Get("categories").Array(); len(categories) != 1 || categories[0].Get("category").String() != "HARM_CATEGORY_HARASSMENT" || !categories[0].Get("blocked").Bool() {
+		t.Fatalf("Expected only the blocking category, got %s", filter.Get("categories").Raw)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAINonStream_BlockedResponse(t *testing.T) {
+	raw := `{"responseId":"resp-2","candidates":[{"content":{"parts":[{"text":"Partial"}],"role":"model"},"finishReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"MEDIUM"},{"category":"HARM_CATEGORY_HARASSMENT","probability":"LOW"}]}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":1,"totalTokenCount":5}}`
+	out := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(raw), nil)
+	choice := gjson.Get(out, "choices.0")
+	if choice.Get("finish_reason").String() != "content_filter" || choice.Get("native_finish_reason").String() != "SAFETY" {
+		t.Fatalf("Expected content_filter finish reason, got %s", out)
+	}
+	filter := choice.Get("content_filter")
+	if filter.Get("source").String() != "response" {
+		t.Fatalf("Expected a response block, got %s", filter.Raw)
+	}
+	if categories := filter.Get("categories").Array(); len(categories) != 1 || categories[0].Get("category").String() != "HARM_CATEGORY_DANGEROUS_CONTENT" {
+		t.Fatalf("Expected the elevated category, got %s", filter.Get("categories").Raw)
+	}
+	if got := choice.Get("message.content").String(); got != "Partial" {
+		t.Errorf("Expected the partial content to be kept, got %q", got)
+	}
+
+	unblocked := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(`{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`), nil)
+	if gjson.Get(unblocked, "choices.0.content_filter").Exists() {
+		t.Errorf("Expected no content_filter on a normal completion, got %s", unblocked)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAI_Logprobs(t *testing.T) {
+	raw := `{"candidates":[{"content":{"parts":[{"text":"Hi!"}],"role":"model"},"finishReason":"STOP","logprobsResult":{"topCandidates":[{"candidates":[{"token":"Hi","logProbability":-0.1},{"token":"Hello","logProbability":-2.5}]},{"candidates":[{"token":"!","logProbability":-0.3}]}],"chosenCandidates":[{"token":"Hi","logProbability":-0.1},{"token":"!","logProbability":-0.3}]}}]}`
+	out := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(raw), nil)
+	content := gjson.Get(out, "choices.0.logprobs.content").Array()
+	if len(content) != 2 {
+		t.Fatalf("Expected two token entries, got %s", out)
+	}
+	if content[0].Get("token").String() != "Hi" || content[0].Get("logprob").Float() != -0.1 || content[0].Get("bytes").Raw != `[72,105]` {
+		t.Fatalf("Unexpected first token entry: %s", content[0].Raw)
+	}
+	if top := content[0].Get("top_logprobs").Array(); len(top) != 2 || top[1].Get("token").String() != "Hello" || top[1].Get("logprob").Float() != -2.5 {
+		t.Fatalf("Unexpected top_logprobs: %s", content[0].Get("top_logprobs").Raw)
+	}
+
+	var param any
+	chunks := ConvertGeminiResponseToOpenAI(context.Background(), "", nil, nil, []byte(raw), &param)
+	if len(chunks) == 0 || len(gjson.Get(chunks[0], "choices.0.logprobs.content").Array()) != 2 {
+		t.Fatalf("Expected logprobs on the stream chunk, got %v", chunks)
+	}
+
+	plain := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(`{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`), nil)
+	if gjson.Get(plain, "choices.0.logprobs").Exists() {
+		t.Errorf("Expected no logprobs when the upstream returned none, got %s", plain)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAI_StreamedToolCallArguments(t *testing.T) {
+	chunks := []string{
+		`{"responseId":"resp-3","candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","willContinue":true}}]}}]}`,
+		`{"responseId":"resp-3","candidates":[{"content":{"parts":[{"functionCall":{"partialArgs":[{"jsonPath":"$.location","stringValue":"San Fr","willContinue":true}],"willContinue":true}}]}}]}`,
+		`{"responseId":"resp-3","candidates":[{"content":{"parts":[{"functionCall":{"partialArgs":[{"jsonPath":"$.location","stringValue":"ancisco \"CA\""},{"jsonPath":"$.filters.days","numberValue":3},{"jsonPath":"$.filters.hourly","boolValue":false},{"jsonPath":"$.units[0]","stringValue":"c"}],"willContinue":true}}]}}]}`,
+		`{"responseId":"resp-3","candidates":[{"content":{"parts":[{"functionCall":{"partialArgs":[{"jsonPath":"$.units[1]","stringValue":"mm"}]}}]}}]}`,
+		`{"responseId":"resp-3","candidates":[{"content":{"parts":[{"functionCall":{"name":"get_time","args":{"tz":"PST"}}}]},"finishReason":"STOP"}]}`,
+	}
+
+	type call struct {
+		name, id string
+		args     strings.Builder
+	}
+	calls := map[int64]*call{}
+	var param any
+	var finishReasons []string
+	for _, chunk := range chunks {
+		out := ConvertGeminiResponseToOpenAI(context.Background(), "", []byte(`{"stream":true}`), nil, []byte(chunk), &param)
+		if len(out) != 1 {
+			t.Fatalf("Expected one chunk, got %v", out)
+		}
+		finishReasons = append(finishReasons, gjson.Get(out[0], "choices.0.finish_reason").String())
+		for _, delta := range gjson.Get(out[0], "choices.0.delta.tool_calls").Array() {
+			index := delta.Get("index").Int()
+			c, seen := calls[index]
+			if !seen {
+				c = &call{name: delta.Get("function.name").String(), id: delta.Get("id").String()}
+				calls[index] = c
+				if c.name == "" || c.id == "" {
+					t.Fatalf("Expected the first delta of call %d to carry its id and name, got %s", index, delta.Raw)
+				}
+			} else if delta.Get("function.name").Exists() || delta.Get("id").Exists() {
+				t.Fatalf("Expected later deltas of call %d to omit the id and name, got %s", index, delta.Raw)
+			}
+			c.args.WriteString(delta.Get("function.arguments").String())
+		}
+	}
+
+	if len(calls) != 2 || calls[0] == nil || calls[1] == nil {
+		t.Fatalf("Expected calls with indices 0 and 1, got %v", calls)
+	}
+	if calls[0].name != "get_weather" || calls[1].name != "get_time" {
+		t.Fatalf("Unexpected call names %q and %q", calls[0].name, calls[1].name)
+	}
+	var weather struct {
+		Location string `json:"location"`
+		Filters  struct {
+			Days   int  `json:"days"`
+			Hourly bool `json:"hourly"`
+		} `json:"filters"`
+		Units []string `json:"units"`
+	}
+	if err := json.Unmarshal([]byte(calls[0].args.String()), &weather); err != nil {
+		t.Fatalf("Expected reassembled arguments to parse, got %q: %v", calls[0].args.String(), err)
+	}
+	if weather.Location != `San Francisco "CA"` || weather.Filters.Days != 3 || weather.Filters.Hourly || len(weather.Units) != 2 || weather.Units[1] != "mm" {
+		t.Fatalf("Unexpected reassembled arguments %q", calls[0].args.String())
+	}
+	if got := calls[1].args.String(); got != `{"tz":"PST"}` {
+		t.Fatalf("Expected complete arguments for the second call, got %q", got)
+	}
+
+	for i, reason := range finishReasons[:len(finishReasons)-1] {
+		if reason != "" {
+			t.Errorf("Expected no finish_reason before the final chunk, chunk %d has %q", i, reason)
+		}
+	}
+	if last := finishReasons[len(finishReasons)-1]; last != "tool_calls" {
+		t.Errorf("Expected the final chunk to finish with tool_calls, got %q", last)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAI_ClosesUnfinishedToolCall(t *testing.T) {
+	chunks := []string{
+		`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"search","partialArgs":[{"jsonPath":"$.query","stringValue":"go ","willContinue":true}],"willContinue":true}}]}}]}`,
+		`{"candidates":[{"content":{"parts":[{"functionCall":{"partialArgs":[{"jsonPath":"$.query","stringValue":"generics","willContinue":true}],"willContinue":true}}]},"finishReason":"STOP"}]}`,
+	}
+	var param any
+	var args strings.Builder
+	var last string
+	for _, chunk := range chunks {
+		out := ConvertGeminiResponseToOpenAI(context.Background(), "", []byte(`{"stream":true}`), nil, []byte(chunk), &param)
+		last = out[0]
+		for _, delta := range gjson.Get(out[0], "choices.0.delta.tool_calls").Array() {
+			if delta.Get("index").Int() != 0 {
+				t.Fatalf("Expected a single call at index 0, got %s", delta.Raw)
+			}
+			args.WriteString(delta.Get("function.arguments").String())
+		}
+	}
+	if got := gjson.Get(args.String(), "query").String(); !gjson.Valid(args.String()) || got != "go generics" {
+		t.Fatalf("Expected the call to be closed with valid arguments, got %q", args.String())
+	}
+	if gjson.Get(last, "choices.0.finish_reason").String() != "tool_calls" {
+		t.Fatalf("Expected finish_reason tool_calls, got %s", last)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAI_GroundingAnnotations(t *testing.T) {
+	raw := `{"candidates":[{"content":{"parts":[{"text":"Paris is the capital of France."}],"role":"model"},"finishReason":"STOP","groundingMetadata":{"groundingChunks":[{"web":{"uri":"https://example.com/paris","title":"example.com"}},{"web":{"uri":"https://example.org/france","title":"example.org"}}],"groundingSupports":[{"segment":{"startIndex":0,"endIndex":30,"text":"Paris is the capital of France"},"groundingChunkIndices":[0]}]}}]}`
+	out := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(raw), nil)
+	annotations := gjson.Get(out, "choices.0.message.annotations").Array()
+	if len(annotations) != 2 {
+		t.Fatalf("Expected two annotations, got %s", out)
+	}
+	first := annotations[0].Get("url_citation")
+	if annotations[0].Get("type").String() != "url_citation" || first.Get("url").String() != "https://example.com/paris" || first.Get("start_index").Int() != 0 || first.Get("end_index").Int() != 30 || first.Get("text").String() != "Paris is the capital of France" {
+		t.Fatalf("Unexpected first annotation: %s", annotations[0].Raw)
+	}
+	if second := annotations[1].Get("url_citation"); second.Get("url").String() != "https://example.org/france" || second.Get("start_index").Exists() {
+		t.Fatalf("Expected the uncited source without a span, got %s", annotations[1].Raw)
+	}
+
+	var param any
+	chunks := ConvertGeminiResponseToOpenAI(context.Background(), "", nil, nil, []byte(raw), &param)
+	if len(chunks) == 0 || len(gjson.Get(chunks[0], "choices.0.delta.annotations").Array()) != 2 {
+		t.Fatalf("Expected annotations on the stream chunk, got %v", chunks)
+	}
+
+	plain := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(`{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`), nil)
+	if gjson.Get(plain, "choices.0.message.annotations").Exists() {
+		t.Errorf("Expected no annotations without grounding, got %s", plain)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAINonStream_MediaOutput(t *testing.T) {
+	raw := `{"candidates":[{"content":{"parts":[{"text":"Here you go"},{"inlineData":{"mimeType":"image/png","data":"aW1n"}}]},"finishReason":"STOP"}]}`
+	out := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(raw), nil)
+	if got := gjson.Get(out, "choices.0.message.images.0.image_url.url").String(); got != "data:image/png;base64,aW1n" {
+		t.Fatalf("Expected the image as a data URL, got %s", out)
+	}
+	if got := gjson.Get(out, "choices.0.message.content").String(); got != "Here you go" {
+		t.Errorf("Expected the text to be kept, got %q", got)
+	}
+
+	raw = `{"candidates":[{"content":{"parts":[{"inlineData":{"mimeType":"audio/L16;codec=pcm;rate=24000","data":"YWI="}},{"inlineData":{"mimeType":"audio/L16;codec=pcm;rate=24000","data":"Y2Q="}}]},"finishReason":"STOP"}]}`
+	out = ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(raw), nil)
+	audio := gjson.Get(out, "choices.0.message.audio")
+	if audio.Get("data").String() != "YWJjZA==" || audio.Get("id").String() == "" {
+		t.Fatalf("Expected one audio object with the joined clip, got %s", out)
+	}
+	if gjson.Get(out, "choices.0.message.images").Exists() {
+		t.Errorf("Expected audio not to be reported as an image, got %s", out)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAI_DeterministicToolCallIDs(t *testing.T) {
+	raw := `{"responseId":"resp-7","candidates":[{"content":{"parts":[{"functionCall":{"name":"lookup","args":{"q":"a"}}},{"functionCall":{"name":"lookup","args":{"q":"b"}}}]},"finishReason":"STOP"}]}`
+	first := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(raw), nil)
+	second := ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(raw), nil)
+
+	ids := gjson.Get(first, "choices.0.message.tool_calls.#.id").Array()
+	if len(ids) != 2 || ids[0].String() == "" || ids[0].String() == ids[1].String() {
+		t.Fatalf("Expected two distinct tool call IDs, got %s", first)
+	}
+	if again := gjson.Get(second, "choices.0.message.tool_calls.#.id").Raw; again != gjson.Get(first, "choices.0.message.tool_calls.#.id").Raw {
+		t.Fatalf("Expected the same IDs on every run, got %s and %s", gjson.Get(first, "choices.0.message.tool_calls.#.id").Raw, again)
+	}
+
+	var param any
+	streamed := ConvertGeminiResponseToOpenAI(context.Background(), "", []byte(`{"stream":true}`), nil, []byte(raw), &param)
+	if got := gjson.Get(streamed[0], "choices.0.delta.tool_calls.#.id").Raw; got != gjson.Get(first, "choices.0.message.tool_calls.#.id").Raw {
+		t.Fatalf("Expected streamed IDs to match the non-streaming ones, got %s", got)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAI_ToolCallIDsWithoutResponseID(t *testing.T) {
+	raw := `{"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]},"finishReason":"STOP"}]}`
+	first := gjson.Get(ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(raw), nil), "choices.0.message.tool_calls.0.id").String()
+	second := gjson.Get(ConvertGeminiResponseToOpenAINonStream(context.Background(), "", nil, nil, []byte(raw), nil), "choices.0.message.tool_calls.0.id").String()
+	if first == "" || first == second {
+		t.Fatalf("Expected distinct tool call IDs across responses without a response ID, got %q and %q", first, second)
+	}
+
+	chunks := []string{
+		`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]}}]}`,
+		`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Rome"}}}]},"finishReason":"STOP"}]}`,
+	}
+	streamIDs := func() []string {
+		var param any
+		var ids []string
+		for _, chunk := range chunks {
+			for _, out := range ConvertGeminiResponseToOpenAI(context.Background(), "", []byte(`{"stream":true}`), nil, []byte(chunk), &param) {
+				for _, id := range gjson.Get(out, "choices.0.delta.tool_calls.#.id").Array() {
+					if id.String() != "" {
+						ids = append(ids, id.String())
+					}
+				}
+			}
+		}
+		return ids
+	}
+	turn1, turn2 := streamIDs(), streamIDs()
+	if len(turn1) != 2 || turn1[0] == turn1[1] {
+		t.Fatalf("Expected two distinct IDs within one stream, got %v", turn1)
+	}
+	if len(turn2) != 2 || turn2[0] == turn1[0] || turn2[1] == turn1[1] {
+		t.Fatalf("Expected IDs of a later turn not to repeat earlier ones, got %v and %v", turn1, turn2)
+	}
+}
@@ -0,0 +1,59 @@
+// Package embeddings provides translation between OpenAI embeddings requests and
+// Gemini batchEmbedContents calls. Every OpenAI input becomes one entry of a single
+// batch request, and the returned vectors are mapped back in input order.
+package embeddings
+
+import (
+	"bytes"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertOpenAIEmbeddingsRequestToGemini converts an OpenAI embeddings request into a Gemini
+// batchEmbedContents request. A string input yields a single entry and an array of strings
+// yields one entry per element. The OpenAI "dimensions" parameter maps to outputDimensionality.
+//
+// Parameters:
+//   - modelName: The name of the embedding model to use
+//   - rawJSON: The raw JSON request data from the OpenAI API
+//   - stream: Unused; embeddings are never streamed
+//
+// Returns:
+//   - []byte: The transformed request data in Gemini batchEmbedContents format
+func ConvertOpenAIEmbeddingsRequestToGemini(modelName string, inputRawJSON []byte, _ bool) []byte {
+	rawJSON := bytes.Clone(inputRawJSON)
+	out := []byte(`{"requests":[]}`)
+
+	dimensions := gjson.GetBytes(rawJSON, "dimensions")
+	for _, text := range EmbeddingInputs(rawJSON) {
+		entry := []byte(`{}`)
+		entry, _ = sjson.SetBytes(entry, "model", "models/"+modelName)
+		entry, _ = sjson.SetBytes(entry, "content.parts.0.text", text)
+		if dimensions.Type == gjson.Number && dimensions.Int() > 0 {
+			entry, _ = sjson.SetBytes(entry, "outputDimensionality", dimensions.Int())
+		}
+		out, _ = sjson.SetRawBytes(out, "requests.-1", entry)
+	}
+	return out
+}
+
+// EmbeddingInputs returns the texts of an OpenAI embeddings request in order.
+// Non-string array elements (such as pre-tokenized input) are skipped.
+func EmbeddingInputs(rawJSON []byte) []string {
+	input := gjson.GetBytes(rawJSON, "input")
+	switch {
+	case input.Type == gjson.String:
+		return []string{input.Str}
+	case input.IsArray():
+		var texts []string
+		input.ForEach(func(_, item gjson.Result) bool {
+			if item.Type == gjson.String {
+				texts = append(texts, item.Str)
+			}
+			return true
+		})
+		return texts
+	}
+	return nil
+}
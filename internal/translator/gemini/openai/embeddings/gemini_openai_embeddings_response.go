@@ -0,0 +1,86 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// ConvertGeminiEmbeddingsResponseToOpenAI converts a Gemini batchEmbedContents response into
+// the OpenAI embeddings list format. Vectors are returned as float arrays, or as base64-encoded
+// little-endian float32 data when the original request asked for encoding_format "base64".
+// Gemini does not report token usage for embeddings, so prompt tokens are estimated locally.
+//
+// Parameters:
+//   - ctx: The context for the request (unused)
+//   - modelName: The name of the embedding model
+//   - originalRequestRawJSON: The original OpenAI embeddings request
+//   - requestRawJSON: The translated Gemini request (unused)
+//   - rawJSON: The raw JSON response from the Gemini API
+//   - param: Unused conversion state
+//
+// Returns:
+//   - string: An OpenAI-compatible embeddings response
+func ConvertGeminiEmbeddingsResponseToOpenAI(_ context.Context, modelName string, originalRequestRawJSON, _, rawJSON []byte, _ *any) string {
+	out := `{"object":"list","data":[],"model":"","usage":{"prompt_tokens":0,"total_tokens":0}}`
+	out, _ = sjson.Set(out, "model", modelName)
+
+	base64Encoding := gjson.GetBytes(originalRequestRawJSON, "encoding_format").String() == "base64"
+	embeddings := gjson.GetBytes(rawJSON, "embeddings")
+	if !embeddings.Exists() {
+		// embedContent returns a single object instead of a list.
+		if single := gjson.GetBytes(rawJSON, "embedding"); single.Exists() {
+			embeddings = gjson.Parse("[" + single.Raw + "]")
+		}
+	}
+	index := 0
+	embeddings.ForEach(func(_, embedding gjson.Result) bool {
+		item := `{"object":"embedding","index":0,"embedding":[]}`
+		item, _ = sjson.Set(item, "index", index)
+		if base64Encoding {
+			item, _ = sjson.Set(item, "embedding", encodeEmbeddingBase64(embedding.Get("values")))
+		} else if values := embedding.Get("values"); values.IsArray() {
+			item, _ = sjson.SetRaw(item, "embedding", values.Raw)
+		}
+		out, _ = sjson.SetRaw(out, "data.-1", item)
+		index++
+		return true
+	})
+
+	tokens := estimateEmbeddingTokens(EmbeddingInputs(originalRequestRawJSON))
+	out, _ = sjson.Set(out, "usage.prompt_tokens", tokens)
+	out, _ = sjson.Set(out, "usage.total_tokens", tokens)
+	return out
+}
+
+// encodeEmbeddingBase64 packs the vector as little-endian float32 values, as OpenAI does.
+func encodeEmbeddingBase64(values gjson.Result) string {
+	arr := values.Array()
+	buf := make([]byte, 4*len(arr))
+	for i, v := range arr {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(v.Float())))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// estimateEmbeddingTokens approximates the prompt token count of the embedded texts.
+func estimateEmbeddingTokens(texts []string) int {
+	if len(texts) == 0 {
+		return 0
+	}
+	enc, err := tokenizer.Get(tokenizer.Cl100kBase)
+	if err != nil {
+		return 0
+	}
+	count, err := enc.Count(strings.Join(texts, "\n"))
+	if err != nil {
+		return 0
+	}
+	return count
+}
@@ -0,0 +1,91 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIEmbeddingsRequestToGemini_SingleString(t *testing.T) {
+	raw := []byte(`{"model":"gemini-embedding-001","input":"hello world","dimensions":768}`)
+
+	out := ConvertOpenAIEmbeddingsRequestToGemini("gemini-embedding-001", raw, false)
+
+	requests := gjson.GetBytes(out, "requests").Array()
+	if len(requests) != 1 {
+		t.Fatalf("Expected one batch entry, got %s", out)
+	}
+	if got := requests[0].Get("model").String(); got != "models/gemini-embedding-001" {
+		t.Fatalf("Expected qualified model name, got %s", got)
+	}
+	if got := requests[0].Get("content.parts.0.text").String(); got != "hello world" {
+		t.Fatalf("Expected input text, got %s", got)
+	}
+	if got := requests[0].Get("outputDimensionality").Int(); got != 768 {
+		t.Fatalf("Expected dimensions to map to outputDimensionality, got %d", got)
+	}
+}
+
+func TestConvertOpenAIEmbeddingsRequestToGemini_ArrayInput(t *testing.T) {
+	raw := []byte(`{"model":"gemini-embedding-001","input":["first","second","third"]}`)
+
+	out := ConvertOpenAIEmbeddingsRequestToGemini("gemini-embedding-001", raw, false)
+
+	requests := gjson.GetBytes(out, "requests").Array()
+	if len(requests) != 3 {
+		t.Fatalf("Expected one batch entry per input, got %s", out)
+	}
+	for i, want := range []string{"first", "second", "third"} {
+		if got := requests[i].Get("content.parts.0.text").String(); got != want {
+			t.Fatalf("Expected entry %d to be %q, got %q", i, want, got)
+		}
+		if requests[i].Get("outputDimensionality").Exists() {
+			t.Fatalf("Expected no outputDimensionality without dimensions, got %s", requests[i].Raw)
+		}
+	}
+}
+
+func TestConvertGeminiEmbeddingsResponseToOpenAI(t *testing.T) {
+	original := []byte(`{"model":"gemini-embedding-001","input":["first","second"]}`)
+	upstream := []byte(`{"embeddings":[{"values":[0.1,0.2]},{"values":[0.3,0.4]}]}`)
+
+	out := ConvertGeminiEmbeddingsResponseToOpenAI(context.Background(), "gemini-embedding-001", original, nil, upstream, nil)
+
+	if got := gjson.Get(out, "object").String(); got != "list" {
+		t.Fatalf("Expected list object, got %s", out)
+	}
+	data := gjson.Get(out, "data").Array()
+	if len(data) != 2 {
+		t.Fatalf("Expected two embeddings, got %s", out)
+	}
+	for i, item := range data {
+		if item.Get("index").Int() != int64(i) || item.Get("object").String() != "embedding" {
+			t.Fatalf("Expected embedding %d to be indexed, got %s", i, item.Raw)
+		}
+	}
+	if got := data[1].Get("embedding.1").Float(); got != 0.4 {
+		t.Fatalf("Expected vectors to be passed through, got %s", data[1].Raw)
+	}
+	if gjson.Get(out, "usage.prompt_tokens").Int() <= 0 || gjson.Get(out, "usage.total_tokens").Int() != gjson.Get(out, "usage.prompt_tokens").Int() {
+		t.Fatalf("Expected estimated usage, got %s", gjson.Get(out, "usage").Raw)
+	}
+}
+
+func TestConvertGeminiEmbeddingsResponseToOpenAI_Base64(t *testing.T) {
+	original := []byte(`{"model":"gemini-embedding-001","input":"hello","encoding_format":"base64"}`)
+	upstream := []byte(`{"embeddings":[{"values":[0.5,-1]}]}`)
+
+	out := ConvertGeminiEmbeddingsResponseToOpenAI(context.Background(), "gemini-embedding-001", original, nil, upstream, nil)
+
+	decoded, err := base64.StdEncoding.DecodeString(gjson.Get(out, "data.0.embedding").String())
+	if err != nil || len(decoded) != 8 {
+		t.Fatalf("Expected 8 bytes of base64 float32 data, got %s (%v)", out, err)
+	}
+	if got := math.Float32frombits(binary.LittleEndian.Uint32(decoded[4:])); got != -1 {
+		t.Fatalf("Expected little-endian float32 encoding, got %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package claude
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIResponseToClaude_EventOrder(t *testing.T) {
+	chunks := []string{
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}`,
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":"}}]}}]}`,
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":5,"completion_tokens":3}}`,
+		`data: [DONE]`,
+	}
+	var param any
+	var events []string
+	var toolInput string
+	for _, chunk := range chunks {
+		for _, out := range ConvertOpenAIResponseToClaude(context.Background(), "gpt-4o", []byte(`{"stream":true}`), nil, []byte(chunk), &param) {
+			for _, line := range strings.Split(out, "\n") {
+				if name, ok := strings.CutPrefix(line, "event: "); ok {
+					events = append(events, name)
+				}
+				if data, ok := strings.CutPrefix(line, "data: "); ok && gjson.Get(data, "delta.type").String() == "input_json_delta" {
+					toolInput += gjson.Get(data, "delta.partial_json").String()
+				}
+			}
+		}
+	}
+
+	if len(events) < 3 || events[0] != "message_start" || events[1] != "ping" || events[len(events)-1] != "message_stop" {
+		t.Fatalf("Expected message_start, ping first and message_stop last, got %v", events)
+	}
+	var blocks []string
+	for _, event := range events[2 : len(events)-1] {
+		if event != "content_block_delta" {
+			blocks = append(blocks, event)
+		}
+	}
+	want := []string{"content_block_start", "content_block_stop", "content_block_start", "content_block_stop", "message_delta"}
+	if !reflect.DeepEqual(blocks, want) {
+		t.Fatalf("Unexpected block events:\n got %v\nwant %v (all events %v)", blocks, want, events)
+	}
+	if toolInput != `{"city":"Paris"}` {
+		t.Fatalf("Expected tool input streamed as input_json_delta, got %q", toolInput)
+	}
+}
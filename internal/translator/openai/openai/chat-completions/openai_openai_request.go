@@ -4,6 +4,9 @@ package chat_completions
 
 import (
 	"bytes"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
@@ -27,5 +30,13 @@ func ConvertOpenAIRequestToOpenAI(modelName string, inputRawJSON []byte, _ bool)
 		// handling mechanism would be needed.
 		return bytes.Clone(inputRawJSON)
 	}
+	// Deduplicate stop sequences and cap them at the Chat Completions limit
+	if stop := gjson.GetBytes(updatedJSON, "stop"); stop.Exists() {
+		if sequences := util.NormalizeStopSequences(stop, util.OpenAIMaxStopSequences, "OpenAI"); len(sequences) > 0 {
+			updatedJSON, _ = sjson.SetBytes(updatedJSON, "stop", sequences)
+		} else {
+			updatedJSON, _ = sjson.DeleteBytes(updatedJSON, "stop")
+		}
+	}
 	return updatedJSON
 }
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+// MaxEndUserLength is the longest OpenAI "user" identifier accepted, in characters.
+const MaxEndUserLength = 256
+
+// NormalizeEndUser validates the OpenAI "user" field identifying the end user of a request and
+// returns it trimmed, with control characters removed. An absent or null field yields "".
+func NormalizeEndUser(value gjson.Result) (string, error) {
+	if !value.Exists() || value.Type == gjson.Null {
+		return "", nil
+	}
+	if value.Type != gjson.String {
+		return "", fmt.Errorf("user must be a string")
+	}
+	user := strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, value.String()))
+	if n := utf8.RuneCountInString(user); n > MaxEndUserLength {
+		return "", fmt.Errorf("user must be at most %d characters, got %d", MaxEndUserLength, n)
+	}
+	return user, nil
+}
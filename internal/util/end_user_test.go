@@ -0,0 +1,29 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestNormalizeEndUser(t *testing.T) {
+	cases := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: `{}`, want: ""},
+		{raw: `{"user":null}`, want: ""},
+		{raw: `{"user":"  user-42 "}`, want: "user-42"},
+		{raw: `{"user":"a\u0000b\nc"}`, want: "abc"},
+		{raw: `{"user":42}`, wantErr: true},
+		{raw: `{"user":"` + strings.Repeat("x", MaxEndUserLength+1) + `"}`, wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := NormalizeEndUser(gjson.Get(tc.raw, "user"))
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("NormalizeEndUser(%s) = %q, %v; want %q, error %v", tc.raw, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
@@ -5,8 +5,25 @@ import (
 	"strings"
 )
 
+// credentialHeaders carry upstream credentials managed by the proxy. A custom header never
+// replaces one of them once the executor has set it.
+var credentialHeaders = map[string]struct{}{
+	"Authorization":       {},
+	"Proxy-Authorization": {},
+	"X-Api-Key":           {},
+	"X-Goog-Api-Key":      {},
+	"Cookie":              {},
+}
+
+// listHeaders hold comma-separated feature flags; custom values are merged into the flags set
+// by the executor instead of replacing them.
+var listHeaders = map[string]struct{}{
+	"Anthropic-Beta": {},
+}
+
 // ApplyCustomHeadersFromAttrs applies user-defined headers stored in the provided attributes map.
-// Custom headers override built-in defaults when conflicts occur.
+// Custom headers override built-in defaults when conflicts occur, except that credential headers
+// already set by the executor are kept and list headers such as anthropic-beta are merged.
 func ApplyCustomHeadersFromAttrs(r *http.Request, attrs map[string]string) {
 	if r == nil {
 		return
@@ -47,6 +64,37 @@ func applyCustomHeaders(r *http.Request, headers map[string]string) {
 		if k == "" || v == "" {
 			continue
 		}
-		r.Header.Set(k, v)
+		name := http.CanonicalHeaderKey(k)
+		existing := r.Header.Get(name)
+		if _, ok := credentialHeaders[name]; ok && existing != "" {
+			continue
+		}
+		if _, ok := listHeaders[name]; ok && existing != "" {
+			r.Header.Set(name, mergeHeaderList(existing, v))
+			continue
+		}
+		r.Header.Set(name, v)
+	}
+}
+
+// mergeHeaderList appends the comma-separated tokens of extra missing from existing.
+func mergeHeaderList(existing, extra string) string {
+	tokens := strings.Split(existing, ",")
+	seen := make(map[string]struct{}, len(tokens))
+	for i, token := range tokens {
+		tokens[i] = strings.TrimSpace(token)
+		seen[tokens[i]] = struct{}{}
+	}
+	for _, token := range strings.Split(extra, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		if _, ok := seen[token]; ok {
+			continue
+		}
+		seen[token] = struct{}{}
+		tokens = append(tokens, token)
 	}
+	return strings.Join(tokens, ",")
 }
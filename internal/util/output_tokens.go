@@ -0,0 +1,41 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// ModelMaxOutputTokens returns the maximum number of output tokens the registry reports for
+// model, preferring MaxCompletionTokens over Gemini's OutputTokenLimit. It returns 0 when
+// the model is unknown or declares no limit.
+func ModelMaxOutputTokens(model string) int {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return 0
+	}
+	info := registry.GetGlobalRegistry().GetModelInfo(model)
+	if info == nil {
+		return 0
+	}
+	if info.MaxCompletionTokens > 0 {
+		return info.MaxCompletionTokens
+	}
+	return info.OutputTokenLimit
+}
+
+// NormalizeMaxOutputTokens clamps a requested output token limit to the model's maximum
+// output as reported by the registry. Requests within the limit, non-positive values and
+// models without a known maximum pass through unchanged.
+func NormalizeMaxOutputTokens(model string, requested int) int {
+	if requested <= 0 {
+		return requested
+	}
+	limit := ModelMaxOutputTokens(model)
+	if limit <= 0 || requested <= limit {
+		return requested
+	}
+	log.Debugf("clamping max output tokens for model %s from %d to %d", model, requested, limit)
+	return limit
+}
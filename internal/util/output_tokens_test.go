@@ -0,0 +1,34 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+)
+
+func TestNormalizeMaxOutputTokens(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("output-tokens-test", "claude", []*registry.ModelInfo{
+		{ID: "output-tokens-claude", MaxCompletionTokens: 64000},
+		{ID: "output-tokens-gemini", OutputTokenLimit: 8192},
+	})
+	t.Cleanup(func() { reg.UnregisterClient("output-tokens-test") })
+
+	cases := []struct {
+		name      string
+		model     string
+		requested int
+		want      int
+	}{
+		{"over max completion tokens", "output-tokens-claude", 128000, 64000},
+		{"under max completion tokens", "output-tokens-claude", 4096, 4096},
+		{"over output token limit", "output-tokens-gemini", 65536, 8192},
+		{"at output token limit", "output-tokens-gemini", 8192, 8192},
+		{"unknown model", "output-tokens-unknown", 1000000, 1000000},
+	}
+	for _, tc := range cases {
+		if got := NormalizeMaxOutputTokens(tc.model, tc.requested); got != tc.want {
+			t.Errorf("%s: NormalizeMaxOutputTokens(%q, %d) = %d, want %d", tc.name, tc.model, tc.requested, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package util
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// Params holds the sampling parameters of a request. Nil fields were not supplied.
+type Params struct {
+	Temperature *float64
+	TopP        *float64
+	TopK        *int64
+}
+
+var (
+	modelParamDefaultsMu sync.RWMutex
+	modelParamDefaults   map[string]config.ModelParamDefaults
+	samplingProfiles     map[string]config.ModelParamDefaults
+)
+
+// SetModelParamDefaults replaces the per-model sampling defaults consulted by
+// ApplyModelParamDefaults. Keys are model names, or model family prefixes ending in "*",
+// matched case-insensitively. Passing nil clears all defaults.
+func SetModelParamDefaults(defaults map[string]config.ModelParamDefaults) {
+	normalized := make(map[string]config.ModelParamDefaults, len(defaults))
+	for model, entry := range defaults {
+		key := strings.ToLower(strings.TrimSpace(model))
+		if key == "" || key == "*" {
+			continue
+		}
+		normalized[key] = entry
+	}
+	modelParamDefaultsMu.Lock()
+	modelParamDefaults = normalized
+	modelParamDefaultsMu.Unlock()
+}
+
+// SetSamplingProfiles replaces the named sampling profiles consulted by ApplySamplingProfile.
+// Names are matched case-insensitively. Passing nil clears all profiles.
+func SetSamplingProfiles(profiles map[string]config.ModelParamDefaults) {
+	normalized := make(map[string]config.ModelParamDefaults, len(profiles))
+	for name, entry := range profiles {
+		if key := strings.ToLower(strings.TrimSpace(name)); key != "" {
+			normalized[key] = entry
+		}
+	}
+	modelParamDefaultsMu.Lock()
+	samplingProfiles = normalized
+	modelParamDefaultsMu.Unlock()
+}
+
+// ApplySamplingProfile fills the parameters params leaves unset from the named profile,
+// clamped like model defaults. It reports false when no such profile is configured.
+func ApplySamplingProfile(model, name string, params *Params) bool {
+	modelParamDefaultsMu.RLock()
+	profile, ok := samplingProfiles[strings.ToLower(strings.TrimSpace(name))]
+	modelParamDefaultsMu.RUnlock()
+	if !ok {
+		return false
+	}
+	if params != nil {
+		fillParams(model, profile, params)
+	}
+	return true
+}
+
+// ApplyModelParamDefaults fills the parameters params leaves unset with the configured
+// defaults for model, matched like DefaultThinkingBudgetFor. Client-provided values are never
+// replaced. Defaults are clamped to the sampling range the registry reports for model, and
+// a non-positive top_k default is ignored.
+func ApplyModelParamDefaults(model string, params *Params) {
+	if params == nil {
+		return
+	}
+	key := strings.ToLower(strings.TrimSpace(model))
+	modelParamDefaultsMu.RLock()
+	defaults, ok := lookupModelPattern(modelParamDefaults, key)
+	modelParamDefaultsMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	fillParams(model, defaults, params)
+}
+
+// fillParams sets the parameters params leaves unset from defaults, clamped to the sampling
+// range the registry reports for model. A non-positive top_k is ignored.
+func fillParams(model string, defaults config.ModelParamDefaults, params *Params) {
+	var sampling *registry.SamplingRange
+	if info := registry.GetGlobalRegistry().GetModelInfo(model); info != nil {
+		sampling = info.Sampling
+	}
+	if params.Temperature == nil && defaults.Temperature != nil {
+		value := *defaults.Temperature
+		if sampling != nil {
+			value = clampDefault(model, "temperature", value, sampling.MaxTemperature)
+		}
+		params.Temperature = &value
+	}
+	if params.TopP == nil && defaults.TopP != nil {
+		value := *defaults.TopP
+		if sampling != nil {
+			value = clampDefault(model, "top_p", value, sampling.MaxTopP)
+		}
+		params.TopP = &value
+	}
+	if params.TopK == nil && defaults.TopK != nil && *defaults.TopK > 0 {
+		value := *defaults.TopK
+		params.TopK = &value
+	}
+}
+
+// clampDefault limits a default sampling parameter to [0, maxValue].
+func clampDefault(model, name string, value, maxValue float64) float64 {
+	clamped := min(max(value, 0), maxValue)
+	if clamped != value {
+		log.Debugf("clamping default %s for model %s from %v to %v", name, model, value, clamped)
+	}
+	return clamped
+}
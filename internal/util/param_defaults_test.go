@@ -0,0 +1,69 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+)
+
+func float64Ptr(v float64) *float64 { return &v }
+
+func TestApplyModelParamDefaults_OmittedAndProvided(t *testing.T) {
+	SetModelParamDefaults(map[string]config.ModelParamDefaults{
+		"param-test-*":     {Temperature: float64Ptr(0.4)},
+		"param-test-model": {Temperature: float64Ptr(0.7), TopP: float64Ptr(0.9)},
+	})
+	t.Cleanup(func() { SetModelParamDefaults(nil) })
+
+	var omitted Params
+	ApplyModelParamDefaults("param-test-model", &omitted)
+	if omitted.Temperature == nil || *omitted.Temperature != 0.7 || omitted.TopP == nil || *omitted.TopP != 0.9 || omitted.TopK != nil {
+		t.Fatalf("Expected the exact model defaults, got %+v", omitted)
+	}
+
+	provided := Params{Temperature: float64Ptr(0)}
+	ApplyModelParamDefaults("param-test-model", &provided)
+	if *provided.Temperature != 0 {
+		t.Fatalf("Expected the client temperature to win, got %v", *provided.Temperature)
+	}
+	if provided.TopP == nil || *provided.TopP != 0.9 {
+		t.Fatalf("Expected the omitted top_p to be defaulted, got %+v", provided)
+	}
+
+	var family Params
+	ApplyModelParamDefaults("Param-Test-Other", &family)
+	if family.Temperature == nil || *family.Temperature != 0.4 || family.TopP != nil {
+		t.Fatalf("Expected the family defaults, got %+v", family)
+	}
+
+	var unknown Params
+	ApplyModelParamDefaults("other-model", &unknown)
+	if unknown.Temperature != nil || unknown.TopP != nil {
+		t.Fatalf("Expected no defaults for an unconfigured model, got %+v", unknown)
+	}
+}
+
+func TestApplyModelParamDefaults_ClampsToRegistryRange(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("param-test-client", "claude", []*registry.ModelInfo{
+		{ID: "param-test-claude", Sampling: &registry.SamplingRange{MaxTemperature: 1, MaxTopP: 1}},
+	})
+	t.Cleanup(func() { reg.UnregisterClient("param-test-client") })
+	SetModelParamDefaults(map[string]config.ModelParamDefaults{
+		"param-test-claude": {Temperature: float64Ptr(1.5), TopP: float64Ptr(-0.2)},
+	})
+	t.Cleanup(func() { SetModelParamDefaults(nil) })
+
+	var params Params
+	ApplyModelParamDefaults("param-test-claude", &params)
+	if *params.Temperature != 1 || *params.TopP != 0 {
+		t.Fatalf("Expected defaults clamped to [0,1], got temperature %v top_p %v", *params.Temperature, *params.TopP)
+	}
+
+	provided := Params{Temperature: float64Ptr(1.8)}
+	ApplyModelParamDefaults("param-test-claude", &provided)
+	if *provided.Temperature != 1.8 {
+		t.Fatalf("Expected the client temperature to pass through, got %v", *provided.Temperature)
+	}
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// NormalizePenalty clamps a frequency or presence penalty to the range the registry reports
+// for model. Models without a known range pass the value through unchanged.
+func NormalizePenalty(model string, value float64) float64 {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return value
+	}
+	info := registry.GetGlobalRegistry().GetModelInfo(model)
+	if info == nil || info.Penalty == nil {
+		return value
+	}
+	clamped := value
+	if clamped < info.Penalty.Min {
+		clamped = info.Penalty.Min
+	}
+	if clamped > info.Penalty.Max {
+		clamped = info.Penalty.Max
+	}
+	if clamped != value {
+		log.Debugf("clamping penalty for model %s from %v to %v", model, value, clamped)
+	}
+	return clamped
+}
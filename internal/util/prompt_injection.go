@@ -0,0 +1,39 @@
+package util
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+var (
+	promptInjectionsMu sync.RWMutex
+	promptInjections   map[string]config.PromptInjection
+)
+
+// SetPromptInjections replaces the per-model prompt injections returned by
+// PromptInjectionFor. Keys are model names, or model family prefixes ending in "*", matched
+// case-insensitively. Entries without text are dropped and passing nil clears all injections.
+func SetPromptInjections(injections map[string]config.PromptInjection) {
+	normalized := make(map[string]config.PromptInjection, len(injections))
+	for model, entry := range injections {
+		key := strings.ToLower(strings.TrimSpace(model))
+		if key == "" || key == "*" || (entry.Prefix == "" && entry.Suffix == "") {
+			continue
+		}
+		normalized[key] = entry
+	}
+	promptInjectionsMu.Lock()
+	promptInjections = normalized
+	promptInjectionsMu.Unlock()
+}
+
+// PromptInjectionFor returns the prompt injection configured for model, matched like
+// DefaultThinkingBudgetFor.
+func PromptInjectionFor(model string) (config.PromptInjection, bool) {
+	key := strings.ToLower(strings.TrimSpace(model))
+	promptInjectionsMu.RLock()
+	defer promptInjectionsMu.RUnlock()
+	return lookupModelPattern(promptInjections, key)
+}
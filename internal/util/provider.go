@@ -191,7 +191,8 @@ func MaskAuthorizationHeader(value string) string {
 //
 // Behavior by header key (case-insensitive):
 //   - "Authorization": Preserve the auth type prefix (e.g., "Bearer ") and mask only the credential part.
-//   - Headers containing "api-key": Mask the entire value using HideAPIKey.
+//   - Headers containing "api-key", "token", "secret", "cookie", "password" or "session": Mask the
+//     entire value using HideAPIKey.
 //   - Others: Return the original value unchanged.
 //
 // Parameters:
@@ -208,7 +209,10 @@ func MaskSensitiveHeaderValue(key, value string) string {
 	case strings.Contains(lowerKey, "api-key"),
 		strings.Contains(lowerKey, "apikey"),
 		strings.Contains(lowerKey, "token"),
-		strings.Contains(lowerKey, "secret"):
+		strings.Contains(lowerKey, "secret"),
+		strings.Contains(lowerKey, "cookie"),
+		strings.Contains(lowerKey, "password"),
+		strings.Contains(lowerKey, "session"):
 		return HideAPIKey(value)
 	default:
 		return value
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Normalized rate-limit headers reported to clients for the account that served a request.
+const (
+	RateLimitRemainingRequestsHeader = "X-Proxy-Ratelimit-Remaining-Requests"
+	RateLimitRemainingTokensHeader   = "X-Proxy-Ratelimit-Remaining-Tokens"
+	// RateLimitResetHeader holds the whole seconds until the earliest reported limit resets.
+	RateLimitResetHeader = "X-Proxy-Ratelimit-Reset"
+)
+
+// UpstreamRateLimitsKey is the gin context key holding the normalized rate-limit headers of
+// the latest upstream response.
+const UpstreamRateLimitsKey = "UPSTREAM_RATE_LIMITS"
+
+var (
+	remainingRequestsHeaders = []string{"x-ratelimit-remaining-requests", "anthropic-ratelimit-requests-remaining", "x-ratelimit-remaining"}
+	remainingTokensHeaders   = []string{"x-ratelimit-remaining-tokens", "anthropic-ratelimit-tokens-remaining", "anthropic-ratelimit-input-tokens-remaining"}
+	resetHeaders             = []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens", "anthropic-ratelimit-requests-reset", "anthropic-ratelimit-tokens-reset", "anthropic-ratelimit-input-tokens-reset", "x-ratelimit-reset"}
+)
+
+// RateLimitHeaders maps the OpenAI-style x-ratelimit-* and Anthropic anthropic-ratelimit-*
+// headers of an upstream response to the normalized proxy headers. The first header present
+// in each group wins; resets may be durations ("6m0s"), RFC 3339 times or plain seconds and
+// are reported relative to now. An empty header set is returned when upstream sent none.
+func RateLimitHeaders(upstream http.Header, now time.Time) http.Header {
+	out := make(http.Header)
+	if value, ok := firstIntegerHeader(upstream, remainingRequestsHeaders); ok {
+		out.Set(RateLimitRemainingRequestsHeader, value)
+	}
+	if value, ok := firstIntegerHeader(upstream, remainingTokensHeaders); ok {
+		out.Set(RateLimitRemainingTokensHeader, value)
+	}
+	reset := -1.0
+	for _, name := range resetHeaders {
+		if seconds, ok := parseRateLimitReset(upstream.Get(name), now); ok && (reset < 0 || seconds < reset) {
+			reset = seconds
+		}
+	}
+	if reset >= 0 {
+		out.Set(RateLimitResetHeader, strconv.FormatInt(int64(math.Ceil(reset)), 10))
+	}
+	return out
+}
+
+func firstIntegerHeader(upstream http.Header, names []string) (string, bool) {
+	for _, name := range names {
+		value := strings.TrimSpace(upstream.Get(name))
+		if value == "" {
+			continue
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+			return strconv.FormatInt(n, 10), true
+		}
+	}
+	return "", false
+}
+
+// parseRateLimitReset returns the seconds from now until the reset described by value.
+func parseRateLimitReset(value string, now time.Time) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		switch {
+		case seconds > 1e12: // Unix time in milliseconds
+			seconds = time.UnixMilli(int64(seconds)).Sub(now).Seconds()
+		case seconds > 1e9: // Unix time in seconds
+			seconds = time.Unix(int64(seconds), 0).Sub(now).Seconds()
+		}
+		return max(seconds, 0), true
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return max(d.Seconds(), 0), true
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return max(at.Sub(now).Seconds(), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimitHeaders_Claude(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	upstream := make(http.Header)
+	upstream.Set("anthropic-ratelimit-requests-limit", "50")
+	upstream.Set("anthropic-ratelimit-requests-remaining", "49")
+	upstream.Set("anthropic-ratelimit-requests-reset", "2025-01-01T12:00:30Z")
+	upstream.Set("anthropic-ratelimit-tokens-limit", "80000")
+	upstream.Set("anthropic-ratelimit-tokens-remaining", "79500")
+	upstream.Set("anthropic-ratelimit-tokens-reset", "2025-01-01T12:00:05.5Z")
+
+	got := RateLimitHeaders(upstream, now)
+	want := map[string]string{
+		RateLimitRemainingRequestsHeader: "49",
+		RateLimitRemainingTokensHeader:   "79500",
+		RateLimitResetHeader:             "6",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected exactly the normalized headers, got %v", got)
+	}
+	for name, value := range want {
+		if got.Get(name) != value {
+			t.Fatalf("Expected %s: %s, got %q", name, value, got.Get(name))
+		}
+	}
+}
+
+func TestRateLimitHeaders_OpenAIDurations(t *testing.T) {
+	upstream := make(http.Header)
+	upstream.Set("x-ratelimit-remaining-requests", "9")
+	upstream.Set("x-ratelimit-reset-requests", "6m0s")
+	upstream.Set("x-ratelimit-reset-tokens", "120ms")
+
+	got := RateLimitHeaders(upstream, time.Now())
+	if got.Get(RateLimitRemainingRequestsHeader) != "9" || got.Get(RateLimitResetHeader) != "1" {
+		t.Fatalf("Expected the remaining requests and the earliest reset, got %v", got)
+	}
+	if got.Get(RateLimitRemainingTokensHeader) != "" {
+		t.Fatalf("Expected no token header when upstream sent none, got %v", got)
+	}
+	if len(RateLimitHeaders(http.Header{"Content-Type": {"application/json"}}, time.Now())) != 0 {
+		t.Fatal("Expected no headers for an upstream without rate-limit headers")
+	}
+}
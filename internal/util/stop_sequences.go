@@ -0,0 +1,67 @@
+package util
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// Maximum number of stop sequences accepted by each upstream API.
+const (
+	OpenAIMaxStopSequences = 4
+	GeminiMaxStopSequences = 5
+)
+
+// NormalizeStopSequences converts a stop value given either as a single string or as an
+// array of strings into a list without empty or duplicate entries, preserving order. When
+// limit is positive, sequences beyond it are dropped with a warning naming provider.
+func NormalizeStopSequences(stop gjson.Result, limit int, provider string) []string {
+	var values []gjson.Result
+	switch {
+	case stop.IsArray():
+		values = stop.Array()
+	case stop.Type == gjson.String:
+		values = []gjson.Result{stop}
+	default:
+		return nil
+	}
+	seen := make(map[string]struct{}, len(values))
+	sequences := make([]string, 0, len(values))
+	for _, value := range values {
+		if value.Type != gjson.String || value.String() == "" {
+			continue
+		}
+		sequence := value.String()
+		if _, dup := seen[sequence]; dup {
+			continue
+		}
+		seen[sequence] = struct{}{}
+		sequences = append(sequences, sequence)
+	}
+	if limit > 0 && len(sequences) > limit {
+		log.Warnf("%s accepts at most %d stop sequences; dropping %d: %q", provider, limit, len(sequences)-limit, sequences[limit:])
+		sequences = sequences[:limit]
+	}
+	if len(sequences) == 0 {
+		return nil
+	}
+	return sequences
+}
+
+// DropWhitespaceStopSequences removes sequences consisting only of whitespace, which Claude
+// rejects, logging a warning when any are dropped.
+func DropWhitespaceStopSequences(sequences []string) []string {
+	kept := sequences[:0:0]
+	for _, sequence := range sequences {
+		if strings.TrimSpace(sequence) == "" {
+			log.Warnf("dropping whitespace-only stop sequence %q not accepted by Claude", sequence)
+			continue
+		}
+		kept = append(kept, sequence)
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestNormalizeStopSequences(t *testing.T) {
+	cases := []struct {
+		name  string
+		stop  string
+		limit int
+		want  []string
+	}{
+		{"string", `"END"`, 4, []string{"END"}},
+		{"array", `["a","b"]`, 4, []string{"a", "b"}},
+		{"deduplicated", `["a","","a","b"]`, 4, []string{"a", "b"}},
+		{"over limit", `["1","2","3","4","5","6"]`, 5, []string{"1", "2", "3", "4", "5"}},
+		{"unlimited", `["1","2","3","4","5","6"]`, 0, []string{"1", "2", "3", "4", "5", "6"}},
+		{"empty string", `""`, 4, nil},
+		{"null", `null`, 4, nil},
+	}
+	for _, tc := range cases {
+		if got := NormalizeStopSequences(gjson.Parse(tc.stop), tc.limit, "test"); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: NormalizeStopSequences(%s, %d) = %q, want %q", tc.name, tc.stop, tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestDropWhitespaceStopSequences(t *testing.T) {
+	if got := DropWhitespaceStopSequences([]string{"\n", "END", " "}); !reflect.DeepEqual(got, []string{"END"}) {
+		t.Fatalf("Expected only END to remain, got %q", got)
+	}
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+var (
+	systemPromptPoliciesMu sync.RWMutex
+	systemPromptPolicies   map[string]config.SystemPromptPolicy
+)
+
+// SetSystemPromptPolicies replaces the per-key system prompt policies returned by
+// SystemPromptPolicyFor. Modes are normalized to lower case with merge as the default;
+// entries without a key or prompt, or with an unknown mode, are dropped. The first policy
+// configured for a key wins and passing nil clears all policies.
+func SetSystemPromptPolicies(policies []config.SystemPromptPolicy) {
+	normalized := make(map[string]config.SystemPromptPolicy, len(policies))
+	for _, policy := range policies {
+		key := strings.TrimSpace(policy.Key)
+		policy.Mode = strings.ToLower(strings.TrimSpace(policy.Mode))
+		if policy.Mode == "" {
+			policy.Mode = config.SystemPromptModeMerge
+		}
+		if key == "" || policy.Prompt == "" {
+			continue
+		}
+		if policy.Mode != config.SystemPromptModeOverride && policy.Mode != config.SystemPromptModeMerge {
+			continue
+		}
+		if _, exists := normalized[key]; !exists {
+			normalized[key] = policy
+		}
+	}
+	systemPromptPoliciesMu.Lock()
+	systemPromptPolicies = normalized
+	systemPromptPoliciesMu.Unlock()
+}
+
+// SystemPromptPolicyFor returns the system prompt policy configured for the client apiKey.
+func SystemPromptPolicyFor(apiKey string) (config.SystemPromptPolicy, bool) {
+	if apiKey == "" {
+		return config.SystemPromptPolicy{}, false
+	}
+	systemPromptPoliciesMu.RLock()
+	defer systemPromptPoliciesMu.RUnlock()
+	policy, ok := systemPromptPolicies[apiKey]
+	return policy, ok
+}
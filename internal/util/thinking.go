@@ -1,7 +1,10 @@
 package util
 
 import (
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 )
@@ -12,22 +15,194 @@ const (
 	DefaultThinkingBudget = 1024
 )
 
+var (
+	defaultThinkingBudgetsMu sync.RWMutex
+	defaultThinkingBudgets   map[string]int
+)
+
+// SetDefaultThinkingBudgets replaces the per-model default thinking budgets consulted by
+// DefaultThinkingBudgetFor. Keys are model names, or model family prefixes ending in "*"
+// (e.g. "gemini-2.5-*"), matched case-insensitively. Passing nil clears all defaults.
+func SetDefaultThinkingBudgets(budgets map[string]int) {
+	normalized := make(map[string]int, len(budgets))
+	for model, budget := range budgets {
+		key := strings.ToLower(strings.TrimSpace(model))
+		if key == "" || key == "*" {
+			continue
+		}
+		normalized[key] = budget
+	}
+	defaultThinkingBudgetsMu.Lock()
+	defaultThinkingBudgets = normalized
+	defaultThinkingBudgetsMu.Unlock()
+}
+
+// DefaultThinkingBudgetFor returns the thinking budget to use when a client enables thinking
+// for model without specifying a budget. An exact model entry wins over family prefixes, and
+// the longest matching prefix wins among families; DefaultThinkingBudget is the fallback.
+// The result is passed through NormalizeThinkingBudget so it respects the model's range.
+func DefaultThinkingBudgetFor(model string) int {
+	budget := DefaultThinkingBudget
+	key := strings.ToLower(strings.TrimSpace(model))
+
+	defaultThinkingBudgetsMu.RLock()
+	if configured, ok := lookupModelPattern(defaultThinkingBudgets, key); ok {
+		budget = configured
+	}
+	defaultThinkingBudgetsMu.RUnlock()
+
+	return NormalizeThinkingBudget(model, budget)
+}
+
+// lookupModelPattern returns the entry of patterns for a lower-cased model name: the exact
+// name if present, otherwise the longest family prefix ending in "*" that matches.
+func lookupModelPattern[V any](patterns map[string]V, key string) (V, bool) {
+	if value, ok := patterns[key]; ok {
+		return value, true
+	}
+	var match V
+	longest, found := 0, false
+	for pattern, value := range patterns {
+		prefix, isFamily := strings.CutSuffix(pattern, "*")
+		if isFamily && len(prefix) > longest && strings.HasPrefix(key, prefix) {
+			match, longest, found = value, len(prefix), true
+		}
+	}
+	return match, found
+}
+
+// referenceEffortBudgets are used for reasoning efforts on models without a known thinking range.
+var referenceEffortBudgets = map[string]int{"minimal": 512, "low": 1024, "medium": 8192, "high": 24576, "xhigh": 24576}
+
+// ReasoningEffortToBudget maps an OpenAI reasoning_effort to a thinking budget for model.
+// With a registered thinking range, "minimal" is the minimum, "low" an eighth of the usable
+// maximum (at least the minimum), "medium" the middle and "high" or "xhigh" the maximum,
+// where the usable maximum leaves at least half of the model's output limit for the answer.
+// "none" asks for no thinking and "auto" or any other value for a dynamic budget. Models
+// without a known range fall back to fixed reference budgets. The result is passed through
+// NormalizeThinkingBudget. Callers are expected to ignore reasoning_effort for models
+// without thinking support.
+func ReasoningEffortToBudget(model, effort string) int {
+	effort = strings.ToLower(strings.TrimSpace(effort))
+	budget := -1
+	if effort == "none" {
+		budget = 0
+	} else if ts, found := thinkingRangeFromRegistry(model); found {
+		ceiling := ts.Max
+		if info := registry.GetGlobalRegistry().GetModelInfo(model); info != nil && info.MaxCompletionTokens > 0 {
+			ceiling = min(ceiling, max(info.MaxCompletionTokens/2, ts.Min))
+		}
+		switch effort {
+		case "minimal":
+			budget = ts.Min
+		case "low":
+			budget = max(ceiling/8, ts.Min)
+		case "medium":
+			budget = (ts.Min + ceiling) / 2
+		case "high", "xhigh":
+			budget = ceiling
+		}
+		if budget > 0 {
+			budget = roundThinkingToStep(budget, ts)
+		}
+	} else if reference, ok := referenceEffortBudgets[effort]; ok {
+		budget = reference
+	}
+	return NormalizeThinkingBudget(model, budget)
+}
+
+// AntigravityThinkingMatch identifies how an AntigravityThinkingRule compares model names.
+type AntigravityThinkingMatch string
+
+const (
+	// AntigravityMatchExact matches the model name exactly.
+	AntigravityMatchExact AntigravityThinkingMatch = "exact"
+	// AntigravityMatchPrefix matches model names starting with the rule value.
+	AntigravityMatchPrefix AntigravityThinkingMatch = "prefix"
+	// AntigravityMatchSuffix matches model names ending with the rule value.
+	AntigravityMatchSuffix AntigravityThinkingMatch = "suffix"
+)
+
+// AntigravityThinkingRule describes a single model name pattern that enables thinking
+// for the Antigravity provider.
+type AntigravityThinkingRule struct {
+	Match AntigravityThinkingMatch
+	Value string
+}
+
+// matches reports whether the rule applies to the given model name.
+func (r AntigravityThinkingRule) matches(modelName string) bool {
+	switch r.Match {
+	case AntigravityMatchExact:
+		return modelName == r.Value
+	case AntigravityMatchPrefix:
+		return strings.HasPrefix(modelName, r.Value)
+	case AntigravityMatchSuffix:
+		return strings.HasSuffix(modelName, r.Value)
+	default:
+		return false
+	}
+}
+
+// defaultAntigravityThinkingRules mirrors the Antigravity2api reference implementation.
+var defaultAntigravityThinkingRules = []AntigravityThinkingRule{
+	{Match: AntigravityMatchSuffix, Value: "-thinking"},
+	{Match: AntigravityMatchExact, Value: "gemini-2.5-pro"},
+	{Match: AntigravityMatchExact, Value: "gemini-2.5-pro-image"},
+	{Match: AntigravityMatchPrefix, Value: "gemini-3-pro-"},
+}
+
+var (
+	antigravityThinkingRulesMu sync.RWMutex
+	antigravityThinkingRules   = append([]AntigravityThinkingRule(nil), defaultAntigravityThinkingRules...)
+)
+
+// AddAntigravityThinkingRule registers an additional rule consulted by
+// IsAntigravityThinkingModel. Rules with an empty value or unknown match kind are ignored.
+func AddAntigravityThinkingRule(rule AntigravityThinkingRule) {
+	if rule.Value == "" {
+		return
+	}
+	switch rule.Match {
+	case AntigravityMatchExact, AntigravityMatchPrefix, AntigravityMatchSuffix:
+	default:
+		return
+	}
+	antigravityThinkingRulesMu.Lock()
+	antigravityThinkingRules = append(antigravityThinkingRules, rule)
+	antigravityThinkingRulesMu.Unlock()
+}
+
+// ResetAntigravityThinkingRules restores the default Antigravity thinking rules,
+// discarding any rules added through AddAntigravityThinkingRule.
+func ResetAntigravityThinkingRules() {
+	antigravityThinkingRulesMu.Lock()
+	antigravityThinkingRules = append([]AntigravityThinkingRule(nil), defaultAntigravityThinkingRules...)
+	antigravityThinkingRulesMu.Unlock()
+}
+
 // IsAntigravityThinkingModel determines if a model should have thinking enabled
 // when used through the Antigravity provider. This follows the Antigravity2api
 // reference implementation logic.
 //
-// Thinking is enabled for:
+// By default thinking is enabled for:
 // - Models ending with "-thinking" (e.g., claude-sonnet-4-5-thinking)
 // - gemini-2.5-pro and gemini-2.5-pro-image explicitly
 // - Models starting with "gemini-3-pro-" (e.g., gemini-3-pro-preview, gemini-3-pro-image-preview)
 //
+// Additional rules can be registered with AddAntigravityThinkingRule.
+//
 // Note: "gemini-3-pro" without a suffix is NOT matched by the prefix check,
 // matching the Antigravity2api reference behavior.
 func IsAntigravityThinkingModel(modelName string) bool {
-	return strings.HasSuffix(modelName, "-thinking") ||
-		modelName == "gemini-2.5-pro" ||
-		modelName == "gemini-2.5-pro-image" ||
-		strings.HasPrefix(modelName, "gemini-3-pro-")
+	antigravityThinkingRulesMu.RLock()
+	defer antigravityThinkingRulesMu.RUnlock()
+	for _, rule := range antigravityThinkingRules {
+		if rule.matches(modelName) {
+			return true
+		}
+	}
+	return false
 }
 
 // IsAntigravityClaudeModel determines if a model is a Claude model in the
@@ -37,66 +212,358 @@ func IsAntigravityClaudeModel(modelName string) bool {
 	return strings.Contains(modelName, "claude")
 }
 
+// antigravityClaudeThinkingIncompatibleParams lists generation parameters that must be
+// removed for Antigravity Claude models when thinking is enabled.
+var antigravityClaudeThinkingIncompatibleParams = []string{"topP"}
+
+// SanitizeAntigravityClaudeParams removes generation parameters that are incompatible
+// with thinking for Antigravity Claude models (e.g., topP). It is a no-op for non-Claude
+// models, when thinking is disabled, or when params is nil. The removed keys are returned
+// in a stable order so callers can log what was dropped.
+func SanitizeAntigravityClaudeParams(model string, params map[string]any, thinkingEnabled bool) []string {
+	if !thinkingEnabled || params == nil || !IsAntigravityClaudeModel(model) {
+		return nil
+	}
+	var removed []string
+	for _, key := range antigravityClaudeThinkingIncompatibleParams {
+		if _, ok := params[key]; ok {
+			delete(params, key)
+			removed = append(removed, key)
+		}
+	}
+	return removed
+}
+
 // ModelSupportsThinking reports whether the given model has Thinking capability
-// according to the model registry metadata (provider-agnostic).
+// according to registered overrides or the model registry metadata (provider-agnostic).
 func ModelSupportsThinking(model string) bool {
+	supported, _ := ThinkingSupport(model)
+	return supported
+}
+
+// ThinkingSupport reports whether the model supports thinking and whether the model
+// is known at all. known is true whenever the registry has an entry for the model
+// (regardless of its Thinking metadata) or a thinking override is registered for it,
+// which lets callers tell an unknown model apart from one without thinking capability.
+func ThinkingSupport(model string) (supported bool, known bool) {
 	if model == "" {
-		return false
+		return false, false
+	}
+	if _, ok := lookupThinkingOverride(model); ok {
+		return true, true
 	}
 	if info := registry.GetGlobalRegistry().GetModelInfo(model); info != nil {
-		return info.Thinking != nil
+		return info.Thinking != nil, true
 	}
-	return false
+	return false, false
+}
+
+// ThinkingBudgetResolver converts a requested thinking budget into the value that
+// should be forwarded upstream for the given model. Implementations can apply
+// tenant-specific policies on top of (or instead of) the registry metadata.
+type ThinkingBudgetResolver interface {
+	Resolve(model string, requested int) int
+}
+
+// ThinkingBudgetResolverFunc adapts a plain function to the ThinkingBudgetResolver interface.
+type ThinkingBudgetResolverFunc func(model string, requested int) int
+
+// Resolve calls f(model, requested).
+func (f ThinkingBudgetResolverFunc) Resolve(model string, requested int) int {
+	return f(model, requested)
+}
+
+// DetailedThinkingBudgetResolver is an optional extension of ThinkingBudgetResolver
+// that also reports why a requested budget was changed.
+type DetailedThinkingBudgetResolver interface {
+	ThinkingBudgetResolver
+	ResolveDetailed(model string, requested int) (result int, reason string, changed bool)
 }
 
-// NormalizeThinkingBudget clamps the requested thinking budget to the
-// supported range for the specified model using registry metadata only.
+// Reasons reported by NormalizeThinkingBudgetDetailed when a budget is changed.
+const (
+	ThinkingReasonClampedToMin      = "clamped_to_min"
+	ThinkingReasonClampedToMax      = "clamped_to_max"
+	ThinkingReasonZeroNotAllowed    = "zero_not_allowed"
+	ThinkingReasonDynamicNotAllowed = "dynamic_not_allowed"
+	ThinkingReasonCustomResolver    = "custom_resolver"
+	ThinkingReasonClampedToHardCap  = "clamped_to_hard_cap"
+)
+
+// DefaultResolver clamps the requested thinking budget to the supported range
+// for the specified model using registry metadata only.
+type DefaultResolver struct{}
+
+// Resolve implements ThinkingBudgetResolver.
+func (r DefaultResolver) Resolve(model string, budget int) int {
+	result, _, _ := r.ResolveDetailed(model, budget)
+	return result
+}
+
+// ResolveDetailed implements DetailedThinkingBudgetResolver.
 // If the model is unknown or has no Thinking metadata, returns the original budget.
 // For dynamic (-1), returns -1 if DynamicAllowed; otherwise approximates mid-range
-// or min (0 if zero is allowed and mid <= 0).
-func NormalizeThinkingBudget(model string, budget int) int {
+// or min (0 if zero is allowed and mid <= 0). When the model declares a Step, the
+// approximated and clamped values are rounded down to a multiple of Step (never below min).
+func (DefaultResolver) ResolveDetailed(model string, budget int) (int, string, bool) {
 	if budget == -1 { // dynamic
-		if found, min, max, zeroAllowed, dynamicAllowed := thinkingRangeFromRegistry(model); found {
-			if dynamicAllowed {
-				return -1
+		if ts, found := thinkingRangeFromRegistry(model); found {
+			if ts.DynamicAllowed {
+				return -1, "", false
 			}
-			mid := (min + max) / 2
-			if mid <= 0 && zeroAllowed {
-				return 0
+			mid := (ts.Min + ts.Max) / 2
+			if mid <= 0 && ts.ZeroAllowed {
+				return 0, ThinkingReasonDynamicNotAllowed, true
 			}
 			if mid <= 0 {
-				return min
+				return ts.Min, ThinkingReasonDynamicNotAllowed, true
 			}
-			return mid
+			return roundThinkingToStep(mid, ts), ThinkingReasonDynamicNotAllowed, true
 		}
-		return -1
+		return -1, "", false
 	}
-	if found, min, max, zeroAllowed, _ := thinkingRangeFromRegistry(model); found {
+	if ts, found := thinkingRangeFromRegistry(model); found {
 		if budget == 0 {
-			if zeroAllowed {
-				return 0
+			if ts.ZeroAllowed {
+				return 0, "", false
 			}
-			return min
+			return ts.Min, ThinkingReasonZeroNotAllowed, ts.Min != 0
 		}
-		if budget < min {
-			return min
+		if budget < ts.Min {
+			return ts.Min, ThinkingReasonClampedToMin, true
 		}
-		if budget > max {
-			return max
+		if budget > ts.Max {
+			return roundThinkingToStep(ts.Max, ts), ThinkingReasonClampedToMax, true
 		}
-		return budget
+		return budget, "", false
 	}
-	return budget
+	return budget, "", false
 }
 
-// thinkingRangeFromRegistry attempts to read thinking ranges from the model registry.
-func thinkingRangeFromRegistry(model string) (found bool, min int, max int, zeroAllowed bool, dynamicAllowed bool) {
+// roundThinkingToStep rounds value down to the nearest multiple of ts.Step without
+// going below ts.Min. A zero Step leaves the value unchanged.
+func roundThinkingToStep(value int, ts registry.ThinkingSupport) int {
+	if ts.Step <= 0 {
+		return value
+	}
+	rounded := (value / ts.Step) * ts.Step
+	if rounded < ts.Min {
+		return ts.Min
+	}
+	return rounded
+}
+
+var (
+	thinkingResolverMu sync.RWMutex
+	thinkingResolver   ThinkingBudgetResolver = DefaultResolver{}
+)
+
+// SetThinkingBudgetResolver replaces the package-level resolver used by
+// NormalizeThinkingBudget. Passing nil restores DefaultResolver.
+func SetThinkingBudgetResolver(resolver ThinkingBudgetResolver) {
+	if resolver == nil {
+		resolver = DefaultResolver{}
+	}
+	thinkingResolverMu.Lock()
+	thinkingResolver = resolver
+	thinkingResolverMu.Unlock()
+}
+
+// CurrentThinkingBudgetResolver returns the package-level resolver used by NormalizeThinkingBudget.
+func CurrentThinkingBudgetResolver() ThinkingBudgetResolver {
+	thinkingResolverMu.RLock()
+	defer thinkingResolverMu.RUnlock()
+	return thinkingResolver
+}
+
+// NormalizeThinkingBudget resolves the requested thinking budget for the model
+// using the package-level resolver (DefaultResolver unless replaced via
+// SetThinkingBudgetResolver).
+func NormalizeThinkingBudget(model string, budget int) int {
+	result, _, _ := NormalizeThinkingBudgetDetailed(model, budget)
+	return result
+}
+
+// NormalizeThinkingBudgetDetailed behaves like NormalizeThinkingBudget but also returns
+// a machine-readable reason (one of the ThinkingReason* constants) and whether the
+// budget was changed. The reason is empty when the budget is returned unchanged.
+// Resolvers that do not implement DetailedThinkingBudgetResolver report
+// ThinkingReasonCustomResolver whenever they change the value.
+func NormalizeThinkingBudgetDetailed(model string, budget int) (result int, reason string, changed bool) {
+	resolver := CurrentThinkingBudgetResolver()
+	if detailed, ok := resolver.(DetailedThinkingBudgetResolver); ok {
+		result, reason, changed = detailed.ResolveDetailed(model, budget)
+	} else {
+		result = resolver.Resolve(model, budget)
+		if result != budget {
+			reason, changed = ThinkingReasonCustomResolver, true
+		}
+	}
+	if hardCap := int(thinkingBudgetHardCap.Load()); hardCap > 0 && result != -1 && result > hardCap {
+		return hardCap, ThinkingReasonClampedToHardCap, true
+	}
+	return result, reason, changed
+}
+
+// thinkingBudgetHardCap is an absolute ceiling applied after resolution; zero disables it.
+var thinkingBudgetHardCap atomic.Int64
+
+// SetThinkingBudgetHardCap sets an absolute ceiling for budgets returned by
+// NormalizeThinkingBudget regardless of per-model registry maximums. Dynamic (-1)
+// budgets are never capped. A value of zero or less disables the cap.
+func SetThinkingBudgetHardCap(n int) {
+	if n < 0 {
+		n = 0
+	}
+	thinkingBudgetHardCap.Store(int64(n))
+}
+
+// thinkingOverride keeps the model name as registered alongside its thinking range.
+type thinkingOverride struct {
+	model   string
+	support registry.ThinkingSupport
+}
+
+var (
+	thinkingOverridesMu sync.RWMutex
+	thinkingOverrides   = make(map[string]thinkingOverride)
+)
+
+// RegisterThinkingOverride stores a thinking range for the model that takes precedence
+// over registry metadata. Model names are matched case-insensitively.
+func RegisterThinkingOverride(model string, min, max int, zeroAllowed, dynamicAllowed bool) {
+	model = strings.TrimSpace(model)
+	key := strings.ToLower(model)
+	if key == "" {
+		return
+	}
+	thinkingOverridesMu.Lock()
+	thinkingOverrides[key] = thinkingOverride{
+		model: model,
+		support: registry.ThinkingSupport{
+			Min:            min,
+			Max:            max,
+			ZeroAllowed:    zeroAllowed,
+			DynamicAllowed: dynamicAllowed,
+		},
+	}
+	thinkingOverridesMu.Unlock()
+	ResetThinkingRangeCache()
+}
+
+// ClearThinkingOverride removes a previously registered override for the model.
+func ClearThinkingOverride(model string) {
+	key := strings.ToLower(strings.TrimSpace(model))
+	thinkingOverridesMu.Lock()
+	delete(thinkingOverrides, key)
+	thinkingOverridesMu.Unlock()
+	ResetThinkingRangeCache()
+}
+
+func lookupThinkingOverride(model string) (registry.ThinkingSupport, bool) {
+	key := strings.ToLower(strings.TrimSpace(model))
+	thinkingOverridesMu.RLock()
+	defer thinkingOverridesMu.RUnlock()
+	override, ok := thinkingOverrides[key]
+	return override.support, ok
+}
+
+// ThinkingCapableModels returns the sorted names of all models that support thinking,
+// combining registry metadata with runtime overrides registered via RegisterThinkingOverride.
+// Names are de-duplicated case-insensitively, preferring the registry spelling.
+func ThinkingCapableModels() []string {
+	ids := registry.GetGlobalRegistry().GetThinkingModelIDs()
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		seen[strings.ToLower(id)] = struct{}{}
+	}
+	thinkingOverridesMu.RLock()
+	for key, override := range thinkingOverrides {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		ids = append(ids, override.model)
+	}
+	thinkingOverridesMu.RUnlock()
+	sort.Strings(ids)
+	return ids
+}
+
+// maxThinkingRangeCacheEntries bounds the cache so arbitrary client-supplied model
+// names cannot grow it without limit.
+const maxThinkingRangeCacheEntries = 1024
+
+// thinkingRangeCacheEntry memoizes the result of a thinking range lookup.
+type thinkingRangeCacheEntry struct {
+	support registry.ThinkingSupport
+	found   bool
+}
+
+var (
+	thinkingRangeCacheMu      sync.RWMutex
+	thinkingRangeCache        = make(map[string]thinkingRangeCacheEntry)
+	thinkingRangeCacheVersion uint64
+	// thinkingRangeCacheGeneration counts resets so a lookup that raced an override
+	// change does not store the range it read before the change.
+	thinkingRangeCacheGeneration uint64
+)
+
+// ResetThinkingRangeCache discards all memoized thinking range lookups.
+func ResetThinkingRangeCache() {
+	thinkingRangeCacheMu.Lock()
+	thinkingRangeCache = make(map[string]thinkingRangeCacheEntry)
+	thinkingRangeCacheGeneration++
+	thinkingRangeCacheMu.Unlock()
+}
+
+// thinkingRangeFromRegistry reads thinking ranges through a cache that is invalidated
+// when the registry version changes or overrides are modified.
+func thinkingRangeFromRegistry(model string) (registry.ThinkingSupport, bool) {
 	if model == "" {
-		return false, 0, 0, false, false
+		return registry.ThinkingSupport{}, false
+	}
+	version := registry.GetGlobalRegistry().Version()
+
+	thinkingRangeCacheMu.RLock()
+	generation := thinkingRangeCacheGeneration
+	if thinkingRangeCacheVersion == version {
+		if entry, ok := thinkingRangeCache[model]; ok {
+			thinkingRangeCacheMu.RUnlock()
+			return entry.support, entry.found
+		}
+	}
+	thinkingRangeCacheMu.RUnlock()
+
+	support, found := lookupThinkingRange(model)
+	storeThinkingRange(model, version, generation, thinkingRangeCacheEntry{support: support, found: found})
+	return support, found
+}
+
+// storeThinkingRange caches entry unless the cache was reset since generation was read,
+// in which case the entry may predate an override change and is dropped.
+func storeThinkingRange(model string, version, generation uint64, entry thinkingRangeCacheEntry) {
+	thinkingRangeCacheMu.Lock()
+	defer thinkingRangeCacheMu.Unlock()
+	if generation != thinkingRangeCacheGeneration {
+		return
+	}
+	if thinkingRangeCacheVersion != version || len(thinkingRangeCache) >= maxThinkingRangeCacheEntries {
+		thinkingRangeCache = make(map[string]thinkingRangeCacheEntry)
+		thinkingRangeCacheVersion = version
+	}
+	thinkingRangeCache[model] = entry
+}
+
+// lookupThinkingRange attempts to read thinking ranges from registered overrides,
+// falling back to the model registry.
+func lookupThinkingRange(model string) (registry.ThinkingSupport, bool) {
+	if override, ok := lookupThinkingOverride(model); ok {
+		return override, true
 	}
 	info := registry.GetGlobalRegistry().GetModelInfo(model)
 	if info == nil || info.Thinking == nil {
-		return false, 0, 0, false, false
+		return registry.ThinkingSupport{}, false
 	}
-	return true, info.Thinking.Min, info.Thinking.Max, info.Thinking.ZeroAllowed, info.Thinking.DynamicAllowed
+	return *info.Thinking, true
 }
@@ -0,0 +1,401 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+)
+
+func registerThinkingTestModel(t *testing.T, id string, thinking *registry.ThinkingSupport) {
+	t.Helper()
+	clientID := "thinking-test-" + id
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient(clientID, "gemini", []*registry.ModelInfo{
+		{ID: id, OwnedBy: "google", Type: "gemini", Thinking: thinking},
+	})
+	t.Cleanup(func() { reg.UnregisterClient(clientID) })
+}
+
+func TestDefaultResolver_Clamp(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-clamp", &registry.ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true})
+
+	resolver := DefaultResolver{}
+	cases := []struct {
+		requested int
+		want      int
+	}{
+		{requested: -1, want: -1},
+		{requested: 0, want: 128},
+		{requested: 64, want: 128},
+		{requested: 4096, want: 4096},
+		{requested: 65536, want: 32768},
+	}
+	for _, tc := range cases {
+		if got := resolver.Resolve("thinking-test-clamp", tc.requested); got != tc.want {
+			t.Errorf("Resolve(%d) = %d, want %d", tc.requested, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultResolver_UnknownModel(t *testing.T) {
+	if got := (DefaultResolver{}).Resolve("thinking-test-unknown", 999); got != 999 {
+		t.Errorf("Expected unknown model to pass budget through, got %d", got)
+	}
+}
+
+func TestSetThinkingBudgetResolver(t *testing.T) {
+	t.Cleanup(func() { SetThinkingBudgetResolver(nil) })
+
+	SetThinkingBudgetResolver(ThinkingBudgetResolverFunc(func(model string, requested int) int {
+		if requested == -1 {
+			return 2048
+		}
+		return requested
+	}))
+	if got := NormalizeThinkingBudget("any-model", -1); got != 2048 {
+		t.Errorf("Expected custom resolver result 2048, got %d", got)
+	}
+
+	SetThinkingBudgetResolver(nil)
+	if _, ok := CurrentThinkingBudgetResolver().(DefaultResolver); !ok {
+		t.Errorf("Expected nil to restore DefaultResolver")
+	}
+}
+
+func TestThinkingOverride_TakesPrecedence(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-override", &registry.ThinkingSupport{Min: 128, Max: 32768, DynamicAllowed: true})
+	t.Cleanup(func() { ClearThinkingOverride("thinking-test-override") })
+
+	RegisterThinkingOverride("Thinking-Test-Override", 512, 8192, true, false)
+	if got := NormalizeThinkingBudget("thinking-test-override", 16384); got != 8192 {
+		t.Errorf("Expected override max 8192, got %d", got)
+	}
+	if got := NormalizeThinkingBudget("thinking-test-override", 0); got != 0 {
+		t.Errorf("Expected override to allow zero, got %d", got)
+	}
+
+	ClearThinkingOverride("THINKING-TEST-OVERRIDE")
+	if got := NormalizeThinkingBudget("thinking-test-override", 16384); got != 16384 {
+		t.Errorf("Expected registry range after clearing override, got %d", got)
+	}
+}
+
+func TestThinkingOverride_UnknownModel(t *testing.T) {
+	t.Cleanup(func() { ClearThinkingOverride("thinking-test-finetune") })
+
+	if ModelSupportsThinking("thinking-test-finetune") {
+		t.Fatal("Expected unknown model to not support thinking")
+	}
+	RegisterThinkingOverride("thinking-test-finetune", 1024, 4096, false, false)
+	if !ModelSupportsThinking("thinking-test-finetune") {
+		t.Fatal("Expected override to enable thinking support")
+	}
+	if got := NormalizeThinkingBudget("thinking-test-finetune", 100); got != 1024 {
+		t.Errorf("Expected override min 1024, got %d", got)
+	}
+}
+
+func TestModelSupportsThinking_AfterRegistryReload(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	t.Cleanup(func() { reg.UnregisterClient("thinking-test-reload") })
+	reg.Reload([]registry.ClientModels{{ClientID: "thinking-test-reload", Provider: "gemini", Models: []*registry.ModelInfo{
+		{ID: "thinking-test-reload-a"},
+	}}})
+	if ModelSupportsThinking("thinking-test-reload-b") {
+		t.Fatal("Expected model missing from the registry to not support thinking")
+	}
+
+	reg.Reload([]registry.ClientModels{{ClientID: "thinking-test-reload", Provider: "gemini", Models: []*registry.ModelInfo{
+		{ID: "thinking-test-reload-a"},
+		{ID: "thinking-test-reload-b", Thinking: &registry.ThinkingSupport{Min: 128, Max: 8192}},
+	}}})
+	if !ModelSupportsThinking("thinking-test-reload-b") {
+		t.Fatal("Expected reloaded model to support thinking")
+	}
+}
+
+func TestIsAntigravityThinkingModel_Defaults(t *testing.T) {
+	cases := map[string]bool{
+		"claude-sonnet-4-5-thinking": true,
+		"gemini-2.5-pro":             true,
+		"gemini-2.5-pro-image":       true,
+		"gemini-3-pro-preview":       true,
+		"gemini-3-pro":               false,
+		"gemini-2.5-flash":           false,
+	}
+	for model, want := range cases {
+		if got := IsAntigravityThinkingModel(model); got != want {
+			t.Errorf("IsAntigravityThinkingModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestAddAntigravityThinkingRule(t *testing.T) {
+	t.Cleanup(ResetAntigravityThinkingRules)
+
+	if IsAntigravityThinkingModel("gemini-4-ultra-preview") {
+		t.Fatal("Expected model to be unmatched before adding a rule")
+	}
+	AddAntigravityThinkingRule(AntigravityThinkingRule{Match: AntigravityMatchPrefix, Value: "gemini-4-ultra"})
+	if !IsAntigravityThinkingModel("gemini-4-ultra-preview") {
+		t.Error("Expected prefix rule to match")
+	}
+
+	ResetAntigravityThinkingRules()
+	if IsAntigravityThinkingModel("gemini-4-ultra-preview") {
+		t.Error("Expected reset to drop custom rules")
+	}
+}
+
+func TestNormalizeThinkingBudgetDetailed(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-detailed", &registry.ThinkingSupport{Min: 128, Max: 8192})
+
+	cases := []struct {
+		requested int
+		want      int
+		reason    string
+		changed   bool
+	}{
+		{requested: 1024, want: 1024, reason: "", changed: false},
+		{requested: 64, want: 128, reason: ThinkingReasonClampedToMin, changed: true},
+		{requested: 10000, want: 8192, reason: ThinkingReasonClampedToMax, changed: true},
+		{requested: 0, want: 128, reason: ThinkingReasonZeroNotAllowed, changed: true},
+		{requested: -1, want: 4160, reason: ThinkingReasonDynamicNotAllowed, changed: true},
+	}
+	for _, tc := range cases {
+		got, reason, changed := NormalizeThinkingBudgetDetailed("thinking-test-detailed", tc.requested)
+		if got != tc.want || reason != tc.reason || changed != tc.changed {
+			t.Errorf("NormalizeThinkingBudgetDetailed(%d) = (%d, %q, %v), want (%d, %q, %v)",
+				tc.requested, got, reason, changed, tc.want, tc.reason, tc.changed)
+		}
+	}
+}
+
+func TestSanitizeAntigravityClaudeParams(t *testing.T) {
+	params := map[string]any{"topP": 0.9, "temperature": 0.5}
+	removed := SanitizeAntigravityClaudeParams("claude-sonnet-4-5-thinking", params, true)
+	if len(removed) != 1 || removed[0] != "topP" {
+		t.Errorf("Expected topP to be removed, got %v", removed)
+	}
+	if _, ok := params["topP"]; ok {
+		t.Error("Expected topP to be deleted from params")
+	}
+	if _, ok := params["temperature"]; !ok {
+		t.Error("Expected temperature to be preserved")
+	}
+
+	params = map[string]any{"topP": 0.9}
+	if removed = SanitizeAntigravityClaudeParams("gemini-2.5-pro", params, true); removed != nil {
+		t.Errorf("Expected no-op for non-Claude model, got %v", removed)
+	}
+	if removed = SanitizeAntigravityClaudeParams("claude-sonnet-4-5", params, false); removed != nil {
+		t.Errorf("Expected no-op when thinking is disabled, got %v", removed)
+	}
+	if removed = SanitizeAntigravityClaudeParams("claude-sonnet-4-5", nil, true); removed != nil {
+		t.Errorf("Expected no-op for nil params, got %v", removed)
+	}
+}
+
+func TestThinkingCapableModels(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-capable-b", &registry.ThinkingSupport{Min: 1, Max: 10})
+	registerThinkingTestModel(t, "thinking-test-plain", nil)
+	RegisterThinkingOverride("thinking-test-capable-a", 1, 10, false, false)
+	RegisterThinkingOverride("Thinking-Test-Capable-B", 1, 10, false, false)
+	t.Cleanup(func() {
+		ClearThinkingOverride("thinking-test-capable-a")
+		ClearThinkingOverride("thinking-test-capable-b")
+	})
+
+	models := ThinkingCapableModels()
+	counts := make(map[string]int)
+	for _, m := range models {
+		counts[m]++
+	}
+	if counts["thinking-test-capable-a"] != 1 || counts["thinking-test-capable-b"] != 1 {
+		t.Errorf("Expected each thinking model exactly once, got %v", models)
+	}
+	if counts["Thinking-Test-Capable-B"] != 0 {
+		t.Errorf("Expected override duplicate to be collapsed, got %v", models)
+	}
+	if counts["thinking-test-plain"] != 0 {
+		t.Errorf("Expected non-thinking model to be excluded, got %v", models)
+	}
+	for i := 1; i < len(models); i++ {
+		if models[i-1] > models[i] {
+			t.Fatalf("Expected sorted result, got %v", models)
+		}
+	}
+}
+
+func TestSetThinkingBudgetHardCap(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-hardcap", &registry.ThinkingSupport{Min: 128, Max: 32768, DynamicAllowed: true})
+	t.Cleanup(func() { SetThinkingBudgetHardCap(0) })
+
+	SetThinkingBudgetHardCap(16000)
+	got, reason, changed := NormalizeThinkingBudgetDetailed("thinking-test-hardcap", 30000)
+	if got != 16000 || reason != ThinkingReasonClampedToHardCap || !changed {
+		t.Errorf("Expected hard cap clamp, got (%d, %q, %v)", got, reason, changed)
+	}
+	if got = NormalizeThinkingBudget("thinking-test-hardcap", 40000); got != 16000 {
+		t.Errorf("Expected hard cap to apply after registry clamp, got %d", got)
+	}
+	if got = NormalizeThinkingBudget("thinking-test-hardcap", -1); got != -1 {
+		t.Errorf("Expected dynamic budget to bypass hard cap, got %d", got)
+	}
+
+	SetThinkingBudgetHardCap(0)
+	if got = NormalizeThinkingBudget("thinking-test-hardcap", 30000); got != 30000 {
+		t.Errorf("Expected unset hard cap to leave budget unchanged, got %d", got)
+	}
+}
+
+func TestThinkingSupport(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-support-on", &registry.ThinkingSupport{Min: 1, Max: 10})
+	registerThinkingTestModel(t, "thinking-test-support-off", nil)
+
+	cases := []struct {
+		model     string
+		supported bool
+		known     bool
+	}{
+		{model: "thinking-test-support-on", supported: true, known: true},
+		{model: "thinking-test-support-off", supported: false, known: true},
+		{model: "thinking-test-support-typo", supported: false, known: false},
+		{model: "", supported: false, known: false},
+	}
+	for _, tc := range cases {
+		supported, known := ThinkingSupport(tc.model)
+		if supported != tc.supported || known != tc.known {
+			t.Errorf("ThinkingSupport(%q) = (%v, %v), want (%v, %v)", tc.model, supported, known, tc.supported, tc.known)
+		}
+		if got := ModelSupportsThinking(tc.model); got != tc.supported {
+			t.Errorf("ModelSupportsThinking(%q) = %v, want %v", tc.model, got, tc.supported)
+		}
+	}
+}
+
+func TestDefaultResolver_Step(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-step", &registry.ThinkingSupport{Min: 128, Max: 24600, Step: 256})
+
+	if got := NormalizeThinkingBudget("thinking-test-step", -1); got != 12288 {
+		t.Errorf("Expected dynamic approximation rounded to step (12288), got %d", got)
+	}
+	if got := NormalizeThinkingBudget("thinking-test-step", 30000); got != 24576 {
+		t.Errorf("Expected max clamp rounded to step (24576), got %d", got)
+	}
+	if got := NormalizeThinkingBudget("thinking-test-step", 16); got != 128 {
+		t.Errorf("Expected min clamp to never drop below min, got %d", got)
+	}
+	if got := NormalizeThinkingBudget("thinking-test-step", 1000); got != 1000 {
+		t.Errorf("Expected in-range budget to be unchanged, got %d", got)
+	}
+}
+
+func TestThinkingRangeCache_InvalidatedByRegistry(t *testing.T) {
+	ResetThinkingRangeCache()
+	if got := NormalizeThinkingBudget("thinking-test-cache", 99999); got != 99999 {
+		t.Fatalf("Expected unknown model to pass through, got %d", got)
+	}
+
+	registerThinkingTestModel(t, "thinking-test-cache", &registry.ThinkingSupport{Min: 128, Max: 8192})
+	if got := NormalizeThinkingBudget("thinking-test-cache", 99999); got != 8192 {
+		t.Errorf("Expected registry change to invalidate cache, got %d", got)
+	}
+}
+
+func TestThinkingRangeCache_DropsLookupsThatRacedAnOverride(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-race", &registry.ThinkingSupport{Min: 128, Max: 8192})
+	t.Cleanup(func() { ClearThinkingOverride("thinking-test-race") })
+
+	version := registry.GetGlobalRegistry().Version()
+	thinkingRangeCacheMu.RLock()
+	generation := thinkingRangeCacheGeneration
+	thinkingRangeCacheMu.RUnlock()
+	stale, found := lookupThinkingRange("thinking-test-race")
+
+	RegisterThinkingOverride("thinking-test-race", 128, 2048, false, false)
+	storeThinkingRange("thinking-test-race", version, generation, thinkingRangeCacheEntry{support: stale, found: found})
+
+	if got := NormalizeThinkingBudget("thinking-test-race", 99999); got != 2048 {
+		t.Errorf("Expected the override range after a racing lookup, got %d", got)
+	}
+}
+
+func TestDefaultThinkingBudgetFor(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-default-pro", &registry.ThinkingSupport{Min: 128, Max: 32768, DynamicAllowed: true})
+	registerThinkingTestModel(t, "thinking-test-default-flash", &registry.ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true})
+	registerThinkingTestModel(t, "thinking-test-default-small", &registry.ThinkingSupport{Min: 2048, Max: 8192})
+	t.Cleanup(func() { SetDefaultThinkingBudgets(nil) })
+
+	if got := DefaultThinkingBudgetFor("thinking-test-default-pro"); got != DefaultThinkingBudget {
+		t.Errorf("Expected global fallback %d without configuration, got %d", DefaultThinkingBudget, got)
+	}
+
+	SetDefaultThinkingBudgets(map[string]int{
+		"thinking-test-default-pro": 16384,
+		"thinking-test-*":           4096,
+		"thinking-test-default-*":   8192,
+		"thinking-test-other-*":     512,
+	})
+	cases := []struct {
+		model string
+		want  int
+	}{
+		{"thinking-test-default-pro", 16384},  // exact entry wins over families
+		{"THINKING-TEST-DEFAULT-PRO", 16384},  // case-insensitive
+		{"thinking-test-default-flash", 8192}, // longest family prefix
+		{"thinking-test-default-small", 8192}, // within registry range
+		{"thinking-test-unregistered", 4096},  // family match, no registry range
+		{"unrelated-model", DefaultThinkingBudget},
+	}
+	for _, tc := range cases {
+		if got := DefaultThinkingBudgetFor(tc.model); got != tc.want {
+			t.Errorf("DefaultThinkingBudgetFor(%q) = %d, want %d", tc.model, got, tc.want)
+		}
+	}
+
+	SetDefaultThinkingBudgets(map[string]int{"thinking-test-default-small": 100000})
+	if got := DefaultThinkingBudgetFor("thinking-test-default-small"); got != 8192 {
+		t.Errorf("Expected configured default to be clamped to registry max, got %d", got)
+	}
+}
+
+func TestReasoningEffortToBudget(t *testing.T) {
+	registerThinkingTestModel(t, "thinking-test-effort-pro", &registry.ThinkingSupport{Min: 128, Max: 32768, DynamicAllowed: true})
+	registerThinkingTestModel(t, "thinking-test-effort-flash", &registry.ThinkingSupport{Min: 0, Max: 24576, ZeroAllowed: true})
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("thinking-test-effort-capped", "claude", []*registry.ModelInfo{
+		{ID: "thinking-test-effort-capped", MaxCompletionTokens: 64000, Thinking: &registry.ThinkingSupport{Min: 1024, Max: 100000, DynamicAllowed: true}},
+	})
+	t.Cleanup(func() { reg.UnregisterClient("thinking-test-effort-capped") })
+
+	cases := []struct {
+		model  string
+		effort string
+		want   int
+	}{
+		{"thinking-test-effort-pro", "minimal", 128},
+		{"thinking-test-effort-pro", "low", 4096},
+		{"thinking-test-effort-pro", "medium", 16448},
+		{"thinking-test-effort-pro", "high", 32768},
+		{"thinking-test-effort-pro", "none", 128}, // zero not allowed
+		{"thinking-test-effort-pro", "auto", -1},
+		{"thinking-test-effort-flash", "low", 3072},
+		{"thinking-test-effort-flash", "medium", 12288},
+		{"thinking-test-effort-flash", "HIGH", 24576},
+		{"thinking-test-effort-flash", "none", 0},
+		{"thinking-test-effort-flash", "auto", 12288}, // dynamic not allowed
+		{"thinking-test-effort-capped", "low", 4000},
+		{"thinking-test-effort-capped", "medium", 16512},
+		{"thinking-test-effort-capped", "high", 32000}, // half of the output limit
+		{"thinking-test-effort-unknown", "low", 1024},
+		{"thinking-test-effort-unknown", "medium", 8192},
+		{"thinking-test-effort-unknown", "high", 24576},
+		{"thinking-test-effort-unknown", "auto", -1},
+	}
+	for _, tc := range cases {
+		if got := ReasoningEffortToBudget(tc.model, tc.effort); got != tc.want {
+			t.Errorf("ReasoningEffortToBudget(%q, %q) = %d, want %d", tc.model, tc.effort, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type reloadTestExecutor struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (e *reloadTestExecutor) Identifier() string { return "gemini" }
+
+func (e *reloadTestExecutor) Execute(_ context.Context, auth *coreauth.Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	e.keys = append(e.keys, auth.Attributes["api_key"])
+	e.mu.Unlock()
+	return cliproxyexecutor.Response{Payload: []byte("ok")}, nil
+}
+
+func (e *reloadTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *reloadTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *reloadTestExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+func writeReloadTestConfig(t *testing.T, path, authDir string, keys ...string) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("auth-dir: " + authDir + "\ngemini-api-key:\n")
+	for _, key := range keys {
+		b.WriteString("  - api-key: " + key + "\n")
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+// applyUpdates mirrors how the service applies watcher updates to the core manager.
+func applyUpdates(t *testing.T, manager *coreauth.Manager, queue <-chan AuthUpdate, want int) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < want; i++ {
+		select {
+		case update := <-queue:
+			switch update.Action {
+			case AuthUpdateActionAdd, AuthUpdateActionModify:
+				if _, ok := manager.GetByID(update.ID); ok {
+					_, _ = manager.Update(ctx, update.Auth)
+				} else {
+					_, _ = manager.Register(ctx, update.Auth)
+				}
+			case AuthUpdateActionDelete:
+				if existing, ok := manager.GetByID(update.ID); ok {
+					existing.Disabled = true
+					existing.Status = coreauth.StatusDisabled
+					_, _ = manager.Update(ctx, existing)
+				}
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for auth update %d of %d", i+1, want)
+		}
+	}
+}
+
+func servedKeys(t *testing.T, manager *coreauth.Manager, exec *reloadTestExecutor, requests int) []string {
+	t.Helper()
+	exec.mu.Lock()
+	exec.keys = nil
+	exec.mu.Unlock()
+	for i := 0; i < requests; i++ {
+		if _, err := manager.Execute(context.Background(), []string{"gemini"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+	}
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
+	seen := make(map[string]struct{})
+	for _, key := range exec.keys {
+		seen[key] = struct{}{}
+	}
+	out := make([]string, 0, len(seen))
+	for key := range seen {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestWatcherReload_SwapsCredentialSet(t *testing.T) {
+	dir := t.TempDir()
+	authDir := filepath.Join(dir, "auth")
+	if err := os.MkdirAll(authDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	writeReloadTestConfig(t, configPath, authDir, "key-a")
+
+	w, err := NewWatcher(configPath, authDir, nil)
+	if err != nil {
+		t.Fatalf("new watcher: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Stop() })
+	queue := make(chan AuthUpdate, 16)
+	w.SetAuthUpdateQueue(queue)
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	w.SetConfig(cfg)
+	w.reloadClients(true, nil)
+
+	exec := &reloadTestExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(exec)
+	applyUpdates(t, manager, queue, 1)
+	if got := servedKeys(t, manager, exec, 2); len(got) != 1 || got[0] != "key-a" {
+		t.Fatalf("Expected only key-a before reload, got %v", got)
+	}
+
+	// Adding a credential.
+	writeReloadTestConfig(t, configPath, authDir, "key-a", "key-b")
+	summary, err := w.Reload()
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if summary != (ReloadSummary{Added: 1, Unchanged: 1}) {
+		t.Fatalf("Unexpected summary after adding a key: %+v", summary)
+	}
+	applyUpdates(t, manager, queue, 1)
+	if got := servedKeys(t, manager, exec, 4); len(got) != 2 || got[0] != "key-a" || got[1] != "key-b" {
+		t.Fatalf("Expected new requests to use both keys, got %v", got)
+	}
+
+	// Removing a credential.
+	writeReloadTestConfig(t, configPath, authDir, "key-b")
+	summary, err = w.Reload()
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if summary != (ReloadSummary{Removed: 1, Unchanged: 1}) {
+		t.Fatalf("Unexpected summary after removing a key: %+v", summary)
+	}
+	applyUpdates(t, manager, queue, 1)
+	if got := servedKeys(t, manager, exec, 4); len(got) != 1 || got[0] != "key-b" {
+		t.Fatalf("Expected new requests to use only key-b, got %v", got)
+	}
+}
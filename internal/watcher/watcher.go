@@ -15,6 +15,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -273,7 +274,7 @@ func (w *Watcher) DispatchRuntimeAuthUpdate(update AuthUpdate) bool {
 	return true
 }
 
-func (w *Watcher) refreshAuthState() {
+func (w *Watcher) refreshAuthState() []AuthUpdate {
 	auths := w.SnapshotCoreAuths()
 	w.clientsMutex.Lock()
 	if len(w.runtimeAuths) > 0 {
@@ -286,6 +287,7 @@ func (w *Watcher) refreshAuthState() {
 	updates := w.prepareAuthUpdatesLocked(auths)
 	w.clientsMutex.Unlock()
 	w.dispatchAuthUpdates(updates)
+	return updates
 }
 
 func (w *Watcher) prepareAuthUpdatesLocked(auths []*coreauth.Auth) []AuthUpdate {
@@ -815,13 +817,68 @@ func (w *Watcher) reloadConfigIfChanged() {
 
 // reloadConfig reloads the configuration and triggers a full reload
 func (w *Watcher) reloadConfig() bool {
+	if _, err := w.applyConfigFromDisk(false); err != nil {
+		log.Errorf("failed to reload config: %v", err)
+		return false
+	}
+	return true
+}
+
+// ReloadSummary reports how an explicit reload changed the active credential set.
+type ReloadSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Modified  int `json:"modified"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Reload re-reads the config file and rescans the auth directory, then dispatches the
+// resulting credential changes as a single batch. Requests already running on a removed or
+// replaced credential keep the credential they were started with until they complete.
+func (w *Watcher) Reload() (ReloadSummary, error) {
+	data, errRead := os.ReadFile(w.configPath)
+	if errRead != nil {
+		return ReloadSummary{}, fmt.Errorf("read config: %w", errRead)
+	}
+	updates, err := w.applyConfigFromDisk(true)
+	if err != nil {
+		return ReloadSummary{}, err
+	}
+	sum := sha256.Sum256(data)
+	w.clientsMutex.Lock()
+	// Record the hash so the file event for the same content does not reload again.
+	w.lastConfigHash = hex.EncodeToString(sum[:])
+	total := len(w.currentAuths)
+	w.clientsMutex.Unlock()
+
+	var summary ReloadSummary
+	for _, update := range updates {
+		switch update.Action {
+		case AuthUpdateActionAdd:
+			summary.Added++
+		case AuthUpdateActionModify:
+			summary.Modified++
+		case AuthUpdateActionDelete:
+			summary.Removed++
+		}
+	}
+	summary.Unchanged = total - summary.Added - summary.Modified
+	if summary.Unchanged < 0 {
+		summary.Unchanged = 0
+	}
+	return summary, nil
+}
+
+// applyConfigFromDisk loads the config file, reloads clients from it and returns the auth
+// updates that were dispatched. The auth directory is rescanned when it changed or when
+// forceRescan is set.
+func (w *Watcher) applyConfigFromDisk(forceRescan bool) ([]AuthUpdate, error) {
 	log.Debug("=========================== CONFIG RELOAD ============================")
 	log.Debugf("starting config reload from: %s", w.configPath)
 
 	newConfig, errLoadConfig := config.LoadConfig(w.configPath)
 	if errLoadConfig != nil {
-		log.Errorf("failed to reload config: %v", errLoadConfig)
-		return false
+		return nil, errLoadConfig
 	}
 
 	if w.mirroredAuthDir != "" {
@@ -871,12 +928,12 @@ func (w *Watcher) reloadConfig() bool {
 
 	log.Infof("config successfully reloaded, triggering client reload")
 	// Reload clients with new config
-	w.reloadClients(authDirChanged, affectedOAuthProviders)
-	return true
+	return w.reloadClients(authDirChanged || forceRescan, affectedOAuthProviders), nil
 }
 
-// reloadClients performs a full scan and reload of all clients.
-func (w *Watcher) reloadClients(rescanAuth bool, affectedOAuthProviders []string) {
+// reloadClients performs a full scan and reload of all clients and returns the auth
+// updates that were dispatched.
+func (w *Watcher) reloadClients(rescanAuth bool, affectedOAuthProviders []string) []AuthUpdate {
 	log.Debugf("starting full client load process")
 
 	w.clientsMutex.RLock()
@@ -885,7 +942,7 @@ func (w *Watcher) reloadClients(rescanAuth bool, affectedOAuthProviders []string
 
 	if cfg == nil {
 		log.Error("config is nil, cannot reload clients")
-		return
+		return nil
 	}
 
 	if len(affectedOAuthProviders) > 0 {
@@ -966,7 +1023,7 @@ func (w *Watcher) reloadClients(rescanAuth bool, affectedOAuthProviders []string
 		w.reloadCallback(cfg)
 	}
 
-	w.refreshAuthState()
+	updates := w.refreshAuthState()
 
 	log.Infof("full client load complete - %d clients (%d auth files + %d Gemini API keys + %d Vertex API keys + %d Claude API keys + %d Codex keys + %d OpenAI-compat)",
 		totalNewClients,
@@ -977,6 +1034,7 @@ func (w *Watcher) reloadClients(rescanAuth bool, affectedOAuthProviders []string
 		codexAPIKeyCount,
 		openAICompatCount,
 	)
+	return updates
 }
 
 // createClientFromFile creates a single client instance from a given token file path.
@@ -1074,6 +1132,8 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 				attrs["base_url"] = base
 			}
 			addConfigHeadersToAttrs(entry.Headers, attrs)
+			addConfigWeightToAttrs(entry.Weight, attrs)
+			addConfigPriorityToAttrs(entry.Priority, attrs)
 			a := &coreauth.Auth{
 				ID:         id,
 				Provider:   "gemini",
@@ -1108,6 +1168,8 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 				attrs["models_hash"] = hash
 			}
 			addConfigHeadersToAttrs(ck.Headers, attrs)
+			addConfigWeightToAttrs(ck.Weight, attrs)
+			addConfigPriorityToAttrs(ck.Priority, attrs)
 			proxyURL := strings.TrimSpace(ck.ProxyURL)
 			a := &coreauth.Auth{
 				ID:         id,
@@ -1138,6 +1200,8 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 				attrs["base_url"] = ck.BaseURL
 			}
 			addConfigHeadersToAttrs(ck.Headers, attrs)
+			addConfigWeightToAttrs(ck.Weight, attrs)
+			addConfigPriorityToAttrs(ck.Priority, attrs)
 			proxyURL := strings.TrimSpace(ck.ProxyURL)
 			a := &coreauth.Auth{
 				ID:         id,
@@ -1181,6 +1245,8 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 					attrs["models_hash"] = hash
 				}
 				addConfigHeadersToAttrs(compat.Headers, attrs)
+				addConfigWeightToAttrs(entry.Weight, attrs)
+				addConfigPriorityToAttrs(entry.Priority, attrs)
 				a := &coreauth.Auth{
 					ID:         id,
 					Provider:   providerName,
@@ -1243,6 +1309,8 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 			attrs["models_hash"] = hash
 		}
 		addConfigHeadersToAttrs(compat.Headers, attrs)
+		addConfigWeightToAttrs(compat.Weight, attrs)
+		addConfigPriorityToAttrs(compat.Priority, attrs)
 		a := &coreauth.Auth{
 			ID:         id,
 			Provider:   providerName,
@@ -1313,6 +1381,7 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
+		addConfigHeadersToAttrs(metadataHeaders(metadata), a.Attributes)
 		applyAuthExcludedModelsMeta(a, cfg, nil, "oauth")
 		if provider == "gemini-cli" {
 			if virtuals := synthesizeGeminiVirtualAuths(a, metadata, now); len(virtuals) > 0 {
@@ -1370,6 +1439,7 @@ func synthesizeGeminiVirtualAuths(primary *coreauth.Auth, metadata map[string]an
 		if authPath != "" {
 			attrs["path"] = authPath
 		}
+		addConfigHeadersToAttrs(metadataHeaders(metadata), attrs)
 		metadataCopy := map[string]any{
 			"email":             email,
 			"project_id":        projectID,
@@ -1628,6 +1698,9 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.Port != newCfg.Port {
 		changes = append(changes, fmt.Sprintf("port: %d -> %d", oldCfg.Port, newCfg.Port))
 	}
+	if oldCfg.ShutdownDrainTimeoutSeconds != newCfg.ShutdownDrainTimeoutSeconds {
+		changes = append(changes, fmt.Sprintf("shutdown-drain-timeout-seconds: %d -> %d", oldCfg.ShutdownDrainTimeoutSeconds, newCfg.ShutdownDrainTimeoutSeconds))
+	}
 	if oldCfg.AuthDir != newCfg.AuthDir {
 		changes = append(changes, fmt.Sprintf("auth-dir: %s -> %s", oldCfg.AuthDir, newCfg.AuthDir))
 	}
@@ -1640,6 +1713,12 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.UsageStatisticsEnabled != newCfg.UsageStatisticsEnabled {
 		changes = append(changes, fmt.Sprintf("usage-statistics-enabled: %t -> %t", oldCfg.UsageStatisticsEnabled, newCfg.UsageStatisticsEnabled))
 	}
+	if oldCfg.MetricsEnabled != newCfg.MetricsEnabled {
+		changes = append(changes, fmt.Sprintf("metrics-enabled: %t -> %t", oldCfg.MetricsEnabled, newCfg.MetricsEnabled))
+	}
+	if oldCfg.MetricsToken != newCfg.MetricsToken {
+		changes = append(changes, "metrics-token: updated")
+	}
 	if oldCfg.DisableCooling != newCfg.DisableCooling {
 		changes = append(changes, fmt.Sprintf("disable-cooling: %t -> %t", oldCfg.DisableCooling, newCfg.DisableCooling))
 	}
@@ -1652,13 +1731,153 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.MaxRetryInterval != newCfg.MaxRetryInterval {
 		changes = append(changes, fmt.Sprintf("max-retry-interval: %d -> %d", oldCfg.MaxRetryInterval, newCfg.MaxRetryInterval))
 	}
+	if oldCfg.MaxCredentialAttempts != newCfg.MaxCredentialAttempts {
+		changes = append(changes, fmt.Sprintf("max-credential-attempts: %d -> %d", oldCfg.MaxCredentialAttempts, newCfg.MaxCredentialAttempts))
+	}
+	if oldCfg.RetryBackoffBaseMs != newCfg.RetryBackoffBaseMs {
+		changes = append(changes, fmt.Sprintf("retry-backoff-base-ms: %d -> %d", oldCfg.RetryBackoffBaseMs, newCfg.RetryBackoffBaseMs))
+	}
+	if oldCfg.RetryBackoffMaxMs != newCfg.RetryBackoffMaxMs {
+		changes = append(changes, fmt.Sprintf("retry-backoff-max-ms: %d -> %d", oldCfg.RetryBackoffMaxMs, newCfg.RetryBackoffMaxMs))
+	}
+	if !reflect.DeepEqual(oldCfg.RetryOn, newCfg.RetryOn) {
+		changes = append(changes, fmt.Sprintf("retry-on: status-codes %v -> %v, %d -> %d body substrings", oldCfg.RetryOn.StatusCodes, newCfg.RetryOn.StatusCodes, len(oldCfg.RetryOn.BodyContains), len(newCfg.RetryOn.BodyContains)))
+	}
+	if oldCfg.AttemptTimeoutSeconds != newCfg.AttemptTimeoutSeconds {
+		changes = append(changes, fmt.Sprintf("attempt-timeout-seconds: %d -> %d", oldCfg.AttemptTimeoutSeconds, newCfg.AttemptTimeoutSeconds))
+	}
+	if oldCfg.StreamAttemptTimeoutSeconds != newCfg.StreamAttemptTimeoutSeconds {
+		changes = append(changes, fmt.Sprintf("stream-attempt-timeout-seconds: %d -> %d", oldCfg.StreamAttemptTimeoutSeconds, newCfg.StreamAttemptTimeoutSeconds))
+	}
+	if oldCfg.RequestDeadlineSeconds != newCfg.RequestDeadlineSeconds {
+		changes = append(changes, fmt.Sprintf("request-deadline-seconds: %d -> %d", oldCfg.RequestDeadlineSeconds, newCfg.RequestDeadlineSeconds))
+	}
+	if oldCfg.StickySessionTTLSeconds != newCfg.StickySessionTTLSeconds {
+		changes = append(changes, fmt.Sprintf("sticky-session-ttl-seconds: %d -> %d", oldCfg.StickySessionTTLSeconds, newCfg.StickySessionTTLSeconds))
+	}
+	if oldCfg.AccountSelection != newCfg.AccountSelection {
+		changes = append(changes, fmt.Sprintf("account-selection: %s -> %s", oldCfg.AccountSelection, newCfg.AccountSelection))
+	}
 	if oldCfg.ProxyURL != newCfg.ProxyURL {
-		changes = append(changes, fmt.Sprintf("proxy-url: %s -> %s", oldCfg.ProxyURL, newCfg.ProxyURL))
+		changes = append(changes, fmt.Sprintf("proxy-url: %s -> %s", config.RedactProxyURL(oldCfg.ProxyURL), config.RedactProxyURL(newCfg.ProxyURL)))
+	}
+	if oldCfg.AllowRoutingOverride != newCfg.AllowRoutingOverride {
+		changes = append(changes, fmt.Sprintf("allow-routing-override: %t -> %t", oldCfg.AllowRoutingOverride, newCfg.AllowRoutingOverride))
+	}
+	if oldCfg.DryRun != newCfg.DryRun {
+		changes = append(changes, fmt.Sprintf("dry-run: %t -> %t", oldCfg.DryRun, newCfg.DryRun))
+	}
+	if oldCfg.CoalesceRequests != newCfg.CoalesceRequests {
+		changes = append(changes, fmt.Sprintf("coalesce-requests: %t -> %t", oldCfg.CoalesceRequests, newCfg.CoalesceRequests))
+	}
+	if oldCfg.StreamKeepAliveSeconds != newCfg.StreamKeepAliveSeconds {
+		changes = append(changes, fmt.Sprintf("stream-keepalive-seconds: %d -> %d", oldCfg.StreamKeepAliveSeconds, newCfg.StreamKeepAliveSeconds))
+	}
+	if oldCfg.UnsupportedLogitBias != newCfg.UnsupportedLogitBias {
+		changes = append(changes, fmt.Sprintf("unsupported-logit-bias: %s -> %s", oldCfg.UnsupportedLogitBias, newCfg.UnsupportedLogitBias))
+	}
+	if !reflect.DeepEqual(oldCfg.TokenEstimation, newCfg.TokenEstimation) {
+		changes = append(changes, "token-estimation: updated")
+	}
+	if oldCfg.RequestParameters.Mode != newCfg.RequestParameters.Mode {
+		changes = append(changes, fmt.Sprintf("request-parameters.mode: %s -> %s", oldCfg.RequestParameters.Mode, newCfg.RequestParameters.Mode))
+	}
+	if !reflect.DeepEqual(oldCfg.RequestParameters.Providers, newCfg.RequestParameters.Providers) {
+		changes = append(changes, fmt.Sprintf("request-parameters.providers: %d -> %d entries", len(oldCfg.RequestParameters.Providers), len(newCfg.RequestParameters.Providers)))
+	}
+	if oldCfg.StructuredOutputStreamRepair != newCfg.StructuredOutputStreamRepair {
+		changes = append(changes, fmt.Sprintf("structured-output-stream-repair: %t -> %t", oldCfg.StructuredOutputStreamRepair, newCfg.StructuredOutputStreamRepair))
+	}
+	if oldCfg.ContextWindowCheck != newCfg.ContextWindowCheck {
+		changes = append(changes, "context-window-check: updated")
+	}
+	if oldCfg.ChoiceFanOut != newCfg.ChoiceFanOut {
+		changes = append(changes, "choice-fan-out: updated")
+	}
+	if oldCfg.Batch != newCfg.Batch {
+		changes = append(changes, "batch: updated")
+	}
+	if oldCfg.ResponseCache != newCfg.ResponseCache {
+		changes = append(changes, fmt.Sprintf("response-cache.enabled: %t -> %t", oldCfg.ResponseCache.Enabled, newCfg.ResponseCache.Enabled))
+	}
+	if oldCfg.ImageInput != newCfg.ImageInput {
+		changes = append(changes, "image-input: updated")
 	}
 	if oldCfg.WebsocketAuth != newCfg.WebsocketAuth {
 		changes = append(changes, fmt.Sprintf("ws-auth: %t -> %t", oldCfg.WebsocketAuth, newCfg.WebsocketAuth))
 	}
 
+	if !reflect.DeepEqual(oldCfg.ModelAliases, newCfg.ModelAliases) {
+		changes = append(changes, fmt.Sprintf("model-aliases: %d -> %d entries", len(oldCfg.ModelAliases), len(newCfg.ModelAliases)))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelAccess, newCfg.ModelAccess) {
+		changes = append(changes, fmt.Sprintf("model-access: %d -> %d rules", len(oldCfg.ModelAccess), len(newCfg.ModelAccess)))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelFallbacks, newCfg.ModelFallbacks) {
+		changes = append(changes, fmt.Sprintf("model-fallbacks: %d -> %d entries", len(oldCfg.ModelFallbacks), len(newCfg.ModelFallbacks)))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelMirrors, newCfg.ModelMirrors) {
+		changes = append(changes, fmt.Sprintf("model-mirrors: %d -> %d entries", len(oldCfg.ModelMirrors), len(newCfg.ModelMirrors)))
+	}
+
+	if oldCfg.Compression != newCfg.Compression {
+		changes = append(changes, fmt.Sprintf("compression: enabled=%t min-bytes=%d -> enabled=%t min-bytes=%d", oldCfg.Compression.Enabled, oldCfg.Compression.MinBytes, newCfg.Compression.Enabled, newCfg.Compression.MinBytes))
+	}
+	if oldCfg.RequestBodyLimit != newCfg.RequestBodyLimit {
+		changes = append(changes, fmt.Sprintf("request-body-limit: enabled=%t max-bytes=%d multimodal-max-bytes=%d -> enabled=%t max-bytes=%d multimodal-max-bytes=%d", oldCfg.RequestBodyLimit.Enabled, oldCfg.RequestBodyLimit.MaxBytes, oldCfg.RequestBodyLimit.MultimodalMaxBytes, newCfg.RequestBodyLimit.Enabled, newCfg.RequestBodyLimit.MaxBytes, newCfg.RequestBodyLimit.MultimodalMaxBytes))
+	}
+	if !reflect.DeepEqual(oldCfg.RateLimit, newCfg.RateLimit) {
+		changes = append(changes, fmt.Sprintf("rate-limit: %d -> %d keys", len(oldCfg.RateLimit.Keys), len(newCfg.RateLimit.Keys)))
+	}
+	if oldCfg.AuditLog.Enabled != newCfg.AuditLog.Enabled {
+		changes = append(changes, fmt.Sprintf("audit-log.enabled: %t -> %t", oldCfg.AuditLog.Enabled, newCfg.AuditLog.Enabled))
+	}
+	if oldCfg.AuditLog.File != newCfg.AuditLog.File {
+		changes = append(changes, fmt.Sprintf("audit-log.file: %s -> %s", oldCfg.AuditLog.File, newCfg.AuditLog.File))
+	}
+	if !reflect.DeepEqual(oldCfg.AuditLog.Redact, newCfg.AuditLog.Redact) {
+		changes = append(changes, fmt.Sprintf("audit-log.redact: %d -> %d patterns", len(oldCfg.AuditLog.Redact), len(newCfg.AuditLog.Redact)))
+	}
+	if oldCfg.CircuitBreaker != newCfg.CircuitBreaker {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.failure-threshold: %d -> %d", oldCfg.CircuitBreaker.FailureThreshold, newCfg.CircuitBreaker.FailureThreshold))
+	}
+	if !reflect.DeepEqual(oldCfg.UpstreamHTTP, newCfg.UpstreamHTTP) {
+		changes = append(changes, "upstream-http: updated")
+	}
+	if !reflect.DeepEqual(oldCfg.ModelConcurrency, newCfg.ModelConcurrency) {
+		changes = append(changes, fmt.Sprintf("model-concurrency: %d -> %d limits, queue-timeout-seconds %d -> %d", len(oldCfg.ModelConcurrency.Limits), len(newCfg.ModelConcurrency.Limits), oldCfg.ModelConcurrency.QueueTimeoutSeconds, newCfg.ModelConcurrency.QueueTimeoutSeconds))
+	}
+	if !reflect.DeepEqual(oldCfg.UpstreamEndpoints, newCfg.UpstreamEndpoints) {
+		changes = append(changes, fmt.Sprintf("upstream-endpoints: %d -> %d providers", len(oldCfg.UpstreamEndpoints), len(newCfg.UpstreamEndpoints)))
+	}
+	if !reflect.DeepEqual(oldCfg.ProviderProxies, newCfg.ProviderProxies) {
+		changes = append(changes, fmt.Sprintf("provider-proxies: %d -> %d providers", len(oldCfg.ProviderProxies), len(newCfg.ProviderProxies)))
+	}
+	if !reflect.DeepEqual(oldCfg.AccountQuotas, newCfg.AccountQuotas) {
+		changes = append(changes, fmt.Sprintf("account-quotas: %d -> %d entries", len(oldCfg.AccountQuotas), len(newCfg.AccountQuotas)))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelRewrites, newCfg.ModelRewrites) {
+		changes = append(changes, fmt.Sprintf("model-rewrites: %d -> %d rules", len(oldCfg.ModelRewrites), len(newCfg.ModelRewrites)))
+	}
+	if oldCfg.Readiness != newCfg.Readiness {
+		changes = append(changes, fmt.Sprintf("readiness.upstream-ping: %t -> %t", oldCfg.Readiness.UpstreamPing, newCfg.Readiness.UpstreamPing))
+	}
+	if !reflect.DeepEqual(oldCfg.DefaultThinkingBudgets, newCfg.DefaultThinkingBudgets) {
+		changes = append(changes, fmt.Sprintf("default-thinking-budgets: %d -> %d entries", len(oldCfg.DefaultThinkingBudgets), len(newCfg.DefaultThinkingBudgets)))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelParamDefaults, newCfg.ModelParamDefaults) {
+		changes = append(changes, fmt.Sprintf("model-param-defaults: %d -> %d entries", len(oldCfg.ModelParamDefaults), len(newCfg.ModelParamDefaults)))
+	}
+	if !reflect.DeepEqual(oldCfg.SamplingProfiles, newCfg.SamplingProfiles) {
+		changes = append(changes, fmt.Sprintf("sampling-profiles: %d -> %d profiles", len(oldCfg.SamplingProfiles), len(newCfg.SamplingProfiles)))
+	}
+	if !reflect.DeepEqual(oldCfg.PromptInjections, newCfg.PromptInjections) {
+		changes = append(changes, fmt.Sprintf("prompt-injections: %d -> %d entries", len(oldCfg.PromptInjections), len(newCfg.PromptInjections)))
+	}
+	if !reflect.DeepEqual(oldCfg.SystemPromptPolicies, newCfg.SystemPromptPolicies) {
+		changes = append(changes, fmt.Sprintf("system-prompt-policies: %d -> %d entries", len(oldCfg.SystemPromptPolicies), len(newCfg.SystemPromptPolicies)))
+	}
+
 	// Quota-exceeded behavior
 	if oldCfg.QuotaExceeded.SwitchProject != newCfg.QuotaExceeded.SwitchProject {
 		changes = append(changes, fmt.Sprintf("quota-exceeded.switch-project: %t -> %t", oldCfg.QuotaExceeded.SwitchProject, newCfg.QuotaExceeded.SwitchProject))
@@ -1686,7 +1905,7 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 				changes = append(changes, fmt.Sprintf("gemini[%d].base-url: %s -> %s", i, strings.TrimSpace(o.BaseURL), strings.TrimSpace(n.BaseURL)))
 			}
 			if strings.TrimSpace(o.ProxyURL) != strings.TrimSpace(n.ProxyURL) {
-				changes = append(changes, fmt.Sprintf("gemini[%d].proxy-url: %s -> %s", i, strings.TrimSpace(o.ProxyURL), strings.TrimSpace(n.ProxyURL)))
+				changes = append(changes, fmt.Sprintf("gemini[%d].proxy-url: %s -> %s", i, config.RedactProxyURL(o.ProxyURL), config.RedactProxyURL(n.ProxyURL)))
 			}
 			if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
 				changes = append(changes, fmt.Sprintf("gemini[%d].api-key: updated", i))
@@ -1716,7 +1935,7 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 				changes = append(changes, fmt.Sprintf("claude[%d].base-url: %s -> %s", i, strings.TrimSpace(o.BaseURL), strings.TrimSpace(n.BaseURL)))
 			}
 			if strings.TrimSpace(o.ProxyURL) != strings.TrimSpace(n.ProxyURL) {
-				changes = append(changes, fmt.Sprintf("claude[%d].proxy-url: %s -> %s", i, strings.TrimSpace(o.ProxyURL), strings.TrimSpace(n.ProxyURL)))
+				changes = append(changes, fmt.Sprintf("claude[%d].proxy-url: %s -> %s", i, config.RedactProxyURL(o.ProxyURL), config.RedactProxyURL(n.ProxyURL)))
 			}
 			if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
 				changes = append(changes, fmt.Sprintf("claude[%d].api-key: updated", i))
@@ -1746,7 +1965,7 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 				changes = append(changes, fmt.Sprintf("codex[%d].base-url: %s -> %s", i, strings.TrimSpace(o.BaseURL), strings.TrimSpace(n.BaseURL)))
 			}
 			if strings.TrimSpace(o.ProxyURL) != strings.TrimSpace(n.ProxyURL) {
-				changes = append(changes, fmt.Sprintf("codex[%d].proxy-url: %s -> %s", i, strings.TrimSpace(o.ProxyURL), strings.TrimSpace(n.ProxyURL)))
+				changes = append(changes, fmt.Sprintf("codex[%d].proxy-url: %s -> %s", i, config.RedactProxyURL(o.ProxyURL), config.RedactProxyURL(n.ProxyURL)))
 			}
 			if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
 				changes = append(changes, fmt.Sprintf("codex[%d].api-key: updated", i))
@@ -1783,6 +2002,16 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			changes = append(changes, "remote-management.secret-key: updated")
 		}
 	}
+	if oldCfg.RemoteManagement.AdminToken != newCfg.RemoteManagement.AdminToken {
+		switch {
+		case oldCfg.RemoteManagement.AdminToken == "":
+			changes = append(changes, "remote-management.admin-token: created")
+		case newCfg.RemoteManagement.AdminToken == "":
+			changes = append(changes, "remote-management.admin-token: deleted")
+		default:
+			changes = append(changes, "remote-management.admin-token: updated")
+		}
+	}
 
 	// OpenAI compatibility providers (summarized)
 	if compat := diffOpenAICompatibility(oldCfg.OpenAICompatibility, newCfg.OpenAICompatibility); len(compat) > 0 {
@@ -1809,6 +2038,36 @@ func addConfigHeadersToAttrs(headers map[string]string, attrs map[string]string)
 	}
 }
 
+// metadataHeaders reads the optional "headers" object of an auth file, which attaches extra
+// upstream headers to that credential like the headers of a configured API key.
+func metadataHeaders(metadata map[string]any) map[string]string {
+	raw, ok := metadata["headers"].(map[string]any)
+	if !ok || len(raw) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if str, isString := value.(string); isString {
+			headers[key] = str
+		}
+	}
+	return headers
+}
+
+func addConfigWeightToAttrs(weight *int, attrs map[string]string) {
+	if weight == nil || *weight < 0 || attrs == nil {
+		return
+	}
+	attrs["weight"] = strconv.Itoa(*weight)
+}
+
+func addConfigPriorityToAttrs(priority *int, attrs map[string]string) {
+	if priority == nil || attrs == nil {
+		return
+	}
+	attrs["priority"] = strconv.Itoa(*priority)
+}
+
 func trimStrings(in []string) []string {
 	out := make([]string, len(in))
 	for i := range in {
@@ -10,8 +10,6 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
-	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -87,9 +85,9 @@ func (h *ClaudeCodeAPIHandler) ClaudeMessages(c *gin.Context) {
 	}
 }
 
-// ClaudeMessages handles Claude-compatible streaming chat completions.
-// This function implements a sophisticated client rotation and quota management system
-// to ensure high availability and optimal resource utilization across multiple backend clients.
+// ClaudeCountTokens handles the Claude-compatible /v1/messages/count_tokens endpoint.
+// Claude upstreams count the tokens themselves; for models no upstream can count, the
+// returned input_tokens is a local estimate flagged with "estimated": true.
 //
 // Parameters:
 //   - c: The Gin context for the request.
@@ -110,7 +108,7 @@ func (h *ClaudeCodeAPIHandler) ClaudeCountTokens(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 
 	alt := h.GetAlt(c)
-	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, c.Request.Context())
 
 	modelName := gjson.GetBytes(rawJSON, "model").String()
 
@@ -147,7 +145,7 @@ func (h *ClaudeCodeAPIHandler) ClaudeModels(c *gin.Context) {
 func (h *ClaudeCodeAPIHandler) handleNonStreamingResponse(c *gin.Context, rawJSON []byte) {
 	c.Header("Content-Type", "application/json")
 	alt := h.GetAlt(c)
-	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, c.Request.Context())
 
 	modelName := gjson.GetBytes(rawJSON, "model").String()
 
@@ -211,7 +209,7 @@ func (h *ClaudeCodeAPIHandler) handleStreamingResponse(c *gin.Context, rawJSON [
 
 	// Create a cancellable context for the backend client request
 	// This allows proper cleanup and cancellation of ongoing requests
-	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, c.Request.Context())
 
 	dataChan, errChan := h.ExecuteStreamWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON, "")
 	h.forwardClaudeStream(c, flusher, func(err error) { cliCancel(err) }, dataChan, errChan)
@@ -252,6 +250,17 @@ func (h *ClaudeCodeAPIHandler) forwardClaudeStream(c *gin.Context, flusher http.
 
 		case chunk, ok := <-data:
 			if !ok {
+				// A failed stream queues its error before the data channel closes; report it
+				// instead of ending the stream as if it had completed.
+				select {
+				case errMsg := <-errs:
+					if errMsg != nil {
+						writeClaudeStreamError(writer, errMsg)
+						cancel(errMsg.Error)
+						return
+					}
+				default:
+				}
 				// Stream ended, flush remaining data
 				_ = writer.Flush()
 				cancel(nil)
@@ -271,13 +280,7 @@ func (h *ClaudeCodeAPIHandler) forwardClaudeStream(c *gin.Context, flusher http.
 				continue
 			}
 			if errMsg != nil {
-				// An error occurred: emit as a proper SSE error event
-				errorBytes, _ := json.Marshal(h.toClaudeError(errMsg))
-				_, _ = writer.WriteString("event: error\n")
-				_, _ = writer.WriteString("data: ")
-				_, _ = writer.Write(errorBytes)
-				_, _ = writer.WriteString("\n\n")
-				_ = writer.Flush()
+				writeClaudeStreamError(writer, errMsg)
 			}
 			var execErr error
 			if errMsg != nil {
@@ -289,22 +292,12 @@ func (h *ClaudeCodeAPIHandler) forwardClaudeStream(c *gin.Context, flusher http.
 	}
 }
 
-type claudeErrorDetail struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
-}
-
-type claudeErrorResponse struct {
-	Type  string            `json:"type"`
-	Error claudeErrorDetail `json:"error"`
-}
-
-func (h *ClaudeCodeAPIHandler) toClaudeError(msg *interfaces.ErrorMessage) claudeErrorResponse {
-	return claudeErrorResponse{
-		Type: "error",
-		Error: claudeErrorDetail{
-			Type:    "api_error",
-			Message: msg.Error.Error(),
-		},
-	}
+// writeClaudeStreamError emits errMsg as a proper SSE error event and flushes the stream.
+func writeClaudeStreamError(writer *bufio.Writer, errMsg *interfaces.ErrorMessage) {
+	errorBytes := handlers.NewProxyError(errMsg).Body()
+	_, _ = writer.WriteString("event: error\n")
+	_, _ = writer.WriteString("data: ")
+	_, _ = writer.Write(errorBytes)
+	_, _ = writer.WriteString("\n\n")
+	_ = writer.Flush()
 }
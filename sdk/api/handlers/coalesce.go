@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// CoalesceHeader opts a single non-streaming request into sharing the response of an identical
+// request that is already in flight.
+const CoalesceHeader = "X-Proxy-Coalesce"
+
+type coalesceContextKey struct{}
+
+// withCoalescing marks ctx for request coalescing when the coalesce-requests config flag is set
+// or the client sends CoalesceHeader with a true value. The authenticated client identity is
+// recorded so responses are only shared between requests from the same client.
+func (h *BaseAPIHandler) withCoalescing(ctx context.Context, c *gin.Context) context.Context {
+	enabled := h.Cfg != nil && h.Cfg.CoalesceRequests
+	if !enabled && c != nil && c.Request != nil {
+		enabled, _ = strconv.ParseBool(strings.TrimSpace(c.GetHeader(CoalesceHeader)))
+	}
+	if !enabled {
+		return ctx
+	}
+	var client string
+	if c != nil {
+		if value, exists := c.Get("apiKey"); exists {
+			client = fmt.Sprint(value)
+		}
+	}
+	return context.WithValue(ctx, coalesceContextKey{}, client)
+}
+
+// WithoutCoalescing clears the coalescing mark set by withCoalescing, for callers that issue
+// deliberately identical requests which must each reach the upstream.
+func WithoutCoalescing(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(coalesceContextKey{}).(string); !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, coalesceContextKey{}, nil)
+}
+
+// coalesceKey derives a stable key for a non-streaming request marked by withCoalescing from
+// its prepared body and routing, so parameters expanded from a sampling profile header and
+// provider or account overrides are included. The body is canonicalised so key order and
+// whitespace do not matter. Dry runs are never coalesced.
+func coalesceKey(ctx context.Context, handlerType string, prepared preparedRequest, alt string) (string, bool) {
+	if ctx == nil || coreexecutor.IsDryRun(ctx) {
+		return "", false
+	}
+	client, ok := ctx.Value(coalesceContextKey{}).(string)
+	if !ok {
+		return "", false
+	}
+	hash := sha256.New()
+	for _, part := range []string{handlerType, prepared.model, alt, client, routingKey(prepared)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(canonicalJSON(prepared.payload))
+	return hex.EncodeToString(hash.Sum(nil)), true
+}
+
+// routingKey describes where a prepared request may be served: its providers, narrowed by a
+// provider override, and the credential pinned by an account override.
+func routingKey(prepared preparedRequest) string {
+	providers := slices.Clone(prepared.providers)
+	slices.Sort(providers)
+	pinned, _ := prepared.metadata[coreauth.PinnedAuthMetadataKey].(string)
+	return strings.Join(providers, ",") + "|" + pinned
+}
+
+// canonicalJSON re-encodes data with sorted object keys, returning data unchanged when it is
+// not valid JSON.
+func canonicalJSON(data []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return data
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+type coalescedResult struct {
+	payload []byte
+	errMsg  *interfaces.ErrorMessage
+}
+
+// executeCoalesced runs execute once per key among concurrent callers and hands every caller
+// its own copy of the result. The shared call is detached from the first caller's cancellation
+// so a disconnecting client does not fail the requests waiting on it; each waiting caller still
+// returns as soon as its own context is done.
+func (h *BaseAPIHandler) executeCoalesced(ctx context.Context, key string, execute func(context.Context) ([]byte, *interfaces.ErrorMessage)) ([]byte, *interfaces.ErrorMessage) {
+	shared := context.WithoutCancel(ctx)
+	ch := h.inflight.DoChan(key, func() (interface{}, error) {
+		payload, errMsg := execute(shared)
+		return coalescedResult{payload: payload, errMsg: errMsg}, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, &interfaces.ErrorMessage{StatusCode: 499, Error: ctx.Err()}
+	case res := <-ch:
+		result := res.Val.(coalescedResult)
+		if result.errMsg != nil {
+			errMsg := *result.errMsg
+			if errMsg.Addon != nil {
+				errMsg.Addon = errMsg.Addon.Clone()
+			}
+			return nil, &errMsg
+		}
+		return cloneBytes(result.payload), nil
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+type coalesceTestExecutor struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e *coalesceTestExecutor) Identifier() string { return "coalesce-test" }
+
+func (e *coalesceTestExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	if e.calls.Add(1) == 1 {
+		close(e.started)
+	}
+	<-e.release
+	return coreexecutor.Response{Payload: []byte(`{"answer":42}`)}, nil
+}
+
+func (e *coalesceTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *coalesceTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *coalesceTestExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+func runConcurrentRequests(t *testing.T, cfg *config.SDKConfig, headers func(i int) map[string]string, n int) (*coalesceTestExecutor, [][]byte) {
+	t.Helper()
+	exec := &coalesceTestExecutor{started: make(chan struct{}), release: make(chan struct{})}
+	manager := newHandlerTestManager(t, "coalesce-test", []*registry.ModelInfo{{ID: "coalesce-model"}}, exec)
+	h := &BaseAPIHandler{Cfg: cfg, AuthManager: manager}
+
+	bodies := []string{
+		`{"model":"coalesce-model","messages":[{"role":"user","content":"same prompt"}],"top_p":1}`,
+		`{"top_p":1, "messages":[{"content":"same prompt","role":"user"}], "model":"coalesce-model"}`,
+	}
+	results := make([][]byte, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			requestHeaders := map[string]string{"X-Request-Id": time.Now().String()}
+			if headers != nil {
+				for key, value := range headers(i) {
+					requestHeaders[key] = value
+				}
+			}
+			ctx, _, _ := newHandlerTestContext(t, h, "/v1/chat/completions", requestHeaders)
+			resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "coalesce-model", []byte(bodies[i%len(bodies)]), "")
+			if errMsg != nil {
+				t.Errorf("request %d failed: %v", i, errMsg.Error)
+				return
+			}
+			results[i] = resp
+		}(i)
+	}
+
+	<-exec.started
+	// Give the remaining requests time to join the in-flight call before it completes.
+	time.Sleep(100 * time.Millisecond)
+	close(exec.release)
+	wg.Wait()
+	return exec, results
+}
+
+func TestExecuteWithAuthManager_CoalescesIdenticalRequests(t *testing.T) {
+	const n = 8
+	optIn := func(int) map[string]string { return map[string]string{CoalesceHeader: "true"} }
+	for name, tc := range map[string]struct {
+		cfg     *config.SDKConfig
+		headers func(int) map[string]string
+	}{
+		"config": {cfg: &config.SDKConfig{CoalesceRequests: true}},
+		"header": {cfg: &config.SDKConfig{}, headers: optIn},
+	} {
+		t.Run(name, func(t *testing.T) {
+			exec, results := runConcurrentRequests(t, tc.cfg, tc.headers, n)
+			if got := exec.calls.Load(); got != 1 {
+				t.Fatalf("Expected a single upstream call, got %d", got)
+			}
+			for i, resp := range results {
+				if string(resp) != `{"answer":42}` {
+					t.Errorf("request %d: unexpected response %q", i, resp)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteWithAuthManager_NoCoalescingByDefault(t *testing.T) {
+	exec, _ := runConcurrentRequests(t, &config.SDKConfig{}, nil, 3)
+	if got := exec.calls.Load(); got != 3 {
+		t.Fatalf("Expected one upstream call per request without opt-in, got %d", got)
+	}
+}
+
+func TestExecuteWithAuthManager_DoesNotCoalesceDifferentProfiles(t *testing.T) {
+	setTestSamplingProfiles(t)
+	profiles := []string{"cold", "warm"}
+	exec, _ := runConcurrentRequests(t, &config.SDKConfig{CoalesceRequests: true}, func(i int) map[string]string {
+		return map[string]string{SamplingProfileHeader: profiles[i%len(profiles)]}
+	}, 4)
+	if got := exec.calls.Load(); got != 2 {
+		t.Fatalf("Expected one upstream call per sampling profile, got %d", got)
+	}
+}
+
+func TestCoalesceKey(t *testing.T) {
+	ctx := context.WithValue(context.Background(), coalesceContextKey{}, "client-a")
+	key := func(ctx context.Context, model, body string) string {
+		k, ok := coalesceKey(ctx, "openai", preparedRequest{providers: []string{"p"}, model: model, payload: []byte(body)}, "")
+		if !ok {
+			t.Fatalf("Expected a coalesce key")
+		}
+		return k
+	}
+	base := key(ctx, "m", `{"a":1,"b":[1,2]}`)
+	if got := key(ctx, "m", `{ "b":[1,2], "a":1 }`); got != base {
+		t.Errorf("Expected key to ignore key order and whitespace")
+	}
+	if got := key(ctx, "m", `{"a":2,"b":[1,2]}`); got == base {
+		t.Errorf("Expected different params to produce a different key")
+	}
+	if got := key(ctx, "other", `{"a":1,"b":[1,2]}`); got == base {
+		t.Errorf("Expected different models to produce a different key")
+	}
+	otherClient := context.WithValue(context.Background(), coalesceContextKey{}, "client-b")
+	if got := key(otherClient, "m", `{"a":1,"b":[1,2]}`); got == base {
+		t.Errorf("Expected different clients to produce a different key")
+	}
+	pinned := preparedRequest{providers: []string{"p"}, model: "m", payload: []byte(`{"a":1,"b":[1,2]}`), metadata: map[string]any{coreauth.PinnedAuthMetadataKey: "auth-a"}}
+	if got, _ := coalesceKey(ctx, "openai", pinned, ""); got == base {
+		t.Errorf("Expected a pinned account to produce a different key")
+	}
+	if got, _ := coalesceKey(ctx, "openai", preparedRequest{providers: []string{"q"}, model: "m", payload: []byte(`{"a":1,"b":[1,2]}`)}, ""); got == base {
+		t.Errorf("Expected different providers to produce a different key")
+	}
+	if _, ok := coalesceKey(context.Background(), "openai", preparedRequest{model: "m", payload: []byte(`{}`)}, ""); ok {
+		t.Errorf("Expected no key for requests that did not opt in")
+	}
+	if _, ok := coalesceKey(coreexecutor.WithDryRun(ctx), "openai", preparedRequest{model: "m", payload: []byte(`{}`)}, ""); ok {
+		t.Errorf("Expected dry runs not to be coalesced")
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/tidwall/gjson"
+)
+
+const defaultContextWindowSafetyMargin = 0.1
+
+// checkContextWindow rejects a request whose estimated size exceeds the context window of
+// model by more than the configured safety margin. It is a no-op unless the check is enabled
+// and the registry knows the model's limit.
+func (h *BaseAPIHandler) checkContextWindow(model string, rawJSON []byte) *interfaces.ErrorMessage {
+	if h.Cfg == nil || !h.Cfg.ContextWindowCheck.Enabled {
+		return nil
+	}
+	limit := contextWindowLimit(model)
+	if limit <= 0 {
+		return nil
+	}
+	margin := h.Cfg.ContextWindowCheck.SafetyMargin
+	if margin <= 0 {
+		margin = defaultContextWindowSafetyMargin
+	}
+	estimated := estimateRequestTokens(rawJSON)
+	if float64(estimated) <= float64(limit)*(1+margin) {
+		return nil
+	}
+	return &interfaces.ErrorMessage{
+		StatusCode: http.StatusBadRequest,
+		Error:      fmt.Errorf("request exceeds the context window of model %s: estimated %d tokens, allowed %d", model, estimated, limit),
+	}
+}
+
+// contextWindowLimit returns the input limit advertised for model in the registry, falling
+// back to its context length, or zero when neither is known.
+func contextWindowLimit(model string) int {
+	normalized, _ := normalizeModelMetadata(model)
+	info := registry.GetGlobalRegistry().GetModelInfo(normalized)
+	if info == nil {
+		return 0
+	}
+	if info.InputTokenLimit > 0 {
+		return info.InputTokenLimit
+	}
+	return info.ContextLength
+}
+
+// exceedsContextWindow reports whether the request is estimated to be larger than the input
+// limit advertised for model in the registry. Models without a known limit never exceed it.
+func exceedsContextWindow(model string, rawJSON []byte) bool {
+	limit := contextWindowLimit(model)
+	return limit > 0 && estimateRequestTokens(rawJSON) > limit
+}
+
+// estimateRequestTokens approximates the prompt size at four characters per token over all
+// string values of the payload. Inline binary data such as images is ignored.
+func estimateRequestTokens(rawJSON []byte) int {
+	var chars int
+	var walk func(value gjson.Result)
+	walk = func(value gjson.Result) {
+		switch {
+		case value.IsObject():
+			value.ForEach(func(key, child gjson.Result) bool {
+				switch key.String() {
+				case "image_url", "inlineData", "inline_data", "source", "model":
+					return true
+				}
+				walk(child)
+				return true
+			})
+		case value.IsArray():
+			value.ForEach(func(_, child gjson.Result) bool {
+				walk(child)
+				return true
+			})
+		case value.Type == gjson.String:
+			if text := value.String(); !strings.HasPrefix(text, "data:") {
+				chars += len(text)
+			}
+		}
+	}
+	walk(gjson.ParseBytes(rawJSON))
+	return chars / 4
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func runContextWindowRequest(t *testing.T, model, prompt string) (*fallbackTestExecutor, *interfaces.ErrorMessage) {
+	t.Helper()
+	exec := &fallbackTestExecutor{}
+	manager := newHandlerTestManager(t, "fallback-test", []*registry.ModelInfo{
+		{ID: "window-limited", ContextLength: 100},
+		{ID: "window-unknown"},
+	}, exec)
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{ContextWindowCheck: config.ContextWindowCheckConfig{Enabled: true, SafetyMargin: 0.2}}, AuthManager: manager}
+
+	ctx, _, _ := newHandlerTestContext(t, h, "/v1/chat/completions", nil)
+	body := `{"model":"` + model + `","messages":[{"role":"user","content":"` + prompt + `"}]}`
+	_, errMsg := h.ExecuteWithAuthManager(ctx, "openai", model, []byte(body), "")
+	return exec, errMsg
+}
+
+func TestContextWindowCheck_RejectsOversizedRequest(t *testing.T) {
+	// 600 characters is ~150 tokens, over the 100-token window plus the 20% margin.
+	exec, errMsg := runContextWindowRequest(t, "window-limited", strings.Repeat("x", 600))
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %+v", errMsg)
+	}
+	if msg := errMsg.Error.Error(); !strings.Contains(msg, "estimated 15") || !strings.Contains(msg, "allowed 100") {
+		t.Errorf("Expected estimated and allowed token counts in %q", msg)
+	}
+	if len(exec.models) != 0 {
+		t.Fatalf("Expected no upstream call, got %v", exec.models)
+	}
+}
+
+func TestContextWindowCheck_AllowsRequestWithinMargin(t *testing.T) {
+	// ~110 tokens is over the window but within the safety margin.
+	exec, errMsg := runContextWindowRequest(t, "window-limited", strings.Repeat("x", 440))
+	if errMsg != nil {
+		t.Fatalf("Expected request to be forwarded, got %v", errMsg.Error)
+	}
+	if len(exec.models) != 1 {
+		t.Fatalf("Expected one upstream call, got %v", exec.models)
+	}
+}
+
+func TestContextWindowCheck_SkipsUnknownLimit(t *testing.T) {
+	exec, errMsg := runContextWindowRequest(t, "window-unknown", strings.Repeat("x", 100000))
+	if errMsg != nil {
+		t.Fatalf("Expected models without a known limit to be forwarded, got %v", errMsg.Error)
+	}
+	if len(exec.models) != 1 {
+		t.Fatalf("Expected one upstream call, got %v", exec.models)
+	}
+}
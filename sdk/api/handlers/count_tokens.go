@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// tokenCountProviders lists the providers whose upstream offers a token-count API.
+var tokenCountProviders = []string{"claude", "gemini", "gemini-cli", "vertex", "aistudio"}
+
+// tokenCountFields names the token count field of a count response per handler type whose
+// counts can be estimated locally.
+var tokenCountFields = map[string]string{
+	"claude": "input_tokens",
+	"gemini": "totalTokens",
+}
+
+// providersWithTokenCount returns the providers of providers that can count tokens upstream.
+func providersWithTokenCount(providers []string) []string {
+	var counting []string
+	for _, provider := range providers {
+		if slices.Contains(tokenCountProviders, strings.ToLower(provider)) {
+			counting = append(counting, provider)
+		}
+	}
+	return counting
+}
+
+// estimateTokenCount counts the prompt of a count request locally, in the count response
+// shape of handlerType flagged with "estimated": true. The estimator configured for model is
+// used when token estimation is enabled, otherwise the model's tiktoken encoding, which falls
+// back to o200k_base for models tiktoken does not know. It reports false for handler types
+// without a count response shape.
+func (h *BaseAPIHandler) estimateTokenCount(handlerType, model string, rawJSON []byte) ([]byte, bool) {
+	field, ok := tokenCountFields[handlerType]
+	if !ok {
+		return nil, false
+	}
+	estimator := h.tokenEstimator(model)
+	if estimator == nil {
+		estimator, _ = coreusage.LookupTokenEstimator(coreusage.EstimatorTiktoken)
+	}
+	if estimator == nil {
+		estimator = coreusage.HeuristicEstimator{}
+	}
+	var prompt strings.Builder
+	collectUsageText(gjson.ParseBytes(rawJSON), &prompt, "\n")
+	out, _ := sjson.SetBytes([]byte(`{}`), field, estimator.EstimateTokens(model, prompt.String()))
+	out, _ = sjson.SetBytes(out, "estimated", true)
+	return out, true
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// countTestExecutor answers count requests with a fixed upstream count.
+type countTestExecutor struct {
+	provider string
+	counted  *int
+}
+
+func (e countTestExecutor) Identifier() string { return e.provider }
+
+func (e countTestExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e countTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e countTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e countTestExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	*e.counted++
+	return coreexecutor.Response{Payload: []byte(`{"input_tokens":42}`)}, nil
+}
+
+const countTokensRequest = `{"model":"count-model","system":"You are terse.","messages":[{"role":"user","content":"How many tokens is this?"}]}`
+
+func runCountTokens(t *testing.T, provider string) ([]byte, int) {
+	t.Helper()
+	counted := 0
+	manager := newHandlerTestManager(t, provider, []*registry.ModelInfo{{ID: "count-model"}}, countTestExecutor{provider: provider, counted: &counted})
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{}, AuthManager: manager}
+
+	ctx, _, _ := newHandlerTestContext(t, h, "/v1/messages/count_tokens", nil)
+	resp, errMsg := h.ExecuteCountWithAuthManager(ctx, "claude", "count-model", []byte(countTokensRequest), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	return resp, counted
+}
+
+func TestExecuteCountWithAuthManager_ClaudeCountsUpstream(t *testing.T) {
+	resp, counted := runCountTokens(t, "claude")
+
+	if counted != 1 || string(resp) != `{"input_tokens":42}` {
+		t.Fatalf("Expected the upstream count to be returned as is, got %s after %d upstream calls", resp, counted)
+	}
+}
+
+func TestExecuteCountWithAuthManager_EstimatesWithoutUpstreamCount(t *testing.T) {
+	resp, counted := runCountTokens(t, "count-test")
+
+	if counted != 0 {
+		t.Fatalf("Expected no upstream call for a provider without a count API, got %d", counted)
+	}
+	estimator, _ := coreusage.LookupTokenEstimator(coreusage.EstimatorTiktoken)
+	want := estimator.EstimateTokens("count-model", "You are terse.\nHow many tokens is this?")
+	if got := gjson.GetBytes(resp, "input_tokens").Int(); got != want || want == 0 {
+		t.Fatalf("Expected the tiktoken estimate %d, got %s", want, resp)
+	}
+	if !gjson.GetBytes(resp, "estimated").Bool() {
+		t.Fatalf("Expected the count to be flagged as estimated, got %s", resp)
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// DryRunHeader requests that the proxy translate and route the request, then return the
+// upstream request it would have sent instead of calling the provider.
+const DryRunHeader = "X-Proxy-Dry-Run"
+
+// withDryRun marks ctx for a dry run when the dry-run config flag is set or the client
+// sends DryRunHeader with a true value.
+func (h *BaseAPIHandler) withDryRun(ctx context.Context, c *gin.Context) context.Context {
+	enabled := h.Cfg != nil && h.Cfg.DryRun
+	if !enabled && c != nil && c.Request != nil {
+		enabled, _ = strconv.ParseBool(strings.TrimSpace(c.GetHeader(DryRunHeader)))
+	}
+	if !enabled {
+		return ctx
+	}
+	return coreexecutor.WithDryRun(ctx)
+}
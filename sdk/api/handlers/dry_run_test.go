@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestWithDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		name   string
+		cfg    config.SDKConfig
+		header string
+		want   bool
+	}{
+		{name: "disabled", want: false},
+		{name: "header", header: "true", want: true},
+		{name: "header false", header: "false", want: false},
+		{name: "config flag", cfg: config.SDKConfig{DryRun: true}, want: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tc.header != "" {
+				c.Request.Header.Set(DryRunHeader, tc.header)
+			}
+			h := &BaseAPIHandler{Cfg: &tc.cfg}
+			if got := coreexecutor.IsDryRun(h.withDryRun(context.Background(), c)); got != tc.want {
+				t.Fatalf("Expected dry run %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
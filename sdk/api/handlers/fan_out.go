@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+// FanOutResult is the outcome of one sub-call issued by ExecuteFanOutWithAuthManager.
+type FanOutResult struct {
+	Payload []byte
+	Err     *interfaces.ErrorMessage
+}
+
+// ExecuteFanOutWithAuthManager executes n copies of a non-streaming request concurrently, at
+// most concurrency at a time, and returns their results in order. The request is prepared
+// once up front exactly as a single request would be; an error there is returned instead of
+// the results. Sub-calls are neither coalesced nor subject to model fallback, and each sees
+// its own copy of the gin context so request logging and usage attribution stay race-free.
+func (h *BaseAPIHandler) ExecuteFanOutWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string, n, concurrency int) ([]FanOutResult, *interfaces.ErrorMessage) {
+	logging.RequestRecordFromContext(ctx).SetModel(modelName, false)
+	prepared, errMsg := h.prepareRequest(ctx, handlerType, modelName, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	if concurrency <= 0 || concurrency > n {
+		concurrency = n
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+
+	results := make([]FanOutResult, n)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].Err = &interfaces.ErrorMessage{StatusCode: 499, Error: ctx.Err()}
+				return
+			}
+			defer func() { <-sem }()
+
+			subCtx := ctx
+			if ginCtx != nil {
+				subCtx = context.WithValue(ctx, "gin", ginCtx.Copy())
+			}
+			subCtx, estimate := h.beginUsageEstimation(subCtx, handlerType, prepared.model, prepared.payload)
+			req := coreexecutor.Request{
+				Model:   prepared.model,
+				Payload: cloneBytes(prepared.payload),
+			}
+			if cloned := cloneMetadata(prepared.metadata); cloned != nil {
+				req.Metadata = cloned
+			}
+			opts := coreexecutor.Options{
+				Stream:          false,
+				Alt:             alt,
+				OriginalRequest: cloneBytes(prepared.payload),
+				SourceFormat:    sdktranslator.FromString(handlerType),
+			}
+			if cloned := cloneMetadata(prepared.metadata); cloned != nil {
+				opts.Metadata = cloned
+			}
+			resp, err := h.AuthManager.Execute(subCtx, prepared.providers, req, opts)
+			if err != nil {
+				status := http.StatusInternalServerError
+				if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
+					if code := se.StatusCode(); code > 0 {
+						status = code
+					}
+				}
+				var addon http.Header
+				if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
+					if hdr := he.Headers(); hdr != nil {
+						addon = hdr.Clone()
+					}
+				}
+				results[i].Err = &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+				return
+			}
+			results[i].Payload = echoServiceTier(prepared.metadata, estimate.finishResponse(cloneBytes(resp.Payload)))
+		}(i)
+	}
+	wg.Wait()
+	return results, nil
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// fanOutTestExecutor records the payload of every call it serves.
+type fanOutTestExecutor struct {
+	mu       sync.Mutex
+	payloads [][]byte
+}
+
+func (e *fanOutTestExecutor) Identifier() string { return "fan-out-test" }
+
+func (e *fanOutTestExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	e.mu.Lock()
+	e.payloads = append(e.payloads, req.Payload)
+	e.mu.Unlock()
+	return coreexecutor.Response{Payload: []byte(`{"choices":[]}`)}, nil
+}
+
+func (e *fanOutTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *fanOutTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *fanOutTestExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func TestExecuteFanOutWithAuthManager_AppliesModelParamDefaults(t *testing.T) {
+	temperature := 0.3
+	util.SetModelParamDefaults(map[string]config.ModelParamDefaults{"fan-out-model": {Temperature: &temperature}})
+	t.Cleanup(func() { util.SetModelParamDefaults(nil) })
+	exec := &fanOutTestExecutor{}
+	manager := newHandlerTestManager(t, "fan-out-test", []*registry.ModelInfo{{ID: "fan-out-model"}}, exec)
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{}, AuthManager: manager}
+
+	ctx, _, _ := newHandlerTestContext(t, h, "/v1/chat/completions", nil)
+	results, errMsg := h.ExecuteFanOutWithAuthManager(ctx, "openai", "fan-out-model", []byte(`{"model":"fan-out-model","messages":[{"role":"user","content":"hi"}]}`), "", 2, 2)
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	if len(results) != 2 || len(exec.payloads) != 2 {
+		t.Fatalf("Expected 2 sub-calls, got %d results and %d calls", len(results), len(exec.payloads))
+	}
+	for i, payload := range exec.payloads {
+		if got := gjson.GetBytes(payload, "temperature").Float(); got != 0.3 {
+			t.Fatalf("Expected sub-call %d to carry the model's default temperature, got %s", i, payload)
+		}
+	}
+}
@@ -8,15 +8,20 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
+	"golang.org/x/sync/singleflight"
 )
 
 // ErrorResponse represents a standard error response format for the API.
@@ -51,6 +56,13 @@ type BaseAPIHandler struct {
 
 	// OpenAICompatProviders is a list of provider names for OpenAI compatibility.
 	OpenAICompatProviders []string
+
+	// inflight coalesces identical concurrent non-streaming requests.
+	inflight singleflight.Group
+
+	// responseCache holds non-streaming responses when the response cache is enabled.
+	responseCache     *responseCache
+	responseCacheOnce sync.Once
 }
 
 // NewBaseAPIHandlers creates a new API handlers instance.
@@ -115,7 +127,28 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 	newCtx, cancel := context.WithCancel(ctx)
 	newCtx = context.WithValue(newCtx, "gin", c)
 	newCtx = context.WithValue(newCtx, "handler", handler)
+	newCtx = h.withDryRun(newCtx, c)
+	newCtx = h.withCoalescing(newCtx, c)
+	newCtx = h.withResponseCache(newCtx, c)
+	var record *logging.RequestRecord
+	if c != nil && c.Request != nil {
+		record = logging.NewRequestRecord(c.Request.Method, c.Request.URL.Path, handler.HandlerType())
+		record.SetRequestID(logging.RequestIDFromContext(ctx))
+		newCtx = logging.WithRequestRecord(newCtx, record)
+		// Exposed to middleware, such as the audit log, that runs after the handler returns.
+		c.Set("REQUEST_RECORD", record)
+	}
 	return newCtx, func(params ...interface{}) {
+		if record != nil {
+			var errRecord error
+			if len(params) == 1 {
+				errRecord, _ = params[0].(error)
+			}
+			status := c.Writer.Status()
+			if record.Emit(status, errRecord) && metrics.Enabled() {
+				metrics.ObserveRequest(record.Snapshot(), status)
+			}
+		}
 		if h.Cfg.RequestLog {
 			if len(params) == 1 {
 				data := params[0]
@@ -159,29 +192,77 @@ func appendAPIResponse(c *gin.Context, data []byte) {
 }
 
 // ExecuteWithAuthManager executes a non-streaming request via the core auth manager.
-// This path is the only supported execution route.
+// This path is the only supported execution route. The rate-limit headers of the serving
+// account are set on the response, and once the request has passed validation and missed
+// the response cache a configured model mirror is started alongside.
 func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
-	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	logging.RequestRecordFromContext(ctx).SetModel(modelName, false)
+	requestedModel := modelName
+	execute := func(modelName string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+		// Cache and coalesce keys are built from the prepared request, so they reflect the
+		// sampling profile and defaults that were applied, not just the client body.
+		prepared, errMsg := h.prepareRequest(ctx, handlerType, modelName, rawJSON)
+		if errMsg != nil {
+			return nil, errMsg
+		}
+		run := func() ([]byte, *interfaces.ErrorMessage) {
+			if modelName == requestedModel {
+				h.startMirror(ctx, handlerType, modelName, rawJSON, alt)
+			}
+			if key, ok := coalesceKey(ctx, handlerType, prepared, alt); ok {
+				return h.executeCoalesced(ctx, key, func(ctx context.Context) ([]byte, *interfaces.ErrorMessage) {
+					return h.executePrepared(ctx, handlerType, prepared, alt)
+				})
+			}
+			return h.executePrepared(ctx, handlerType, prepared, alt)
+		}
+		if key, ok := h.responseCacheKey(ctx, handlerType, prepared, alt); ok {
+			return h.executeCached(ctx, key, run)
+		}
+		return run()
+	}
+	var (
+		resp   []byte
+		errMsg *interfaces.ErrorMessage
+	)
+	if chain := h.fallbackChain(modelName); chain != nil {
+		resp, errMsg = executeWithFallback(ctx, chain, rawJSON, execute)
+	} else {
+		resp, errMsg = execute(modelName, rawJSON)
+	}
+	setRateLimitHeaders(ctx)
+	return resp, errMsg
+}
+
+// executeWithAuthManager performs one non-streaming execution without coalescing.
+func (h *BaseAPIHandler) executeWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+	prepared, errMsg := h.prepareRequest(ctx, handlerType, modelName, rawJSON)
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	return h.executePrepared(ctx, handlerType, prepared, alt)
+}
+
+// executePrepared sends a prepared non-streaming request to its providers.
+func (h *BaseAPIHandler) executePrepared(ctx context.Context, handlerType string, prepared preparedRequest, alt string) ([]byte, *interfaces.ErrorMessage) {
+	ctx, estimate := h.beginUsageEstimation(ctx, handlerType, prepared.model, prepared.payload)
 	req := coreexecutor.Request{
-		Model:   normalizedModel,
-		Payload: cloneBytes(rawJSON),
+		Model:   prepared.model,
+		Payload: cloneBytes(prepared.payload),
 	}
-	if cloned := cloneMetadata(metadata); cloned != nil {
+	if cloned := cloneMetadata(prepared.metadata); cloned != nil {
 		req.Metadata = cloned
 	}
 	opts := coreexecutor.Options{
 		Stream:          false,
 		Alt:             alt,
-		OriginalRequest: cloneBytes(rawJSON),
+		OriginalRequest: cloneBytes(prepared.payload),
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
-	if cloned := cloneMetadata(metadata); cloned != nil {
+	if cloned := cloneMetadata(prepared.metadata); cloned != nil {
 		opts.Metadata = cloned
 	}
-	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
+	resp, err := h.AuthManager.Execute(ctx, prepared.providers, req, opts)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
@@ -197,16 +278,30 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 		}
 		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 	}
-	return cloneBytes(resp.Payload), nil
+	return echoServiceTier(prepared.metadata, estimate.finishResponse(cloneBytes(resp.Payload))), nil
 }
 
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
-// This path is the only supported execution route.
+// This path is the only supported execution route. Counts go to providers with an upstream
+// token-count API; when the model has none, or the upstream does not support counting, the
+// prompt is counted locally and the result is flagged as estimated.
 func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+	logging.RequestRecordFromContext(ctx).SetModel(modelName, false)
 	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	if errMsg == nil {
+		errMsg = h.checkModelAccess(ctx, normalizedModel)
+	}
+	if errMsg == nil {
+		providers, metadata, errMsg = h.applyRoutingOverride(ctx, normalizedModel, providers, metadata)
+	}
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	if counting := providersWithTokenCount(providers); len(counting) > 0 {
+		providers = counting
+	} else if estimated, ok := h.estimateTokenCount(handlerType, normalizedModel, rawJSON); ok {
+		return estimated, nil
+	}
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
 		Payload: cloneBytes(rawJSON),
@@ -231,6 +326,11 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 				status = code
 			}
 		}
+		if status == http.StatusNotImplemented {
+			if estimated, ok := h.estimateTokenCount(handlerType, normalizedModel, rawJSON); ok {
+				return estimated, nil
+			}
+		}
 		var addon http.Header
 		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
 			if hdr := he.Headers(); hdr != nil {
@@ -245,32 +345,61 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 // ExecuteStreamWithAuthManager executes a streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
-	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	logging.RequestRecordFromContext(ctx).SetModel(modelName, true)
+	type stream struct {
+		data <-chan []byte
+		errs <-chan *interfaces.ErrorMessage
+	}
+	execute := func(modelName string, rawJSON []byte) (stream, *interfaces.ErrorMessage) {
+		data, errs, errMsg := h.executeStreamWithAuthManager(ctx, handlerType, modelName, rawJSON, alt)
+		return stream{data: data, errs: errs}, errMsg
+	}
+	var (
+		result stream
+		errMsg *interfaces.ErrorMessage
+	)
+	// Fallback is only possible before the first chunk has been produced.
+	if chain := h.fallbackChain(modelName); chain != nil {
+		result, errMsg = executeWithFallback(ctx, chain, rawJSON, execute)
+	} else {
+		result, errMsg = execute(modelName, rawJSON)
+	}
+	setRateLimitHeaders(ctx)
 	if errMsg != nil {
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		errChan <- errMsg
 		close(errChan)
 		return nil, errChan
 	}
+	return result.data, result.errs
+}
+
+// executeStreamWithAuthManager starts one streaming execution. Errors raised before the
+// stream is established are returned directly so callers can still choose another model.
+func (h *BaseAPIHandler) executeStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage, *interfaces.ErrorMessage) {
+	prepared, errMsg := h.prepareRequest(ctx, handlerType, modelName, rawJSON)
+	if errMsg != nil {
+		return nil, nil, errMsg
+	}
+	ctx, estimate := h.beginUsageEstimation(ctx, handlerType, prepared.model, prepared.payload)
 	req := coreexecutor.Request{
-		Model:   normalizedModel,
-		Payload: cloneBytes(rawJSON),
+		Model:   prepared.model,
+		Payload: cloneBytes(prepared.payload),
 	}
-	if cloned := cloneMetadata(metadata); cloned != nil {
+	if cloned := cloneMetadata(prepared.metadata); cloned != nil {
 		req.Metadata = cloned
 	}
 	opts := coreexecutor.Options{
 		Stream:          true,
 		Alt:             alt,
-		OriginalRequest: cloneBytes(rawJSON),
+		OriginalRequest: cloneBytes(prepared.payload),
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
-	if cloned := cloneMetadata(metadata); cloned != nil {
+	if cloned := cloneMetadata(prepared.metadata); cloned != nil {
 		opts.Metadata = cloned
 	}
-	chunks, err := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
+	chunks, err := h.AuthManager.ExecuteStream(ctx, prepared.providers, req, opts)
 	if err != nil {
-		errChan := make(chan *interfaces.ErrorMessage, 1)
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
 			if code := se.StatusCode(); code > 0 {
@@ -283,16 +412,19 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 				addon = hdr.Clone()
 			}
 		}
-		errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
-		close(errChan)
-		return nil, errChan
+		return nil, nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 	}
 	dataChan := make(chan []byte)
 	errChan := make(chan *interfaces.ErrorMessage, 1)
 	go func() {
 		defer close(dataChan)
 		defer close(errChan)
+		defer estimate.finish()
 		for chunk := range chunks {
+			if ctx.Err() != nil {
+				// The client disconnected; drain the stream so the upstream call is torn down.
+				continue
+			}
 			if chunk.Err != nil {
 				status := http.StatusInternalServerError
 				if se, ok := chunk.Err.(interface{ StatusCode() int }); ok && se != nil {
@@ -310,14 +442,79 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 				return
 			}
 			if len(chunk.Payload) > 0 {
-				dataChan <- cloneBytes(chunk.Payload)
+				estimate.observe(chunk.Payload)
+				select {
+				case dataChan <- echoServiceTier(prepared.metadata, cloneBytes(chunk.Payload)):
+				case <-ctx.Done():
+				}
 			}
 		}
 	}()
-	return dataChan, errChan
+	return dataChan, errChan, nil
+}
+
+// preparedRequest is a request that passed validation, ready to be sent to providers.
+type preparedRequest struct {
+	providers []string
+	model     string
+	metadata  map[string]any
+	payload   []byte
+}
+
+// prepareRequest runs the checks and rewrites shared by every execution path: model
+// resolution, access control, routing overrides, sampling profiles, provider restrictions,
+// parameter filtering, the service tier, the context window and per-model parameter defaults.
+func (h *BaseAPIHandler) prepareRequest(ctx context.Context, handlerType, modelName string, rawJSON []byte) (preparedRequest, *interfaces.ErrorMessage) {
+	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	if errMsg == nil {
+		errMsg = h.checkModelAccess(ctx, normalizedModel)
+	}
+	if errMsg == nil {
+		providers, metadata, errMsg = h.applyRoutingOverride(ctx, normalizedModel, providers, metadata)
+	}
+	if errMsg == nil {
+		rawJSON, errMsg = applySamplingProfile(ctx, handlerType, normalizedModel, rawJSON)
+	}
+	if errMsg == nil {
+		providers, errMsg = restrictLogprobsProviders(handlerType, normalizedModel, providers, rawJSON)
+	}
+	if errMsg == nil {
+		providers, errMsg = restrictModalityProviders(handlerType, normalizedModel, providers, rawJSON)
+	}
+	if errMsg == nil {
+		providers, errMsg = h.restrictLogitBiasProviders(handlerType, normalizedModel, providers, rawJSON)
+	}
+	if errMsg == nil {
+		rawJSON, errMsg = h.filterRequestParameters(handlerType, normalizedModel, providers, rawJSON)
+	}
+	if errMsg == nil {
+		metadata, errMsg = applyServiceTier(handlerType, rawJSON, metadata)
+	}
+	if errMsg == nil {
+		errMsg = h.checkContextWindow(normalizedModel, rawJSON)
+	}
+	if errMsg != nil {
+		return preparedRequest{}, errMsg
+	}
+	return preparedRequest{
+		providers: providers,
+		model:     normalizedModel,
+		metadata:  metadata,
+		payload:   applyModelParamDefaults(handlerType, normalizedModel, rawJSON),
+	}, nil
 }
 
 func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string, normalizedModel string, metadata map[string]any, err *interfaces.ErrorMessage) {
+	// Rewrite configured aliases before anything else so the target drives provider selection.
+	requestedModel := modelName
+	aliased := false
+	if h.Cfg != nil {
+		modelName, aliased = resolveModelAlias(h.Cfg.ModelAliases, modelName)
+		if aliased {
+			log.Debugf("model alias resolved: %s -> %s", requestedModel, modelName)
+		}
+	}
+
 	// Resolve "auto" model to an actual available model first
 	resolvedModelName := util.ResolveAutoModel(modelName)
 
@@ -345,6 +542,13 @@ func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string
 	// If it's a non-dynamic model, normalizedModel was set by normalizeModelMetadata.
 	// So, normalizedModel is already correctly set at this point.
 
+	if aliased {
+		if metadata == nil {
+			metadata = make(map[string]any, 1)
+		}
+		metadata[ModelAliasMetadataKey] = requestedModel
+	}
+
 	return providers, normalizedModel, metadata, nil
 }
 
@@ -397,6 +601,7 @@ func cloneMetadata(src map[string]any) map[string]any {
 }
 
 // WriteErrorResponse writes an error message to the response writer using the HTTP status embedded in the message.
+// The body is the ProxyError rendering of msg, identical for every endpoint format.
 func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.ErrorMessage) {
 	status := http.StatusInternalServerError
 	if msg != nil && msg.StatusCode > 0 {
@@ -414,11 +619,10 @@ func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.Erro
 		}
 	}
 	c.Status(status)
-	if msg != nil && msg.Error != nil {
-		_, _ = c.Writer.Write([]byte(msg.Error.Error()))
-	} else {
-		_, _ = c.Writer.Write([]byte(http.StatusText(status)))
+	if !c.Writer.Written() {
+		c.Writer.Header().Set("Content-Type", "application/json")
 	}
+	_, _ = c.Writer.Write(NewProxyError(msg).Body())
 }
 
 func (h *BaseAPIHandler) LoggingAPIResponseError(ctx context.Context, err *interfaces.ErrorMessage) {
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// newHandlerTestManager returns an auth manager whose single credential, provider + "-auth",
+// serves models through exec.
+func newHandlerTestManager(t *testing.T, provider string, models []*registry.ModelInfo, exec coreauth.ProviderExecutor) *coreauth.Manager {
+	t.Helper()
+	return newHandlerTestManagerWithAuth(t, &coreauth.Auth{ID: provider + "-auth", Provider: provider}, models, exec)
+}
+
+// newHandlerTestManagerWithAuth is newHandlerTestManager for a caller-built credential. The
+// models are registered for the credential and unregistered when the test ends.
+func newHandlerTestManagerWithAuth(t *testing.T, auth *coreauth.Auth, models []*registry.ModelInfo, exec coreauth.ProviderExecutor) *coreauth.Manager {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, models)
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(exec)
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	return manager
+}
+
+// newHandlerTestContext returns the request context of a POST to path handled by h, with the
+// gin context and the recorder behind it. The context is cancelled when the test ends.
+func newHandlerTestContext(t *testing.T, h *BaseAPIHandler, path string, headers map[string]string) (context.Context, *gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, path, nil)
+	for key, value := range headers {
+		c.Request.Header.Set(key, value)
+	}
+	ctx, cancel := h.GetContextWithCancel(recordTestHandler{}, c, context.Background())
+	t.Cleanup(func() { cancel() })
+	return ctx, c, recorder
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import "time"
+
+// KeepAliveComment is the SSE comment line sent to keep an idle stream open. Compliant SSE
+// clients ignore comment lines.
+const KeepAliveComment = ": keepalive\n\n"
+
+// StreamKeepAlive ticks while a streaming response waits for its first upstream chunk.
+// A nil StreamKeepAlive never ticks.
+type StreamKeepAlive struct {
+	ticker *time.Ticker
+}
+
+// NewStreamKeepAlive returns a keepalive ticking at the configured interval, or nil when
+// keepalives are disabled.
+func (h *BaseAPIHandler) NewStreamKeepAlive() *StreamKeepAlive {
+	if h == nil || h.Cfg == nil || h.Cfg.StreamKeepAliveSeconds <= 0 {
+		return nil
+	}
+	return &StreamKeepAlive{ticker: time.NewTicker(time.Duration(h.Cfg.StreamKeepAliveSeconds) * time.Second)}
+}
+
+// C returns the tick channel; it is nil, and so blocks forever in a select, once the
+// keepalive is stopped or when it is disabled.
+func (k *StreamKeepAlive) C() <-chan time.Time {
+	if k == nil || k.ticker == nil {
+		return nil
+	}
+	return k.ticker.C
+}
+
+// Stop ends the keepalive; it is called when real chunks start flowing and when the stream ends.
+func (k *StreamKeepAlive) Stop() {
+	if k == nil || k.ticker == nil {
+		return
+	}
+	k.ticker.Stop()
+	k.ticker = nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/tidwall/gjson"
+)
+
+// maxLogitBias bounds the absolute value of a logit_bias entry, as in the OpenAI API.
+const maxLogitBias = 100
+
+// logitBiasUnsupportedProviders lists the providers whose upstream has no logit bias mechanism.
+// OpenAI compatible upstreams receive logit_bias unchanged.
+var logitBiasUnsupportedProviders = map[string]struct{}{
+	"claude":      {},
+	"codex":       {},
+	"gemini":      {},
+	"vertex":      {},
+	"aistudio":    {},
+	"gemini-cli":  {},
+	"antigravity": {},
+}
+
+// SupportsLogitBias reports whether any of the providers honours logit_bias.
+func SupportsLogitBias(providers []string) bool {
+	for _, provider := range providers {
+		if _, unsupported := logitBiasUnsupportedProviders[provider]; !unsupported {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictLogitBiasProviders validates the logit_bias of an OpenAI chat request. Keys must be
+// token IDs and values lie within [-100, 100]. When the unsupported-logit-bias policy is
+// "reject", providers that cannot apply the bias are dropped and a request that only they can
+// serve is rejected with 400; otherwise those providers ignore it.
+func (h *BaseAPIHandler) restrictLogitBiasProviders(handlerType, modelName string, providers []string, rawJSON []byte) ([]string, *interfaces.ErrorMessage) {
+	if handlerType != constant.OpenAI {
+		return providers, nil
+	}
+	bias := gjson.GetBytes(rawJSON, "logit_bias")
+	if !bias.Exists() || bias.Type == gjson.Null {
+		return providers, nil
+	}
+	if errMsg := validateLogitBias(bias); errMsg != nil {
+		return nil, errMsg
+	}
+	if len(bias.Map()) == 0 || h.Cfg == nil || h.Cfg.UnsupportedLogitBias != "reject" {
+		return providers, nil
+	}
+	supported := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if _, unsupported := logitBiasUnsupportedProviders[provider]; !unsupported {
+			supported = append(supported, provider)
+		}
+	}
+	if len(supported) == 0 {
+		return nil, &interfaces.ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Errorf("logit_bias is not supported for model %s (providers: %s)", modelName, strings.Join(providers, ", ")),
+		}
+	}
+	return supported, nil
+}
+
+// validateLogitBias checks that logit_bias maps token IDs to biases within range.
+func validateLogitBias(bias gjson.Result) *interfaces.ErrorMessage {
+	if !bias.IsObject() {
+		return &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("logit_bias must be an object mapping token IDs to biases")}
+	}
+	var errMsg *interfaces.ErrorMessage
+	bias.ForEach(func(key, value gjson.Result) bool {
+		if id, err := strconv.ParseInt(key.String(), 10, 64); err != nil || id < 0 {
+			errMsg = &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("logit_bias key %q is not a token ID", key.String())}
+			return false
+		}
+		if value.Type != gjson.Number || value.Float() < -maxLogitBias || value.Float() > maxLogitBias {
+			errMsg = &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("logit_bias value for token %s must be a number between -%d and %d", key.String(), maxLogitBias, maxLogitBias)}
+			return false
+		}
+		return true
+	})
+	return errMsg
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestRestrictLogitBiasProviders_Validates(t *testing.T) {
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{}}
+	for _, tc := range []struct {
+		bias    string
+		wantErr bool
+	}{
+		{`{"50256":-100,"1234":5.5}`, false},
+		{`{}`, false},
+		{`null`, false},
+		{`{"abc":1}`, true},
+		{`{"-1":1}`, true},
+		{`{"42":101}`, true},
+		{`{"42":"high"}`, true},
+		{`[1,2]`, true},
+	} {
+		body := []byte(`{"model":"m","messages":[],"logit_bias":` + tc.bias + `}`)
+		_, errMsg := h.restrictLogitBiasProviders("openai", "m", []string{"openrouter"}, body)
+		if (errMsg != nil) != tc.wantErr {
+			t.Errorf("logit_bias %s: error = %v, wantErr %v", tc.bias, errMsg, tc.wantErr)
+		}
+		if errMsg != nil && errMsg.StatusCode != http.StatusBadRequest {
+			t.Errorf("logit_bias %s: expected 400, got %d", tc.bias, errMsg.StatusCode)
+		}
+	}
+}
+
+func TestRestrictLogitBiasProviders_RejectsUnsupported(t *testing.T) {
+	body := []byte(`{"model":"m","messages":[],"logit_bias":{"50256":-100}}`)
+	reject := &BaseAPIHandler{Cfg: &config.SDKConfig{UnsupportedLogitBias: "reject"}}
+
+	providers, errMsg := reject.restrictLogitBiasProviders("openai", "m", []string{"claude", "openrouter"}, body)
+	if errMsg != nil || !reflect.DeepEqual(providers, []string{"openrouter"}) {
+		t.Fatalf("Expected only providers with logit_bias support, got %v (%v)", providers, errMsg)
+	}
+	_, errMsg = reject.restrictLogitBiasProviders("openai", "m", []string{"claude", "gemini"}, body)
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 when no provider supports logit_bias, got %v", errMsg)
+	}
+
+	ignore := &BaseAPIHandler{Cfg: &config.SDKConfig{}}
+	providers, errMsg = ignore.restrictLogitBiasProviders("openai", "m", []string{"claude"}, body)
+	if errMsg != nil || !reflect.DeepEqual(providers, []string{"claude"}) {
+		t.Fatalf("Expected the default policy to leave providers untouched, got %v (%v)", providers, errMsg)
+	}
+}
+
+func TestLogitBias_PassesThroughToUpstream(t *testing.T) {
+	exec := &fallbackTestExecutor{}
+	manager := newHandlerTestManager(t, "fallback-test", []*registry.ModelInfo{{ID: "logit-bias-model"}}, exec)
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{UnsupportedLogitBias: "reject"}, AuthManager: manager}
+
+	ctx, _, _ := newHandlerTestContext(t, h, "/v1/chat/completions", nil)
+	body := `{"model":"logit-bias-model","messages":[{"role":"user","content":"hi"}],"logit_bias":{"50256":-100,"1234":7}}`
+	if _, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "logit-bias-model", []byte(body), ""); errMsg != nil {
+		t.Fatalf("Expected the request to be forwarded, got %v", errMsg.Error)
+	}
+	if len(exec.payloads) != 1 {
+		t.Fatalf("Expected one upstream call, got %v", exec.models)
+	}
+	bias := gjson.Get(exec.payloads[0], "logit_bias")
+	if bias.Get("50256").Int() != -100 || bias.Get("1234").Int() != 7 {
+		t.Fatalf("Expected logit_bias to reach the upstream unchanged, got %s", exec.payloads[0])
+	}
+}
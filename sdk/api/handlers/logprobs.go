@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/tidwall/gjson"
+)
+
+// logprobsUnsupportedProviders lists the providers whose upstream cannot return token log
+// probabilities. Gemini, Vertex and AI Studio map them natively; OpenAI compatible upstreams
+// receive the flags unchanged.
+var logprobsUnsupportedProviders = map[string]struct{}{
+	"claude":      {},
+	"codex":       {},
+	"gemini-cli":  {},
+	"antigravity": {},
+}
+
+// restrictLogprobsProviders drops providers without log probability support from an OpenAI
+// chat request that explicitly asks for logprobs, so the flag is never silently ignored. A
+// request that only such providers can serve is rejected with 400.
+func restrictLogprobsProviders(handlerType, modelName string, providers []string, rawJSON []byte) ([]string, *interfaces.ErrorMessage) {
+	if handlerType != constant.OpenAI || !gjson.GetBytes(rawJSON, "logprobs").Bool() {
+		return providers, nil
+	}
+	supported := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if _, unsupported := logprobsUnsupportedProviders[provider]; !unsupported {
+			supported = append(supported, provider)
+		}
+	}
+	if len(supported) == 0 {
+		return nil, &interfaces.ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Errorf("logprobs is not supported for model %s (providers: %s)", modelName, strings.Join(providers, ", ")),
+		}
+	}
+	return supported, nil
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRestrictLogprobsProviders(t *testing.T) {
+	body := []byte(`{"model":"m","logprobs":true,"messages":[]}`)
+
+	providers, errMsg := restrictLogprobsProviders("openai", "m", []string{"claude", "gemini", "vertex"}, body)
+	if errMsg != nil || !reflect.DeepEqual(providers, []string{"gemini", "vertex"}) {
+		t.Fatalf("Expected only providers with logprobs support, got %v (%v)", providers, errMsg)
+	}
+
+	_, errMsg = restrictLogprobsProviders("openai", "m", []string{"claude", "codex"}, body)
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 when no provider supports logprobs, got %v", errMsg)
+	}
+
+	for _, tc := range []struct {
+		handlerType string
+		body        string
+	}{
+		{"openai", `{"model":"m","logprobs":false}`},
+		{"openai", `{"model":"m"}`},
+		{"claude", `{"model":"m","logprobs":true}`},
+	} {
+		providers, errMsg = restrictLogprobsProviders(tc.handlerType, "m", []string{"claude"}, []byte(tc.body))
+		if errMsg != nil || !reflect.DeepEqual(providers, []string{"claude"}) {
+			t.Errorf("%s %s: expected providers untouched, got %v (%v)", tc.handlerType, tc.body, providers, errMsg)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/tidwall/gjson"
+)
+
+// modalityUnsupportedProviders lists, per output modality beyond text, the providers whose
+// translators cannot produce it. Gemini, Vertex and AI Studio return images and speech as
+// inline data; Gemini CLI and Antigravity only images. OpenAI compatible upstreams receive
+// modalities unchanged.
+var modalityUnsupportedProviders = map[string]map[string]struct{}{
+	"image": {
+		"claude": {},
+		"codex":  {},
+	},
+	"audio": {
+		"claude":      {},
+		"codex":       {},
+		"gemini-cli":  {},
+		"antigravity": {},
+	},
+}
+
+// restrictModalityProviders validates the modalities of an OpenAI chat request and drops
+// providers that cannot produce every requested output modality. Unknown modalities, and
+// requests that only such providers can serve, are rejected with 400 naming the modality.
+func restrictModalityProviders(handlerType, modelName string, providers []string, rawJSON []byte) ([]string, *interfaces.ErrorMessage) {
+	if handlerType != constant.OpenAI {
+		return providers, nil
+	}
+	modalities := gjson.GetBytes(rawJSON, "modalities")
+	if !modalities.Exists() || modalities.Type == gjson.Null {
+		return providers, nil
+	}
+	if !modalities.IsArray() {
+		return nil, &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("modalities must be an array of output modalities")}
+	}
+	supported := providers
+	for _, value := range modalities.Array() {
+		modality := strings.ToLower(strings.TrimSpace(value.String()))
+		if modality == "text" {
+			continue
+		}
+		unsupported, known := modalityUnsupportedProviders[modality]
+		if !known {
+			return nil, &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("unsupported output modality %q: must be text, image or audio", value.String())}
+		}
+		remaining := make([]string, 0, len(supported))
+		for _, provider := range supported {
+			if _, ok := unsupported[provider]; !ok {
+				remaining = append(remaining, provider)
+			}
+		}
+		if len(remaining) == 0 {
+			return nil, &interfaces.ErrorMessage{
+				StatusCode: http.StatusBadRequest,
+				Error:      fmt.Errorf("output modality %s is not supported for model %s (providers: %s)", modality, modelName, strings.Join(providers, ", ")),
+			}
+		}
+		supported = remaining
+	}
+	return supported, nil
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRestrictModalityProviders(t *testing.T) {
+	image := []byte(`{"model":"m","modalities":["text","image"],"messages":[]}`)
+	providers, errMsg := restrictModalityProviders("openai", "m", []string{"claude", "gemini", "antigravity"}, image)
+	if errMsg != nil || !reflect.DeepEqual(providers, []string{"gemini", "antigravity"}) {
+		t.Fatalf("Expected only providers with image output, got %v (%v)", providers, errMsg)
+	}
+
+	audio := []byte(`{"model":"m","modalities":["text","audio"],"messages":[]}`)
+	providers, errMsg = restrictModalityProviders("openai", "m", []string{"antigravity", "vertex"}, audio)
+	if errMsg != nil || !reflect.DeepEqual(providers, []string{"vertex"}) {
+		t.Fatalf("Expected only providers with audio output, got %v (%v)", providers, errMsg)
+	}
+
+	for _, body := range []string{
+		`{"model":"m","modalities":["text","image"]}`,
+		`{"model":"m","modalities":["video"]}`,
+		`{"model":"m","modalities":"image"}`,
+	} {
+		_, errMsg = restrictModalityProviders("openai", "m", []string{"claude", "codex"}, []byte(body))
+		if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %v", body, errMsg)
+		}
+	}
+	_, errMsg = restrictModalityProviders("openai", "m", []string{"claude"}, image)
+	if errMsg == nil || !strings.Contains(errMsg.Error.Error(), "output modality image") {
+		t.Fatalf("Expected the error to name the unsupported modality, got %v", errMsg)
+	}
+
+	for _, tc := range []struct {
+		handlerType string
+		body        string
+	}{
+		{"openai", `{"model":"m","modalities":["text"]}`},
+		{"openai", `{"model":"m"}`},
+		{"claude", `{"model":"m","modalities":["image"]}`},
+	} {
+		providers, errMsg = restrictModalityProviders(tc.handlerType, "m", []string{"claude"}, []byte(tc.body))
+		if errMsg != nil || !reflect.DeepEqual(providers, []string{"claude"}) {
+			t.Errorf("%s %s: expected providers untouched, got %v (%v)", tc.handlerType, tc.body, providers, errMsg)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// checkModelAccess enforces the model-access rule of the client API key, if any, against the
+// model resolved from the request. A model that is denied, or missing from a configured allow
+// list, is rejected with 403 listing the models the key may use.
+func (h *BaseAPIHandler) checkModelAccess(ctx context.Context, modelName string) *interfaces.ErrorMessage {
+	if h.Cfg == nil || len(h.Cfg.ModelAccess) == 0 {
+		return nil
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return nil
+	}
+	value, exists := ginCtx.Get("apiKey")
+	if !exists {
+		return nil
+	}
+	apiKey, _ := value.(string)
+	rule := modelAccessRuleFor(h.Cfg.ModelAccess, apiKey)
+	if rule == nil || modelPermitted(rule, modelName) {
+		return nil
+	}
+	return &interfaces.ErrorMessage{
+		StatusCode: http.StatusForbidden,
+		Error:      fmt.Errorf("model %s is not permitted for this API key (permitted: %s)", modelName, permittedModels(rule)),
+	}
+}
+
+// modelAccessRuleFor returns the first rule configured for apiKey, or nil when the key is
+// unrestricted.
+func modelAccessRuleFor(rules []config.ModelAccessRule, apiKey string) *config.ModelAccessRule {
+	if apiKey == "" {
+		return nil
+	}
+	for i := range rules {
+		if rules[i].Key == apiKey {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// modelPermitted reports whether rule lets its key request model; deny entries win.
+func modelPermitted(rule *config.ModelAccessRule, model string) bool {
+	for _, pattern := range rule.Deny {
+		if matchModelPattern(pattern, model) {
+			return false
+		}
+	}
+	if len(rule.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range rule.Allow {
+		if matchModelPattern(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// permittedModels lists the available models rule permits, or its allow patterns when none
+// of them are currently available.
+func permittedModels(rule *config.ModelAccessRule) string {
+	var permitted []string
+	for _, model := range registry.GetGlobalRegistry().GetAvailableModels("openai") {
+		if id, _ := model["id"].(string); id != "" && modelPermitted(rule, id) {
+			permitted = append(permitted, id)
+		}
+	}
+	if len(permitted) == 0 {
+		permitted = append(permitted, rule.Allow...)
+	}
+	if len(permitted) == 0 {
+		return "none"
+	}
+	sort.Strings(permitted)
+	return strings.Join(permitted, ", ")
+}
+
+// matchModelPattern matches model against pattern case-insensitively, where '*' matches any
+// run of characters.
+func matchModelPattern(pattern, model string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	model = strings.ToLower(strings.TrimSpace(model))
+	if pattern == "" {
+		return false
+	}
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == model
+	}
+	if !strings.HasPrefix(model, parts[0]) {
+		return false
+	}
+	model = model[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(model, part)
+		if index < 0 {
+			return false
+		}
+		model = model[index+len(part):]
+	}
+	return strings.HasSuffix(model, last)
+}
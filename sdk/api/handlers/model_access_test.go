@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func modelAccessContext(apiKey string) context.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	if apiKey != "" {
+		c.Set("apiKey", apiKey)
+	}
+	return context.WithValue(context.Background(), "gin", c)
+}
+
+func TestCheckModelAccess(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("model-access-test", "gemini", []*registry.ModelInfo{
+		{ID: "access-flash"},
+		{ID: "access-flash-lite"},
+		{ID: "access-pro"},
+	})
+	t.Cleanup(func() { reg.UnregisterClient("model-access-test") })
+
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{ModelAccess: []config.ModelAccessRule{
+		{Key: "intern", Allow: []string{"access-flash*"}, Deny: []string{"access-flash-lite"}},
+		{Key: "contractor", Deny: []string{"*-pro"}},
+	}}}
+
+	cases := []struct {
+		name      string
+		apiKey    string
+		model     string
+		forbidden bool
+	}{
+		{"allowed by pattern", "intern", "access-flash", false},
+		{"deny overrides allow", "intern", "access-flash-lite", true},
+		{"not in allow list", "intern", "access-pro", true},
+		{"deny only permits the rest", "contractor", "access-flash", false},
+		{"deny only", "contractor", "ACCESS-PRO", true},
+		{"unconfigured key", "admin", "access-pro", false},
+		{"unauthenticated request", "", "access-pro", false},
+	}
+	for _, tc := range cases {
+		errMsg := h.checkModelAccess(modelAccessContext(tc.apiKey), tc.model)
+		if !tc.forbidden {
+			if errMsg != nil {
+				t.Errorf("%s: expected %s to be permitted, got %v", tc.name, tc.model, errMsg.Error)
+			}
+			continue
+		}
+		if errMsg == nil || errMsg.StatusCode != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for %s, got %+v", tc.name, tc.model, errMsg)
+		}
+	}
+
+	errMsg := h.checkModelAccess(modelAccessContext("intern"), "access-pro")
+	if errMsg == nil || !strings.Contains(errMsg.Error.Error(), "(permitted: access-flash)") {
+		t.Fatalf("Expected the error to list the permitted models, got %v", errMsg)
+	}
+}
+
+func TestCheckModelAccess_NoRules(t *testing.T) {
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{}}
+	if errMsg := h.checkModelAccess(modelAccessContext("anyone"), "access-pro"); errMsg != nil {
+		t.Fatalf("Expected every model to be permitted without rules, got %v", errMsg.Error)
+	}
+}
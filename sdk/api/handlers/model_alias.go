@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// ModelAliasMetadataKey records the alias originally requested by the client when
+// the model name was rewritten through the configured alias table.
+const ModelAliasMetadataKey = "model_alias"
+
+// resolveModelAlias rewrites modelName using the alias table. Lookups are
+// case-insensitive and follow at most one additional hop, so "fast" -> "default" ->
+// "gemini-2.5-flash" resolves while longer chains stop after the second hop and
+// cycles stop before returning to the requested name. Unknown names pass through
+// untouched. The boolean reports whether a rewrite took place.
+func resolveModelAlias(aliases []config.ModelAlias, modelName string) (string, bool) {
+	if len(aliases) == 0 {
+		return modelName, false
+	}
+	requested := strings.TrimSpace(modelName)
+	if requested == "" {
+		return modelName, false
+	}
+	target, ok := lookupModelAlias(aliases, requested)
+	if !ok {
+		return modelName, false
+	}
+	if next, chained := lookupModelAlias(aliases, target); chained {
+		if strings.EqualFold(next, requested) {
+			log.Warnf("model alias loop detected: %s -> %s -> %s, using %s", requested, target, next, target)
+		} else {
+			target = next
+		}
+	}
+	return target, true
+}
+
+func lookupModelAlias(aliases []config.ModelAlias, name string) (string, bool) {
+	for i := range aliases {
+		from := strings.TrimSpace(aliases[i].From)
+		to := strings.TrimSpace(aliases[i].To)
+		if from == "" || to == "" {
+			continue
+		}
+		if strings.EqualFold(from, name) {
+			return to, true
+		}
+	}
+	return "", false
+}
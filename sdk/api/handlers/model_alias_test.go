@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestResolveModelAlias(t *testing.T) {
+	aliases := []config.ModelAlias{
+		{From: "fast", To: "gemini-2.5-flash"},
+		{From: "Smart", To: "best"},
+		{From: "best", To: "gemini-2.5-pro"},
+		{From: "a", To: "b"},
+		{From: "b", To: "c"},
+		{From: "c", To: "d"},
+		{From: "ping", To: "pong"},
+		{From: "pong", To: "ping"},
+		{From: "empty", To: ""},
+	}
+
+	cases := []struct {
+		name    string
+		model   string
+		want    string
+		aliased bool
+	}{
+		{"direct alias", "fast", "gemini-2.5-flash", true},
+		{"case insensitive with one chain hop", "smart", "gemini-2.5-pro", true},
+		{"chain stops after second hop", "a", "c", true},
+		{"loop stops before returning to request", "ping", "pong", true},
+		{"unknown passes through", "gemini-2.5-pro", "gemini-2.5-pro", false},
+		{"empty target ignored", "empty", "empty", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, aliased := resolveModelAlias(aliases, tc.model)
+			if got != tc.want || aliased != tc.aliased {
+				t.Fatalf("resolveModelAlias(%q) = (%q, %v), want (%q, %v)", tc.model, got, aliased, tc.want, tc.aliased)
+			}
+		})
+	}
+}
+
+func TestGetRequestDetails_RecordsAlias(t *testing.T) {
+	h := &BaseAPIHandler{
+		Cfg:                   &config.SDKConfig{ModelAliases: []config.ModelAlias{{From: "fast", To: "compat://upstream-model"}}},
+		OpenAICompatProviders: []string{"compat"},
+	}
+
+	providers, model, metadata, errMsg := h.getRequestDetails("fast")
+	if errMsg != nil {
+		t.Fatalf("getRequestDetails returned error: %v", errMsg.Error)
+	}
+	if len(providers) != 1 || providers[0] != "compat" || model != "upstream-model" {
+		t.Fatalf("Expected alias target routing, got providers=%v model=%s", providers, model)
+	}
+	if metadata[ModelAliasMetadataKey] != "fast" {
+		t.Fatalf("Expected original alias in metadata, got %v", metadata)
+	}
+}
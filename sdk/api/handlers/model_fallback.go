@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ModelServedHeader reports the model that actually served a request with a configured
+// fallback chain.
+const ModelServedHeader = "X-Proxy-Served-Model"
+
+// capabilityErrorMarkers are substrings of upstream error messages that indicate the model
+// cannot serve the request as sent, as opposed to a malformed request.
+var capabilityErrorMarkers = []string{
+	"context length",
+	"context_length",
+	"context window",
+	"maximum context",
+	"too many tokens",
+	"prompt is too long",
+	"token limit",
+	"does not support",
+	"not supported for this model",
+	"image input",
+	"vision",
+}
+
+// fallbackChain returns the requested model followed by its configured fallbacks, or nil when
+// no chain is configured for modelName. Lookups are case-insensitive.
+func (h *BaseAPIHandler) fallbackChain(modelName string) []string {
+	if h.Cfg == nil || len(h.Cfg.ModelFallbacks) == 0 {
+		return nil
+	}
+	requested := strings.TrimSpace(modelName)
+	for i := range h.Cfg.ModelFallbacks {
+		entry := h.Cfg.ModelFallbacks[i]
+		if !strings.EqualFold(strings.TrimSpace(entry.Model), requested) {
+			continue
+		}
+		chain := []string{modelName}
+		for _, fallback := range entry.Fallbacks {
+			if fallback = strings.TrimSpace(fallback); fallback != "" && !strings.EqualFold(fallback, requested) {
+				chain = append(chain, fallback)
+			}
+		}
+		if len(chain) == 1 {
+			return nil
+		}
+		return chain
+	}
+	return nil
+}
+
+// executeWithFallback runs execute against each model of chain in turn. A model is skipped
+// without an upstream call when the request is estimated to exceed its context window, and
+// abandoned for the next one when it fails with a capability error. The last model is always
+// attempted so the client sees its real error. The served model is reported via
+// ModelServedHeader.
+func executeWithFallback[T any](ctx context.Context, chain []string, rawJSON []byte, execute func(model string, payload []byte) (T, *interfaces.ErrorMessage)) (T, *interfaces.ErrorMessage) {
+	var (
+		result T
+		errMsg *interfaces.ErrorMessage
+	)
+	for i, model := range chain {
+		last := i == len(chain)-1
+		payload := rawJSON
+		if i > 0 {
+			payload = withModelField(rawJSON, model)
+		}
+		if !last && exceedsContextWindow(model, payload) {
+			log.Debugf("model fallback: request exceeds context window of %s, trying %s", model, chain[i+1])
+			continue
+		}
+		result, errMsg = execute(model, payload)
+		if errMsg == nil || last || !isCapabilityError(errMsg) {
+			if errMsg == nil {
+				setServedModelHeader(ctx, model)
+			}
+			return result, errMsg
+		}
+		log.Debugf("model fallback: %s cannot serve the request (status %d), trying %s", model, errMsg.StatusCode, chain[i+1])
+	}
+	return result, errMsg
+}
+
+// isCapabilityError reports whether errMsg is a non-retryable rejection caused by the model's
+// limits rather than by the request itself.
+func isCapabilityError(errMsg *interfaces.ErrorMessage) bool {
+	if errMsg == nil || errMsg.Error == nil {
+		return false
+	}
+	switch errMsg.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+	default:
+		return false
+	}
+	message := strings.ToLower(errMsg.Error.Error())
+	for _, marker := range capabilityErrorMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// withModelField rewrites the model field of the payload when it carries one.
+func withModelField(rawJSON []byte, model string) []byte {
+	if !gjson.GetBytes(rawJSON, "model").Exists() {
+		return rawJSON
+	}
+	updated, err := sjson.SetBytes(cloneBytes(rawJSON), "model", model)
+	if err != nil {
+		return rawJSON
+	}
+	return updated
+}
+
+func setServedModelHeader(ctx context.Context, model string) {
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		ginCtx.Header(ModelServedHeader, model)
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type fallbackTestExecutor struct {
+	failures map[string]error
+	models   []string
+	payloads []string
+}
+
+func (e *fallbackTestExecutor) Identifier() string { return "fallback-test" }
+
+func (e *fallbackTestExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	e.models = append(e.models, req.Model)
+	e.payloads = append(e.payloads, string(req.Payload))
+	if err := e.failures[req.Model]; err != nil {
+		return coreexecutor.Response{}, err
+	}
+	return coreexecutor.Response{Payload: []byte(`{"served":"` + req.Model + `"}`)}, nil
+}
+
+func (e *fallbackTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *fallbackTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *fallbackTestExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+type fallbackStatusError struct {
+	code int
+	msg  string
+}
+
+func (e *fallbackStatusError) Error() string   { return e.msg }
+func (e *fallbackStatusError) StatusCode() int { return e.code }
+
+func runFallbackRequest(t *testing.T, exec *fallbackTestExecutor, body string) ([]byte, *httptest.ResponseRecorder, error) {
+	t.Helper()
+	manager := newHandlerTestManager(t, "fallback-test", []*registry.ModelInfo{
+		{ID: "fallback-small", InputTokenLimit: 10},
+		{ID: "fallback-large", InputTokenLimit: 100000},
+	}, exec)
+	cfg := &config.SDKConfig{ModelFallbacks: []config.ModelFallback{
+		{Model: "fallback-small", Fallbacks: []string{"fallback-large"}},
+	}}
+	h := &BaseAPIHandler{Cfg: cfg, AuthManager: manager}
+
+	ctx, c, recorder := newHandlerTestContext(t, h, "/v1/chat/completions", nil)
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "fallback-small", []byte(body), "")
+	if errMsg != nil {
+		return nil, recorder, errMsg.Error
+	}
+	c.Writer.WriteHeaderNow()
+	return resp, recorder, nil
+}
+
+func TestExecuteWithAuthManager_FallsBackOnContextOverflow(t *testing.T) {
+	exec := &fallbackTestExecutor{}
+	body := `{"model":"fallback-small","messages":[{"role":"user","content":"` + strings.Repeat("long prompt ", 20) + `"}]}`
+	resp, recorder, err := runFallbackRequest(t, exec, body)
+	if err != nil {
+		t.Fatalf("Expected fallback to succeed, got %v", err)
+	}
+	if len(exec.models) != 1 || exec.models[0] != "fallback-large" {
+		t.Fatalf("Expected the oversized request to skip the small model, got %v", exec.models)
+	}
+	if got := gjson.Get(exec.payloads[0], "model").String(); got != "fallback-large" {
+		t.Errorf("Expected payload model to be rewritten, got %q", got)
+	}
+	if string(resp) != `{"served":"fallback-large"}` {
+		t.Errorf("Unexpected response %q", resp)
+	}
+	if got := recorder.Header().Get(ModelServedHeader); got != "fallback-large" {
+		t.Errorf("Expected %s to report the fallback model, got %q", ModelServedHeader, got)
+	}
+}
+
+func TestExecuteWithAuthManager_FallsBackOnCapabilityError(t *testing.T) {
+	exec := &fallbackTestExecutor{failures: map[string]error{
+		"fallback-small": &fallbackStatusError{code: http.StatusBadRequest, msg: "prompt is too long: 210000 tokens > 200000 maximum"},
+	}}
+	resp, recorder, err := runFallbackRequest(t, exec, `{"model":"fallback-small","messages":[{"role":"user","content":"hi"}]}`)
+	if err != nil {
+		t.Fatalf("Expected fallback to succeed, got %v", err)
+	}
+	if len(exec.models) != 2 || exec.models[0] != "fallback-small" || exec.models[1] != "fallback-large" {
+		t.Fatalf("Expected primary then fallback, got %v", exec.models)
+	}
+	if string(resp) != `{"served":"fallback-large"}` || recorder.Header().Get(ModelServedHeader) != "fallback-large" {
+		t.Errorf("Expected the fallback model to serve the request, got %q", resp)
+	}
+}
+
+func TestExecuteWithAuthManager_NoFallbackOnOtherErrors(t *testing.T) {
+	exec := &fallbackTestExecutor{failures: map[string]error{
+		"fallback-small": &fallbackStatusError{code: http.StatusBadRequest, msg: "invalid temperature"},
+	}}
+	if _, _, err := runFallbackRequest(t, exec, `{"model":"fallback-small","messages":[{"role":"user","content":"hi"}]}`); err == nil {
+		t.Fatal("Expected the primary error to be returned")
+	}
+	if len(exec.models) != 1 {
+		t.Fatalf("Expected no fallback for a request error, got %v", exec.models)
+	}
+}
+
+func TestEstimateRequestTokens_IgnoresInlineImages(t *testing.T) {
+	body := `{"messages":[{"role":"user","content":[{"type":"text","text":"abcdefgh"},{"type":"image_url","image_url":{"url":"data:image/png;base64,` + strings.Repeat("A", 4000) + `"}}]}]}`
+	if got := estimateRequestTokens([]byte(body)); got > 10 {
+		t.Fatalf("Expected image data to be ignored, estimated %d tokens", got)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// mirrorTimeout bounds a mirrored request, which outlives the client request it copies.
+const mirrorTimeout = 5 * time.Minute
+
+// mirrorRule returns the mirror configured for modelName, or nil. Lookups are
+// case-insensitive.
+func (h *BaseAPIHandler) mirrorRule(modelName string) *config.ModelMirror {
+	if h.Cfg == nil {
+		return nil
+	}
+	requested := strings.TrimSpace(modelName)
+	for i := range h.Cfg.ModelMirrors {
+		rule := &h.Cfg.ModelMirrors[i]
+		if strings.EqualFold(strings.TrimSpace(rule.Model), requested) && strings.TrimSpace(rule.Candidate) != "" {
+			return rule
+		}
+	}
+	return nil
+}
+
+// startMirror sends a sampled copy of a non-streaming request to the configured candidate
+// model in the background and records its response in the audit log next to the primary's.
+// Mirrors need the audit log, are skipped for dry runs and never affect the client: the
+// copy keeps the request-scoped values of ctx, such as the client API key, but not its
+// cancellation, and its failures are only recorded.
+func (h *BaseAPIHandler) startMirror(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) {
+	rule := h.mirrorRule(modelName)
+	if rule == nil || rule.Fraction <= 0 || coreexecutor.IsDryRun(ctx) {
+		return
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil || ginCtx.Request == nil {
+		return
+	}
+	value, _ := ginCtx.Get("AUDITOR")
+	auditor, _ := value.(*audit.Auditor)
+	if auditor == nil || rand.Float64() >= rule.Fraction {
+		return
+	}
+
+	candidate := strings.TrimSpace(rule.Candidate)
+	record := audit.AuditRecord{
+		Method:    ginCtx.Request.Method,
+		Path:      ginCtx.Request.URL.Path,
+		Model:     candidate,
+		Request:   string(rawJSON),
+		RequestID: logging.RequestRecordFromContext(ctx).Snapshot().RequestID,
+		MirrorOf:  modelName,
+	}
+	if apiKey, exists := ginCtx.Get("apiKey"); exists {
+		record.Client = util.HideAPIKey(fmt.Sprint(apiKey))
+	}
+	payload := withModelField(cloneBytes(rawJSON), candidate)
+	// The gin context is recycled once the request completes, and the request record
+	// belongs to the primary request, so the mirror gets a copy of the former and none of
+	// the latter.
+	detached := context.WithValue(context.WithoutCancel(ctx), "gin", ginCtx.Copy())
+	detached = logging.WithRequestRecord(detached, nil)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorf("model mirror %s -> %s panicked: %v", modelName, candidate, r)
+			}
+		}()
+		mirrorCtx, cancel := context.WithTimeout(detached, mirrorTimeout)
+		defer cancel()
+		started := time.Now()
+		resp, errMsg := h.executeWithAuthManager(mirrorCtx, handlerType, candidate, payload, alt)
+		record.Time = started.UTC()
+		record.DurationMs = time.Since(started).Milliseconds()
+		record.Status = http.StatusOK
+		record.Response = string(resp)
+		if errMsg != nil {
+			record.Status = http.StatusInternalServerError
+			if errMsg.StatusCode > 0 {
+				record.Status = errMsg.StatusCode
+			}
+			record.Response = string(NewProxyError(errMsg).Body())
+			log.Debugf("model mirror %s -> %s failed: %v", modelName, candidate, errMsg.Error)
+		}
+		auditor.Record(record)
+	}()
+}
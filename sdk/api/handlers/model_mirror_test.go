@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// mirrorTestExecutor answers with the model it served and fails for the failing model.
+type mirrorTestExecutor struct {
+	failing string
+}
+
+func (e mirrorTestExecutor) Identifier() string { return "mirror-test" }
+
+func (e mirrorTestExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	if req.Model == e.failing {
+		return coreexecutor.Response{}, &fallbackStatusError{code: http.StatusBadGateway, msg: "candidate unavailable"}
+	}
+	return coreexecutor.Response{Payload: []byte(`{"served":"` + req.Model + `"}`)}, nil
+}
+
+func (e mirrorTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e mirrorTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e mirrorTestExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+type channelAuditSink chan audit.AuditRecord
+
+func (s channelAuditSink) Write(record audit.AuditRecord) error {
+	s <- record
+	return nil
+}
+
+const mirrorTestBody = `{"model":"mirror-primary","messages":[{"role":"user","content":"hi"}]}`
+
+func runMirrorRequest(t *testing.T, exec mirrorTestExecutor, body string) ([]byte, *interfaces.ErrorMessage, channelAuditSink) {
+	t.Helper()
+	manager := newHandlerTestManager(t, "mirror-test", []*registry.ModelInfo{{ID: "mirror-primary"}, {ID: "mirror-candidate"}}, exec)
+	cfg := &config.SDKConfig{ModelMirrors: []config.ModelMirror{{Model: "mirror-primary", Candidate: "mirror-candidate", Fraction: 1}}}
+	h := &BaseAPIHandler{Cfg: cfg, AuthManager: manager}
+
+	sink := make(channelAuditSink, 1)
+	ctx, c, _ := newHandlerTestContext(t, h, "/v1/chat/completions", nil)
+	c.Set("AUDITOR", &audit.Auditor{Sink: sink})
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "mirror-primary", []byte(body), "")
+	return resp, errMsg, sink
+}
+
+func waitMirrorRecord(t *testing.T, sink channelAuditSink) audit.AuditRecord {
+	t.Helper()
+	select {
+	case record := <-sink:
+		return record
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the mirror to record the candidate response")
+		return audit.AuditRecord{}
+	}
+}
+
+func TestExecuteWithAuthManager_MirrorsToCandidate(t *testing.T) {
+	resp, errMsg, sink := runMirrorRequest(t, mirrorTestExecutor{}, mirrorTestBody)
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+
+	if string(resp) != `{"served":"mirror-primary"}` {
+		t.Fatalf("Expected the client to get only the primary response, got %s", resp)
+	}
+	record := waitMirrorRecord(t, sink)
+	if record.Response != `{"served":"mirror-candidate"}` || record.Status != http.StatusOK {
+		t.Fatalf("Expected the candidate response in the audit record, got %+v", record)
+	}
+	if record.MirrorOf != "mirror-primary" || record.Model != "mirror-candidate" {
+		t.Fatalf("Expected the record to name both models, got %+v", record)
+	}
+}
+
+func TestExecuteWithAuthManager_MirrorFailureDoesNotAffectClient(t *testing.T) {
+	resp, errMsg, sink := runMirrorRequest(t, mirrorTestExecutor{failing: "mirror-candidate"}, mirrorTestBody)
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+
+	if string(resp) != `{"served":"mirror-primary"}` {
+		t.Fatalf("Expected the primary response despite the failing mirror, got %s", resp)
+	}
+	if record := waitMirrorRecord(t, sink); record.Status != http.StatusBadGateway {
+		t.Fatalf("Expected the mirror failure to be recorded, got %+v", record)
+	}
+}
+
+func TestExecuteWithAuthManager_RejectedRequestIsNotMirrored(t *testing.T) {
+	body := `{"model":"mirror-primary","sampling_profile":"unknown","messages":[{"role":"user","content":"hi"}]}`
+	_, errMsg, sink := runMirrorRequest(t, mirrorTestExecutor{}, body)
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected the request to be rejected, got %+v", errMsg)
+	}
+	select {
+	case record := <-sink:
+		t.Fatalf("Expected no mirror for a rejected request, got %+v", record)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
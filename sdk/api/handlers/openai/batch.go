@@ -0,0 +1,222 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	defaultBatchMaxItems    = 100
+	defaultBatchConcurrency = 4
+)
+
+// ChatCompletionsBatch handles the /v1/chat/completions/batch endpoint. The body is a JSON
+// array of non-streaming chat completion requests, or an object holding one under "requests".
+// Items run concurrently up to the configured limit, and each one succeeds or fails on its
+// own: the response lists one result per item, in request order, with the item's status and
+// either its chat completion or its error. Every item after the first consumes a further
+// rate-limit token of the caller, and items go through the same model access checks and
+// per-model concurrency limits as individual requests.
+func (h *OpenAIAPIHandler) ChatCompletionsBatch(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", err),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	var cfg config.BatchConfig
+	if h.Cfg != nil {
+		cfg = h.Cfg.Batch
+	}
+	items, errBatch := parseBatchItems(rawJSON, cfg)
+	if errBatch != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: errBatch.Error(),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, c.Request.Context())
+	results := h.executeBatch(cliCtx, c, items, concurrency)
+
+	out := []byte(`{"object":"batch","data":[]}`)
+	for _, result := range results {
+		out, _ = sjson.SetRawBytes(out, "data.-1", result)
+	}
+	c.Header("Content-Type", "application/json")
+	_, _ = c.Writer.Write(out)
+	cliCancel()
+}
+
+// parseBatchItems returns the request bodies of a batch.
+func parseBatchItems(rawJSON []byte, cfg config.BatchConfig) ([]gjson.Result, error) {
+	if !gjson.ValidBytes(rawJSON) {
+		return nil, errors.New("Invalid request: body is not valid JSON")
+	}
+	root := gjson.ParseBytes(rawJSON)
+	if root.IsObject() {
+		root = root.Get("requests")
+	}
+	if !root.IsArray() {
+		return nil, errors.New("batch must be a JSON array of chat completion requests")
+	}
+	items := root.Array()
+	if len(items) == 0 {
+		return nil, errors.New("batch must contain at least one request")
+	}
+	maxItems := cfg.MaxItems
+	if maxItems <= 0 {
+		maxItems = defaultBatchMaxItems
+	}
+	if len(items) > maxItems {
+		return nil, fmt.Errorf("batch must contain at most %d requests, got %d", maxItems, len(items))
+	}
+	return items, nil
+}
+
+// executeBatch runs the items of a batch and returns one result object per item, in order.
+// Rate-limit tokens are taken in item order before anything is dispatched, so a batch that
+// exceeds the caller's budget fails its trailing items.
+func (h *OpenAIAPIHandler) executeBatch(ctx context.Context, c *gin.Context, items []gjson.Result, concurrency int) [][]byte {
+	results := make([][]byte, len(items))
+	var allow func(context.Context) (bool, time.Duration)
+	if value, exists := c.Get("rateLimitAllow"); exists {
+		allow, _ = value.(func(context.Context) (bool, time.Duration))
+	}
+	runnable := make([]int, 0, len(items))
+	for i, item := range items {
+		if errMsg := validateBatchItem(item); errMsg != nil {
+			results[i] = batchItemError(i, errMsg)
+			continue
+		}
+		// The first item was admitted by the rate-limit middleware with the batch itself.
+		if allow != nil && len(runnable) > 0 {
+			if allowed, retryAfter := allow(ctx); !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				results[i] = batchItemError(i, &interfaces.ErrorMessage{
+					StatusCode: http.StatusTooManyRequests,
+					Error:      fmt.Errorf("rate limit exceeded, retry after %ds", seconds),
+				})
+				continue
+			}
+		}
+		runnable = append(runnable, i)
+	}
+
+	if concurrency > len(runnable) {
+		concurrency = len(runnable)
+	}
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for _, i := range runnable {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i] = batchItemError(i, &interfaces.ErrorMessage{StatusCode: 499, Error: ctx.Err()})
+				return
+			}
+			defer func() { <-sem }()
+			results[i] = h.executeBatchItem(ctx, c, i, []byte(items[i].Raw))
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// executeBatchItem runs one batch item with its own copy of the gin context, converting a
+// panic into a 500 result so one item cannot take down the batch.
+func (h *OpenAIAPIHandler) executeBatchItem(ctx context.Context, c *gin.Context, index int, rawJSON []byte) (result []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("batch item %d panicked: %v", index, r)
+			result = batchItemError(index, &interfaces.ErrorMessage{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Errorf("internal error: %v", r),
+			})
+		}
+	}()
+	itemGin := c.Copy()
+	itemGin.Writer = &batchItemWriter{ResponseWriter: itemGin.Writer, header: make(http.Header)}
+	itemCtx := context.WithValue(ctx, "gin", itemGin)
+	rawJSON, err := prepareImageInputs(itemCtx, h.Cfg, rawJSON)
+	if err != nil {
+		return batchItemError(index, &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: err})
+	}
+	resp, errMsg := h.executeChatCompletion(itemCtx, itemGin, rawJSON)
+	if errMsg != nil {
+		return batchItemError(index, errMsg)
+	}
+	out := []byte(`{}`)
+	out, _ = sjson.SetBytes(out, "index", index)
+	out, _ = sjson.SetBytes(out, "status", http.StatusOK)
+	out, _ = sjson.SetRawBytes(out, "response", resp)
+	return out
+}
+
+// batchItemWriter gives a batch item the response headers that a detached gin context lacks,
+// so response headers set while serving it, such as the served model, are kept per item
+// instead of reaching the shared response.
+type batchItemWriter struct {
+	gin.ResponseWriter
+	header http.Header
+}
+
+func (w *batchItemWriter) Header() http.Header { return w.header }
+
+// validateBatchItem rejects items that cannot be served as a non-streaming chat completion.
+func validateBatchItem(item gjson.Result) *interfaces.ErrorMessage {
+	switch {
+	case !item.IsObject():
+		return &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: errors.New("batch item must be a chat completion request object")}
+	case item.Get("stream").Bool():
+		return &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: errors.New("stream is not supported in batch requests")}
+	case item.Get("model").String() == "":
+		return &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: errors.New("model is required")}
+	}
+	if _, err := util.NormalizeEndUser(item.Get("user")); err != nil {
+		return &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: err}
+	}
+	return nil
+}
+
+// batchItemError renders the result of a failed item with the error body used by every
+// endpoint.
+func batchItemError(index int, errMsg *interfaces.ErrorMessage) []byte {
+	proxyErr := handlers.NewProxyError(errMsg)
+	out := []byte(`{}`)
+	out, _ = sjson.SetBytes(out, "index", index)
+	out, _ = sjson.SetBytes(out, "status", proxyErr.StatusCode)
+	out, _ = sjson.SetRawBytes(out, "error", []byte(gjson.GetBytes(proxyErr.Body(), "error").Raw))
+	return out
+}
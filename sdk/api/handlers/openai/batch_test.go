@@ -0,0 +1,135 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// batchTestExecutor answers with the last user message; a prompt of "fail" is rejected with 400.
+type batchTestExecutor struct{}
+
+func (batchTestExecutor) Identifier() string { return "batch-test" }
+
+func (batchTestExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	messages := gjson.GetBytes(req.Payload, "messages").Array()
+	prompt := messages[len(messages)-1].Get("content").String()
+	if prompt == "fail" {
+		return coreexecutor.Response{}, &websocketStatusError{code: http.StatusBadRequest}
+	}
+	return coreexecutor.Response{Payload: []byte(`{"object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"` + prompt + `"}}]}`)}, nil
+}
+
+func (batchTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (batchTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e batchTestExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+func postBatch(t *testing.T, body string, middleware ...gin.HandlerFunc) gjson.Result {
+	t.Helper()
+	h := newOpenAITestHandler(t, &config.SDKConfig{Batch: config.BatchConfig{Concurrency: 2}}, "batch-test", []*registry.ModelInfo{{ID: "batch-model"}}, batchTestExecutor{})
+	router := gin.New()
+	router.POST("/v1/chat/completions/batch", append(middleware, h.ChatCompletionsBatch)...)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	return gjson.Parse(rec.Body.String())
+}
+
+func batchItem(prompt string) string {
+	return `{"model":"batch-model","messages":[{"role":"user","content":"` + prompt + `"}]}`
+}
+
+func TestChatCompletionsBatch_IsolatesFailedItems(t *testing.T) {
+	body := "[" + strings.Join([]string{
+		batchItem("one"),
+		batchItem("fail"),
+		`{"model":"batch-model","stream":true,"messages":[{"role":"user","content":"two"}]}`,
+		batchItem("three"),
+	}, ",") + "]"
+	data := postBatch(t, body).Get("data").Array()
+	if len(data) != 4 {
+		t.Fatalf("got %d results, want 4", len(data))
+	}
+	want := []struct {
+		status  int64
+		content string
+	}{{200, "one"}, {400, ""}, {400, ""}, {200, "three"}}
+	for i, w := range want {
+		item := data[i]
+		if item.Get("index").Int() != int64(i) || item.Get("status").Int() != w.status {
+			t.Fatalf("result %d = %s, want index %d status %d", i, item.Raw, i, w.status)
+		}
+		if w.content != "" {
+			if got := item.Get("response.choices.0.message.content").String(); got != w.content {
+				t.Fatalf("result %d content = %q, want %q", i, got, w.content)
+			}
+		} else if !item.Get("error.message").Exists() {
+			t.Fatalf("result %d has no error: %s", i, item.Raw)
+		}
+	}
+	if got := data[2].Get("error.message").String(); !strings.Contains(got, "stream") {
+		t.Fatalf("stream item error = %q", got)
+	}
+}
+
+func TestChatCompletionsBatch_ConsumesRateLimitPerItem(t *testing.T) {
+	// Admits the batch plus one further item.
+	tokens := 1
+	limit := func(c *gin.Context) {
+		c.Set("rateLimitAllow", func(context.Context) (bool, time.Duration) {
+			if tokens == 0 {
+				return false, 2 * time.Second
+			}
+			tokens--
+			return true, 0
+		})
+	}
+	body := `{"requests":[` + batchItem("one") + "," + batchItem("two") + "," + batchItem("three") + `]}`
+	data := postBatch(t, body, limit).Get("data").Array()
+	statuses := make([]int64, len(data))
+	for i, item := range data {
+		statuses[i] = item.Get("status").Int()
+	}
+	if len(statuses) != 3 || statuses[0] != 200 || statuses[1] != 200 || statuses[2] != http.StatusTooManyRequests {
+		t.Fatalf("statuses = %v, want [200 200 429]", statuses)
+	}
+}
+
+func TestChatCompletionsBatch_RejectsOversizedBatch(t *testing.T) {
+	items := make([]string, defaultBatchMaxItems+1)
+	for i := range items {
+		items[i] = batchItem("x")
+	}
+	h := NewOpenAIAPIHandler(&handlers.BaseAPIHandler{Cfg: &config.SDKConfig{}})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/batch", h.ChatCompletionsBatch)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader("["+strings.Join(items, ",")+"]")))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
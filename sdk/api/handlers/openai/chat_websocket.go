@@ -0,0 +1,171 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// chatWebSocketDone is sent as a text frame after the last chunk of each response,
+	// mirroring the "data: [DONE]" terminator of the SSE transport.
+	chatWebSocketDone = "[DONE]"
+	// chatWebSocketErrorCodeBase is added to the HTTP status of an upstream error to form the
+	// close code, using the range RFC 6455 reserves for applications (e.g. 4429).
+	chatWebSocketErrorCodeBase = 4000
+	// maxCloseReasonBytes is the largest reason that fits in a close frame.
+	maxCloseReasonBytes    = 123
+	chatWebSocketWriteWait = 10 * time.Second
+)
+
+var chatWebSocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// ChatCompletionsWebSocket handles the /v1/chat/completions/ws endpoint.
+// Each text frame sent by the client is a Chat Completions request, always served as a
+// stream: every chunk is returned as a text frame followed by a "[DONE]" frame, after which
+// the client may send its next request on the same socket. Closing the socket cancels the
+// request in flight, and upstream errors close the socket with code 4000 plus the HTTP
+// status (4400 for invalid requests).
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP upgrade request
+func (h *OpenAIAPIHandler) ChatCompletionsWebSocket(c *gin.Context) {
+	conn, err := chatWebSocketUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already replied with an HTTP error.
+		log.Debugf("chat websocket: upgrade failed: %v", err)
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	connCtx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// Read in the background so a client closing the socket mid-stream cancels the upstream call.
+	requests := make(chan []byte)
+	go func() {
+		defer cancel()
+		for {
+			messageType, data, errRead := conn.ReadMessage()
+			if errRead != nil {
+				return
+			}
+			if messageType != websocket.TextMessage {
+				continue
+			}
+			select {
+			case requests <- data:
+			case <-connCtx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-connCtx.Done():
+			return
+		case rawJSON := <-requests:
+			errStream := h.streamChatOverWebSocket(connCtx, c, conn, rawJSON)
+			if errStream == nil {
+				continue
+			}
+			var closeErr *websocket.CloseError
+			if errors.As(errStream, &closeErr) {
+				message := websocket.FormatCloseMessage(closeErr.Code, closeErr.Text)
+				_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(chatWebSocketWriteWait))
+			}
+			return
+		}
+	}
+}
+
+// streamChatOverWebSocket serves one request on the socket. It returns a *websocket.CloseError
+// when the socket should be closed with that code, or another error when the connection is
+// no longer usable.
+func (h *OpenAIAPIHandler) streamChatOverWebSocket(ctx context.Context, c *gin.Context, conn *websocket.Conn, rawJSON []byte) error {
+	if !gjson.ValidBytes(rawJSON) {
+		return chatWebSocketCloseError(http.StatusBadRequest, "Invalid request: body is not valid JSON")
+	}
+	rawJSON, err := prepareImageInputs(ctx, h.Cfg, rawJSON)
+	if err != nil {
+		return chatWebSocketCloseError(http.StatusBadRequest, err.Error())
+	}
+	// The socket is a streaming transport; a request without "stream" is streamed anyway.
+	if rawJSON, err = sjson.SetBytes(rawJSON, "stream", true); err != nil {
+		return chatWebSocketCloseError(http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+	}
+
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, ctx)
+	dataChan, errChan := h.ExecuteStreamWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON, "")
+	for {
+		select {
+		case <-ctx.Done():
+			cliCancel(ctx.Err())
+			return ctx.Err()
+		case chunk, ok := <-dataChan:
+			if !ok {
+				errWrite := writeChatWebSocketText(conn, []byte(chatWebSocketDone))
+				cliCancel(errWrite)
+				return errWrite
+			}
+			if errWrite := writeChatWebSocketText(conn, chunk); errWrite != nil {
+				cliCancel(errWrite)
+				return errWrite
+			}
+		case errMsg, ok := <-errChan:
+			if !ok || errMsg == nil {
+				continue
+			}
+			cliCancel(errMsg.Error)
+			return chatWebSocketUpstreamError(errMsg)
+		}
+	}
+}
+
+func writeChatWebSocketText(conn *websocket.Conn, data []byte) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(chatWebSocketWriteWait))
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// chatWebSocketUpstreamError maps an upstream error to the close frame sent to the client; the
+// reason starts with the stable ProxyError code.
+func chatWebSocketUpstreamError(errMsg *interfaces.ErrorMessage) *websocket.CloseError {
+	proxyErr := handlers.NewProxyError(errMsg)
+	return chatWebSocketCloseError(proxyErr.StatusCode, proxyErr.Error())
+}
+
+func chatWebSocketCloseError(status int, text string) *websocket.CloseError {
+	code := websocket.CloseInternalServerErr
+	if status >= 400 && status < 600 {
+		code = chatWebSocketErrorCodeBase + status
+	}
+	if len(text) > maxCloseReasonBytes {
+		cut := maxCloseReasonBytes
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	return &websocket.CloseError{Code: code, Text: text}
+}
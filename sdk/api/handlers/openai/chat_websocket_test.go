@@ -0,0 +1,160 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// websocketTestExecutor streams the last user message back one word per chunk; a prompt of
+// "fail" is rejected with a 429 and a prompt of "hang" streams nothing until cancelled.
+type websocketTestExecutor struct {
+	cancelled chan struct{}
+}
+
+func (e *websocketTestExecutor) Identifier() string { return "ws-test" }
+
+func (e *websocketTestExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *websocketTestExecutor) ExecuteStream(ctx context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	messages := gjson.GetBytes(req.Payload, "messages").Array()
+	prompt := messages[len(messages)-1].Get("content").String()
+	if prompt == "fail" {
+		return nil, &websocketStatusError{code: http.StatusTooManyRequests}
+	}
+	out := make(chan coreexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		if prompt == "hang" {
+			<-ctx.Done()
+			close(e.cancelled)
+			return
+		}
+		for _, word := range strings.Fields(prompt) {
+			chunk := `{"object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"` + word + `"}}]}`
+			select {
+			case out <- coreexecutor.StreamChunk{Payload: []byte(chunk)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (e *websocketTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *websocketTestExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+type websocketStatusError struct{ code int }
+
+func (e *websocketStatusError) Error() string   { return "rate limited" }
+func (e *websocketStatusError) StatusCode() int { return e.code }
+
+func dialChatWebSocket(t *testing.T, exec *websocketTestExecutor) *websocket.Conn {
+	t.Helper()
+	h := newOpenAITestHandler(t, &config.SDKConfig{}, "ws-test", []*registry.ModelInfo{{ID: "ws-model"}}, exec)
+	router := gin.New()
+	router.GET("/v1/chat/completions/ws", h.ChatCompletionsWebSocket)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/chat/completions/ws", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func sendChatWebSocket(t *testing.T, conn *websocket.Conn, prompt string) {
+	t.Helper()
+	body := `{"model":"ws-model","messages":[{"role":"user","content":"` + prompt + `"}]}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(body)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+// readChatWebSocketTurn collects the streamed content up to the [DONE] frame.
+func readChatWebSocketTurn(t *testing.T, conn *websocket.Conn) []string {
+	t.Helper()
+	var words []string
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if string(data) == chatWebSocketDone {
+			return words
+		}
+		words = append(words, gjson.GetBytes(data, "choices.0.delta.content").String())
+	}
+}
+
+func TestChatCompletionsWebSocket_StreamsConversation(t *testing.T) {
+	conn := dialChatWebSocket(t, &websocketTestExecutor{})
+
+	sendChatWebSocket(t, conn, "hello there")
+	if got := readChatWebSocketTurn(t, conn); strings.Join(got, " ") != "hello there" {
+		t.Fatalf("Unexpected first turn %v", got)
+	}
+	sendChatWebSocket(t, conn, "second turn works")
+	if got := readChatWebSocketTurn(t, conn); strings.Join(got, " ") != "second turn works" {
+		t.Fatalf("Unexpected second turn %v", got)
+	}
+
+	// Upstream errors close the socket with 4000 plus the HTTP status.
+	sendChatWebSocket(t, conn, "fail")
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != 4000+http.StatusTooManyRequests || !strings.Contains(closeErr.Text, "rate limited") {
+		t.Fatalf("Expected close code 4429, got %v", err)
+	}
+}
+
+func TestChatCompletionsWebSocket_InvalidRequest(t *testing.T) {
+	conn := dialChatWebSocket(t, &websocketTestExecutor{})
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("not json")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != 4000+http.StatusBadRequest {
+		t.Fatalf("Expected close code 4400, got %v", err)
+	}
+}
+
+func TestChatCompletionsWebSocket_ClientCloseCancelsUpstream(t *testing.T) {
+	exec := &websocketTestExecutor{cancelled: make(chan struct{})}
+	conn := dialChatWebSocket(t, exec)
+	sendChatWebSocket(t, conn, "hang")
+	// Give the server time to start the upstream stream before closing.
+	time.Sleep(50 * time.Millisecond)
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	_ = conn.Close()
+
+	select {
+	case <-exec.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected closing the socket to cancel the upstream request")
+	}
+}
@@ -0,0 +1,125 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	defaultMaxChoices        = 8
+	defaultChoiceConcurrency = 4
+)
+
+// choiceUsageFields are the usage counters summed across fanned-out sub-calls.
+var choiceUsageFields = []string{
+	"prompt_tokens",
+	"completion_tokens",
+	"total_tokens",
+	"prompt_tokens_details.cached_tokens",
+	"completion_tokens_details.reasoning_tokens",
+}
+
+// choicesToFanOut returns the number of choices a non-streaming chat request asks for when it
+// has to be served by fan-out, or zero when a single upstream call suffices.
+func choicesToFanOut(rawJSON []byte, modelName string) int {
+	n := int(gjson.GetBytes(rawJSON, "n").Int())
+	if n <= 1 || supportsNativeChoices(modelName) {
+		return 0
+	}
+	return n
+}
+
+// supportsNativeChoices reports whether the upstream of modelName accepts n itself, which is
+// the case for OpenAI-compatible providers and models advertising the parameter.
+func supportsNativeChoices(modelName string) bool {
+	info := registry.GetGlobalRegistry().GetModelInfo(modelName)
+	if info == nil {
+		return false
+	}
+	return info.Type == "openai-compatibility" || slices.Contains(info.SupportedParameters, "n")
+}
+
+// executeChoiceFanOut serves an n > 1 chat request by issuing n single-choice upstream calls
+// and merging them into one response.
+func (h *OpenAIAPIHandler) executeChoiceFanOut(ctx context.Context, c *gin.Context, modelName string, rawJSON []byte, n int) ([]byte, *interfaces.ErrorMessage) {
+	var cfg config.ChoiceFanOutConfig
+	if h.Cfg != nil {
+		cfg = h.Cfg.ChoiceFanOut
+	}
+	maxN := cfg.MaxN
+	if maxN <= 0 {
+		maxN = defaultMaxChoices
+	}
+	if n > maxN {
+		return nil, &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("n must be at most %d, got %d", maxN, n)}
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultChoiceConcurrency
+	}
+	single, _ := sjson.DeleteBytes(rawJSON, "n")
+	results, errMsg := h.ExecuteFanOutWithAuthManager(handlers.WithoutResponseCache(handlers.WithoutCoalescing(ctx)), h.HandlerType(), modelName, single, h.GetAlt(c), n, concurrency)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	return mergeChoices(results, strings.EqualFold(strings.TrimSpace(cfg.FailurePolicy), "fail"))
+}
+
+// mergeChoices assembles the sub-call responses into one chat completion with choices indexed
+// from zero and usage summed. When some sub-calls failed the response is marked
+// "partial": true, unless failOnError is set, in which case the first error is returned.
+func mergeChoices(results []handlers.FanOutResult, failOnError bool) ([]byte, *interfaces.ErrorMessage) {
+	var (
+		out      []byte
+		firstErr *interfaces.ErrorMessage
+		failed   int
+	)
+	choices := []byte("[]")
+	usage := make(map[string]int64, len(choiceUsageFields))
+	index := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+			continue
+		}
+		if out == nil {
+			out = result.Payload
+		}
+		gjson.GetBytes(result.Payload, "choices").ForEach(func(_, choice gjson.Result) bool {
+			updated, _ := sjson.Set(choice.Raw, "index", index)
+			choices, _ = sjson.SetRawBytes(choices, "-1", []byte(updated))
+			index++
+			return true
+		})
+		for _, field := range choiceUsageFields {
+			usage[field] += gjson.GetBytes(result.Payload, "usage."+field).Int()
+		}
+	}
+	if out == nil || (failed > 0 && failOnError) {
+		return nil, firstErr
+	}
+	out, _ = sjson.SetRawBytes(out, "choices", choices)
+	for _, field := range choiceUsageFields {
+		if gjson.GetBytes(out, "usage."+field).Exists() {
+			out, _ = sjson.SetBytes(out, "usage."+field, usage[field])
+		}
+	}
+	if failed > 0 {
+		out, _ = sjson.SetBytes(out, "partial", true)
+	}
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type fanOutTestExecutor struct {
+	calls  atomic.Int32
+	failOn int32
+	sawN   atomic.Bool
+}
+
+func (e *fanOutTestExecutor) Identifier() string { return "fanout-test" }
+
+func (e *fanOutTestExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	call := e.calls.Add(1)
+	if gjson.GetBytes(req.Payload, "n").Exists() {
+		e.sawN.Store(true)
+	}
+	if call == e.failOn {
+		return coreexecutor.Response{}, &fanOutStatusError{code: http.StatusBadRequest}
+	}
+	payload := `{"id":"chatcmpl-` + strconv.Itoa(int(call)) + `","object":"chat.completion","model":"fanout-model",` +
+		`"choices":[{"index":0,"message":{"role":"assistant","content":"answer"},"finish_reason":"stop"}],` +
+		`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`
+	return coreexecutor.Response{Payload: []byte(payload)}, nil
+}
+
+func (e *fanOutTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *fanOutTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *fanOutTestExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+type fanOutStatusError struct{ code int }
+
+func (e *fanOutStatusError) Error() string   { return "sub-call rejected" }
+func (e *fanOutStatusError) StatusCode() int { return e.code }
+
+func runFanOutRequest(t *testing.T, exec *fanOutTestExecutor, cfg *config.SDKConfig, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	h := newOpenAITestHandler(t, cfg, "fanout-test", []*registry.ModelInfo{{ID: "fanout-model"}}, exec)
+	router := gin.New()
+	router.POST("/v1/chat/completions", h.ChatCompletions)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	return recorder
+}
+
+const fanOutBody = `{"model":"fanout-model","n":3,"messages":[{"role":"user","content":"hi"}]}`
+
+func TestChatCompletions_FansOutChoices(t *testing.T) {
+	exec := &fanOutTestExecutor{}
+	recorder := runFanOutRequest(t, exec, &config.SDKConfig{}, fanOutBody)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if got := exec.calls.Load(); got != 3 {
+		t.Fatalf("Expected 3 upstream calls, got %d", got)
+	}
+	if exec.sawN.Load() {
+		t.Error("Expected n to be stripped from sub-call payloads")
+	}
+	resp := gjson.Parse(recorder.Body.String())
+	choices := resp.Get("choices").Array()
+	if len(choices) != 3 {
+		t.Fatalf("Expected 3 choices, got %s", resp.Get("choices").Raw)
+	}
+	for i, choice := range choices {
+		if choice.Get("index").Int() != int64(i) {
+			t.Errorf("choice %d: unexpected index %d", i, choice.Get("index").Int())
+		}
+	}
+	if resp.Get("usage.prompt_tokens").Int() != 30 || resp.Get("usage.completion_tokens").Int() != 15 || resp.Get("usage.total_tokens").Int() != 45 {
+		t.Errorf("Expected usage summed across sub-calls, got %s", resp.Get("usage").Raw)
+	}
+	if resp.Get("partial").Exists() {
+		t.Error("Expected no partial flag when every sub-call succeeded")
+	}
+}
+
+func TestChatCompletions_FanOutSubCallFailure(t *testing.T) {
+	t.Run("partial", func(t *testing.T) {
+		recorder := runFanOutRequest(t, &fanOutTestExecutor{failOn: 2}, &config.SDKConfig{}, fanOutBody)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		resp := gjson.Parse(recorder.Body.String())
+		if n := len(resp.Get("choices").Array()); n != 2 || !resp.Get("partial").Bool() {
+			t.Fatalf("Expected 2 choices marked partial, got %s", recorder.Body.String())
+		}
+		if resp.Get("choices.1.index").Int() != 1 || resp.Get("usage.total_tokens").Int() != 30 {
+			t.Errorf("Expected contiguous indexes and usage of the successful calls, got %s", recorder.Body.String())
+		}
+	})
+	t.Run("fail", func(t *testing.T) {
+		cfg := &config.SDKConfig{ChoiceFanOut: config.ChoiceFanOutConfig{FailurePolicy: "fail"}}
+		recorder := runFanOutRequest(t, &fanOutTestExecutor{failOn: 2}, cfg, fanOutBody)
+		if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "sub-call rejected") {
+			t.Fatalf("Expected the sub-call error, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestChatCompletions_FanOutRejectsLargeN(t *testing.T) {
+	exec := &fanOutTestExecutor{}
+	cfg := &config.SDKConfig{ChoiceFanOut: config.ChoiceFanOutConfig{MaxN: 2}}
+	recorder := runFanOutRequest(t, exec, cfg, fanOutBody)
+	if recorder.Code != http.StatusBadRequest || exec.calls.Load() != 0 {
+		t.Fatalf("Expected 400 without upstream calls, got %d after %d calls", recorder.Code, exec.calls.Load())
+	}
+}
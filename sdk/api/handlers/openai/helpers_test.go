@@ -0,0 +1,35 @@
+package openai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// newHandlerTestManager returns an auth manager whose single credential, provider + "-auth",
+// serves models through exec. The models are unregistered when the test ends.
+func newHandlerTestManager(t *testing.T, provider string, models []*registry.ModelInfo, exec coreauth.ProviderExecutor) *coreauth.Manager {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	authID := provider + "-auth"
+	registry.GetGlobalRegistry().RegisterClient(authID, provider, models)
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(authID) })
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(exec)
+	if _, err := manager.Register(context.Background(), &coreauth.Auth{ID: authID, Provider: provider}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	return manager
+}
+
+// newOpenAITestHandler returns an OpenAI handler configured with cfg whose models are served
+// by exec through a single credential of provider.
+func newOpenAITestHandler(t *testing.T, cfg *config.SDKConfig, provider string, models []*registry.ModelInfo, exec coreauth.ProviderExecutor) *OpenAIAPIHandler {
+	t.Helper()
+	return NewOpenAIAPIHandler(&handlers.BaseAPIHandler{Cfg: cfg, AuthManager: newHandlerTestManager(t, provider, models, exec)})
+}
@@ -0,0 +1,145 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// defaultMaxImages caps image_url parts per request when not configured.
+	defaultMaxImages = 16
+	// defaultMaxImageBytes caps the decoded size of a single image when not configured.
+	defaultMaxImageBytes int64 = 20 << 20
+	// remoteImageTimeout bounds how long a remote image fetch may take.
+	remoteImageTimeout = 30 * time.Second
+
+	remoteImagesFileData = "file-data"
+)
+
+// prepareImageInputs validates the image_url parts of an OpenAI chat request against the
+// configured count and size limits. Remote images are fetched and rewritten to data URIs
+// unless the configuration forwards them as URLs. The optional detail field is left in
+// place; translators for upstreams without an equivalent ignore it.
+func prepareImageInputs(ctx context.Context, cfg *config.SDKConfig, rawJSON []byte) ([]byte, error) {
+	var imageCfg config.ImageInputConfig
+	if cfg != nil {
+		imageCfg = cfg.ImageInput
+	}
+	maxImages := imageCfg.MaxImages
+	if maxImages <= 0 {
+		maxImages = defaultMaxImages
+	}
+	maxBytes := imageCfg.MaxImageBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxImageBytes
+	}
+	inlineRemote := !strings.EqualFold(strings.TrimSpace(imageCfg.RemoteURLs), remoteImagesFileData)
+
+	count := 0
+	out := rawJSON
+	for i, message := range gjson.GetBytes(rawJSON, "messages").Array() {
+		content := message.Get("content")
+		if !content.IsArray() {
+			continue
+		}
+		for j, item := range content.Array() {
+			if item.Get("type").String() != "image_url" {
+				continue
+			}
+			count++
+			if count > maxImages {
+				return nil, fmt.Errorf("too many images: at most %d are allowed per request", maxImages)
+			}
+			urlPath := fmt.Sprintf("messages.%d.content.%d.image_url", i, j)
+			imageURL := item.Get("image_url.url")
+			if !imageURL.Exists() && item.Get("image_url").Type == gjson.String {
+				imageURL = item.Get("image_url")
+			} else {
+				urlPath += ".url"
+			}
+			value := strings.TrimSpace(imageURL.String())
+			lower := strings.ToLower(value)
+			switch {
+			case strings.HasPrefix(lower, "data:"):
+				if err := checkDataURI(value, maxBytes); err != nil {
+					return nil, err
+				}
+			case strings.HasPrefix(lower, "https://"), strings.HasPrefix(lower, "http://"):
+				if !inlineRemote {
+					continue
+				}
+				dataURI, err := fetchImageAsDataURI(ctx, cfg, value, maxBytes)
+				if err != nil {
+					return nil, err
+				}
+				out, _ = sjson.SetBytes(out, urlPath, dataURI)
+			default:
+				return nil, fmt.Errorf("unsupported image_url: expected a data URI or an http(s) URL")
+			}
+		}
+	}
+	return out, nil
+}
+
+// checkDataURI verifies that a base64 data URI is well formed and within maxBytes.
+func checkDataURI(value string, maxBytes int64) error {
+	header, data, ok := strings.Cut(value[len("data:"):], ",")
+	if !ok || !strings.HasSuffix(strings.ToLower(header), ";base64") {
+		return fmt.Errorf("invalid image data URI: expected base64 encoding")
+	}
+	if int64(base64.StdEncoding.DecodedLen(len(data))) > maxBytes+2 {
+		return fmt.Errorf("image exceeds the maximum size of %d bytes", maxBytes)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("invalid image data URI: %v", err)
+	}
+	if int64(len(decoded)) > maxBytes {
+		return fmt.Errorf("image exceeds the maximum size of %d bytes", maxBytes)
+	}
+	return nil
+}
+
+// fetchImageAsDataURI downloads a remote image through the configured proxy and returns it
+// as a base64 data URI.
+func fetchImageAsDataURI(ctx context.Context, cfg *config.SDKConfig, imageURL string, maxBytes int64) (string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid image URL: %v", err)
+	}
+	client := &http.Client{Timeout: remoteImageTimeout}
+	if cfg != nil {
+		client = util.SetProxy(cfg, client)
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch image: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch image: upstream returned status %d", resp.StatusCode)
+	}
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("failed to fetch image: unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch image: %v", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return "", fmt.Errorf("image exceeds the maximum size of %d bytes", maxBytes)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,79 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func imageRequest(urls ...string) []byte {
+	parts := make([]string, 0, len(urls)+1)
+	parts = append(parts, `{"type":"text","text":"describe"}`)
+	for _, url := range urls {
+		parts = append(parts, `{"type":"image_url","image_url":{"url":"`+url+`","detail":"high"}}`)
+	}
+	return []byte(`{"model":"gemini-2.5-pro","messages":[{"role":"user","content":[` + strings.Join(parts, ",") + `]}]}`)
+}
+
+func TestPrepareImageInputs_DataURI(t *testing.T) {
+	dataURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("png-bytes"))
+	out, err := prepareImageInputs(context.Background(), &config.SDKConfig{}, imageRequest(dataURI))
+	if err != nil {
+		t.Fatalf("Expected data URI to be accepted, got %v", err)
+	}
+	if got := gjson.GetBytes(out, "messages.0.content.1.image_url.url").String(); got != dataURI {
+		t.Fatalf("Expected data URI to be unchanged, got %s", got)
+	}
+
+	cfg := &config.SDKConfig{ImageInput: config.ImageInputConfig{MaxImageBytes: 4}}
+	if _, err = prepareImageInputs(context.Background(), cfg, imageRequest(dataURI)); err == nil {
+		t.Fatal("Expected oversized image to be rejected")
+	}
+	cfg = &config.SDKConfig{ImageInput: config.ImageInputConfig{MaxImages: 1}}
+	if _, err = prepareImageInputs(context.Background(), cfg, imageRequest(dataURI, dataURI)); err == nil {
+		t.Fatal("Expected too many images to be rejected")
+	}
+	if _, err = prepareImageInputs(context.Background(), &config.SDKConfig{}, imageRequest("data:image/png,raw")); err == nil {
+		t.Fatal("Expected non-base64 data URI to be rejected")
+	}
+}
+
+func TestPrepareImageInputs_RemoteURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cat.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte("jpeg-bytes"))
+	}))
+	defer server.Close()
+
+	out, err := prepareImageInputs(context.Background(), &config.SDKConfig{}, imageRequest(server.URL+"/cat.jpg"))
+	if err != nil {
+		t.Fatalf("Expected remote image to be fetched, got %v", err)
+	}
+	want := "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString([]byte("jpeg-bytes"))
+	if got := gjson.GetBytes(out, "messages.0.content.1.image_url.url").String(); got != want {
+		t.Fatalf("Expected remote image to be inlined, got %s", got)
+	}
+
+	if _, err = prepareImageInputs(context.Background(), &config.SDKConfig{}, imageRequest(server.URL+"/missing.jpg")); err == nil {
+		t.Fatal("Expected failed fetch to be rejected")
+	}
+
+	cfg := &config.SDKConfig{ImageInput: config.ImageInputConfig{RemoteURLs: "file-data"}}
+	out, err = prepareImageInputs(context.Background(), cfg, imageRequest(server.URL+"/missing.jpg"))
+	if err != nil {
+		t.Fatalf("Expected file-data mode to skip fetching, got %v", err)
+	}
+	if got := gjson.GetBytes(out, "messages.0.content.1.image_url.url").String(); got != server.URL+"/missing.jpg" {
+		t.Fatalf("Expected URL to be forwarded unchanged, got %s", got)
+	}
+}
@@ -0,0 +1,96 @@
+package openai
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+)
+
+// maxEmbeddingInputs mirrors the per-call request limit of Gemini batchEmbedContents.
+const maxEmbeddingInputs = 100
+
+// Embeddings handles the /v1/embeddings endpoint.
+// It validates the OpenAI embeddings request and forwards every input in a single
+// batched upstream call, returning the vectors in OpenAI embeddings format.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIAPIHandler) Embeddings(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", err),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	if errValidate := validateEmbeddingsRequest(rawJSON); errValidate != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: errValidate.Error(),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/json")
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, c.Request.Context())
+	resp, errMsg := h.ExecuteWithAuthManager(cliCtx, OpenAIEmbeddings, modelName, rawJSON, "")
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	_, _ = c.Writer.Write(resp)
+	cliCancel()
+}
+
+// validateEmbeddingsRequest checks the model and input shape of an embeddings request.
+// Pre-tokenized (integer) input is rejected because the upstream only embeds text.
+func validateEmbeddingsRequest(rawJSON []byte) error {
+	model := gjson.GetBytes(rawJSON, "model").String()
+	if model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if info := registry.GetGlobalRegistry().GetModelInfo(model); info != nil && !slices.Contains(info.SupportedGenerationMethods, "embedContent") {
+		return fmt.Errorf("model %s does not support embeddings", model)
+	}
+
+	input := gjson.GetBytes(rawJSON, "input")
+	switch {
+	case input.Type == gjson.String:
+		if input.Str == "" {
+			return fmt.Errorf("input must not be empty")
+		}
+	case input.IsArray():
+		items := input.Array()
+		if len(items) == 0 {
+			return fmt.Errorf("input must not be empty")
+		}
+		if len(items) > maxEmbeddingInputs {
+			return fmt.Errorf("input must contain at most %d items", maxEmbeddingInputs)
+		}
+		for _, item := range items {
+			if item.Type != gjson.String || item.Str == "" {
+				return fmt.Errorf("input must be a string or an array of non-empty strings")
+			}
+		}
+	default:
+		return fmt.Errorf("input must be a string or an array of non-empty strings")
+	}
+
+	if dimensions := gjson.GetBytes(rawJSON, "dimensions"); dimensions.Exists() && (dimensions.Type != gjson.Number || dimensions.Int() <= 0) {
+		return fmt.Errorf("dimensions must be a positive integer")
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package openai
+
+import "testing"
+
+func TestValidateEmbeddingsRequest(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"single string", `{"model":"gemini-embedding-001","input":"hello"}`, false},
+		{"string array", `{"model":"gemini-embedding-001","input":["a","b"],"dimensions":256}`, false},
+		{"missing model", `{"input":"hello"}`, true},
+		{"empty input", `{"model":"gemini-embedding-001","input":[]}`, true},
+		{"token input", `{"model":"gemini-embedding-001","input":[1,2,3]}`, true},
+		{"invalid dimensions", `{"model":"gemini-embedding-001","input":"hello","dimensions":0}`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateEmbeddingsRequest([]byte(tc.body))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateEmbeddingsRequest(%s) error = %v, wantErr %v", tc.body, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -11,12 +11,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -55,13 +57,14 @@ func (h *OpenAIAPIHandler) Models() []map[string]any {
 }
 
 // OpenAIModels handles the /v1/models endpoint.
-// It returns a list of available AI models with their capabilities
-// and specifications in OpenAI-compatible format.
+// It returns the aggregated model catalog of every configured provider in
+// OpenAI-compatible format. Models whose credentials are currently exhausted
+// are still listed with "available": false.
 func (h *OpenAIAPIHandler) OpenAIModels(c *gin.Context) {
-	// Get all available models
-	allModels := h.Models()
+	// Get every registered model, including temporarily unavailable ones
+	allModels := registry.GetGlobalRegistry().GetAllModels("openai")
 
-	// Filter to only include the 4 required fields: id, object, created, owned_by
+	// Filter to the OpenAI fields (id, object, created, owned_by) plus the availability flag
 	filteredModels := make([]map[string]any, len(allModels))
 	for i, model := range allModels {
 		filteredModel := map[string]any{
@@ -79,6 +82,10 @@ func (h *OpenAIAPIHandler) OpenAIModels(c *gin.Context) {
 			filteredModel["owned_by"] = ownedBy
 		}
 
+		if available, exists := model["available"]; exists {
+			filteredModel["available"] = available
+		}
+
 		filteredModels[i] = filteredModel
 	}
 
@@ -88,6 +95,28 @@ func (h *OpenAIAPIHandler) OpenAIModels(c *gin.Context) {
 	})
 }
 
+// ModelCapabilities handles the /v1/models/:id/capabilities endpoint. It reports the
+// registry-derived capabilities of a model: thinking budget range, context and output
+// limits, tool, vision and logit_bias support and input and output modalities. Unknown
+// models get 404.
+func (h *OpenAIAPIHandler) ModelCapabilities(c *gin.Context) {
+	modelID := strings.TrimSpace(c.Param("id"))
+	info := registry.GetGlobalRegistry().GetModelInfo(modelID)
+	if info == nil {
+		c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("The model '%s' does not exist", modelID),
+				Type:    "invalid_request_error",
+				Code:    "model_not_found",
+			},
+		})
+		return
+	}
+	caps := registry.CapabilitiesOf(info)
+	caps.LogitBias = handlers.SupportsLogitBias(util.GetProviderName(modelID))
+	c.JSON(http.StatusOK, caps)
+}
+
 // ChatCompletions handles the /v1/chat/completions endpoint.
 // It determines whether the request is for a streaming or non-streaming response
 // and calls the appropriate handler based on the model provider.
@@ -106,6 +135,19 @@ func (h *OpenAIAPIHandler) ChatCompletions(c *gin.Context) {
 		})
 		return
 	}
+	rawJSON, err = prepareImageInputs(c.Request.Context(), h.Cfg, rawJSON)
+	if err == nil {
+		_, err = util.NormalizeEndUser(gjson.GetBytes(rawJSON, "user"))
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: err.Error(),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
 
 	// Check if the client requested a streaming response.
 	streamResult := gjson.GetBytes(rawJSON, "stream")
@@ -397,9 +439,8 @@ func convertChatCompletionsStreamChunkToCompletions(chunkData []byte) []byte {
 func (h *OpenAIAPIHandler) handleNonStreamingResponse(c *gin.Context, rawJSON []byte) {
 	c.Header("Content-Type", "application/json")
 
-	modelName := gjson.GetBytes(rawJSON, "model").String()
-	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
-	resp, errMsg := h.ExecuteWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON, h.GetAlt(c))
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, c.Request.Context())
+	resp, errMsg := h.executeChatCompletion(cliCtx, c, rawJSON)
 	if errMsg != nil {
 		h.WriteErrorResponse(c, errMsg)
 		cliCancel(errMsg.Error)
@@ -409,6 +450,29 @@ func (h *OpenAIAPIHandler) handleNonStreamingResponse(c *gin.Context, rawJSON []
 	cliCancel()
 }
 
+// executeChatCompletion serves one non-streaming chat request, fanning out n > 1 requests when
+// the upstream cannot return several choices and validating structured output.
+func (h *OpenAIAPIHandler) executeChatCompletion(ctx context.Context, c *gin.Context, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	var (
+		resp   []byte
+		errMsg *interfaces.ErrorMessage
+	)
+	if n := choicesToFanOut(rawJSON, modelName); n > 0 {
+		resp, errMsg = h.executeChoiceFanOut(ctx, c, modelName, rawJSON, n)
+	} else {
+		resp, errMsg = h.ExecuteWithAuthManager(ctx, h.HandlerType(), modelName, rawJSON, h.GetAlt(c))
+	}
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	resp, errValidate := validateStructuredOutput(rawJSON, resp)
+	if errValidate != nil {
+		return nil, &interfaces.ErrorMessage{StatusCode: http.StatusBadGateway, Error: errValidate}
+	}
+	return resp, nil
+}
+
 // handleStreamingResponse handles streaming responses for Gemini models.
 // It establishes a streaming connection with the backend service and forwards
 // the response chunks to the client in real-time using Server-Sent Events.
@@ -435,8 +499,12 @@ func (h *OpenAIAPIHandler) handleStreamingResponse(c *gin.Context, rawJSON []byt
 	}
 
 	modelName := gjson.GetBytes(rawJSON, "model").String()
-	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, c.Request.Context())
 	dataChan, errChan := h.ExecuteStreamWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON, h.GetAlt(c))
+	if format := h.structuredStreamFormat(rawJSON); format != "" {
+		h.handleStructuredStreamResult(c, flusher, func(err error) { cliCancel(err) }, format, dataChan, errChan)
+		return
+	}
 	h.handleStreamResult(c, flusher, func(err error) { cliCancel(err) }, dataChan, errChan)
 }
 
@@ -454,7 +522,7 @@ func (h *OpenAIAPIHandler) handleCompletionsNonStreamingResponse(c *gin.Context,
 	chatCompletionsJSON := convertCompletionsRequestToChatCompletions(rawJSON)
 
 	modelName := gjson.GetBytes(chatCompletionsJSON, "model").String()
-	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, c.Request.Context())
 	resp, errMsg := h.ExecuteWithAuthManager(cliCtx, h.HandlerType(), modelName, chatCompletionsJSON, "")
 	if errMsg != nil {
 		h.WriteErrorResponse(c, errMsg)
@@ -495,16 +563,29 @@ func (h *OpenAIAPIHandler) handleCompletionsStreamingResponse(c *gin.Context, ra
 	chatCompletionsJSON := convertCompletionsRequestToChatCompletions(rawJSON)
 
 	modelName := gjson.GetBytes(chatCompletionsJSON, "model").String()
-	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, c.Request.Context())
 	dataChan, errChan := h.ExecuteStreamWithAuthManager(cliCtx, h.HandlerType(), modelName, chatCompletionsJSON, "")
 
+	keepAlive := h.NewStreamKeepAlive()
+	defer keepAlive.Stop()
+	progress := &streamProgress{}
 	for {
 		select {
 		case <-c.Request.Context().Done():
 			cliCancel(c.Request.Context().Err())
 			return
+		case <-keepAlive.C():
+			_, _ = c.Writer.Write([]byte(handlers.KeepAliveComment))
+			flusher.Flush()
 		case chunk, isOk := <-dataChan:
+			keepAlive.Stop()
 			if !isOk {
+				// A failed stream queues its error before the data channel closes.
+				if errMsg := pendingStreamError(errChan); errMsg != nil {
+					h.writeStreamError(c, flusher, progress, errMsg, convertChatCompletionsStreamChunkToCompletions)
+					cliCancel(errMsg.Error)
+					return
+				}
 				_, _ = fmt.Fprintf(c.Writer, "data: [DONE]\n\n")
 				flusher.Flush()
 				cliCancel()
@@ -512,6 +593,7 @@ func (h *OpenAIAPIHandler) handleCompletionsStreamingResponse(c *gin.Context, ra
 			}
 			converted := convertChatCompletionsStreamChunkToCompletions(chunk)
 			if converted != nil {
+				progress.observe(chunk)
 				_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(converted))
 				flusher.Flush()
 			}
@@ -520,8 +602,7 @@ func (h *OpenAIAPIHandler) handleCompletionsStreamingResponse(c *gin.Context, ra
 				continue
 			}
 			if errMsg != nil {
-				h.WriteErrorResponse(c, errMsg)
-				flusher.Flush()
+				h.writeStreamError(c, flusher, progress, errMsg, convertChatCompletionsStreamChunkToCompletions)
 			}
 			var execErr error
 			if errMsg != nil {
@@ -534,18 +615,32 @@ func (h *OpenAIAPIHandler) handleCompletionsStreamingResponse(c *gin.Context, ra
 	}
 }
 func (h *OpenAIAPIHandler) handleStreamResult(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
+	keepAlive := h.NewStreamKeepAlive()
+	defer keepAlive.Stop()
+	progress := &streamProgress{}
 	for {
 		select {
 		case <-c.Request.Context().Done():
 			cancel(c.Request.Context().Err())
 			return
+		case <-keepAlive.C():
+			_, _ = c.Writer.Write([]byte(handlers.KeepAliveComment))
+			flusher.Flush()
 		case chunk, ok := <-data:
+			keepAlive.Stop()
 			if !ok {
+				// A failed stream queues its error before the data channel closes.
+				if errMsg := pendingStreamError(errs); errMsg != nil {
+					h.writeStreamError(c, flusher, progress, errMsg, nil)
+					cancel(errMsg.Error)
+					return
+				}
 				_, _ = fmt.Fprintf(c.Writer, "data: [DONE]\n\n")
 				flusher.Flush()
 				cancel(nil)
 				return
 			}
+			progress.observe(chunk)
 			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunk))
 			flusher.Flush()
 		case errMsg, ok := <-errs:
@@ -553,8 +648,7 @@ func (h *OpenAIAPIHandler) handleStreamResult(c *gin.Context, flusher http.Flush
 				continue
 			}
 			if errMsg != nil {
-				h.WriteErrorResponse(c, errMsg)
-				flusher.Flush()
+				h.writeStreamError(c, flusher, progress, errMsg, nil)
 			}
 			var execErr error
 			if errMsg != nil {
@@ -0,0 +1,133 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func getCapabilities(t *testing.T, model string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	h := NewOpenAIAPIHandler(&handlers.BaseAPIHandler{Cfg: &config.SDKConfig{}})
+	router := gin.New()
+	router.GET("/v1/models/:id/capabilities", h.ModelCapabilities)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models/"+model+"/capabilities", nil))
+	return w
+}
+
+func TestModelCapabilities_ThinkingModel(t *testing.T) {
+	registry.GetGlobalRegistry().RegisterClient("caps-auth", "claude", []*registry.ModelInfo{{
+		ID:                  "caps-thinking-model",
+		OwnedBy:             "anthropic",
+		Type:                "claude",
+		ContextLength:       200000,
+		MaxCompletionTokens: 64000,
+		Thinking:            &registry.ThinkingSupport{Min: 1024, Max: 100000, DynamicAllowed: true},
+	}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient("caps-auth") })
+
+	w := getCapabilities(t, "caps-thinking-model")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := gjson.Parse(w.Body.String())
+	if body.Get("id").String() != "caps-thinking-model" || body.Get("object").String() != "model.capabilities" {
+		t.Fatalf("Unexpected capabilities identity: %s", w.Body.String())
+	}
+	if body.Get("context_length").Int() != 200000 || body.Get("max_output_tokens").Int() != 64000 {
+		t.Fatalf("Expected the registry limits, got %s", w.Body.String())
+	}
+	if body.Get("thinking.min").Int() != 1024 || body.Get("thinking.max").Int() != 100000 || !body.Get("thinking.dynamic_allowed").Bool() {
+		t.Fatalf("Expected the thinking range, got %s", body.Get("thinking").Raw)
+	}
+	if !body.Get("tools").Bool() || !body.Get("vision").Bool() {
+		t.Fatalf("Expected tools and vision for a Claude model, got %s", w.Body.String())
+	}
+	if body.Get("logit_bias").Bool() {
+		t.Fatalf("Expected no logit_bias support for a Claude model, got %s", w.Body.String())
+	}
+}
+
+func TestModelCapabilities_UnknownModel(t *testing.T) {
+	w := getCapabilities(t, "no-such-model")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := gjson.Get(w.Body.String(), "error.code").String(); code != "model_not_found" {
+		t.Fatalf("Expected model_not_found, got %s", w.Body.String())
+	}
+}
+
+// slowStreamExecutor waits before streaming two chunks, like an upstream in a long thinking phase.
+type slowStreamExecutor struct {
+	delay time.Duration
+}
+
+func (e slowStreamExecutor) Identifier() string { return "slow-stream-test" }
+
+func (e slowStreamExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e slowStreamExecutor) ExecuteStream(ctx context.Context, _ *coreauth.Auth, _ coreexecutor.Request, _ coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	out := make(chan coreexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		select {
+		case <-time.After(e.delay):
+		case <-ctx.Done():
+			return
+		}
+		for _, word := range []string{"hello", "world"} {
+			out <- coreexecutor.StreamChunk{Payload: []byte(`{"object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"` + word + `"}}]}`)}
+			time.Sleep(1200 * time.Millisecond)
+		}
+	}()
+	return out, nil
+}
+
+func (e slowStreamExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e slowStreamExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+func TestChatCompletions_StreamKeepAliveBeforeFirstChunk(t *testing.T) {
+	h := newOpenAITestHandler(t, &config.SDKConfig{StreamKeepAliveSeconds: 1}, "slow-stream-test", []*registry.ModelInfo{{ID: "slow-model"}}, slowStreamExecutor{delay: 2500 * time.Millisecond})
+	router := gin.New()
+	router.POST("/v1/chat/completions", h.ChatCompletions)
+	w := httptest.NewRecorder()
+	body := `{"model":"slow-model","stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+
+	out := w.Body.String()
+	firstData := strings.Index(out, "data: ")
+	if firstData < 0 {
+		t.Fatalf("Expected data chunks, got %q", out)
+	}
+	if n := strings.Count(out[:firstData], handlers.KeepAliveComment); n < 2 {
+		t.Fatalf("Expected keepalive comments before the first chunk, got %d in %q", n, out)
+	}
+	if strings.Contains(out[firstData:], ": keepalive") {
+		t.Fatalf("Expected keepalives to stop once chunks flow, got %q", out)
+	}
+	if !strings.HasSuffix(out, "data: [DONE]\n\n") {
+		t.Fatalf("Expected the stream to end with [DONE], got %q", out)
+	}
+}
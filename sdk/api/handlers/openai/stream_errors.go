@@ -0,0 +1,114 @@
+package openai
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// streamProgress tracks what a chat completion stream has sent, so a stream that fails
+// midway can be closed with a final chunk for every choice still open.
+type streamProgress struct {
+	first   []byte
+	open    []int64
+	written bool
+}
+
+// observe records a chat completion chunk that was sent to the client.
+func (p *streamProgress) observe(chunk []byte) {
+	if !gjson.ValidBytes(chunk) {
+		return
+	}
+	p.written = true
+	if p.first == nil {
+		p.first = chunk
+	}
+	gjson.GetBytes(chunk, "choices").ForEach(func(_, choice gjson.Result) bool {
+		index := choice.Get("index").Int()
+		if reason := choice.Get("finish_reason"); reason.Exists() && reason.Type != gjson.Null && reason.String() != "" {
+			p.close(index)
+		} else if !p.isOpen(index) {
+			p.open = append(p.open, index)
+		}
+		return true
+	})
+}
+
+func (p *streamProgress) isOpen(index int64) bool {
+	for _, open := range p.open {
+		if open == index {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *streamProgress) close(index int64) {
+	for i, open := range p.open {
+		if open == index {
+			p.open = append(p.open[:i], p.open[i+1:]...)
+			return
+		}
+	}
+}
+
+// truncatedChunk returns a chunk finishing every open choice with finish_reason "length",
+// or nil when no choice is open.
+func (p *streamProgress) truncatedChunk() []byte {
+	if len(p.open) == 0 {
+		return nil
+	}
+	out := []byte(`{"object":"chat.completion.chunk","choices":[]}`)
+	for _, key := range []string{"id", "created", "model", "system_fingerprint"} {
+		if value := gjson.GetBytes(p.first, key); value.Exists() {
+			out, _ = sjson.SetRawBytes(out, key, []byte(value.Raw))
+		}
+	}
+	for _, index := range p.open {
+		choice, _ := sjson.SetBytes([]byte(`{"delta":{},"finish_reason":"length"}`), "index", index)
+		out, _ = sjson.SetRawBytes(out, "choices.-1", choice)
+	}
+	return out
+}
+
+// writeStreamError reports an upstream failure on a chat completion stream. Before anything
+// reached the client the error is sent as a regular error response with its status. Once the
+// stream has started, open choices are finished as truncated and the error follows as a final
+// SSE event; [DONE] is not sent, so clients can tell the answer is incomplete. convert, when
+// set, turns the closing chat chunk into the client's chunk format.
+func (h *OpenAIAPIHandler) writeStreamError(c *gin.Context, flusher http.Flusher, progress *streamProgress, errMsg *interfaces.ErrorMessage, convert func([]byte) []byte) {
+	defer flusher.Flush()
+	if !c.Writer.Written() {
+		h.WriteErrorResponse(c, errMsg)
+		return
+	}
+	if progress.written {
+		log.Warnf("chat completion stream for model %s failed after content was sent: %v", gjson.GetBytes(progress.first, "model").String(), errMsg.Error)
+	}
+	if final := progress.truncatedChunk(); final != nil {
+		if convert != nil {
+			final = convert(final)
+		}
+		if final != nil {
+			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(final))
+		}
+	}
+	_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(handlers.NewProxyError(errMsg).Body()))
+}
+
+// pendingStreamError returns the error a failed stream queued before closing its data
+// channel, if any.
+func pendingStreamError(errs <-chan *interfaces.ErrorMessage) *interfaces.ErrorMessage {
+	select {
+	case errMsg := <-errs:
+		return errMsg
+	default:
+		return nil
+	}
+}
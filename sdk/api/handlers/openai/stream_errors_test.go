@@ -0,0 +1,48 @@
+package openai
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+const chatStreamRequest = `{"model":"json-model","stream":true,"messages":[{"role":"user","content":"hi"}]}`
+
+func TestChatCompletions_StreamFailureMidwayEndsWithErrorEvent(t *testing.T) {
+	w := streamStructured(t, jsonStreamExecutor{pieces: []string{"Hello", " wor"}, failAfter: true}, chatStreamRequest)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the started stream to keep its 200 status, got %d", w.Code)
+	}
+	events := dataEvents(w.Body.String())
+	if len(events) != 4 {
+		t.Fatalf("Expected two content chunks, a closing chunk and an error event, got %q", w.Body.String())
+	}
+	if gjson.Get(events[0], "choices.0.delta.content").String() != "Hello" || gjson.Get(events[1], "choices.0.delta.content").String() != " wor" {
+		t.Fatalf("Expected the content sent before the failure to be kept, got %q", w.Body.String())
+	}
+	final := gjson.Parse(events[2])
+	if final.Get("choices.0.finish_reason").String() != "length" || final.Get("id").String() != "chatcmpl-1" || final.Get("model").String() != "json-model" {
+		t.Fatalf("Expected the open choice to be finished as truncated, got %s", events[2])
+	}
+	if gjson.Get(events[3], "error.message").String() == "" {
+		t.Fatalf("Expected a terminating error event, got %s", events[3])
+	}
+	for _, event := range events {
+		if event == "[DONE]" {
+			t.Fatalf("Expected no [DONE] after a failed stream, got %q", w.Body.String())
+		}
+	}
+}
+
+func TestChatCompletions_StreamFailureBeforeContentReturnsStatus(t *testing.T) {
+	w := streamStructured(t, jsonStreamExecutor{failAfter: true}, chatStreamRequest)
+
+	if w.Code == http.StatusOK {
+		t.Fatalf("Expected an error status when nothing was streamed, got %d: %s", w.Code, w.Body.String())
+	}
+	if gjson.Get(w.Body.String(), "error.message").String() == "" {
+		t.Fatalf("Expected a JSON error body, got %s", w.Body.String())
+	}
+}
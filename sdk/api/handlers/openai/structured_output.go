@@ -0,0 +1,65 @@
+package openai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// validateStructuredOutput checks that a non-streaming chat completion honours a JSON
+// response_format from the request. Markdown code fences around otherwise valid JSON are
+// stripped from the message content; any other non-JSON content is reported as an error.
+// Responses to requests without a JSON response_format are returned unchanged.
+func validateStructuredOutput(rawJSON, resp []byte) ([]byte, error) {
+	format := jsonResponseFormat(rawJSON)
+	if format == "" {
+		return resp, nil
+	}
+	var invalid error
+	gjson.GetBytes(resp, "choices").ForEach(func(key, choice gjson.Result) bool {
+		content := choice.Get("message.content")
+		if content.Type != gjson.String || content.Str == "" {
+			// Tool calls and refusals carry no JSON body to check.
+			return true
+		}
+		if gjson.Valid(content.Str) {
+			return true
+		}
+		unwrapped := stripJSONCodeFence(content.Str)
+		if !gjson.Valid(unwrapped) {
+			invalid = fmt.Errorf("upstream returned non-JSON content for response_format %s", format)
+			return false
+		}
+		resp, _ = sjson.SetBytes(resp, "choices."+key.String()+".message.content", unwrapped)
+		return true
+	})
+	if invalid != nil {
+		return nil, invalid
+	}
+	return resp, nil
+}
+
+// jsonResponseFormat returns the JSON response_format type of a chat request, or "" when the
+// request does not ask for JSON output.
+func jsonResponseFormat(rawJSON []byte) string {
+	format := gjson.GetBytes(rawJSON, "response_format.type").String()
+	if format != "json_object" && format != "json_schema" {
+		return ""
+	}
+	return format
+}
+
+// stripJSONCodeFence removes a surrounding ```json ... ``` markdown fence.
+func stripJSONCodeFence(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return trimmed
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(trimmed, "```"), "```")
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 && !strings.ContainsAny(body[:newline], "{[\"") {
+		body = body[newline+1:]
+	}
+	return strings.TrimSpace(body)
+}
@@ -0,0 +1,36 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestValidateStructuredOutput(t *testing.T) {
+	request := []byte(`{"response_format":{"type":"json_schema","json_schema":{"name":"user","schema":{"type":"object"}}}}`)
+
+	resp, err := validateStructuredOutput(request, []byte(`{"choices":[{"message":{"content":"{\"user\":{\"name\":\"Ada\",\"address\":{\"city\":\"London\"}}}"}}]}`))
+	if err != nil {
+		t.Fatalf("Expected nested JSON content to validate, got %v", err)
+	}
+	if got := gjson.GetBytes(resp, "choices.0.message.content").String(); !gjson.Valid(got) {
+		t.Fatalf("Expected content to be unchanged, got %s", got)
+	}
+
+	resp, err = validateStructuredOutput(request, []byte(`{"choices":[{"message":{"content":"`+"```json\\n{\\\"name\\\":\\\"Ada\\\"}\\n```"+`"}}]}`))
+	if err != nil {
+		t.Fatalf("Expected fenced JSON to be accepted, got %v", err)
+	}
+	if got := gjson.GetBytes(resp, "choices.0.message.content").String(); got != `{"name":"Ada"}` {
+		t.Fatalf("Expected code fence to be stripped, got %q", got)
+	}
+
+	if _, err = validateStructuredOutput(request, []byte(`{"choices":[{"message":{"content":"Sure! Here is the user."}}]}`)); err == nil {
+		t.Fatal("Expected prose content to be rejected")
+	}
+
+	plain := []byte(`{"choices":[{"message":{"content":"not json"}}]}`)
+	if resp, err = validateStructuredOutput([]byte(`{}`), plain); err != nil || string(resp) != string(plain) {
+		t.Fatalf("Expected requests without response_format to pass through, got %s (%v)", resp, err)
+	}
+}
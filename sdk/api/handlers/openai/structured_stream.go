@@ -0,0 +1,295 @@
+package openai
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// structuredStreamFormat returns the JSON response_format of a streaming chat request whose
+// response should be buffered and repaired, or "" when structured-output-stream-repair is off
+// or the request does not ask for JSON.
+func (h *OpenAIAPIHandler) structuredStreamFormat(rawJSON []byte) string {
+	if h.Cfg == nil || !h.Cfg.StructuredOutputStreamRepair {
+		return ""
+	}
+	return jsonResponseFormat(rawJSON)
+}
+
+// bufferedChoice accumulates the deltas of one choice of a buffered stream.
+type bufferedChoice struct {
+	index        int64
+	role         string
+	content      strings.Builder
+	reasoning    strings.Builder
+	finishReason string
+}
+
+// structuredStream buffers the chunks of a structured-output chat stream so its content can be
+// validated as a whole before anything reaches the client.
+type structuredStream struct {
+	format  string
+	first   []byte
+	chunks  [][]byte
+	choices []*bufferedChoice
+	usage   gjson.Result
+	// toolCalls is set when a delta carries tool calls; those are forwarded unchanged since the
+	// arguments are not the structured output.
+	toolCalls bool
+}
+
+func (s *structuredStream) add(chunk []byte) {
+	if s.first == nil {
+		s.first = chunk
+	}
+	s.chunks = append(s.chunks, chunk)
+	if usage := gjson.GetBytes(chunk, "usage"); usage.IsObject() {
+		s.usage = usage
+	}
+	gjson.GetBytes(chunk, "choices").ForEach(func(_, choice gjson.Result) bool {
+		delta := choice.Get("delta")
+		if delta.Get("tool_calls").Exists() || delta.Get("function_call").Exists() {
+			s.toolCalls = true
+		}
+		buffered := s.choice(choice.Get("index").Int())
+		if role := delta.Get("role").String(); role != "" {
+			buffered.role = role
+		}
+		buffered.content.WriteString(delta.Get("content").String())
+		buffered.reasoning.WriteString(delta.Get("reasoning_content").String())
+		if reason := choice.Get("finish_reason").String(); reason != "" {
+			buffered.finishReason = reason
+		}
+		return true
+	})
+}
+
+func (s *structuredStream) choice(index int64) *bufferedChoice {
+	for _, buffered := range s.choices {
+		if buffered.index == index {
+			return buffered
+		}
+	}
+	buffered := &bufferedChoice{index: index, role: "assistant"}
+	s.choices = append(s.choices, buffered)
+	return buffered
+}
+
+// final builds the single chunk that replaces the buffered stream, with each choice's content
+// validated and, if needed, repaired. Choices of a truncated stream that never received a
+// finish_reason are marked "length".
+func (s *structuredStream) final(truncated bool) ([]byte, error) {
+	out := []byte(`{}`)
+	for _, field := range []string{"id", "object", "created", "model", "system_fingerprint"} {
+		if value := gjson.GetBytes(s.first, field); value.Exists() {
+			out, _ = sjson.SetRawBytes(out, field, []byte(value.Raw))
+		}
+	}
+	for i, buffered := range s.choices {
+		path := "choices." + strconv.Itoa(i)
+		out, _ = sjson.SetBytes(out, path+".index", buffered.index)
+		out, _ = sjson.SetBytes(out, path+".delta.role", buffered.role)
+		if reasoning := buffered.reasoning.String(); reasoning != "" {
+			out, _ = sjson.SetBytes(out, path+".delta.reasoning_content", reasoning)
+		}
+		if content := buffered.content.String(); content != "" {
+			repaired, ok := repairJSON(content)
+			if !ok {
+				return nil, fmt.Errorf("upstream returned non-JSON content for response_format %s", s.format)
+			}
+			out, _ = sjson.SetBytes(out, path+".delta.content", repaired)
+		}
+		finishReason := buffered.finishReason
+		if finishReason == "" {
+			finishReason = "stop"
+			if truncated {
+				finishReason = "length"
+			}
+		}
+		out, _ = sjson.SetBytes(out, path+".finish_reason", finishReason)
+	}
+	if s.usage.Exists() {
+		out, _ = sjson.SetRawBytes(out, "usage", []byte(s.usage.Raw))
+	}
+	return out, nil
+}
+
+// handleStructuredStreamResult buffers a structured-output stream and sends it as one final
+// chunk once the upstream finishes or fails. Keepalives continue while buffering, since the
+// client sees no data until the end.
+func (h *OpenAIAPIHandler) handleStructuredStreamResult(c *gin.Context, flusher http.Flusher, cancel func(error), format string, data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
+	keepAlive := h.NewStreamKeepAlive()
+	defer keepAlive.Stop()
+	stream := &structuredStream{format: format}
+	for {
+		var errMsg *interfaces.ErrorMessage
+		select {
+		case <-c.Request.Context().Done():
+			cancel(c.Request.Context().Err())
+			return
+		case <-keepAlive.C():
+			_, _ = c.Writer.Write([]byte(handlers.KeepAliveComment))
+			flusher.Flush()
+			continue
+		case chunk, ok := <-data:
+			if ok {
+				stream.add(chunk)
+				continue
+			}
+			// A failed stream queues its error before the data channel closes.
+			select {
+			case errMsg = <-errs:
+			default:
+			}
+		case msg, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			errMsg = msg
+		}
+		h.writeStructuredStream(c, flusher, stream, errMsg)
+		var execErr error
+		if errMsg != nil {
+			execErr = errMsg.Error
+		}
+		cancel(execErr)
+		return
+	}
+}
+
+// writeStructuredStream sends the outcome of a buffered stream. Output cut short by
+// upstreamErr is repaired when possible; when the content cannot be made valid JSON the client
+// receives upstreamErr, or a 502 when the stream itself completed.
+func (h *OpenAIAPIHandler) writeStructuredStream(c *gin.Context, flusher http.Flusher, stream *structuredStream, upstreamErr *interfaces.ErrorMessage) {
+	defer flusher.Flush()
+	if stream.toolCalls {
+		for _, chunk := range stream.chunks {
+			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunk))
+		}
+	}
+	if stream.first != nil && !stream.toolCalls {
+		final, err := stream.final(upstreamErr != nil)
+		if err != nil {
+			if upstreamErr == nil {
+				upstreamErr = &interfaces.ErrorMessage{StatusCode: http.StatusBadGateway, Error: err}
+			}
+			h.WriteErrorResponse(c, upstreamErr)
+			return
+		}
+		if upstreamErr != nil {
+			log.Warnf("structured output stream for model %s ended early (%v); sending repaired JSON", gjson.GetBytes(stream.first, "model").String(), upstreamErr.Error)
+			upstreamErr = nil
+		}
+		_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(final))
+	}
+	if upstreamErr != nil {
+		h.WriteErrorResponse(c, upstreamErr)
+		return
+	}
+	_, _ = fmt.Fprintf(c.Writer, "data: [DONE]\n\n")
+}
+
+// repairJSON returns text as valid JSON: unchanged when it already parses, without a markdown
+// code fence, or with the strings and brackets left open by truncation closed. It reports
+// false for content that is not a JSON prefix.
+func repairJSON(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if gjson.Valid(text) {
+		return text, true
+	}
+	if unwrapped := stripJSONCodeFence(text); gjson.Valid(unwrapped) {
+		return unwrapped, true
+	}
+	if body, ok := strings.CutPrefix(text, "```"); ok {
+		// A fence that was opened but, being truncated, never closed.
+		if newline := strings.IndexByte(body, '\n'); newline >= 0 && !strings.ContainsAny(body[:newline], "{[\"") {
+			body = body[newline+1:]
+		}
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(body), "```"))
+	}
+	return closeTruncatedJSON(text)
+}
+
+// closeTruncatedJSON completes a JSON document cut off mid-way by closing an open string and
+// the open objects and arrays. When the last value is itself incomplete (a half-written key or
+// literal), the document is cut back to the last comma or opening bracket before closing it.
+func closeTruncatedJSON(text string) (string, bool) {
+	var (
+		stack             []byte
+		inString, escaped bool
+		cut               = -1
+		cutClosers        string
+	)
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, ch)
+			cut, cutClosers = i+1, jsonClosers(stack)
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != ch-2 {
+				// '{' and '[' are two code points below their closers.
+				return "", false
+			}
+			stack = stack[:len(stack)-1]
+		case ',':
+			cut, cutClosers = i, jsonClosers(stack)
+		}
+	}
+	if len(stack) == 0 && !inString {
+		return text, gjson.Valid(text)
+	}
+
+	completed := text
+	if inString {
+		if escaped {
+			completed = completed[:len(completed)-1]
+		}
+		completed += `"`
+	}
+	completed = strings.TrimSuffix(strings.TrimSpace(completed), ",")
+	if strings.HasSuffix(completed, ":") {
+		completed += "null"
+	}
+	completed += jsonClosers(stack)
+	if gjson.Valid(completed) {
+		return completed, true
+	}
+	if cut >= 0 {
+		if candidate := strings.TrimSpace(text[:cut]) + cutClosers; gjson.Valid(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// jsonClosers returns the brackets closing stack, innermost first.
+func jsonClosers(stack []byte) string {
+	closers := make([]byte, len(stack))
+	for i, open := range stack {
+		closers[len(stack)-1-i] = open + 2
+	}
+	return string(closers)
+}
@@ -0,0 +1,155 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// jsonStreamExecutor streams content in pieces and, when failAfter is set, fails instead of
+// finishing, like an upstream connection dropped mid-answer.
+type jsonStreamExecutor struct {
+	pieces    []string
+	failAfter bool
+}
+
+func (e jsonStreamExecutor) Identifier() string { return "json-stream-test" }
+
+func (e jsonStreamExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e jsonStreamExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	out := make(chan coreexecutor.StreamChunk, len(e.pieces)+1)
+	for i, piece := range e.pieces {
+		finish := "null"
+		if i == len(e.pieces)-1 && !e.failAfter {
+			finish = `"stop"`
+		}
+		content := strings.ReplaceAll(piece, `"`, `\"`)
+		out <- coreexecutor.StreamChunk{Payload: []byte(`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"json-model","choices":[{"index":0,"delta":{"content":"` + content + `"},"finish_reason":` + finish + `}]}`)}
+	}
+	if e.failAfter {
+		out <- coreexecutor.StreamChunk{Err: errors.New("upstream connection reset")}
+	}
+	close(out)
+	return out, nil
+}
+
+func (e jsonStreamExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e jsonStreamExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func streamStructured(t *testing.T, exec jsonStreamExecutor, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	h := newOpenAITestHandler(t, &config.SDKConfig{StructuredOutputStreamRepair: true}, "json-stream-test", []*registry.ModelInfo{{ID: "json-model"}}, exec)
+	router := gin.New()
+	router.POST("/v1/chat/completions", h.ChatCompletions)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	return w
+}
+
+// dataEvents returns the payloads of the SSE data lines in body.
+func dataEvents(body string) []string {
+	var events []string
+	for _, line := range strings.Split(body, "\n") {
+		if payload, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, payload)
+		}
+	}
+	return events
+}
+
+const structuredStreamRequest = `{"model":"json-model","stream":true,"response_format":{"type":"json_object"},"messages":[{"role":"user","content":"user as JSON"}]}`
+
+func TestChatCompletions_StructuredStreamSentAsOneChunk(t *testing.T) {
+	w := streamStructured(t, jsonStreamExecutor{pieces: []string{`{"name":`, `"Ada","tags":["a",`, `"b"]}`}}, structuredStreamRequest)
+
+	events := dataEvents(w.Body.String())
+	if len(events) != 2 || events[1] != "[DONE]" {
+		t.Fatalf("Expected one final chunk followed by [DONE], got %q", w.Body.String())
+	}
+	choice := gjson.Get(events[0], "choices.0")
+	if got := choice.Get("delta.content").String(); got != `{"name":"Ada","tags":["a","b"]}` {
+		t.Fatalf("Expected the complete JSON content, got %q", got)
+	}
+	if choice.Get("finish_reason").String() != "stop" || gjson.Get(events[0], "id").String() != "chatcmpl-1" {
+		t.Fatalf("Expected the upstream id and finish_reason to be kept, got %s", events[0])
+	}
+}
+
+func TestChatCompletions_StructuredStreamTruncatedIsRepaired(t *testing.T) {
+	w := streamStructured(t, jsonStreamExecutor{pieces: []string{`{"user":{"name":"Ada",`, `"tags":["a","b`}, failAfter: true}, structuredStreamRequest)
+
+	events := dataEvents(w.Body.String())
+	if len(events) != 2 || events[1] != "[DONE]" {
+		t.Fatalf("Expected one repaired chunk followed by [DONE], got %q", w.Body.String())
+	}
+	choice := gjson.Get(events[0], "choices.0")
+	if got := choice.Get("delta.content").String(); got != `{"user":{"name":"Ada","tags":["a","b"]}}` {
+		t.Fatalf("Expected the truncated JSON to be closed, got %q", got)
+	}
+	if choice.Get("finish_reason").String() != "length" {
+		t.Fatalf("Expected finish_reason length for truncated output, got %s", events[0])
+	}
+}
+
+func TestChatCompletions_StructuredStreamInvalidContentFails(t *testing.T) {
+	w := streamStructured(t, jsonStreamExecutor{pieces: []string{"Sure! ", "Here is the user."}}, structuredStreamRequest)
+
+	if w.Code != http.StatusBadGateway || len(dataEvents(w.Body.String())) != 0 {
+		t.Fatalf("Expected a 502 without data chunks, got %d: %q", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "non-JSON content") {
+		t.Fatalf("Expected the error to explain the failure, got %q", w.Body.String())
+	}
+}
+
+func TestChatCompletions_StructuredStreamRequiresResponseFormat(t *testing.T) {
+	w := streamStructured(t, jsonStreamExecutor{pieces: []string{`{"a":`, `1}`}}, `{"model":"json-model","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+
+	if events := dataEvents(w.Body.String()); len(events) != 3 {
+		t.Fatalf("Expected chunks to stream unchanged without response_format, got %q", w.Body.String())
+	}
+}
+
+func TestRepairJSON(t *testing.T) {
+	cases := map[string]string{
+		`{"a":1}`:                   `{"a":1}`,
+		"```json\n{\"a\":1}\n```":   `{"a":1}`,
+		"```json\n{\"a\":[1,2":      `{"a":[1,2]}`,
+		`{"a":"hel`:                 `{"a":"hel"}`,
+		`{"a":"esc\`:                `{"a":"esc"}`,
+		`{"a":1,`:                   `{"a":1}`,
+		`{"a":`:                     `{"a":null}`,
+		`{"a":1,"b`:                 `{"a":1}`,
+		`{"a":{"b":tr`:              `{"a":{}}`,
+		`[{"id":1},{"id":2},{"id":`: `[{"id":1},{"id":2},{"id":null}]`,
+		`{"text":"} and ]","b":`:    `{"text":"} and ]","b":null}`,
+	}
+	for input, want := range cases {
+		if got, ok := repairJSON(input); !ok || got != want {
+			t.Errorf("repairJSON(%q) = %q, %t; want %q", input, got, ok, want)
+		}
+	}
+	for _, input := range []string{"Sure! Here it is.", `{"a":1]`, `{"a":1}}`} {
+		if got, ok := repairJSON(input); ok {
+			t.Errorf("Expected repairJSON(%q) to fail, got %q", input, got)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// SamplingProfileHeader names the sampling profile to expand into a request.
+	SamplingProfileHeader = "X-Sampling-Profile"
+	// samplingProfileField is the request body alternative to SamplingProfileHeader.
+	samplingProfileField = "sampling_profile"
+)
+
+// samplingPaths locates the sampling parameters in a request of one source format. An empty
+// path means the format has no such parameter.
+type samplingPaths struct {
+	temperature string
+	topP        string
+	topK        string
+}
+
+var samplingPathsByFormat = map[string]samplingPaths{
+	constant.OpenAI:         {temperature: "temperature", topP: "top_p"},
+	constant.OpenaiResponse: {temperature: "temperature", topP: "top_p"},
+	constant.Claude:         {temperature: "temperature", topP: "top_p", topK: "top_k"},
+	constant.Gemini:         {temperature: "generationConfig.temperature", topP: "generationConfig.topP", topK: "generationConfig.topK"},
+	constant.GeminiCLI:      {temperature: "request.generationConfig.temperature", topP: "request.generationConfig.topP", topK: "request.generationConfig.topK"},
+}
+
+// applySamplingProfile expands the sampling profile named by SamplingProfileHeader or the
+// sampling_profile request field into the parameters the request omits, so explicit client
+// values win. The field is always removed before the request is forwarded. An unknown
+// profile is rejected with 400.
+func applySamplingProfile(ctx context.Context, handlerType, model string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	var name string
+	if field := gjson.GetBytes(rawJSON, samplingProfileField); field.Exists() {
+		name = strings.TrimSpace(field.String())
+		rawJSON, _ = sjson.DeleteBytes(rawJSON, samplingProfileField)
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+		if header := strings.TrimSpace(ginCtx.GetHeader(SamplingProfileHeader)); header != "" && name == "" {
+			name = header
+		}
+	}
+	if name == "" {
+		return rawJSON, nil
+	}
+	params := readSamplingParams(handlerType, rawJSON)
+	provided := params
+	if !util.ApplySamplingProfile(model, name, &params) {
+		return nil, &interfaces.ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Errorf("unknown sampling profile %q", name),
+		}
+	}
+	return writeSamplingParams(handlerType, rawJSON, provided, params), nil
+}
+
+// applyModelParamDefaults writes the configured sampling defaults for model into a request
+// that omits them, before it is translated for the upstream. Parameters present in the
+// request, including explicit nulls, are left alone.
+func applyModelParamDefaults(handlerType, model string, rawJSON []byte) []byte {
+	if _, ok := samplingPathsByFormat[handlerType]; !ok {
+		return rawJSON
+	}
+	params := readSamplingParams(handlerType, rawJSON)
+	provided := params
+	util.ApplyModelParamDefaults(model, &params)
+	return writeSamplingParams(handlerType, rawJSON, provided, params)
+}
+
+// readSamplingParams returns the sampling parameters present in a request.
+func readSamplingParams(handlerType string, rawJSON []byte) util.Params {
+	paths := samplingPathsByFormat[handlerType]
+	var params util.Params
+	if paths.temperature == "" {
+		return params
+	}
+	if t := gjson.GetBytes(rawJSON, paths.temperature); t.Exists() {
+		value := t.Float()
+		params.Temperature = &value
+	}
+	if p := gjson.GetBytes(rawJSON, paths.topP); p.Exists() {
+		value := p.Float()
+		params.TopP = &value
+	}
+	if paths.topK != "" {
+		if k := gjson.GetBytes(rawJSON, paths.topK); k.Exists() {
+			value := k.Int()
+			params.TopK = &value
+		}
+	}
+	return params
+}
+
+// writeSamplingParams sets the parameters of params that provided lacks.
+func writeSamplingParams(handlerType string, rawJSON []byte, provided, params util.Params) []byte {
+	paths, ok := samplingPathsByFormat[handlerType]
+	if !ok {
+		return rawJSON
+	}
+	out := rawJSON
+	if provided.Temperature == nil && params.Temperature != nil {
+		out, _ = sjson.SetBytes(out, paths.temperature, *params.Temperature)
+	}
+	if provided.TopP == nil && params.TopP != nil {
+		out, _ = sjson.SetBytes(out, paths.topP, *params.TopP)
+	}
+	if paths.topK != "" && provided.TopK == nil && params.TopK != nil {
+		out, _ = sjson.SetBytes(out, paths.topK, *params.TopK)
+	}
+	return out
+}
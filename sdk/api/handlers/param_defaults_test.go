@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyModelParamDefaults_WritesOnlyOmittedParameters(t *testing.T) {
+	temperature, topP, topK := 0.6, 0.8, int64(32)
+	util.SetModelParamDefaults(map[string]config.ModelParamDefaults{
+		"defaults-model": {Temperature: &temperature, TopP: &topP, TopK: &topK},
+	})
+	t.Cleanup(func() { util.SetModelParamDefaults(nil) })
+
+	out := applyModelParamDefaults("openai", "defaults-model", []byte(`{"model":"defaults-model","temperature":1.2}`))
+	if gjson.GetBytes(out, "temperature").Float() != 1.2 || gjson.GetBytes(out, "top_p").Float() != 0.8 || gjson.GetBytes(out, "top_k").Exists() {
+		t.Fatalf("Unexpected OpenAI request: %s", out)
+	}
+
+	out = applyModelParamDefaults("gemini", "defaults-model", []byte(`{"contents":[],"generationConfig":{"topK":5}}`))
+	if gjson.GetBytes(out, "generationConfig.temperature").Float() != 0.6 || gjson.GetBytes(out, "generationConfig.topP").Float() != 0.8 || gjson.GetBytes(out, "generationConfig.topK").Int() != 5 {
+		t.Fatalf("Unexpected Gemini request: %s", out)
+	}
+
+	body := `{"model":"other","messages":[]}`
+	if out = applyModelParamDefaults("claude", "other", []byte(body)); string(out) != body {
+		t.Fatalf("Expected an unconfigured model to be left alone, got %s", out)
+	}
+}
+
+func TestApplySamplingProfile_ExpandsProfile(t *testing.T) {
+	temperature, topP, topK := 0.2, 0.5, int64(10)
+	util.SetSamplingProfiles(map[string]config.ModelParamDefaults{
+		"Precise": {Temperature: &temperature, TopP: &topP, TopK: &topK},
+	})
+	t.Cleanup(func() { util.SetSamplingProfiles(nil) })
+
+	out, errMsg := applySamplingProfile(context.Background(), "claude", "profile-model", []byte(`{"model":"profile-model","sampling_profile":"precise","messages":[]}`))
+	if errMsg != nil {
+		t.Fatalf("Unexpected error: %v", errMsg.Error)
+	}
+	if gjson.GetBytes(out, "temperature").Float() != 0.2 || gjson.GetBytes(out, "top_p").Float() != 0.5 || gjson.GetBytes(out, "top_k").Int() != 10 {
+		t.Fatalf("Expected the profile parameters, got %s", out)
+	}
+	if gjson.GetBytes(out, "sampling_profile").Exists() {
+		t.Fatalf("Expected the profile field to be removed, got %s", out)
+	}
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Request.Header.Set(SamplingProfileHeader, "precise")
+	ctx := context.WithValue(context.Background(), "gin", c)
+	out, errMsg = applySamplingProfile(ctx, "gemini", "profile-model", []byte(`{"contents":[]}`))
+	if errMsg != nil || gjson.GetBytes(out, "generationConfig.temperature").Float() != 0.2 {
+		t.Fatalf("Expected the header to select the profile, got %s (%v)", out, errMsg)
+	}
+
+	_, errMsg = applySamplingProfile(context.Background(), "openai", "profile-model", []byte(`{"model":"profile-model","sampling_profile":"wild"}`))
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an unknown profile, got %v", errMsg)
+	}
+}
+
+func TestApplySamplingProfile_ClientParametersWin(t *testing.T) {
+	profileTemperature, profileTopP := 1.0, 0.95
+	util.SetSamplingProfiles(map[string]config.ModelParamDefaults{
+		"creative": {Temperature: &profileTemperature, TopP: &profileTopP},
+	})
+	defaultTemperature, defaultTopP, defaultTopK := 0.6, 0.8, int64(32)
+	util.SetModelParamDefaults(map[string]config.ModelParamDefaults{
+		"profile-model": {Temperature: &defaultTemperature, TopP: &defaultTopP, TopK: &defaultTopK},
+	})
+	t.Cleanup(func() {
+		util.SetSamplingProfiles(nil)
+		util.SetModelParamDefaults(nil)
+	})
+
+	out, errMsg := applySamplingProfile(context.Background(), "claude", "profile-model", []byte(`{"model":"profile-model","sampling_profile":"creative","temperature":0.3}`))
+	if errMsg != nil {
+		t.Fatalf("Unexpected error: %v", errMsg.Error)
+	}
+	out = applyModelParamDefaults("claude", "profile-model", out)
+	if got := gjson.GetBytes(out, "temperature").Float(); got != 0.3 {
+		t.Errorf("Expected the client temperature to win, got %v", got)
+	}
+	if got := gjson.GetBytes(out, "top_p").Float(); got != 0.95 {
+		t.Errorf("Expected the profile top_p over the model default, got %v", got)
+	}
+	if got := gjson.GetBytes(out, "top_k").Int(); got != 32 {
+		t.Errorf("Expected the model default to fill top_k, got %v", got)
+	}
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+)
+
+// ErrorCode is a stable, provider independent identifier for a failed request. Clients can
+// branch on it without knowing which upstream served the request.
+type ErrorCode string
+
+const (
+	ErrorCodeRateLimited         ErrorCode = "rate_limited"
+	ErrorCodeQuotaExhausted      ErrorCode = "quota_exhausted"
+	ErrorCodeContextExceeded     ErrorCode = "context_exceeded"
+	ErrorCodeInvalidRequest      ErrorCode = "invalid_request"
+	ErrorCodeAuthFailed          ErrorCode = "auth_failed"
+	ErrorCodePermissionDenied    ErrorCode = "permission_denied"
+	ErrorCodeNotFound            ErrorCode = "not_found"
+	ErrorCodeContentFiltered     ErrorCode = "content_filtered"
+	ErrorCodeUpstreamUnavailable ErrorCode = "upstream_unavailable"
+	ErrorCodeTimeout             ErrorCode = "timeout"
+	ErrorCodeCancelled           ErrorCode = "cancelled"
+	ErrorCodeInternal            ErrorCode = "internal_error"
+)
+
+// errorTypes maps each code to the error type reported alongside it. The values are shared by
+// the OpenAI and Claude error formats, so existing clients keep recognising them.
+var errorTypes = map[ErrorCode]string{
+	ErrorCodeRateLimited:         "rate_limit_error",
+	ErrorCodeQuotaExhausted:      "rate_limit_error",
+	ErrorCodeContextExceeded:     "invalid_request_error",
+	ErrorCodeInvalidRequest:      "invalid_request_error",
+	ErrorCodeAuthFailed:          "authentication_error",
+	ErrorCodePermissionDenied:    "permission_error",
+	ErrorCodeNotFound:            "not_found_error",
+	ErrorCodeContentFiltered:     "invalid_request_error",
+	ErrorCodeUpstreamUnavailable: "overloaded_error",
+	ErrorCodeTimeout:             "api_error",
+	ErrorCodeCancelled:           "api_error",
+	ErrorCodeInternal:            "api_error",
+}
+
+// ProxyError is the normalised form of an error returned to clients. Upstream errors of every
+// provider are mapped onto a Code, while the original upstream body is kept in Detail.
+type ProxyError struct {
+	// Code is the stable error code.
+	Code ErrorCode
+	// StatusCode is the HTTP status returned to the client.
+	StatusCode int
+	// Message is a human readable description of the failure.
+	Message string
+	// Detail holds the original upstream error: its JSON body when it was JSON, else its text.
+	Detail json.RawMessage
+}
+
+// proxyErrorBody is the wire form of a ProxyError. The top-level "type" and the nested
+// "type"/"message" fields keep the body readable by OpenAI and Claude SDKs alike.
+type proxyErrorBody struct {
+	Type  string          `json:"type"`
+	Error proxyErrorField `json:"error"`
+}
+
+type proxyErrorField struct {
+	Type    string          `json:"type"`
+	Code    ErrorCode       `json:"code"`
+	Message string          `json:"message"`
+	Detail  json.RawMessage `json:"detail,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *ProxyError) Error() string {
+	if e == nil {
+		return ""
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+// Body renders the error in the response format shared by all endpoints.
+func (e *ProxyError) Body() []byte {
+	errorType, ok := errorTypes[e.Code]
+	if !ok {
+		errorType = "api_error"
+	}
+	body, _ := json.Marshal(proxyErrorBody{
+		Type:  "error",
+		Error: proxyErrorField{Type: errorType, Code: e.Code, Message: e.Message, Detail: e.Detail},
+	})
+	return body
+}
+
+// NewProxyError maps msg onto the error taxonomy. The upstream body is inspected for the
+// Gemini ({"error":{"status":...}}), Claude ({"error":{"type":...}}) and OpenAI
+// ({"error":{"code":...}}) error shapes; anything else is classified by HTTP status.
+func NewProxyError(msg *interfaces.ErrorMessage) *ProxyError {
+	status := http.StatusInternalServerError
+	if msg != nil && msg.StatusCode > 0 {
+		status = msg.StatusCode
+	}
+	if msg == nil || msg.Error == nil {
+		return &ProxyError{Code: errorCodeForStatus(status), StatusCode: status, Message: http.StatusText(status)}
+	}
+	var proxyErr *ProxyError
+	if errors.As(msg.Error, &proxyErr) {
+		return proxyErr
+	}
+
+	text := msg.Error.Error()
+	result := &ProxyError{StatusCode: status, Message: text}
+	var authErr *coreauth.Error
+	if errors.As(msg.Error, &authErr) {
+		result.Message = authErr.Message
+		result.Code = errorCodeForManager(authErr.Code)
+	}
+	if raw := strings.TrimSpace(text); raw != "" && gjson.Valid(raw) {
+		result.Detail = json.RawMessage(raw)
+		upstream := gjson.Get(raw, "error")
+		if message := upstream.Get("message"); message.Exists() {
+			result.Message = message.String()
+		} else if upstream.Type == gjson.String {
+			result.Message = upstream.String()
+		}
+		if result.Code == "" {
+			result.Code = errorCodeForUpstream(upstream, result.Message)
+		}
+	} else if text != "" {
+		result.Detail, _ = json.Marshal(text)
+	}
+	if result.Code == "" {
+		switch {
+		case errors.Is(msg.Error, context.DeadlineExceeded):
+			result.Code = ErrorCodeTimeout
+		case errors.Is(msg.Error, context.Canceled):
+			result.Code = ErrorCodeCancelled
+		case isContextExceededMessage(result.Message):
+			result.Code = ErrorCodeContextExceeded
+		default:
+			result.Code = errorCodeForStatus(status)
+		}
+	}
+	return result
+}
+
+// errorCodeForManager maps the codes of errors raised by the auth manager itself.
+func errorCodeForManager(code string) ErrorCode {
+	switch code {
+	case "quota_exhausted":
+		return ErrorCodeQuotaExhausted
+	case "concurrency_limited":
+		return ErrorCodeRateLimited
+	case "auth_not_found", "auth_unavailable", "circuit_open":
+		return ErrorCodeUpstreamUnavailable
+	case "provider_not_found", "executor_not_found":
+		return ErrorCodeNotFound
+	}
+	return ""
+}
+
+// errorCodeForUpstream maps the "error" object of an upstream body. It returns "" when the
+// object carries nothing recognisable, so the HTTP status decides.
+func errorCodeForUpstream(upstream gjson.Result, message string) ErrorCode {
+	if isContextExceededMessage(message) {
+		return ErrorCodeContextExceeded
+	}
+	// Gemini: google.rpc status names.
+	switch upstream.Get("status").String() {
+	case "RESOURCE_EXHAUSTED":
+		return ErrorCodeRateLimited
+	case "INVALID_ARGUMENT", "FAILED_PRECONDITION", "OUT_OF_RANGE":
+		return ErrorCodeInvalidRequest
+	case "UNAUTHENTICATED":
+		return ErrorCodeAuthFailed
+	case "PERMISSION_DENIED":
+		return ErrorCodePermissionDenied
+	case "NOT_FOUND":
+		return ErrorCodeNotFound
+	case "UNAVAILABLE", "INTERNAL":
+		return ErrorCodeUpstreamUnavailable
+	case "DEADLINE_EXCEEDED":
+		return ErrorCodeTimeout
+	}
+	// OpenAI: string error codes.
+	if code := upstream.Get("code"); code.Type == gjson.String {
+		switch code.String() {
+		case "context_length_exceeded", "string_above_max_length":
+			return ErrorCodeContextExceeded
+		case "rate_limit_exceeded":
+			return ErrorCodeRateLimited
+		case "insufficient_quota":
+			return ErrorCodeQuotaExhausted
+		case "invalid_api_key", "invalid_authentication":
+			return ErrorCodeAuthFailed
+		case "content_filter", "content_policy_violation":
+			return ErrorCodeContentFiltered
+		case "model_not_found":
+			return ErrorCodeNotFound
+		}
+	}
+	// Claude and OpenAI: error types.
+	switch upstream.Get("type").String() {
+	case "rate_limit_error", "tokens":
+		return ErrorCodeRateLimited
+	case "insufficient_quota":
+		return ErrorCodeQuotaExhausted
+	case "request_too_large":
+		return ErrorCodeContextExceeded
+	case "invalid_request_error":
+		return ErrorCodeInvalidRequest
+	case "authentication_error":
+		return ErrorCodeAuthFailed
+	case "permission_error":
+		return ErrorCodePermissionDenied
+	case "not_found_error":
+		return ErrorCodeNotFound
+	case "overloaded_error", "api_error":
+		return ErrorCodeUpstreamUnavailable
+	}
+	return ""
+}
+
+// contextExceededMarkers are fragments of the messages providers return when the prompt does
+// not fit the model's context window.
+var contextExceededMarkers = []string{
+	"prompt is too long",
+	"context window",
+	"context length",
+	"maximum context",
+	"input token count",
+	"exceeds the maximum number of tokens",
+}
+
+func isContextExceededMessage(message string) bool {
+	lower := strings.ToLower(message)
+	for _, marker := range contextExceededMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func errorCodeForStatus(status int) ErrorCode {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrorCodeRateLimited
+	case status == http.StatusUnauthorized:
+		return ErrorCodeAuthFailed
+	case status == http.StatusForbidden:
+		return ErrorCodePermissionDenied
+	case status == http.StatusNotFound:
+		return ErrorCodeNotFound
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrorCodeContextExceeded
+	case status == http.StatusRequestTimeout, status == http.StatusGatewayTimeout:
+		return ErrorCodeTimeout
+	case status == 499:
+		return ErrorCodeCancelled
+	case status >= 400 && status < 500:
+		return ErrorCodeInvalidRequest
+	case status == http.StatusBadGateway, status == http.StatusServiceUnavailable, status == 529:
+		return ErrorCodeUpstreamUnavailable
+	}
+	return ErrorCodeInternal
+}
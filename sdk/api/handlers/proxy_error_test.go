@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+)
+
+func TestNewProxyError_MapsUpstreamPayloads(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		body    string
+		code    ErrorCode
+		message string
+	}{
+		{
+			name:    "gemini rate limit",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":{"code":429,"message":"Resource has been exhausted (e.g. check quota).","status":"RESOURCE_EXHAUSTED"}}`,
+			code:    ErrorCodeRateLimited,
+			message: "Resource has been exhausted (e.g. check quota).",
+		},
+		{
+			name:   "gemini context window",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"code":400,"message":"The input token count (1200000) exceeds the maximum number of tokens allowed (1048576).","status":"INVALID_ARGUMENT"}}`,
+			code:   ErrorCodeContextExceeded,
+		},
+		{
+			name:   "gemini invalid argument",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"code":400,"message":"Invalid JSON payload received.","status":"INVALID_ARGUMENT"}}`,
+			code:   ErrorCodeInvalidRequest,
+		},
+		{
+			name:   "gemini unauthenticated",
+			status: http.StatusUnauthorized,
+			body:   `{"error":{"code":401,"message":"Request had invalid authentication credentials.","status":"UNAUTHENTICATED"}}`,
+			code:   ErrorCodeAuthFailed,
+		},
+		{
+			name:   "gemini unavailable",
+			status: http.StatusServiceUnavailable,
+			body:   `{"error":{"code":503,"message":"The model is overloaded.","status":"UNAVAILABLE"}}`,
+			code:   ErrorCodeUpstreamUnavailable,
+		},
+		{
+			name:    "claude rate limit",
+			status:  http.StatusTooManyRequests,
+			body:    `{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your per-minute rate limit"}}`,
+			code:    ErrorCodeRateLimited,
+			message: "Number of request tokens has exceeded your per-minute rate limit",
+		},
+		{
+			name:   "claude prompt too long",
+			status: http.StatusBadRequest,
+			body:   `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 215000 tokens > 200000 maximum"}}`,
+			code:   ErrorCodeContextExceeded,
+		},
+		{
+			name:   "claude authentication",
+			status: http.StatusUnauthorized,
+			body:   `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			code:   ErrorCodeAuthFailed,
+		},
+		{
+			name:   "claude overloaded",
+			status: 529,
+			body:   `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			code:   ErrorCodeUpstreamUnavailable,
+		},
+		{
+			name:   "openai content filter",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"message":"The response was filtered","type":"invalid_request_error","code":"content_filter"}}`,
+			code:   ErrorCodeContentFiltered,
+		},
+		{
+			name:   "plain text",
+			status: http.StatusBadGateway,
+			body:   "upstream connection reset",
+			code:   ErrorCodeUpstreamUnavailable,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			proxyErr := NewProxyError(&interfaces.ErrorMessage{StatusCode: tc.status, Error: errors.New(tc.body)})
+			if proxyErr.Code != tc.code || proxyErr.StatusCode != tc.status {
+				t.Fatalf("Expected %s with status %d, got %s with status %d", tc.code, tc.status, proxyErr.Code, proxyErr.StatusCode)
+			}
+			if tc.message != "" && proxyErr.Message != tc.message {
+				t.Fatalf("Expected upstream message %q, got %q", tc.message, proxyErr.Message)
+			}
+		})
+	}
+}
+
+func TestNewProxyError_ManagerAndContextErrors(t *testing.T) {
+	quota := NewProxyError(&interfaces.ErrorMessage{StatusCode: http.StatusTooManyRequests, Error: &coreauth.Error{Code: "quota_exhausted", Message: "all credentials exhausted their quota", HTTPStatus: http.StatusTooManyRequests}})
+	if quota.Code != ErrorCodeQuotaExhausted || quota.Message != "all credentials exhausted their quota" {
+		t.Fatalf("Expected quota_exhausted, got %+v", quota)
+	}
+	timeout := NewProxyError(&interfaces.ErrorMessage{StatusCode: http.StatusInternalServerError, Error: context.DeadlineExceeded})
+	if timeout.Code != ErrorCodeTimeout {
+		t.Fatalf("Expected timeout, got %s", timeout.Code)
+	}
+	local := NewProxyError(&interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: errors.New("request exceeds the context window of model m: estimated 15 tokens, allowed 10")})
+	if local.Code != ErrorCodeContextExceeded {
+		t.Fatalf("Expected context_exceeded, got %s", local.Code)
+	}
+}
+
+func TestWriteErrorResponse_RendersProxyError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := `{"error":{"code":429,"message":"Quota exceeded","status":"RESOURCE_EXHAUSTED"}}`
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	(&BaseAPIHandler{}).WriteErrorResponse(c, &interfaces.ErrorMessage{StatusCode: http.StatusTooManyRequests, Error: errors.New(upstream)})
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", recorder.Code)
+	}
+	body := gjson.Parse(recorder.Body.String())
+	if body.Get("type").String() != "error" || body.Get("error.code").String() != "rate_limited" || body.Get("error.type").String() != "rate_limit_error" {
+		t.Fatalf("Unexpected error body %s", recorder.Body.String())
+	}
+	if body.Get("error.message").String() != "Quota exceeded" || body.Get("error.detail").Raw != upstream {
+		t.Fatalf("Expected the upstream error to be preserved in detail, got %s", recorder.Body.String())
+	}
+}
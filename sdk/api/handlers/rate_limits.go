@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// rateLimitHeaders lists the normalized rate-limit headers reported to clients.
+var rateLimitHeaders = []string{util.RateLimitRemainingRequestsHeader, util.RateLimitRemainingTokensHeader, util.RateLimitResetHeader}
+
+// setRateLimitHeaders copies the rate-limit headers of the upstream response that served the
+// request, as recorded by the executor, onto the client response. When requests were retried
+// on other accounts only the last attempt counts, and headers the upstream did not send are
+// left out.
+func setRateLimitHeaders(ctx context.Context) {
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return
+	}
+	value, exists := ginCtx.Get(util.UpstreamRateLimitsKey)
+	if !exists {
+		return
+	}
+	headers, _ := value.(http.Header)
+	for _, name := range rateLimitHeaders {
+		ginCtx.Writer.Header().Del(name)
+		if v := headers.Get(name); v != "" {
+			ginCtx.Writer.Header().Set(name, v)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// openAIChatParameters is the default allowlist: the documented OpenAI chat completion
+// parameters plus the extensions the translators understand (top_k, image_config, extra_body).
+var openAIChatParameters = []string{
+	"model", "messages", "audio", "frequency_penalty", "function_call", "functions", "logit_bias",
+	"logprobs", "max_completion_tokens", "max_tokens", "metadata", "modalities", "n",
+	"parallel_tool_calls", "prediction", "presence_penalty", "prompt_cache_key", "reasoning_effort",
+	"response_format", "safety_identifier", "seed", "service_tier", "stop", "store", "stream",
+	"stream_options", "temperature", "tool_choice", "tools", "top_logprobs", "top_p", "user",
+	"verbosity", "web_search_options", "top_k", "image_config", "extra_body",
+}
+
+// filterRequestParameters applies the request-parameters policy to an OpenAI chat request.
+// Parameters outside the allowlist of every provider are dropped in "strip" mode and rejected
+// with 400 in "strict" mode; "passthrough", the default, forwards the request unchanged.
+func (h *BaseAPIHandler) filterRequestParameters(handlerType, modelName string, providers []string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	if handlerType != constant.OpenAI || h.Cfg == nil {
+		return rawJSON, nil
+	}
+	mode := strings.ToLower(strings.TrimSpace(h.Cfg.RequestParameters.Mode))
+	if mode != "strip" && mode != "strict" {
+		return rawJSON, nil
+	}
+	unsupported := h.unsupportedParameters(providers, rawJSON)
+	if len(unsupported) == 0 {
+		return rawJSON, nil
+	}
+	if mode == "strict" {
+		return nil, &interfaces.ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Errorf("unsupported parameters for model %s: %s", modelName, strings.Join(unsupported, ", ")),
+		}
+	}
+	for _, name := range unsupported {
+		rawJSON, _ = sjson.DeleteBytes(rawJSON, gjson.Escape(name))
+	}
+	log.Infof("request parameters: dropped %s unsupported for model %s (providers: %s)", strings.Join(unsupported, ", "), modelName, strings.Join(providers, ", "))
+	return rawJSON, nil
+}
+
+// unsupportedParameters returns, sorted, the top-level parameters of rawJSON that at least one
+// of providers does not accept, so the remaining request is valid for whichever is selected.
+func (h *BaseAPIHandler) unsupportedParameters(providers []string, rawJSON []byte) []string {
+	root := gjson.ParseBytes(rawJSON)
+	if !root.IsObject() {
+		return nil
+	}
+	allowlists := make([]map[string]bool, 0, len(providers))
+	for _, provider := range providers {
+		allowlists = append(allowlists, h.parameterAllowlist(provider))
+	}
+	var unsupported []string
+	root.ForEach(func(key, _ gjson.Result) bool {
+		name := key.String()
+		for _, allowed := range allowlists {
+			if !allowed[name] {
+				unsupported = append(unsupported, name)
+				break
+			}
+		}
+		return true
+	})
+	sort.Strings(unsupported)
+	return unsupported
+}
+
+// parameterAllowlist returns the parameters provider accepts: its configured list, or the
+// built-in OpenAI chat parameters when it has none.
+func (h *BaseAPIHandler) parameterAllowlist(provider string) map[string]bool {
+	names := openAIChatParameters
+	for _, entry := range h.Cfg.RequestParameters.Providers {
+		if strings.EqualFold(strings.TrimSpace(entry.Provider), provider) {
+			names = append([]string{"model", "messages"}, entry.Allow...)
+			break
+		}
+	}
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[strings.TrimSpace(name)] = true
+	}
+	return allowed
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type payloadCaptureExecutor struct {
+	payloads chan []byte
+}
+
+func (e *payloadCaptureExecutor) Identifier() string { return "params-test" }
+
+func (e *payloadCaptureExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	e.payloads <- req.Payload
+	return coreexecutor.Response{Payload: []byte(`{"ok":true}`)}, nil
+}
+
+func (e *payloadCaptureExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *payloadCaptureExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *payloadCaptureExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func executeWithParameters(t *testing.T, cfg *config.SDKConfig, body string) (*payloadCaptureExecutor, *BaseAPIHandler, []byte) {
+	t.Helper()
+	exec := &payloadCaptureExecutor{payloads: make(chan []byte, 1)}
+	manager := newHandlerTestManager(t, "params-test", []*registry.ModelInfo{{ID: "params-model"}}, exec)
+	h := &BaseAPIHandler{Cfg: cfg, AuthManager: manager}
+	ctx, _, _ := newHandlerTestContext(t, h, "/v1/chat/completions", nil)
+	_, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "params-model", []byte(body), "")
+	if errMsg != nil {
+		if errMsg.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Expected a 400 error, got %d: %v", errMsg.StatusCode, errMsg.Error)
+		}
+		return exec, h, []byte(errMsg.Error.Error())
+	}
+	return exec, h, <-exec.payloads
+}
+
+const experimentalRequest = `{"model":"params-model","messages":[{"role":"user","content":"hi"}],"temperature":0.2,"experimental_cache":{"ttl":60},"x.flag":true}`
+
+func TestFilterRequestParameters_StripMode(t *testing.T) {
+	_, _, payload := executeWithParameters(t, &config.SDKConfig{RequestParameters: config.RequestParametersConfig{Mode: "strip"}}, experimentalRequest)
+	if gjson.GetBytes(payload, "experimental_cache").Exists() || gjson.GetBytes(payload, `x\.flag`).Exists() {
+		t.Fatalf("Expected unknown parameters to be stripped, got %s", payload)
+	}
+	if gjson.GetBytes(payload, "temperature").Float() != 0.2 || gjson.GetBytes(payload, "messages.0.content").String() != "hi" {
+		t.Fatalf("Expected supported parameters to be kept, got %s", payload)
+	}
+}
+
+func TestFilterRequestParameters_StrictMode(t *testing.T) {
+	exec, _, message := executeWithParameters(t, &config.SDKConfig{RequestParameters: config.RequestParametersConfig{Mode: "strict"}}, experimentalRequest)
+	if got := string(message); got != "unsupported parameters for model params-model: experimental_cache, x.flag" {
+		t.Fatalf("Expected the offending fields to be listed, got %q", got)
+	}
+	if len(exec.payloads) != 0 {
+		t.Fatal("Expected the request not to reach the upstream")
+	}
+}
+
+func TestFilterRequestParameters_PassthroughByDefault(t *testing.T) {
+	_, _, payload := executeWithParameters(t, &config.SDKConfig{}, experimentalRequest)
+	if !gjson.GetBytes(payload, "experimental_cache").Exists() {
+		t.Fatalf("Expected parameters to pass through by default, got %s", payload)
+	}
+}
+
+func TestFilterRequestParameters_PerProviderAllowlist(t *testing.T) {
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{RequestParameters: config.RequestParametersConfig{
+		Mode:      "strict",
+		Providers: []config.ProviderParameters{{Provider: "strict-upstream", Allow: []string{"stream"}}},
+	}}}
+	body := []byte(`{"model":"m","messages":[],"stream":true,"temperature":1}`)
+
+	if _, errMsg := h.filterRequestParameters("openai", "m", []string{"openrouter"}, body); errMsg != nil {
+		t.Fatalf("Expected the built-in allowlist to accept temperature, got %v", errMsg.Error)
+	}
+	if got := h.unsupportedParameters([]string{"openrouter", "strict-upstream"}, body); !reflect.DeepEqual(got, []string{"temperature"}) {
+		t.Fatalf("Expected parameters any provider rejects to be reported, got %v", got)
+	}
+	if _, errMsg := h.filterRequestParameters("claude", "m", []string{"strict-upstream"}, body); errMsg != nil {
+		t.Fatalf("Expected non-OpenAI requests to be left alone, got %v", errMsg.Error)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+type recordTestExecutor struct {
+	err error
+}
+
+func (e *recordTestExecutor) Identifier() string { return "record-test" }
+
+func (e *recordTestExecutor) Execute(ctx context.Context, _ *coreauth.Auth, _ coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	if e.err != nil {
+		return coreexecutor.Response{}, e.err
+	}
+	// Real executors report usage through their usage reporter.
+	logging.RequestRecordFromContext(ctx).ObserveUsage(12, 5, 0, 0, 17)
+	return coreexecutor.Response{Payload: []byte(`{"ok":true}`)}, nil
+}
+
+func (e *recordTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *recordTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *recordTestExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+type recordTestHandler struct{}
+
+func (recordTestHandler) HandlerType() string      { return "openai" }
+func (recordTestHandler) Models() []map[string]any { return nil }
+
+func runRecordedRequest(t *testing.T, exec *recordTestExecutor) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	logging.SetRequestRecordLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { logging.SetRequestRecordLogger(nil) })
+
+	auth := &coreauth.Auth{ID: "record-auth", Provider: "record-test", Attributes: map[string]string{"api_key": "sk-secret"}}
+	manager := newHandlerTestManagerWithAuth(t, auth, []*registry.ModelInfo{{ID: "record-model"}}, exec)
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{}, AuthManager: manager}
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	ctx, cancel := h.GetContextWithCancel(recordTestHandler{}, c, context.Background())
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "record-model", []byte(`{"messages":[{"role":"user","content":"top secret prompt"}]}`), "")
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cancel(errMsg.Error)
+	} else {
+		_, _ = c.Writer.Write(resp)
+		cancel()
+	}
+
+	if bytes.Contains(buf.Bytes(), []byte("sk-secret")) || bytes.Contains(buf.Bytes(), []byte("top secret prompt")) {
+		t.Fatalf("Expected credentials and prompt content to be omitted, got %s", buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	return record
+}
+
+func TestRequestRecord_Success(t *testing.T) {
+	record := runRecordedRequest(t, &recordTestExecutor{})
+
+	want := map[string]any{
+		"msg":               "request completed",
+		"path":              "/v1/chat/completions",
+		"handler":           "openai",
+		"model":             "record-model",
+		"provider":          "record-test",
+		"auth_id":           "record-auth",
+		"attempts":          float64(1),
+		"retries":           float64(0),
+		"prompt_tokens":     float64(12),
+		"completion_tokens": float64(5),
+		"status":            float64(http.StatusOK),
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("Expected %s=%v, got %v (record %v)", key, value, record[key], record)
+		}
+	}
+	for _, key := range []string{"upstream_latency_ms", "duration_ms"} {
+		if _, ok := record[key]; !ok {
+			t.Errorf("Expected %s in record %v", key, record)
+		}
+	}
+	if _, ok := record["error"]; ok {
+		t.Errorf("Expected no error field on success, got %v", record)
+	}
+}
+
+func TestRequestRecord_Failure(t *testing.T) {
+	record := runRecordedRequest(t, &recordTestExecutor{err: &recordStatusError{code: http.StatusTooManyRequests}})
+
+	if record["level"] != "WARN" || record["status"] != float64(http.StatusTooManyRequests) {
+		t.Fatalf("Expected warn-level record with upstream status, got %v", record)
+	}
+	if record["error"] != "quota exhausted" || record["attempts"] != float64(1) || record["provider"] != "record-test" {
+		t.Fatalf("Expected failure details in record, got %v", record)
+	}
+}
+
+type recordStatusError struct{ code int }
+
+func (e *recordStatusError) Error() string   { return "quota exhausted" }
+func (e *recordStatusError) StatusCode() int { return e.code }
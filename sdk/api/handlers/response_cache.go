@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// CacheBypassHeader skips the response cache for a single request when set to a true value;
+	// the response is neither served from nor stored in the cache.
+	CacheBypassHeader = "X-Proxy-Cache-Bypass"
+	// CacheStatusHeader reports whether a cacheable request was a cache "hit" or "miss".
+	CacheStatusHeader = "X-Proxy-Cache"
+
+	defaultResponseCacheTTL        = 5 * time.Minute
+	defaultResponseCacheMaxEntries = 1000
+)
+
+type responseCacheContextKey struct{}
+
+// temperaturePaths locate the sampling temperature in the request formats served by the handlers.
+var temperaturePaths = []string{"temperature", "generationConfig.temperature", "request.generationConfig.temperature"}
+
+// responseCache is an LRU of non-streaming response payloads. Limits are passed on every call
+// so configuration reloads apply without rebuilding the cache.
+type responseCache struct {
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type responseCacheEntry struct {
+	key      string
+	payload  []byte
+	storedAt time.Time
+}
+
+// withResponseCache marks ctx for the response cache when it is enabled and the client did not
+// send CacheBypassHeader. The authenticated client identity is recorded so cached responses
+// are only served to the client that produced them.
+func (h *BaseAPIHandler) withResponseCache(ctx context.Context, c *gin.Context) context.Context {
+	if h.Cfg == nil || !h.Cfg.ResponseCache.Enabled {
+		return ctx
+	}
+	var client string
+	if c != nil {
+		if c.Request != nil {
+			if bypass, _ := strconv.ParseBool(strings.TrimSpace(c.GetHeader(CacheBypassHeader))); bypass {
+				return ctx
+			}
+		}
+		if value, exists := c.Get("apiKey"); exists {
+			client = fmt.Sprint(value)
+		}
+	}
+	return context.WithValue(ctx, responseCacheContextKey{}, client)
+}
+
+// WithoutResponseCache clears the mark set by withResponseCache, for callers that issue
+// deliberately identical requests expecting independent responses.
+func WithoutResponseCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(responseCacheContextKey{}).(string); !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, responseCacheContextKey{}, nil)
+}
+
+// responseCacheKey derives the cache key of a request marked by withResponseCache from the
+// model, the routing it was restricted to, the canonicalised prepared body (messages and
+// sampling parameters, including those expanded from a sampling profile or model defaults)
+// and the client. Dry runs and, when configured, requests sampling with a temperature above
+// zero are not cacheable.
+func (h *BaseAPIHandler) responseCacheKey(ctx context.Context, handlerType string, prepared preparedRequest, alt string) (string, bool) {
+	if ctx == nil || coreexecutor.IsDryRun(ctx) {
+		return "", false
+	}
+	client, ok := ctx.Value(responseCacheContextKey{}).(string)
+	if !ok {
+		return "", false
+	}
+	if h.Cfg != nil && h.Cfg.ResponseCache.SkipNondeterministic {
+		for _, path := range temperaturePaths {
+			if gjson.GetBytes(prepared.payload, path).Float() > 0 {
+				return "", false
+			}
+		}
+	}
+	hash := sha256.New()
+	for _, part := range []string{"response-cache", handlerType, prepared.model, alt, client, routingKey(prepared)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(canonicalJSON(prepared.payload))
+	return hex.EncodeToString(hash.Sum(nil)), true
+}
+
+// executeCached serves key from the response cache, calling execute and storing a successful
+// result on a miss. Hits are recorded on the request record so they still show up in metrics.
+func (h *BaseAPIHandler) executeCached(ctx context.Context, key string, execute func() ([]byte, *interfaces.ErrorMessage)) ([]byte, *interfaces.ErrorMessage) {
+	h.responseCacheOnce.Do(func() { h.responseCache = newResponseCache() })
+	ttl := defaultResponseCacheTTL
+	maxEntries := defaultResponseCacheMaxEntries
+	if h.Cfg != nil {
+		if h.Cfg.ResponseCache.TTLSeconds > 0 {
+			ttl = time.Duration(h.Cfg.ResponseCache.TTLSeconds) * time.Second
+		}
+		if h.Cfg.ResponseCache.MaxEntries > 0 {
+			maxEntries = h.Cfg.ResponseCache.MaxEntries
+		}
+	}
+	if payload, ok := h.responseCache.get(key, ttl, time.Now()); ok {
+		logging.RequestRecordFromContext(ctx).ObserveCacheHit()
+		setCacheStatusHeader(ctx, "hit")
+		return payload, nil
+	}
+	payload, errMsg := execute()
+	if errMsg == nil {
+		h.responseCache.put(key, payload, maxEntries, time.Now())
+		setCacheStatusHeader(ctx, "miss")
+	}
+	return payload, errMsg
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns a copy of the payload stored under key if it is younger than ttl.
+func (c *responseCache) get(key string, ttl time.Duration, now time.Time) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*responseCacheEntry)
+	if now.Sub(entry.storedAt) >= ttl {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return cloneBytes(entry.payload), true
+}
+
+// put stores a copy of payload under key, evicting the least recently used entries beyond
+// maxEntries.
+func (c *responseCache) put(key string, payload []byte, maxEntries int, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*responseCacheEntry)
+		entry.payload = cloneBytes(payload)
+		entry.storedAt = now
+		c.order.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.order.PushFront(&responseCacheEntry{key: key, payload: cloneBytes(payload), storedAt: now})
+	}
+	for c.order.Len() > maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseCacheEntry).key)
+	}
+}
+
+func setCacheStatusHeader(ctx context.Context, status string) {
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		ginCtx.Header(CacheStatusHeader, status)
+	}
+}
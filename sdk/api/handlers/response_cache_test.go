@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+type cacheTestExecutor struct {
+	calls atomic.Int32
+}
+
+func (e *cacheTestExecutor) Identifier() string { return "cache-test" }
+
+func (e *cacheTestExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	call := e.calls.Add(1)
+	return coreexecutor.Response{Payload: []byte(`{"call":` + strconv.Itoa(int(call)) + `}`)}, nil
+}
+
+func (e *cacheTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *cacheTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *cacheTestExecutor) CountTokens(ctx context.Context, auth *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+func newCacheTestHandler(t *testing.T, cfg *config.SDKConfig) (*BaseAPIHandler, *cacheTestExecutor) {
+	t.Helper()
+	exec := &cacheTestExecutor{}
+	manager := newHandlerTestManager(t, "cache-test", []*registry.ModelInfo{{ID: "cache-model"}}, exec)
+	return &BaseAPIHandler{Cfg: cfg, AuthManager: manager}, exec
+}
+
+// runCachedRequest executes body and returns the response, the X-Proxy-Cache header and the
+// request record snapshot.
+func runCachedRequest(t *testing.T, h *BaseAPIHandler, body string, headers map[string]string) (string, string, logging.RequestSnapshot) {
+	t.Helper()
+	ctx, _, recorder := newHandlerTestContext(t, h, "/v1/chat/completions", headers)
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "cache-model", []byte(body), "")
+	if errMsg != nil {
+		t.Fatalf("request failed: %v", errMsg.Error)
+	}
+	return string(resp), recorder.Header().Get(CacheStatusHeader), logging.RequestRecordFromContext(ctx).Snapshot()
+}
+
+const cacheTestBody = `{"model":"cache-model","messages":[{"role":"user","content":"same prompt"}],"temperature":0}`
+
+func TestResponseCache_HitAndMiss(t *testing.T) {
+	h, exec := newCacheTestHandler(t, &config.SDKConfig{ResponseCache: config.ResponseCacheConfig{Enabled: true}})
+
+	resp, status, snapshot := runCachedRequest(t, h, cacheTestBody, nil)
+	if resp != `{"call":1}` || status != "miss" || snapshot.CacheHit {
+		t.Fatalf("Expected first request to miss, got %s (status %q)", resp, status)
+	}
+	// Key order and whitespace do not affect the cache key.
+	resp, status, snapshot = runCachedRequest(t, h, `{"temperature":0, "messages":[{"content":"same prompt","role":"user"}], "model":"cache-model"}`, nil)
+	if resp != `{"call":1}` || status != "hit" || !snapshot.CacheHit || snapshot.Attempts != 0 {
+		t.Fatalf("Expected equivalent request to hit the cache, got %s (status %q, %+v)", resp, status, snapshot)
+	}
+	if got := exec.calls.Load(); got != 1 {
+		t.Fatalf("Expected one upstream call, got %d", got)
+	}
+
+	if resp, _, _ = runCachedRequest(t, h, `{"model":"cache-model","messages":[{"role":"user","content":"other prompt"}],"temperature":0}`, nil); resp != `{"call":2}` {
+		t.Fatalf("Expected a different prompt to miss, got %s", resp)
+	}
+	if resp, status, _ = runCachedRequest(t, h, cacheTestBody, map[string]string{CacheBypassHeader: "true"}); resp != `{"call":3}` || status != "" {
+		t.Fatalf("Expected bypass header to skip the cache, got %s (status %q)", resp, status)
+	}
+}
+
+func TestResponseCache_SkipNondeterministic(t *testing.T) {
+	h, exec := newCacheTestHandler(t, &config.SDKConfig{ResponseCache: config.ResponseCacheConfig{Enabled: true, SkipNondeterministic: true}})
+	body := `{"model":"cache-model","messages":[{"role":"user","content":"same prompt"}],"temperature":0.7}`
+	for i := 0; i < 2; i++ {
+		runCachedRequest(t, h, body, nil)
+	}
+	if got := exec.calls.Load(); got != 2 {
+		t.Fatalf("Expected sampled requests to bypass the cache, got %d upstream calls", got)
+	}
+	runCachedRequest(t, h, cacheTestBody, nil)
+	if _, status, _ := runCachedRequest(t, h, cacheTestBody, nil); status != "hit" {
+		t.Fatalf("Expected temperature 0 to stay cacheable, got status %q", status)
+	}
+}
+
+// setTestSamplingProfiles installs a "cold" and a "warm" sampling profile for the test.
+func setTestSamplingProfiles(t *testing.T) {
+	t.Helper()
+	cold, warm := 0.0, 0.7
+	util.SetSamplingProfiles(map[string]internalconfig.ModelParamDefaults{
+		"cold": {Temperature: &cold},
+		"warm": {Temperature: &warm},
+	})
+	t.Cleanup(func() { util.SetSamplingProfiles(nil) })
+}
+
+const cacheTestUnsampledBody = `{"model":"cache-model","messages":[{"role":"user","content":"same prompt"}]}`
+
+func TestResponseCache_KeyedByHeaderSamplingProfile(t *testing.T) {
+	setTestSamplingProfiles(t)
+	h, exec := newCacheTestHandler(t, &config.SDKConfig{ResponseCache: config.ResponseCacheConfig{Enabled: true}})
+
+	if resp, _, _ := runCachedRequest(t, h, cacheTestUnsampledBody, map[string]string{SamplingProfileHeader: "cold"}); resp != `{"call":1}` {
+		t.Fatalf("Expected the first profile to miss, got %s", resp)
+	}
+	if resp, status, _ := runCachedRequest(t, h, cacheTestUnsampledBody, map[string]string{SamplingProfileHeader: "warm"}); resp != `{"call":2}` || status != "miss" {
+		t.Fatalf("Expected a different profile to miss, got %s (status %q)", resp, status)
+	}
+	if resp, status, _ := runCachedRequest(t, h, cacheTestUnsampledBody, map[string]string{SamplingProfileHeader: "cold"}); resp != `{"call":1}` || status != "hit" {
+		t.Fatalf("Expected the same profile to hit, got %s (status %q)", resp, status)
+	}
+	if got := exec.calls.Load(); got != 2 {
+		t.Fatalf("Expected one upstream call per profile, got %d", got)
+	}
+}
+
+func TestResponseCache_SkipNondeterministicProfile(t *testing.T) {
+	setTestSamplingProfiles(t)
+	h, exec := newCacheTestHandler(t, &config.SDKConfig{ResponseCache: config.ResponseCacheConfig{Enabled: true, SkipNondeterministic: true}})
+	for i := 0; i < 2; i++ {
+		runCachedRequest(t, h, cacheTestUnsampledBody, map[string]string{SamplingProfileHeader: "warm"})
+	}
+	if got := exec.calls.Load(); got != 2 {
+		t.Fatalf("Expected a temperature from the profile to bypass the cache, got %d upstream calls", got)
+	}
+}
+
+func TestResponseCache_KeyedByRoutingOverride(t *testing.T) {
+	h, exec := newCacheTestHandler(t, &config.SDKConfig{AllowRoutingOverride: true, ResponseCache: config.ResponseCacheConfig{Enabled: true}})
+	pinned := map[string]string{AccountOverrideHeader: "cache-test-auth"}
+
+	if resp, _, _ := runCachedRequest(t, h, cacheTestBody, nil); resp != `{"call":1}` {
+		t.Fatalf("Expected the unpinned request to miss, got %s", resp)
+	}
+	if resp, status, _ := runCachedRequest(t, h, cacheTestBody, pinned); resp != `{"call":2}` || status != "miss" {
+		t.Fatalf("Expected a pinned request not to be served the unpinned response, got %s (status %q)", resp, status)
+	}
+	if resp, status, _ := runCachedRequest(t, h, cacheTestBody, pinned); resp != `{"call":2}` || status != "hit" {
+		t.Fatalf("Expected the same pin to hit, got %s (status %q)", resp, status)
+	}
+
+	ctx, _, _ := newHandlerTestContext(t, h, "/v1/chat/completions", map[string]string{AccountOverrideHeader: "missing"})
+	if _, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "cache-model", []byte(cacheTestBody), ""); errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected an invalid pin to be rejected despite cached responses, got %+v", errMsg)
+	}
+	if got := exec.calls.Load(); got != 2 {
+		t.Fatalf("Expected one upstream call per routing, got %d", got)
+	}
+}
+
+func TestResponseCache_TTLAndEviction(t *testing.T) {
+	cache := newResponseCache()
+	start := time.Now()
+	cache.put("a", []byte("A"), 2, start)
+	if payload, ok := cache.get("a", time.Minute, start.Add(59*time.Second)); !ok || string(payload) != "A" {
+		t.Fatalf("Expected entry within TTL, got %q, %v", payload, ok)
+	}
+	if _, ok := cache.get("a", time.Minute, start.Add(time.Minute)); ok {
+		t.Fatal("Expected entry to expire after the TTL")
+	}
+	if cache.order.Len() != 0 {
+		t.Fatal("Expected expired entry to be removed")
+	}
+
+	cache.put("a", []byte("A"), 2, start)
+	cache.put("b", []byte("B"), 2, start)
+	cache.get("a", time.Minute, start)
+	cache.put("c", []byte("C"), 2, start)
+	if _, ok := cache.get("b", time.Minute, start); ok {
+		t.Fatal("Expected least recently used entry to be evicted")
+	}
+	if _, ok := cache.get("a", time.Minute, start); !ok {
+		t.Fatal("Expected recently used entry to be kept")
+	}
+}
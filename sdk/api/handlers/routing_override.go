@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+const (
+	// ProviderOverrideHeader pins a request to the named provider when routing overrides are allowed.
+	ProviderOverrideHeader = "X-Proxy-Provider"
+	// AccountOverrideHeader pins a request to the credential with the given ID when routing overrides are allowed.
+	AccountOverrideHeader = "X-Proxy-Account"
+	// SessionIDHeader identifies a conversation for sticky sessions; the session_id query
+	// parameter is accepted as well.
+	SessionIDHeader = "X-Session-ID"
+)
+
+// applyRoutingOverride narrows provider and credential selection according to the routing
+// override headers. The headers are always removed from the inbound request; they only take
+// effect when allow-routing-override is enabled. Names that cannot serve the model are
+// rejected with 400 instead of silently falling back to the load balancer. The client's
+// session ID, if any, is always passed on for sticky sessions.
+func (h *BaseAPIHandler) applyRoutingOverride(ctx context.Context, modelName string, providers []string, metadata map[string]any) ([]string, map[string]any, *interfaces.ErrorMessage) {
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil || ginCtx.Request == nil {
+		return providers, metadata, nil
+	}
+	metadata = withSessionID(ginCtx, metadata)
+	provider := strings.ToLower(strings.TrimSpace(ginCtx.GetHeader(ProviderOverrideHeader)))
+	account := strings.TrimSpace(ginCtx.GetHeader(AccountOverrideHeader))
+	ginCtx.Request.Header.Del(ProviderOverrideHeader)
+	ginCtx.Request.Header.Del(AccountOverrideHeader)
+	if h.Cfg == nil || !h.Cfg.AllowRoutingOverride || (provider == "" && account == "") {
+		return providers, metadata, nil
+	}
+
+	if provider != "" {
+		if !slices.Contains(providers, provider) {
+			return nil, nil, &interfaces.ErrorMessage{
+				StatusCode: http.StatusBadRequest,
+				Error:      fmt.Errorf("%s: provider %q does not serve model %s (available: %s)", ProviderOverrideHeader, provider, modelName, strings.Join(providers, ", ")),
+			}
+		}
+		providers = []string{provider}
+	}
+
+	if account != "" {
+		var auth *coreauth.Auth
+		if h.AuthManager != nil {
+			auth, _ = h.AuthManager.GetByID(account)
+		}
+		if auth == nil || auth.Disabled {
+			return nil, nil, &interfaces.ErrorMessage{
+				StatusCode: http.StatusBadRequest,
+				Error:      fmt.Errorf("%s: unknown account %q", AccountOverrideHeader, account),
+			}
+		}
+		if !slices.Contains(providers, auth.Provider) {
+			return nil, nil, &interfaces.ErrorMessage{
+				StatusCode: http.StatusBadRequest,
+				Error:      fmt.Errorf("%s: account %q (provider %s) cannot serve model %s", AccountOverrideHeader, account, auth.Provider, modelName),
+			}
+		}
+		providers = []string{auth.Provider}
+		if metadata == nil {
+			metadata = make(map[string]any)
+		}
+		metadata[coreauth.PinnedAuthMetadataKey] = auth.ID
+	}
+	return providers, metadata, nil
+}
+
+// withSessionID records the session ID sent in SessionIDHeader or the session_id query
+// parameter in metadata, where the auth manager uses it for session affinity.
+func withSessionID(ginCtx *gin.Context, metadata map[string]any) map[string]any {
+	session := strings.TrimSpace(ginCtx.GetHeader(SessionIDHeader))
+	if session == "" {
+		session = strings.TrimSpace(ginCtx.Query("session_id"))
+	}
+	if session == "" {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	metadata[coreauth.SessionMetadataKey] = session
+	return metadata
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func newRoutingOverrideContext(t *testing.T, allow bool, headers map[string]string) (*BaseAPIHandler, context.Context, *http.Request) {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	for _, auth := range []*coreauth.Auth{
+		{ID: "gemini-a", Provider: "gemini"},
+		{ID: "claude-a", Provider: "claude"},
+	} {
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("register %s: %v", auth.ID, err)
+		}
+	}
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{AllowRoutingOverride: allow}, AuthManager: manager}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	return h, context.WithValue(context.Background(), "gin", c), req
+}
+
+func TestApplyRoutingOverride_Valid(t *testing.T) {
+	h, ctx, req := newRoutingOverrideContext(t, true, map[string]string{
+		ProviderOverrideHeader: "Gemini",
+		AccountOverrideHeader:  "gemini-a",
+	})
+
+	providers, metadata, errMsg := h.applyRoutingOverride(ctx, "shared-model", []string{"claude", "gemini"}, nil)
+	if errMsg != nil {
+		t.Fatalf("Expected override to be accepted, got %v", errMsg.Error)
+	}
+	if len(providers) != 1 || providers[0] != "gemini" {
+		t.Fatalf("Expected routing pinned to gemini, got %v", providers)
+	}
+	if metadata[coreauth.PinnedAuthMetadataKey] != "gemini-a" {
+		t.Fatalf("Expected credential to be pinned, got %v", metadata)
+	}
+	if req.Header.Get(ProviderOverrideHeader) != "" || req.Header.Get(AccountOverrideHeader) != "" {
+		t.Fatal("Expected override headers to be stripped from the request")
+	}
+}
+
+func TestApplyRoutingOverride_Invalid(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{"provider not serving model", map[string]string{ProviderOverrideHeader: "codex"}},
+		{"unknown account", map[string]string{AccountOverrideHeader: "missing"}},
+		{"account of other provider", map[string]string{ProviderOverrideHeader: "gemini", AccountOverrideHeader: "claude-a"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, ctx, _ := newRoutingOverrideContext(t, true, tc.headers)
+			_, _, errMsg := h.applyRoutingOverride(ctx, "shared-model", []string{"claude", "gemini"}, nil)
+			if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+				t.Fatalf("Expected 400 for %v, got %+v", tc.headers, errMsg)
+			}
+		})
+	}
+}
+
+func TestApplyRoutingOverride_Disabled(t *testing.T) {
+	h, ctx, req := newRoutingOverrideContext(t, false, map[string]string{
+		ProviderOverrideHeader: "codex",
+		AccountOverrideHeader:  "missing",
+	})
+
+	providers, metadata, errMsg := h.applyRoutingOverride(ctx, "shared-model", []string{"claude", "gemini"}, nil)
+	if errMsg != nil {
+		t.Fatalf("Expected headers to be ignored when disabled, got %v", errMsg.Error)
+	}
+	if len(providers) != 2 || metadata != nil {
+		t.Fatalf("Expected routing to be unchanged, got providers=%v metadata=%v", providers, metadata)
+	}
+	if req.Header.Get(ProviderOverrideHeader) != "" {
+		t.Fatal("Expected override headers to be stripped even when disabled")
+	}
+}
+
+func TestApplyRoutingOverride_PassesSessionID(t *testing.T) {
+	// The session ID is forwarded even when routing overrides are not allowed.
+	h, ctx, _ := newRoutingOverrideContext(t, false, map[string]string{SessionIDHeader: " conv-1 "})
+	_, metadata, errMsg := h.applyRoutingOverride(ctx, "shared-model", []string{"gemini"}, nil)
+	if errMsg != nil || metadata[coreauth.SessionMetadataKey] != "conv-1" {
+		t.Fatalf("Expected session ID in metadata, got %v (%v)", metadata, errMsg)
+	}
+
+	h, ctx, req := newRoutingOverrideContext(t, false, nil)
+	req.URL.RawQuery = "session_id=conv-2"
+	if _, metadata, _ = h.applyRoutingOverride(ctx, "shared-model", []string{"gemini"}, nil); metadata[coreauth.SessionMetadataKey] != "conv-2" {
+		t.Fatalf("Expected session ID from the query parameter, got %v", metadata)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// serviceTiers lists the service_tier values of the OpenAI chat completions API.
+var serviceTiers = []string{"auto", "default", "flex", "priority"}
+
+// applyServiceTier validates the service_tier of an OpenAI chat request and records it in the
+// execution metadata, where the auth manager lets flex requests queue longer under the
+// concurrency limits. The request itself is left to the translators: Claude maps the tier to
+// its own, OpenAI compatible upstreams receive it unchanged and the others drop it.
+func applyServiceTier(handlerType string, rawJSON []byte, metadata map[string]any) (map[string]any, *interfaces.ErrorMessage) {
+	if handlerType != constant.OpenAI {
+		return metadata, nil
+	}
+	tier := gjson.GetBytes(rawJSON, "service_tier")
+	if !tier.Exists() || tier.Type == gjson.Null {
+		return metadata, nil
+	}
+	if tier.Type != gjson.String || !slices.Contains(serviceTiers, tier.Str) {
+		return nil, &interfaces.ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Errorf("service_tier must be one of %s", strings.Join(serviceTiers, ", ")),
+		}
+	}
+	if metadata == nil {
+		metadata = make(map[string]any, 1)
+	}
+	metadata[coreauth.ServiceTierMetadataKey] = tier.Str
+	return metadata, nil
+}
+
+// echoServiceTier reports the effective service_tier on a response or stream chunk to a
+// request that set one. The tier the upstream reported is kept; upstreams without service
+// tiers serve every request at the default tier, which is reported otherwise.
+func echoServiceTier(metadata map[string]any, payload []byte) []byte {
+	if _, requested := metadata[coreauth.ServiceTierMetadataKey].(string); !requested {
+		return payload
+	}
+	root := gjson.ParseBytes(payload)
+	if !root.IsObject() || root.Get("service_tier").Exists() {
+		return payload
+	}
+	if out, err := sjson.SetBytes(payload, "service_tier", "default"); err == nil {
+		return out
+	}
+	return payload
+}
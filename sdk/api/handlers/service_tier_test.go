@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// serviceTierExecutor answers like an upstream without service tiers unless reported is set,
+// and records the execution metadata of the last request.
+type serviceTierExecutor struct {
+	reported string
+	metadata chan map[string]any
+}
+
+func (e *serviceTierExecutor) Identifier() string { return "tier-test" }
+
+func (e *serviceTierExecutor) payload() []byte {
+	if e.reported != "" {
+		return []byte(`{"id":"chatcmpl-1","service_tier":"` + e.reported + `","choices":[]}`)
+	}
+	return []byte(`{"id":"chatcmpl-1","choices":[]}`)
+}
+
+func (e *serviceTierExecutor) Execute(_ context.Context, _ *coreauth.Auth, _ coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	e.metadata <- opts.Metadata
+	return coreexecutor.Response{Payload: e.payload()}, nil
+}
+
+func (e *serviceTierExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, _ coreexecutor.Request, opts coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	e.metadata <- opts.Metadata
+	out := make(chan coreexecutor.StreamChunk, 2)
+	out <- coreexecutor.StreamChunk{Payload: e.payload()}
+	out <- coreexecutor.StreamChunk{Payload: e.payload()}
+	close(out)
+	return out, nil
+}
+
+func (e *serviceTierExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *serviceTierExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func newServiceTierHandler(t *testing.T, exec *serviceTierExecutor) (*BaseAPIHandler, context.Context) {
+	t.Helper()
+	exec.metadata = make(chan map[string]any, 1)
+	manager := newHandlerTestManager(t, "tier-test", []*registry.ModelInfo{{ID: "tier-model"}}, exec)
+	h := &BaseAPIHandler{Cfg: &config.SDKConfig{}, AuthManager: manager}
+	ctx, _, _ := newHandlerTestContext(t, h, "/v1/chat/completions", nil)
+	return h, ctx
+}
+
+func TestExecuteWithAuthManager_ServiceTierEchoedAndRecorded(t *testing.T) {
+	exec := &serviceTierExecutor{}
+	h, ctx := newServiceTierHandler(t, exec)
+
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "tier-model", []byte(`{"model":"tier-model","service_tier":"flex","messages":[]}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	if got := gjson.GetBytes(resp, "service_tier").String(); got != "default" {
+		t.Fatalf("Expected the default tier to be reported for an upstream without tiers, got %s", resp)
+	}
+	if metadata := <-exec.metadata; metadata[coreauth.ServiceTierMetadataKey] != "flex" {
+		t.Fatalf("Expected the requested tier in the execution metadata, got %v", metadata)
+	}
+
+	exec.reported = "priority"
+	resp, errMsg = h.ExecuteWithAuthManager(ctx, "openai", "tier-model", []byte(`{"model":"tier-model","service_tier":"auto","messages":[]}`), "")
+	<-exec.metadata
+	if errMsg != nil || gjson.GetBytes(resp, "service_tier").String() != "priority" {
+		t.Fatalf("Expected the tier reported by the upstream to be kept, got %s (%v)", resp, errMsg)
+	}
+}
+
+func TestExecuteStreamWithAuthManager_ServiceTierEchoed(t *testing.T) {
+	exec := &serviceTierExecutor{}
+	h, ctx := newServiceTierHandler(t, exec)
+
+	data, errs := h.ExecuteStreamWithAuthManager(ctx, "openai", "tier-model", []byte(`{"model":"tier-model","stream":true,"service_tier":"default","messages":[]}`), "")
+	chunks := 0
+	for chunk := range data {
+		chunks++
+		if got := gjson.GetBytes(chunk, "service_tier").String(); got != "default" {
+			t.Fatalf("Expected every chunk to carry the effective tier, got %s", chunk)
+		}
+	}
+	if errMsg := <-errs; errMsg != nil || chunks != 2 {
+		t.Fatalf("Expected two chunks, got %d (%v)", chunks, errMsg)
+	}
+}
+
+func TestExecuteWithAuthManager_ServiceTierNotRequested(t *testing.T) {
+	exec := &serviceTierExecutor{}
+	h, ctx := newServiceTierHandler(t, exec)
+
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "tier-model", []byte(`{"model":"tier-model","messages":[]}`), "")
+	if errMsg != nil || gjson.GetBytes(resp, "service_tier").Exists() {
+		t.Fatalf("Expected no service_tier without one in the request, got %s (%v)", resp, errMsg)
+	}
+	if _, recorded := (<-exec.metadata)[coreauth.ServiceTierMetadataKey]; recorded {
+		t.Fatal("Expected no service tier in the execution metadata")
+	}
+}
+
+func TestApplyServiceTier_RejectsUnknownTier(t *testing.T) {
+	if _, errMsg := applyServiceTier("openai", []byte(`{"service_tier":"scale"}`), nil); errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an unknown service tier, got %v", errMsg)
+	}
+	if metadata, errMsg := applyServiceTier("claude", []byte(`{"service_tier":"scale"}`), nil); errMsg != nil || metadata != nil {
+		t.Fatalf("Expected non-OpenAI requests to be left alone, got %v (%v)", metadata, errMsg)
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// usageFields locates the prompt, completion and total token counts in a non-streaming
+// response of each handler type whose usage can be estimated.
+var usageFields = map[string]struct {
+	object, prompt, completion, total string
+}{
+	"openai":          {"usage", "prompt_tokens", "completion_tokens", "total_tokens"},
+	"openai-response": {"usage", "input_tokens", "output_tokens", "total_tokens"},
+	"claude":          {"usage", "input_tokens", "output_tokens", ""},
+	"gemini":          {"usageMetadata", "promptTokenCount", "candidatesTokenCount", "totalTokenCount"},
+}
+
+// usageTextSkippedKeys holds the keys whose values are identifiers, enums or binary data rather
+// than text a model would tokenize.
+var usageTextSkippedKeys = map[string]bool{
+	"id": true, "object": true, "model": true, "created": true, "role": true, "type": true,
+	"index": true, "status": true, "finish_reason": true, "finishReason": true, "stop_reason": true,
+	"modelVersion": true, "responseId": true, "system_fingerprint": true, "tool_call_id": true,
+	"call_id": true, "signature": true, "thoughtSignature": true, "encrypted_content": true,
+	"logprobs": true, "image_url": true, "inlineData": true, "inline_data": true, "source": true,
+}
+
+// usageEstimate approximates the usage of one request for which the upstream may not report
+// any. Records published without token counts are held until finish resolves them.
+type usageEstimate struct {
+	handlerType string
+	model       string
+	estimator   coreusage.TokenEstimator
+	estimation  *coreusage.Estimation
+	prompt      int64
+	output      strings.Builder
+}
+
+// tokenEstimator returns the estimator configured for model, or nil when estimation is off.
+func (h *BaseAPIHandler) tokenEstimator(model string) coreusage.TokenEstimator {
+	if h.Cfg == nil || !h.Cfg.TokenEstimation.Enabled {
+		return nil
+	}
+	name := h.Cfg.TokenEstimation.Estimator
+	for _, rule := range h.Cfg.TokenEstimation.Models {
+		if matchModelPattern(rule.Model, model) {
+			name = rule.Estimator
+			break
+		}
+	}
+	if strings.TrimSpace(name) == "" {
+		name = coreusage.EstimatorHeuristic
+	}
+	if estimator, ok := coreusage.LookupTokenEstimator(name); ok {
+		return estimator
+	}
+	log.Warnf("token estimation: unknown estimator %q for model %s, using %s", name, model, coreusage.EstimatorHeuristic)
+	return coreusage.HeuristicEstimator{}
+}
+
+// beginUsageEstimation prepares estimating the usage of a request to model. It returns ctx
+// unchanged and a nil estimate when estimation is off, unsupported for handlerType or the
+// request is a dry run.
+func (h *BaseAPIHandler) beginUsageEstimation(ctx context.Context, handlerType, model string, rawJSON []byte) (context.Context, *usageEstimate) {
+	if _, ok := usageFields[handlerType]; !ok || coreexecutor.IsDryRun(ctx) {
+		return ctx, nil
+	}
+	estimator := h.tokenEstimator(model)
+	if estimator == nil {
+		return ctx, nil
+	}
+	ctx, estimation := coreusage.WithEstimation(ctx)
+	var prompt strings.Builder
+	collectUsageText(gjson.ParseBytes(rawJSON), &prompt, "\n")
+	return ctx, &usageEstimate{
+		handlerType: handlerType,
+		model:       model,
+		estimator:   estimator,
+		estimation:  estimation,
+		prompt:      estimator.EstimateTokens(model, prompt.String()),
+	}
+}
+
+// observe accumulates the generated text of a response payload or stream chunk, which may
+// hold several SSE lines.
+func (u *usageEstimate) observe(payload []byte) {
+	if u == nil {
+		return
+	}
+	if gjson.ValidBytes(payload) {
+		collectUsageText(gjson.ParseBytes(payload), &u.output, "")
+		return
+	}
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("data:")) {
+			line = bytes.TrimSpace(line[len("data:"):])
+		}
+		if len(line) > 0 && gjson.ValidBytes(line) {
+			collectUsageText(gjson.ParseBytes(line), &u.output, "")
+		}
+	}
+}
+
+// finish estimates the usage from the observed text and publishes the held records with it.
+func (u *usageEstimate) finish() coreusage.Detail {
+	if u == nil {
+		return coreusage.Detail{}
+	}
+	detail := coreusage.Detail{
+		InputTokens:  u.prompt,
+		OutputTokens: u.estimator.EstimateTokens(u.model, u.output.String()),
+	}
+	detail.TotalTokens = detail.InputTokens + detail.OutputTokens
+	u.estimation.Resolve(detail)
+	return detail
+}
+
+// finishResponse estimates the usage of a non-streaming response and adds it, flagged with
+// "estimated": true, when the response carries no usage of its own.
+func (u *usageEstimate) finishResponse(payload []byte) []byte {
+	if u == nil {
+		return payload
+	}
+	u.observe(payload)
+	detail := u.finish()
+	fields := usageFields[u.handlerType]
+	reported := gjson.GetBytes(payload, fields.object)
+	if !gjson.ValidBytes(payload) || reported.Get(fields.prompt).Int() > 0 || reported.Get(fields.completion).Int() > 0 {
+		return payload
+	}
+	out := payload
+	out, _ = sjson.SetBytes(out, fields.object+"."+fields.prompt, detail.InputTokens)
+	out, _ = sjson.SetBytes(out, fields.object+"."+fields.completion, detail.OutputTokens)
+	if fields.total != "" {
+		out, _ = sjson.SetBytes(out, fields.object+"."+fields.total, detail.TotalTokens)
+	}
+	out, _ = sjson.SetBytes(out, fields.object+".estimated", true)
+	return out
+}
+
+// collectUsageText appends the string values of value that a model would tokenize to out,
+// separated by sep. Stream deltas are joined without a separator so they read as one text.
+func collectUsageText(value gjson.Result, out *strings.Builder, sep string) {
+	switch {
+	case value.IsObject():
+		value.ForEach(func(key, child gjson.Result) bool {
+			if !usageTextSkippedKeys[key.String()] {
+				collectUsageText(child, out, sep)
+			}
+			return true
+		})
+	case value.IsArray():
+		value.ForEach(func(_, child gjson.Result) bool {
+			collectUsageText(child, out, sep)
+			return true
+		})
+	case value.Type == gjson.String:
+		if text := value.String(); text != "" && !strings.HasPrefix(text, "data:") {
+			if out.Len() > 0 {
+				out.WriteString(sep)
+			}
+			out.WriteString(text)
+		}
+	}
+}
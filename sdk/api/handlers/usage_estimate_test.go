@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// noUsageExecutor answers like an upstream that reports no usage, publishing the zero record
+// executors emit to count such requests.
+type noUsageExecutor struct{}
+
+func (noUsageExecutor) Identifier() string { return "no-usage-test" }
+
+func (noUsageExecutor) Execute(ctx context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	coreusage.PublishRecord(ctx, coreusage.Record{Provider: "no-usage-test", Model: req.Model})
+	return coreexecutor.Response{Payload: []byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"estimate","choices":[{"index":0,"message":{"role":"assistant","content":"The quick brown fox jumps over the lazy dog."},"finish_reason":"stop"}]}`)}, nil
+}
+
+func (noUsageExecutor) ExecuteStream(ctx context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	out := make(chan coreexecutor.StreamChunk, 3)
+	out <- coreexecutor.StreamChunk{Payload: []byte(`data: {"choices":[{"index":0,"delta":{"content":"The quick brown fox "}}]}`)}
+	out <- coreexecutor.StreamChunk{Payload: []byte(`data: {"choices":[{"index":0,"delta":{"content":"jumps over the lazy dog."}}]}`)}
+	coreusage.PublishRecord(ctx, coreusage.Record{Provider: "no-usage-test", Model: req.Model})
+	close(out)
+	return out, nil
+}
+
+func (noUsageExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (noUsageExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+// estimateRecorder captures the usage records of one model; plugins stay registered on the
+// default manager, so each test uses its own model.
+type estimateRecorder struct {
+	model   string
+	records chan coreusage.Record
+}
+
+func (r *estimateRecorder) HandleUsage(_ context.Context, record coreusage.Record) {
+	if record.Model == r.model {
+		r.records <- record
+	}
+}
+
+func newUsageEstimateHandler(t *testing.T, cfg *config.SDKConfig, model string) (*BaseAPIHandler, *estimateRecorder) {
+	t.Helper()
+	manager := newHandlerTestManager(t, "no-usage-test", []*registry.ModelInfo{{ID: model}}, noUsageExecutor{})
+	records := &estimateRecorder{model: model, records: make(chan coreusage.Record, 4)}
+	coreusage.RegisterPlugin(records)
+	return &BaseAPIHandler{Cfg: cfg, AuthManager: manager}, records
+}
+
+func awaitEstimatedRecord(t *testing.T, records *estimateRecorder) coreusage.Record {
+	t.Helper()
+	select {
+	case record := <-records.records:
+		if !record.Detail.Estimated || record.Detail.InputTokens == 0 || record.Detail.OutputTokens == 0 {
+			t.Fatalf("Expected an estimated usage record, got %+v", record.Detail)
+		}
+		return record
+	case <-time.After(time.Second):
+		t.Fatal("Expected a usage record")
+	}
+	return coreusage.Record{}
+}
+
+func TestExecuteWithAuthManager_EstimatesMissingUsage(t *testing.T) {
+	cfg := &config.SDKConfig{TokenEstimation: config.TokenEstimationConfig{
+		Enabled: true,
+		Models:  []config.TokenEstimatorRule{{Model: "estimate-*", Estimator: coreusage.EstimatorTiktoken}},
+	}}
+	h, records := newUsageEstimateHandler(t, cfg, "estimate-tiktoken")
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	ctx, cancel := h.GetContextWithCancel(recordTestHandler{}, c, context.Background())
+	defer cancel()
+
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "estimate-tiktoken", []byte(`{"model":"estimate-tiktoken","messages":[{"role":"user","content":"Tell me about foxes"}]}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	usage := gjson.GetBytes(resp, "usage")
+	if !usage.Get("estimated").Bool() || usage.Get("prompt_tokens").Int() != 5 || usage.Get("completion_tokens").Int() != 10 || usage.Get("total_tokens").Int() != 15 {
+		t.Fatalf("Expected estimated tiktoken usage in the response, got %s", usage.Raw)
+	}
+	if record := awaitEstimatedRecord(t, records); record.Detail.TotalTokens != 15 {
+		t.Fatalf("Expected the usage record to match the response, got %+v", record.Detail)
+	}
+}
+
+func TestExecuteStreamWithAuthManager_EstimatesMissingUsage(t *testing.T) {
+	h, records := newUsageEstimateHandler(t, &config.SDKConfig{TokenEstimation: config.TokenEstimationConfig{Enabled: true}}, "estimate-heuristic")
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	ctx, cancel := h.GetContextWithCancel(recordTestHandler{}, c, context.Background())
+	defer cancel()
+
+	data, errs := h.ExecuteStreamWithAuthManager(ctx, "openai", "estimate-heuristic", []byte(`{"model":"estimate-heuristic","stream":true,"messages":[{"role":"user","content":"Tell me about foxes"}]}`), "")
+	for range data {
+	}
+	if errMsg := <-errs; errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	// The heuristic counts four characters per token: 19 prompt and 44 completion characters.
+	if record := awaitEstimatedRecord(t, records); record.Detail.InputTokens != 5 || record.Detail.OutputTokens != 11 {
+		t.Fatalf("Expected heuristic estimates of 5 and 11 tokens, got %+v", record.Detail)
+	}
+}
+
+func TestExecuteWithAuthManager_EstimationDisabled(t *testing.T) {
+	h, records := newUsageEstimateHandler(t, &config.SDKConfig{}, "estimate-disabled")
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	ctx, cancel := h.GetContextWithCancel(recordTestHandler{}, c, context.Background())
+	defer cancel()
+
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "estimate-disabled", []byte(`{"model":"estimate-disabled","messages":[{"role":"user","content":"hi"}]}`), "")
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %v", errMsg.Error)
+	}
+	if gjson.GetBytes(resp, "usage").Exists() {
+		t.Fatalf("Expected no usage to be added, got %s", resp)
+	}
+	select {
+	case record := <-records.records:
+		if record.Detail.Estimated {
+			t.Fatalf("Expected the record unchanged, got %+v", record.Detail)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the zero record to be published")
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"sort"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// AccountStatus reports the routing health of a single credential. It carries no secrets:
+// API keys are masked and tokens, attributes and metadata are left out.
+type AccountStatus struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	Label    string `json:"label,omitempty"`
+	// Account is the OAuth e-mail or the masked API key identifying the credential.
+	Account  string `json:"account,omitempty"`
+	Status   Status `json:"status"`
+	Disabled bool   `json:"disabled"`
+	// CooldownUntil is set while the whole credential is cooling down.
+	CooldownUntil *time.Time `json:"cooldown_until,omitempty"`
+	// ModelCooldowns lists the models the credential is cooling down for and until when.
+	ModelCooldowns      map[string]time.Time `json:"model_cooldowns,omitempty"`
+	ConsecutiveFailures int                  `json:"consecutive_failures"`
+	CircuitState        CircuitState         `json:"circuit_state"`
+	Quotas              []QuotaStatus        `json:"quotas"`
+}
+
+// Accounts reports the health of every registered credential from local state, sorted by
+// provider and ID. It never contacts upstreams.
+func (m *Manager) Accounts() []AccountStatus {
+	now := time.Now()
+	m.mu.RLock()
+	out := make([]AccountStatus, 0, len(m.auths))
+	for _, auth := range m.auths {
+		status := AccountStatus{
+			ID:           auth.ID,
+			Provider:     auth.Provider,
+			Label:        auth.Label,
+			Status:       auth.Status,
+			Disabled:     auth.Disabled || auth.Status == StatusDisabled,
+			CircuitState: CircuitClosed,
+			Quotas:       []QuotaStatus{},
+		}
+		if accountType, info := auth.AccountInfo(); accountType == "api_key" {
+			status.Account = util.HideAPIKey(info)
+		} else {
+			status.Account = info
+		}
+		if blocked, _, next := isAuthBlockedForModel(auth, "", now); blocked && !next.IsZero() {
+			status.CooldownUntil = &next
+		}
+		for model, state := range auth.ModelStates {
+			if blocked, _, next := isAuthBlockedForModel(auth, model, now); blocked && state != nil && !next.IsZero() {
+				if status.ModelCooldowns == nil {
+					status.ModelCooldowns = make(map[string]time.Time)
+				}
+				status.ModelCooldowns[model] = next
+			}
+		}
+		out = append(out, status)
+	}
+	m.mu.RUnlock()
+
+	m.breakers.mu.Lock()
+	for i := range out {
+		if breaker := m.breakers.entries[out[i].ID]; breaker != nil {
+			out[i].CircuitState = breaker.currentState(now)
+			out[i].ConsecutiveFailures = breaker.failures
+		}
+	}
+	m.breakers.mu.Unlock()
+
+	quotas := m.Quotas()
+	for i := range out {
+		for _, quota := range quotas {
+			if quota.AuthID == out[i].ID {
+				out[i].Quotas = append(out[i].Quotas, quota)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
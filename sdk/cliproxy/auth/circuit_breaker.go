@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCircuitWindow   = time.Minute
+	defaultCircuitCooldown = 30 * time.Second
+)
+
+// CircuitState is the state of a credential's circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed lets requests through while failures are counted.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects requests until the cooldown elapses.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single probe request through to test recovery.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreakerConfig controls per-credential circuit breaking.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive upstream failures within Window that
+	// opens the circuit; zero or less disables circuit breaking.
+	FailureThreshold int
+	// Window bounds how far apart consecutive failures may be; zero defaults to one minute.
+	Window time.Duration
+	// Cooldown is how long an open circuit rejects requests before probing; zero defaults
+	// to 30 seconds.
+	Cooldown time.Duration
+}
+
+// CircuitBreakerStatus reports the breaker state of a single credential.
+type CircuitBreakerStatus struct {
+	AuthID              string       `json:"auth_id"`
+	Provider            string       `json:"provider"`
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenUntil           time.Time    `json:"open_until,omitempty"`
+}
+
+type circuitBreaker struct {
+	provider     string
+	state        CircuitState
+	failures     int
+	firstFailure time.Time
+	openUntil    time.Time
+	probing      bool
+}
+
+// circuitBreakers tracks one breaker per credential so an outage on one account never
+// blocks its healthy siblings.
+type circuitBreakers struct {
+	mu      sync.Mutex
+	cfg     CircuitBreakerConfig
+	entries map[string]*circuitBreaker
+}
+
+// SetCircuitBreaker configures per-credential circuit breaking. Changing the configuration
+// resets all breakers; a FailureThreshold of zero or less disables circuit breaking.
+func (m *Manager) SetCircuitBreaker(cfg CircuitBreakerConfig) {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultCircuitWindow
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultCircuitCooldown
+	}
+	m.breakers.mu.Lock()
+	defer m.breakers.mu.Unlock()
+	if m.breakers.cfg == cfg {
+		return
+	}
+	m.breakers.cfg = cfg
+	m.breakers.entries = nil
+}
+
+// CircuitBreakers returns the state of every credential whose breaker has recorded a
+// failure, sorted by provider and auth ID.
+func (m *Manager) CircuitBreakers() []CircuitBreakerStatus {
+	now := time.Now()
+	m.breakers.mu.Lock()
+	out := make([]CircuitBreakerStatus, 0, len(m.breakers.entries))
+	for id, breaker := range m.breakers.entries {
+		status := CircuitBreakerStatus{
+			AuthID:              id,
+			Provider:            breaker.provider,
+			State:               breaker.currentState(now),
+			ConsecutiveFailures: breaker.failures,
+		}
+		if status.State == CircuitOpen {
+			status.OpenUntil = breaker.openUntil
+		}
+		out = append(out, status)
+	}
+	m.breakers.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].AuthID < out[j].AuthID
+	})
+	return out
+}
+
+// currentState reports the effective state, treating an open circuit whose cooldown has
+// elapsed as half-open.
+func (b *circuitBreaker) currentState(now time.Time) CircuitState {
+	if b.state == CircuitOpen && !now.Before(b.openUntil) {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// state reports the effective breaker state of authID; untracked credentials are closed.
+func (c *circuitBreakers) state(authID string, now time.Time) CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	breaker, ok := c.entries[authID]
+	if !ok || c.cfg.FailureThreshold <= 0 {
+		return CircuitClosed
+	}
+	return breaker.currentState(now)
+}
+
+// blocked reports whether authID must not be selected: its circuit is open, or half-open
+// with a probe already in flight.
+func (c *circuitBreakers) blocked(authID string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blockedLocked(authID, now)
+}
+
+func (c *circuitBreakers) blockedLocked(authID string, now time.Time) bool {
+	breaker, ok := c.entries[authID]
+	if !ok || c.cfg.FailureThreshold <= 0 {
+		return false
+	}
+	switch breaker.currentState(now) {
+	case CircuitOpen:
+		return true
+	case CircuitHalfOpen:
+		return breaker.probing
+	default:
+		return false
+	}
+}
+
+// tryAcquire claims authID once it has been selected. A half-open circuit admits the request
+// as its single recovery probe; false means the circuit is open or another request already
+// holds the probe, checked and claimed under one lock so concurrent pickers cannot both probe.
+func (c *circuitBreakers) tryAcquire(authID string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.blockedLocked(authID, now) {
+		return false
+	}
+	breaker, ok := c.entries[authID]
+	if ok && breaker.currentState(now) == CircuitHalfOpen {
+		breaker.state = CircuitHalfOpen
+		breaker.probing = true
+	}
+	return true
+}
+
+// abandon releases a probe held by authID for an attempt that ends without a result being
+// recorded, such as a dry run or a request that failed to translate.
+func (c *circuitBreakers) abandon(authID string) {
+	c.record(authID, "", circuitAbandoned, time.Now())
+}
+
+// record feeds an execution outcome into the breaker of authID. Only outage-like failures
+// (transport errors, timeouts and 5xx responses) count; any other response shows the
+// upstream is reachable and closes the circuit. Abandoned attempts release a pending probe
+// without changing state.
+func (c *circuitBreakers) record(authID, provider string, outcome circuitOutcome, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cfg.FailureThreshold <= 0 || authID == "" {
+		return
+	}
+	breaker, ok := c.entries[authID]
+	switch outcome {
+	case circuitSuccess:
+		if ok {
+			delete(c.entries, authID)
+		}
+		return
+	case circuitAbandoned:
+		if ok {
+			breaker.probing = false
+		}
+		return
+	}
+	if !ok {
+		breaker = &circuitBreaker{provider: provider, state: CircuitClosed}
+		if c.entries == nil {
+			c.entries = make(map[string]*circuitBreaker)
+		}
+		c.entries[authID] = breaker
+	}
+	switch breaker.currentState(now) {
+	case CircuitHalfOpen:
+		breaker.failures++
+		breaker.open(now, c.cfg.Cooldown)
+		return
+	case CircuitOpen:
+		// Requests selected before the circuit opened may still be failing.
+		breaker.failures++
+		return
+	}
+	if breaker.failures == 0 || now.Sub(breaker.firstFailure) > c.cfg.Window {
+		breaker.failures = 0
+		breaker.firstFailure = now
+	}
+	breaker.failures++
+	if breaker.failures >= c.cfg.FailureThreshold {
+		breaker.open(now, c.cfg.Cooldown)
+	}
+}
+
+func (b *circuitBreaker) open(now time.Time, cooldown time.Duration) {
+	b.state = CircuitOpen
+	b.openUntil = now.Add(cooldown)
+	b.probing = false
+}
+
+type circuitOutcome int
+
+const (
+	circuitSuccess circuitOutcome = iota
+	circuitFailure
+	circuitAbandoned
+)
+
+// circuitOutcomeFor classifies an execution result for the circuit breaker.
+func circuitOutcomeFor(result Result, cancelled bool) circuitOutcome {
+	if result.Success {
+		return circuitSuccess
+	}
+	if cancelled {
+		return circuitAbandoned
+	}
+	switch status := statusCodeFromResult(result.Error); {
+	case status == 0, status == http.StatusRequestTimeout, status >= 500:
+		return circuitFailure
+	default:
+		return circuitSuccess
+	}
+}
+
+// circuitOpenError reports that every remaining credential for provider has an open circuit.
+func circuitOpenError(provider string) *Error {
+	return &Error{
+		Code:       "circuit_open",
+		Message:    "upstream " + provider + " is failing; circuit breaker open for all available credentials",
+		Retryable:  true,
+		HTTPStatus: http.StatusServiceUnavailable,
+	}
+}
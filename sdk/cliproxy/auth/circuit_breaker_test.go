@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestCircuitBreaker_StateTransitions(t *testing.T) {
+	breakers := &circuitBreakers{cfg: CircuitBreakerConfig{FailureThreshold: 3, Window: time.Minute, Cooldown: 30 * time.Second}}
+	start := time.Now()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	breakers.record("a", "test", circuitFailure, at(0))
+	breakers.record("a", "test", circuitFailure, at(time.Second))
+	if got := breakers.state("a", at(time.Second)); got != CircuitClosed {
+		t.Fatalf("Expected closed below the threshold, got %s", got)
+	}
+	breakers.record("a", "test", circuitFailure, at(2*time.Second))
+	if got := breakers.state("a", at(2*time.Second)); got != CircuitOpen || !breakers.blocked("a", at(2*time.Second)) {
+		t.Fatalf("Expected open and blocked after 3 failures, got %s", got)
+	}
+
+	// Cooldown elapsed: exactly one probe is admitted.
+	probeAt := at(33 * time.Second)
+	if got := breakers.state("a", probeAt); got != CircuitHalfOpen || breakers.blocked("a", probeAt) {
+		t.Fatalf("Expected half-open and selectable after cooldown, got %s", got)
+	}
+	if !breakers.tryAcquire("a", probeAt) {
+		t.Fatal("Expected the first request after cooldown to claim the probe")
+	}
+	if !breakers.blocked("a", probeAt) || breakers.tryAcquire("a", probeAt) {
+		t.Fatal("Expected further requests to be blocked while the probe is in flight")
+	}
+
+	// A failed probe reopens the circuit for another cooldown.
+	breakers.record("a", "test", circuitFailure, probeAt)
+	if got := breakers.state("a", at(50*time.Second)); got != CircuitOpen {
+		t.Fatalf("Expected failed probe to reopen the circuit, got %s", got)
+	}
+
+	// An abandoned probe releases the slot without changing state.
+	probeAt = at(64 * time.Second)
+	breakers.tryAcquire("a", probeAt)
+	breakers.record("a", "test", circuitAbandoned, probeAt)
+	if breakers.blocked("a", probeAt) {
+		t.Fatal("Expected abandoned probe to release the half-open slot")
+	}
+
+	// A successful probe closes the circuit.
+	breakers.tryAcquire("a", probeAt)
+	breakers.record("a", "test", circuitSuccess, probeAt)
+	if got := breakers.state("a", probeAt); got != CircuitClosed || breakers.blocked("a", probeAt) {
+		t.Fatalf("Expected successful probe to close the circuit, got %s", got)
+	}
+}
+
+func TestCircuitBreaker_WindowResetsFailures(t *testing.T) {
+	breakers := &circuitBreakers{cfg: CircuitBreakerConfig{FailureThreshold: 2, Window: 10 * time.Second, Cooldown: time.Minute}}
+	start := time.Now()
+	breakers.record("a", "test", circuitFailure, start)
+	breakers.record("a", "test", circuitFailure, start.Add(11*time.Second))
+	if got := breakers.state("a", start.Add(11*time.Second)); got != CircuitClosed {
+		t.Fatalf("Expected failures outside the window not to accumulate, got %s", got)
+	}
+	breakers.record("a", "test", circuitFailure, start.Add(12*time.Second))
+	if got := breakers.state("a", start.Add(12*time.Second)); got != CircuitOpen {
+		t.Fatalf("Expected consecutive failures within the window to open, got %s", got)
+	}
+}
+
+func TestCircuitOutcomeFor(t *testing.T) {
+	cases := []struct {
+		name      string
+		result    Result
+		cancelled bool
+		want      circuitOutcome
+	}{
+		{"success", Result{Success: true}, false, circuitSuccess},
+		{"transport error", Result{Error: &Error{Message: "dial tcp: refused"}}, false, circuitFailure},
+		{"server error", Result{Error: &Error{HTTPStatus: http.StatusBadGateway}}, false, circuitFailure},
+		{"timeout", Result{Error: &Error{HTTPStatus: http.StatusRequestTimeout}}, false, circuitFailure},
+		{"rate limit", Result{Error: &Error{HTTPStatus: http.StatusTooManyRequests}}, false, circuitSuccess},
+		{"client cancelled", Result{Error: &Error{Message: "context canceled"}}, true, circuitAbandoned},
+	}
+	for _, tc := range cases {
+		if got := circuitOutcomeFor(tc.result, tc.cancelled); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestManagerExecute_CircuitBreakerFailsFast(t *testing.T) {
+	// Transport errors carry no status and therefore no quota cooldown; only the breaker
+	// stops them from reaching the upstream again.
+	executor := &failoverTestExecutor{failures: map[string]error{
+		"a": errors.New("dial tcp: i/o timeout"),
+	}}
+	m := newFailoverTestManager(t, executor, "a")
+	m.SetCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2})
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err == nil {
+			t.Fatal("Expected upstream failure")
+		}
+	}
+	_, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.Code != "circuit_open" || authErr.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("Expected circuit_open error, got %#v", err)
+	}
+	if len(executor.calls) != 2 {
+		t.Fatalf("Expected no upstream call once the circuit is open, got %v", executor.calls)
+	}
+	states := m.CircuitBreakers()
+	if len(states) != 1 || states[0].AuthID != "a" || states[0].State != CircuitOpen || states[0].ConsecutiveFailures != 2 {
+		t.Fatalf("Unexpected breaker states: %+v", states)
+	}
+}
+
+func TestManagerExecute_CircuitBreakerSparesSiblings(t *testing.T) {
+	executor := &failoverTestExecutor{failures: map[string]error{
+		"a": &testStatusError{code: http.StatusServiceUnavailable, msg: "unavailable"},
+	}}
+	m := newFailoverTestManager(t, executor, "a", "b")
+	m.SetCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1})
+
+	for i := 0; i < 3; i++ {
+		resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+		if err != nil || string(resp.Payload) != "b" {
+			t.Fatalf("Expected healthy sibling b to serve request %d, got %q, %v", i, resp.Payload, err)
+		}
+	}
+	for _, id := range executor.calls[1:] {
+		if id == "a" {
+			t.Fatalf("Expected open credential a to be skipped after its first failure, calls %v", executor.calls)
+		}
+	}
+	if got := m.breakers.state("b", time.Now()); got != CircuitClosed {
+		t.Fatalf("Expected sibling b to stay closed, got %s", got)
+	}
+
+	health := m.Readiness(context.Background(), ReadinessOptions{})
+	if len(health) != 1 || !health[0].Ready || health[0].OpenCircuits != 1 || health[0].Available != 1 {
+		t.Fatalf("Expected provider ready with one open circuit, got %+v", health)
+	}
+}
+
+func TestManagerExecute_HalfOpenProbeReleasedWithoutResult(t *testing.T) {
+	executor := &failoverTestExecutor{failures: map[string]error{
+		"a": errors.New("dial tcp: i/o timeout"),
+	}}
+	m := newFailoverTestManager(t, executor, "a")
+	m.SetCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, Cooldown: 10 * time.Millisecond})
+
+	if _, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Expected upstream failure")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := m.breakers.state("a", time.Now()); got != CircuitHalfOpen {
+		t.Fatalf("Expected half-open after the cooldown, got %s", got)
+	}
+
+	// The probe ends in a transformer error, which records no result for the credential.
+	executor.failures["a"] = &cliproxyexecutor.TransformError{Err: errors.New("rejected by policy")}
+	if _, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Expected the transformer error")
+	}
+	if m.breakers.blocked("a", time.Now()) {
+		t.Fatal("Expected the abandoned probe to release the half-open slot")
+	}
+
+	delete(executor.failures, "a")
+	resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != "a" {
+		t.Fatalf("Expected the next probe to reach the credential, got %q, %v", resp.Payload, err)
+	}
+	if got := m.breakers.state("a", time.Now()); got != CircuitClosed {
+		t.Fatalf("Expected the successful probe to close the circuit, got %s", got)
+	}
+}
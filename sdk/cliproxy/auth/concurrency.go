@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// ConcurrencyConfig caps the number of requests in flight per model across all credentials.
+type ConcurrencyConfig struct {
+	// Limits maps model names, or family prefixes ending in "*", to the maximum number of
+	// concurrent requests for each matching model. An exact entry wins over prefixes and the
+	// longest prefix wins among families; models without an entry are unlimited.
+	Limits map[string]int
+	// QueueTimeout is how long a request beyond the limit waits for a slot. Zero or less
+	// rejects it immediately with 429.
+	QueueTimeout time.Duration
+	// FlexQueueTimeout is how long a request with the "flex" service tier waits instead.
+	// Flex requests accept higher latency, so this is usually longer; zero uses QueueTimeout.
+	FlexQueueTimeout time.Duration
+}
+
+// ServiceTierMetadataKey carries the OpenAI service_tier of a request in the execution options
+// metadata. Providers without service tiers never see it; the auth manager uses it to let
+// "flex" requests queue longer under the concurrency limits.
+const ServiceTierMetadataKey = "service_tier"
+
+// ServiceTierFlex is the service tier that trades latency for cost.
+const ServiceTierFlex = "flex"
+
+// ModelConcurrencyStatus reports the in-flight requests of a limited model.
+type ModelConcurrencyStatus struct {
+	Model    string `json:"model"`
+	Limit    int    `json:"limit"`
+	InFlight int    `json:"in_flight"`
+	Queued   int    `json:"queued"`
+}
+
+type modelSlots struct {
+	sem    chan struct{}
+	queued atomic.Int64
+}
+
+// modelConcurrency holds one semaphore per limited model. Replacing the configuration starts
+// fresh semaphores; requests admitted earlier release into the semaphore they acquired.
+type modelConcurrency struct {
+	mu    sync.Mutex
+	cfg   ConcurrencyConfig
+	slots map[string]*modelSlots
+}
+
+// SetConcurrencyLimits configures the per-model concurrency limits. Passing a configuration
+// without limits disables limiting.
+func (m *Manager) SetConcurrencyLimits(cfg ConcurrencyConfig) {
+	normalized := make(map[string]int, len(cfg.Limits))
+	for model, limit := range cfg.Limits {
+		key := strings.ToLower(strings.TrimSpace(model))
+		if key == "" || limit <= 0 {
+			continue
+		}
+		normalized[key] = limit
+	}
+	cfg.Limits = normalized
+	m.concurrency.mu.Lock()
+	defer m.concurrency.mu.Unlock()
+	if reflect.DeepEqual(m.concurrency.cfg, cfg) {
+		return
+	}
+	m.concurrency.cfg = cfg
+	m.concurrency.slots = nil
+}
+
+// ModelConcurrency returns the current load of every limited model that has served a
+// request, sorted by model.
+func (m *Manager) ModelConcurrency() []ModelConcurrencyStatus {
+	m.concurrency.mu.Lock()
+	out := make([]ModelConcurrencyStatus, 0, len(m.concurrency.slots))
+	for model, slots := range m.concurrency.slots {
+		out = append(out, ModelConcurrencyStatus{
+			Model:    model,
+			Limit:    cap(slots.sem),
+			InFlight: len(slots.sem),
+			Queued:   int(slots.queued.Load()),
+		})
+	}
+	m.concurrency.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Model < out[j].Model })
+	return out
+}
+
+// limitFor returns the configured limit of model, or zero when it is unlimited.
+func (c *modelConcurrency) limitFor(model string) int {
+	if limit, ok := c.cfg.Limits[model]; ok {
+		return limit
+	}
+	limit, longest := 0, -1
+	for pattern, configured := range c.cfg.Limits {
+		prefix, isFamily := strings.CutSuffix(pattern, "*")
+		if isFamily && len(prefix) > longest && strings.HasPrefix(model, prefix) {
+			limit, longest = configured, len(prefix)
+		}
+	}
+	return limit
+}
+
+// acquire takes a slot for model, waiting up to the queue timeout when the model is at its
+// limit; flex requests wait up to the flex queue timeout. The returned release is safe to call
+// more than once and must be called when the request finishes, whether it succeeded, failed
+// or panicked.
+func (c *modelConcurrency) acquire(ctx context.Context, model string, flex bool) (func(), error) {
+	key := strings.ToLower(strings.TrimSpace(model))
+	c.mu.Lock()
+	limit := c.limitFor(key)
+	if limit <= 0 {
+		c.mu.Unlock()
+		return func() {}, nil
+	}
+	if c.slots == nil {
+		c.slots = make(map[string]*modelSlots)
+	}
+	slots, ok := c.slots[key]
+	if !ok {
+		slots = &modelSlots{sem: make(chan struct{}, limit)}
+		c.slots[key] = slots
+	}
+	timeout := c.cfg.QueueTimeout
+	if flex && c.cfg.FlexQueueTimeout > 0 {
+		timeout = c.cfg.FlexQueueTimeout
+	}
+	c.mu.Unlock()
+
+	var once sync.Once
+	release := func() { once.Do(func() { <-slots.sem }) }
+	select {
+	case slots.sem <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if timeout <= 0 {
+		return nil, concurrencyLimitError(model, limit)
+	}
+
+	slots.queued.Add(1)
+	defer slots.queued.Add(-1)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case slots.sem <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, concurrencyLimitError(model, limit)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func concurrencyLimitError(model string, limit int) *Error {
+	return &Error{
+		Code:       "concurrency_limited",
+		Message:    fmt.Sprintf("model %s is at its limit of %d concurrent requests", model, limit),
+		Retryable:  true,
+		HTTPStatus: http.StatusTooManyRequests,
+	}
+}
+
+// isFlexServiceTier reports whether the execution metadata asks for the flex service tier.
+func isFlexServiceTier(metadata map[string]any) bool {
+	tier, _ := metadata[ServiceTierMetadataKey].(string)
+	return tier == ServiceTierFlex
+}
+
+// releaseOnClose forwards chunks and calls release once the stream has ended, so a streaming
+// request keeps its slot for as long as the upstream stream is open.
+func releaseOnClose(ctx context.Context, chunks <-chan cliproxyexecutor.StreamChunk, release func()) <-chan cliproxyexecutor.StreamChunk {
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		defer release()
+		for chunk := range chunks {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// registerLimitedModel makes limited-model available to the given credentials.
+func registerLimitedModel(t *testing.T, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		registry.GetGlobalRegistry().RegisterClient(id, "test", []*registry.ModelInfo{{ID: "limited-model"}})
+	}
+	t.Cleanup(func() {
+		for _, id := range ids {
+			registry.GetGlobalRegistry().UnregisterClient(id)
+		}
+	})
+}
+
+// newConcurrencyTestManager returns a manager whose streams stay open until the returned
+// channel is closed.
+func newConcurrencyTestManager(t *testing.T, cfg ConcurrencyConfig) (*Manager, chan cliproxyexecutor.StreamChunk) {
+	t.Helper()
+	upstream := make(chan cliproxyexecutor.StreamChunk)
+	executor := &failoverTestExecutor{stream: func(context.Context) <-chan cliproxyexecutor.StreamChunk { return upstream }}
+	m := newFailoverTestManager(t, executor, "a", "b")
+	registerLimitedModel(t, "a", "b")
+	m.SetConcurrencyLimits(cfg)
+	return m, upstream
+}
+
+func startConcurrencyTestStream(m *Manager) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return m.ExecuteStream(context.Background(), []string{"test"}, cliproxyexecutor.Request{Model: "limited-model"}, cliproxyexecutor.Options{})
+}
+
+func waitForConcurrency(t *testing.T, m *Manager, inFlight, queued int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		loads := m.ModelConcurrency()
+		if len(loads) == 1 && loads[0].InFlight == inFlight && loads[0].Queued == queued {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d in flight and %d queued, got %+v", inFlight, queued, loads)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestModelConcurrency_RejectsBeyondLimit(t *testing.T) {
+	m, upstream := newConcurrencyTestManager(t, ConcurrencyConfig{Limits: map[string]int{"limited-*": 1}})
+
+	first, err := startConcurrencyTestStream(m)
+	if err != nil {
+		t.Fatalf("Expected the first stream to start, got %v", err)
+	}
+	waitForConcurrency(t, m, 1, 0)
+
+	_, err = startConcurrencyTestStream(m)
+	var limitErr *Error
+	if !errors.As(err, &limitErr) || limitErr.Code != "concurrency_limited" || limitErr.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("Expected a 429 concurrency error, got %v", err)
+	}
+
+	close(upstream)
+	for range first {
+	}
+	waitForConcurrency(t, m, 0, 0)
+	if _, err = m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{Model: "limited-model"}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Expected a request after the stream ended to pass, got %v", err)
+	}
+}
+
+func TestModelConcurrency_QueuesUntilSlotFrees(t *testing.T) {
+	m, upstream := newConcurrencyTestManager(t, ConcurrencyConfig{Limits: map[string]int{"limited-model": 1}, QueueTimeout: 5 * time.Second})
+
+	first, err := startConcurrencyTestStream(m)
+	if err != nil {
+		t.Fatalf("Expected the first stream to start, got %v", err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, errQueued := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{Model: "limited-model"}, cliproxyexecutor.Options{})
+		errCh <- errQueued
+	}()
+	waitForConcurrency(t, m, 1, 1)
+
+	close(upstream)
+	for range first {
+	}
+	if err = <-errCh; err != nil {
+		t.Fatalf("Expected the queued request to run once the slot freed, got %v", err)
+	}
+	waitForConcurrency(t, m, 0, 0)
+}
+
+func TestModelConcurrency_QueueTimeout(t *testing.T) {
+	m, upstream := newConcurrencyTestManager(t, ConcurrencyConfig{Limits: map[string]int{"limited-model": 1}, QueueTimeout: 20 * time.Millisecond})
+	defer close(upstream)
+
+	if _, err := startConcurrencyTestStream(m); err != nil {
+		t.Fatalf("Expected the first stream to start, got %v", err)
+	}
+	started := time.Now()
+	_, err := startConcurrencyTestStream(m)
+	var limitErr *Error
+	if !errors.As(err, &limitErr) || limitErr.Code != "concurrency_limited" {
+		t.Fatalf("Expected a concurrency error after the queue timeout, got %v", err)
+	}
+	if waited := time.Since(started); waited < 20*time.Millisecond {
+		t.Fatalf("Expected the request to wait for the queue timeout, waited %v", waited)
+	}
+}
+
+func TestModelConcurrency_ReleasesOnErrorAndPanic(t *testing.T) {
+	executor := &failoverTestExecutor{
+		failures: map[string]error{"a": &testStatusError{code: http.StatusBadRequest, msg: "bad request"}},
+		stream:   func(context.Context) <-chan cliproxyexecutor.StreamChunk { panic("executor bug") },
+	}
+	m := newFailoverTestManager(t, executor, "a")
+	registerLimitedModel(t, "a")
+	m.SetConcurrencyLimits(ConcurrencyConfig{Limits: map[string]int{"limited-model": 1}})
+
+	if _, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{Model: "limited-model"}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Expected the upstream error")
+	}
+	waitForConcurrency(t, m, 0, 0)
+
+	func() {
+		defer func() { _ = recover() }()
+		_, _ = startConcurrencyTestStream(m)
+	}()
+	waitForConcurrency(t, m, 0, 0)
+}
+
+func TestModelConcurrency_FlexServiceTierQueuesLonger(t *testing.T) {
+	m, upstream := newConcurrencyTestManager(t, ConcurrencyConfig{Limits: map[string]int{"limited-model": 1}, FlexQueueTimeout: 5 * time.Second})
+
+	first, err := startConcurrencyTestStream(m)
+	if err != nil {
+		t.Fatalf("Expected the first stream to start, got %v", err)
+	}
+	if _, err = startConcurrencyTestStream(m); err == nil {
+		t.Fatal("Expected a default-tier request to be rejected without a queue timeout")
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		flex := cliproxyexecutor.Options{Metadata: map[string]any{ServiceTierMetadataKey: ServiceTierFlex}}
+		_, errQueued := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{Model: "limited-model"}, flex)
+		errCh <- errQueued
+	}()
+	waitForConcurrency(t, m, 1, 1)
+
+	close(upstream)
+	for range first {
+	}
+	if err = <-errCh; err != nil {
+		t.Fatalf("Expected the flex request to wait for the slot, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CredentialStore is the source the manager loads credentials from. The default wraps the
+// persistence Store, so credentials come from the auth directory; hosts can plug in a remote
+// source such as Vault or AWS Secrets Manager instead.
+type CredentialStore interface {
+	// List returns every credential currently held by the store.
+	List(ctx context.Context) ([]*Auth, error)
+	// Get returns the credential identified by id.
+	Get(ctx context.Context, id string) (*Auth, error)
+	// Watch streams the full credential set each time it changes, until ctx is done. Every
+	// value replaces the manager's credentials atomically, like an admin reload.
+	Watch(ctx context.Context) (<-chan []*Auth, error)
+}
+
+// StoreCredentials adapts a persistence Store into the default CredentialStore. Its Watch
+// never emits: changes to file-backed credentials reach the manager through the config
+// watcher instead.
+func StoreCredentials(store Store) CredentialStore {
+	if store == nil {
+		return nil
+	}
+	return storeCredentials{store: store}
+}
+
+type storeCredentials struct {
+	store Store
+}
+
+func (s storeCredentials) List(ctx context.Context) ([]*Auth, error) {
+	return s.store.List(ctx)
+}
+
+func (s storeCredentials) Get(ctx context.Context, id string) (*Auth, error) {
+	items, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, auth := range items {
+		if auth != nil && auth.ID == id {
+			return auth, nil
+		}
+	}
+	return nil, fmt.Errorf("credential %s not found", id)
+}
+
+func (s storeCredentials) Watch(ctx context.Context) (<-chan []*Auth, error) {
+	updates := make(chan []*Auth)
+	go func() {
+		<-ctx.Done()
+		close(updates)
+	}()
+	return updates, nil
+}
+
+// SetCredentialStore replaces the source Load and WatchCredentials read credentials from.
+// Nil falls back to the persistence store.
+func (m *Manager) SetCredentialStore(store CredentialStore) {
+	m.mu.Lock()
+	m.credentials = store
+	m.mu.Unlock()
+}
+
+// credentialStore returns the configured credential source, defaulting to the persistence store.
+func (m *Manager) credentialStore() CredentialStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.credentials != nil {
+		return m.credentials
+	}
+	return StoreCredentials(m.store)
+}
+
+// WatchCredentials applies the credential sets pushed by the credential store until ctx is
+// done or the store closes its channel. Each set goes to apply, which lets the host register
+// executors and models first; nil applies it with ReplaceAuths directly.
+func (m *Manager) WatchCredentials(ctx context.Context, apply func(context.Context, []*Auth)) error {
+	store := m.credentialStore()
+	if store == nil {
+		return nil
+	}
+	updates, err := store.Watch(ctx)
+	if err != nil {
+		return err
+	}
+	if apply == nil {
+		apply = func(ctx context.Context, auths []*Auth) { m.ReplaceAuths(ctx, auths) }
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case auths, ok := <-updates:
+				if !ok {
+					return
+				}
+				apply(ctx, auths)
+				log.Debugf("credential store pushed %d credentials", len(auths))
+			}
+		}
+	}()
+	return nil
+}
+
+// ReplaceAuths swaps in auths as the complete credential set in one step, so a request never
+// sees a mix of old and new credentials. Credentials that are no longer present are disabled,
+// as on a reload; requests already running on them complete with the credential they hold.
+// The IDs of the disabled credentials are returned.
+func (m *Manager) ReplaceAuths(ctx context.Context, auths []*Auth) []string {
+	incoming := make(map[string]*Auth, len(auths))
+	for _, auth := range auths {
+		if auth == nil || auth.ID == "" {
+			continue
+		}
+		incoming[auth.ID] = auth.Clone()
+	}
+	var registered, updated []*Auth
+	var removedIDs []string
+	m.mu.Lock()
+	for id, auth := range incoming {
+		if existing, ok := m.auths[id]; ok && existing != nil {
+			if !auth.indexAssigned && auth.Index == 0 {
+				auth.Index = existing.Index
+				auth.indexAssigned = existing.indexAssigned
+			}
+			updated = append(updated, auth)
+		} else {
+			registered = append(registered, auth)
+		}
+		auth.EnsureIndex()
+		m.auths[id] = auth
+	}
+	for id, existing := range m.auths {
+		if _, ok := incoming[id]; ok || existing == nil || existing.Disabled {
+			continue
+		}
+		removed := existing.Clone()
+		removed.Disabled = true
+		removed.Status = StatusDisabled
+		m.auths[id] = removed
+		updated = append(updated, removed)
+		removedIDs = append(removedIDs, id)
+	}
+	m.mu.Unlock()
+	for _, auth := range registered {
+		m.hook.OnAuthRegistered(ctx, auth.Clone())
+	}
+	for _, auth := range updated {
+		m.hook.OnAuthUpdated(ctx, auth.Clone())
+	}
+	return removedIDs
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// memoryCredentialStore is an in-memory CredentialStore whose Watch emits on push.
+type memoryCredentialStore struct {
+	mu      sync.Mutex
+	auths   []*Auth
+	updates chan []*Auth
+}
+
+func (s *memoryCredentialStore) List(context.Context) ([]*Auth, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.auths, nil
+}
+
+func (s *memoryCredentialStore) Get(_ context.Context, id string) (*Auth, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, auth := range s.auths {
+		if auth.ID == id {
+			return auth, nil
+		}
+	}
+	return nil, fmt.Errorf("credential %s not found", id)
+}
+
+func (s *memoryCredentialStore) Watch(context.Context) (<-chan []*Auth, error) {
+	return s.updates, nil
+}
+
+func (s *memoryCredentialStore) push(auths ...*Auth) {
+	s.mu.Lock()
+	s.auths = auths
+	s.mu.Unlock()
+	s.updates <- auths
+}
+
+func TestManagerWatchCredentials_UsesRefreshedCredentials(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &memoryCredentialStore{
+		auths:   []*Auth{{ID: "old-key", Provider: "test"}},
+		updates: make(chan []*Auth),
+	}
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(&failoverTestExecutor{})
+	m.SetCredentialStore(store)
+	if err := m.Load(ctx); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := m.WatchCredentials(ctx, nil); err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+
+	served := func() string {
+		resp, err := m.Execute(ctx, []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+		if err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+		return string(resp.Payload)
+	}
+	if got := served(); got != "old-key" {
+		t.Fatalf("Expected the loaded credential, got %q", got)
+	}
+
+	store.push(&Auth{ID: "new-key", Provider: "test"})
+	deadline := time.Now().Add(2 * time.Second)
+	for served() != "new-key" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected new requests to use the refreshed credential")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 4; i++ {
+		if got := served(); got != "new-key" {
+			t.Fatalf("Expected the removed credential to stay out of rotation, got %q", got)
+		}
+	}
+	if old, ok := m.GetByID("old-key"); !ok || !old.Disabled {
+		t.Fatalf("Expected the removed credential to be disabled, got %+v", old)
+	}
+}
+
+func TestStoreCredentials_Get(t *testing.T) {
+	store := StoreCredentials(&memoryStore{auths: []*Auth{{ID: "a"}, {ID: "b"}}})
+	if auth, err := store.Get(context.Background(), "b"); err != nil || auth.ID != "b" {
+		t.Fatalf("Expected credential b, got %v (%v)", auth, err)
+	}
+	if _, err := store.Get(context.Background(), "missing"); err == nil {
+		t.Fatal("Expected an error for an unknown credential")
+	}
+}
+
+// memoryStore is a persistence Store backed by a slice.
+type memoryStore struct {
+	auths []*Auth
+}
+
+func (s *memoryStore) List(context.Context) ([]*Auth, error) { return s.auths, nil }
+
+func (s *memoryStore) Save(_ context.Context, auth *Auth) (string, error) {
+	s.auths = append(s.auths, auth)
+	return auth.ID, nil
+}
+
+func (s *memoryStore) Delete(context.Context, string) error { return nil }
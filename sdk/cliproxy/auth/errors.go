@@ -1,5 +1,11 @@
 package auth
 
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
 // Error describes an authentication related failure in a provider agnostic format.
 type Error struct {
 	// Code is a short machine readable identifier.
@@ -30,3 +36,71 @@ func (e *Error) StatusCode() int {
 	}
 	return e.HTTPStatus
 }
+
+// CredentialAttemptsHeader reports how many credentials were tried before a request failed.
+const CredentialAttemptsHeader = "X-CLIProxy-Credentials-Tried"
+
+// CredentialAttemptsError wraps the last upstream failure after failing over across
+// several credentials of the same provider. The error message is left untouched so
+// upstream error bodies are still returned verbatim to clients.
+type CredentialAttemptsError struct {
+	// Err is the error returned by the last credential that was tried.
+	Err error
+	// Attempts is the number of credentials that were tried.
+	Attempts int
+}
+
+// withCredentialAttempts annotates err with the number of credentials tried when more than one was used.
+func withCredentialAttempts(err error, attempts int) error {
+	if err == nil || attempts <= 1 {
+		return err
+	}
+	return &CredentialAttemptsError{Err: err, Attempts: attempts}
+}
+
+// Error implements the error interface.
+func (e *CredentialAttemptsError) Error() string {
+	if e == nil || e.Err == nil {
+		return ""
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *CredentialAttemptsError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// StatusCode returns the status code of the underlying error.
+func (e *CredentialAttemptsError) StatusCode() int {
+	if e == nil {
+		return 0
+	}
+	return statusCodeFromError(e.Err)
+}
+
+// RetryAfter returns the retry hint of the underlying error, if any.
+func (e *CredentialAttemptsError) RetryAfter() *time.Duration {
+	if e == nil {
+		return nil
+	}
+	return retryAfterFromError(e.Err)
+}
+
+// Headers returns the underlying error headers plus the credential attempt count.
+func (e *CredentialAttemptsError) Headers() http.Header {
+	headers := make(http.Header)
+	if e == nil {
+		return headers
+	}
+	if he, ok := e.Err.(interface{ Headers() http.Header }); ok && he != nil {
+		if hdr := he.Headers(); hdr != nil {
+			headers = hdr.Clone()
+		}
+	}
+	headers.Set(CredentialAttemptsHeader, strconv.Itoa(e.Attempts))
+	return headers
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultPingCacheTTL is used when ReadinessOptions enables pings without a cache interval.
+const defaultPingCacheTTL = time.Minute
+
+// Pinger is implemented by provider executors that can cheaply verify a credential
+// against the upstream. Providers whose executor does not implement it are judged on
+// local credential state alone.
+type Pinger interface {
+	Ping(ctx context.Context, auth *Auth) error
+}
+
+// ReadinessOptions controls how Readiness evaluates providers.
+type ReadinessOptions struct {
+	// Ping enables an upstream ping through executors implementing Pinger.
+	Ping bool
+	// PingTTL caches ping results per provider so probes do not hammer upstreams.
+	PingTTL time.Duration
+}
+
+// ProviderHealth reports the readiness of a single provider.
+type ProviderHealth struct {
+	Provider    string `json:"provider"`
+	Ready       bool   `json:"ready"`
+	Credentials int    `json:"credentials"`
+	Available   int    `json:"available"`
+	// OpenCircuits counts credentials whose circuit breaker is currently open.
+	OpenCircuits int `json:"open_circuits,omitempty"`
+	// QuotaExhausted counts credentials that reached their configured usage quota.
+	QuotaExhausted int    `json:"quota_exhausted,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+type pingResult struct {
+	err       error
+	checkedAt time.Time
+}
+
+type pingCache struct {
+	mu      sync.Mutex
+	results map[string]pingResult
+}
+
+// Readiness reports, per enabled provider, whether at least one credential is usable.
+// A provider is enabled when it has at least one credential that is not disabled; a
+// credential is usable when it is neither disabled, cooling down, behind an open circuit
+// breaker, nor out of quota. Results are sorted by provider name.
+func (m *Manager) Readiness(ctx context.Context, opts ReadinessOptions) []ProviderHealth {
+	now := time.Now()
+	type providerState struct {
+		health ProviderHealth
+		usable *Auth
+	}
+	states := make(map[string]*providerState)
+	executors := make(map[string]ProviderExecutor)
+
+	m.mu.RLock()
+	for _, auth := range m.auths {
+		if auth == nil || auth.Disabled || auth.Status == StatusDisabled {
+			continue
+		}
+		state, ok := states[auth.Provider]
+		if !ok {
+			state = &providerState{health: ProviderHealth{Provider: auth.Provider}}
+			states[auth.Provider] = state
+			executors[auth.Provider] = m.executors[auth.Provider]
+		}
+		state.health.Credentials++
+		if m.breakers.state(auth.ID, now) == CircuitOpen {
+			state.health.OpenCircuits++
+			continue
+		}
+		if m.quotas.exhausted(auth.ID, strings.ToLower(auth.Provider), now) {
+			state.health.QuotaExhausted++
+			continue
+		}
+		if authUsable(auth, now) {
+			state.health.Available++
+			if state.usable == nil || auth.ID < state.usable.ID {
+				state.usable = auth
+			}
+		}
+	}
+	for _, state := range states {
+		state.usable = state.usable.Clone()
+	}
+	m.mu.RUnlock()
+
+	result := make([]ProviderHealth, 0, len(states))
+	for provider, state := range states {
+		health := state.health
+		switch {
+		case health.Available == 0 && health.OpenCircuits > 0:
+			health.Reason = "all credentials are cooling down or have an open circuit breaker"
+		case health.Available == 0 && health.QuotaExhausted > 0:
+			health.Reason = "all credentials are cooling down or have exhausted their quota"
+		case health.Available == 0:
+			health.Reason = "all credentials are cooling down"
+		case opts.Ping:
+			if pinger, ok := executors[provider].(Pinger); ok {
+				if err := m.cachedPing(ctx, pinger, state.usable, opts.PingTTL, now); err != nil {
+					health.Reason = "upstream ping failed: " + err.Error()
+				}
+			}
+		}
+		health.Ready = health.Reason == ""
+		result = append(result, health)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Provider < result[j].Provider })
+	return result
+}
+
+func (m *Manager) cachedPing(ctx context.Context, pinger Pinger, auth *Auth, ttl time.Duration, now time.Time) error {
+	if ttl <= 0 {
+		ttl = defaultPingCacheTTL
+	}
+	m.pings.mu.Lock()
+	defer m.pings.mu.Unlock()
+	if cached, ok := m.pings.results[auth.Provider]; ok && now.Sub(cached.checkedAt) < ttl {
+		return cached.err
+	}
+	err := pinger.Ping(ctx, auth)
+	if m.pings.results == nil {
+		m.pings.results = make(map[string]pingResult)
+	}
+	m.pings.results[auth.Provider] = pingResult{err: err, checkedAt: now}
+	return err
+}
+
+// authUsable reports whether auth can currently serve requests. Credentials whose every
+// tracked model is blocked count as unusable.
+func authUsable(auth *Auth, now time.Time) bool {
+	if blocked, _, _ := isAuthBlockedForModel(auth, "", now); blocked {
+		return false
+	}
+	if len(auth.ModelStates) == 0 {
+		return true
+	}
+	for model := range auth.ModelStates {
+		if blocked, _, _ := isAuthBlockedForModel(auth, model, now); !blocked {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type pingTestExecutor struct {
+	failoverTestExecutor
+	pings int
+	err   error
+}
+
+func (e *pingTestExecutor) Ping(context.Context, *Auth) error {
+	e.pings++
+	return e.err
+}
+
+func TestManagerReadiness_Ready(t *testing.T) {
+	m := newFailoverTestManager(t, &failoverTestExecutor{}, "a", "b")
+	now := time.Now()
+	m.auths["a"].Unavailable = true
+	m.auths["a"].NextRetryAfter = now.Add(time.Minute)
+
+	health := m.Readiness(context.Background(), ReadinessOptions{})
+	if len(health) != 1 {
+		t.Fatalf("Expected one provider, got %+v", health)
+	}
+	if !health[0].Ready || health[0].Credentials != 2 || health[0].Available != 1 {
+		t.Fatalf("Expected provider ready with one available credential, got %+v", health[0])
+	}
+}
+
+func TestManagerReadiness_NotReady(t *testing.T) {
+	m := newFailoverTestManager(t, &failoverTestExecutor{}, "a", "b", "c")
+	now := time.Now()
+	m.auths["a"].Unavailable = true
+	m.auths["a"].NextRetryAfter = now.Add(time.Minute)
+	m.auths["b"].ModelStates = map[string]*ModelState{
+		"m": {Unavailable: true, NextRetryAfter: now.Add(time.Minute)},
+	}
+	m.auths["c"].Disabled = true
+
+	health := m.Readiness(context.Background(), ReadinessOptions{})
+	if len(health) != 1 || health[0].Ready {
+		t.Fatalf("Expected provider not ready, got %+v", health)
+	}
+	if health[0].Credentials != 2 || health[0].Available != 0 || health[0].Reason == "" {
+		t.Fatalf("Expected disabled credential excluded and a reason, got %+v", health[0])
+	}
+}
+
+func TestManagerReadiness_PingIsCached(t *testing.T) {
+	executor := &pingTestExecutor{err: errors.New("status 401")}
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(executor)
+	if _, err := m.Register(context.Background(), &Auth{ID: "a", Provider: "test"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	opts := ReadinessOptions{Ping: true, PingTTL: time.Hour}
+	for i := 0; i < 3; i++ {
+		health := m.Readiness(context.Background(), opts)
+		if len(health) != 1 || health[0].Ready {
+			t.Fatalf("Expected failed ping to mark provider not ready, got %+v", health)
+		}
+	}
+	if executor.pings != 1 {
+		t.Fatalf("Expected ping result to be cached, got %d pings", executor.pings)
+	}
+
+	if health := m.Readiness(context.Background(), ReadinessOptions{}); !health[0].Ready {
+		t.Fatalf("Expected provider ready when pings are disabled, got %+v", health)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
@@ -43,8 +45,23 @@ const (
 	refreshFailureBackoff = 5 * time.Minute
 	quotaBackoffBase      = time.Second
 	quotaBackoffMax       = 30 * time.Minute
+
+	defaultRetryBackoffBase = 500 * time.Millisecond
+	defaultRetryBackoffMax  = 30 * time.Second
 )
 
+// PinnedAuthMetadataKey restricts credential selection to a single auth ID when present in
+// the execution options metadata, so the request never fails over to another credential.
+const PinnedAuthMetadataKey = "pinned_auth_id"
+
+// retryJitter returns a random duration in [0, n]; replaced in tests.
+var retryJitter = func(n time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(n) + 1))
+}
+
 var quotaCooldownDisabled atomic.Bool
 
 // SetQuotaCooldownDisabled toggles quota cooldown scheduling globally.
@@ -109,6 +126,36 @@ type Manager struct {
 	// Retry controls request retry behavior.
 	requestRetry     atomic.Int32
 	maxRetryInterval atomic.Int64
+	// credentialAttempts caps how many credentials of one provider are tried per request (0 = all).
+	credentialAttempts atomic.Int32
+	// retryBackoffBase and retryBackoffMax shape the exponential backoff between retries.
+	retryBackoffBase atomic.Int64
+	retryBackoffMax  atomic.Int64
+	// attemptTimeout and streamAttemptTimeout bound each upstream call; requestDeadline bounds
+	// a request across all of its retries. Zero disables each.
+	attemptTimeout       atomic.Int64
+	streamAttemptTimeout atomic.Int64
+	requestDeadline      atomic.Int64
+
+	// pings caches upstream readiness pings per provider.
+	pings pingCache
+	// breakers fails fast on credentials whose upstream keeps failing.
+	breakers circuitBreakers
+	// quotas stops routing to credentials that reached their configured usage quota.
+	quotas quotaTracker
+	// sessions keeps client sessions on the credential that served them last.
+	sessions sessionAffinity
+	// concurrency caps the requests in flight per model.
+	concurrency modelConcurrency
+	// refreshes coalesces concurrent token refreshes of the same credential.
+	refreshes refreshFlights
+	// retryClass decides which upstream failures are retried.
+	retryClass retryClassifier
+	// rewrites maps canonical model names to the names individual providers expect.
+	rewrites modelRewriter
+
+	// credentials is the source Load and WatchCredentials read from; nil uses store.
+	credentials CredentialStore
 
 	// Optional HTTP RoundTripper provider injected by host.
 	rtProvider RoundTripperProvider
@@ -142,6 +189,40 @@ func (m *Manager) SetStore(store Store) {
 	m.store = store
 }
 
+// SetSelector replaces the credential selector, for example so tests can pin which credential
+// serves a request. Nil restores the default round-robin selector.
+func (m *Manager) SetSelector(selector Selector) {
+	if selector == nil {
+		selector = &RoundRobinSelector{}
+	}
+	m.mu.Lock()
+	m.selector = selector
+	m.mu.Unlock()
+}
+
+// SetSelectionMode switches between the built-in selectors: "deterministic" selects the
+// DeterministicSelector and anything else round-robin. A custom selector installed through
+// NewManager or SetSelector is left in place.
+func (m *Manager) SetSelectionMode(mode string) {
+	if m == nil {
+		return
+	}
+	deterministic := strings.EqualFold(strings.TrimSpace(mode), "deterministic")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch m.selector.(type) {
+	case *RoundRobinSelector:
+		if deterministic {
+			log.Warn("account selection is deterministic; requests are not balanced across credentials")
+			m.selector = DeterministicSelector{}
+		}
+	case DeterministicSelector:
+		if !deterministic {
+			m.selector = &RoundRobinSelector{}
+		}
+	}
+}
+
 // SetRoundTripperProvider register a provider that returns a per-auth RoundTripper.
 func (m *Manager) SetRoundTripperProvider(p RoundTripperProvider) {
 	m.mu.Lock()
@@ -149,7 +230,9 @@ func (m *Manager) SetRoundTripperProvider(p RoundTripperProvider) {
 	m.mu.Unlock()
 }
 
-// SetRetryConfig updates retry attempts and cooldown wait interval.
+// SetRetryConfig updates retry attempts and cooldown wait interval. A retry count of zero
+// makes exactly one attempt. Retries are separate from failover: within every attempt each
+// provider and up to the credential attempt limit of its credentials are still tried once.
 func (m *Manager) SetRetryConfig(retry int, maxRetryInterval time.Duration) {
 	if m == nil {
 		return
@@ -164,6 +247,107 @@ func (m *Manager) SetRetryConfig(retry int, maxRetryInterval time.Duration) {
 	m.maxRetryInterval.Store(maxRetryInterval.Nanoseconds())
 }
 
+// SetCredentialAttempts limits how many credentials of the same provider are tried
+// before a request fails. Zero or a negative value tries every available credential.
+func (m *Manager) SetCredentialAttempts(maxAttempts int) {
+	if m == nil {
+		return
+	}
+	if maxAttempts < 0 {
+		maxAttempts = 0
+	}
+	m.credentialAttempts.Store(int32(maxAttempts))
+}
+
+// SetRetryBackoff configures exponential backoff with full jitter between retries.
+// Non-positive values fall back to the defaults (500ms base, 30s max).
+func (m *Manager) SetRetryBackoff(base, maxDelay time.Duration) {
+	if m == nil {
+		return
+	}
+	if base < 0 {
+		base = 0
+	}
+	if maxDelay < 0 {
+		maxDelay = 0
+	}
+	m.retryBackoffBase.Store(base.Nanoseconds())
+	m.retryBackoffMax.Store(maxDelay.Nanoseconds())
+}
+
+// SetTimeouts configures the per-attempt timeouts of non-streaming and streaming upstream
+// calls and the overall deadline of a request across all retries. Non-positive values disable
+// the respective limit. The streaming timeout covers the whole stream.
+func (m *Manager) SetTimeouts(attempt, streamAttempt, deadline time.Duration) {
+	if m == nil {
+		return
+	}
+	m.attemptTimeout.Store(max(attempt, 0).Nanoseconds())
+	m.streamAttemptTimeout.Store(max(streamAttempt, 0).Nanoseconds())
+	m.requestDeadline.Store(max(deadline, 0).Nanoseconds())
+}
+
+// withRequestDeadline bounds ctx by the configured overall request deadline.
+func (m *Manager) withRequestDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m != nil {
+		if deadline := time.Duration(m.requestDeadline.Load()); deadline > 0 {
+			return context.WithTimeout(ctx, deadline)
+		}
+	}
+	return ctx, func() {}
+}
+
+// withAttemptTimeout bounds a single upstream call by the configured per-attempt timeout.
+func (m *Manager) withAttemptTimeout(ctx context.Context, stream bool) (context.Context, context.CancelFunc) {
+	if m != nil {
+		timeout := time.Duration(m.attemptTimeout.Load())
+		if stream {
+			timeout = time.Duration(m.streamAttemptTimeout.Load())
+		}
+		if timeout > 0 {
+			return context.WithTimeout(ctx, timeout)
+		}
+	}
+	return ctx, func() {}
+}
+
+// retryBackoff returns the full-jitter delay for the given zero-based retry attempt.
+func (m *Manager) retryBackoff(attempt int) time.Duration {
+	return retryJitter(m.retryBackoffCeiling(attempt))
+}
+
+// retryBackoffCeiling returns the upper bound of the backoff for the given retry attempt.
+func (m *Manager) retryBackoffCeiling(attempt int) time.Duration {
+	base := defaultRetryBackoffBase
+	maxDelay := defaultRetryBackoffMax
+	if m != nil {
+		if v := time.Duration(m.retryBackoffBase.Load()); v > 0 {
+			base = v
+		}
+		if v := time.Duration(m.retryBackoffMax.Load()); v > 0 {
+			maxDelay = v
+		}
+	}
+	if base > maxDelay {
+		base = maxDelay
+	}
+	ceiling := base
+	for i := 0; i < attempt && ceiling < maxDelay; i++ {
+		ceiling *= 2
+	}
+	if ceiling > maxDelay {
+		ceiling = maxDelay
+	}
+	return ceiling
+}
+
+func (m *Manager) credentialAttemptLimit() int {
+	if m == nil {
+		return 0
+	}
+	return int(m.credentialAttempts.Load())
+}
+
 // RegisterExecutor registers a provider executor with the manager.
 func (m *Manager) RegisterExecutor(executor ProviderExecutor) {
 	if executor == nil {
@@ -220,17 +404,18 @@ func (m *Manager) Update(ctx context.Context, auth *Auth) (*Auth, error) {
 	return auth.Clone(), nil
 }
 
-// Load resets manager state from the backing store.
+// Load resets manager state from the credential store.
 func (m *Manager) Load(ctx context.Context) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if m.store == nil {
+	store := m.credentialStore()
+	if store == nil {
 		return nil
 	}
-	items, err := m.store.List(ctx)
+	items, err := store.List(ctx)
 	if err != nil {
 		return err
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.auths = make(map[string]*Auth, len(items))
 	for _, auth := range items {
 		if auth == nil || auth.ID == "" {
@@ -251,12 +436,15 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 	}
 	rotated := m.rotateProviders(req.Model, normalized)
 	defer m.advanceProviderCursor(req.Model, normalized)
-
-	retryTimes, maxWait := m.retrySettings()
-	attempts := retryTimes + 1
-	if attempts < 1 {
-		attempts = 1
+	ctx, cancel := m.withRequestDeadline(ctx)
+	defer cancel()
+	release, errSlot := m.concurrency.acquire(ctx, req.Model, isFlexServiceTier(opts.Metadata))
+	if errSlot != nil {
+		return cliproxyexecutor.Response{}, errSlot
 	}
+	defer release()
+
+	attempts, maxWait := m.retrySettings()
 
 	var lastErr error
 	for attempt := 0; attempt < attempts; attempt++ {
@@ -264,10 +452,13 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 			return m.executeWithProvider(execCtx, provider, req, opts)
 		})
 		if errExec == nil {
+			if errTransform := cliproxyexecutor.TransformResponse(ctx, &resp); errTransform != nil {
+				return cliproxyexecutor.Response{}, errTransform
+			}
 			return resp, nil
 		}
 		lastErr = errExec
-		wait, shouldRetry := m.shouldRetryAfterError(errExec, attempt, attempts, rotated, req.Model, maxWait)
+		wait, shouldRetry := m.shouldRetryAfterError(ctx, errExec, attempt, attempts, rotated, req.Model, maxWait)
 		if !shouldRetry {
 			break
 		}
@@ -290,12 +481,10 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 	}
 	rotated := m.rotateProviders(req.Model, normalized)
 	defer m.advanceProviderCursor(req.Model, normalized)
+	ctx, cancel := m.withRequestDeadline(ctx)
+	defer cancel()
 
-	retryTimes, maxWait := m.retrySettings()
-	attempts := retryTimes + 1
-	if attempts < 1 {
-		attempts = 1
-	}
+	attempts, maxWait := m.retrySettings()
 
 	var lastErr error
 	for attempt := 0; attempt < attempts; attempt++ {
@@ -306,7 +495,7 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 			return resp, nil
 		}
 		lastErr = errExec
-		wait, shouldRetry := m.shouldRetryAfterError(errExec, attempt, attempts, rotated, req.Model, maxWait)
+		wait, shouldRetry := m.shouldRetryAfterError(ctx, errExec, attempt, attempts, rotated, req.Model, maxWait)
 		if !shouldRetry {
 			break
 		}
@@ -330,26 +519,43 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 	rotated := m.rotateProviders(req.Model, normalized)
 	defer m.advanceProviderCursor(req.Model, normalized)
 
-	retryTimes, maxWait := m.retrySettings()
-	attempts := retryTimes + 1
-	if attempts < 1 {
-		attempts = 1
+	// The overall deadline bounds retries and the waits between them, not the stream that
+	// eventually succeeds; long streams are bounded by the streaming attempt timeout instead.
+	retryCtx, cancel := m.withRequestDeadline(ctx)
+	defer cancel()
+
+	// The slot is held until the stream ends; it is released here on every early return and
+	// by releaseOnClose once a stream is handed to the caller.
+	release, errSlot := m.concurrency.acquire(retryCtx, req.Model, isFlexServiceTier(opts.Metadata))
+	if errSlot != nil {
+		return nil, errSlot
 	}
+	streaming := false
+	defer func() {
+		if !streaming {
+			release()
+		}
+	}()
+
+	attempts, maxWait := m.retrySettings()
 
 	var lastErr error
 	for attempt := 0; attempt < attempts; attempt++ {
 		chunks, errStream := m.executeStreamProvidersOnce(ctx, rotated, func(execCtx context.Context, provider string) (<-chan cliproxyexecutor.StreamChunk, error) {
 			return m.executeStreamWithProvider(execCtx, provider, req, opts)
 		})
+		// Once the stream is handed to the caller bytes may reach the client, so a later
+		// failure is delivered on the stream and never retried.
 		if errStream == nil {
-			return chunks, nil
+			streaming = true
+			return releaseOnClose(ctx, chunks, release), nil
 		}
 		lastErr = errStream
-		wait, shouldRetry := m.shouldRetryAfterError(errStream, attempt, attempts, rotated, req.Model, maxWait)
+		wait, shouldRetry := m.shouldRetryAfterError(retryCtx, errStream, attempt, attempts, rotated, req.Model, maxWait)
 		if !shouldRetry {
 			break
 		}
-		if errWait := waitForCooldown(ctx, wait); errWait != nil {
+		if errWait := waitForCooldown(retryCtx, wait); errWait != nil {
 			return nil, errWait
 		}
 	}
@@ -363,22 +569,28 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 	if provider == "" {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "provider identifier is empty"}
 	}
+	upstreamReq := req
+	upstreamReq.Model = m.upstreamModel(provider, req.Model)
 	tried := make(map[string]struct{})
+	maxAttempts := m.credentialAttemptLimit()
 	var lastErr error
 	for {
+		if lastErr != nil && maxAttempts > 0 && len(tried) >= maxAttempts {
+			return cliproxyexecutor.Response{}, withCredentialAttempts(lastErr, len(tried))
+		}
 		auth, executor, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
-				return cliproxyexecutor.Response{}, lastErr
+				return cliproxyexecutor.Response{}, withCredentialAttempts(lastErr, len(tried))
 			}
 			return cliproxyexecutor.Response{}, errPick
 		}
 
 		accountType, accountInfo := auth.AccountInfo()
 		if accountType == "api_key" {
-			log.Debugf("Use API key %s for model %s", util.HideAPIKey(accountInfo), req.Model)
+			log.WithContext(ctx).Debugf("Use API key %s for model %s", util.HideAPIKey(accountInfo), req.Model)
 		} else if accountType == "oauth" {
-			log.Debugf("Use OAuth %s for model %s", accountInfo, req.Model)
+			log.WithContext(ctx).Debugf("Use OAuth %s for model %s", accountInfo, req.Model)
 		}
 
 		tried[auth.ID] = struct{}{}
@@ -387,7 +599,30 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
 		}
-		resp, errExec := executor.Execute(execCtx, auth, req, opts)
+		attemptStart := time.Now()
+		attemptCtx, cancelAttempt := m.withAttemptTimeout(execCtx, false)
+		resp, errExec := executor.Execute(attemptCtx, auth, upstreamReq, opts)
+		cancelAttempt()
+		if isUnauthorized(errExec) {
+			if refreshed, ok := m.refreshAfterUnauthorized(ctx, auth, attemptStart); ok {
+				auth = refreshed
+				attemptCtx, cancelAttempt = m.withAttemptTimeout(execCtx, false)
+				resp, errExec = executor.Execute(attemptCtx, auth, upstreamReq, opts)
+				cancelAttempt()
+			}
+		}
+		if errExec == nil {
+			errExec = m.embeddedError(resp.Payload)
+		}
+		logging.RequestRecordFromContext(ctx).ObserveAttempt(provider, auth.ID, time.Since(attemptStart))
+		if dry := dryRunResult(errExec, provider, auth, upstreamReq, opts); dry != nil {
+			m.breakers.abandon(auth.ID)
+			return cliproxyexecutor.Response{}, dry
+		}
+		if isTransformError(errExec) {
+			m.breakers.abandon(auth.ID)
+			return cliproxyexecutor.Response{}, errExec
+		}
 		result := Result{AuthID: auth.ID, Provider: provider, Model: req.Model, Success: errExec == nil}
 		if errExec != nil {
 			result.Error = &Error{Message: errExec.Error()}
@@ -403,6 +638,7 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 			continue
 		}
 		m.MarkResult(execCtx, result)
+		resp.Payload = canonicalResponseModel(resp.Payload, upstreamReq.Model, req.Model)
 		return resp, nil
 	}
 }
@@ -411,22 +647,28 @@ func (m *Manager) executeCountWithProvider(ctx context.Context, provider string,
 	if provider == "" {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "provider identifier is empty"}
 	}
+	upstreamReq := req
+	upstreamReq.Model = m.upstreamModel(provider, req.Model)
 	tried := make(map[string]struct{})
+	maxAttempts := m.credentialAttemptLimit()
 	var lastErr error
 	for {
+		if lastErr != nil && maxAttempts > 0 && len(tried) >= maxAttempts {
+			return cliproxyexecutor.Response{}, withCredentialAttempts(lastErr, len(tried))
+		}
 		auth, executor, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
-				return cliproxyexecutor.Response{}, lastErr
+				return cliproxyexecutor.Response{}, withCredentialAttempts(lastErr, len(tried))
 			}
 			return cliproxyexecutor.Response{}, errPick
 		}
 
 		accountType, accountInfo := auth.AccountInfo()
 		if accountType == "api_key" {
-			log.Debugf("Use API key %s for model %s", util.HideAPIKey(accountInfo), req.Model)
+			log.WithContext(ctx).Debugf("Use API key %s for model %s", util.HideAPIKey(accountInfo), req.Model)
 		} else if accountType == "oauth" {
-			log.Debugf("Use OAuth %s for model %s", accountInfo, req.Model)
+			log.WithContext(ctx).Debugf("Use OAuth %s for model %s", accountInfo, req.Model)
 		}
 
 		tried[auth.ID] = struct{}{}
@@ -435,7 +677,27 @@ func (m *Manager) executeCountWithProvider(ctx context.Context, provider string,
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
 		}
-		resp, errExec := executor.CountTokens(execCtx, auth, req, opts)
+		attemptStart := time.Now()
+		attemptCtx, cancelAttempt := m.withAttemptTimeout(execCtx, false)
+		resp, errExec := executor.CountTokens(attemptCtx, auth, upstreamReq, opts)
+		cancelAttempt()
+		if isUnauthorized(errExec) {
+			if refreshed, ok := m.refreshAfterUnauthorized(ctx, auth, attemptStart); ok {
+				auth = refreshed
+				attemptCtx, cancelAttempt = m.withAttemptTimeout(execCtx, false)
+				resp, errExec = executor.CountTokens(attemptCtx, auth, upstreamReq, opts)
+				cancelAttempt()
+			}
+		}
+		logging.RequestRecordFromContext(ctx).ObserveAttempt(provider, auth.ID, time.Since(attemptStart))
+		if dry := dryRunResult(errExec, provider, auth, upstreamReq, opts); dry != nil {
+			m.breakers.abandon(auth.ID)
+			return cliproxyexecutor.Response{}, dry
+		}
+		if isTransformError(errExec) {
+			m.breakers.abandon(auth.ID)
+			return cliproxyexecutor.Response{}, errExec
+		}
 		result := Result{AuthID: auth.ID, Provider: provider, Model: req.Model, Success: errExec == nil}
 		if errExec != nil {
 			result.Error = &Error{Message: errExec.Error()}
@@ -459,22 +721,28 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 	if provider == "" {
 		return nil, &Error{Code: "provider_not_found", Message: "provider identifier is empty"}
 	}
+	upstreamReq := req
+	upstreamReq.Model = m.upstreamModel(provider, req.Model)
 	tried := make(map[string]struct{})
+	maxAttempts := m.credentialAttemptLimit()
 	var lastErr error
 	for {
+		if lastErr != nil && maxAttempts > 0 && len(tried) >= maxAttempts {
+			return nil, withCredentialAttempts(lastErr, len(tried))
+		}
 		auth, executor, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
-				return nil, lastErr
+				return nil, withCredentialAttempts(lastErr, len(tried))
 			}
 			return nil, errPick
 		}
 
 		accountType, accountInfo := auth.AccountInfo()
 		if accountType == "api_key" {
-			log.Debugf("Use API key %s for model %s", util.HideAPIKey(accountInfo), req.Model)
+			log.WithContext(ctx).Debugf("Use API key %s for model %s", util.HideAPIKey(accountInfo), req.Model)
 		} else if accountType == "oauth" {
-			log.Debugf("Use OAuth %s for model %s", accountInfo, req.Model)
+			log.WithContext(ctx).Debugf("Use OAuth %s for model %s", accountInfo, req.Model)
 		}
 
 		tried[auth.ID] = struct{}{}
@@ -483,8 +751,30 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
 		}
-		chunks, errStream := executor.ExecuteStream(execCtx, auth, req, opts)
+		attemptStart := time.Now()
+		attemptCtx, cancelAttempt := m.withAttemptTimeout(execCtx, true)
+		chunks, errStream := executor.ExecuteStream(attemptCtx, auth, upstreamReq, opts)
+		if isUnauthorized(errStream) {
+			if refreshed, ok := m.refreshAfterUnauthorized(ctx, auth, attemptStart); ok {
+				cancelAttempt()
+				auth = refreshed
+				attemptCtx, cancelAttempt = m.withAttemptTimeout(execCtx, true)
+				chunks, errStream = executor.ExecuteStream(attemptCtx, auth, upstreamReq, opts)
+			}
+		}
+		logging.RequestRecordFromContext(ctx).ObserveAttempt(provider, auth.ID, time.Since(attemptStart))
+		if dry := dryRunResult(errStream, provider, auth, upstreamReq, opts); dry != nil {
+			cancelAttempt()
+			m.breakers.abandon(auth.ID)
+			return nil, dry
+		}
+		if isTransformError(errStream) {
+			cancelAttempt()
+			m.breakers.abandon(auth.ID)
+			return nil, errStream
+		}
 		if errStream != nil {
+			cancelAttempt()
 			rerr := &Error{Message: errStream.Error()}
 			var se cliproxyexecutor.StatusError
 			if errors.As(errStream, &se) && se != nil {
@@ -497,10 +787,17 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 			continue
 		}
 		out := make(chan cliproxyexecutor.StreamChunk)
+		transform := cliproxyexecutor.HasResponseTransformers()
 		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
 			defer close(out)
+			defer cancelAttempt()
 			var failed bool
 			for chunk := range streamChunks {
+				if streamCtx.Err() != nil {
+					// The caller went away: keep draining so the executor observes the
+					// cancellation, releases the upstream body and exits.
+					continue
+				}
 				if chunk.Err != nil && !failed {
 					failed = true
 					rerr := &Error{Message: chunk.Err.Error()}
@@ -510,10 +807,21 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 					}
 					m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: req.Model, Success: false, Error: rerr})
 				}
-				out <- chunk
+				chunk.Payload = canonicalResponseModel(chunk.Payload, upstreamReq.Model, req.Model)
+				// Transformer errors are not upstream failures, so they are applied after the
+				// credential outcome has been recorded.
+				if transform {
+					chunk = cliproxyexecutor.TransformStreamChunk(streamCtx, chunk)
+				}
+				select {
+				case out <- chunk:
+				case <-streamCtx.Done():
+				}
 			}
-			if !failed {
+			if !failed && streamCtx.Err() == nil {
 				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: req.Model, Success: true})
+			} else if !failed {
+				m.breakers.abandon(streamAuth.ID)
 			}
 		}(execCtx, auth.Clone(), provider, chunks)
 		return out, nil
@@ -575,11 +883,13 @@ func (m *Manager) advanceProviderCursor(model string, providers []string) {
 	m.mu.Unlock()
 }
 
+// retrySettings returns how many attempts a request gets, the first one plus the configured
+// retries, and the longest wait allowed before a retry.
 func (m *Manager) retrySettings() (int, time.Duration) {
 	if m == nil {
-		return 0, 0
+		return 1, 0
 	}
-	return int(m.requestRetry.Load()), time.Duration(m.maxRetryInterval.Load())
+	return int(m.requestRetry.Load()) + 1, time.Duration(m.maxRetryInterval.Load())
 }
 
 func (m *Manager) closestCooldownWait(providers []string, model string) (time.Duration, bool) {
@@ -625,23 +935,66 @@ func (m *Manager) closestCooldownWait(providers []string, model string) (time.Du
 	return minWait, found
 }
 
-func (m *Manager) shouldRetryAfterError(err error, attempt, maxAttempts int, providers []string, model string, maxWait time.Duration) (time.Duration, bool) {
-	if err == nil || attempt >= maxAttempts-1 {
+// shouldRetryAfterError decides whether a failed attempt is retried and how long to wait.
+// An upstream Retry-After hint is used verbatim; otherwise the wait is the larger of the
+// jittered exponential backoff and the closest credential cooldown. Retries are skipped
+// when the wait exceeds maxWait or when, after waiting, less than one backoff would remain
+// before the request context deadline.
+func (m *Manager) shouldRetryAfterError(ctx context.Context, err error, attempt, maxAttempts int, providers []string, model string, maxWait time.Duration) (time.Duration, bool) {
+	if err == nil || attempt >= maxAttempts-1 || isTransformError(err) {
 		return 0, false
 	}
 	if maxWait <= 0 {
 		return 0, false
 	}
-	if status := statusCodeFromError(err); status == http.StatusOK {
+	status := statusCodeFromError(err)
+	// Selection failures raised by the manager itself carry no status; they only
+	// benefit from a retry once a cooling credential becomes available again.
+	var authErr *Error
+	selectionFailure := status == 0 && errors.As(err, &authErr) && authErr != nil
+	if !selectionFailure && !m.isRetryableError(err, status) {
 		return 0, false
 	}
-	wait, found := m.closestCooldownWait(providers, model)
-	if !found || wait > maxWait {
+	var wait time.Duration
+	if retryAfter := retryAfterFromError(err); retryAfter != nil {
+		wait = *retryAfter
+	} else {
+		cooldown, found := m.closestCooldownWait(providers, model)
+		if selectionFailure {
+			if !found {
+				return 0, false
+			}
+			wait = cooldown
+		} else {
+			wait = m.retryBackoff(attempt)
+			if found && cooldown > wait {
+				wait = cooldown
+			}
+		}
+	}
+	if wait > maxWait {
 		return 0, false
 	}
+	if ctx != nil {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait+m.retryBackoffCeiling(attempt) {
+			return 0, false
+		}
+	}
 	return wait, true
 }
 
+// isRetryableStatus reports whether an upstream status is retried by default.
+// A zero status denotes a transport failure without an HTTP response.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case 0, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
 func waitForCooldown(ctx context.Context, wait time.Duration) error {
 	if wait <= 0 {
 		return nil
@@ -666,6 +1019,9 @@ func (m *Manager) executeProvidersOnce(ctx context.Context, providers []string,
 		if errExec == nil {
 			return resp, nil
 		}
+		if isDryRun(errExec) || isTransformError(errExec) {
+			return cliproxyexecutor.Response{}, errExec
+		}
 		lastErr = errExec
 	}
 	if lastErr != nil {
@@ -684,6 +1040,9 @@ func (m *Manager) executeStreamProvidersOnce(ctx context.Context, providers []st
 		if errExec == nil {
 			return chunks, nil
 		}
+		if isDryRun(errExec) || isTransformError(errExec) {
+			return nil, errExec
+		}
 		lastErr = errExec
 	}
 	if lastErr != nil {
@@ -697,6 +1056,8 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	if result.AuthID == "" {
 		return
 	}
+	cancelled := ctx != nil && ctx.Err() != nil
+	m.breakers.record(result.AuthID, result.Provider, circuitOutcomeFor(result, cancelled), time.Now())
 
 	shouldResumeModel := false
 	shouldSuspendModel := false
@@ -1091,31 +1452,82 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 	candidates := make([]*Auth, 0, len(m.auths))
 	modelKey := strings.TrimSpace(model)
 	registryRef := registry.GetGlobalRegistry()
+	pinned, _ := opts.Metadata[PinnedAuthMetadataKey].(string)
+	now := time.Now()
+	circuitOpen := 0
+	quotaExhausted := 0
 	for _, candidate := range m.auths {
 		if candidate.Provider != provider || candidate.Disabled {
 			continue
 		}
+		if pinned != "" && candidate.ID != pinned {
+			continue
+		}
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
+		if m.breakers.blocked(candidate.ID, now) {
+			circuitOpen++
+			continue
+		}
+		if m.quotas.exhausted(candidate.ID, strings.ToLower(candidate.Provider), now) {
+			quotaExhausted++
+			continue
+		}
 		candidates = append(candidates, candidate)
 	}
 	if len(candidates) == 0 {
 		m.mu.RUnlock()
+		if circuitOpen > 0 {
+			return nil, nil, circuitOpenError(provider)
+		}
+		if quotaExhausted > 0 {
+			return nil, nil, quotaExhaustedError(provider)
+		}
 		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	selected, errPick := m.selector.Pick(ctx, provider, model, opts, candidates)
-	if errPick != nil {
-		m.mu.RUnlock()
-		return nil, nil, errPick
+	session, _ := opts.Metadata[SessionMetadataKey].(string)
+	var selected *Auth
+	if sticky, ok := m.sessions.lookup(session, provider, now); ok && pinned == "" {
+		for _, candidate := range candidates {
+			if candidate.ID != sticky {
+				continue
+			}
+			// A sticky credential yields once a higher-priority tier is available again.
+			if blocked, _, _ := isAuthBlockedForModel(candidate, model, now); !blocked && !outranked(candidate, candidates, model, now) {
+				selected = candidate
+			}
+			break
+		}
 	}
-	if selected == nil {
-		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+	for {
+		if selected == nil {
+			var errPick error
+			selected, errPick = m.selector.Pick(ctx, provider, model, opts, candidates)
+			if errPick != nil {
+				m.mu.RUnlock()
+				return nil, nil, errPick
+			}
+			if selected == nil {
+				m.mu.RUnlock()
+				return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+			}
+		}
+		if m.breakers.tryAcquire(selected.ID, now) {
+			break
+		}
+		// A concurrent request claimed the half-open probe after the candidates were filtered.
+		candidates = removeAuth(candidates, selected.ID)
+		selected = nil
+		if len(candidates) == 0 {
+			m.mu.RUnlock()
+			return nil, nil, circuitOpenError(provider)
+		}
 	}
+	m.sessions.remember(session, provider, selected.ID, now)
 	authCopy := selected.Clone()
 	m.mu.RUnlock()
 	if !selected.indexAssigned {
@@ -1129,6 +1541,16 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 	return authCopy, executor, nil
 }
 
+func removeAuth(auths []*Auth, id string) []*Auth {
+	out := make([]*Auth, 0, len(auths))
+	for _, auth := range auths {
+		if auth.ID != id {
+			out = append(out, auth)
+		}
+	}
+	return out
+}
+
 func (m *Manager) persist(ctx context.Context, auth *Auth) error {
 	if m.store == nil || auth == nil {
 		return nil
@@ -1438,6 +1860,12 @@ func (m *Manager) markRefreshPending(id string, now time.Time) bool {
 }
 
 func (m *Manager) refreshAuth(ctx context.Context, id string) {
+	_, _ = m.refreshCoalesced(ctx, id)
+}
+
+// refreshAuthNow refreshes credential id through its executor and stores the result. Callers
+// go through refreshCoalesced so a credential is never refreshed twice concurrently.
+func (m *Manager) refreshAuthNow(ctx context.Context, id string) (*Auth, error) {
 	m.mu.RLock()
 	auth := m.auths[id]
 	var exec ProviderExecutor
@@ -1446,7 +1874,7 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	}
 	m.mu.RUnlock()
 	if auth == nil || exec == nil {
-		return
+		return nil, &Error{Code: "auth_not_found", Message: "no auth to refresh"}
 	}
 	cloned := auth.Clone()
 	updated, err := exec.Refresh(ctx, cloned)
@@ -1460,7 +1888,7 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 			m.auths[id] = current
 		}
 		m.mu.Unlock()
-		return
+		return nil, err
 	}
 	if updated == nil {
 		updated = cloned
@@ -1474,7 +1902,7 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	updated.NextRefreshAfter = time.Time{}
 	updated.LastError = nil
 	updated.UpdatedAt = now
-	_, _ = m.Update(ctx, updated)
+	return m.Update(ctx, updated)
 }
 
 func (m *Manager) executorFor(provider string) ProviderExecutor {
@@ -1486,6 +1914,33 @@ func (m *Manager) executorFor(provider string) ProviderExecutor {
 // roundTripperContextKey is an unexported context key type to avoid collisions.
 type roundTripperContextKey struct{}
 
+// isTransformError reports whether err is a rejection by a request or response transformer.
+func isTransformError(err error) bool {
+	var transformErr *cliproxyexecutor.TransformError
+	return errors.As(err, &transformErr)
+}
+
+// isDryRun reports whether err carries a request captured by a dry run.
+func isDryRun(err error) bool {
+	var dry *cliproxyexecutor.DryRunError
+	return errors.As(err, &dry)
+}
+
+// dryRunResult completes a captured dry-run request with the routing decision and the
+// parameters translation changed. It returns nil when err is not a dry-run capture.
+func dryRunResult(err error, provider string, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) *cliproxyexecutor.DryRunError {
+	var dry *cliproxyexecutor.DryRunError
+	if !errors.As(err, &dry) {
+		return nil
+	}
+	dry.Provider = provider
+	dry.AuthID = auth.ID
+	dry.AuthLabel = auth.Label
+	dry.Model = req.Model
+	dry.Mutations = cliproxyexecutor.DiffParams(opts.OriginalRequest, dry.Body)
+	return dry
+}
+
 // roundTripperFor retrieves an HTTP RoundTripper for the given auth if a provider is registered.
 func (m *Manager) roundTripperFor(auth *Auth) http.RoundTripper {
 	m.mu.RLock()
@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type testStatusError struct {
+	code int
+	msg  string
+}
+
+func (e *testStatusError) Error() string   { return e.msg }
+func (e *testStatusError) StatusCode() int { return e.code }
+
+type failoverTestExecutor struct {
+	mu       sync.Mutex
+	calls    []string
+	failures map[string]error
+	// hangs lists credentials whose calls block until the context is done.
+	hangs  map[string]bool
+	stream func(ctx context.Context) <-chan cliproxyexecutor.StreamChunk
+}
+
+func (e *failoverTestExecutor) Identifier() string { return "test" }
+
+func (e *failoverTestExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, auth.ID)
+	if e.hangs[auth.ID] {
+		<-ctx.Done()
+		return cliproxyexecutor.Response{}, ctx.Err()
+	}
+	if err := e.failures[auth.ID]; err != nil {
+		return cliproxyexecutor.Response{}, err
+	}
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func (e *failoverTestExecutor) ExecuteStream(ctx context.Context, _ *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	if e.stream == nil {
+		return nil, errors.New("not implemented")
+	}
+	return e.stream(ctx), nil
+}
+
+func (e *failoverTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	return auth, nil
+}
+
+func (e *failoverTestExecutor) CountTokens(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+func newFailoverTestManager(t *testing.T, executor *failoverTestExecutor, ids ...string) *Manager {
+	t.Helper()
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(executor)
+	for _, id := range ids {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: "test"}); err != nil {
+			t.Fatalf("register %s: %v", id, err)
+		}
+	}
+	return m
+}
+
+func TestManagerExecute_FailsOverOnRateLimit(t *testing.T) {
+	executor := &failoverTestExecutor{failures: map[string]error{
+		"a": &testStatusError{code: http.StatusTooManyRequests, msg: "quota exceeded"},
+	}}
+	m := newFailoverTestManager(t, executor, "a", "b")
+
+	resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Expected failover to succeed, got %v", err)
+	}
+	if string(resp.Payload) != "b" {
+		t.Fatalf("Expected response from credential b, got %q (calls %v)", resp.Payload, executor.calls)
+	}
+}
+
+func TestManagerExecute_ReportsCredentialsTried(t *testing.T) {
+	executor := &failoverTestExecutor{failures: map[string]error{
+		"a": &testStatusError{code: http.StatusTooManyRequests, msg: "quota exceeded"},
+		"b": &testStatusError{code: http.StatusServiceUnavailable, msg: "unavailable"},
+		"c": &testStatusError{code: http.StatusServiceUnavailable, msg: "unavailable"},
+	}}
+	m := newFailoverTestManager(t, executor, "a", "b", "c")
+	m.SetCredentialAttempts(2)
+
+	_, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err == nil {
+		t.Fatal("Expected an error when every credential fails")
+	}
+	if len(executor.calls) != 2 {
+		t.Fatalf("Expected attempts to be capped at 2, got %v", executor.calls)
+	}
+	var attemptsErr *CredentialAttemptsError
+	if !errors.As(err, &attemptsErr) || attemptsErr.Attempts != 2 {
+		t.Fatalf("Expected CredentialAttemptsError with 2 attempts, got %#v", err)
+	}
+	hdr, ok := err.(interface{ Headers() http.Header })
+	if !ok || hdr.Headers().Get(CredentialAttemptsHeader) != "2" {
+		t.Fatalf("Expected %s header of 2, got %v", CredentialAttemptsHeader, err)
+	}
+	if se, ok := err.(interface{ StatusCode() int }); !ok || se.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status of last failure to be preserved, got %v", err)
+	}
+}
+
+func TestManagerExecute_SingleCredentialReturnsRawError(t *testing.T) {
+	upstream := &testStatusError{code: http.StatusTooManyRequests, msg: "quota exceeded"}
+	executor := &failoverTestExecutor{failures: map[string]error{"a": upstream}}
+	m := newFailoverTestManager(t, executor, "a")
+
+	_, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != upstream {
+		t.Fatalf("Expected the upstream error unchanged, got %#v", err)
+	}
+	if len(executor.calls) != 1 {
+		t.Fatalf("Expected a single attempt, got %v", executor.calls)
+	}
+}
+
+type testRetryAfterError struct {
+	testStatusError
+	retryAfter time.Duration
+}
+
+func (e *testRetryAfterError) RetryAfter() *time.Duration { return &e.retryAfter }
+
+func stubRetryJitter(t *testing.T) {
+	t.Helper()
+	original := retryJitter
+	retryJitter = func(n time.Duration) time.Duration { return n }
+	t.Cleanup(func() { retryJitter = original })
+}
+
+func TestManagerRetryBackoff_GrowsAndCaps(t *testing.T) {
+	stubRetryJitter(t)
+	m := NewManager(nil, nil, nil)
+	m.SetRetryBackoff(100*time.Millisecond, time.Second)
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for attempt, expected := range want {
+		if got := m.retryBackoff(attempt); got != expected {
+			t.Errorf("attempt %d: expected ceiling %v, got %v", attempt, expected, got)
+		}
+	}
+
+	m.SetRetryBackoff(0, 0)
+	if got := m.retryBackoff(0); got != defaultRetryBackoffBase {
+		t.Errorf("Expected default base %v, got %v", defaultRetryBackoffBase, got)
+	}
+}
+
+func TestManagerShouldRetry_RetryAfterOverridesBackoff(t *testing.T) {
+	stubRetryJitter(t)
+	m := NewManager(nil, nil, nil)
+	m.SetRetryBackoff(10*time.Second, 20*time.Second)
+
+	limited := &testRetryAfterError{
+		testStatusError: testStatusError{code: http.StatusTooManyRequests, msg: "slow down"},
+		retryAfter:      2 * time.Second,
+	}
+	wait, ok := m.shouldRetryAfterError(context.Background(), limited, 0, 3, []string{"test"}, "", time.Minute)
+	if !ok || wait != 2*time.Second {
+		t.Fatalf("Expected Retry-After of 2s to override backoff, got wait=%v retry=%v", wait, ok)
+	}
+
+	plain := &testStatusError{code: http.StatusServiceUnavailable, msg: "unavailable"}
+	wait, ok = m.shouldRetryAfterError(context.Background(), plain, 0, 3, []string{"test"}, "", time.Minute)
+	if !ok || wait != 10*time.Second {
+		t.Fatalf("Expected computed backoff of 10s, got wait=%v retry=%v", wait, ok)
+	}
+}
+
+func TestManagerShouldRetry_SkipsNonRetryableAndDeadline(t *testing.T) {
+	stubRetryJitter(t)
+	m := NewManager(nil, nil, nil)
+	m.SetRetryBackoff(time.Second, time.Second)
+
+	badRequest := &testStatusError{code: http.StatusBadRequest, msg: "bad request"}
+	if _, ok := m.shouldRetryAfterError(context.Background(), badRequest, 0, 3, []string{"test"}, "", time.Minute); ok {
+		t.Fatal("Expected 400 to be non-retryable")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	unavailable := &testStatusError{code: http.StatusServiceUnavailable, msg: "unavailable"}
+	if _, ok := m.shouldRetryAfterError(ctx, unavailable, 0, 3, []string{"test"}, "", time.Minute); ok {
+		t.Fatal("Expected retry to be skipped when the backoff outlives the context deadline")
+	}
+}
+
+func TestManagerExecute_StopsRetryingNearDeadline(t *testing.T) {
+	stubRetryJitter(t)
+	executor := &failoverTestExecutor{failures: map[string]error{"a": errors.New("connection reset")}}
+	m := newFailoverTestManager(t, executor, "a")
+	m.SetRetryConfig(10, time.Minute)
+	m.SetRetryBackoff(300*time.Millisecond, 300*time.Millisecond)
+	m.SetTimeouts(0, 0, time.Second)
+
+	start := time.Now()
+	_, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	// Retries at 300ms and 600ms leave more than one backoff before the 1s deadline; a third
+	// retry at 900ms would not.
+	if len(executor.calls) != 3 {
+		t.Fatalf("Expected 3 attempts before the deadline, got %v", executor.calls)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Fatalf("Expected the request to give up before the deadline, took %v", elapsed)
+	}
+}
+
+func TestManagerExecute_ZeroRetriesMakesOneAttempt(t *testing.T) {
+	executor := &failoverTestExecutor{failures: map[string]error{"a": errors.New("connection reset")}}
+	m := newFailoverTestManager(t, executor, "a")
+	m.SetRetryConfig(0, time.Minute)
+	m.SetRetryBackoff(time.Millisecond, time.Millisecond)
+
+	if _, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if len(executor.calls) != 1 {
+		t.Fatalf("Expected exactly one attempt with zero retries, got %v", executor.calls)
+	}
+
+	m.SetRetryConfig(1, time.Minute)
+	executor.calls = nil
+	if _, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if len(executor.calls) != 2 {
+		t.Fatalf("Expected one retry, got %v", executor.calls)
+	}
+}
+
+func TestManagerExecuteStream_NoRetryAfterBytesSent(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	unavailable := &testStatusError{code: http.StatusServiceUnavailable, msg: "unavailable"}
+	executor := &failoverTestExecutor{stream: func(context.Context) <-chan cliproxyexecutor.StreamChunk {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		out := make(chan cliproxyexecutor.StreamChunk, 2)
+		out <- cliproxyexecutor.StreamChunk{Payload: []byte("partial")}
+		out <- cliproxyexecutor.StreamChunk{Err: unavailable}
+		close(out)
+		return out
+	}}
+	m := newFailoverTestManager(t, executor, "a", "b")
+	m.SetRetryConfig(3, time.Minute)
+	m.SetRetryBackoff(time.Millisecond, time.Millisecond)
+
+	chunks, err := m.ExecuteStream(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream: %v", err)
+	}
+	var payloads []string
+	var streamErr error
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			streamErr = chunk.Err
+			continue
+		}
+		payloads = append(payloads, string(chunk.Payload))
+	}
+	if len(payloads) != 1 || payloads[0] != "partial" || !errors.Is(streamErr, unavailable) {
+		t.Fatalf("Expected the partial output followed by the upstream error, got %v, %v", payloads, streamErr)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Fatalf("Expected no retry or failover after bytes were sent, got %d calls", calls)
+	}
+}
+
+func TestManagerExecute_AttemptTimeoutFailsOver(t *testing.T) {
+	executor := &failoverTestExecutor{hangs: map[string]bool{"a": true}}
+	m := newFailoverTestManager(t, executor, "a", "b")
+	m.SetTimeouts(50*time.Millisecond, 0, 0)
+
+	resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != "b" {
+		t.Fatalf("Expected the timed out attempt to fail over to b, got %q, %v", resp.Payload, err)
+	}
+}
+
+func TestManagerExecuteStream_DrainsAfterCancel(t *testing.T) {
+	producerDone := make(chan struct{})
+	exec := &failoverTestExecutor{stream: func(ctx context.Context) <-chan cliproxyexecutor.StreamChunk {
+		out := make(chan cliproxyexecutor.StreamChunk)
+		go func() {
+			defer close(producerDone)
+			defer close(out)
+			for {
+				select {
+				case <-ctx.Done():
+					out <- cliproxyexecutor.StreamChunk{Err: ctx.Err()}
+					return
+				case out <- cliproxyexecutor.StreamChunk{Payload: []byte("chunk")}:
+				}
+			}
+		}()
+		return out
+	}}
+	m := newFailoverTestManager(t, exec, "a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := m.ExecuteStream(ctx, []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream returned error: %v", err)
+	}
+	<-stream
+	cancel()
+
+	select {
+	case <-producerDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the executor stream to finish once the caller stopped reading")
+	}
+	auth, ok := m.GetByID("a")
+	if !ok {
+		t.Fatal("Expected auth a to be registered")
+	}
+	if auth.Unavailable {
+		t.Fatal("Expected a cancelled stream not to penalise the credential")
+	}
+}
+
+func TestManagerExecute_PinnedAuthSkipsFailover(t *testing.T) {
+	exec := &failoverTestExecutor{failures: map[string]error{
+		"a": &testStatusError{code: http.StatusServiceUnavailable, msg: "down"},
+	}}
+	m := newFailoverTestManager(t, exec, "a", "b")
+
+	opts := cliproxyexecutor.Options{Metadata: map[string]any{PinnedAuthMetadataKey: "a"}}
+	if _, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, opts); err == nil {
+		t.Fatal("Expected the pinned credential's failure to be returned")
+	}
+	for _, id := range exec.calls {
+		if id != "a" {
+			t.Fatalf("Expected only the pinned credential to be used, got calls %v", exec.calls)
+		}
+	}
+}
+
+func TestManagerExecute_DoesNotFailOverOnTransformError(t *testing.T) {
+	executor := &failoverTestExecutor{failures: map[string]error{
+		"a": &cliproxyexecutor.TransformError{Err: errors.New("rejected by policy")},
+	}}
+	m := newFailoverTestManager(t, executor, "a", "b")
+
+	_, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	var transformErr *cliproxyexecutor.TransformError
+	if !errors.As(err, &transformErr) {
+		t.Fatalf("Expected the transformer error, got %v", err)
+	}
+	if len(executor.calls) != 1 {
+		t.Fatalf("Expected no failover after a transformer error, got calls %v", executor.calls)
+	}
+	if auth, _ := m.GetByID("a"); auth.Unavailable {
+		t.Fatal("Expected the credential not to be penalised for a transformer error")
+	}
+}
+
+func TestManagerExecute_AppliesResponseTransformers(t *testing.T) {
+	t.Cleanup(cliproxyexecutor.ResetTransformers)
+	cliproxyexecutor.RegisterResponseTransformer(cliproxyexecutor.ResponseTransformerFunc(func(_ context.Context, resp *cliproxyexecutor.Response) error {
+		resp.Payload = append(resp.Payload, "-transformed"...)
+		return nil
+	}))
+	executor := &failoverTestExecutor{}
+	m := newFailoverTestManager(t, executor, "a")
+
+	resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if string(resp.Payload) != "a-transformed" {
+		t.Fatalf("Expected the response transformer to run, got %q", resp.Payload)
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ModelRewrite rewrites the model name of requests dispatched to one provider.
+type ModelRewrite struct {
+	// Provider is the provider key the rule applies to.
+	Provider string
+	// Match is replaced wherever it occurs in the model name, or is a regular expression
+	// when Regex is set.
+	Match string
+	// Replace is the replacement; regular expression rules may reference groups as $1.
+	Replace string
+	// Regex treats Match as a regular expression.
+	Regex bool
+}
+
+type compiledModelRewrite struct {
+	literal string
+	pattern *regexp.Regexp
+	replace string
+}
+
+// modelRewriter holds the active rewrite rules per provider.
+type modelRewriter struct {
+	mu    sync.RWMutex
+	rules map[string][]compiledModelRewrite
+}
+
+// SetModelRewrites replaces the per-provider model name rewrite rules. Rules without a
+// provider or match, and rules whose regular expression does not compile, are ignored.
+func (m *Manager) SetModelRewrites(rules []ModelRewrite) {
+	if m == nil {
+		return
+	}
+	var compiled map[string][]compiledModelRewrite
+	for _, rule := range rules {
+		provider := strings.ToLower(strings.TrimSpace(rule.Provider))
+		if provider == "" || rule.Match == "" {
+			continue
+		}
+		entry := compiledModelRewrite{literal: rule.Match, replace: rule.Replace}
+		if rule.Regex {
+			pattern, err := regexp.Compile(rule.Match)
+			if err != nil {
+				log.Warnf("model rewrite: ignoring rule for %s with invalid pattern %q: %v", provider, rule.Match, err)
+				continue
+			}
+			entry.pattern = pattern
+		}
+		if compiled == nil {
+			compiled = make(map[string][]compiledModelRewrite)
+		}
+		compiled[provider] = append(compiled[provider], entry)
+	}
+	m.rewrites.mu.Lock()
+	m.rewrites.rules = compiled
+	m.rewrites.mu.Unlock()
+}
+
+// upstreamModel returns the model name to send to provider for the canonical model. The
+// provider's rules apply in order, each to the result of the previous one.
+func (m *Manager) upstreamModel(provider, model string) string {
+	m.rewrites.mu.RLock()
+	defer m.rewrites.mu.RUnlock()
+	for _, rule := range m.rewrites.rules[strings.ToLower(provider)] {
+		if rule.pattern != nil {
+			model = rule.pattern.ReplaceAllString(model, rule.replace)
+		} else {
+			model = strings.ReplaceAll(model, rule.literal, rule.replace)
+		}
+	}
+	return model
+}
+
+// canonicalResponseModel reports the canonical model name in a response or stream chunk that
+// names the rewritten upstream model. Payloads are already in the client's format, so the
+// compact "model" and "modelVersion" fields are replaced wherever they occur, which covers
+// the OpenAI, Claude and Gemini response shapes including nested stream events.
+func canonicalResponseModel(payload []byte, upstream, canonical string) []byte {
+	if upstream == canonical || len(payload) == 0 {
+		return payload
+	}
+	for _, key := range []string{`"model":"`, `"modelVersion":"`} {
+		payload = bytes.ReplaceAll(payload, []byte(key+upstream+`"`), []byte(key+canonical+`"`))
+	}
+	return payload
+}
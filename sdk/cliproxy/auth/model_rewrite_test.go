@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// modelEchoExecutor records the model it was asked for and answers with it, like an
+// upstream that reports the model it served.
+type modelEchoExecutor struct {
+	models []string
+}
+
+func (e *modelEchoExecutor) Identifier() string { return "rewrite-test" }
+
+func (e *modelEchoExecutor) Execute(_ context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.models = append(e.models, req.Model)
+	return cliproxyexecutor.Response{Payload: []byte(`{"id":"msg_1","model":"` + req.Model + `"}`)}, nil
+}
+
+func (e *modelEchoExecutor) ExecuteStream(_ context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	e.models = append(e.models, req.Model)
+	out := make(chan cliproxyexecutor.StreamChunk, 1)
+	out <- cliproxyexecutor.StreamChunk{Payload: []byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"" + req.Model + "\"}}")}
+	close(out)
+	return out, nil
+}
+
+func (e *modelEchoExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	return auth, nil
+}
+
+func (e *modelEchoExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, errors.New("not implemented")
+}
+
+func newModelRewriteTestManager(t *testing.T, executor *modelEchoExecutor, rules ...ModelRewrite) *Manager {
+	t.Helper()
+	registry.GetGlobalRegistry().RegisterClient("rewrite-auth", "rewrite-test", []*registry.ModelInfo{{ID: "claude-3.5-sonnet"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient("rewrite-auth") })
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(executor)
+	if _, err := m.Register(context.Background(), &Auth{ID: "rewrite-auth", Provider: "rewrite-test"}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	m.SetModelRewrites(rules)
+	return m
+}
+
+func TestManagerExecute_RewritesModelForProvider(t *testing.T) {
+	executor := &modelEchoExecutor{}
+	m := newModelRewriteTestManager(t, executor,
+		ModelRewrite{Provider: "other", Match: "claude", Replace: "never"},
+		ModelRewrite{Provider: "Rewrite-Test", Match: "3.5", Replace: "3-5"},
+	)
+
+	resp, err := m.Execute(context.Background(), []string{"rewrite-test"}, cliproxyexecutor.Request{Model: "claude-3.5-sonnet"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executor.models) != 1 || executor.models[0] != "claude-3-5-sonnet" {
+		t.Fatalf("Expected the provider to receive the rewritten model, got %v", executor.models)
+	}
+	if string(resp.Payload) != `{"id":"msg_1","model":"claude-3.5-sonnet"}` {
+		t.Fatalf("Expected the response model to be reported under the canonical name, got %s", resp.Payload)
+	}
+}
+
+func TestManagerExecuteStream_RegexRewriteNormalizesChunks(t *testing.T) {
+	executor := &modelEchoExecutor{}
+	m := newModelRewriteTestManager(t, executor, ModelRewrite{Provider: "rewrite-test", Match: `^claude-(\d)\.(\d)-(.+)$`, Replace: "anthropic/claude-$1-$2-$3", Regex: true})
+
+	chunks, err := m.ExecuteStream(context.Background(), []string{"rewrite-test"}, cliproxyexecutor.Request{Model: "claude-3.5-sonnet"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var payload string
+	for chunk := range chunks {
+		payload += string(chunk.Payload)
+	}
+	if executor.models[0] != "anthropic/claude-3-5-sonnet" {
+		t.Fatalf("Expected the provider to receive the regex rewrite, got %v", executor.models)
+	}
+	if want := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-3.5-sonnet\"}}"; payload != want {
+		t.Fatalf("Expected the streamed model to be reported under the canonical name, got %q", payload)
+	}
+}
+
+func TestSetModelRewrites_IgnoresInvalidPattern(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetModelRewrites([]ModelRewrite{{Provider: "claude", Match: "(", Regex: true}, {Provider: "claude", Match: "3.5", Replace: "3-5"}})
+
+	if got := m.upstreamModel("claude", "claude-3.5-haiku"); got != "claude-3-5-haiku" {
+		t.Fatalf("Expected the valid rule to apply, got %s", got)
+	}
+	if got := m.upstreamModel("gemini", "claude-3.5-haiku"); got != "claude-3.5-haiku" {
+		t.Fatalf("Expected other providers to be left alone, got %s", got)
+	}
+}
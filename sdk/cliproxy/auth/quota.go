@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+// QuotaWindow is the calendar period a quota limit applies to. Windows are aligned to UTC.
+type QuotaWindow string
+
+const (
+	// QuotaDaily resets at midnight UTC.
+	QuotaDaily QuotaWindow = "daily"
+	// QuotaMonthly resets on the first day of each month, UTC.
+	QuotaMonthly QuotaWindow = "monthly"
+)
+
+// QuotaLimit caps the usage of one credential, or of each credential of a provider, within a
+// calendar window. A zero maximum leaves that dimension unlimited.
+type QuotaLimit struct {
+	// AuthID selects a single credential. When empty the limit applies to every credential of
+	// Provider individually.
+	AuthID   string
+	Provider string
+	Window   QuotaWindow
+	// MaxTokens caps prompt plus completion tokens.
+	MaxTokens int64
+	// MaxRequests caps upstream requests.
+	MaxRequests int64
+}
+
+// QuotaUsage is the usage accumulated within one window.
+type QuotaUsage struct {
+	Tokens   int64
+	Requests int64
+}
+
+// QuotaStore persists quota counters. Add accumulates usage under key and returns the new
+// totals; expiresAt is when the window ends and the counter may be discarded.
+type QuotaStore interface {
+	Add(ctx context.Context, key string, expiresAt time.Time, delta QuotaUsage) (QuotaUsage, error)
+}
+
+// MemoryQuotaStore keeps quota counters in process memory; counters are lost on restart.
+type MemoryQuotaStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryQuotaEntry
+}
+
+type memoryQuotaEntry struct {
+	usage     QuotaUsage
+	expiresAt time.Time
+}
+
+// NewMemoryQuotaStore returns an empty in-memory quota store.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{entries: make(map[string]memoryQuotaEntry)}
+}
+
+// Add implements QuotaStore.
+func (s *MemoryQuotaStore) Add(_ context.Context, key string, expiresAt time.Time, delta QuotaUsage) (QuotaUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	entry := s.entries[key]
+	entry.usage.Tokens += delta.Tokens
+	entry.usage.Requests += delta.Requests
+	entry.expiresAt = expiresAt
+	s.entries[key] = entry
+	return entry.usage, nil
+}
+
+// QuotaStatus reports the usage of one credential against one configured limit.
+type QuotaStatus struct {
+	AuthID            string      `json:"auth_id"`
+	Provider          string      `json:"provider"`
+	Window            QuotaWindow `json:"window"`
+	TokensUsed        int64       `json:"tokens_used"`
+	MaxTokens         int64       `json:"max_tokens,omitempty"`
+	RemainingTokens   *int64      `json:"remaining_tokens,omitempty"`
+	RequestsUsed      int64       `json:"requests_used"`
+	MaxRequests       int64       `json:"max_requests,omitempty"`
+	RemainingRequests *int64      `json:"remaining_requests,omitempty"`
+	ResetsAt          time.Time   `json:"resets_at"`
+	Exhausted         bool        `json:"exhausted"`
+}
+
+// quotaTracker caches the latest counters reported by the store so credential selection
+// never waits on a (possibly remote) store.
+type quotaTracker struct {
+	mu     sync.Mutex
+	limits []QuotaLimit
+	store  QuotaStore
+	usage  map[string]QuotaUsage
+}
+
+// SetQuotas replaces the configured per-credential quota limits. Limits with an unknown
+// window or without any maximum are ignored.
+func (m *Manager) SetQuotas(limits []QuotaLimit) {
+	valid := make([]QuotaLimit, 0, len(limits))
+	for _, limit := range limits {
+		limit.AuthID = strings.TrimSpace(limit.AuthID)
+		limit.Provider = strings.ToLower(strings.TrimSpace(limit.Provider))
+		limit.Window = QuotaWindow(strings.ToLower(strings.TrimSpace(string(limit.Window))))
+		if limit.Window != QuotaDaily && limit.Window != QuotaMonthly {
+			log.Warnf("quota: ignoring limit with unknown window %q", limit.Window)
+			continue
+		}
+		if (limit.AuthID == "" && limit.Provider == "") || (limit.MaxTokens <= 0 && limit.MaxRequests <= 0) {
+			continue
+		}
+		valid = append(valid, limit)
+	}
+	m.quotas.mu.Lock()
+	defer m.quotas.mu.Unlock()
+	if reflect.DeepEqual(m.quotas.limits, valid) {
+		return
+	}
+	m.quotas.limits = valid
+}
+
+// SetQuotaStore replaces the store used to persist quota counters. The default is an
+// in-memory store.
+func (m *Manager) SetQuotaStore(store QuotaStore) {
+	m.quotas.mu.Lock()
+	m.quotas.store = store
+	m.quotas.usage = nil
+	m.quotas.mu.Unlock()
+}
+
+// HandleUsage implements usage.Plugin, charging each reported upstream request and its tokens
+// to the quotas of the credential that served it. Usage is delivered asynchronously, so
+// concurrent requests may overshoot a limit slightly before the credential is skipped.
+func (m *Manager) HandleUsage(ctx context.Context, record usage.Record) {
+	if record.AuthID == "" {
+		return
+	}
+	provider := strings.ToLower(strings.TrimSpace(record.Provider))
+	if provider == "" {
+		if auth, ok := m.GetByID(record.AuthID); ok {
+			provider = strings.ToLower(auth.Provider)
+		}
+	}
+	now := record.RequestedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	tokens := record.Detail.TotalTokens
+	if tokens == 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	m.quotas.charge(ctx, record.AuthID, provider, now, QuotaUsage{Tokens: tokens, Requests: 1})
+}
+
+// Quotas reports every registered credential's usage against each limit that applies to it,
+// sorted by provider, auth ID and window.
+func (m *Manager) Quotas() []QuotaStatus {
+	m.mu.RLock()
+	auths := make([]*Auth, 0, len(m.auths))
+	for _, auth := range m.auths {
+		auths = append(auths, auth)
+	}
+	m.mu.RUnlock()
+	now := time.Now()
+	out := make([]QuotaStatus, 0)
+	m.quotas.mu.Lock()
+	for _, auth := range auths {
+		provider := strings.ToLower(auth.Provider)
+		for _, limit := range m.quotas.limitsFor(auth.ID, provider) {
+			start, end := quotaWindowBounds(limit.Window, now)
+			used := m.quotas.usage[quotaKey(auth.ID, limit.Window, start)]
+			status := QuotaStatus{
+				AuthID:       auth.ID,
+				Provider:     provider,
+				Window:       limit.Window,
+				TokensUsed:   used.Tokens,
+				MaxTokens:    limit.MaxTokens,
+				RequestsUsed: used.Requests,
+				MaxRequests:  limit.MaxRequests,
+				ResetsAt:     end,
+				Exhausted:    limit.exhausted(used),
+			}
+			if limit.MaxTokens > 0 {
+				remaining := max(limit.MaxTokens-used.Tokens, 0)
+				status.RemainingTokens = &remaining
+			}
+			if limit.MaxRequests > 0 {
+				remaining := max(limit.MaxRequests-used.Requests, 0)
+				status.RemainingRequests = &remaining
+			}
+			out = append(out, status)
+		}
+	}
+	m.quotas.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		if out[i].AuthID != out[j].AuthID {
+			return out[i].AuthID < out[j].AuthID
+		}
+		return out[i].Window < out[j].Window
+	})
+	return out
+}
+
+func (l QuotaLimit) exhausted(used QuotaUsage) bool {
+	return (l.MaxTokens > 0 && used.Tokens >= l.MaxTokens) || (l.MaxRequests > 0 && used.Requests >= l.MaxRequests)
+}
+
+// limitsFor returns the limits applying to a credential; the caller holds q.mu.
+func (q *quotaTracker) limitsFor(authID, provider string) []QuotaLimit {
+	var out []QuotaLimit
+	for _, limit := range q.limits {
+		if limit.AuthID != "" {
+			if limit.AuthID == authID {
+				out = append(out, limit)
+			}
+			continue
+		}
+		if limit.Provider == provider {
+			out = append(out, limit)
+		}
+	}
+	return out
+}
+
+// exhausted reports whether any limit of the credential has been reached in its current window.
+func (q *quotaTracker) exhausted(authID, provider string, now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, limit := range q.limitsFor(authID, provider) {
+		start, _ := quotaWindowBounds(limit.Window, now)
+		if limit.exhausted(q.usage[quotaKey(authID, limit.Window, start)]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *quotaTracker) charge(ctx context.Context, authID, provider string, now time.Time, delta QuotaUsage) {
+	q.mu.Lock()
+	windows := make(map[QuotaWindow]struct{})
+	for _, limit := range q.limitsFor(authID, provider) {
+		windows[limit.Window] = struct{}{}
+	}
+	if q.store == nil {
+		q.store = NewMemoryQuotaStore()
+	}
+	store := q.store
+	q.mu.Unlock()
+
+	for window := range windows {
+		start, end := quotaWindowBounds(window, now)
+		key := quotaKey(authID, window, start)
+		total, err := store.Add(ctx, key, end, delta)
+		if err != nil {
+			log.Warnf("quota: failed to record usage for %s: %v", authID, err)
+			continue
+		}
+		q.mu.Lock()
+		if q.usage == nil {
+			q.usage = make(map[string]QuotaUsage)
+		}
+		q.usage[key] = total
+		for k := range q.usage {
+			// Drop counters of windows that have ended.
+			if strings.HasPrefix(k, authID+"|"+string(window)+"|") && k != key {
+				delete(q.usage, k)
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+func quotaKey(authID string, window QuotaWindow, start time.Time) string {
+	return authID + "|" + string(window) + "|" + start.Format("2006-01-02")
+}
+
+// quotaWindowBounds returns the UTC calendar window containing now.
+func quotaWindowBounds(window QuotaWindow, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	if window == QuotaMonthly {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// quotaExhaustedError reports that every remaining credential for provider has used up its quota.
+func quotaExhaustedError(provider string) *Error {
+	return &Error{
+		Code:       "quota_exhausted",
+		Message:    "all available " + provider + " credentials have reached their configured quota",
+		HTTPStatus: http.StatusTooManyRequests,
+	}
+}
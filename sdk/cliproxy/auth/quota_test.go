@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestManagerExecute_SkipsExhaustedQuota(t *testing.T) {
+	executor := &failoverTestExecutor{}
+	m := newFailoverTestManager(t, executor, "a", "b")
+	m.SetQuotas([]QuotaLimit{{AuthID: "a", Window: QuotaDaily, MaxTokens: 100}})
+
+	m.HandleUsage(context.Background(), usage.Record{AuthID: "a", Provider: "test", Detail: usage.Detail{TotalTokens: 100}})
+
+	for i := 0; i < 3; i++ {
+		resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+		if err != nil || string(resp.Payload) != "b" {
+			t.Fatalf("Expected credential b to serve request %d, got %q, %v", i, resp.Payload, err)
+		}
+	}
+
+	statuses := m.Quotas()
+	if len(statuses) != 1 || statuses[0].AuthID != "a" || !statuses[0].Exhausted {
+		t.Fatalf("Expected exhausted quota for a, got %+v", statuses)
+	}
+	if statuses[0].RemainingTokens == nil || *statuses[0].RemainingTokens != 0 || statuses[0].RemainingRequests != nil {
+		t.Fatalf("Expected zero remaining tokens and no request limit, got %+v", statuses[0])
+	}
+
+	health := m.Readiness(context.Background(), ReadinessOptions{})
+	if len(health) != 1 || !health[0].Ready || health[0].QuotaExhausted != 1 || health[0].Available != 1 {
+		t.Fatalf("Expected provider ready with one exhausted quota, got %+v", health)
+	}
+}
+
+func TestManagerExecute_AllQuotasExhausted(t *testing.T) {
+	executor := &failoverTestExecutor{}
+	m := newFailoverTestManager(t, executor, "a")
+	m.SetQuotas([]QuotaLimit{{Provider: "test", Window: QuotaMonthly, MaxRequests: 2}})
+
+	for i := 0; i < 2; i++ {
+		m.HandleUsage(context.Background(), usage.Record{AuthID: "a", Provider: "test"})
+	}
+	_, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.Code != "quota_exhausted" || authErr.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("Expected quota_exhausted error, got %#v", err)
+	}
+	if len(executor.calls) != 0 {
+		t.Fatalf("Expected no upstream call once the quota is exhausted, got %v", executor.calls)
+	}
+}
+
+func TestQuotaTracker_WindowReset(t *testing.T) {
+	tracker := &quotaTracker{limits: []QuotaLimit{{AuthID: "a", Window: QuotaDaily, MaxTokens: 10}}}
+	day := time.Date(2024, time.March, 31, 23, 0, 0, 0, time.UTC)
+	tracker.charge(context.Background(), "a", "test", day, QuotaUsage{Tokens: 10, Requests: 1})
+	if !tracker.exhausted("a", "test", day) {
+		t.Fatal("Expected quota to be exhausted within the window")
+	}
+	if tracker.exhausted("a", "test", day.Add(2*time.Hour)) {
+		t.Fatal("Expected quota to reset at the next UTC day")
+	}
+
+	start, end := quotaWindowBounds(QuotaMonthly, day)
+	if !start.Equal(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected monthly window %s - %s", start, end)
+	}
+}
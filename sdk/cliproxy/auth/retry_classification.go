@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+)
+
+// RetryClassification decides which upstream failures are retried.
+type RetryClassification struct {
+	// StatusCodes replaces the default retryable statuses when non-empty. Transport failures
+	// without a response are always retryable.
+	StatusCodes []int
+	// BodySubstrings makes an error retryable when its message contains any of them, and turns
+	// a successful non-streaming response whose error field contains one into a retryable error.
+	BodySubstrings []string
+}
+
+// retryClassifier holds the active retry classification; nil statuses use isRetryableStatus.
+type retryClassifier struct {
+	mu         sync.RWMutex
+	statuses   map[int]struct{}
+	substrings []string
+}
+
+// SetRetryClassification configures which upstream statuses and error bodies are retried.
+// An empty StatusCodes restores the default statuses.
+func (m *Manager) SetRetryClassification(cfg RetryClassification) {
+	if m == nil {
+		return
+	}
+	var statuses map[int]struct{}
+	if len(cfg.StatusCodes) > 0 {
+		statuses = make(map[int]struct{}, len(cfg.StatusCodes))
+		for _, code := range cfg.StatusCodes {
+			statuses[code] = struct{}{}
+		}
+	}
+	var substrings []string
+	for _, substring := range cfg.BodySubstrings {
+		if substring != "" {
+			substrings = append(substrings, substring)
+		}
+	}
+	m.retryClass.mu.Lock()
+	m.retryClass.statuses = statuses
+	m.retryClass.substrings = substrings
+	m.retryClass.mu.Unlock()
+}
+
+// isRetryableError reports whether a failed attempt with the given upstream status warrants
+// another attempt. A zero status denotes a transport failure without an HTTP response.
+func (m *Manager) isRetryableError(err error, status int) bool {
+	if status == 0 {
+		return true
+	}
+	m.retryClass.mu.RLock()
+	defer m.retryClass.mu.RUnlock()
+	if m.retryClass.statuses == nil {
+		if isRetryableStatus(status) {
+			return true
+		}
+	} else if _, ok := m.retryClass.statuses[status]; ok {
+		return true
+	}
+	return err != nil && containsAny(err.Error(), m.retryClass.substrings)
+}
+
+// embeddedErrorFields are the payload fields in which providers report errors inside a
+// successful response. Only these are matched, so generated content never triggers a retry.
+var embeddedErrorFields = []string{"error", "error.message", "error.status"}
+
+// embeddedError returns a retryable error for a successful payload whose error fields match
+// one of the configured body substrings, or nil.
+func (m *Manager) embeddedError(payload []byte) error {
+	m.retryClass.mu.RLock()
+	defer m.retryClass.mu.RUnlock()
+	if len(m.retryClass.substrings) == 0 {
+		return nil
+	}
+	for _, field := range embeddedErrorFields {
+		value := gjson.GetBytes(payload, field)
+		if value.Type == gjson.String && containsAny(value.String(), m.retryClass.substrings) {
+			return &embeddedUpstreamError{body: string(payload)}
+		}
+	}
+	return nil
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedUpstreamError is an upstream error reported inside a 200 response. It is surfaced
+// as a bad gateway so it is retried and, once retries run out, returned to the client.
+type embeddedUpstreamError struct {
+	body string
+}
+
+func (e *embeddedUpstreamError) Error() string { return e.body }
+
+func (e *embeddedUpstreamError) StatusCode() int { return http.StatusBadGateway }
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// scriptedExecutor answers each call with the next scripted response or error.
+type scriptedExecutor struct {
+	mu      sync.Mutex
+	calls   int
+	results []func() (cliproxyexecutor.Response, error)
+}
+
+func (e *scriptedExecutor) Identifier() string { return "test" }
+
+func (e *scriptedExecutor) Execute(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	result := e.results[min(e.calls, len(e.results)-1)]
+	e.calls++
+	return result()
+}
+
+func (e *scriptedExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *scriptedExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	return auth, nil
+}
+
+func (e *scriptedExecutor) CountTokens(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+func newScriptedManager(t *testing.T, executor *scriptedExecutor, ids ...string) *Manager {
+	t.Helper()
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(executor)
+	for _, id := range ids {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: "test"}); err != nil {
+			t.Fatalf("register %s: %v", id, err)
+		}
+	}
+	m.SetRetryConfig(2, time.Minute)
+	m.SetRetryBackoff(time.Millisecond, time.Millisecond)
+	return m
+}
+
+func TestManagerShouldRetry_CustomStatusCodes(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetRetryBackoff(time.Millisecond, time.Millisecond)
+	gateway := &testStatusError{code: 520, msg: "unknown error"}
+	rateLimited := &testStatusError{code: http.StatusTooManyRequests, msg: "quota exceeded"}
+
+	if _, ok := m.shouldRetryAfterError(context.Background(), gateway, 0, 3, []string{"test"}, "", time.Minute); ok {
+		t.Fatal("Expected 520 to be non-retryable by default")
+	}
+	if _, ok := m.shouldRetryAfterError(context.Background(), rateLimited, 0, 3, []string{"test"}, "", time.Minute); !ok {
+		t.Fatal("Expected 429 to be retryable by default")
+	}
+
+	m.SetRetryClassification(RetryClassification{StatusCodes: []int{520, 524}})
+	if _, ok := m.shouldRetryAfterError(context.Background(), gateway, 0, 3, []string{"test"}, "", time.Minute); !ok {
+		t.Fatal("Expected the configured 520 to be retryable")
+	}
+	if _, ok := m.shouldRetryAfterError(context.Background(), rateLimited, 0, 3, []string{"test"}, "", time.Minute); ok {
+		t.Fatal("Expected configured status codes to replace the defaults")
+	}
+	if _, ok := m.shouldRetryAfterError(context.Background(), errors.New("connection reset"), 0, 3, []string{"test"}, "", time.Minute); !ok {
+		t.Fatal("Expected transport failures to stay retryable")
+	}
+
+	m.SetRetryClassification(RetryClassification{})
+	if _, ok := m.shouldRetryAfterError(context.Background(), rateLimited, 0, 3, []string{"test"}, "", time.Minute); !ok {
+		t.Fatal("Expected clearing the status codes to restore the defaults")
+	}
+}
+
+func TestManagerExecute_RetriesOnConfiguredStatus(t *testing.T) {
+	executor := &scriptedExecutor{results: []func() (cliproxyexecutor.Response, error){
+		func() (cliproxyexecutor.Response, error) {
+			return cliproxyexecutor.Response{}, &testStatusError{code: 524, msg: "timeout occurred"}
+		},
+		func() (cliproxyexecutor.Response, error) {
+			return cliproxyexecutor.Response{Payload: []byte("ok")}, nil
+		},
+	}}
+	m := newScriptedManager(t, executor, "a")
+	m.SetRetryClassification(RetryClassification{StatusCodes: []int{524}})
+
+	resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != "ok" {
+		t.Fatalf("Expected the 524 to be retried, got %q, %v", resp.Payload, err)
+	}
+	if executor.calls != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", executor.calls)
+	}
+}
+
+func TestManagerExecute_RetriesOnBodySubstring(t *testing.T) {
+	executor := &scriptedExecutor{results: []func() (cliproxyexecutor.Response, error){
+		func() (cliproxyexecutor.Response, error) {
+			return cliproxyexecutor.Response{}, &testStatusError{code: http.StatusBadRequest, msg: `{"error":"upstream_overloaded"}`}
+		},
+		func() (cliproxyexecutor.Response, error) {
+			return cliproxyexecutor.Response{Payload: []byte("ok")}, nil
+		},
+	}}
+	m := newScriptedManager(t, executor, "a")
+	m.SetRetryClassification(RetryClassification{BodySubstrings: []string{"upstream_overloaded"}})
+
+	resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != "ok" {
+		t.Fatalf("Expected an error matching the body substring to be retried, got %q, %v", resp.Payload, err)
+	}
+	if executor.calls != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", executor.calls)
+	}
+}
+
+func TestManagerExecute_TreatsMatchingSuccessAsError(t *testing.T) {
+	embedded := `{"error":{"status":"upstream_overloaded"}}`
+	executor := &scriptedExecutor{results: []func() (cliproxyexecutor.Response, error){
+		func() (cliproxyexecutor.Response, error) {
+			return cliproxyexecutor.Response{Payload: []byte(embedded)}, nil
+		},
+		func() (cliproxyexecutor.Response, error) {
+			return cliproxyexecutor.Response{Payload: []byte("ok")}, nil
+		},
+	}}
+	m := newScriptedManager(t, executor, "a", "b")
+	m.SetRetryClassification(RetryClassification{BodySubstrings: []string{"upstream_overloaded"}})
+
+	resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != "ok" {
+		t.Fatalf("Expected the embedded error to fail over, got %q, %v", resp.Payload, err)
+	}
+
+	m.SetRetryClassification(RetryClassification{})
+	executor.calls = 0
+	resp, err = m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != embedded {
+		t.Fatalf("Expected the payload to be returned without body substrings, got %q, %v", resp.Payload, err)
+	}
+}
+
+func TestManagerExecute_MatchingContentIsNotAnError(t *testing.T) {
+	content := `{"choices":[{"message":{"content":"the upstream_overloaded flag means the server is busy"}}]}`
+	executor := &scriptedExecutor{results: []func() (cliproxyexecutor.Response, error){
+		func() (cliproxyexecutor.Response, error) {
+			return cliproxyexecutor.Response{Payload: []byte(content)}, nil
+		},
+	}}
+	m := newScriptedManager(t, executor, "a", "b")
+	m.SetRetryClassification(RetryClassification{BodySubstrings: []string{"upstream_overloaded"}})
+
+	resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != content {
+		t.Fatalf("Expected the response to be returned as is, got %q, %v", resp.Payload, err)
+	}
+	if executor.calls != 1 {
+		t.Fatalf("Expected a single attempt for matching content, got %d", executor.calls)
+	}
+}
+
+func TestManagerExecute_NonRetryableFailsFast(t *testing.T) {
+	executor := &scriptedExecutor{results: []func() (cliproxyexecutor.Response, error){
+		func() (cliproxyexecutor.Response, error) {
+			return cliproxyexecutor.Response{}, &testStatusError{code: http.StatusBadRequest, msg: "invalid request"}
+		},
+	}}
+	m := newScriptedManager(t, executor, "a")
+	m.SetRetryClassification(RetryClassification{StatusCodes: []int{520}, BodySubstrings: []string{"upstream_overloaded"}})
+
+	if _, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if executor.calls != 1 {
+		t.Fatalf("Expected a single attempt for a non-retryable error, got %d", executor.calls)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"net/http"
 	"sort"
@@ -14,10 +15,12 @@ import (
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 )
 
-// RoundRobinSelector provides a simple provider scoped round-robin selection strategy.
+// RoundRobinSelector provides a provider scoped weighted round-robin selection strategy.
+// Credentials with equal weights are visited in plain round-robin order; a credential
+// with weight N receives roughly N times the traffic of a weight-1 credential.
 type RoundRobinSelector struct {
 	mu      sync.Mutex
-	cursors map[string]int
+	current map[string]map[string]int
 }
 
 type blockReason int
@@ -98,22 +101,45 @@ func (e *modelCooldownError) Headers() http.Header {
 	return headers
 }
 
-// Pick selects the next available auth for the provider in a round-robin manner.
+// Pick selects the next available auth for the provider in a weighted round-robin manner.
 func (s *RoundRobinSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
 	_ = ctx
 	_ = opts
+	available, err := availableAuths(provider, model, auths)
+	if err != nil {
+		return nil, err
+	}
+	key := provider + ":" + model
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		s.current = make(map[string]map[string]int)
+	}
+	selected := smoothWeightedPick(s.current, key, available)
+	if selected == nil {
+		return nil, &Error{Code: "auth_unavailable", Message: "no auth available"}
+	}
+	return selected, nil
+}
+
+// availableAuths returns the candidates that can serve model now, sorted by ID, or the error
+// to report when there are none.
+func availableAuths(provider, model string, auths []*Auth) ([]*Auth, error) {
 	if len(auths) == 0 {
 		return nil, &Error{Code: "auth_not_found", Message: "no auth candidates"}
 	}
-	if s.cursors == nil {
-		s.cursors = make(map[string]int)
-	}
 	available := make([]*Auth, 0, len(auths))
 	now := time.Now()
 	cooldownCount := 0
+	excludedCount := 0
 	var earliest time.Time
 	for i := 0; i < len(auths); i++ {
 		candidate := auths[i]
+		// Zero-weight credentials stay registered but receive no new traffic.
+		if candidate != nil && candidate.Weight() <= 0 {
+			excludedCount++
+			continue
+		}
 		blocked, reason, next := isAuthBlockedForModel(candidate, model, now)
 		if !blocked {
 			available = append(available, candidate)
@@ -127,7 +153,7 @@ func (s *RoundRobinSelector) Pick(ctx context.Context, provider, model string, o
 		}
 	}
 	if len(available) == 0 {
-		if cooldownCount == len(auths) && !earliest.IsZero() {
+		if cooldownCount > 0 && cooldownCount == len(auths)-excludedCount && !earliest.IsZero() {
 			resetIn := earliest.Sub(now)
 			if resetIn < 0 {
 				resetIn = 0
@@ -136,22 +162,106 @@ func (s *RoundRobinSelector) Pick(ctx context.Context, provider, model string, o
 		}
 		return nil, &Error{Code: "auth_unavailable", Message: "no auth available"}
 	}
-	// Make round-robin deterministic even if caller's candidate order is unstable.
+	available = highestPriority(available)
+	// Make selection deterministic even if caller's candidate order is unstable.
 	if len(available) > 1 {
 		sort.Slice(available, func(i, j int) bool { return available[i].ID < available[j].ID })
 	}
-	key := provider + ":" + model
-	s.mu.Lock()
-	index := s.cursors[key]
+	return available, nil
+}
 
-	if index >= 2_147_483_640 {
-		index = 0
+// highestPriority keeps the candidates of the highest priority tier in available, so
+// lower tiers are only used once every higher-priority credential is unavailable.
+func highestPriority(available []*Auth) []*Auth {
+	top := available[0].Priority()
+	for _, candidate := range available[1:] {
+		if priority := candidate.Priority(); priority > top {
+			top = priority
+		}
+	}
+	tier := available[:0]
+	for _, candidate := range available {
+		if candidate.Priority() == top {
+			tier = append(tier, candidate)
+		}
 	}
+	return tier
+}
 
-	s.cursors[key] = index + 1
-	s.mu.Unlock()
-	// log.Debugf("available: %d, index: %d, key: %d", len(available), index, index%len(available))
-	return available[index%len(available)], nil
+// outranked reports whether a credential of higher priority than auth could serve model now.
+func outranked(auth *Auth, candidates []*Auth, model string, now time.Time) bool {
+	priority := auth.Priority()
+	for _, candidate := range candidates {
+		if candidate.Priority() <= priority || candidate.Weight() <= 0 {
+			continue
+		}
+		if blocked, _, _ := isAuthBlockedForModel(candidate, model, now); !blocked {
+			return true
+		}
+	}
+	return false
+}
+
+// DeterministicSelector chooses the credential from a hash of the model and the original
+// request body, so the same request against the same set of available credentials always
+// lands on the same credential, across runs and processes. Weights are honoured: a credential
+// with weight N covers N times as many hash slots. It exists to make tests reproducible and
+// does not spread identical requests, so it is not meant for production traffic.
+type DeterministicSelector struct{}
+
+// Pick selects the credential for the request hash.
+func (DeterministicSelector) Pick(_ context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	available, err := availableAuths(provider, model, auths)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, candidate := range available {
+		total += candidate.Weight()
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(provider + "\x00" + model + "\x00"))
+	_, _ = hash.Write(opts.OriginalRequest)
+	slot := int(hash.Sum64() % uint64(total))
+	for _, candidate := range available {
+		if slot < candidate.Weight() {
+			return candidate, nil
+		}
+		slot -= candidate.Weight()
+	}
+	return available[len(available)-1], nil
+}
+
+// smoothWeightedPick implements smooth weighted round-robin: every candidate gains its
+// weight on each pick, the highest running total wins and is reduced by the total weight.
+// Zero-weight candidates never win. Callers must hold the selector lock.
+func smoothWeightedPick(state map[string]map[string]int, key string, available []*Auth) *Auth {
+	current := state[key]
+	if current == nil {
+		current = make(map[string]int, len(available))
+		state[key] = current
+	}
+	total := 0
+	var selected *Auth
+	best := 0
+	for _, candidate := range available {
+		weight := candidate.Weight()
+		if weight <= 0 {
+			continue
+		}
+		total += weight
+		score := current[candidate.ID] + weight
+		current[candidate.ID] = score
+		if selected == nil || score > best {
+			selected = candidate
+			best = score
+		}
+	}
+	if selected == nil {
+		return nil
+	}
+	current[selected.ID] -= total
+	return selected
 }
 
 func isAuthBlockedForModel(auth *Auth, model string, now time.Time) (bool, blockReason, time.Time) {
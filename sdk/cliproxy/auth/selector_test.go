@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func weightedAuth(id, weight string) *Auth {
+	a := &Auth{ID: id, Provider: "gemini", Attributes: map[string]string{}}
+	if weight != "" {
+		a.Attributes["weight"] = weight
+	}
+	return a
+}
+
+func TestRoundRobinSelector_WeightedDistribution(t *testing.T) {
+	selector := &RoundRobinSelector{}
+	auths := []*Auth{weightedAuth("heavy", "3"), weightedAuth("light", "1"), weightedAuth("default", "")}
+
+	const picks = 5000
+	counts := make(map[string]int)
+	for i := 0; i < picks; i++ {
+		got, err := selector.Pick(context.Background(), "gemini", "gemini-2.5-pro", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick returned error: %v", err)
+		}
+		counts[got.ID]++
+	}
+
+	expected := map[string]float64{"heavy": 0.6, "light": 0.2, "default": 0.2}
+	for id, share := range expected {
+		actual := float64(counts[id]) / picks
+		if math.Abs(actual-share) > 0.02 {
+			t.Errorf("Expected %s to receive ~%.0f%% of picks, got %.2f%% (%v)", id, share*100, actual*100, counts)
+		}
+	}
+}
+
+func TestRoundRobinSelector_ZeroWeightExcluded(t *testing.T) {
+	selector := &RoundRobinSelector{}
+	auths := []*Auth{weightedAuth("active", "1"), weightedAuth("drained", "0")}
+
+	for i := 0; i < 100; i++ {
+		got, err := selector.Pick(context.Background(), "gemini", "", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick returned error: %v", err)
+		}
+		if got.ID == "drained" {
+			t.Fatal("Expected zero-weight credential to receive no traffic")
+		}
+	}
+
+	if _, err := selector.Pick(context.Background(), "gemini", "", cliproxyexecutor.Options{}, auths[1:]); err == nil {
+		t.Fatal("Expected an error when only zero-weight credentials remain")
+	}
+}
+
+func TestRoundRobinSelector_EqualWeightsRotate(t *testing.T) {
+	selector := &RoundRobinSelector{}
+	auths := []*Auth{weightedAuth("b", ""), weightedAuth("a", ""), weightedAuth("c", "")}
+
+	want := []string{"a", "b", "c", "a", "b", "c"}
+	for i, id := range want {
+		got, err := selector.Pick(context.Background(), "gemini", "", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick returned error: %v", err)
+		}
+		if got.ID != id {
+			t.Fatalf("Pick %d: expected %s, got %s", i, id, got.ID)
+		}
+	}
+}
+
+func TestAuthWeight_Metadata(t *testing.T) {
+	a := &Auth{Metadata: map[string]any{"weight": float64(4)}}
+	if got := a.Weight(); got != 4 {
+		t.Fatalf("Expected metadata weight 4, got %d", got)
+	}
+	a = &Auth{Attributes: map[string]string{"weight": "bogus"}}
+	if got := a.Weight(); got != DefaultWeight {
+		t.Fatalf("Expected default weight for invalid value, got %d", got)
+	}
+}
+
+func TestDeterministicSelector_RepeatableSelection(t *testing.T) {
+	auths := []*Auth{weightedAuth("c", ""), weightedAuth("a", ""), weightedAuth("b", "")}
+	shuffled := []*Auth{auths[1], auths[2], auths[0]}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		opts := cliproxyexecutor.Options{OriginalRequest: []byte(fmt.Sprintf(`{"messages":[{"role":"user","content":"prompt %d"}]}`, i))}
+		first, err := DeterministicSelector{}.Pick(context.Background(), "gemini", "gemini-2.5-pro", opts, auths)
+		if err != nil {
+			t.Fatalf("Pick returned error: %v", err)
+		}
+		for run := 0; run < 3; run++ {
+			again, _ := (DeterministicSelector{}).Pick(context.Background(), "gemini", "gemini-2.5-pro", opts, shuffled)
+			if again.ID != first.ID {
+				t.Fatalf("Prompt %d: expected %s on every pick regardless of candidate order, got %s", i, first.ID, again.ID)
+			}
+		}
+		seen[first.ID] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("Expected different requests to hash onto every credential, got %v", seen)
+	}
+}
+
+func TestManager_SetSelectionModeKeepsCustomSelector(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetSelectionMode("deterministic")
+	if _, ok := m.selector.(DeterministicSelector); !ok {
+		t.Fatalf("Expected deterministic selector, got %T", m.selector)
+	}
+	m.SetSelectionMode("")
+	if _, ok := m.selector.(*RoundRobinSelector); !ok {
+		t.Fatalf("Expected round-robin to be restored, got %T", m.selector)
+	}
+
+	m.SetSelector(fixedSelector{id: "pinned"})
+	m.SetSelectionMode("deterministic")
+	if _, ok := m.selector.(fixedSelector); !ok {
+		t.Fatalf("Expected the custom selector to stay in place, got %T", m.selector)
+	}
+	m.SetSelector(nil)
+	if _, ok := m.selector.(*RoundRobinSelector); !ok {
+		t.Fatalf("Expected nil to restore round-robin, got %T", m.selector)
+	}
+}
+
+type fixedSelector struct{ id string }
+
+func (s fixedSelector) Pick(_ context.Context, _, _ string, _ cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	for _, auth := range auths {
+		if auth.ID == s.id {
+			return auth, nil
+		}
+	}
+	return nil, &Error{Code: "auth_not_found", Message: "pinned auth not available"}
+}
+
+func TestAuthPriority(t *testing.T) {
+	if got := (&Auth{Metadata: map[string]any{"priority": float64(5)}}).Priority(); got != 5 {
+		t.Fatalf("Expected metadata priority 5, got %d", got)
+	}
+	if got := (&Auth{Attributes: map[string]string{"priority": "-1"}, Metadata: map[string]any{"priority": 3}}).Priority(); got != -1 {
+		t.Fatalf("Expected the attribute to win over metadata, got %d", got)
+	}
+	if got := (&Auth{}).Priority(); got != 0 {
+		t.Fatalf("Expected default priority 0, got %d", got)
+	}
+}
+
+func TestRoundRobinSelector_PrefersHighestPriority(t *testing.T) {
+	selector := &RoundRobinSelector{}
+	free := weightedAuth("free", "")
+	free.Attributes["priority"] = "10"
+	auths := []*Auth{weightedAuth("paid-a", ""), free, weightedAuth("paid-b", "")}
+	for i := 0; i < 5; i++ {
+		got, err := selector.Pick(context.Background(), "gemini", "gemini-2.5-pro", cliproxyexecutor.Options{}, auths)
+		if err != nil || got.ID != "free" {
+			t.Fatalf("Expected the priority tier to serve pick %d, got %v, %v", i, got, err)
+		}
+	}
+
+	free.ModelStates = map[string]*ModelState{"gemini-2.5-pro": {
+		Unavailable:    true,
+		NextRetryAfter: time.Now().Add(time.Minute),
+		Quota:          QuotaState{Exceeded: true},
+	}}
+	seen := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		got, err := selector.Pick(context.Background(), "gemini", "gemini-2.5-pro", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick returned error: %v", err)
+		}
+		seen[got.ID] = true
+	}
+	if len(seen) != 2 || !seen["paid-a"] || !seen["paid-b"] {
+		t.Fatalf("Expected the lower tier to rotate while the priority tier cools down, got %v", seen)
+	}
+}
+
+func TestManagerExecute_PriorityTierPromotion(t *testing.T) {
+	executor := &failoverTestExecutor{failures: map[string]error{
+		"free-a": &testStatusError{code: http.StatusTooManyRequests, msg: "quota exceeded"},
+		"free-b": &testStatusError{code: http.StatusTooManyRequests, msg: "quota exceeded"},
+	}}
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(executor)
+	for id, priority := range map[string]string{"free-a": "1", "free-b": "1", "paid": "0"} {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: "test", Attributes: map[string]string{"priority": priority}}); err != nil {
+			t.Fatalf("register %s: %v", id, err)
+		}
+	}
+	execute := func() string {
+		t.Helper()
+		resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+		if err != nil {
+			t.Fatalf("Execute returned error: %v (calls %v)", err, executor.calls)
+		}
+		return string(resp.Payload)
+	}
+
+	// Tier 1 is exhausted by the first request, which then falls through to tier 2.
+	if got := execute(); got != "paid" || len(executor.calls) != 3 {
+		t.Fatalf("Expected both free credentials to be tried before paid, got %q after %v", got, executor.calls)
+	}
+	executor.calls = nil
+	if got := execute(); got != "paid" || len(executor.calls) != 1 {
+		t.Fatalf("Expected tier 2 to serve while tier 1 cools down, got %q after %v", got, executor.calls)
+	}
+
+	delete(executor.failures, "free-a")
+	m.MarkResult(context.Background(), Result{AuthID: "free-a", Provider: "test", Success: true})
+	for i := 0; i < 3; i++ {
+		if got := execute(); got != "free-a" {
+			t.Fatalf("Expected new traffic to return to the recovered tier, got %q", got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// SessionMetadataKey carries a client supplied session ID in the execution options metadata.
+// With session affinity enabled, requests sharing a session ID keep using the credential that
+// served the session last, so providers with prompt caching keep their cache warm.
+const SessionMetadataKey = "session_id"
+
+type sessionAffinityEntry struct {
+	authID   string
+	lastUsed time.Time
+}
+
+// sessionAffinity maps sessions to the credential serving them, per provider. Entries expire
+// after the idle TTL; expired entries are swept at most once per TTL so the map stays bounded
+// by the number of sessions active within one TTL.
+type sessionAffinity struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]sessionAffinityEntry
+	lastSweep time.Time
+}
+
+// SetSessionAffinity enables sticky sessions with the given idle TTL. A session stays on its
+// credential while that credential is available; when it is in cooldown, disabled or failing
+// the request falls back to normal selection and the session moves to the new credential.
+// A non-positive TTL disables affinity and forgets all sessions.
+func (m *Manager) SetSessionAffinity(ttl time.Duration) {
+	if m == nil {
+		return
+	}
+	m.sessions.mu.Lock()
+	defer m.sessions.mu.Unlock()
+	if ttl <= 0 {
+		m.sessions.ttl = 0
+		m.sessions.entries = nil
+		return
+	}
+	m.sessions.ttl = ttl
+}
+
+func sessionAffinityKey(session, provider string) string {
+	return provider + "\x00" + session
+}
+
+// lookup returns the credential that last served session on provider.
+func (s *sessionAffinity) lookup(session, provider string, now time.Time) (string, bool) {
+	if session == "" {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ttl <= 0 {
+		return "", false
+	}
+	key := sessionAffinityKey(session, provider)
+	entry, ok := s.entries[key]
+	if !ok {
+		return "", false
+	}
+	if now.Sub(entry.lastUsed) >= s.ttl {
+		delete(s.entries, key)
+		return "", false
+	}
+	return entry.authID, true
+}
+
+// remember pins session on provider to authID and refreshes its idle timer.
+func (s *sessionAffinity) remember(session, provider, authID string, now time.Time) {
+	if session == "" || authID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ttl <= 0 {
+		return
+	}
+	if s.entries == nil {
+		s.entries = make(map[string]sessionAffinityEntry)
+		s.lastSweep = now
+	}
+	if now.Sub(s.lastSweep) >= s.ttl {
+		for key, entry := range s.entries {
+			if now.Sub(entry.lastUsed) >= s.ttl {
+				delete(s.entries, key)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.entries[sessionAffinityKey(session, provider)] = sessionAffinityEntry{authID: authID, lastUsed: now}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func sessionOptions(session string) cliproxyexecutor.Options {
+	return cliproxyexecutor.Options{Metadata: map[string]any{SessionMetadataKey: session}}
+}
+
+func TestManagerExecute_StickySessionRoutesToSameAccount(t *testing.T) {
+	executor := &failoverTestExecutor{}
+	m := newFailoverTestManager(t, executor, "a", "b", "c")
+	m.SetSessionAffinity(time.Minute)
+
+	served := make(map[string]string)
+	for i := 0; i < 6; i++ {
+		for _, session := range []string{"s1", "s2"} {
+			resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, sessionOptions(session))
+			if err != nil {
+				t.Fatalf("request %d of %s failed: %v", i, session, err)
+			}
+			if first, ok := served[session]; ok && first != string(resp.Payload) {
+				t.Fatalf("Expected session %s to stay on %s, got %s", session, first, resp.Payload)
+			}
+			served[session] = string(resp.Payload)
+		}
+	}
+	if served["s1"] == served["s2"] {
+		t.Fatalf("Expected new sessions to still be balanced, both went to %s", served["s1"])
+	}
+
+	// Without a session the round robin is unaffected.
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		resp, _ := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+		seen[string(resp.Payload)] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("Expected requests without a session to rotate, got %v", seen)
+	}
+}
+
+func TestManagerExecute_StickySessionFallsBackOnCooldown(t *testing.T) {
+	executor := &failoverTestExecutor{failures: map[string]error{}}
+	m := newFailoverTestManager(t, executor, "a", "b")
+	m.SetSessionAffinity(time.Minute)
+
+	resp, err := m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, sessionOptions("s1"))
+	if err != nil {
+		t.Fatalf("first request failed: %v", err)
+	}
+	sticky, other := string(resp.Payload), "a"
+	if sticky == "a" {
+		other = "b"
+	}
+
+	executor.mu.Lock()
+	executor.failures[sticky] = &testStatusError{code: http.StatusTooManyRequests, msg: "quota exceeded"}
+	executor.mu.Unlock()
+	if resp, err = m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, sessionOptions("s1")); err != nil || string(resp.Payload) != other {
+		t.Fatalf("Expected failover to %s, got %q, %v", other, resp.Payload, err)
+	}
+
+	// The session moved to the healthy account and stays there while the first cools down.
+	executor.mu.Lock()
+	delete(executor.failures, sticky)
+	executor.calls = nil
+	executor.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		if resp, err = m.Execute(context.Background(), []string{"test"}, cliproxyexecutor.Request{}, sessionOptions("s1")); err != nil || string(resp.Payload) != other {
+			t.Fatalf("Expected the session to stay on %s, got %q, %v", other, resp.Payload, err)
+		}
+	}
+	if len(executor.calls) != 3 {
+		t.Fatalf("Expected no calls to the cooling account, got %v", executor.calls)
+	}
+}
+
+func TestSessionAffinity_ExpiresIdleSessions(t *testing.T) {
+	affinity := &sessionAffinity{ttl: time.Minute}
+	start := time.Now()
+	affinity.remember("s1", "test", "a", start)
+	affinity.remember("s2", "test", "b", start.Add(30*time.Second))
+	if id, ok := affinity.lookup("s1", "test", start.Add(59*time.Second)); !ok || id != "a" {
+		t.Fatalf("Expected s1 within its TTL, got %q, %v", id, ok)
+	}
+	if _, ok := affinity.lookup("s1", "other", start); ok {
+		t.Fatal("Expected sessions to be tracked per provider")
+	}
+	if _, ok := affinity.lookup("s1", "test", start.Add(time.Minute)); ok {
+		t.Fatal("Expected s1 to expire after the idle TTL")
+	}
+
+	// Recording a session after a TTL has elapsed sweeps every idle entry.
+	affinity.remember("s3", "test", "c", start.Add(2*time.Minute))
+	if len(affinity.entries) != 1 {
+		t.Fatalf("Expected idle sessions to be swept, got %d entries", len(affinity.entries))
+	}
+}
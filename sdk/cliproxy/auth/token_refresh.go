@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+)
+
+type refreshCall struct {
+	done chan struct{}
+	auth *Auth
+	err  error
+}
+
+// refreshFlights tracks the refresh in progress per credential.
+type refreshFlights struct {
+	mu    sync.Mutex
+	calls map[string]*refreshCall
+}
+
+// refreshCoalesced refreshes credential id, or waits for the refresh already in progress for
+// it, so concurrent callers share a single call to the token endpoint. The refresh itself is
+// detached from the caller's cancellation because its result is shared by every waiter.
+func (m *Manager) refreshCoalesced(ctx context.Context, id string) (*Auth, error) {
+	m.refreshes.mu.Lock()
+	if call, ok := m.refreshes.calls[id]; ok {
+		m.refreshes.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.auth, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if m.refreshes.calls == nil {
+		m.refreshes.calls = make(map[string]*refreshCall)
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	m.refreshes.calls[id] = call
+	m.refreshes.mu.Unlock()
+
+	call.auth, call.err = m.refreshAuthNow(context.WithoutCancel(ctx), id)
+
+	m.refreshes.mu.Lock()
+	delete(m.refreshes.calls, id)
+	m.refreshes.mu.Unlock()
+	close(call.done)
+	return call.auth, call.err
+}
+
+// refreshAfterUnauthorized refreshes an OAuth credential whose access token was rejected by
+// an attempt started at attemptStart. When another request already refreshed the credential
+// after that attempt began, the stored token is reused instead of refreshing again. It
+// returns false for API keys and when the refresh fails.
+func (m *Manager) refreshAfterUnauthorized(ctx context.Context, auth *Auth, attemptStart time.Time) (*Auth, bool) {
+	if !isOAuthCredential(auth) {
+		return nil, false
+	}
+	if current, ok := m.GetByID(auth.ID); ok && current.LastRefreshedAt.After(attemptStart) {
+		return current, true
+	}
+	refreshed, err := m.refreshCoalesced(ctx, auth.ID)
+	if err != nil || refreshed == nil {
+		log.Warnf("failed to refresh %s credential %s after 401: %v", auth.Provider, auth.ID, err)
+		return nil, false
+	}
+	log.Debugf("refreshed %s credential %s after 401, retrying request", auth.Provider, auth.ID)
+	return refreshed, true
+}
+
+// isOAuthCredential reports whether auth holds refreshable tokens rather than a static API key.
+func isOAuthCredential(auth *Auth) bool {
+	if auth == nil || len(auth.Metadata) == 0 {
+		return false
+	}
+	return auth.Attributes["api_key"] == ""
+}
+
+// isUnauthorized reports whether err is an upstream 401.
+func isUnauthorized(err error) bool {
+	var se cliproxyexecutor.StatusError
+	return errors.As(err, &se) && se != nil && se.StatusCode() == http.StatusUnauthorized
+}
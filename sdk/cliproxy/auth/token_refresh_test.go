@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// expiringTokenExecutor rejects requests whose access token is not the latest one issued.
+type expiringTokenExecutor struct {
+	refreshes atomic.Int32
+	// refreshStarted, when set, receives a value once a refresh begins and the refresh then
+	// waits for releaseRefresh.
+	refreshStarted chan struct{}
+	releaseRefresh chan struct{}
+}
+
+func (e *expiringTokenExecutor) Identifier() string { return "oauth-test" }
+
+func (e *expiringTokenExecutor) validToken() string {
+	return "token-" + string(rune('0'+e.refreshes.Load()))
+}
+
+func (e *expiringTokenExecutor) Execute(_ context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	if token, _ := auth.Metadata["access_token"].(string); token != e.validToken() {
+		return cliproxyexecutor.Response{}, &testStatusError{code: http.StatusUnauthorized, msg: "token expired"}
+	}
+	return cliproxyexecutor.Response{Payload: []byte("ok")}, nil
+}
+
+func (e *expiringTokenExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, &testStatusError{code: http.StatusNotImplemented, msg: "not implemented"}
+}
+
+func (e *expiringTokenExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	if e.refreshStarted != nil {
+		e.refreshStarted <- struct{}{}
+		<-e.releaseRefresh
+	}
+	e.refreshes.Add(1)
+	auth.Metadata["access_token"] = e.validToken()
+	return auth, nil
+}
+
+func (e *expiringTokenExecutor) CountTokens(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+type recordingStore struct {
+	mu    sync.Mutex
+	saved []*Auth
+}
+
+func (s *recordingStore) List(context.Context) ([]*Auth, error) { return nil, nil }
+
+func (s *recordingStore) Save(_ context.Context, auth *Auth) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = append(s.saved, auth.Clone())
+	return auth.ID, nil
+}
+
+func (s *recordingStore) Delete(context.Context, string) error { return nil }
+
+func newTokenRefreshTestManager(t *testing.T, executor *expiringTokenExecutor, store Store) *Manager {
+	t.Helper()
+	m := NewManager(store, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(executor)
+	auth := &Auth{ID: "oauth-a", Provider: "oauth-test", Metadata: map[string]any{"access_token": "expired", "refresh_token": "r"}}
+	if _, err := m.Register(context.Background(), auth); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	return m
+}
+
+func TestManagerExecute_RefreshesExpiredToken(t *testing.T) {
+	executor := &expiringTokenExecutor{}
+	store := &recordingStore{}
+	m := newTokenRefreshTestManager(t, executor, store)
+
+	resp, err := m.Execute(context.Background(), []string{"oauth-test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != "ok" {
+		t.Fatalf("Expected the retried request to succeed, got %q, %v", resp.Payload, err)
+	}
+	if got := executor.refreshes.Load(); got != 1 {
+		t.Fatalf("Expected one refresh, got %d", got)
+	}
+	current, _ := m.GetByID("oauth-a")
+	if current.Metadata["access_token"] != "token-1" || current.LastRefreshedAt.IsZero() {
+		t.Fatalf("Expected the refreshed token to be stored, got %+v", current.Metadata)
+	}
+	store.mu.Lock()
+	last := store.saved[len(store.saved)-1]
+	store.mu.Unlock()
+	if last.Metadata["access_token"] != "token-1" {
+		t.Fatalf("Expected the refreshed token to be persisted, got %+v", last.Metadata)
+	}
+}
+
+func TestManagerExecute_CoalescesConcurrentRefreshes(t *testing.T) {
+	executor := &expiringTokenExecutor{refreshStarted: make(chan struct{}, 8), releaseRefresh: make(chan struct{})}
+	m := newTokenRefreshTestManager(t, executor, nil)
+
+	const requests = 8
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			_, err := m.Execute(context.Background(), []string{"oauth-test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+			errs <- err
+		}()
+	}
+	<-executor.refreshStarted
+	// Give the other requests time to hit the 401 and join the refresh in progress.
+	time.Sleep(50 * time.Millisecond)
+	close(executor.releaseRefresh)
+
+	for i := 0; i < requests; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Expected every request to succeed after the shared refresh, got %v", err)
+		}
+	}
+	if got := executor.refreshes.Load(); got != 1 {
+		t.Fatalf("Expected concurrent 401s to share one refresh, got %d", got)
+	}
+}
+
+func TestManagerExecute_DoesNotRefreshAPIKeys(t *testing.T) {
+	executor := &expiringTokenExecutor{}
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(executor)
+	if _, err := m.Register(context.Background(), &Auth{ID: "key-a", Provider: "oauth-test", Attributes: map[string]string{"api_key": "k"}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if _, err := m.Execute(context.Background(), []string{"oauth-test"}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Expected the 401 to be returned for an API key")
+	}
+	if got := executor.refreshes.Load(); got != 0 {
+		t.Fatalf("Expected no refresh for an API key, got %d", got)
+	}
+}
@@ -208,6 +208,77 @@ func (a *Auth) AccountInfo() (string, string) {
 	return "", ""
 }
 
+// DefaultWeight is the selection weight applied when a credential does not declare one.
+const DefaultWeight = 1
+
+// Weight returns the selection weight used by weighted round-robin.
+// It reads the "weight" attribute (config-backed credentials) and falls back to the
+// "weight" metadata entry (file-backed credentials). A weight of zero keeps the
+// credential registered but excludes it from new traffic; negative or unparsable
+// values fall back to DefaultWeight.
+func (a *Auth) Weight() int {
+	if a == nil {
+		return DefaultWeight
+	}
+	if a.Attributes != nil {
+		if raw := strings.TrimSpace(a.Attributes["weight"]); raw != "" {
+			if w, err := strconv.Atoi(raw); err == nil && w >= 0 {
+				return w
+			}
+			return DefaultWeight
+		}
+	}
+	if a.Metadata != nil {
+		if w, ok := metadataInt(a.Metadata["weight"]); ok && w >= 0 {
+			return w
+		}
+	}
+	return DefaultWeight
+}
+
+// Priority returns the selection tier of the credential; higher values are preferred.
+// Selectors only use the credentials of the highest priority that can currently serve a
+// request, so lower tiers take traffic only while every credential above them is in
+// cooldown, quota-exhausted or otherwise unavailable. It reads the "priority" attribute and
+// falls back to the "priority" metadata entry; credentials without one, or with an
+// unparsable one, have priority 0.
+func (a *Auth) Priority() int {
+	if a == nil {
+		return 0
+	}
+	if a.Attributes != nil {
+		if raw := strings.TrimSpace(a.Attributes["priority"]); raw != "" {
+			p, _ := strconv.Atoi(raw)
+			return p
+		}
+	}
+	if a.Metadata != nil {
+		if p, ok := metadataInt(a.Metadata["priority"]); ok {
+			return p
+		}
+	}
+	return 0
+}
+
+// metadataInt converts a decoded metadata value to an int.
+func metadataInt(value any) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case json.Number:
+		n, err := strconv.Atoi(v.String())
+		return n, err == nil
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		return n, err == nil
+	}
+	return 0, false
+}
+
 // ExpirationTime attempts to extract the credential expiration timestamp from metadata.
 // It inspects common keys such as "expired", "expire", "expires_at", and also
 // nested "token" objects to remain compatible with legacy auth file formats.
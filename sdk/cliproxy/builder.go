@@ -44,6 +44,9 @@ type Builder struct {
 	// coreManager handles core authentication and execution.
 	coreManager *coreauth.Manager
 
+	// credentialStore optionally replaces the auth directory as the source of credentials.
+	credentialStore coreauth.CredentialStore
+
 	// serverOptions contains additional server configuration options.
 	serverOptions []api.ServerOption
 }
@@ -136,6 +139,13 @@ func (b *Builder) WithCoreAuthManager(mgr *coreauth.Manager) *Builder {
 	return b
 }
 
+// WithCredentialStore sources credentials from store instead of the auth directory. Credential
+// sets pushed by its Watch replace the active credentials atomically.
+func (b *Builder) WithCredentialStore(store coreauth.CredentialStore) *Builder {
+	b.credentialStore = store
+	return b
+}
+
 // WithServerOptions appends server configuration options used during construction.
 func (b *Builder) WithServerOptions(opts ...api.ServerOption) *Builder {
 	b.serverOptions = append(b.serverOptions, opts...)
@@ -199,6 +209,9 @@ func (b *Builder) Build() (*Service, error) {
 		}
 		coreManager = coreauth.NewManager(tokenStore, nil, nil)
 	}
+	if b.credentialStore != nil {
+		coreManager.SetCredentialStore(b.credentialStore)
+	}
 	// Attach a default RoundTripper provider so providers can opt-in per-auth transports.
 	coreManager.SetRoundTripperProvider(newDefaultRoundTripperProvider())
 
@@ -214,5 +227,6 @@ func (b *Builder) Build() (*Service, error) {
 		coreManager:    coreManager,
 		serverOptions:  append([]api.ServerOption(nil), b.serverOptions...),
 	}
+	service.credentialStore = b.credentialStore
 	return service, nil
 }
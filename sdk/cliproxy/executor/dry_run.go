@@ -0,0 +1,189 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+type dryRunContextKey struct{}
+
+// WithDryRun marks ctx so that provider executors stop at the first upstream HTTP request
+// and report it as a *DryRunError instead of sending it.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunContextKey{}, true)
+}
+
+// IsDryRun reports whether ctx was marked by WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	enabled, _ := ctx.Value(dryRunContextKey{}).(bool)
+	return enabled
+}
+
+// DryRunTransport returns an http.RoundTripper that captures requests as *DryRunError
+// without performing any network I/O.
+func DryRunTransport() http.RoundTripper { return dryRunTransport{} }
+
+type dryRunTransport struct{}
+
+func (dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	captured := &DryRunError{Method: req.Method, URL: redactURL(req.URL), RequestHeaders: redactHeaders(req.Header)}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		captured.Body = body
+	}
+	return nil, captured
+}
+
+// ParamMutation describes a request parameter that translation changed or removed.
+type ParamMutation struct {
+	Param    string          `json:"param"`
+	Original json.RawMessage `json:"original"`
+	Sent     json.RawMessage `json:"sent,omitempty"`
+	Action   string          `json:"action"`
+}
+
+// DryRunError carries the upstream request captured during a dry run. It is returned as
+// an error so it unwinds through executors and the auth manager without side effects;
+// its Error text is the JSON description rendered to the client with status 200.
+type DryRunError struct {
+	Provider       string          `json:"provider"`
+	AuthID         string          `json:"auth_id"`
+	AuthLabel      string          `json:"auth_label,omitempty"`
+	Model          string          `json:"model"`
+	Method         string          `json:"method"`
+	URL            string          `json:"url"`
+	RequestHeaders http.Header     `json:"headers,omitempty"`
+	Body           []byte          `json:"-"`
+	Mutations      []ParamMutation `json:"mutated_params"`
+}
+
+// Error renders the captured request as JSON.
+func (e *DryRunError) Error() string {
+	type payload struct {
+		DryRun bool `json:"dry_run"`
+		*DryRunError
+		Body json.RawMessage `json:"body"`
+	}
+	out := payload{DryRun: true, DryRunError: e, Body: e.bodyJSON()}
+	if out.Mutations == nil {
+		out.Mutations = []ParamMutation{}
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return `{"dry_run":true}`
+	}
+	return string(data)
+}
+
+// StatusCode reports 200 so handlers render the dry-run description as a success.
+func (e *DryRunError) StatusCode() int { return http.StatusOK }
+
+// Headers sets the response content type for the rendered description.
+func (e *DryRunError) Headers() http.Header {
+	return http.Header{"Content-Type": {"application/json"}}
+}
+
+func (e *DryRunError) bodyJSON() json.RawMessage {
+	if len(e.Body) == 0 {
+		return json.RawMessage("null")
+	}
+	if json.Valid(e.Body) {
+		return json.RawMessage(e.Body)
+	}
+	quoted, _ := json.Marshal(string(e.Body))
+	return quoted
+}
+
+// dryRunParams lists well-known generation parameters with their locations in client and
+// upstream payloads across the supported formats.
+var dryRunParams = []struct {
+	name     string
+	paths    []string
+	upstream []string
+}{
+	{"temperature", []string{"temperature"}, []string{"temperature", "generationConfig.temperature", "request.generationConfig.temperature"}},
+	{"top_p", []string{"top_p"}, []string{"top_p", "generationConfig.topP", "request.generationConfig.topP"}},
+	{"top_k", []string{"top_k"}, []string{"top_k", "generationConfig.topK", "request.generationConfig.topK"}},
+	{"max_tokens", []string{"max_tokens", "max_completion_tokens", "max_output_tokens"}, []string{"max_tokens", "max_output_tokens", "generationConfig.maxOutputTokens", "request.generationConfig.maxOutputTokens"}},
+	{"thinking_budget", []string{"thinking.budget_tokens", "generationConfig.thinkingConfig.thinkingBudget"}, []string{"thinking.budget_tokens", "generationConfig.thinkingConfig.thinkingBudget", "request.generationConfig.thinkingConfig.thinkingBudget"}},
+}
+
+// DiffParams compares well-known generation parameters in the client payload with the
+// translated upstream payload and reports those that were changed or stripped.
+func DiffParams(original, sent []byte) []ParamMutation {
+	var mutations []ParamMutation
+	for _, param := range dryRunParams {
+		before := firstExisting(original, param.paths)
+		if !before.Exists() {
+			continue
+		}
+		after := firstExisting(sent, param.upstream)
+		switch {
+		case !after.Exists():
+			mutations = append(mutations, ParamMutation{Param: param.name, Original: json.RawMessage(before.Raw), Action: "stripped"})
+		case !sameJSON(before.Raw, after.Raw):
+			mutations = append(mutations, ParamMutation{Param: param.name, Original: json.RawMessage(before.Raw), Sent: json.RawMessage(after.Raw), Action: "changed"})
+		}
+	}
+	return mutations
+}
+
+func firstExisting(body []byte, paths []string) gjson.Result {
+	for _, path := range paths {
+		if result := gjson.GetBytes(body, path); result.Exists() {
+			return result
+		}
+	}
+	return gjson.Result{}
+}
+
+func sameJSON(a, b string) bool {
+	var left, right interface{}
+	if json.Unmarshal([]byte(a), &left) != nil || json.Unmarshal([]byte(b), &right) != nil {
+		return a == b
+	}
+	l, _ := json.Marshal(left)
+	r, _ := json.Marshal(right)
+	return bytes.Equal(l, r)
+}
+
+var sensitiveHeaders = []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key", "Cookie"}
+
+func redactHeaders(header http.Header) http.Header {
+	out := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, "[redacted]")
+		}
+	}
+	return out
+}
+
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	clone := *u
+	query := clone.Query()
+	for key := range query {
+		if strings.EqualFold(key, "key") {
+			query.Set(key, "[redacted]")
+		}
+	}
+	clone.RawQuery = query.Encode()
+	return clone.String()
+}
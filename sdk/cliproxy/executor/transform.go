@@ -0,0 +1,159 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// RequestTransformer rewrites a request after it has been translated to the provider format
+// and before it is sent upstream. req.Format names the provider schema of req.Payload.
+// Returning ErrStopTransform ends the chain and sends the request as it is; any other error
+// aborts the request without trying other credentials and is returned to the client as a
+// *TransformError, with the status of the error when it implements StatusError and 400
+// otherwise.
+type RequestTransformer interface {
+	Transform(ctx context.Context, req *Request) error
+}
+
+// ResponseTransformer rewrites a response after it has been translated back to the client
+// format, before it is returned. For streams it sees each chunk as a separate Response.
+// Errors follow the same rules as for RequestTransformer.
+type ResponseTransformer interface {
+	Transform(ctx context.Context, resp *Response) error
+}
+
+// RequestTransformerFunc adapts a function to RequestTransformer.
+type RequestTransformerFunc func(ctx context.Context, req *Request) error
+
+// Transform implements RequestTransformer.
+func (f RequestTransformerFunc) Transform(ctx context.Context, req *Request) error {
+	return f(ctx, req)
+}
+
+// ResponseTransformerFunc adapts a function to ResponseTransformer.
+type ResponseTransformerFunc func(ctx context.Context, resp *Response) error
+
+// Transform implements ResponseTransformer.
+func (f ResponseTransformerFunc) Transform(ctx context.Context, resp *Response) error {
+	return f(ctx, resp)
+}
+
+// ErrStopTransform is returned by a transformer to skip the transformers registered after it.
+var ErrStopTransform = errors.New("stop transform chain")
+
+// TransformError reports that a transformer rejected a request or response. It is not an
+// upstream failure: the auth manager returns it without failover and without penalising the
+// credential.
+type TransformError struct {
+	Err error
+}
+
+// Error implements error.
+func (e *TransformError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the transformer error.
+func (e *TransformError) Unwrap() error { return e.Err }
+
+// StatusCode returns the status carried by the transformer error, or 400.
+func (e *TransformError) StatusCode() int {
+	var se StatusError
+	if errors.As(e.Err, &se) && se != nil {
+		if code := se.StatusCode(); code > 0 {
+			return code
+		}
+	}
+	return http.StatusBadRequest
+}
+
+var (
+	transformersMu       sync.RWMutex
+	requestTransformers  []RequestTransformer
+	responseTransformers []ResponseTransformer
+)
+
+// RegisterRequestTransformer appends t to the request transformer chain. Transformers run
+// in registration order.
+func RegisterRequestTransformer(t RequestTransformer) {
+	if t == nil {
+		return
+	}
+	transformersMu.Lock()
+	requestTransformers = append(requestTransformers, t)
+	transformersMu.Unlock()
+}
+
+// RegisterResponseTransformer appends t to the response transformer chain. Transformers run
+// in registration order.
+func RegisterResponseTransformer(t ResponseTransformer) {
+	if t == nil {
+		return
+	}
+	transformersMu.Lock()
+	responseTransformers = append(responseTransformers, t)
+	transformersMu.Unlock()
+}
+
+// ResetTransformers removes every registered request and response transformer.
+func ResetTransformers() {
+	transformersMu.Lock()
+	requestTransformers = nil
+	responseTransformers = nil
+	transformersMu.Unlock()
+}
+
+// HasResponseTransformers reports whether any response transformer is registered, so
+// callers can skip copying responses when the chain is empty.
+func HasResponseTransformers() bool {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+	return len(responseTransformers) > 0
+}
+
+// TransformRequest runs the request transformer chain over req.
+func TransformRequest(ctx context.Context, req *Request) error {
+	transformersMu.RLock()
+	chain := requestTransformers
+	transformersMu.RUnlock()
+	for _, t := range chain {
+		if err := t.Transform(ctx, req); err != nil {
+			if errors.Is(err, ErrStopTransform) {
+				return nil
+			}
+			return &TransformError{Err: err}
+		}
+	}
+	return nil
+}
+
+// TransformResponse runs the response transformer chain over resp.
+func TransformResponse(ctx context.Context, resp *Response) error {
+	transformersMu.RLock()
+	chain := responseTransformers
+	transformersMu.RUnlock()
+	for _, t := range chain {
+		if err := t.Transform(ctx, resp); err != nil {
+			if errors.Is(err, ErrStopTransform) {
+				return nil
+			}
+			return &TransformError{Err: err}
+		}
+	}
+	return nil
+}
+
+// TransformStreamChunk runs the response transformer chain over the payload of a stream
+// chunk. A transformer error replaces the chunk with one carrying that error, which ends the
+// stream for the client.
+func TransformStreamChunk(ctx context.Context, chunk StreamChunk) StreamChunk {
+	if chunk.Err != nil || len(chunk.Payload) == 0 {
+		return chunk
+	}
+	resp := Response{Payload: chunk.Payload}
+	if err := TransformResponse(ctx, &resp); err != nil {
+		return StreamChunk{Err: err}
+	}
+	chunk.Payload = resp.Payload
+	return chunk
+}
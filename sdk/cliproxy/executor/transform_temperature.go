@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"context"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// TemperatureCap is an example RequestTransformer that lowers the sampling temperature of
+// every request to at most Max. It knows where each provider format keeps the temperature,
+// so it can be registered once for all upstreams:
+//
+//	executor.RegisterRequestTransformer(executor.TemperatureCap{Max: 0.7})
+type TemperatureCap struct {
+	Max float64
+}
+
+// Transform implements RequestTransformer.
+func (t TemperatureCap) Transform(_ context.Context, req *Request) error {
+	path := temperaturePath(req.Format)
+	if temperature := gjson.GetBytes(req.Payload, path); temperature.Type == gjson.Number && temperature.Float() > t.Max {
+		payload, err := sjson.SetBytes(req.Payload, path, t.Max)
+		if err != nil {
+			return err
+		}
+		req.Payload = payload
+	}
+	return nil
+}
+
+func temperaturePath(format sdktranslator.Format) string {
+	switch format {
+	case sdktranslator.FormatGemini:
+		return "generationConfig.temperature"
+	case sdktranslator.FormatGeminiCLI, sdktranslator.FormatAntigravity:
+		return "request.generationConfig.temperature"
+	default:
+		return "temperature"
+	}
+}
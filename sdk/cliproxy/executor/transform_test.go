@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+type testStatusError struct{ code int }
+
+func (e testStatusError) Error() string   { return http.StatusText(e.code) }
+func (e testStatusError) StatusCode() int { return e.code }
+
+// appendTransformer appends name to the payload so the test can observe the order.
+func appendTransformer(name string, err error) RequestTransformerFunc {
+	return func(_ context.Context, req *Request) error {
+		req.Payload = append(req.Payload, name...)
+		return err
+	}
+}
+
+func TestTransformRequest_RunsInOrder(t *testing.T) {
+	t.Cleanup(ResetTransformers)
+	RegisterRequestTransformer(appendTransformer("a", nil))
+	RegisterRequestTransformer(appendTransformer("b", nil))
+	RegisterRequestTransformer(appendTransformer("c", nil))
+
+	req := &Request{}
+	if err := TransformRequest(context.Background(), req); err != nil {
+		t.Fatalf("TransformRequest: %v", err)
+	}
+	if string(req.Payload) != "abc" {
+		t.Fatalf("Expected transformers to run in registration order, got %q", req.Payload)
+	}
+}
+
+func TestTransformRequest_StopSkipsRemaining(t *testing.T) {
+	t.Cleanup(ResetTransformers)
+	RegisterRequestTransformer(appendTransformer("a", ErrStopTransform))
+	RegisterRequestTransformer(appendTransformer("b", nil))
+
+	req := &Request{}
+	if err := TransformRequest(context.Background(), req); err != nil {
+		t.Fatalf("Expected ErrStopTransform to end the chain without an error, got %v", err)
+	}
+	if string(req.Payload) != "a" {
+		t.Fatalf("Expected later transformers to be skipped, got %q", req.Payload)
+	}
+}
+
+func TestTransformRequest_ErrorAbortsRequest(t *testing.T) {
+	t.Cleanup(ResetTransformers)
+	RegisterRequestTransformer(appendTransformer("a", testStatusError{code: http.StatusForbidden}))
+	RegisterRequestTransformer(appendTransformer("b", nil))
+
+	req := &Request{}
+	err := TransformRequest(context.Background(), req)
+	var transformErr *TransformError
+	if !errors.As(err, &transformErr) || transformErr.StatusCode() != http.StatusForbidden {
+		t.Fatalf("Expected a TransformError with status 403, got %v", err)
+	}
+	if string(req.Payload) != "a" {
+		t.Fatalf("Expected the chain to stop at the failing transformer, got %q", req.Payload)
+	}
+
+	if code := (&TransformError{Err: errors.New("policy")}).StatusCode(); code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for errors without a status, got %d", code)
+	}
+}
+
+func TestTransformStreamChunk(t *testing.T) {
+	t.Cleanup(ResetTransformers)
+	RegisterResponseTransformer(ResponseTransformerFunc(func(_ context.Context, resp *Response) error {
+		if strings.Contains(string(resp.Payload), "secret") {
+			return errors.New("blocked")
+		}
+		resp.Payload = []byte(strings.ToUpper(string(resp.Payload)))
+		return nil
+	}))
+
+	if got := TransformStreamChunk(context.Background(), StreamChunk{Payload: []byte("data")}); string(got.Payload) != "DATA" || got.Err != nil {
+		t.Fatalf("Expected the chunk to be transformed, got %+v", got)
+	}
+	if got := TransformStreamChunk(context.Background(), StreamChunk{Payload: []byte("secret")}); got.Err == nil || got.Payload != nil {
+		t.Fatalf("Expected the chunk to be replaced by an error, got %+v", got)
+	}
+}
+
+func TestTemperatureCap(t *testing.T) {
+	cases := []struct {
+		format  sdktranslator.Format
+		payload string
+		path    string
+		want    float64
+	}{
+		{sdktranslator.FormatOpenAI, `{"temperature":1.5}`, "temperature", 0.7},
+		{sdktranslator.FormatClaude, `{"temperature":0.2}`, "temperature", 0.2},
+		{sdktranslator.FormatGemini, `{"generationConfig":{"temperature":2}}`, "generationConfig.temperature", 0.7},
+		{sdktranslator.FormatGeminiCLI, `{"request":{"generationConfig":{"temperature":1}}}`, "request.generationConfig.temperature", 0.7},
+	}
+	for _, tc := range cases {
+		req := &Request{Format: tc.format, Payload: []byte(tc.payload)}
+		if err := (TemperatureCap{Max: 0.7}).Transform(context.Background(), req); err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		if got := gjson.GetBytes(req.Payload, tc.path).Float(); got != tc.want {
+			t.Errorf("%s: expected temperature %v, got %s", tc.format, tc.want, req.Payload)
+		}
+	}
+
+	req := &Request{Format: sdktranslator.FormatOpenAI, Payload: []byte(`{"model":"m"}`)}
+	if err := (TemperatureCap{Max: 0.7}).Transform(context.Background(), req); err != nil || string(req.Payload) != `{"model":"m"}` {
+		t.Fatalf("Expected requests without a temperature to be left alone, got %s (%v)", req.Payload, err)
+	}
+}
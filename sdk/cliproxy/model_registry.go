@@ -13,6 +13,8 @@ type ModelRegistry interface {
 	ClearModelQuotaExceeded(clientID, modelID string)
 	ClientSupportsModel(clientID, modelID string) bool
 	GetAvailableModels(handlerType string) []map[string]any
+	GetAllModels(handlerType string) []map[string]any
+	GetModelsForClient(clientID string) []*ModelInfo
 }
 
 // GlobalModelRegistry returns the shared registry instance.
@@ -42,7 +42,8 @@ func (p *defaultRoundTripperProvider) RoundTripperFor(auth *coreauth.Auth) http.
 	// Parse the proxy URL to determine the scheme.
 	proxyURL, errParse := url.Parse(proxyStr)
 	if errParse != nil {
-		log.Errorf("parse proxy URL failed: %v", errParse)
+		// The parse error quotes the URL, credentials included.
+		log.Errorf("parse proxy URL failed: invalid URL")
 		return nil
 	}
 	var transport *http.Transport
@@ -81,6 +81,9 @@ type Service struct {
 	// coreManager handles core authentication and execution.
 	coreManager *coreauth.Manager
 
+	// credentialStore is the custom credential source, if any; nil means the auth directory.
+	credentialStore coreauth.CredentialStore
+
 	// shutdownOnce ensures shutdown is called only once.
 	shutdownOnce sync.Once
 
@@ -309,12 +312,72 @@ func (s *Service) applyCoreAuthRemoval(ctx context.Context, id string) {
 	}
 }
 
+// prepareCredential registers the executor and models a credential needs to serve requests.
+func (s *Service) prepareCredential(auth *coreauth.Auth) {
+	if auth == nil || auth.ID == "" || auth.Disabled {
+		return
+	}
+	s.ensureExecutorsForAuth(auth)
+	s.registerModelsForAuth(auth)
+}
+
+// applyCredentialSet swaps in a credential set pushed by the credential store. Models of new
+// credentials are registered before the swap and those of removed ones dropped after it.
+func (s *Service) applyCredentialSet(ctx context.Context, auths []*coreauth.Auth) {
+	if s == nil || s.coreManager == nil {
+		return
+	}
+	for _, auth := range auths {
+		s.prepareCredential(auth.Clone())
+	}
+	for _, id := range s.coreManager.ReplaceAuths(ctx, auths) {
+		GlobalModelRegistry().UnregisterClient(id)
+	}
+}
+
 func (s *Service) applyRetryConfig(cfg *config.Config) {
 	if s == nil || s.coreManager == nil || cfg == nil {
 		return
 	}
 	maxInterval := time.Duration(cfg.MaxRetryInterval) * time.Second
 	s.coreManager.SetRetryConfig(cfg.RequestRetry, maxInterval)
+	s.coreManager.SetCredentialAttempts(cfg.MaxCredentialAttempts)
+	s.coreManager.SetRetryBackoff(time.Duration(cfg.RetryBackoffBaseMs)*time.Millisecond, time.Duration(cfg.RetryBackoffMaxMs)*time.Millisecond)
+	s.coreManager.SetTimeouts(time.Duration(cfg.AttemptTimeoutSeconds)*time.Second, time.Duration(cfg.StreamAttemptTimeoutSeconds)*time.Second, time.Duration(cfg.RequestDeadlineSeconds)*time.Second)
+	s.coreManager.SetSessionAffinity(time.Duration(cfg.StickySessionTTLSeconds) * time.Second)
+	s.coreManager.SetSelectionMode(cfg.AccountSelection)
+	s.coreManager.SetRetryClassification(coreauth.RetryClassification{StatusCodes: cfg.RetryOn.StatusCodes, BodySubstrings: cfg.RetryOn.BodyContains})
+	s.coreManager.SetCircuitBreaker(coreauth.CircuitBreakerConfig{
+		FailureThreshold: cfg.CircuitBreaker.FailureThreshold,
+		Window:           time.Duration(cfg.CircuitBreaker.WindowSeconds) * time.Second,
+		Cooldown:         time.Duration(cfg.CircuitBreaker.CooldownSeconds) * time.Second,
+	})
+	s.coreManager.SetConcurrencyLimits(coreauth.ConcurrencyConfig{
+		Limits:           cfg.ModelConcurrency.Limits,
+		QueueTimeout:     time.Duration(cfg.ModelConcurrency.QueueTimeoutSeconds) * time.Second,
+		FlexQueueTimeout: time.Duration(cfg.ModelConcurrency.FlexQueueTimeoutSeconds) * time.Second,
+	})
+	quotas := make([]coreauth.QuotaLimit, 0, len(cfg.AccountQuotas))
+	for _, quota := range cfg.AccountQuotas {
+		quotas = append(quotas, coreauth.QuotaLimit{
+			AuthID:      quota.AuthID,
+			Provider:    quota.Provider,
+			Window:      coreauth.QuotaWindow(quota.Window),
+			MaxTokens:   quota.MaxTokens,
+			MaxRequests: quota.MaxRequests,
+		})
+	}
+	s.coreManager.SetQuotas(quotas)
+	rewrites := make([]coreauth.ModelRewrite, 0, len(cfg.ModelRewrites))
+	for _, rule := range cfg.ModelRewrites {
+		rewrites = append(rewrites, coreauth.ModelRewrite{
+			Provider: rule.Provider,
+			Match:    rule.Match,
+			Replace:  rule.Replace,
+			Regex:    rule.Regex,
+		})
+	}
+	s.coreManager.SetModelRewrites(rewrites)
 }
 
 func openAICompatInfoFromAuth(a *coreauth.Auth) (providerKey string, compatName string, ok bool) {
@@ -417,10 +480,16 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 
 	usage.StartDefault(ctx)
+	if s.coreManager != nil {
+		// Feed token usage into per-account quotas.
+		usage.RegisterPlugin(s.coreManager)
+	}
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer shutdownCancel()
 	defer func() {
+		// The shutdown budget starts when shutdown begins, leaving room to stop the
+		// remaining components after the HTTP server has drained.
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), api.ShutdownDrainTimeout(s.cfg)+10*time.Second)
+		defer shutdownCancel()
 		if err := s.Shutdown(shutdownCtx); err != nil {
 			log.Errorf("service shutdown returned error: %v", err)
 		}
@@ -436,6 +505,14 @@ func (s *Service) Run(ctx context.Context) error {
 		if errLoad := s.coreManager.Load(ctx); errLoad != nil {
 			log.Warnf("failed to load auth store: %v", errLoad)
 		}
+		if s.credentialStore != nil {
+			for _, auth := range s.coreManager.List() {
+				s.prepareCredential(auth)
+			}
+		}
+		if errWatch := s.coreManager.WatchCredentials(ctx, s.applyCredentialSet); errWatch != nil {
+			log.Warnf("failed to watch credential store: %v", errWatch)
+		}
 	}
 
 	tokenResult, err := s.tokenProvider.Load(ctx, s.cfg)
@@ -458,6 +535,7 @@ func (s *Service) Run(ctx context.Context) error {
 
 	// handlers no longer depend on legacy clients; pass nil slice initially
 	s.server = api.NewServer(s.cfg, s.coreManager, s.accessManager, s.configPath, s.serverOptions...)
+	s.server.SetModelReloader(s.ReloadModelRegistry)
 
 	if s.authManager == nil {
 		s.authManager = newDefaultAuthManager()
@@ -529,6 +607,9 @@ func (s *Service) Run(ctx context.Context) error {
 		return fmt.Errorf("cliproxy: failed to create watcher: %w", err)
 	}
 	s.watcher = watcherWrapper
+	if s.server != nil {
+		s.server.SetCredentialReloader(watcherWrapper.Reload)
+	}
 	s.ensureAuthUpdateQueue(ctx)
 	if s.authUpdates != nil {
 		watcherWrapper.SetAuthUpdateQueue(s.authUpdates)
@@ -607,9 +688,7 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		// no legacy clients to persist
 
 		if s.server != nil {
-			shutdownCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-			defer cancel()
-			if err := s.server.Stop(shutdownCtx); err != nil {
+			if err := s.server.Stop(ctx); err != nil {
 				log.Errorf("error stopping API server: %v", err)
 				if shutdownErr == nil {
 					shutdownErr = err
@@ -645,13 +724,6 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 	if a == nil || a.ID == "" {
 		return
 	}
-	authKind := strings.ToLower(strings.TrimSpace(a.Attributes["auth_kind"]))
-	if a.Attributes != nil {
-		if v := strings.TrimSpace(a.Attributes["gemini_virtual_primary"]); strings.EqualFold(v, "true") {
-			GlobalModelRegistry().UnregisterClient(a.ID)
-			return
-		}
-	}
 	// Unregister legacy client ID (if present) to avoid double counting
 	if a.Runtime != nil {
 		if idGetter, ok := a.Runtime.(interface{ GetClientID() string }); ok {
@@ -660,6 +732,51 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 			}
 		}
 	}
+	if key, models, _ := s.modelsForAuth(a); len(models) > 0 {
+		GlobalModelRegistry().RegisterClient(a.ID, key, models)
+		return
+	}
+	GlobalModelRegistry().UnregisterClient(a.ID)
+}
+
+// ReloadModelRegistry rebuilds the models of every enabled credential from the built-in
+// definitions, the current config and, for providers that publish a catalog, the upstream,
+// then replaces the registry contents in one step. Credentials of providers the service does
+// not know keep the models registered for them by their plugin.
+func (s *Service) ReloadModelRegistry() (registry.ReloadSummary, error) {
+	if s == nil || s.coreManager == nil {
+		return registry.ReloadSummary{}, fmt.Errorf("cliproxy: service not running")
+	}
+	auths := s.coreManager.List()
+	clients := make([]registry.ClientModels, 0, len(auths))
+	for _, a := range auths {
+		if a == nil || a.ID == "" || a.Disabled {
+			continue
+		}
+		key, models, handled := s.modelsForAuth(a)
+		if !handled {
+			key, models = strings.ToLower(strings.TrimSpace(a.Provider)), GlobalModelRegistry().GetModelsForClient(a.ID)
+		}
+		if len(models) == 0 {
+			continue
+		}
+		clients = append(clients, registry.ClientModels{ClientID: a.ID, Provider: key, Models: models})
+	}
+	summary := registry.GetGlobalRegistry().Reload(clients)
+	log.Infof("model registry reloaded: %d clients, %d models (+%d, -%d)", summary.Clients, summary.Models, len(summary.Added), len(summary.Removed))
+	return summary, nil
+}
+
+// modelsForAuth returns the registry provider key and models of a credential. No models means
+// the credential should not be registered. handled is false for providers the service has no
+// model source for.
+func (s *Service) modelsForAuth(a *coreauth.Auth) (string, []*ModelInfo, bool) {
+	authKind := strings.ToLower(strings.TrimSpace(a.Attributes["auth_kind"]))
+	if a.Attributes != nil {
+		if v := strings.TrimSpace(a.Attributes["gemini_virtual_primary"]); strings.EqualFold(v, "true") {
+			return "", nil, true
+		}
+	}
 	provider := strings.ToLower(strings.TrimSpace(a.Provider))
 	compatProviderKey, compatDisplayName, compatDetected := openAICompatInfoFromAuth(a)
 	if compatDetected {
@@ -695,6 +812,13 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		models = executor.FetchAntigravityModels(ctx, a, s.cfg)
 		cancel()
+		if len(models) == 0 {
+			// Keep the last known catalog so a failed fetch does not drop the provider from listings.
+			if previous := GlobalModelRegistry().GetModelsForClient(a.ID); len(previous) > 0 {
+				log.Warnf("antigravity model fetch failed for %s, keeping %d previously registered models", a.ID, len(previous))
+				models = previous
+			}
+		}
 		models = applyExcludedModels(models, excluded)
 	case "claude":
 		models = registry.GetClaudeModels()
@@ -782,36 +906,25 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 							DisplayName: m.Name,
 						})
 					}
-					// Register and return
-					if len(ms) > 0 {
-						if providerKey == "" {
-							providerKey = "openai-compatibility"
-						}
-						GlobalModelRegistry().RegisterClient(a.ID, providerKey, ms)
-					} else {
-						// Ensure stale registrations are cleared when model list becomes empty.
-						GlobalModelRegistry().UnregisterClient(a.ID)
+					// An empty model list clears stale registrations.
+					if providerKey == "" {
+						providerKey = "openai-compatibility"
 					}
-					return
+					return providerKey, ms, true
 				}
 			}
 			if isCompatAuth {
 				// No matching provider found or models removed entirely; drop any prior registration.
-				GlobalModelRegistry().UnregisterClient(a.ID)
-				return
+				return "", nil, true
 			}
 		}
+		return "", nil, false
 	}
-	if len(models) > 0 {
-		key := provider
-		if key == "" {
-			key = strings.ToLower(strings.TrimSpace(a.Provider))
-		}
-		GlobalModelRegistry().RegisterClient(a.ID, key, models)
-		return
+	key := provider
+	if key == "" {
+		key = strings.ToLower(strings.TrimSpace(a.Provider))
 	}
-
-	GlobalModelRegistry().UnregisterClient(a.ID)
+	return key, models, true
 }
 
 func (s *Service) resolveConfigClaudeKey(auth *coreauth.Auth) *config.ClaudeKey {
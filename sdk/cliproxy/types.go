@@ -5,6 +5,7 @@ package cliproxy
 
 import (
 	"context"
+	"errors"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
@@ -89,6 +90,7 @@ type WatcherWrapper struct {
 	snapshotAuths         func() []*coreauth.Auth
 	setUpdateQueue        func(queue chan<- watcher.AuthUpdate)
 	dispatchRuntimeUpdate func(update watcher.AuthUpdate) bool
+	reload                func() (watcher.ReloadSummary, error)
 }
 
 // Start proxies to the underlying watcher Start implementation.
@@ -139,6 +141,14 @@ func (w *WatcherWrapper) SnapshotAuths() []*coreauth.Auth {
 	return w.snapshotAuths()
 }
 
+// Reload re-reads the config and auth directory and swaps in the resulting credential set.
+func (w *WatcherWrapper) Reload() (watcher.ReloadSummary, error) {
+	if w == nil || w.reload == nil {
+		return watcher.ReloadSummary{}, errors.New("credential reload not supported")
+	}
+	return w.reload()
+}
+
 // SetAuthUpdateQueue registers the channel used to propagate auth updates.
 func (w *WatcherWrapper) SetAuthUpdateQueue(queue chan<- watcher.AuthUpdate) {
 	if w == nil || w.setUpdateQueue == nil {
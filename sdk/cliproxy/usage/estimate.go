@@ -0,0 +1,163 @@
+package usage
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/tiktoken-go/tokenizer"
+)
+
+const (
+	// EstimatorHeuristic names the built-in estimator counting four characters per token.
+	EstimatorHeuristic = "heuristic"
+
+	// EstimatorTiktoken names the built-in estimator using the OpenAI tiktoken encodings.
+	EstimatorTiktoken = "tiktoken"
+)
+
+// TokenEstimator approximates the number of tokens model would count for text. It is used to
+// fill usage when an upstream does not report it.
+type TokenEstimator interface {
+	EstimateTokens(model, text string) int64
+}
+
+// HeuristicEstimator counts one token per four characters, which is close for English prose
+// on every model family and needs no tokenizer data.
+type HeuristicEstimator struct{}
+
+// EstimateTokens implements TokenEstimator.
+func (HeuristicEstimator) EstimateTokens(_ string, text string) int64 {
+	chars := utf8.RuneCountInString(text)
+	return int64((chars + 3) / 4)
+}
+
+// TiktokenEstimator encodes text with the tiktoken encoding of model, falling back to
+// o200k_base for models tiktoken does not know. It is exact for OpenAI models and a close
+// approximation for others.
+type TiktokenEstimator struct {
+	codecs sync.Map
+}
+
+// EstimateTokens implements TokenEstimator.
+func (e *TiktokenEstimator) EstimateTokens(model, text string) int64 {
+	if text == "" {
+		return 0
+	}
+	codec := e.codec(model)
+	if codec == nil {
+		return HeuristicEstimator{}.EstimateTokens(model, text)
+	}
+	count, err := codec.Count(text)
+	if err != nil {
+		return HeuristicEstimator{}.EstimateTokens(model, text)
+	}
+	return int64(count)
+}
+
+func (e *TiktokenEstimator) codec(model string) tokenizer.Codec {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if cached, ok := e.codecs.Load(model); ok {
+		return cached.(tokenizer.Codec)
+	}
+	codec, err := tokenizer.ForModel(tokenizer.Model(model))
+	if err != nil {
+		if codec, err = tokenizer.Get(tokenizer.O200kBase); err != nil {
+			return nil
+		}
+	}
+	e.codecs.Store(model, codec)
+	return codec
+}
+
+var (
+	estimatorsMu sync.RWMutex
+	estimators   = map[string]TokenEstimator{
+		EstimatorHeuristic: HeuristicEstimator{},
+		EstimatorTiktoken:  &TiktokenEstimator{},
+	}
+)
+
+// RegisterTokenEstimator makes estimator selectable under name, replacing any estimator
+// registered with the same name.
+func RegisterTokenEstimator(name string, estimator TokenEstimator) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || estimator == nil {
+		return
+	}
+	estimatorsMu.Lock()
+	estimators[name] = estimator
+	estimatorsMu.Unlock()
+}
+
+// LookupTokenEstimator returns the estimator registered under name.
+func LookupTokenEstimator(name string) (TokenEstimator, bool) {
+	estimatorsMu.RLock()
+	defer estimatorsMu.RUnlock()
+	estimator, ok := estimators[strings.ToLower(strings.TrimSpace(name))]
+	return estimator, ok
+}
+
+type estimationContextKey struct{}
+
+// Estimation collects the usage records published without token counts during one request
+// so they can be published once the caller has estimated the usage.
+type Estimation struct {
+	mu       sync.Mutex
+	held     []queueItem
+	resolved bool
+	detail   Detail
+}
+
+// WithEstimation returns a context under which successful records without token counts are
+// held back until Resolve is called on the returned Estimation.
+func WithEstimation(ctx context.Context) (context.Context, *Estimation) {
+	estimation := &Estimation{}
+	return context.WithValue(ctx, estimationContextKey{}, estimation), estimation
+}
+
+// Resolve publishes the held records with detail, flagged as estimated. Records arriving
+// afterwards are published with the same detail. A zero detail publishes them unchanged.
+func (e *Estimation) Resolve(detail Detail) {
+	if e == nil {
+		return
+	}
+	if !detail.IsZero() {
+		detail.Estimated = true
+	}
+	e.mu.Lock()
+	held := e.held
+	e.held = nil
+	e.resolved = true
+	e.detail = detail
+	e.mu.Unlock()
+	for _, item := range held {
+		item.record.Detail = detail
+		DefaultManager().Publish(item.ctx, item.record)
+	}
+}
+
+// hold keeps record until Resolve, reporting false when it should be published as is.
+func (e *Estimation) hold(ctx context.Context, record Record) bool {
+	if e == nil || record.Failed || !record.Detail.IsZero() {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.resolved {
+		record.Detail = e.detail
+		DefaultManager().Publish(ctx, record)
+		return true
+	}
+	e.held = append(e.held, queueItem{ctx: ctx, record: record})
+	return true
+}
+
+func estimationFromContext(ctx context.Context) *Estimation {
+	if ctx == nil {
+		return nil
+	}
+	estimation, _ := ctx.Value(estimationContextKey{}).(*Estimation)
+	return estimation
+}
@@ -0,0 +1,93 @@
+package usage
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// estimateFixture is counted at 32 tokens by both cl100k_base and o200k_base.
+const estimateFixture = "The quick brown fox jumps over the lazy dog. Proxies translate requests between API formats, so token counts reported by one provider rarely match another's tokenizer exactly."
+
+const estimateFixtureTokens = 32
+
+func TestTokenEstimatorsMatchFixture(t *testing.T) {
+	cases := []struct {
+		name      string
+		model     string
+		tolerance float64
+	}{
+		{name: EstimatorTiktoken, model: "gpt-4o", tolerance: 0.05},
+		{name: EstimatorTiktoken, model: "gemini-2.5-pro", tolerance: 0.05},
+		{name: EstimatorHeuristic, model: "claude-sonnet-4", tolerance: 0.4},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name+"/"+tc.model, func(t *testing.T) {
+			estimator, ok := LookupTokenEstimator(tc.name)
+			if !ok {
+				t.Fatalf("Expected estimator %q to be registered", tc.name)
+			}
+			got := estimator.EstimateTokens(tc.model, estimateFixture)
+			if diff := math.Abs(float64(got-estimateFixtureTokens)) / estimateFixtureTokens; diff > tc.tolerance {
+				t.Fatalf("Expected about %d tokens (±%.0f%%), got %d", estimateFixtureTokens, tc.tolerance*100, got)
+			}
+		})
+	}
+}
+
+type fixedEstimator int64
+
+func (e fixedEstimator) EstimateTokens(string, string) int64 { return int64(e) }
+
+func TestRegisterTokenEstimator(t *testing.T) {
+	RegisterTokenEstimator("Fixed", fixedEstimator(7))
+	estimator, ok := LookupTokenEstimator("fixed")
+	if !ok || estimator.EstimateTokens("any", "text") != 7 {
+		t.Fatalf("Expected the registered estimator to be found case-insensitively, got %v", estimator)
+	}
+}
+
+type recordingPlugin struct {
+	model   string
+	records chan Record
+}
+
+func (p *recordingPlugin) HandleUsage(_ context.Context, record Record) {
+	if record.Model == p.model {
+		p.records <- record
+	}
+}
+
+func TestEstimationHoldsZeroRecordsUntilResolved(t *testing.T) {
+	plugin := &recordingPlugin{model: "estimation-hold", records: make(chan Record, 4)}
+	RegisterPlugin(plugin)
+
+	ctx, estimation := WithEstimation(context.Background())
+	PublishRecord(ctx, Record{Model: "estimation-hold"})
+	select {
+	case record := <-plugin.records:
+		t.Fatalf("Expected the zero record to be held, got %+v", record)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	estimation.Resolve(Detail{InputTokens: 10, OutputTokens: 5, TotalTokens: 15})
+	select {
+	case record := <-plugin.records:
+		if !record.Detail.Estimated || record.Detail.TotalTokens != 15 {
+			t.Fatalf("Expected the held record with estimated usage, got %+v", record.Detail)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the held record to be published on resolve")
+	}
+
+	PublishRecord(ctx, Record{Model: "estimation-hold", Detail: Detail{InputTokens: 3, TotalTokens: 3}})
+	select {
+	case record := <-plugin.records:
+		if record.Detail.Estimated || record.Detail.InputTokens != 3 {
+			t.Fatalf("Expected a reported record to pass through unchanged, got %+v", record.Detail)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the reported record to be published")
+	}
+}
@@ -28,6 +28,15 @@ type Detail struct {
 	ReasoningTokens int64
 	CachedTokens    int64
 	TotalTokens     int64
+
+	// Estimated marks counts approximated by a TokenEstimator because the upstream did not
+	// report them.
+	Estimated bool
+}
+
+// IsZero reports whether detail carries no token counts.
+func (d Detail) IsZero() bool {
+	return d.InputTokens == 0 && d.OutputTokens == 0 && d.ReasoningTokens == 0 && d.CachedTokens == 0 && d.TotalTokens == 0
 }
 
 // Plugin consumes usage records emitted by the proxy runtime.
@@ -171,8 +180,14 @@ func DefaultManager() *Manager { return defaultManager }
 // RegisterPlugin registers a plugin on the default manager.
 func RegisterPlugin(plugin Plugin) { DefaultManager().Register(plugin) }
 
-// PublishRecord publishes a record using the default manager.
-func PublishRecord(ctx context.Context, record Record) { DefaultManager().Publish(ctx, record) }
+// PublishRecord publishes a record using the default manager. Successful records without
+// token counts are held back when ctx carries an Estimation.
+func PublishRecord(ctx context.Context, record Record) {
+	if estimationFromContext(ctx).hold(ctx, record) {
+		return
+	}
+	DefaultManager().Publish(ctx, record)
+}
 
 // StartDefault starts the default manager's dispatcher.
 func StartDefault(ctx context.Context) { DefaultManager().Start(ctx) }
@@ -17,6 +17,218 @@ type SDKConfig struct {
 
 	// Access holds request authentication provider configuration.
 	Access AccessConfig `yaml:"auth,omitempty" json:"auth,omitempty"`
+
+	// ModelAliases rewrites friendly model names requested by clients to concrete models
+	// before provider selection.
+	ModelAliases []ModelAlias `yaml:"model-aliases,omitempty" json:"model-aliases,omitempty"`
+
+	// ModelAccess restricts the models individual client API keys may request.
+	ModelAccess []ModelAccessRule `yaml:"model-access,omitempty" json:"model-access,omitempty"`
+
+	// ModelFallbacks lists, per model, the models to retry against when the primary rejects
+	// a request it is not capable of serving (e.g. context too long or no vision support).
+	ModelFallbacks []ModelFallback `yaml:"model-fallbacks,omitempty" json:"model-fallbacks,omitempty"`
+
+	// ModelMirrors duplicates a fraction of non-streaming requests for a model to a candidate
+	// model in the background and records the candidate's response in the audit log.
+	ModelMirrors []ModelMirror `yaml:"model-mirrors,omitempty" json:"model-mirrors,omitempty"`
+
+	// AllowRoutingOverride honours the X-Proxy-Provider and X-Proxy-Account request headers,
+	// which pin a request to one provider or credential. Keep disabled for untrusted clients.
+	AllowRoutingOverride bool `yaml:"allow-routing-override" json:"allow-routing-override"`
+
+	// DryRun makes every request return the translated upstream request instead of sending it.
+	// Individual requests can opt in with the X-Proxy-Dry-Run header.
+	DryRun bool `yaml:"dry-run" json:"dry-run"`
+
+	// CoalesceRequests shares one upstream call between identical non-streaming requests that
+	// are in flight at the same time. Individual requests can opt in with the X-Proxy-Coalesce header.
+	CoalesceRequests bool `yaml:"coalesce-requests" json:"coalesce-requests"`
+
+	// ImageInput bounds and shapes image_url parts in OpenAI chat requests.
+	ImageInput ImageInputConfig `yaml:"image-input,omitempty" json:"image-input,omitempty"`
+
+	// ContextWindowCheck rejects requests whose estimated size clearly exceeds the model's
+	// context window before any upstream call is made.
+	ContextWindowCheck ContextWindowCheckConfig `yaml:"context-window-check,omitempty" json:"context-window-check,omitempty"`
+
+	// ChoiceFanOut controls how OpenAI chat requests with n > 1 are served by upstreams that
+	// cannot return multiple choices natively.
+	ChoiceFanOut ChoiceFanOutConfig `yaml:"choice-fan-out,omitempty" json:"choice-fan-out,omitempty"`
+
+	// Batch bounds the chat completion requests accepted by /v1/chat/completions/batch.
+	Batch BatchConfig `yaml:"batch,omitempty" json:"batch,omitempty"`
+
+	// ResponseCache reuses the responses of identical non-streaming requests for a limited time.
+	// Individual requests can skip the cache with the X-Proxy-Cache-Bypass header.
+	ResponseCache ResponseCacheConfig `yaml:"response-cache,omitempty" json:"response-cache,omitempty"`
+
+	// StreamKeepAliveSeconds sends an SSE comment on streaming responses every this many seconds
+	// until the first upstream chunk arrives, so idle connections are not dropped by intermediaries.
+	// Zero disables keepalives.
+	StreamKeepAliveSeconds int `yaml:"stream-keepalive-seconds,omitempty" json:"stream-keepalive-seconds,omitempty"`
+
+	// UnsupportedLogitBias decides what happens to an OpenAI logit_bias that the serving provider
+	// cannot apply: "ignore" (default) drops it, "reject" answers 400. Token IDs are specific to
+	// the OpenAI tokenizers, so no approximation is attempted for other model families.
+	UnsupportedLogitBias string `yaml:"unsupported-logit-bias,omitempty" json:"unsupported-logit-bias,omitempty"`
+
+	// TokenEstimation fills token usage that the upstream did not report with an estimate.
+	TokenEstimation TokenEstimationConfig `yaml:"token-estimation,omitempty" json:"token-estimation,omitempty"`
+
+	// RequestParameters decides what happens to top-level OpenAI chat request parameters outside
+	// the allowlist of the serving providers.
+	RequestParameters RequestParametersConfig `yaml:"request-parameters,omitempty" json:"request-parameters,omitempty"`
+
+	// StructuredOutputStreamRepair buffers streamed OpenAI chat responses to requests with a JSON
+	// response_format and sends the content as one final chunk once it parses, closing the
+	// brackets of output cut short by an upstream error.
+	StructuredOutputStreamRepair bool `yaml:"structured-output-stream-repair" json:"structured-output-stream-repair"`
+}
+
+// RequestParametersConfig controls the inbound parameter allowlist of OpenAI chat requests.
+type RequestParametersConfig struct {
+	// Mode is "passthrough" (default), which forwards every parameter, "strip", which drops
+	// parameters outside the allowlist and logs them, or "strict", which rejects the request
+	// with 400 listing them.
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+
+	// Providers replaces the built-in allowlist of OpenAI chat parameters for the named providers.
+	Providers []ProviderParameters `yaml:"providers,omitempty" json:"providers,omitempty"`
+}
+
+// ProviderParameters lists the top-level request parameters one provider accepts. The model and
+// messages parameters are always allowed.
+type ProviderParameters struct {
+	Provider string   `yaml:"provider" json:"provider"`
+	Allow    []string `yaml:"allow" json:"allow"`
+}
+
+// TokenEstimationConfig selects the estimators used when an upstream omits token usage.
+type TokenEstimationConfig struct {
+	// Enabled turns estimation on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Estimator names the default estimator: "heuristic" (default) or "tiktoken", or the name
+	// of an estimator registered through the SDK.
+	Estimator string `yaml:"estimator,omitempty" json:"estimator,omitempty"`
+
+	// Models overrides the estimator per model; the first matching rule wins.
+	Models []TokenEstimatorRule `yaml:"models,omitempty" json:"models,omitempty"`
+}
+
+// TokenEstimatorRule selects an estimator for the models matching Model, which may contain
+// "*" wildcards.
+type TokenEstimatorRule struct {
+	Model     string `yaml:"model" json:"model"`
+	Estimator string `yaml:"estimator" json:"estimator"`
+}
+
+// ResponseCacheConfig controls the in-memory LRU cache of non-streaming responses.
+type ResponseCacheConfig struct {
+	// Enabled turns the cache on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// TTLSeconds is how long a cached response is served; zero uses the default of 300.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+
+	// MaxEntries bounds the number of cached responses, evicting the least recently used;
+	// zero uses the default of 1000.
+	MaxEntries int `yaml:"max-entries,omitempty" json:"max-entries,omitempty"`
+
+	// SkipNondeterministic leaves requests with a sampling temperature above zero uncached.
+	SkipNondeterministic bool `yaml:"skip-nondeterministic" json:"skip-nondeterministic"`
+}
+
+// ChoiceFanOutConfig bounds the concurrent upstream calls issued for n > 1 chat requests.
+type ChoiceFanOutConfig struct {
+	// MaxN is the largest n accepted; larger requests are rejected with 400. Zero uses the
+	// default of 8.
+	MaxN int `yaml:"max-n,omitempty" json:"max-n,omitempty"`
+
+	// Concurrency caps the sub-calls in flight per request; zero uses the default of 4.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+
+	// FailurePolicy decides what happens when some sub-calls fail: "partial" (default) returns
+	// the successful choices with "partial": true, "fail" returns the first error.
+	FailurePolicy string `yaml:"failure-policy,omitempty" json:"failure-policy,omitempty"`
+}
+
+// BatchConfig bounds the batch chat completions endpoint.
+type BatchConfig struct {
+	// MaxItems is the largest number of requests accepted in one batch; larger batches are
+	// rejected with 400. Zero uses the default of 100.
+	MaxItems int `yaml:"max-items,omitempty" json:"max-items,omitempty"`
+
+	// Concurrency caps the batch items in flight at once; zero uses the default of 4.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+}
+
+// ContextWindowCheckConfig controls the pre-flight context window check.
+type ContextWindowCheckConfig struct {
+	// Enabled turns the check on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// SafetyMargin is the fraction by which the token estimate must exceed the model's limit
+	// before a request is rejected, absorbing estimation error; zero uses the default of 0.1.
+	SafetyMargin float64 `yaml:"safety-margin,omitempty" json:"safety-margin,omitempty"`
+}
+
+// ImageInputConfig controls how images in OpenAI multimodal content are validated and forwarded.
+type ImageInputConfig struct {
+	// RemoteURLs selects how http(s) image URLs are forwarded: "inline" (default) fetches the
+	// image and embeds it as base64, "file-data" passes the URL upstream unchanged.
+	RemoteURLs string `yaml:"remote-urls,omitempty" json:"remote-urls,omitempty"`
+
+	// MaxImages caps the number of images per request; zero uses the default of 16.
+	MaxImages int `yaml:"max-images,omitempty" json:"max-images,omitempty"`
+
+	// MaxImageBytes caps the decoded size of each image; zero uses the default of 20 MiB.
+	MaxImageBytes int64 `yaml:"max-image-bytes,omitempty" json:"max-image-bytes,omitempty"`
+}
+
+// ModelAlias maps a client-facing model name to the model that should be requested instead.
+type ModelAlias struct {
+	// From is the alias requested by clients (e.g., "fast").
+	From string `yaml:"from" json:"from"`
+
+	// To is the target model name (e.g., "gemini-2.5-flash").
+	To string `yaml:"to" json:"to"`
+}
+
+// ModelAccessRule limits the models one client API key may request. Entries are model names,
+// after alias resolution, and may contain "*" wildcards.
+type ModelAccessRule struct {
+	// Key is the client API key the rule applies to.
+	Key string `yaml:"key" json:"key"`
+
+	// Allow lists the permitted models; empty permits every model not denied.
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+
+	// Deny lists forbidden models and wins over Allow.
+	Deny []string `yaml:"deny,omitempty" json:"deny,omitempty"`
+}
+
+// ModelFallback defines the fallback chain for one model.
+type ModelFallback struct {
+	// Model is the model name requested by clients, before alias resolution.
+	Model string `yaml:"model" json:"model"`
+
+	// Fallbacks are tried in order when the previous model cannot serve the request.
+	Fallbacks []string `yaml:"fallbacks" json:"fallbacks"`
+}
+
+// ModelMirror mirrors requests for one model to a candidate model for offline comparison.
+type ModelMirror struct {
+	// Model is the model name requested by clients, before alias resolution.
+	Model string `yaml:"model" json:"model"`
+
+	// Candidate is the model that receives the copy; openai-compatibility providers can be
+	// addressed as "provider://model".
+	Candidate string `yaml:"candidate" json:"candidate"`
+
+	// Fraction is the share of requests mirrored, between 0 and 1.
+	Fraction float64 `yaml:"fraction" json:"fraction"`
 }
 
 // AccessConfig groups request authentication providers.
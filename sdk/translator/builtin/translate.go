@@ -0,0 +1,89 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+// ErrUnsupportedTranslation is returned when no built-in translator converts between two formats.
+var ErrUnsupportedTranslation = errors.New("unsupported translation")
+
+// streamDone is the terminator the response translators expect after the last upstream chunk.
+const streamDone = "[DONE]"
+
+// TranslateRequest converts a client request body from one schema to another, e.g. an OpenAI
+// Chat Completions request to a Gemini generateContent request. model is the upstream model
+// name and stream selects the streaming variant of the target request. Identical formats
+// return the body unchanged.
+func TranslateRequest(from, to sdktranslator.Format, model string, body []byte, stream bool) ([]byte, error) {
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("translate %s request to %s: body is not valid JSON", from, to)
+	}
+	if from == to {
+		return body, nil
+	}
+	if !sdktranslator.HasRequestTransformer(from, to) {
+		return nil, fmt.Errorf("translate %s request to %s: %w", from, to, ErrUnsupportedTranslation)
+	}
+	return sdktranslator.TranslateRequest(from, to, model, body, stream), nil
+}
+
+// TranslateResponse converts a complete non-streaming upstream response in schema from back
+// to the client schema to. originalRequest is the client request and translatedRequest the
+// body produced for it by TranslateRequest; some translators consult them to restore
+// request-specific details such as tool names.
+func TranslateResponse(ctx context.Context, from, to sdktranslator.Format, model string, originalRequest, translatedRequest, body []byte) ([]byte, error) {
+	if from == to {
+		return body, nil
+	}
+	if !sdktranslator.HasResponseTransformer(to, from) {
+		return nil, fmt.Errorf("translate %s response to %s: %w", from, to, ErrUnsupportedTranslation)
+	}
+	var param any
+	return []byte(sdktranslator.TranslateNonStream(ctx, from, to, model, originalRequest, translatedRequest, body, &param)), nil
+}
+
+// StreamTranslator converts the chunks of one streaming upstream response, keeping the state
+// the translators carry between chunks. It is not safe for concurrent use.
+type StreamTranslator struct {
+	from, to          sdktranslator.Format
+	model             string
+	originalRequest   []byte
+	translatedRequest []byte
+	param             any
+}
+
+// NewStreamTranslator prepares the translation of a streaming response in schema from back
+// to the client schema to, with the same arguments as TranslateResponse.
+func NewStreamTranslator(from, to sdktranslator.Format, model string, originalRequest, translatedRequest []byte) (*StreamTranslator, error) {
+	if from != to && !sdktranslator.HasResponseTransformer(to, from) {
+		return nil, fmt.Errorf("translate %s stream to %s: %w", from, to, ErrUnsupportedTranslation)
+	}
+	return &StreamTranslator{
+		from:              from,
+		to:                to,
+		model:             model,
+		originalRequest:   originalRequest,
+		translatedRequest: translatedRequest,
+	}, nil
+}
+
+// Translate converts one upstream chunk into zero or more client chunks.
+func (s *StreamTranslator) Translate(ctx context.Context, chunk []byte) []string {
+	if s.from == s.to {
+		return []string{string(chunk)}
+	}
+	return sdktranslator.TranslateStream(ctx, s.from, s.to, s.model, s.originalRequest, s.translatedRequest, chunk, &s.param)
+}
+
+// Close returns the chunks that terminate the client stream, such as a trailing usage chunk.
+func (s *StreamTranslator) Close(ctx context.Context) []string {
+	if s.from == s.to {
+		return nil
+	}
+	return sdktranslator.TranslateStream(ctx, s.from, s.to, s.model, s.originalRequest, s.translatedRequest, []byte(streamDone), &s.param)
+}
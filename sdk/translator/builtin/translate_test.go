@@ -0,0 +1,131 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	openAIRequest = `{"model":"m","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hello"}]}`
+	claudeRequest = `{"model":"m","max_tokens":64,"system":"be brief","messages":[{"role":"user","content":[{"type":"text","text":"hello"}]}]}`
+	geminiRequest = `{"systemInstruction":{"parts":[{"text":"be brief"}]},"contents":[{"role":"user","parts":[{"text":"hello"}]}]}`
+
+	openAIResponse = `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hi there"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`
+	claudeResponse = `data: {"type":"message_start","message":{"id":"msg_1","model":"m","usage":{"input_tokens":3}}}` + "\n" +
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi there"}}` + "\n" +
+		`data: {"type":"content_block_stop","index":0}` + "\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}` + "\n" +
+		`data: {"type":"message_stop"}`
+	geminiResponse = `{"candidates":[{"content":{"role":"model","parts":[{"text":"hi there"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5},"modelVersion":"m","responseId":"resp-1"}`
+)
+
+func TestTranslateRequest_Directions(t *testing.T) {
+	cases := []struct {
+		from, to sdktranslator.Format
+		body     string
+		path     string
+	}{
+		{sdktranslator.FormatOpenAI, sdktranslator.FormatGemini, openAIRequest, "contents.0.parts.0.text"},
+		{sdktranslator.FormatOpenAI, sdktranslator.FormatClaude, openAIRequest, "messages.0.content.0.text"},
+		{sdktranslator.FormatClaude, sdktranslator.FormatGemini, claudeRequest, "contents.0.parts.0.text"},
+		{sdktranslator.FormatClaude, sdktranslator.FormatOpenAI, claudeRequest, "messages.1.content.0.text"},
+		{sdktranslator.FormatGemini, sdktranslator.FormatOpenAI, geminiRequest, "messages.1.content"},
+		{sdktranslator.FormatGemini, sdktranslator.FormatClaude, geminiRequest, "messages.0.content.0.text"},
+	}
+	for _, tc := range cases {
+		out, err := TranslateRequest(tc.from, tc.to, "m", []byte(tc.body), false)
+		if err != nil {
+			t.Errorf("%s -> %s: %v", tc.from, tc.to, err)
+			continue
+		}
+		if got := gjson.GetBytes(out, tc.path).String(); got != "hello" {
+			t.Errorf("%s -> %s: expected %s to hold the prompt, got %s", tc.from, tc.to, tc.path, out)
+		}
+	}
+}
+
+func TestTranslateResponse_Directions(t *testing.T) {
+	cases := []struct {
+		from, to sdktranslator.Format
+		request  string
+		body     string
+		path     string
+	}{
+		{sdktranslator.FormatGemini, sdktranslator.FormatOpenAI, openAIRequest, geminiResponse, "choices.0.message.content"},
+		{sdktranslator.FormatClaude, sdktranslator.FormatOpenAI, openAIRequest, claudeResponse, "choices.0.message.content"},
+		{sdktranslator.FormatGemini, sdktranslator.FormatClaude, claudeRequest, geminiResponse, "content.0.text"},
+		{sdktranslator.FormatOpenAI, sdktranslator.FormatClaude, claudeRequest, openAIResponse, "content.0.text"},
+		{sdktranslator.FormatOpenAI, sdktranslator.FormatGemini, geminiRequest, openAIResponse, "candidates.0.content.parts.0.text"},
+		{sdktranslator.FormatClaude, sdktranslator.FormatGemini, geminiRequest, claudeResponse, "candidates.0.content.parts.0.text"},
+	}
+	for _, tc := range cases {
+		// The client request was in the target format and was translated for the upstream.
+		translated, err := TranslateRequest(tc.to, tc.from, "m", []byte(tc.request), false)
+		if err != nil {
+			t.Errorf("%s -> %s: translate request: %v", tc.to, tc.from, err)
+			continue
+		}
+		out, err := TranslateResponse(context.Background(), tc.from, tc.to, "m", []byte(tc.request), translated, []byte(tc.body))
+		if err != nil {
+			t.Errorf("%s -> %s: %v", tc.from, tc.to, err)
+			continue
+		}
+		if got := gjson.GetBytes(out, tc.path).String(); got != "hi there" {
+			t.Errorf("%s -> %s: expected %s to hold the reply, got %s", tc.from, tc.to, tc.path, out)
+		}
+	}
+}
+
+func TestStreamTranslator_GeminiToOpenAI(t *testing.T) {
+	original := []byte(`{"model":"m","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hello"}]}`)
+	translated, err := TranslateRequest(sdktranslator.FormatOpenAI, sdktranslator.FormatGemini, "m", original, true)
+	if err != nil {
+		t.Fatalf("translate request: %v", err)
+	}
+	stream, err := NewStreamTranslator(sdktranslator.FormatGemini, sdktranslator.FormatOpenAI, "m", original, translated)
+	if err != nil {
+		t.Fatalf("new stream translator: %v", err)
+	}
+	var content []string
+	for _, chunk := range []string{
+		`{"candidates":[{"content":{"parts":[{"text":"hi "}]}}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":1,"totalTokenCount":4}}`,
+		`{"candidates":[{"content":{"parts":[{"text":"there"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5}}`,
+	} {
+		for _, out := range stream.Translate(context.Background(), []byte(chunk)) {
+			content = append(content, gjson.Get(out, "choices.0.delta.content").String())
+		}
+	}
+	if got := strings.Join(content, ""); got != "hi there" {
+		t.Fatalf("Expected streamed content %q, got %q", "hi there", got)
+	}
+	final := stream.Close(context.Background())
+	if len(final) != 1 || gjson.Get(final[0], "usage.total_tokens").Int() != 5 {
+		t.Fatalf("Expected a trailing usage chunk, got %v", final)
+	}
+}
+
+func TestTranslate_Errors(t *testing.T) {
+	if _, err := TranslateRequest(sdktranslator.FormatOpenAI, sdktranslator.FormatGemini, "m", []byte("not json"), false); err == nil {
+		t.Fatal("Expected invalid JSON to be rejected")
+	}
+	unknown := sdktranslator.FromString("unknown")
+	if _, err := TranslateRequest(sdktranslator.FormatOpenAI, unknown, "m", []byte(openAIRequest), false); !errors.Is(err, ErrUnsupportedTranslation) {
+		t.Fatalf("Expected ErrUnsupportedTranslation, got %v", err)
+	}
+	if _, err := TranslateResponse(context.Background(), unknown, sdktranslator.FormatOpenAI, "m", nil, nil, []byte(`{}`)); !errors.Is(err, ErrUnsupportedTranslation) {
+		t.Fatalf("Expected ErrUnsupportedTranslation, got %v", err)
+	}
+	if _, err := NewStreamTranslator(unknown, sdktranslator.FormatOpenAI, "m", nil, nil); !errors.Is(err, ErrUnsupportedTranslation) {
+		t.Fatalf("Expected ErrUnsupportedTranslation, got %v", err)
+	}
+	out, err := TranslateRequest(sdktranslator.FormatClaude, sdktranslator.FormatClaude, "m", []byte(claudeRequest), false)
+	if err != nil || string(out) != claudeRequest {
+		t.Fatalf("Expected identical formats to pass through, got %s, %v", out, err)
+	}
+}